@@ -0,0 +1,679 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/assets"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/config"
+	"nuclei-mcp/pkg/distributed"
+	"nuclei-mcp/pkg/encryption"
+	"nuclei-mcp/pkg/enrich"
+	"nuclei-mcp/pkg/exclusions"
+	"nuclei-mcp/pkg/logging"
+	"nuclei-mcp/pkg/notify"
+	"nuclei-mcp/pkg/paths"
+	"nuclei-mcp/pkg/policy"
+	"nuclei-mcp/pkg/scanner"
+	"nuclei-mcp/pkg/schedule"
+	"nuclei-mcp/pkg/secrets"
+	"nuclei-mcp/pkg/telemetry"
+	"nuclei-mcp/pkg/templates"
+	"nuclei-mcp/pkg/tracker"
+	"nuclei-mcp/pkg/wordlists"
+)
+
+// templateDir holds the custom templates of a tenant in its workspace
+const templateDir = "nuclei-templates"
+
+// tracingFlushTimeout bounds how long Close waits for pending traces to be
+// sent
+const tracingFlushTimeout = 5 * time.Second
+
+// app holds the components shared by the subcommands, built from config
+type app struct {
+	cfg            config.Config
+	console        *logging.ConsoleLogger
+	output         io.Writer
+	resultCache    *cache.ResultCache
+	tenantCaches   []*cache.ResultCache
+	verifier       *templates.Verifier
+	bundleDirs     []string
+	sourceDirs     []string
+	templatePolicy *policy.TemplatePolicy
+	egress         *policy.EgressPolicy
+	selfGuard      *policy.SelfTargetGuard
+	exclusions     *exclusions.Store
+	tracker        *tracker.Store
+	schedules      *schedule.Store
+	enricher       *enrich.Enricher
+	assets         *assets.Registry
+	wordlists      *wordlists.Store
+	queue          *scanner.ScanQueue
+	maintenance    *scanner.Maintenance
+	quotas         *scanner.QuotaTracker
+	templateIndex  *scanner.TemplateIndex
+	timings        *scanner.TemplateTimings
+	quarantine     *scanner.TemplateQuarantine
+	alerter        *notify.Alerter
+	encryption     *encryption.Key
+	scanDefaults   api.ScanDefaults
+	tracing        telemetry.Shutdown
+	// scanner runs the scans of the subcommands: the local scanner, or the
+	// coordinator when workers are configured
+	scanner     scanner.ScannerService
+	local       scanner.ScannerService
+	coordinator *distributed.Coordinator
+	templates   templates.TemplateManager
+}
+
+// newApp loads the configuration and builds the scanner and template
+// manager. Logs are written to the configured log file and to console.
+func newApp(console io.Writer) (*app, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	return newAppWithConfig(cfg, console)
+}
+
+// loadConfig loads the configuration from the working directory or the
+// config directory
+func loadConfig() (config.Config, error) {
+	cfg, err := config.LoadConfig(paths.FindConfigDir())
+	if err != nil {
+		return config.Config{}, fmt.Errorf("cannot load config: %w", err)
+	}
+	return cfg, nil
+}
+
+// newAppWithConfig builds the app of a loaded configuration, logging to the
+// configured log file and to console
+func newAppWithConfig(cfg config.Config, console io.Writer) (*app, error) {
+	// Move files left at the previous default locations to the per-user
+	// directories, before the log file is opened in its new place
+	moved, migrateErr := paths.Migrate(migrations(cfg))
+
+	// Create console logger
+	consoleLogger, err := logging.NewConsoleLoggerWithOutput(cfg.Logging.Path, console)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create console logger: %w", err)
+	}
+	for _, move := range moved {
+		consoleLogger.Log("Moved %s to %s", move.From, move.To)
+	}
+	if migrateErr != nil {
+		consoleLogger.Log("Failed to move files to the per-user directories: %v", migrateErr)
+	}
+	for _, stray := range strays(cfg) {
+		consoleLogger.Log("Found %s at a previous default location; if it is the server's, move it to %s to keep using it", stray.From, stray.To)
+	}
+
+	a := &app{
+		cfg:            cfg,
+		console:        consoleLogger,
+		output:         console,
+		resultCache:    cache.NewResultCache(cfg.Cache.Expiry, log.New(console, "[Cache] ", log.LstdFlags)),
+		templatePolicy: policy.NewTemplatePolicy(cfg.Policy.DeniedTags),
+	}
+	if err := a.init(); err != nil {
+		consoleLogger.Close()
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *app) init() error {
+	cfg := a.cfg
+
+	// Export traces of tool calls and scans
+	tracing, err := telemetry.Setup(context.Background(), cfg.Telemetry.OTLPEndpoint)
+	if err != nil {
+		return fmt.Errorf("failed to set up tracing: %w", err)
+	}
+	a.tracing = tracing
+	if cfg.Telemetry.OTLPEndpoint != "" {
+		a.console.Log("Sending traces to %s", cfg.Telemetry.OTLPEndpoint)
+	}
+
+	// Set up template signature verification
+	if sigCfg := cfg.Nuclei.SignatureVerification; sigCfg.Mode != "" {
+		verifier, err := templates.NewVerifier(sigCfg.Mode, sigCfg.PublicKey, sigCfg.Enforce)
+		if err != nil {
+			return fmt.Errorf("failed to set up template signature verification: %w", err)
+		}
+		a.verifier = verifier
+	}
+
+	// Load offline template bundles into their namespaces
+	for _, bundle := range cfg.Nuclei.TemplateBundles {
+		result, err := templates.LoadBundle(context.Background(), templates.Bundle{Name: bundle.Name, Source: bundle.Source}, cfg.Nuclei.BundlesDir, a.verifier)
+		if err != nil {
+			return fmt.Errorf("failed to load template bundle %s: %w", bundle.Name, err)
+		}
+		if len(result.Invalid) > 0 {
+			a.console.Log("Template bundle %s: skipped %d invalid templates", result.Name, len(result.Invalid))
+		}
+		if len(result.Unverified) > 0 {
+			a.console.Log("Template bundle %s: %d templates failed signature verification", result.Name, len(result.Unverified))
+		}
+		a.console.Log("Loaded template bundle %s (%d templates) into %s", result.Name, result.Templates, result.Dir)
+		a.bundleDirs = append(a.bundleDirs, result.Dir)
+	}
+
+	// Encrypt spilled findings, the finding tracker, the asset registry and
+	// workspace archives at rest
+	if cfg.Encryption.Enabled {
+		key, err := encryption.Load(cfg.Encryption.KeyEnv, cfg.Encryption.KeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load encryption key: %w", err)
+		}
+		a.encryption = key
+	}
+
+	// Restrict where scans may connect to
+	a.egress, err = policy.NewEgressPolicy(egressOptions(cfg))
+	if err != nil {
+		return fmt.Errorf("invalid egress policy: %w", err)
+	}
+	if cfg.Policy.SelfTargetGuard {
+		a.selfGuard, err = policy.NewSelfTargetGuard()
+		if err != nil {
+			return fmt.Errorf("failed to create self-target guard: %w", err)
+		}
+	}
+
+	// Load the per-target template exclusion rules
+	a.exclusions, err = exclusions.Open(cfg.Scanner.ExclusionsFile)
+	if err != nil {
+		return fmt.Errorf("failed to load exclusions: %w", err)
+	}
+
+	// Load the status and retests of tracked findings
+	a.tracker, err = tracker.Open(cfg.Findings.TrackerFile, tracker.WithEncryptionKey(a.encryption))
+	if err != nil {
+		return fmt.Errorf("failed to load finding tracker: %w", err)
+	}
+
+	// Load the scheduled scans restored from workspace archives
+	a.schedules, err = schedule.OpenStore(cfg.Schedules.File)
+	if err != nil {
+		return fmt.Errorf("failed to load scheduled scans: %w", err)
+	}
+
+	// Load the recon context collected by target_context
+	a.assets, err = assets.Open(cfg.Assets.RegistryFile, assets.WithEncryptionKey(a.encryption))
+	if err != nil {
+		return fmt.Errorf("failed to load asset registry: %w", err)
+	}
+
+	// Wordlists referenced by fuzzing templates
+	a.wordlists = wordlists.New(cfg.Wordlists.Dir, cfg.Wordlists.MaxEntries)
+
+	// Describe where scanned hosts are hosted
+	if cfg.Enrichment.Enabled {
+		a.enricher, err = newEnricher(cfg.Enrichment)
+		if err != nil {
+			return fmt.Errorf("failed to set up enrichment: %w", err)
+		}
+	}
+
+	// Apply the configured scan profile to omitted scan arguments
+	a.scanDefaults = scanDefaults(cfg)
+	if err := a.scanDefaults.Validate(); err != nil {
+		return fmt.Errorf("invalid scanner.defaults: %w", err)
+	}
+
+	// Create Template Manager over the custom templates directory and the
+	// configured template directories, highest priority first
+	var sources []templates.Source
+	for _, dir := range cfg.Nuclei.TemplateDirs {
+		sources = append(sources, templates.Source{Name: dir.Name, Dir: dir.Path})
+		a.sourceDirs = append(a.sourceDirs, dir.Path)
+	}
+	a.templates, err = templates.NewTemplateManager(cfg.Nuclei.TemplatesDir, templates.WithSources(sources...))
+	if err != nil {
+		return fmt.Errorf("failed to create template manager: %w", err)
+	}
+
+	// Order scans by priority when more are requested than can run at once
+	if cfg.Scanner.Queue.Slots > 0 {
+		a.queue = scanner.NewScanQueue(cfg.Scanner.Queue.Slots, scanner.QueuePolicy(cfg.Scanner.Queue.Policy), cfg.Scanner.Queue.InteractiveWeight)
+	}
+
+	// Refuse new scans while scanning is paused for maintenance
+	a.maintenance = scanner.NewMaintenance()
+
+	// Account the resources of each client's scans against its quota
+	if cfg.Scanner.Quotas.Enabled {
+		a.quotas = quotaTracker(cfg.Scanner.Quotas)
+	}
+
+	// Select the template files of filtered scans from the template index
+	if cfg.Scanner.TemplateIndex {
+		a.templateIndex = scanner.NewTemplateIndex(paths.TemplateIndexFile())
+	}
+
+	// Estimate the progress of scans from the run times of past scans
+	a.timings = scanner.NewTemplateTimings(paths.TemplateTimingsFile())
+
+	// Leave templates that keep erroring at runtime out of scans
+	if cfg.Scanner.Quarantine.Enabled {
+		a.quarantine = scanner.NewTemplateQuarantine(paths.TemplateQuarantineFile(), cfg.Scanner.Quarantine.Threshold)
+	}
+
+	// Alert on the findings of completed scans
+	a.alerter, err = newAlerter(cfg.Notifications, a.console)
+	if err != nil {
+		return fmt.Errorf("failed to set up notifications: %w", err)
+	}
+
+	// Create scanner service with console logger
+	// Only the server's own scans resolve the configured secrets and send
+	// alerts
+	secretSources := make(map[string]secrets.Source, len(cfg.Secrets))
+	for name, secret := range cfg.Secrets {
+		secretSources[name] = secrets.Source{Env: secret.Env, File: secret.File}
+	}
+	localOpts := append(a.serviceOptions(cfg.Nuclei.TemplatesDir, a.templates, a.exclusions), scanner.WithSecrets(secrets.NewStore(secretSources)))
+	if a.alerter != nil {
+		localOpts = append(localOpts, scanner.WithResultNotifier(a.alerter))
+	}
+	a.scanner = scanner.NewScannerService(a.resultCache, a.console, localOpts...)
+	a.local = a.scanner
+
+	// Send scans to the configured workers
+	if len(cfg.Distributed.Workers) > 0 {
+		var workers []distributed.Worker
+		for _, worker := range cfg.Distributed.Workers {
+			workers = append(workers, distributed.Worker{Name: worker.Name, URL: worker.URL, Capacity: worker.Capacity})
+		}
+		coordinatorOpts := []distributed.CoordinatorOption{
+			distributed.WithHealthInterval(cfg.Distributed.HealthInterval),
+			distributed.WithPassiveByDefault(cfg.Scanner.PassiveByDefault),
+			distributed.WithScanQueue(a.queue),
+			distributed.WithMaintenance(a.maintenance),
+			distributed.WithQuotas(a.quotas),
+			distributed.WithTargetPolicy(a.egress, a.selfGuard),
+		}
+		if a.alerter != nil {
+			coordinatorOpts = append(coordinatorOpts, distributed.WithResultNotifier(a.alerter))
+		}
+		if cfg.Distributed.CAFile != "" {
+			client, err := workerClient(cfg.Distributed.CAFile)
+			if err != nil {
+				return fmt.Errorf("invalid distributed configuration: %w", err)
+			}
+			coordinatorOpts = append(coordinatorOpts, distributed.WithHTTPClient(client))
+		}
+		a.coordinator, err = distributed.NewCoordinator(a.local, a.resultCache, a.console, cfg.Distributed.Token, workers, coordinatorOpts...)
+		if err != nil {
+			return fmt.Errorf("invalid distributed configuration: %w", err)
+		}
+		a.scanner = a.coordinator
+	}
+	return nil
+}
+
+// workerClient returns the client a coordinator reaches its workers with,
+// trusting the CAs in caFile besides the system's
+func workerClient(caFile string) (*http.Client, error) {
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no PEM certificates in CA file %s", caFile)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	return &http.Client{Transport: transport}, nil
+}
+
+// serviceOptions returns the configuration of a scanner service over the
+// custom templates in customDir, managed by tm, and the exclusion rules in
+// excl. The server and each tenant have a scanner service of their own.
+func (a *app) serviceOptions(customDir string, tm templates.TemplateManager, excl *exclusions.Store) []scanner.ServiceOption {
+	cfg := a.cfg
+	opts := []scanner.ServiceOption{
+		scanner.WithTemplateDirs(a.templateDirs(customDir)...),
+		scanner.WithShadowedTemplates(a.shadowedTemplates(tm)),
+		scanner.WithCodeTemplatesAllowed(cfg.Scanner.AllowCodeTemplates),
+		scanner.WithDeniedTags(cfg.Policy.DeniedTags),
+		scanner.WithEgressPolicy(a.egress),
+		scanner.WithSelfTargetGuard(a.selfGuard),
+		scanner.WithPassiveByDefault(cfg.Scanner.PassiveByDefault),
+		scanner.WithPassiveRateLimit(cfg.Scanner.PassiveRateLimit),
+		scanner.WithTemplateCache(cfg.Scanner.TemplateCache),
+		scanner.WithTemplateIndex(a.templateIndex),
+		scanner.WithTemplateTimings(a.timings),
+		scanner.WithTemplateQuarantine(a.quarantine),
+		scanner.WithResultBuffer(cfg.Scanner.ResultBuffer),
+		scanner.WithSpillThreshold(cfg.Scanner.SpillThreshold, cfg.Scanner.SpillDir),
+		scanner.WithEngineTimeout(cfg.Scanner.EngineTimeout),
+		scanner.WithForceHTTP2(cfg.Scanner.ForceHTTP2),
+		scanner.WithSafetyLimits(scanner.SafetyLimits{
+			MaxConnectionLifetime: cfg.Scanner.Safety.MaxConnectionLifetime,
+			MaxBodyRead:           cfg.Scanner.Safety.MaxBodyRead,
+			TemplateTimeout:       cfg.Scanner.Safety.TemplateTimeout,
+			MaxTemplateRequests:   cfg.Scanner.Safety.MaxTemplateRequests,
+		}),
+		scanner.WithExclusions(excl),
+		scanner.WithAdaptiveTuning(cfg.Scanner.Adaptive.Enabled, scanner.AdaptiveTuning{
+			ErrorRate:    cfg.Scanner.Adaptive.ErrorRate,
+			Window:       cfg.Scanner.Adaptive.Window,
+			MinRateLimit: cfg.Scanner.Adaptive.MinRateLimit,
+		}),
+		scanner.WithScanQueue(a.queue),
+		scanner.WithMaintenance(a.maintenance),
+		scanner.WithQuotas(a.quotas),
+		scanner.WithEncryptionKey(a.encryption),
+	}
+	if a.enricher != nil {
+		opts = append(opts, scanner.WithHostEnricher(a.enricher))
+	}
+	return opts
+}
+
+// templateDirs returns the template directories scans load in addition to
+// the default nuclei templates directory
+func (a *app) templateDirs(customDir string) []string {
+	dirs := append([]string(nil), a.bundleDirs...)
+	if len(a.sourceDirs) == 0 {
+		return dirs
+	}
+	return append(append(dirs, customDir), a.sourceDirs...)
+}
+
+// shadowedTemplates returns a function listing the template files of tm
+// overridden by a template with the same ID in a higher priority template
+// directory
+func (a *app) shadowedTemplates(tm templates.TemplateManager) func() []string {
+	return func() []string {
+		catalog, err := tm.Catalog()
+		if err != nil {
+			a.console.Log("Failed to index template directories: %v", err)
+			return nil
+		}
+		var shadowed []string
+		for _, template := range catalog {
+			if template.ShadowedBy != "" {
+				shadowed = append(shadowed, template.Path)
+			}
+		}
+		return shadowed
+	}
+}
+
+// Close flushes pending traces and releases the log file
+func (a *app) Close() error {
+	if a.alerter != nil {
+		// Alerts of the last scans, such as the one of the scan command
+		a.alerter.Wait()
+	}
+	// Spill files only hold findings of cached results
+	a.resultCache.Close()
+	for _, tenantCache := range a.tenantCaches {
+		tenantCache.Close()
+	}
+	if a.tracing != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), tracingFlushTimeout)
+		if err := a.tracing(ctx); err != nil {
+			a.console.Log("Failed to flush traces: %v", err)
+		}
+		cancel()
+	}
+	return a.console.Close()
+}
+
+// concurrency returns the multi-target scan limits from config
+func (a *app) concurrency() scanner.Concurrency {
+	return scanner.Concurrency{Host: a.cfg.Scanner.HostConcurrency, Global: a.cfg.Scanner.GlobalConcurrency}
+}
+
+// migrations returns the moves of files at the previous default locations
+// (next to the executable, where the server used to be run from, then the
+// config directory) to the per-user directories, for the paths the
+// configuration leaves at their defaults
+func migrations(cfg config.Config) []paths.Move {
+	configDir := paths.ConfigDir()
+	if !filepath.IsAbs(configDir) {
+		// Without a home directory it is the working directory, see strays
+		configDir = ""
+	}
+	return previousDefaults(cfg, paths.InstallDir(), configDir)
+}
+
+// strays returns the files at the previous default locations relative to the
+// working directory. An MCP host may start the server in any project, whose
+// files of the same names are not necessarily ours, so they are only
+// reported, not moved.
+func strays(cfg config.Config) []paths.Move {
+	return paths.Pending(previousDefaults(cfg, ".", ""))
+}
+
+// previousDefaults returns the moves of the files the server used to keep
+// relative to workDir and in configDir to the per-user directories, for the
+// paths the configuration leaves at their defaults. Empty directories are
+// skipped.
+func previousDefaults(cfg config.Config, workDir, configDir string) []paths.Move {
+	var moves []paths.Move
+	add := func(configured, current, dir, previous string) {
+		if configured != current || dir == "" {
+			return
+		}
+		if abs, err := filepath.Abs(filepath.Join(dir, previous)); err == nil {
+			moves = append(moves, paths.Move{From: abs, To: current})
+		}
+	}
+	add(cfg.Nuclei.TemplatesDir, paths.TemplatesDir(), workDir, "nuclei-templates")
+	add(cfg.Nuclei.TemplatesDir, paths.TemplatesDir(), configDir, "nuclei-templates")
+	add(cfg.Server.ExportDir, paths.ExportsDir(), configDir, "exports")
+	add(cfg.Scanner.ExclusionsFile, paths.DataFile("exclusions.json"), workDir, "exclusions.json")
+	add(cfg.Findings.TrackerFile, paths.DataFile("findings.json"), workDir, "findings.json")
+	add(cfg.Assets.RegistryFile, paths.DataFile("assets.json"), workDir, "assets.json")
+	add(cfg.Wordlists.Dir, paths.DataFile("wordlists"), workDir, "wordlists")
+	add(cfg.Logging.Path, paths.LogFile(), workDir, filepath.Join("logs", "nuclei_mcp.log"))
+	add(paths.TenantsDir(), paths.TenantsDir(), workDir, "tenants")
+	return moves
+}
+
+// egressOptions returns the configured egress restrictions
+func egressOptions(cfg config.Config) policy.EgressOptions {
+	return policy.EgressOptions{
+		DenyPrivate:  cfg.Policy.Egress.DenyPrivate,
+		DenyMetadata: cfg.Policy.Egress.DenyMetadata,
+		DenyCIDRs:    cfg.Policy.Egress.DenyCIDRs,
+		AllowedPorts: cfg.Policy.Egress.AllowedPorts,
+	}
+}
+
+// scanDefaults returns the configured scan profile, with the default
+// template filters
+func scanDefaults(cfg config.Config) api.ScanDefaults {
+	filters := cfg.Nuclei.DefaultFilters
+	severity := cfg.Scanner.Defaults.Severity
+	if len(filters.Severities) > 0 {
+		severity = strings.Join(filters.Severities, ",")
+	}
+	return api.ScanDefaults{
+		Severity:          severity,
+		Protocols:         cfg.Scanner.Defaults.Protocols,
+		Tags:              append(append([]string(nil), cfg.Scanner.Defaults.Tags...), filters.Tags...),
+		RateLimit:         cfg.Scanner.Defaults.RateLimit,
+		Format:            cfg.Scanner.Defaults.Format,
+		ExcludeTags:       filters.ExcludeTags,
+		ExcludeIDs:        filters.ExcludeIDs,
+		ExcludeSeverities: filters.ExcludeSeverities,
+		Authors:           filters.Authors,
+		TemplateIDs:       filters.IDs,
+	}
+}
+
+// scheduleDefinitions returns the configured scheduled scans followed by
+// those restored from workspace archives
+func (a *app) scheduleDefinitions() []schedule.Definition {
+	definitions := make([]schedule.Definition, 0, len(a.cfg.Schedules.Scans))
+	for _, scan := range a.cfg.Schedules.Scans {
+		definitions = append(definitions, schedule.Definition{
+			Target:        scan.Target,
+			Severity:      scan.Severity,
+			Protocols:     scan.Protocols,
+			Tags:          scan.Tags,
+			Every:         scan.Every,
+			WarmCache:     scan.WarmCache,
+			SkipUnchanged: scan.SkipUnchanged,
+		})
+	}
+	if a.schedules != nil {
+		definitions = append(definitions, a.schedules.List()...)
+	}
+	return definitions
+}
+
+// scheduledScans returns the scheduled scans of definitions, with their
+// arguments resolved against the scan profile like those of nuclei_scan so
+// their results are cached under the same keys
+func (a *app) scheduledScans(definitions []schedule.Definition) []schedule.Scan {
+	scans := make([]schedule.Scan, 0, len(definitions))
+	for _, definition := range definitions {
+		severity, protocols, opts := a.scanDefaults.Resolve(map[string]any{
+			"severity":  definition.Severity,
+			"protocols": definition.Protocols,
+			"tags":      definition.Tags,
+		})
+		scans = append(scans, schedule.Scan{
+			Target:        definition.Target,
+			Severity:      severity,
+			Protocols:     protocols,
+			Options:       opts,
+			Every:         definition.Every,
+			WarmCache:     definition.WarmCache,
+			SkipUnchanged: definition.SkipUnchanged,
+		})
+	}
+	return scans
+}
+
+// scheduleBackup backs up the scheduled scans with the workspace, and
+// schedules the restored ones on the running scheduler
+type scheduleBackup struct {
+	app       *app
+	scheduler *schedule.Scheduler
+}
+
+func (b scheduleBackup) List() []schedule.Definition {
+	return b.app.scheduleDefinitions()
+}
+
+func (b scheduleBackup) Restore(definitions []schedule.Definition) (int, error) {
+	added, err := b.app.schedules.Add(definitions, b.app.scheduleDefinitions())
+	if err != nil {
+		return 0, err
+	}
+	b.scheduler.Add(b.app.scheduledScans(added)...)
+	return len(added), nil
+}
+
+// newEnricher opens the configured MaxMind databases
+func newEnricher(cfg config.EnrichmentConfig) (*enrich.Enricher, error) {
+	var opts []enrich.Option
+	if cfg.ASNDatabase != "" {
+		db, err := enrich.OpenDB(cfg.ASNDatabase)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, enrich.WithASNDatabase(db))
+	}
+	if cfg.GeoIPDatabase != "" {
+		db, err := enrich.OpenDB(cfg.GeoIPDatabase)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, enrich.WithGeoDatabase(db))
+	}
+	return enrich.New(opts...), nil
+}
+
+// newAlerter returns the alerter of the configured notification sinks, or
+// nil when none is enabled
+func newAlerter(cfg config.NotificationsConfig, console scanner.LoggerInterface) (*notify.Alerter, error) {
+	var sinks []notify.Sink
+	if email := cfg.Email; email.Enabled {
+		var password string
+		if email.PasswordEnv != "" {
+			password = os.Getenv(email.PasswordEnv)
+		}
+		if email.Username != "" && password == "" {
+			return nil, fmt.Errorf("smtp username is set but %s holds no password", email.PasswordEnv)
+		}
+		sink, err := notify.NewSMTPSink(notify.SMTPConfig{
+			Host:     email.Host,
+			Port:     email.Port,
+			TLS:      email.TLS,
+			From:     email.From,
+			To:       email.To,
+			Username: email.Username,
+			Password: password,
+		})
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+		console.Log("Sending alerts by email to %s", strings.Join(email.To, ", "))
+	}
+	for i, webhook := range cfg.Webhooks {
+		rawURL := webhook.URL
+		if rawURL == "" && webhook.URLEnv != "" {
+			rawURL = os.Getenv(webhook.URLEnv)
+		}
+		sink, err := notify.NewWebhookSink(rawURL, webhook.Format)
+		if err != nil {
+			return nil, fmt.Errorf("webhook %d: %w", i+1, err)
+		}
+		sinks = append(sinks, sink)
+		console.Log("Sending alerts to a %s webhook", sink.Name())
+	}
+	if len(sinks) == 0 {
+		return nil, nil
+	}
+	minSeverity, err := notify.ParseSeverity(cfg.MinSeverity)
+	if err != nil {
+		return nil, err
+	}
+	return notify.NewAlerter(sinks, minSeverity, console), nil
+}
+
+// quotaTracker returns the configured client quotas
+func quotaTracker(cfg config.QuotasConfig) *scanner.QuotaTracker {
+	clients := map[string]scanner.Quota{}
+	for _, client := range cfg.Clients {
+		clients[client.Client] = quotaOf(client.QuotaConfig)
+	}
+	return scanner.NewQuotaTracker(cfg.Period, quotaOf(cfg.Default), clients)
+}
+
+func quotaOf(cfg config.QuotaConfig) scanner.Quota {
+	return scanner.Quota{
+		Scans:    cfg.Scans,
+		Requests: cfg.Requests,
+		Bytes:    cfg.Bytes,
+		WallTime: cfg.WallTime,
+		CPUTime:  cfg.CPUTime,
+	}
+}
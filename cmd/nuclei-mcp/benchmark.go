@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"nuclei-mcp/pkg/benchmark"
+)
+
+// runBenchmark measures the scan throughput of the local scanner service
+// against a built-in test server and prints the report to stdout. Logs go
+// to stderr.
+func runBenchmark(args []string) error {
+	flags := flag.NewFlagSet("benchmark", flag.ContinueOnError)
+	concurrency := flags.String("concurrency", "1,2,4,8", "comma-separated numbers of scans to run at once")
+	rateLimits := flags.String("rate-limits", "0", "comma-separated requests per second of each scan (0 for nuclei's default)")
+	scans := flags.Int("scans", benchmark.DefaultScans, "scans to run for each setting")
+	format := flags.String("format", scanFormatText, "output format: text or json")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("benchmark takes no arguments, got %v", flags.Args())
+	}
+	if *format != scanFormatText && *format != scanFormatJSON {
+		return fmt.Errorf("unsupported format %q, use %s or %s", *format, scanFormatText, scanFormatJSON)
+	}
+	opts := benchmark.Options{Scans: *scans}
+	var err error
+	if opts.Concurrency, err = parseInts(*concurrency); err != nil {
+		return fmt.Errorf("invalid -concurrency: %w", err)
+	}
+	if opts.RateLimits, err = parseInts(*rateLimits); err != nil {
+		return fmt.Errorf("invalid -rate-limits: %w", err)
+	}
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	a, err := newApp(os.Stderr)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	report, err := benchmark.Run(ctx, a.local, opts)
+	if err != nil {
+		return err
+	}
+	return writeBenchmarkReport(os.Stdout, *format, report)
+}
+
+// parseInts parses a comma-separated list of integers
+func parseInts(list string) ([]int, error) {
+	var values []int
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		value, err := strconv.Atoi(item)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// writeBenchmarkReport prints a benchmark report as a table or JSON
+func writeBenchmarkReport(w io.Writer, format string, report benchmark.Report) error {
+	if format == scanFormatJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "CONCURRENCY\tRATE LIMIT\tSCANS\tFAILED\tREQUESTS\tREQ/S\tSCANS/MIN\tP50\tP90\tP99\tMAX")
+	for _, m := range report.Measurements {
+		rateLimit := "default"
+		if m.RateLimit > 0 {
+			rateLimit = strconv.Itoa(m.RateLimit)
+		}
+		fmt.Fprintf(table, "%d\t%s\t%d\t%d\t%d\t%.2f\t%.2f\t%s\t%s\t%s\t%s\n", m.Concurrency, rateLimit, m.Scans, m.Failed, m.Requests,
+			m.RequestsPerSecond, m.ScansPerMinute, m.Latency.P50, m.Latency.P90, m.Latency.P99, m.Latency.Max)
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+	for _, m := range report.Measurements {
+		if m.Error != "" {
+			fmt.Fprintf(w, "\nconcurrency %d, rate limit %d: %s\n", m.Concurrency, m.RateLimit, m.Error)
+		}
+	}
+	if report.Best != nil {
+		_, err := fmt.Fprintf(w, "\nHighest throughput: concurrency %d, rate limit %d\n", report.Best.Concurrency, report.Best.RateLimit)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"nuclei-mcp/pkg/config"
+	"nuclei-mcp/pkg/encryption"
+	"nuclei-mcp/pkg/i18n"
+	"nuclei-mcp/pkg/paths"
+	"nuclei-mcp/pkg/policy"
+	"nuclei-mcp/pkg/templates"
+
+	"gopkg.in/yaml.v3"
+)
+
+const configUsage = `Usage:
+  nuclei-mcp config validate
+  nuclei-mcp config print`
+
+// errInvalidConfig reports that config validate found problems
+var errInvalidConfig = errors.New("invalid configuration")
+
+// runConfig checks or prints the configuration, with environment overrides
+// applied
+func runConfig(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("missing config command\n%s", configUsage)
+	}
+
+	inspection, err := config.Inspect(paths.FindConfigDir())
+	if err != nil {
+		return fmt.Errorf("cannot load config: %w", err)
+	}
+
+	switch args[0] {
+	case "validate":
+		problems := configProblems(inspection)
+		for _, problem := range problems {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", inspection.File, problem)
+		}
+		if len(problems) > 0 {
+			return fmt.Errorf("%w: %d problems", errInvalidConfig, len(problems))
+		}
+		fmt.Printf("%s: OK\n", inspection.File)
+		return nil
+	case "print":
+		out, err := yaml.Marshal(inspection.Settings)
+		if err != nil {
+			return fmt.Errorf("failed to render config: %w", err)
+		}
+		fmt.Printf("# Effective configuration from %s and the environment\n%s", inspection.File, out)
+		return nil
+	}
+	return fmt.Errorf("invalid config command %q\n%s", args[0], configUsage)
+}
+
+// configProblems returns the unknown keys and invalid values of the
+// configuration, checked by the same constructors the server uses
+func configProblems(inspection config.Inspection) []error {
+	var problems []error
+	for _, key := range inspection.UnknownKeys {
+		problems = append(problems, fmt.Errorf("%s: unknown key", key))
+	}
+	problems = append(problems, inspection.Config.Validate()...)
+
+	cfg := inspection.Config
+	if err := scanDefaults(cfg).Validate(); err != nil {
+		problems = append(problems, fmt.Errorf("scanner.defaults: %w", err))
+	}
+	if _, err := policy.NewEgressPolicy(egressOptions(cfg)); err != nil {
+		problems = append(problems, fmt.Errorf("policy.egress: %w", err))
+	}
+	if _, err := i18n.New(cfg.Report.Language); err != nil {
+		problems = append(problems, fmt.Errorf("report.language: %w", err))
+	}
+	if sigCfg := cfg.Nuclei.SignatureVerification; sigCfg.Mode != "" {
+		if _, err := templates.NewVerifier(sigCfg.Mode, sigCfg.PublicKey, sigCfg.Enforce); err != nil {
+			problems = append(problems, fmt.Errorf("nuclei.signature_verification: %w", err))
+		}
+	}
+	if cfg.Encryption.Enabled {
+		if _, err := encryption.Load(cfg.Encryption.KeyEnv, cfg.Encryption.KeyFile); err != nil {
+			problems = append(problems, fmt.Errorf("encryption: %w", err))
+		}
+	}
+	if cfg.Enrichment.Enabled {
+		if _, err := newEnricher(cfg.Enrichment); err != nil {
+			problems = append(problems, fmt.Errorf("enrichment: %w", err))
+		}
+	}
+	return problems
+}
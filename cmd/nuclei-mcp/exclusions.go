@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"nuclei-mcp/pkg/api"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const exclusionsUsage = `Usage:
+  nuclei-mcp exclusions list
+  nuclei-mcp exclusions export [file]
+  nuclei-mcp exclusions import [-replace] <file>`
+
+// runExclusions lists the template exclusion rules and moves them to and
+// from YAML files, so triage decisions can be reviewed and restored
+func runExclusions(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing exclusions command\n%s", exclusionsUsage)
+	}
+
+	var arguments map[string]any
+	var output string
+	switch {
+	case args[0] == "list" && len(args) == 1:
+	case args[0] == "export" && len(args) <= 2:
+		if len(args) == 2 {
+			output = args[1]
+		}
+	case args[0] == "import":
+		flags := flag.NewFlagSet("exclusions import", flag.ContinueOnError)
+		replace := flags.Bool("replace", false, "remove the stored rules missing from the file")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() != 1 {
+			return fmt.Errorf("invalid exclusions command %v\n%s", args, exclusionsUsage)
+		}
+		content, err := os.ReadFile(flags.Arg(0))
+		if err != nil {
+			return fmt.Errorf("failed to read exclusions file: %w", err)
+		}
+		arguments = map[string]any{"content": string(content), "replace": *replace}
+	default:
+		return fmt.Errorf("invalid exclusions command %v\n%s", args, exclusionsUsage)
+	}
+
+	a, err := newApp(os.Stderr)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	ctx := context.Background()
+	request := toolRequest(arguments)
+	var result *mcp.CallToolResult
+	switch args[0] {
+	case "list":
+		result, err = api.HandleListExclusions(ctx, request, a.exclusions)
+	case "export":
+		result, err = api.HandleExportExclusions(ctx, request, a.exclusions)
+	case "import":
+		result, err = api.HandleImportExclusions(ctx, request, a.exclusions)
+	}
+	if err != nil {
+		return err
+	}
+	if output != "" {
+		if err := os.WriteFile(output, []byte(result.Content[0].(mcp.TextContent).Text), 0644); err != nil {
+			return fmt.Errorf("failed to write exclusions file: %w", err)
+		}
+		return nil
+	}
+	printResult(result)
+	return nil
+}
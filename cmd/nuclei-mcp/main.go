@@ -1,84 +1,58 @@
 package main
 
 import (
-	"context"
-	"log"
+	"errors"
+	"flag"
+	"fmt"
 	"os"
-	"os/signal"
-	"syscall"
-
-	"nuclei-mcp/pkg/api"
-	"nuclei-mcp/pkg/cache"
-	"nuclei-mcp/pkg/config"
-	"nuclei-mcp/pkg/logging"
-	"nuclei-mcp/pkg/scanner"
-	"nuclei-mcp/pkg/templates"
-
-	"github.com/mark3labs/mcp-go/server"
 )
 
-// setupSignalHandling configures graceful shutdown
-func setupSignalHandling() chan os.Signal {
-	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
-
-	return sigs
+const usage = `Usage: nuclei-mcp <command> [arguments]
+
+Commands:
+  serve      run the MCP server on stdio (default), or on HTTP for tenants
+  scan       scan targets once and print the results
+  templates  list, show or add custom templates
+  exclusions list, export or import template exclusion rules
+  config     validate or print the effective configuration
+  worker     run scan jobs sent by a coordinator
+  benchmark  measure scan throughput against a built-in test server
+
+Run "nuclei-mcp scan -h" for scan flags.`
+
+// commands maps subcommand names to their entry points
+var commands = map[string]func(args []string) error{
+	"serve":      runServe,
+	"scan":       runScan,
+	"templates":  runTemplates,
+	"exclusions": runExclusions,
+	"config":     runConfig,
+	"worker":     runWorker,
+	"benchmark":  runBenchmark,
 }
 
 func main() {
-	// Load configuration
-	cfg, err := config.LoadConfig(".")
-	if err != nil {
-		log.Fatalf("cannot load config: %v", err)
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
 	}
 
-	// Create console logger
-	consoleLogger, err := logging.NewConsoleLogger(cfg.Logging.Path)
-	if err != nil {
-		log.Fatalf("Failed to create console logger: %v", err)
+	switch name {
+	case "help", "-h", "-help", "--help":
+		fmt.Println(usage)
+		return
 	}
-	defer consoleLogger.Close()
-
-	// Create result cache
-	resultCache := cache.NewResultCache(cfg.Cache.Expiry, log.New(os.Stdout, "[Cache] ", log.LstdFlags))
 
-	// Create scanner service with console logger
-	scannerService := scanner.NewScannerService(resultCache, consoleLogger)
-
-	// Log startup information
-	consoleLogger.Log("Starting MCP inspector...")
-	consoleLogger.Log("Proxy server listening on port 3000")
-	consoleLogger.Log("🔍 MCP Inspector is up and running at http://localhost:5173 🚀")
-
-	// Create Template Manager
-	templateDir := "nuclei-templates"
-	tm, err := templates.NewTemplateManager(templateDir)
-	if err != nil {
-		log.Fatalf("Failed to create template manager: %v", err)
+	command, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s\n", name, usage)
+		os.Exit(2)
 	}
-
-	// Create MCP server
-	mcpServer := api.NewNucleiMCPServer(scannerService, log.New(os.Stdout, "[MCP] ", log.LstdFlags), tm)
-
-	// Set up signal handling for graceful shutdown
-	sigChan := setupSignalHandling()
-
-	// Create context for graceful shutdown
-	_, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	// Start server using stdio transport
-	go func() {
-		if err := server.ServeStdio(mcpServer); err != nil {
-			consoleLogger.Log("Failed to start MCP server: %v", err)
-			cancel()
+	if err := command(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
 		}
-	}()
-
-	// Wait for shutdown signal
-	<-sigChan
-	consoleLogger.Log("Shutting down...")
-
-	// Cancel context to stop server
-	cancel()
+		fmt.Fprintf(os.Stderr, "nuclei-mcp %s: %v\n", name, err)
+		os.Exit(1)
+	}
 }
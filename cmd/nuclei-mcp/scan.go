@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/engine"
+	"nuclei-mcp/pkg/report"
+	"nuclei-mcp/pkg/scanner"
+)
+
+// Output formats of the scan command
+const (
+	scanFormatJSON  = "json"
+	scanFormatSARIF = "sarif"
+	scanFormatText  = "text"
+)
+
+// scanOutput is the JSON record of one scanned target
+type scanOutput struct {
+	cache.ScanResult
+	Host      string        `json:"host"`
+	Error     string        `json:"error,omitempty"`
+	ErrorCode api.ErrorCode `json:"error_code,omitempty"`
+}
+
+// runScan scans the given targets once with the configured scanner service
+// and scan profile, without an MCP client, and prints the results to stdout.
+// Logs go to stderr so stdout carries only the results.
+func runScan(args []string) error {
+	flags := flag.NewFlagSet("scan", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: nuclei-mcp scan [flags] target...")
+		flags.PrintDefaults()
+	}
+	severity := flags.String("severity", "", "minimum severity (default from scanner.defaults)")
+	protocols := flags.String("protocols", "", "comma-separated protocols: "+strings.Join(scanner.SupportedProtocols(), ", "))
+	tags := flags.String("tags", "", "comma-separated template tags")
+	templateIDs := flags.String("template-ids", "", "comma-separated template IDs")
+	rateLimit := flags.Int("rate-limit", 0, "maximum requests per second")
+	passive := flags.Bool("passive", false, "run only templates that send no attack payloads")
+	format := flags.String("format", scanFormatJSON, "output format: json, sarif or text")
+	priority := flags.String("priority", scanner.PriorityBackground.String(), "scan queue priority: interactive or background")
+	addressFamily := flags.String("address-family", "", "IP versions HTTP templates connect over: v4, v6 or dual (default: the resolver's choice)")
+	sni := flags.String("sni", "", "TLS server name to send instead of the target's host")
+	hostHeader := flags.String("host-header", "", "Host header HTTP templates send while connecting to the target, e.g. to scan a site on its origin IP")
+	var labels []string
+	flags.Func("label", "key=value label stored with the results, e.g. environment=staging (repeatable)", func(value string) error {
+		labels = append(labels, value)
+		return nil
+	})
+	var variables []string
+	flags.Func("var", "name=value template variable; secret://name references a configured secret, e.g. password=secret://db-admin (repeatable)", func(value string) error {
+		variables = append(variables, value)
+		return nil
+	})
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	targets := flags.Args()
+	if len(targets) == 0 {
+		flags.Usage()
+		return fmt.Errorf("no targets given")
+	}
+	switch *format {
+	case scanFormatJSON, scanFormatSARIF, scanFormatText:
+	default:
+		return fmt.Errorf("unsupported format %q, use %s, %s or %s", *format, scanFormatJSON, scanFormatSARIF, scanFormatText)
+	}
+
+	a, err := newApp(os.Stderr)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	// Resolve the flags against the scan profile like the scan tools do
+	arguments := map[string]any{
+		"severity":   *severity,
+		"protocols":  *protocols,
+		"tags":       *tags,
+		"rate_limit": float64(*rateLimit),
+	}
+	resolvedSeverity, resolvedProtocols, scanOpts := a.scanDefaults.Resolve(arguments)
+	resolvedProtocols, err = scanner.NormalizeProtocols(resolvedProtocols)
+	if err != nil {
+		return fmt.Errorf("[%s] %w", api.ErrorCodeOf(err), err)
+	}
+	scanPriority, err := scanner.ParsePriority(*priority)
+	if err != nil {
+		return fmt.Errorf("[%s] %w", api.ErrorCodeOf(err), err)
+	}
+	scanOpts = append(scanOpts, scanner.WithPriority(scanPriority))
+	family, err := scanner.ParseAddressFamily(*addressFamily)
+	if err != nil {
+		return fmt.Errorf("[%s] %w", api.ErrorCodeOf(err), err)
+	}
+	scanOpts = append(scanOpts, scanner.WithAddressFamily(family))
+	if err := scanner.ValidateHostOverrides(*sni, *hostHeader); err != nil {
+		return fmt.Errorf("[%s] %w", api.ErrorCodeOf(err), err)
+	}
+	scanOpts = append(scanOpts, scanner.WithSNI(*sni), scanner.WithHostHeader(*hostHeader))
+	scanLabels, err := scanner.ParseLabels(labels)
+	if err != nil {
+		return fmt.Errorf("[%s] %w", api.ErrorCodeOf(err), err)
+	}
+	scanOpts = append(scanOpts, scanner.WithLabels(scanLabels))
+	scanVariables, err := scanner.ParseVariables(variables)
+	if err != nil {
+		return fmt.Errorf("[%s] %w", api.ErrorCodeOf(err), err)
+	}
+	scanOpts = append(scanOpts, scanner.WithVariables(scanVariables))
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "passive" {
+			scanOpts = append(scanOpts, scanner.WithPassive(*passive))
+		}
+	})
+	var ids []string
+	for _, id := range strings.Split(*templateIDs, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if a.coordinator != nil {
+		a.coordinator.CheckHealth(ctx)
+	}
+
+	multi := scanner.NewMultiScanner(a.scanner, a.concurrency())
+	hosts := multi.Scan(ctx, targets, resolvedSeverity, resolvedProtocols, ids, scanOpts...)
+
+	var outputs []scanOutput
+	var results []cache.ScanResult
+	failed := 0
+	for _, host := range hosts {
+		for _, target := range host.Targets {
+			output := scanOutput{ScanResult: target.Result, Host: host.Host}
+			output.Target = target.Target
+			if target.Err != nil {
+				failed++
+				output.Error = target.Err.Error()
+				output.ErrorCode = api.ErrorCodeOf(target.Err)
+				fmt.Fprintf(os.Stderr, "scan of %s failed [%s]: %v\n", target.Target, output.ErrorCode, target.Err)
+			} else {
+				results = append(results, target.Result)
+			}
+			outputs = append(outputs, output)
+		}
+	}
+
+	version := engine.NewUpdater("", a.cfg.Nuclei.TemplatesVersion).Info().EngineVersion
+	if err := writeScanOutput(os.Stdout, *format, outputs, results, version); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d targets failed", failed, len(outputs))
+	}
+	return nil
+}
+
+// writeScanOutput prints the scan results in the requested format
+func writeScanOutput(w io.Writer, format string, outputs []scanOutput, results []cache.ScanResult, engineVersion string) error {
+	switch format {
+	case scanFormatSARIF:
+		sarif, err := report.SARIF(results, engineVersion)
+		if err != nil {
+			return fmt.Errorf("failed to render SARIF: %w", err)
+		}
+		_, err = fmt.Fprintln(w, string(sarif))
+		return err
+	case scanFormatText:
+		_, err := fmt.Fprint(w, report.NewGenerator(nil).Markdown(results, time.Now()))
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		Targets []scanOutput `json:"targets"`
+	}{Targets: outputs})
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/assets"
+	"nuclei-mcp/pkg/bridge"
+	"nuclei-mcp/pkg/engine"
+	"nuclei-mcp/pkg/i18n"
+	"nuclei-mcp/pkg/logging"
+	"nuclei-mcp/pkg/scanner"
+	"nuclei-mcp/pkg/schedule"
+	"nuclei-mcp/pkg/tracker"
+	"nuclei-mcp/pkg/workspace"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// setupSignalHandling configures graceful shutdown
+func setupSignalHandling() chan os.Signal {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+
+	return sigs
+}
+
+// runServe starts the MCP server on stdio, or on HTTP for the configured
+// tenants, and blocks until a shutdown signal
+func runServe(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	listen := flags.String("listen", "", "serve tenants over streamable HTTP on this address (default from server.listen)")
+	console := flags.Bool("console", false, "mirror logs to stdout when serving over HTTP (default from logging.console)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("serve takes no arguments, got %v", flags.Args())
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if *listen == "" {
+		*listen = cfg.Server.Listen
+	}
+
+	// Over stdio, stdout carries the MCP stream: logs go to stderr, and
+	// stray writes to stdout are sent there too
+	stdio := *listen == ""
+	if stdio && *console {
+		return fmt.Errorf("-console requires serving over HTTP (-listen or server.listen)")
+	}
+	stdout := os.Stdout
+	if stdio {
+		stdout = logging.ReserveStdout()
+	}
+
+	a, err := newAppWithConfig(cfg, logging.ServerOutput(stdio, *console || cfg.Logging.Console))
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+	if stdio && cfg.Logging.Console {
+		a.console.Log("logging.console is ignored over stdio; logs go to stderr")
+	}
+
+	// Log startup information
+	a.console.Log("Starting MCP inspector...")
+	a.console.Log("Proxy server listening on port 3000")
+	a.console.Log("🔍 MCP Inspector is up and running at http://localhost:5173 🚀")
+
+	// Schedule the configured and restored scans, warming the cache for key
+	// targets, outside the blackouts
+	var blackouts *schedule.Calendar
+	if cfg.Schedules.BlackoutCalendar != "" {
+		if blackouts, err = schedule.LoadCalendar(cfg.Schedules.BlackoutCalendar); err != nil {
+			return fmt.Errorf("invalid schedules configuration: %w", err)
+		}
+	}
+	scheduler := schedule.NewScheduler(a.scanner, a.console, a.scheduledScans(a.scheduleDefinitions()),
+		schedule.WithInterval(cfg.Schedules.Interval),
+		schedule.WithCacheWarming(cfg.Cache.Expiry, cfg.Schedules.WarmMargin),
+		schedule.WithBlackouts(blackouts),
+	)
+
+	// Create workspace for backup and restore
+	ws := workspace.NewWorkspace(a.resultCache, a.templates,
+		workspace.WithEncryptionKey(a.encryption), workspace.WithExportDir(a.cfg.Server.ExportDir),
+		workspace.WithExclusions(a.exclusions), workspace.WithFindingTracker(a.tracker),
+		workspace.WithSchedules(scheduleBackup{app: a, scheduler: scheduler}))
+
+	// Create engine updater for the pinned templates release
+	updater := engine.NewUpdater("", cfg.Nuclei.TemplatesVersion)
+
+	// Localize reports and summaries
+	localizer, err := i18n.New(cfg.Report.Language)
+	if err != nil {
+		return fmt.Errorf("invalid report configuration: %w", err)
+	}
+
+	// Serve the tenants over HTTP instead of stdio
+	if !stdio {
+		return a.serveTenants(*listen, localizer)
+	}
+
+	// Bridge stdio so the server can request roots, sampling and elicitation
+	// from the client
+	clientBridge := bridge.NewBridge(os.Stdin, stdout)
+
+	// Answer argument completions from the templates and scanned targets
+	completer := api.NewCompleter(a.scanner, a.templates, a.templateIndex, append(append(a.bundleDirs, a.cfg.Nuclei.TemplatesDir), a.sourceDirs...)...)
+	clientBridge.HandleRequest("completion/complete", "completions", func(ctx context.Context, params json.RawMessage) (any, error) {
+		var request mcp.CompleteRequest
+		if err := json.Unmarshal(params, &request.Params); err != nil {
+			return nil, fmt.Errorf("invalid completion request: %w", err)
+		}
+		return api.HandleComplete(ctx, request, completer)
+	})
+
+	// Create MCP server
+	serverOpts := []api.ServerOption{
+		api.WithWorkspace(ws),
+		api.WithEngineUpdater(updater),
+		api.WithUpdateCheck(cfg.Nuclei.UpdateCheck),
+		api.WithTemplatePolicy(a.templatePolicy),
+		api.WithTemplateVerifier(a.verifier),
+		api.WithScopeRoots(clientBridge.WaitForRoots),
+		api.WithSampler(clientBridge),
+		api.WithResultPager(api.NewResultPager(cfg.Server.PageSize, api.DefaultContinuationTTL)),
+		api.WithLocalizer(localizer),
+		api.WithScanConcurrency(a.concurrency()),
+		api.WithExclusionStore(a.exclusions),
+		api.WithScanDefaults(a.scanDefaults),
+		api.WithEncryptionKey(a.encryption),
+		api.WithFindingTracker(a.tracker, cfg.Findings.RetestAfter),
+		api.WithWordlists(a.wordlists),
+		api.WithAssetRegistry(a.assets, assets.NewCollector(assets.WithCTLogURL(cfg.Assets.CTLogURL), assets.WithTimeout(cfg.Assets.Timeout), assets.WithTargetPolicy(a.egress, a.selfGuard))),
+		api.WithTemplateDirs(append(append(a.bundleDirs, a.cfg.Nuclei.TemplatesDir), a.sourceDirs...)...),
+		api.WithTemplateIndex(a.templateIndex),
+		api.WithMaintenance(a.maintenance),
+	}
+	if cfg.Server.Elicitation {
+		serverOpts = append(serverOpts, api.WithElicitor(clientBridge))
+	}
+	if a.coordinator != nil {
+		serverOpts = append(serverOpts, api.WithWorkerPool(a.coordinator))
+	}
+	if a.quotas != nil {
+		serverOpts = append(serverOpts, api.WithQuotaTracker(a.quotas))
+	}
+	if a.quarantine != nil {
+		serverOpts = append(serverOpts, api.WithTemplateQuarantine(a.quarantine))
+	}
+	if blackouts != nil {
+		serverOpts = append(serverOpts, api.WithBlackoutCalendar(blackouts))
+	}
+	mcpServer := api.NewNucleiMCPServer(a.scanner, log.New(a.output, "[MCP] ", log.LstdFlags), a.templates, serverOpts...)
+
+	// Set up signal handling for graceful shutdown
+	sigChan := setupSignalHandling()
+
+	// Create context for graceful shutdown
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Warm up the scan engine in the background so startup is not delayed
+	if preloader, ok := a.scanner.(scanner.Preloader); ok && cfg.Scanner.Preload {
+		go func() {
+			if err := preloader.Preload(ctx); err != nil {
+				a.console.Log("Scan engine preload failed: %v", err)
+			}
+		}()
+	}
+
+	// Track the health of the scan workers
+	if a.coordinator != nil {
+		go a.coordinator.Run(ctx)
+	}
+
+	// Retest remediated findings when their fix window has passed
+	go tracker.NewRetester(a.tracker, a.scanner, a.console, cfg.Findings.RetestInterval).Run(ctx)
+
+	// Run the scheduled scans
+	go scheduler.Run(ctx)
+
+	// Start server using stdio transport
+	clientBridge.Start(ctx)
+	stdioServer := server.NewStdioServer(mcpServer)
+	stdioServer.SetErrorLogger(log.New(os.Stderr, "", log.LstdFlags))
+	go func() {
+		if err := stdioServer.Listen(ctx, clientBridge.Reader(), clientBridge.Writer()); err != nil {
+			a.console.Log("Failed to start MCP server: %v", err)
+			cancel()
+		}
+	}()
+
+	// Wait for shutdown signal
+	select {
+	case <-sigChan:
+	case <-ctx.Done():
+	}
+	a.console.Log("Shutting down...")
+
+	// Cancel context to stop server
+	cancel()
+	return nil
+}
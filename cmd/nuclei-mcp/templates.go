@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"nuclei-mcp/pkg/api"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const templatesUsage = `Usage:
+  nuclei-mcp templates list [query]
+  nuclei-mcp templates get <name>
+  nuclei-mcp templates add <name> <file>`
+
+// runTemplates manages the custom templates served by the list_templates,
+// get_template and add_template tools
+func runTemplates(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing templates command\n%s", templatesUsage)
+	}
+
+	var arguments map[string]any
+	switch {
+	case args[0] == "list" && len(args) == 1:
+	case args[0] == "list" && len(args) == 2:
+		arguments = map[string]any{"query": args[1]}
+	case args[0] == "get" && len(args) == 2:
+		arguments = map[string]any{"name": args[1]}
+	case args[0] == "add" && len(args) == 3:
+		content, err := os.ReadFile(args[2])
+		if err != nil {
+			return fmt.Errorf("failed to read template: %w", err)
+		}
+		arguments = map[string]any{"name": args[1], "content": string(content)}
+	default:
+		return fmt.Errorf("invalid templates command %v\n%s", args, templatesUsage)
+	}
+
+	a, err := newApp(os.Stderr)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	ctx := context.Background()
+	request := toolRequest(arguments)
+	var result *mcp.CallToolResult
+	switch args[0] {
+	case "list":
+		result, err = api.HandleListTemplates(ctx, request, a.templates)
+	case "get":
+		result, err = api.HandleGetTemplate(ctx, request, a.templates)
+	case "add":
+		result, err = api.HandleAddTemplate(ctx, request, a.templates, a.templatePolicy, a.verifier, a.wordlists)
+	}
+	if err != nil {
+		return err
+	}
+	printResult(result)
+	return nil
+}
+
+// toolRequest wraps command line arguments as a tool call
+func toolRequest(arguments map[string]any) mcp.CallToolRequest {
+	return mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: arguments}}
+}
+
+// printResult writes the text content of a tool result to stdout
+func printResult(result *mcp.CallToolResult) {
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			fmt.Println(text.Text)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/config"
+	"nuclei-mcp/pkg/exclusions"
+	"nuclei-mcp/pkg/i18n"
+	"nuclei-mcp/pkg/paths"
+	"nuclei-mcp/pkg/scanner"
+	"nuclei-mcp/pkg/templates"
+	"nuclei-mcp/pkg/tenant"
+	"nuclei-mcp/pkg/wordlists"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// serveTenants serves MCP over streamable HTTP on listen until a shutdown
+// signal. Each tenant authenticates with its own token and gets a server of
+// its own, so tenants never share results, templates or exclusion rules.
+func (a *app) serveTenants(listen string, localizer *i18n.Localizer) error {
+	if len(a.cfg.Server.Tenants) == 0 {
+		return fmt.Errorf("serving over HTTP requires server.tenants")
+	}
+
+	var tenants []tenant.Tenant
+	for _, tenantCfg := range a.cfg.Server.Tenants {
+		handler, err := a.tenantHandler(tenantCfg, localizer)
+		if err != nil {
+			return fmt.Errorf("failed to set up tenant %s: %w", tenantCfg.Name, err)
+		}
+		tenants = append(tenants, tenant.Tenant{Name: tenantCfg.Name, Token: tenantCfg.Token, Handler: handler})
+	}
+	router, err := tenant.NewRouter(tenants)
+	if err != nil {
+		return fmt.Errorf("invalid tenant configuration: %w", err)
+	}
+	srv := &http.Server{Addr: listen, Handler: router, ReadHeaderTimeout: 10 * time.Second}
+
+	// Set up signal handling for graceful shutdown
+	sigChan := setupSignalHandling()
+
+	errChan := make(chan error, 1)
+	go func() {
+		a.console.Log("MCP server listening on %s for %d tenants", listen, len(tenants))
+		errChan <- srv.ListenAndServe()
+	}()
+
+	// Wait for shutdown signal
+	select {
+	case <-sigChan:
+	case err := <-errChan:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("MCP server stopped: %w", err)
+		}
+	}
+	a.console.Log("Shutting down...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), workerShutdownTimeout)
+	defer cancel()
+	return srv.Shutdown(ctx)
+}
+
+// tenantHandler builds the MCP server of a tenant over its own result
+// cache, custom templates, wordlists and exclusion rules in its workspace, limited to
+// its rate limit and scope. Tools that write to server paths or update the
+// shared engine are not offered to tenants.
+func (a *app) tenantHandler(tenantCfg config.TenantConfig, localizer *i18n.Localizer) (http.Handler, error) {
+	workspace := tenantCfg.Workspace
+	if workspace == "" {
+		workspace = filepath.Join(paths.TenantsDir(), tenantCfg.Name)
+	}
+	customDir := filepath.Join(workspace, templateDir)
+
+	var sources []templates.Source
+	for _, dir := range a.cfg.Nuclei.TemplateDirs {
+		sources = append(sources, templates.Source{Name: dir.Name, Dir: dir.Path})
+	}
+	tm, err := templates.NewTemplateManager(customDir, templates.WithSources(sources...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create template manager: %w", err)
+	}
+
+	excl, err := exclusions.Open(filepath.Join(workspace, "exclusions.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load exclusions: %w", err)
+	}
+
+	resultCache := cache.NewResultCache(a.cfg.Cache.Expiry, log.New(a.output, "[Cache "+tenantCfg.Name+"] ", log.LstdFlags))
+	a.tenantCaches = append(a.tenantCaches, resultCache)
+	service := scanner.NewScannerService(resultCache, a.console,
+		append(a.serviceOptions(customDir, tm, excl), scanner.WithMaxRateLimit(tenantCfg.RateLimit))...)
+
+	serverOpts := []api.ServerOption{
+		api.WithTemplatePolicy(a.templatePolicy),
+		api.WithTemplateVerifier(a.verifier),
+		api.WithScope(tenantCfg.Scope),
+		api.WithResultPager(api.NewResultPager(a.cfg.Server.PageSize, api.DefaultContinuationTTL)),
+		api.WithLocalizer(localizer),
+		api.WithScanConcurrency(a.concurrency()),
+		api.WithExclusionStore(excl),
+		api.WithWordlists(wordlists.New(filepath.Join(workspace, "wordlists"), a.cfg.Wordlists.MaxEntries)),
+		api.WithScanDefaults(a.scanDefaults),
+		api.WithEncryptionKey(a.encryption),
+		api.WithTemplateDirs(append(append(a.bundleDirs, customDir), a.sourceDirs...)...),
+		api.WithTemplateIndex(a.templateIndex),
+	}
+	mcpServer := api.NewNucleiMCPServer(service, log.New(a.output, "[MCP "+tenantCfg.Name+"] ", log.LstdFlags), tm, serverOpts...)
+	a.console.Log("Tenant %s: workspace %s, rate limit %d, scope %v", tenantCfg.Name, workspace, tenantCfg.RateLimit, tenantCfg.Scope)
+	return server.NewStreamableHTTPServer(mcpServer), nil
+}
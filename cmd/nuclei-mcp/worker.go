@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"nuclei-mcp/pkg/distributed"
+)
+
+// workerShutdownTimeout is how long running jobs get to finish on shutdown
+const workerShutdownTimeout = 30 * time.Second
+
+// runWorker serves scan jobs from a coordinator over HTTP, or HTTPS with
+// distributed.tls_cert and tls_key, until a shutdown signal. Jobs run on the
+// local scanner service with this instance's configuration.
+func runWorker(args []string) error {
+	flags := flag.NewFlagSet("worker", flag.ContinueOnError)
+	listen := flags.String("listen", "", "address to serve jobs on (default from distributed.listen)")
+	name := flags.String("name", "", "worker name reported to the coordinator (default the hostname)")
+	capacity := flags.Int("capacity", 0, "jobs run at once (default from distributed.capacity)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("worker takes no arguments, got %v", flags.Args())
+	}
+
+	a, err := newApp(os.Stdout)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+	cfg := a.cfg
+
+	if *listen == "" {
+		*listen = cfg.Distributed.Listen
+	}
+	if *name == "" {
+		if *name, err = os.Hostname(); err != nil {
+			*name = "worker"
+		}
+	}
+	if *capacity == 0 {
+		*capacity = cfg.Distributed.Capacity
+	}
+	if (cfg.Distributed.TLSCert == "") != (cfg.Distributed.TLSKey == "") {
+		return fmt.Errorf("invalid distributed configuration: set both tls_cert and tls_key to serve jobs over HTTPS")
+	}
+	useTLS := cfg.Distributed.TLSCert != ""
+
+	handler, err := distributed.NewWorkerHandler(a.local, a.console, cfg.Distributed.Token,
+		distributed.WithWorkerName(*name),
+		distributed.WithCapacity(*capacity),
+	)
+	if err != nil {
+		return fmt.Errorf("invalid distributed configuration: %w", err)
+	}
+	srv := &http.Server{Addr: *listen, Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+
+	// Set up signal handling for graceful shutdown
+	sigChan := setupSignalHandling()
+
+	errChan := make(chan error, 1)
+	go func() {
+		if !useTLS {
+			a.console.Log("Scan worker %s listening on %s (capacity %d) over plain HTTP; coordinators on other hosts need HTTPS, set distributed.tls_cert and tls_key", *name, *listen, handler.Status().Capacity)
+			errChan <- srv.ListenAndServe()
+			return
+		}
+		a.console.Log("Scan worker %s listening on %s over HTTPS (capacity %d)", *name, *listen, handler.Status().Capacity)
+		errChan <- srv.ListenAndServeTLS(cfg.Distributed.TLSCert, cfg.Distributed.TLSKey)
+	}()
+
+	// Wait for shutdown signal
+	select {
+	case <-sigChan:
+	case err := <-errChan:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("scan worker stopped: %w", err)
+		}
+	}
+	a.console.Log("Shutting down...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), workerShutdownTimeout)
+	defer cancel()
+	return srv.Shutdown(ctx)
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"nuclei-mcp/pkg/assets"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// HandleTargetContext collects the recon context of a target, stores it in
+// the asset registry and returns it
+func HandleTargetContext(ctx context.Context, request mcp.CallToolRequest, registry *assets.Registry, collector *assets.Collector) (*mcp.CallToolResult, error) {
+	argMap, ok := request.Params.Arguments.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid arguments format")
+	}
+
+	target, _ := argMap["target"].(string)
+	if strings.TrimSpace(target) == "" {
+		return nil, fmt.Errorf("invalid or missing target parameter")
+	}
+	ct, _ := argMap["certificate_transparency"].(bool)
+
+	asset, err := registry.Put(collector.Collect(ctx, target, ct))
+	if err != nil {
+		return nil, fmt.Errorf("failed to store target context: %w", err)
+	}
+
+	assetJSON, err := json.Marshal(asset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal target context: %w", err)
+	}
+
+	return mcp.NewToolResultText(string(assetJSON)), nil
+}
+
+// jsReconTags are the template tags run against each script: token and key
+// exposures
+var jsReconTags = []string{"token", "keys", "secret"}
+
+// HandleJSRecon analyses the scripts a target serves for the endpoints and
+// secrets they reveal, runs the token exposure templates against each of
+// them, and stores the analysis in the target's asset
+func HandleJSRecon(ctx context.Context, request mcp.CallToolRequest, registry *assets.Registry, collector *assets.Collector, multiScanner *scanner.MultiScanner, defaults ScanDefaults) (*mcp.CallToolResult, error) {
+	argMap, ok := request.Params.Arguments.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid arguments format")
+	}
+
+	target, _ := argMap["target"].(string)
+	if strings.TrimSpace(target) == "" {
+		return nil, fmt.Errorf("invalid or missing target parameter")
+	}
+
+	js, err := collector.JavaScript(ctx, target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyse JavaScript: %w", err)
+	}
+
+	type jsFinding struct {
+		Name       string   `json:"name"`
+		TemplateID string   `json:"template_id"`
+		Severity   string   `json:"severity"`
+		File       string   `json:"file"`
+		Extracted  []string `json:"extracted,omitempty"`
+		Resource   string   `json:"resource"`
+	}
+	response := struct {
+		Host       string             `json:"host"`
+		JavaScript *assets.JavaScript `json:"javascript"`
+		Findings   []jsFinding        `json:"findings"`
+		ScanErrors map[string]string  `json:"scan_errors,omitempty"`
+	}{JavaScript: js, Findings: []jsFinding{}}
+
+	if len(js.Files) > 0 {
+		defaults.Tags = jsReconTags
+		severity, _, scanOpts := defaults.Resolve(argMap)
+		scanOpts = append(scanOpts, scanner.WithClient(clientName(ctx)))
+		for _, host := range multiScanner.Scan(ctx, js.Files, severity, "http", nil, scanOpts...) {
+			for _, file := range host.Targets {
+				if file.Err != nil {
+					if response.ScanErrors == nil {
+						response.ScanErrors = map[string]string{}
+					}
+					response.ScanErrors[file.Target] = file.Err.Error()
+					continue
+				}
+				for _, finding := range file.Result.Findings {
+					response.Findings = append(response.Findings, jsFinding{
+						Name:       finding.Info.Name,
+						TemplateID: finding.TemplateID,
+						Severity:   finding.Info.SeverityHolder.Severity.String(),
+						File:       file.Target,
+						Extracted:  finding.ExtractedResults,
+						Resource:   cache.FindingURI(finding),
+					})
+				}
+			}
+		}
+	}
+
+	asset, ok := registry.Get(scanner.TargetHost(target))
+	if !ok {
+		asset = assets.Asset{Host: scanner.TargetHost(target)}
+	}
+	asset.JavaScript, asset.UpdatedAt = js, time.Now().UTC()
+	if asset, err = registry.Put(asset); err != nil {
+		return nil, fmt.Errorf("failed to store JavaScript analysis: %w", err)
+	}
+	response.Host = asset.Host
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JavaScript analysis: %w", err)
+	}
+	return mcp.NewToolResultText(string(responseJSON)), nil
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"nuclei-mcp/pkg/benchmark"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// HandleBenchmark measures the scan throughput and latency of service at
+// the requested concurrency levels and rate limits
+func HandleBenchmark(ctx context.Context, request mcp.CallToolRequest, service scanner.ScannerService) (*mcp.CallToolResult, error) {
+	argMap, _ := request.Params.Arguments.(map[string]any)
+
+	concurrency, err := intList(argMap["concurrency"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid concurrency parameter: %w", err)
+	}
+	rateLimits, err := intList(argMap["rate_limits"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid rate_limits parameter: %w", err)
+	}
+	scans, _ := argMap["scans"].(float64)
+
+	report, err := benchmark.Run(ctx, service, benchmark.Options{
+		Concurrency: concurrency,
+		RateLimits:  rateLimits,
+		Scans:       int(scans),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("benchmark failed: %w", err)
+	}
+
+	reportJSON, err := json.Marshal(report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal report: %w", err)
+	}
+
+	return mcp.NewToolResultText(string(reportJSON)), nil
+}
+
+// intList converts an array tool argument of whole numbers into ints
+func intList(raw any) ([]int, error) {
+	items, _ := raw.([]any)
+	list := make([]int, 0, len(items))
+	for _, item := range items {
+		value, ok := item.(float64)
+		if !ok || value != math.Trunc(value) {
+			return nil, fmt.Errorf("expected whole numbers, got %v", item)
+		}
+		list = append(list, int(value))
+	}
+	return list, nil
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"nuclei-mcp/pkg/schedule"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// WithBlackoutCalendar enables the next_scan_window tool querying the
+// blackout calendar suspending scheduled scans
+func WithBlackoutCalendar(calendar *schedule.Calendar) ServerOption {
+	return func(o *serverOptions) {
+		o.blackouts = calendar
+	}
+}
+
+// HandleNextScanWindow reports the blackout in force, the next window in
+// which scheduled scans may run and the upcoming blackouts
+func HandleNextScanWindow(_ context.Context, request mcp.CallToolRequest, calendar *schedule.Calendar) (*mcp.CallToolResult, error) {
+	argMap, _ := request.Params.Arguments.(map[string]any)
+
+	at := time.Now()
+	if raw, _ := argMap["after"].(string); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid after: %s is not an RFC 3339 time", raw)
+		}
+		at = parsed
+	}
+	var length time.Duration
+	if raw, _ := argMap["duration"].(string); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("invalid duration: %s", raw)
+		}
+		length = parsed
+	}
+
+	window, err := calendar.Window(at, length)
+	if err != nil {
+		return nil, err
+	}
+	windowJSON, err := json.Marshal(window)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal scan window: %w", err)
+	}
+
+	return mcp.NewToolResultText(string(windowJSON)), nil
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Sizes of the lists of the template catalog
+const (
+	catalogTopTags    = 50
+	catalogNewest     = 20
+	catalogRecentCVEs = 20
+)
+
+// TemplateCatalog summarizes the templates scans load, so agents can judge
+// coverage before choosing scan filters
+type TemplateCatalog struct {
+	// Templates counts templates by ID; a template in several directories
+	// is counted once, from the first holding it
+	Templates int `json:"templates"`
+	// BySeverity and ByProtocol count the templates declaring one; the
+	// protocols are those nuclei_scan filters by
+	BySeverity map[string]int `json:"by_severity"`
+	ByProtocol map[string]int `json:"by_protocol"`
+	// Tags is the number of distinct tags, of which TopTags are the most
+	// used
+	Tags    int           `json:"tags"`
+	TopTags []catalogTag  `json:"top_tags"`
+	CVEs    int           `json:"cves"`
+	Newest  []catalogItem `json:"newest"`
+	// RecentCVEs are the templates of the latest CVEs, by CVE year and
+	// number
+	RecentCVEs  []catalogItem `json:"recent_cves"`
+	Directories []string      `json:"directories"`
+}
+
+type catalogTag struct {
+	Tag       string `json:"tag"`
+	Templates int    `json:"templates"`
+}
+
+type catalogItem struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name,omitempty"`
+	CVE      string    `json:"cve,omitempty"`
+	Severity string    `json:"severity,omitempty"`
+	Protocol string    `json:"protocol,omitempty"`
+	Path     string    `json:"path"`
+	ModTime  time.Time `json:"mod_time"`
+}
+
+// templateCatalog summarizes the templates index reads under dirs
+func templateCatalog(index *scanner.TemplateIndex, dirs []string) TemplateCatalog {
+	catalog := TemplateCatalog{
+		BySeverity:  map[string]int{},
+		ByProtocol:  map[string]int{},
+		TopTags:     []catalogTag{},
+		Newest:      []catalogItem{},
+		RecentCVEs:  []catalogItem{},
+		Directories: dirs,
+	}
+	if catalog.Directories == nil {
+		catalog.Directories = []string{}
+	}
+
+	tags := map[string]int{}
+	seen := map[string]bool{}
+	var items, cves []catalogItem
+	for _, template := range index.Templates(dirs...) {
+		if seen[template.ID] {
+			continue
+		}
+		seen[template.ID] = true
+		catalog.Templates++
+		if template.Severity != "" {
+			catalog.BySeverity[template.Severity]++
+		}
+		if template.Protocol != "" {
+			catalog.ByProtocol[template.Protocol]++
+		}
+		templateTags := map[string]bool{}
+		for _, tag := range template.Tags {
+			if tag = strings.ToLower(tag); !templateTags[tag] {
+				templateTags[tag] = true
+				tags[tag]++
+			}
+		}
+
+		item := catalogItem{
+			ID:       template.ID,
+			Name:     template.Name,
+			Severity: template.Severity,
+			Protocol: template.Protocol,
+			Path:     template.Path,
+			ModTime:  template.ModTime,
+		}
+		items = append(items, item)
+		if cve := templateCVE(template); cve != "" {
+			catalog.CVEs++
+			item.CVE = cve
+			cves = append(cves, item)
+		}
+	}
+
+	catalog.Tags = len(tags)
+	for tag, count := range tags {
+		catalog.TopTags = append(catalog.TopTags, catalogTag{Tag: tag, Templates: count})
+	}
+	sort.Slice(catalog.TopTags, func(i, j int) bool {
+		if catalog.TopTags[i].Templates != catalog.TopTags[j].Templates {
+			return catalog.TopTags[i].Templates > catalog.TopTags[j].Templates
+		}
+		return catalog.TopTags[i].Tag < catalog.TopTags[j].Tag
+	})
+	if len(catalog.TopTags) > catalogTopTags {
+		catalog.TopTags = catalog.TopTags[:catalogTopTags]
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].ModTime.Equal(items[j].ModTime) {
+			return items[i].ModTime.After(items[j].ModTime)
+		}
+		return items[i].ID < items[j].ID
+	})
+	for i := 0; i < len(items) && i < catalogNewest; i++ {
+		catalog.Newest = append(catalog.Newest, items[i])
+	}
+
+	sort.Slice(cves, func(i, j int) bool {
+		return cveAfter(cves[i].CVE, cves[j].CVE)
+	})
+	for i := 0; i < len(cves) && i < catalogRecentCVEs; i++ {
+		catalog.RecentCVEs = append(catalog.RecentCVEs, cves[i])
+	}
+	return catalog
+}
+
+// templateCVE returns the CVE a template checks: its ID when named after
+// one, otherwise the latest CVE of its classification
+func templateCVE(template scanner.TemplateMetadata) string {
+	if id := strings.ToUpper(template.ID); cvePattern.MatchString(id) {
+		return id
+	}
+	latest := ""
+	for _, cve := range template.CVEs {
+		if cvePattern.MatchString(cve) && (latest == "" || cveAfter(cve, latest)) {
+			latest = cve
+		}
+	}
+	return latest
+}
+
+// cveAfter reports whether CVE ID a was assigned after b, by year and then
+// number. Both must match cvePattern.
+func cveAfter(a, b string) bool {
+	pa, pb := strings.Split(a, "-"), strings.Split(b, "-")
+	if pa[1] != pb[1] {
+		return pa[1] > pb[1]
+	}
+	na, _ := strconv.Atoi(pa[2])
+	nb, _ := strconv.Atoi(pb[2])
+	if na != nb {
+		return na > nb
+	}
+	return a < b
+}
+
+// HandleCatalogResource returns the statistics of the template catalog
+func HandleCatalogResource(_ context.Context, request mcp.ReadResourceRequest, index *scanner.TemplateIndex, dirs []string) ([]mcp.ResourceContents, error) {
+	catalogJSON, err := json.Marshal(templateCatalog(index, dirs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal template catalog: %w", err)
+	}
+
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      request.Params.URI,
+			MIMEType: "application/json",
+			Text:     string(catalogJSON),
+		},
+	}, nil
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"nuclei-mcp/pkg/assets"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+)
+
+// cloudTargets adds the hosts of the asset registry, and the names
+// certificate transparency logs hold for them, to the targets of a
+// cloud_scan call with from_assets, before scopeGuard checks them
+func cloudTargets(registry *assets.Registry, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		argMap, _ := request.Params.Arguments.(map[string]any)
+		if fromAssets, _ := argMap["from_assets"].(bool); !fromAssets {
+			return handler(ctx, request)
+		}
+		if registry == nil {
+			return nil, fmt.Errorf("from_assets needs the asset registry, which this server does not keep")
+		}
+
+		targets := stringList(argMap["targets"])
+		seen := map[string]bool{}
+		for _, target := range targets {
+			seen[strings.ToLower(target)] = true
+		}
+		for _, asset := range registry.List() {
+			for _, host := range append([]string{asset.Host}, asset.CTNames...) {
+				if !strings.HasPrefix(host, "*.") && !seen[host] {
+					seen[host] = true
+					targets = append(targets, host)
+				}
+			}
+		}
+		if len(targets) == 0 {
+			return nil, fmt.Errorf("the asset registry is empty, collect assets with target_context or pass targets")
+		}
+
+		arguments := make(map[string]any, len(argMap))
+		for name, value := range argMap {
+			arguments[name] = value
+		}
+		list := make([]any, 0, len(targets))
+		for _, target := range targets {
+			list = append(list, target)
+		}
+		arguments["targets"] = list
+		request.Params.Arguments = arguments
+		return handler(ctx, request)
+	}
+}
+
+// HandleCloudScanTool runs the cloud and Kubernetes exposure preset against
+// many targets and groups the findings by the cloud provider they concern
+func HandleCloudScanTool(ctx context.Context, request mcp.CallToolRequest, multiScanner *scanner.MultiScanner, defaults ScanDefaults) (*mcp.CallToolResult, error) {
+	argMap, ok := request.Params.Arguments.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid arguments format")
+	}
+	targets := stringList(argMap["targets"])
+	if len(targets) == 0 {
+		return nil, errInvalidTargets
+	}
+
+	// The preset replaces the tags of the scan profile
+	defaults.Tags = scanner.CloudTags
+	severity, protocols, scanOpts := defaults.Resolve(argMap)
+	protocols, err := scanner.NormalizeProtocols(protocols)
+	if err != nil {
+		return nil, err
+	}
+
+	if passive, ok := argMap["passive"].(bool); ok {
+		scanOpts = append(scanOpts, scanner.WithPassive(passive))
+	}
+	if raw, ok := argMap["priority"].(string); ok {
+		priority, err := scanner.ParsePriority(raw)
+		if err != nil {
+			return nil, err
+		}
+		scanOpts = append(scanOpts, scanner.WithPriority(priority))
+	}
+	if allowSelf, _ := argMap["allow_self_target"].(bool); allowSelf {
+		approval, _ := argMap["approval"].(string)
+		scanOpts = append(scanOpts, scanner.WithSelfTargetAllowed(approval))
+	}
+	if raw, ok := argMap["labels"]; ok {
+		labels, err := labelsArg(raw)
+		if err != nil {
+			return nil, err
+		}
+		scanOpts = append(scanOpts, scanner.WithLabels(labels))
+	}
+	scanOpts = append(scanOpts, scanner.WithClient(clientName(ctx)))
+
+	hosts := multiScanner.Scan(ctx, targets, severity, protocols, stringList(argMap["template_ids"]), scanOpts...)
+
+	byProvider := map[string]int{}
+	scanned, failed, total := 0, 0, 0
+	for _, host := range hosts {
+		for _, target := range host.Targets {
+			scanned++
+			if target.Err != nil {
+				failed++
+				continue
+			}
+			for _, finding := range target.Result.Findings {
+				byProvider[cloudProviderName(finding)]++
+				total++
+			}
+		}
+	}
+
+	responseText := fmt.Sprintf("Cloud scan of %d targets on %d hosts (%d failed) found %d exposures.\n", scanned, len(hosts), failed, total)
+	if total > 0 {
+		providers := make([]string, 0, len(byProvider))
+		for provider := range byProvider {
+			providers = append(providers, provider)
+		}
+		sort.Strings(providers)
+		responseText += "\nBy provider:\n"
+		for _, provider := range providers {
+			responseText += fmt.Sprintf("- %s: %d\n", provider, byProvider[provider])
+		}
+	}
+	for _, host := range hosts {
+		responseText += fmt.Sprintf("\nHost: %s (%d findings)\n", host.Host, host.Findings())
+		for _, target := range host.Targets {
+			if target.Err != nil {
+				responseText += fmt.Sprintf("- %s: scan failed [%s]: %v\n", target.Target, ErrorCodeOf(target.Err), target.Err)
+				continue
+			}
+			for _, finding := range target.Result.Findings {
+				responseText += fmt.Sprintf("- [%s] %s (%s) at %s: %s\n", cloudProviderName(finding), finding.Info.Name,
+					finding.Info.SeverityHolder.Severity.String(), finding.Host, cache.FindingURI(finding))
+			}
+		}
+	}
+	return mcp.NewToolResultText(responseText), nil
+}
+
+// cloudProviderName is the provider findings are grouped under
+func cloudProviderName(finding *output.ResultEvent) string {
+	if provider := scanner.CloudProvider(finding); provider != "" {
+		return provider
+	}
+	return "unknown"
+}
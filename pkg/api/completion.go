@@ -0,0 +1,165 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"nuclei-mcp/pkg/i18n"
+	"nuclei-mcp/pkg/scanner"
+	"nuclei-mcp/pkg/templates"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	nucleiconfig "github.com/projectdiscovery/nuclei/v3/pkg/catalog/config"
+)
+
+const (
+	// MaxCompletions is the most values returned per completion request
+	MaxCompletions = 100
+	// completionIndexTTL is how long the template IDs and tags offered as
+	// completions are reused before the template directories are re-read
+	completionIndexTTL = 5 * time.Minute
+)
+
+// completionRefs are the reference types completion requests may use.
+// ref/tool is an extension naming a tool, since MCP completions only cover
+// prompts and resources.
+var completionRefs = map[string]bool{
+	"ref/prompt":   true,
+	"ref/resource": true,
+	"ref/tool":     true,
+}
+
+// severities are the nuclei severities, least severe first
+var severities = []string{"info", "low", "medium", "high", "critical"}
+
+// Completer suggests values for tool arguments from the templates on disk,
+// the targets scanned so far and the fixed choices of enumerated arguments
+type Completer struct {
+	service       scanner.ScannerService
+	tm            templates.TemplateManager
+	templateDirs  []string
+	templateIndex *scanner.TemplateIndex
+
+	mu      sync.Mutex
+	indexed time.Time
+	ids     []string
+	tags    []string
+}
+
+// NewCompleter creates a completer offering the template IDs and tags index
+// reads in the default nuclei templates directory and templateDirs. A nil
+// index is replaced by an in-memory one.
+func NewCompleter(service scanner.ScannerService, tm templates.TemplateManager, index *scanner.TemplateIndex, templateDirs ...string) *Completer {
+	var dirs []string
+	if defaultDir := nucleiconfig.DefaultConfig.TemplatesDirectory; defaultDir != "" {
+		dirs = append(dirs, defaultDir)
+	}
+	if index == nil {
+		index = scanner.NewTemplateIndex("")
+	}
+	return &Completer{service: service, tm: tm, templateDirs: append(dirs, templateDirs...), templateIndex: index}
+}
+
+// Complete returns the values of the named argument matching value, values
+// starting with it first, and the number of matches before truncation to
+// MaxCompletions. Unknown arguments have no completions.
+func (c *Completer) Complete(argument string, value string) ([]string, int) {
+	var candidates []string
+	switch argument {
+	case "target", "targets":
+		for _, result := range c.service.GetAll() {
+			candidates = append(candidates, result.Target)
+		}
+	case "template_id", "template_ids":
+		candidates, _ = c.index()
+	case "tags":
+		_, candidates = c.index()
+	case "severity":
+		candidates = severities
+	case "protocols":
+		candidates = append(scanner.SupportedProtocols(), scanner.ProtocolAliases()...)
+	case "format":
+		candidates = []string{FormatText, FormatJSON}
+	case "extractors":
+		candidates = scanner.PresetNames()
+	case "name":
+		candidates, _ = c.tm.ListTemplates()
+	case "language":
+		candidates = i18n.Supported()
+	}
+
+	matches := matchCompletions(candidates, value)
+	total := len(matches)
+	if total > MaxCompletions {
+		matches = matches[:MaxCompletions]
+	}
+	return matches, total
+}
+
+// index returns the template IDs and tags, re-reading the template
+// directories when the index is older than completionIndexTTL
+func (c *Completer) index() ([]string, []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if time.Since(c.indexed) < completionIndexTTL {
+		return c.ids, c.tags
+	}
+
+	ids, tags := map[string]bool{}, map[string]bool{}
+	for _, template := range c.templateIndex.Templates(c.templateDirs...) {
+		ids[template.ID] = true
+		for _, tag := range template.Tags {
+			tags[tag] = true
+		}
+	}
+	c.ids, c.tags = sortedSet(ids), sortedSet(tags)
+	c.indexed = time.Now()
+	return c.ids, c.tags
+}
+
+// matchCompletions returns the distinct candidates containing value,
+// ignoring case, those starting with it first
+func matchCompletions(candidates []string, value string) []string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	prefixed, contained := map[string]bool{}, map[string]bool{}
+	for _, candidate := range candidates {
+		lower := strings.ToLower(candidate)
+		switch {
+		case strings.HasPrefix(lower, value):
+			prefixed[candidate] = true
+		case strings.Contains(lower, value):
+			contained[candidate] = true
+		}
+	}
+	return append(sortedSet(prefixed), sortedSet(contained)...)
+}
+
+func sortedSet(set map[string]bool) []string {
+	values := make([]string, 0, len(set))
+	for value := range set {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+	return values
+}
+
+func HandleComplete(_ context.Context, request mcp.CompleteRequest, completer *Completer) (*mcp.CompleteResult, error) {
+	ref, _ := request.Params.Ref.(map[string]any)
+	if refType, _ := ref["type"].(string); !completionRefs[refType] {
+		return nil, fmt.Errorf("unsupported completion reference %v", request.Params.Ref)
+	}
+	if request.Params.Argument.Name == "" {
+		return nil, fmt.Errorf("invalid or missing argument name")
+	}
+
+	values, total := completer.Complete(request.Params.Argument.Name, request.Params.Argument.Value)
+	result := &mcp.CompleteResult{}
+	result.Completion.Values = values
+	result.Completion.Total = total
+	result.Completion.HasMore = total > len(values)
+	return result, nil
+}
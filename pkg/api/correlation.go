@@ -0,0 +1,55 @@
+package api
+
+import (
+	"context"
+	"log"
+
+	"nuclei-mcp/pkg/correlation"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// correlate gives each tool call a correlation ID, taken from the
+// correlation_id _meta field of the call when the client sets a valid one.
+// The ID travels in the context into the scanner, prefixes the call's log
+// lines and is returned in the _meta of the result.
+func correlate(logger *log.Logger) server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			id := requestCorrelationID(request)
+			ctx = correlation.NewContext(ctx, id)
+			logger.Printf("[%s] Tool %s called", id, request.Params.Name)
+
+			result, err := next(ctx, request)
+			if err != nil {
+				logger.Printf("[%s] Tool %s failed: %v", id, request.Params.Name, err)
+				return result, err
+			}
+			if result == nil {
+				return result, nil
+			}
+			if result.IsError {
+				logger.Printf("[%s] Tool %s returned an error", id, request.Params.Name)
+			} else {
+				logger.Printf("[%s] Tool %s completed", id, request.Params.Name)
+			}
+			if result.Meta == nil {
+				result.Meta = map[string]any{}
+			}
+			result.Meta[correlation.MetaKey] = id
+			return result, nil
+		}
+	}
+}
+
+// requestCorrelationID returns the correlation ID set by the client in the
+// _meta of request, or a new one
+func requestCorrelationID(request mcp.CallToolRequest) string {
+	if meta := request.Params.Meta; meta != nil {
+		if id, ok := meta.AdditionalFields[correlation.MetaKey].(string); ok && correlation.Valid(id) {
+			return id
+		}
+	}
+	return correlation.New()
+}
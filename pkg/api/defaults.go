@@ -0,0 +1,157 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"nuclei-mcp/pkg/scanner"
+)
+
+// Output formats of nuclei_scan results
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// ScanDefaults is the scan profile applied when a nuclei_scan or
+// nuclei_scan_targets caller omits an argument
+type ScanDefaults struct {
+	// Severity is the minimum severity; empty runs templates of any severity
+	Severity string
+	// Protocols are comma-separated template protocols; empty runs any
+	Protocols string
+	// Tags limits scans to templates carrying any of these tags
+	Tags []string
+	// RateLimit caps requests per second; zero leaves nuclei's default
+	RateLimit int
+	// Format is FormatText or FormatJSON
+	Format string
+	// ExcludeTags, ExcludeIDs and ExcludeSeverities leave templates out of
+	// scans whose callers do not pass their own
+	ExcludeTags       []string
+	ExcludeIDs        []string
+	ExcludeSeverities []string
+	// Authors limits scans to templates by these authors
+	Authors []string
+	// TemplateIDs are run when a caller names no template IDs
+	TemplateIDs []string
+}
+
+// DefaultScanDefaults is the scan profile used unless WithScanDefaults
+// configures another
+var DefaultScanDefaults = ScanDefaults{
+	Severity:  "info",
+	Protocols: "http,https",
+	Format:    FormatText,
+}
+
+// WithScanDefaults sets the scan profile applied to arguments omitted by scan
+// tool callers
+func WithScanDefaults(defaults ScanDefaults) ServerOption {
+	return func(o *serverOptions) {
+		o.defaults = defaults
+	}
+}
+
+// Validate checks the protocols and format of the profile
+func (d ScanDefaults) Validate() error {
+	if _, err := scanner.NormalizeProtocols(d.Protocols); err != nil {
+		return err
+	}
+	if _, err := d.format(""); err != nil {
+		return err
+	}
+	if d.RateLimit < 0 {
+		return fmt.Errorf("invalid default rate limit: %d", d.RateLimit)
+	}
+	if err := scanner.ValidateSeverities(d.ExcludeSeverities); err != nil {
+		return fmt.Errorf("invalid default excluded severities: %w", err)
+	}
+	return nil
+}
+
+// Resolve fills the scan arguments missing from argMap with the profile,
+// returning the severity, protocols and the tag, template filter and rate
+// limit options. An exclusion argument passed empty lifts the profile's.
+func (d ScanDefaults) Resolve(argMap map[string]any) (string, string, []scanner.ScanOption) {
+	severity, _ := argMap["severity"].(string)
+	if severity == "" {
+		severity = d.Severity
+	}
+
+	protocols := strings.Join(stringList(argMap["protocols"]), ",")
+	if protocols == "" {
+		protocols = d.Protocols
+	}
+
+	var scanOpts []scanner.ScanOption
+	tags := d.Tags
+	if requested := stringList(argMap["tags"]); len(requested) > 0 {
+		tags = requested
+	}
+	if len(tags) > 0 {
+		scanOpts = append(scanOpts, scanner.WithTags(tags...))
+	}
+
+	if excludeTags := listArgument(argMap, "exclude_tags", d.ExcludeTags); len(excludeTags) > 0 {
+		scanOpts = append(scanOpts, scanner.WithExcludedTags(excludeTags...))
+	}
+	if excludeIDs := listArgument(argMap, "exclude_ids", d.ExcludeIDs); len(excludeIDs) > 0 {
+		scanOpts = append(scanOpts, scanner.WithExcludedIDs(excludeIDs...))
+	}
+	if excludeSeverities := listArgument(argMap, "exclude_severities", d.ExcludeSeverities); len(excludeSeverities) > 0 {
+		scanOpts = append(scanOpts, scanner.WithExcludedSeverities(excludeSeverities...))
+	}
+	if authors := listArgument(argMap, "authors", d.Authors); len(authors) > 0 {
+		scanOpts = append(scanOpts, scanner.WithAuthors(authors...))
+	}
+	if len(d.TemplateIDs) > 0 {
+		scanOpts = append(scanOpts, scanner.WithDefaultTemplateIDs(d.TemplateIDs...))
+	}
+
+	rateLimit := d.RateLimit
+	if raw, ok := argMap["rate_limit"].(float64); ok && raw > 0 {
+		rateLimit = int(raw)
+	}
+	if rateLimit > 0 {
+		scanOpts = append(scanOpts, scanner.WithRateLimit(rateLimit))
+	}
+
+	return severity, protocols, scanOpts
+}
+
+// forInline returns the profile for scans of inline templates, which run
+// the templates the caller wrote whatever their severity, protocol, tags or
+// authors; only exclusions, the rate limit and the format apply
+func (d ScanDefaults) forInline() ScanDefaults {
+	return ScanDefaults{
+		RateLimit:   d.RateLimit,
+		Format:      d.Format,
+		ExcludeTags: d.ExcludeTags,
+		ExcludeIDs:  d.ExcludeIDs,
+	}
+}
+
+// listArgument returns the list argument key of argMap, or fallback when
+// the caller did not pass it
+func listArgument(argMap map[string]any, key string, fallback []string) []string {
+	if raw, ok := argMap[key]; ok && raw != nil {
+		return stringList(raw)
+	}
+	return fallback
+}
+
+// format returns the requested output format, or the profile's when empty
+func (d ScanDefaults) format(requested string) (string, error) {
+	format := strings.ToLower(strings.TrimSpace(requested))
+	if format == "" {
+		format = strings.ToLower(strings.TrimSpace(d.Format))
+	}
+	switch format {
+	case "", FormatText:
+		return FormatText, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	}
+	return "", fmt.Errorf("unsupported format %q, use %s or %s", format, FormatText, FormatJSON)
+}
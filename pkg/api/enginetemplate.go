@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"nuclei-mcp/pkg/scanner"
+	"nuclei-mcp/pkg/templates"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// EngineTemplate is a template found by its nuclei ID in any template
+// directory
+type EngineTemplate struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+	// Path is the template's file and Source the template directory holding
+	// it
+	Path    string `json:"path"`
+	Source  string `json:"source"`
+	Format  string `json:"format"`
+	Content string `json:"content"`
+	// Shadowed lists the files of later directories with the same ID, which
+	// the template takes precedence over
+	Shadowed []string `json:"shadowed,omitempty"`
+}
+
+// findEngineTemplate returns the template with the nuclei ID id from the
+// first of dirs holding one, as index reads them
+func findEngineTemplate(id string, index *scanner.TemplateIndex, dirs []string) (*EngineTemplate, error) {
+	var found *EngineTemplate
+	for _, dir := range dirs {
+		for _, template := range index.Templates(dir) {
+			if !strings.EqualFold(template.ID, id) {
+				continue
+			}
+			if found != nil {
+				found.Shadowed = append(found.Shadowed, template.Path)
+				continue
+			}
+			found = &EngineTemplate{
+				ID:     template.ID,
+				Name:   template.Name,
+				Path:   template.Path,
+				Source: filepath.Clean(dir),
+				Format: templates.FormatOf(template.Path),
+			}
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("template %s not found in %d template directories", id, len(dirs))
+	}
+
+	content, err := os.ReadFile(found.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template %s: %w", id, err)
+	}
+	found.Content = string(content)
+	return found, nil
+}
+
+// HandleGetEngineTemplate returns the content and location of a template by
+// its nuclei ID
+func HandleGetEngineTemplate(_ context.Context, request mcp.CallToolRequest, index *scanner.TemplateIndex, dirs []string) (*mcp.CallToolResult, error) {
+	argMap, ok := request.Params.Arguments.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid arguments format")
+	}
+
+	id, ok := argMap["id"].(string)
+	if !ok || strings.TrimSpace(id) == "" {
+		return nil, fmt.Errorf("invalid or missing id parameter")
+	}
+
+	template, err := findEngineTemplate(strings.TrimSpace(id), index, dirs)
+	if err != nil {
+		return nil, err
+	}
+
+	if format, _ := argMap["format"].(string); format != "" && format != template.Format {
+		content, err := templates.ConvertTemplate([]byte(template.Content), format)
+		if err != nil {
+			return nil, err
+		}
+		template.Content, template.Format = string(content), format
+	}
+
+	templateJSON, err := json.Marshal(template)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal template: %w", err)
+	}
+	return mcp.NewToolResultText(string(templateJSON)), nil
+}
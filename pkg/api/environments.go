@@ -0,0 +1,207 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+)
+
+// DefaultEnvironmentLabel is the scan label compare_environments reads the
+// environment of a scan from unless another is given
+const DefaultEnvironmentLabel = "environment"
+
+// EnvironmentComparison lists the findings only one of two environments has.
+// Findings match across environments by template and path, whatever host
+// each environment is served on.
+type EnvironmentComparison struct {
+	Label        string            `json:"label"`
+	Environments [2]string         `json:"environments"`
+	Filter       map[string]string `json:"filter,omitempty"`
+	// Targets are the scan targets compared in each environment, whose
+	// latest matching scan is used
+	Targets map[string][]string `json:"targets"`
+	// Only lists, for each environment, the findings the other has not
+	Only map[string][]environmentFinding `json:"only"`
+	// Common counts the findings both environments have
+	Common int `json:"common"`
+}
+
+type environmentFinding struct {
+	TemplateID string `json:"template_id"`
+	Name       string `json:"name"`
+	Severity   string `json:"severity"`
+	// Path is the path the finding matched at, empty for findings of
+	// templates that match on no URL, such as DNS and SSL ones
+	Path     string   `json:"path"`
+	Matched  []string `json:"matched"`
+	Resource string   `json:"resource"`
+}
+
+// compareEnvironments compares the findings of the latest scans of each
+// target labelled label=left with those labelled label=right, among the
+// scans carrying the filter labels too
+func compareEnvironments(results []cache.ScanResult, label, left, right string, filter map[string]string) (EnvironmentComparison, error) {
+	if left == right {
+		return EnvironmentComparison{}, fmt.Errorf("compare two different environments, got %q twice", left)
+	}
+	comparison := EnvironmentComparison{
+		Label:        label,
+		Environments: [2]string{left, right},
+		Filter:       filter,
+		Targets:      map[string][]string{left: {}, right: {}},
+		Only:         map[string][]environmentFinding{left: {}, right: {}},
+	}
+
+	// The latest scan of each target in each environment
+	latest := map[string]map[string]cache.ScanResult{left: {}, right: {}}
+	for _, result := range results {
+		environment := result.Labels[label]
+		if (environment != left && environment != right) || !hasLabels(result.Labels, filter) {
+			continue
+		}
+		if scanned, ok := latest[environment][result.Target]; !ok || result.ScanTime.After(scanned.ScanTime) {
+			latest[environment][result.Target] = result
+		}
+	}
+
+	findings := map[string]map[string]*environmentFinding{left: {}, right: {}}
+	for environment, scans := range latest {
+		for target, result := range scans {
+			comparison.Targets[environment] = append(comparison.Targets[environment], target)
+			for _, finding := range result.Findings {
+				if finding == nil {
+					continue
+				}
+				path := findingPath(finding)
+				key := finding.TemplateID + "\x00" + path
+				entry, ok := findings[environment][key]
+				if !ok {
+					entry = &environmentFinding{
+						TemplateID: finding.TemplateID,
+						Name:       finding.Info.Name,
+						Severity:   finding.Info.SeverityHolder.Severity.String(),
+						Path:       path,
+						Resource:   cache.FindingURI(finding),
+					}
+					findings[environment][key] = entry
+				}
+				matched := finding.Matched
+				if matched == "" {
+					matched = finding.Host
+				}
+				entry.Matched = append(entry.Matched, matched)
+			}
+		}
+		sort.Strings(comparison.Targets[environment])
+	}
+	for _, environment := range comparison.Environments {
+		if len(latest[environment]) == 0 {
+			return EnvironmentComparison{}, fmt.Errorf("no cached scans labelled %s=%s to compare", label, environment)
+		}
+	}
+
+	for key, entry := range findings[left] {
+		if _, ok := findings[right][key]; ok {
+			comparison.Common++
+			continue
+		}
+		comparison.Only[left] = append(comparison.Only[left], *entry)
+	}
+	for key, entry := range findings[right] {
+		if _, ok := findings[left][key]; !ok {
+			comparison.Only[right] = append(comparison.Only[right], *entry)
+		}
+	}
+	for _, only := range comparison.Only {
+		sort.Slice(only, func(i, j int) bool {
+			if only[i].TemplateID != only[j].TemplateID {
+				return only[i].TemplateID < only[j].TemplateID
+			}
+			return only[i].Path < only[j].Path
+		})
+		for _, entry := range only {
+			sort.Strings(entry.Matched)
+		}
+	}
+	return comparison, nil
+}
+
+// hasLabels reports whether labels carry each of the wanted labels
+func hasLabels(labels, wanted map[string]string) bool {
+	for key, value := range wanted {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// findingPath returns the path of the URL a finding matched at, without
+// its host, or empty when it matched on no URL
+func findingPath(finding *output.ResultEvent) string {
+	matched := finding.Matched
+	if matched == "" {
+		matched = finding.Host
+	}
+	if !strings.Contains(matched, "://") {
+		return ""
+	}
+	u, err := url.Parse(matched)
+	if err != nil {
+		return ""
+	}
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return path
+}
+
+// HandleCompareEnvironments compares the cached findings of two
+// environments of the same application, told apart by a scan label
+func HandleCompareEnvironments(_ context.Context, request mcp.CallToolRequest, service scanner.ScannerService) (*mcp.CallToolResult, error) {
+	argMap, ok := request.Params.Arguments.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid arguments format")
+	}
+
+	environments := stringList(argMap["environments"])
+	if len(environments) != 2 {
+		return nil, fmt.Errorf("invalid environments parameter, pass the two label values to compare, such as [\"staging\", \"production\"]")
+	}
+	label, _ := argMap["label"].(string)
+	if label = strings.TrimSpace(label); label == "" {
+		label = DefaultEnvironmentLabel
+	}
+	var filter map[string]string
+	if raw, ok := argMap["labels"]; ok {
+		labels, err := labelsArg(raw)
+		if err != nil {
+			return nil, err
+		}
+		filter = labels
+	}
+
+	comparison, err := compareEnvironments(service.GetAll(), label, environments[0], environments[1], filter)
+	if err != nil {
+		return nil, err
+	}
+
+	comparisonJSON, err := json.Marshal(comparison)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal environment comparison: %w", err)
+	}
+	return mcp.NewToolResultText(string(comparisonJSON)), nil
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"nuclei-mcp/pkg/policy"
+	"nuclei-mcp/pkg/scanner"
+	"nuclei-mcp/pkg/secrets"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ErrorCode classifies a failed scan tool call so agents can branch on it
+type ErrorCode string
+
+// Error codes of failed scan tool calls
+const (
+	// CodeTargetInvalid: the target is missing, malformed or could not be loaded
+	CodeTargetInvalid ErrorCode = "TARGET_INVALID"
+	// CodeTemplatesNotFound: no template matches the requested filters
+	CodeTemplatesNotFound ErrorCode = "TEMPLATES_NOT_FOUND"
+	// CodeEngineInitFailed: the scan engine could not be created or its
+	// templates loaded
+	CodeEngineInitFailed ErrorCode = "ENGINE_INIT_FAILED"
+	// CodeTimeout: the scan or its engine did not finish in time
+	CodeTimeout ErrorCode = "TIMEOUT"
+	// CodeRateLimited: the scan was refused by a rate or concurrency limit
+	CodeRateLimited ErrorCode = "RATE_LIMITED"
+	// CodeQuotaExceeded: the client used up its scan resource quota
+	CodeQuotaExceeded ErrorCode = "QUOTA_EXCEEDED"
+	// CodeMaintenance: scanning is paused for maintenance
+	CodeMaintenance ErrorCode = "MAINTENANCE"
+	// CodeScopeDenied: the target or template is refused by the scan scope,
+	// egress or template policy
+	CodeScopeDenied ErrorCode = "SCOPE_DENIED"
+	// CodeInvalidParameter: a scan argument such as protocols has an
+	// unsupported value
+	CodeInvalidParameter ErrorCode = "INVALID_PARAMETER"
+	// CodeScanFailed: any other failure
+	CodeScanFailed ErrorCode = "SCAN_FAILED"
+)
+
+// ErrRateLimited is wrapped by errors of scans refused by a rate limit
+var ErrRateLimited = errors.New("rate limited")
+
+var (
+	errInvalidTarget  = errors.New("invalid or missing target parameter")
+	errInvalidTargets = errors.New("invalid or missing targets parameter")
+)
+
+// ToolError is the JSON payload of a failed scan tool call
+type ToolError struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+}
+
+// ErrorCodeOf classifies an error returned by a scan
+func ErrorCodeOf(err error) ErrorCode {
+	switch {
+	case errors.Is(err, policy.ErrDenied):
+		return CodeScopeDenied
+	case errors.Is(err, errInvalidTarget), errors.Is(err, errInvalidTargets),
+		errors.Is(err, policy.ErrInvalidTarget), errors.Is(err, scanner.ErrNoTargets):
+		return CodeTargetInvalid
+	case errors.Is(err, scanner.ErrInvalidProtocol), errors.Is(err, scanner.ErrInvalidPriority),
+		errors.Is(err, scanner.ErrInvalidStrategy), errors.Is(err, scanner.ErrInvalidSeverity),
+		errors.Is(err, scanner.ErrInvalidLabel), errors.Is(err, scanner.ErrInvalidVariable),
+		errors.Is(err, secrets.ErrUnknownSecret), errors.Is(err, scanner.ErrInvalidAddressFamily),
+		errors.Is(err, scanner.ErrInvalidHostOverride), errors.Is(err, scanner.ErrInvalidOpenAPI),
+		errors.Is(err, scanner.ErrInvalidAPIParameter), errors.Is(err, scanner.ErrPreviewUnsupported),
+		errors.Is(err, scanner.ErrInvalidInlineTemplate):
+		return CodeInvalidParameter
+	case errors.Is(err, scanner.ErrNoTemplates):
+		return CodeTemplatesNotFound
+	case errors.Is(err, scanner.ErrEngineTimeout), errors.Is(err, context.DeadlineExceeded):
+		return CodeTimeout
+	case errors.Is(err, scanner.ErrEngineInit):
+		return CodeEngineInitFailed
+	case errors.Is(err, ErrRateLimited):
+		return CodeRateLimited
+	case errors.Is(err, scanner.ErrQuotaExceeded):
+		return CodeQuotaExceeded
+	case errors.Is(err, scanner.ErrMaintenance):
+		return CodeMaintenance
+	}
+	return CodeScanFailed
+}
+
+// errorResult returns a tool result flagged as an error whose text is the
+// JSON encoded ToolError of err
+func errorResult(err error) *mcp.CallToolResult {
+	payload, _ := json.Marshal(ToolError{Code: ErrorCodeOf(err), Message: err.Error()})
+	return mcp.NewToolResultError(string(payload))
+}
+
+// recordViolations records the calls the handler refuses by policy in the
+// violation log
+func recordViolations(options *serverOptions, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	if options.violations == nil {
+		return handler
+	}
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := handler(ctx, request)
+		if errors.Is(err, policy.ErrDenied) {
+			argMap, _ := request.Params.Arguments.(map[string]any)
+			target, _ := argMap["target"].(string)
+			if target == "" {
+				target = strings.Join(stringList(argMap["targets"]), ", ")
+			}
+			if target == "" {
+				target, _ = argMap["name"].(string)
+			}
+			options.violations.Record(policy.Violation{Tool: request.Params.Name, Target: target, Reason: err.Error()})
+		}
+		return result, err
+	}
+}
+
+// structuredErrors reports the errors of a scan tool as error results with a
+// code instead of protocol errors
+func structuredErrors(handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := handler(ctx, request)
+		if err != nil {
+			return errorResult(err), nil
+		}
+		return result, nil
+	}
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/encryption"
+	"nuclei-mcp/pkg/report"
+	"nuclei-mcp/pkg/scanner"
+	"nuclei-mcp/pkg/tracker"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// EvidenceScheme is the URI scheme of finding evidence blobs
+const EvidenceScheme = "evidence://"
+
+// inlineEvidenceLimit is the largest request or response inlined in a
+// finding's detail; larger evidence is only served as a blob
+const inlineEvidenceLimit = 4096
+
+// EvidenceLink names an evidence blob of a finding
+type EvidenceLink struct {
+	Name     string `json:"name"`
+	URI      string `json:"uri"`
+	MIMEType string `json:"mime_type"`
+	Size     int    `json:"size"`
+}
+
+// FindingDetail is the content of a finding://{fingerprint} resource: the
+// full detail of a finding the scan tools list compactly
+type FindingDetail struct {
+	Fingerprint      string         `json:"fingerprint"`
+	Target           string         `json:"target"`
+	ScanTime         string         `json:"scan_time"`
+	TemplateID       string         `json:"template_id"`
+	Name             string         `json:"name"`
+	Severity         string         `json:"severity"`
+	Description      string         `json:"description,omitempty"`
+	Remediation      string         `json:"remediation,omitempty"`
+	References       []string       `json:"references,omitempty"`
+	Tags             []string       `json:"tags,omitempty"`
+	Host             string         `json:"host"`
+	Matched          string         `json:"matched,omitempty"`
+	IP               string         `json:"ip,omitempty"`
+	MatcherName      string         `json:"matcher_name,omitempty"`
+	ExtractorName    string         `json:"extractor_name,omitempty"`
+	ExtractedResults []string       `json:"extracted_results,omitempty"`
+	CURLCommand      string         `json:"curl_command,omitempty"`
+	Request          string         `json:"request,omitempty"`
+	Response         string         `json:"response,omitempty"`
+	Timestamp        string         `json:"timestamp,omitempty"`
+	Evidence         []EvidenceLink `json:"evidence,omitempty"`
+	Status           tracker.Status `json:"status,omitempty"`
+	StatusNote       string         `json:"status_note,omitempty"`
+	AcceptedUntil    string         `json:"accepted_until,omitempty"`
+	// Labels are the labels of the scan that reported the finding
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// HandleFindingResource returns the full detail of the cached finding named
+// by a finding://{fingerprint} URI. A request or response larger than
+// inlineEvidenceLimit is left out and linked as an evidence blob instead.
+// key decrypts encrypted spill files and may be nil. The finding's
+// lifecycle status is included when store is set.
+func HandleFindingResource(_ context.Context, request mcp.ReadResourceRequest, service scanner.ScannerService, key *encryption.Key, store *tracker.Store) ([]mcp.ResourceContents, error) {
+	fingerprint := strings.TrimPrefix(request.Params.URI, cache.FindingScheme)
+	if fingerprint == "" || fingerprint == request.Params.URI {
+		return nil, fmt.Errorf("invalid finding URI: %s", request.Params.URI)
+	}
+
+	result, finding, ok := cache.FindFinding(service.GetAll(), fingerprint, key)
+	if !ok {
+		return nil, fmt.Errorf("finding %s not found in cached scan results", fingerprint)
+	}
+
+	detail := FindingDetail{
+		Fingerprint:      fingerprint,
+		Target:           result.Target,
+		ScanTime:         result.ScanTime.Format(time.RFC3339),
+		TemplateID:       finding.TemplateID,
+		Name:             finding.Info.Name,
+		Severity:         finding.Info.SeverityHolder.Severity.String(),
+		Description:      finding.Info.Description,
+		Remediation:      finding.Info.Remediation,
+		Tags:             finding.Info.Tags.ToSlice(),
+		Host:             finding.Host,
+		Matched:          finding.Matched,
+		IP:               finding.IP,
+		MatcherName:      finding.MatcherName,
+		ExtractorName:    finding.ExtractorName,
+		ExtractedResults: finding.ExtractedResults,
+		CURLCommand:      report.CurlCommand(finding),
+		Labels:           result.Labels,
+	}
+	if len(finding.Request) <= inlineEvidenceLimit {
+		detail.Request = finding.Request
+	}
+	if len(finding.Response) <= inlineEvidenceLimit {
+		detail.Response = finding.Response
+	}
+	if store != nil {
+		detail.Status = tracker.StatusNew
+		if record, ok := store.Get(fingerprint); ok {
+			detail.Status, detail.StatusNote = record.Status, record.Note
+			if record.AcceptedUntil != nil {
+				detail.AcceptedUntil = record.AcceptedUntil.Format(time.RFC3339)
+			}
+		}
+	}
+	for _, evidence := range report.FindingEvidence(finding) {
+		detail.Evidence = append(detail.Evidence, EvidenceLink{
+			Name:     evidence.Name,
+			URI:      EvidenceScheme + fingerprint + "/" + evidence.Name,
+			MIMEType: evidence.MIMEType,
+			Size:     len(evidence.Data),
+		})
+	}
+	if finding.Info.Reference != nil {
+		detail.References = finding.Info.Reference.ToSlice()
+	}
+	if !finding.Timestamp.IsZero() {
+		detail.Timestamp = finding.Timestamp.Format(time.RFC3339)
+	}
+
+	detailJSON, err := json.Marshal(detail)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal finding: %w", err)
+	}
+
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      request.Params.URI,
+			MIMEType: "application/json",
+			Text:     string(detailJSON),
+		},
+	}, nil
+}
+
+// HandleEvidenceResource returns an evidence blob of a cached finding named
+// by an evidence://{fingerprint}/{name} URI, base64 encoded with the MIME
+// type of the evidence
+func HandleEvidenceResource(_ context.Context, request mcp.ReadResourceRequest, service scanner.ScannerService, key *encryption.Key) ([]mcp.ResourceContents, error) {
+	fingerprint, name, ok := strings.Cut(strings.TrimPrefix(request.Params.URI, EvidenceScheme), "/")
+	if !ok || fingerprint == "" || name == "" || !strings.HasPrefix(request.Params.URI, EvidenceScheme) {
+		return nil, fmt.Errorf("invalid evidence URI: %s", request.Params.URI)
+	}
+
+	_, finding, found := cache.FindFinding(service.GetAll(), fingerprint, key)
+	if !found {
+		return nil, fmt.Errorf("finding %s not found in cached scan results", fingerprint)
+	}
+
+	for _, evidence := range report.FindingEvidence(finding) {
+		if evidence.Name == name {
+			return []mcp.ResourceContents{
+				mcp.BlobResourceContents{
+					URI:      request.Params.URI,
+					MIMEType: evidence.MIMEType,
+					Blob:     base64.StdEncoding.EncodeToString(evidence.Data),
+				},
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("finding %s has no %s evidence", fingerprint, name)
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"nuclei-mcp/pkg/policy"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// inlineTemplates returns the template contents of the inline_templates
+// argument
+func inlineTemplates(raw any) ([]string, error) {
+	var items []any
+	switch value := raw.(type) {
+	case nil:
+		return nil, nil
+	case []any:
+		items = value
+	case []string:
+		for _, item := range value {
+			items = append(items, item)
+		}
+	default:
+		return nil, fmt.Errorf("invalid inline_templates: expected a list of template contents")
+	}
+	contents := make([]string, 0, len(items))
+	for i, item := range items {
+		content, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid inline_templates: item %d is not template content", i+1)
+		}
+		contents = append(contents, content)
+	}
+	return contents, nil
+}
+
+// inlineTemplateGuard holds inline templates to the template policy and
+// signature verification of templates added to the library
+func inlineTemplateGuard(options *serverOptions, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	if options.policy == nil && options.verifier == nil {
+		return handler
+	}
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		argMap, _ := request.Params.Arguments.(map[string]any)
+		contents, err := inlineTemplates(argMap["inline_templates"])
+		if err != nil {
+			return nil, err
+		}
+		allowUnsafe, _ := argMap["allow_unsafe"].(bool)
+		approval, _ := argMap["approval"].(string)
+		for i, content := range contents {
+			if options.policy != nil {
+				if err := options.policy.CheckTemplate([]byte(content), policy.Override{Allow: allowUnsafe, Approval: approval}); err != nil {
+					return nil, fmt.Errorf("inline template %d: %w", i+1, err)
+				}
+			}
+			if options.verifier != nil && options.verifier.Enforced() {
+				if err := options.verifier.Verify([]byte(content), nil); err != nil {
+					return nil, fmt.Errorf("inline template %d rejected by %s signature verification: %w", i+1, options.verifier.Mode(), err)
+				}
+			}
+		}
+		return handler(ctx, request)
+	}
+}
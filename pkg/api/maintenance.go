@@ -0,0 +1,61 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// maxDrainWait bounds how long pause_scanning waits for active scans
+const maxDrainWait = 30 * time.Minute
+
+// WithMaintenance enables the pause_scanning and resume_scanning tools
+// switching m
+func WithMaintenance(m *scanner.Maintenance) ServerOption {
+	return func(o *serverOptions) {
+		o.maintenance = m
+	}
+}
+
+// HandlePauseScanning refuses new scans until resume_scanning, optionally
+// waiting for the active scans to finish
+func HandlePauseScanning(ctx context.Context, request mcp.CallToolRequest, m *scanner.Maintenance) (*mcp.CallToolResult, error) {
+	argMap, _ := request.Params.Arguments.(map[string]any)
+	reason, _ := argMap["reason"].(string)
+	wait, _ := argMap["wait_seconds"].(float64)
+	if wait < 0 {
+		return nil, fmt.Errorf("invalid wait_seconds: must not be negative, got %g", wait)
+	}
+
+	status := m.Pause(reason)
+	if wait > 0 && !status.Drained {
+		timeout := time.Duration(wait * float64(time.Second))
+		if timeout > maxDrainWait {
+			timeout = maxDrainWait
+		}
+		waitCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		_ = m.Wait(waitCtx)
+		status = m.Status()
+	}
+	return maintenanceResult(status)
+}
+
+// HandleResumeScanning admits new scans again
+func HandleResumeScanning(_ context.Context, _ mcp.CallToolRequest, m *scanner.Maintenance) (*mcp.CallToolResult, error) {
+	return maintenanceResult(m.Resume())
+}
+
+func maintenanceResult(status scanner.MaintenanceStatus) (*mcp.CallToolResult, error) {
+	statusJSON, err := json.Marshal(status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal maintenance status: %w", err)
+	}
+
+	return mcp.NewToolResultText(string(statusJSON)), nil
+}
@@ -0,0 +1,298 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+)
+
+// DefaultNextStepsLimit is the number of templates next_steps recommends
+// unless told otherwise
+const DefaultNextStepsLimit = 25
+
+// genericTags are template tags that say what kind of check a template is
+// rather than what technology it found, so they suggest no follow-up
+var genericTags = map[string]bool{
+	"tech": true, "detect": true, "discovery": true, "misconfig": true, "exposure": true,
+	"panel": true, "login": true, "cve": true, "kev": true, "vkev": true, "info": true,
+	"osint": true, "ssl": true, "tls": true, "dns": true, "http": true, "network": true,
+	"headers": true, "generic": true, "vuln": true, "intrusive": true, "oast": true,
+	"edb": true, "packetstorm": true, "seclists": true, "huntr": true, "hackerone": true,
+	"config": true, "file": true, "default-login": true, "unauth": true, "auth-bypass": true,
+	"xss": true, "sqli": true, "lfi": true, "rce": true, "ssrf": true, "redirect": true,
+	"injection": true, "disclosure": true, "listing": true, "debug": true, "backup": true,
+	"token": true, "keys": true, "secret": true, "fuzz": true, "dos": true, "takeover": true,
+}
+
+// cveYearTag matches the cve2021 style tags of CVE templates
+var cveYearTag = regexp.MustCompile(`^cve\d{4}$`)
+
+// versionPattern finds version numbers in the values templates extract
+var versionPattern = regexp.MustCompile(`\b\d+\.\d+(?:\.\d+)*\b`)
+
+// relatedTags are the tags of templates that follow up on a technology but
+// are not tagged with its name
+var relatedTags = map[string][]string{
+	"wordpress":  {"wp-plugin", "wp-theme", "wp"},
+	"wp":         {"wordpress", "wp-plugin"},
+	"joomla":     {"joomla-extension"},
+	"jira":       {"atlassian"},
+	"confluence": {"atlassian"},
+	"tomcat":     {"apache"},
+	"jenkins":    {"ci"},
+	"gitlab":     {"git"},
+	"kubernetes": {"k8s"},
+	"k8s":        {"kubernetes"},
+}
+
+// NextSteps recommends follow-up templates for a target from the
+// technologies its findings revealed
+type NextSteps struct {
+	Target string `json:"target"`
+	// Signals are the technologies the target's findings revealed
+	Signals []nextStepSignal `json:"signals"`
+	// Recommendations are the templates not yet reporting on the target
+	// that are tagged with a signal, most severe first
+	Recommendations []nextStepTemplate `json:"recommendations"`
+	// Tags are the tags to scan the target with to run the recommendations
+	// and the templates like them
+	Tags []string `json:"tags"`
+	// Presets are the preset scan tools suited to the signals
+	Presets []nextStepPreset `json:"presets,omitempty"`
+}
+
+type nextStepPreset struct {
+	Tool   string `json:"tool"`
+	Reason string `json:"reason"`
+}
+
+type nextStepSignal struct {
+	Tag string `json:"tag"`
+	// From lists the findings revealing the technology, as template ID or
+	// template:matcher
+	From []string `json:"from"`
+	// Versions are the version numbers the findings extracted, such as
+	// 5.8.1 for WordPress 5.8.1
+	Versions []string `json:"versions,omitempty"`
+}
+
+type nextStepTemplate struct {
+	ID       string   `json:"id"`
+	Severity string   `json:"severity,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	CVEs     []string `json:"cve_ids,omitempty"`
+	Path     string   `json:"path"`
+	// Reason names the signals the template follows up on
+	Reason string `json:"reason"`
+}
+
+// nextSteps derives signals from the cached findings of target and
+// recommends at most limit of the templates index reads under dirs tagged
+// with them
+func nextSteps(target string, results []cache.ScanResult, index *scanner.TemplateIndex, dirs []string, limit int) (NextSteps, error) {
+	steps := NextSteps{Target: target, Signals: []nextStepSignal{}, Recommendations: []nextStepTemplate{}, Tags: []string{}}
+
+	signals := map[string]*nextStepSignal{}
+	reported := map[string]bool{}
+	scanned := false
+	for _, result := range results {
+		if result.Target != target {
+			continue
+		}
+		scanned = true
+		for _, finding := range result.Findings {
+			if finding == nil {
+				continue
+			}
+			reported[finding.TemplateID] = true
+			for _, tag := range findingSignals(finding) {
+				signal, ok := signals[tag]
+				if !ok {
+					signal = &nextStepSignal{Tag: tag}
+					signals[tag] = signal
+				}
+				from := finding.TemplateID
+				if finding.MatcherName != "" {
+					from += ":" + finding.MatcherName
+				}
+				if !slices.Contains(signal.From, from) {
+					signal.From = append(signal.From, from)
+				}
+				for _, value := range finding.ExtractedResults {
+					for _, version := range versionPattern.FindAllString(value, -1) {
+						if !slices.Contains(signal.Versions, version) {
+							signal.Versions = append(signal.Versions, version)
+						}
+					}
+				}
+			}
+		}
+	}
+	if !scanned {
+		return NextSteps{}, fmt.Errorf("no cached scans of %s, scan it first", target)
+	}
+
+	// Templates tagged with a signal follow up on it directly; those with
+	// a related tag come after them
+	wanted := map[string]string{}
+	for tag := range signals {
+		wanted[tag] = tag
+	}
+	for tag := range signals {
+		for _, related := range relatedTags[tag] {
+			if _, ok := wanted[related]; !ok {
+				wanted[related] = tag
+			}
+		}
+	}
+	for tag := range wanted {
+		steps.Tags = append(steps.Tags, tag)
+	}
+	sort.Strings(steps.Tags)
+	for _, tag := range sortedSignalTags(signals) {
+		steps.Signals = append(steps.Signals, *signals[tag])
+		if slices.Contains(scanner.CloudTags, tag) && len(steps.Presets) == 0 {
+			steps.Presets = append(steps.Presets, nextStepPreset{Tool: "cloud_scan", Reason: "the findings reveal " + tag})
+		}
+	}
+
+	type candidate struct {
+		template nextStepTemplate
+		direct   int
+		related  int
+	}
+	var candidates []candidate
+	seen := map[string]bool{}
+	for _, template := range index.Templates(dirs...) {
+		if reported[template.ID] || seen[template.ID] {
+			continue
+		}
+		var direct, related int
+		var reasons []string
+		for _, tag := range template.Tags {
+			tag = strings.ToLower(tag)
+			signal, ok := wanted[tag]
+			if !ok {
+				continue
+			}
+			if signal == tag {
+				direct++
+				reasons = append(reasons, "tagged "+tag)
+			} else {
+				related++
+				reasons = append(reasons, fmt.Sprintf("tagged %s, related to %s", tag, signal))
+			}
+		}
+		if direct+related == 0 {
+			continue
+		}
+		seen[template.ID] = true
+		candidates = append(candidates, candidate{direct: direct, related: related, template: nextStepTemplate{
+			ID:       template.ID,
+			Severity: template.Severity,
+			Tags:     template.Tags,
+			CVEs:     template.CVEs,
+			Path:     template.Path,
+			Reason:   strings.Join(reasons, "; "),
+		}})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if (a.direct > 0) != (b.direct > 0) {
+			return a.direct > 0
+		}
+		if rankA, rankB := templateSeverityRank(a.template.Severity), templateSeverityRank(b.template.Severity); rankA != rankB {
+			return rankA < rankB
+		}
+		if a.direct+a.related != b.direct+b.related {
+			return a.direct+a.related > b.direct+b.related
+		}
+		return a.template.ID < b.template.ID
+	})
+	for i := 0; i < len(candidates) && i < limit; i++ {
+		steps.Recommendations = append(steps.Recommendations, candidates[i].template)
+	}
+	return steps, nil
+}
+
+// findingSignals returns the technologies a finding reveals: its template's
+// tags that name one, and the matcher name of technology detections
+func findingSignals(finding *output.ResultEvent) []string {
+	var tags []string
+	add := func(tag string) {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || genericTags[tag] || cveYearTag.MatchString(tag) || slices.Contains(tags, tag) {
+			return
+		}
+		tags = append(tags, tag)
+	}
+	findingTags := finding.Info.Tags.ToSlice()
+	for _, tag := range findingTags {
+		add(tag)
+	}
+	if slices.Contains(findingTags, "tech") && finding.MatcherName != "" {
+		// tech-detect style templates name the technology in the matcher
+		add(finding.MatcherName)
+	}
+	return tags
+}
+
+func sortedSignalTags(signals map[string]*nextStepSignal) []string {
+	tags := make([]string, 0, len(signals))
+	for tag := range signals {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// templateSeverityRank orders template severities from most to least
+// severe, templates without one last
+func templateSeverityRank(severity string) int {
+	if rank, ok := findingSeverityRank[severity]; ok {
+		return rank
+	}
+	return len(findingSeverityRank)
+}
+
+// HandleNextSteps recommends follow-up templates for a target from the
+// technologies its cached findings revealed
+func HandleNextSteps(_ context.Context, request mcp.CallToolRequest, service scanner.ScannerService, index *scanner.TemplateIndex, dirs []string) (*mcp.CallToolResult, error) {
+	argMap, ok := request.Params.Arguments.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid arguments format")
+	}
+
+	target, _ := argMap["target"].(string)
+	if target = strings.TrimSpace(target); target == "" {
+		return nil, fmt.Errorf("invalid or missing target parameter")
+	}
+	limit := DefaultNextStepsLimit
+	if raw, ok := argMap["limit"].(float64); ok {
+		if raw < 1 {
+			return nil, fmt.Errorf("invalid limit: must be at least 1")
+		}
+		limit = int(raw)
+	}
+
+	steps, err := nextSteps(target, service.GetAll(), index, dirs, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	stepsJSON, err := json.Marshal(steps)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal next steps: %w", err)
+	}
+	return mcp.NewToolResultText(string(stepsJSON)), nil
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// openAPIInput fetches the OpenAPI document of a scan_openapi call given by
+// spec_url, which must pass the checks of scopeGuard and of a scan target,
+// and defaults the target to the document's server before scopeGuard
+// checks it
+func openAPIInput(options *serverOptions, service scanner.ScannerService, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		argMap, _ := request.Params.Arguments.(map[string]any)
+		spec, _ := argMap["spec"].(string)
+		specURL, _ := argMap["spec_url"].(string)
+		switch {
+		case spec != "" && specURL != "":
+			return nil, fmt.Errorf("pass either spec or spec_url, not both")
+		case specURL != "":
+			fetcher, ok := service.(scanner.OpenAPIFetcher)
+			if !ok {
+				return nil, fmt.Errorf("%w: this scanner cannot fetch spec_url, pass spec", scanner.ErrInvalidOpenAPI)
+			}
+			if err := checkScope(ctx, options, argMap, []string{specURL}); err != nil {
+				return nil, err
+			}
+			var fetchOpts []scanner.ScanOption
+			if allowSelf, _ := argMap["allow_self_target"].(bool); allowSelf {
+				approval, _ := argMap["approval"].(string)
+				fetchOpts = append(fetchOpts, scanner.WithSelfTargetAllowed(approval))
+			}
+			fetched, err := fetcher.FetchOpenAPI(ctx, specURL, fetchOpts...)
+			if err != nil {
+				return nil, err
+			}
+			spec = string(fetched)
+		case spec == "":
+			return nil, fmt.Errorf("invalid or missing spec parameter, pass an OpenAPI document or spec_url")
+		}
+
+		arguments := make(map[string]any, len(argMap)+1)
+		for name, value := range argMap {
+			arguments[name] = value
+		}
+		delete(arguments, "spec_url")
+		arguments["spec"] = spec
+		if target, _ := argMap["target"].(string); target == "" {
+			target, err := scanner.OpenAPITarget([]byte(spec))
+			if err != nil {
+				return nil, err
+			}
+			arguments["target"] = target
+		}
+		request.Params.Arguments = arguments
+		return handler(ctx, request)
+	}
+}
+
+// HandleOpenAPIScanTool scans the operations of the OpenAPI or Swagger
+// document in the spec argument on the target. Only HTTP templates run:
+// regular ones against each operation's URL, or DAST templates fuzzing
+// each operation with fuzz.
+func HandleOpenAPIScanTool(
+	ctx context.Context,
+	request mcp.CallToolRequest,
+	service scanner.ScannerService,
+	logger *log.Logger,
+	pager *ResultPager,
+	defaults ScanDefaults,
+) (*mcp.CallToolResult, error) {
+	argMap, ok := request.Params.Arguments.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid arguments format")
+	}
+	spec, _ := argMap["spec"].(string)
+	if spec == "" {
+		return nil, fmt.Errorf("invalid or missing spec parameter, pass an OpenAPI document or spec_url")
+	}
+
+	var parameters map[string]string
+	if raw, ok := argMap["parameters"].(map[string]any); ok {
+		parameters = make(map[string]string, len(raw))
+		for name, value := range raw {
+			switch value := value.(type) {
+			case string:
+				parameters[name] = value
+			case float64, bool:
+				parameters[name] = fmt.Sprint(value)
+			default:
+				return nil, fmt.Errorf("%w value for %s, use a string", scanner.ErrInvalidAPIParameter, name)
+			}
+		}
+		if err := scanner.ValidateAPIParameters(parameters); err != nil {
+			return nil, err
+		}
+	}
+	opts := []scanner.ScanOption{scanner.WithOpenAPI([]byte(spec), parameters)}
+	if fuzz, _ := argMap["fuzz"].(bool); fuzz {
+		opts = append(opts, scanner.WithFuzzing())
+	}
+
+	// API operations are only scanned by HTTP templates
+	arguments := make(map[string]any, len(argMap))
+	for name, value := range argMap {
+		arguments[name] = value
+	}
+	arguments["protocols"] = []any{"http"}
+	arguments["passive"] = false
+	delete(arguments, "inline_templates")
+	request.Params.Arguments = arguments
+	return HandleNucleiScanTool(ctx, request, service, logger, pager, defaults, opts...)
+}
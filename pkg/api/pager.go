@@ -0,0 +1,195 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"nuclei-mcp/pkg/report"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+)
+
+const (
+	// DefaultPageSize is the number of findings returned per tool response
+	DefaultPageSize = 50
+	// DefaultContinuationTTL is how long unread findings are kept for
+	// fetch_more_results
+	DefaultContinuationTTL = 30 * time.Minute
+)
+
+// ResultPager splits large findings lists into pages and holds the remainder
+// behind continuation tokens. A token names a result set and an offset, so
+// fetching the same token again returns the same page.
+type ResultPager struct {
+	pageSize int
+	ttl      time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*pagedResult
+}
+
+type pagedResult struct {
+	target    string
+	findings  []*output.ResultEvent
+	endpoints [][]string
+	expires   time.Time
+}
+
+// Page is one chunk of a findings list
+type Page struct {
+	Target   string
+	Findings []*output.ResultEvent
+	// Offset is the index of the first finding of the page
+	Offset int
+	Total  int
+	// NextToken fetches the following page; empty on the last page
+	NextToken string
+	// Endpoints lists the affected endpoints of each finding of clustered
+	// results; nil otherwise
+	Endpoints [][]string
+}
+
+// NewResultPager creates a pager returning pageSize findings per page
+func NewResultPager(pageSize int, ttl time.Duration) *ResultPager {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	if ttl <= 0 {
+		ttl = DefaultContinuationTTL
+	}
+	return &ResultPager{
+		pageSize: pageSize,
+		ttl:      ttl,
+		entries:  make(map[string]*pagedResult),
+	}
+}
+
+// First returns the first page of findings, storing the rest when there is
+// more than one page. A pageSize of 0 uses the pager default.
+func (p *ResultPager) First(target string, findings []*output.ResultEvent, pageSize int) Page {
+	return p.first(target, findings, nil, pageSize)
+}
+
+// FirstClusters returns the first page of clustered findings: one finding
+// per cluster, with the cluster's endpoints
+func (p *ResultPager) FirstClusters(target string, clusters []report.Cluster, pageSize int) Page {
+	page := clusterPage(target, clusters)
+	return p.first(target, page.Findings, page.Endpoints, pageSize)
+}
+
+// clusterPage lists clusters on a single page
+func clusterPage(target string, clusters []report.Cluster) Page {
+	page := Page{
+		Target:    target,
+		Findings:  make([]*output.ResultEvent, len(clusters)),
+		Total:     len(clusters),
+		Endpoints: make([][]string, len(clusters)),
+	}
+	for i, cluster := range clusters {
+		page.Findings[i], page.Endpoints[i] = cluster.Finding, cluster.Endpoints
+	}
+	return page
+}
+
+func (p *ResultPager) first(target string, findings []*output.ResultEvent, endpoints [][]string, pageSize int) Page {
+	pageSize = p.size(pageSize)
+	if len(findings) <= pageSize {
+		return Page{Target: target, Findings: findings, Total: len(findings), Endpoints: endpoints}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.evictExpired()
+
+	id := newResultSetID()
+	p.entries[id] = &pagedResult{target: target, findings: findings, endpoints: endpoints, expires: time.Now().Add(p.ttl)}
+
+	page := Page{
+		Target:    target,
+		Findings:  findings[:pageSize],
+		Total:     len(findings),
+		NextToken: continuationToken(id, pageSize),
+	}
+	if endpoints != nil {
+		page.Endpoints = endpoints[:pageSize]
+	}
+	return page
+}
+
+// Next returns the page a continuation token points to
+func (p *ResultPager) Next(token string, pageSize int) (Page, error) {
+	id, offset, err := parseContinuationToken(token)
+	if err != nil {
+		return Page{}, err
+	}
+	pageSize = p.size(pageSize)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.evictExpired()
+
+	entry, ok := p.entries[id]
+	if !ok {
+		return Page{}, fmt.Errorf("continuation token expired or unknown")
+	}
+	if offset >= len(entry.findings) {
+		return Page{}, fmt.Errorf("continuation token is past the end of the results")
+	}
+	entry.expires = time.Now().Add(p.ttl)
+
+	end := min(offset+pageSize, len(entry.findings))
+	page := Page{
+		Target:   entry.target,
+		Findings: entry.findings[offset:end],
+		Offset:   offset,
+		Total:    len(entry.findings),
+	}
+	if entry.endpoints != nil {
+		page.Endpoints = entry.endpoints[offset:end]
+	}
+	if end < len(entry.findings) {
+		page.NextToken = continuationToken(id, end)
+	}
+	return page, nil
+}
+
+func (p *ResultPager) size(pageSize int) int {
+	if pageSize <= 0 {
+		return p.pageSize
+	}
+	return pageSize
+}
+
+// evictExpired drops result sets whose tokens expired. Callers hold p.mu.
+func (p *ResultPager) evictExpired() {
+	now := time.Now()
+	for id, entry := range p.entries {
+		if now.After(entry.expires) {
+			delete(p.entries, id)
+		}
+	}
+}
+
+func newResultSetID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func continuationToken(id string, offset int) string {
+	return id + "." + strconv.Itoa(offset)
+}
+
+func parseContinuationToken(token string) (string, int, error) {
+	id, offsetText, ok := strings.Cut(token, ".")
+	offset, err := strconv.Atoi(offsetText)
+	if !ok || id == "" || err != nil || offset < 0 {
+		return "", 0, fmt.Errorf("invalid continuation token")
+	}
+	return id, offset, nil
+}
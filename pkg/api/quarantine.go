@@ -0,0 +1,60 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// WithTemplateQuarantine enables the list_quarantined_templates and
+// unquarantine_template tools managing q
+func WithTemplateQuarantine(q *scanner.TemplateQuarantine) ServerOption {
+	return func(o *serverOptions) {
+		o.quarantine = q
+	}
+}
+
+// HandleListQuarantinedTemplates lists the quarantined templates, and with
+// watched the templates erroring towards quarantine
+func HandleListQuarantinedTemplates(_ context.Context, request mcp.CallToolRequest, q *scanner.TemplateQuarantine) (*mcp.CallToolResult, error) {
+	argMap, _ := request.Params.Arguments.(map[string]any)
+	watched, _ := argMap["watched"].(bool)
+
+	listJSON, err := json.Marshal(map[string]any{
+		"threshold": q.Threshold(),
+		"templates": q.List(watched),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal quarantined templates: %w", err)
+	}
+
+	return mcp.NewToolResultText(string(listJSON)), nil
+}
+
+// HandleUnquarantineTemplate lets a quarantined template run in scans again
+func HandleUnquarantineTemplate(_ context.Context, request mcp.CallToolRequest, q *scanner.TemplateQuarantine) (*mcp.CallToolResult, error) {
+	argMap, ok := request.Params.Arguments.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid arguments format")
+	}
+
+	id, _ := argMap["id"].(string)
+	if id = strings.TrimSpace(id); id == "" {
+		return nil, fmt.Errorf("invalid or missing id parameter")
+	}
+
+	released, err := q.Release(id)
+	if err != nil {
+		return nil, err
+	}
+	if !released {
+		return nil, fmt.Errorf("template %s is not quarantined", id)
+	}
+
+	return mcp.NewToolResultText(fmt.Sprintf("Template '%s' released from quarantine; it runs in scans again.", id)), nil
+}
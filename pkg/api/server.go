@@ -3,329 +3,2882 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 	"time"
 
+	"nuclei-mcp/pkg/assets"
+	"nuclei-mcp/pkg/benchmark"
+	"nuclei-mcp/pkg/bridge"
 	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/compliance"
+	"nuclei-mcp/pkg/correlation"
+	"nuclei-mcp/pkg/distributed"
+	"nuclei-mcp/pkg/encryption"
+	"nuclei-mcp/pkg/engine"
+	"nuclei-mcp/pkg/exclusions"
+	"nuclei-mcp/pkg/i18n"
+	"nuclei-mcp/pkg/policy"
+	"nuclei-mcp/pkg/report"
+	"nuclei-mcp/pkg/sandbox"
 	"nuclei-mcp/pkg/scanner"
+	"nuclei-mcp/pkg/schedule"
+	"nuclei-mcp/pkg/selftest"
+	"nuclei-mcp/pkg/telemetry"
 	"nuclei-mcp/pkg/templates"
+	"nuclei-mcp/pkg/tenant"
+	"nuclei-mcp/pkg/tracker"
+	"nuclei-mcp/pkg/trends"
+	"nuclei-mcp/pkg/triage"
+	"nuclei-mcp/pkg/wordlists"
+	"nuclei-mcp/pkg/workspace"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
 
-func NewNucleiMCPServer(service scanner.ScannerService, logger *log.Logger, tm templates.TemplateManager) *server.MCPServer {
+// rootsWaitTimeout bounds how long a scan waits for the client's roots after
+// initialization
+const rootsWaitTimeout = 5 * time.Second
+
+// ServerOption configures optional components of the MCP server
+type ServerOption func(*serverOptions)
+
+type serverOptions struct {
+	workspace   *workspace.Workspace
+	updater     *engine.Updater
+	updateCheck bool
+	policy      *policy.TemplatePolicy
+	verifier    *templates.Verifier
+	roots       func(ctx context.Context) []mcp.Root
+	scope       *policy.Scope
+	sampler     triage.Sampler
+	elicitor    Elicitor
+	pager       *ResultPager
+	localizer   *i18n.Localizer
+	concurrency scanner.Concurrency
+	exclusions  *exclusions.Store
+	violations  *policy.ViolationLog
+	defaults    ScanDefaults
+	workers     WorkerPool
+	quotas      *scanner.QuotaTracker
+	maintenance *scanner.Maintenance
+	quarantine  *scanner.TemplateQuarantine
+	blackouts   *schedule.Calendar
+	usage       *UsageTracker
+	encryption  *encryption.Key
+	tracker     *tracker.Store
+	retestAfter time.Duration
+	assets      *assets.Registry
+	collector   *assets.Collector
+	wordlists   *wordlists.Store
+	// templateDirs are searched by cross_reference
+	templateDirs []string
+	index        *scanner.TemplateIndex
+}
+
+// WorkerPool reports the scan workers of a distributed scanner service
+type WorkerPool interface {
+	Workers() []distributed.WorkerStatus
+}
+
+// Elicitor asks the user to clarify tool arguments through the client
+type Elicitor interface {
+	Elicit(ctx context.Context, message string, schema map[string]any) (*bridge.ElicitationResult, error)
+}
+
+// WithWorkspace enables the workspace backup and restore tools
+func WithWorkspace(ws *workspace.Workspace) ServerOption {
+	return func(o *serverOptions) {
+		o.workspace = ws
+	}
+}
+
+// WithEngineUpdater enables the engine_info and engine_update tools
+func WithEngineUpdater(u *engine.Updater) ServerOption {
+	return func(o *serverOptions) {
+		o.updater = u
+	}
+}
+
+// WithUpdateCheck enables the check_updates tool, which asks GitHub for the
+// latest templates and engine releases. It needs WithEngineUpdater.
+func WithUpdateCheck(enabled bool) ServerOption {
+	return func(o *serverOptions) {
+		o.updateCheck = enabled
+	}
+}
+
+// WithTemplatePolicy sets the policy applied to templates added through
+// add_template. Defaults to denying policy.DefaultDeniedTags.
+func WithTemplatePolicy(p *policy.TemplatePolicy) ServerOption {
+	return func(o *serverOptions) {
+		o.policy = p
+	}
+}
+
+// WithTemplateVerifier checks signatures of templates added through
+// add_template
+func WithTemplateVerifier(v *templates.Verifier) ServerOption {
+	return func(o *serverOptions) {
+		o.verifier = v
+	}
+}
+
+// WithScopeRoots restricts scan targets to the http(s) roots reported by the
+// client. Targets outside them are refused unless allow_out_of_scope and an
+// approval are given. Clients without roots leave scans unrestricted.
+func WithScopeRoots(roots func(ctx context.Context) []mcp.Root) ServerOption {
+	return func(o *serverOptions) {
+		o.roots = roots
+	}
+}
+
+// WithScope restricts scan targets to fixed hosts and URL prefixes, such as
+// those a tenant may scan. Unlike client roots, the scope cannot be
+// overridden with allow_out_of_scope.
+func WithScope(entries []string) ServerOption {
+	return func(o *serverOptions) {
+		o.scope = policy.NewScope(entries)
+	}
+}
+
+// WithSampler lets summarize_findings ask the client's model for triage
+// summaries through MCP sampling
+func WithSampler(sampler triage.Sampler) ServerOption {
+	return func(o *serverOptions) {
+		o.sampler = sampler
+	}
+}
+
+// WithElicitor asks the client to clarify ambiguous scan parameters, such as
+// a target without a scheme, instead of silently applying defaults
+func WithElicitor(elicitor Elicitor) ServerOption {
+	return func(o *serverOptions) {
+		o.elicitor = elicitor
+	}
+}
+
+// elicitTarget asks the user which scheme to scan when the target argument
+// has none. Clients without elicitation keep the default behaviour.
+func elicitTarget(options *serverOptions, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	if options.elicitor == nil {
+		return handler
+	}
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		argMap, _ := request.Params.Arguments.(map[string]any)
+		target, _ := argMap["target"].(string)
+		if target == "" || strings.Contains(target, "://") {
+			return handler(ctx, request)
+		}
+
+		result, err := options.elicitor.Elicit(ctx,
+			fmt.Sprintf("The target %q has no scheme. Which scheme should be scanned?", target),
+			map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"scheme": map[string]any{
+						"type":        "string",
+						"title":       "Scheme",
+						"description": "Use https or http for web targets, or none to scan the host with all protocols",
+						"enum":        []string{"https", "http", "none"},
+					},
+				},
+				"required": []string{"scheme"},
+			})
+		if errors.Is(err, bridge.ErrUnsupported) {
+			return handler(ctx, request)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to clarify target: %w", err)
+		}
+		if !result.Accepted() {
+			return nil, fmt.Errorf("scan of %s was not confirmed by the user (%s)", target, result.Action)
+		}
+
+		if scheme, _ := result.Content["scheme"].(string); scheme == "http" || scheme == "https" {
+			clarified := make(map[string]any, len(argMap))
+			for key, value := range argMap {
+				clarified[key] = value
+			}
+			clarified["target"] = scheme + "://" + target
+			request.Params.Arguments = clarified
+		}
+		return handler(ctx, request)
+	}
+}
+
+// WithResultPager sets how scan findings are split into pages
+func WithResultPager(pager *ResultPager) ServerOption {
+	return func(o *serverOptions) {
+		o.pager = pager
+	}
+}
+
+// WithLocalizer sets the default language of reports and summaries
+func WithLocalizer(localizer *i18n.Localizer) ServerOption {
+	return func(o *serverOptions) {
+		o.localizer = localizer
+	}
+}
+
+// WithScanConcurrency sets the per-host and global limits of
+// nuclei_scan_targets
+func WithScanConcurrency(concurrency scanner.Concurrency) ServerOption {
+	return func(o *serverOptions) {
+		o.concurrency = concurrency
+	}
+}
+
+// WithViolationLog records tool calls refused by policy, shown on the
+// dashboard resource. Defaults to a log of policy.DefaultViolationLimit
+// entries.
+func WithViolationLog(violations *policy.ViolationLog) ServerOption {
+	return func(o *serverOptions) {
+		o.violations = violations
+	}
+}
+
+// WithExclusionStore enables the add_exclusion, list_exclusions and
+// remove_exclusion tools
+func WithExclusionStore(store *exclusions.Store) ServerOption {
+	return func(o *serverOptions) {
+		o.exclusions = store
+	}
+}
+
+// WithFindingTracker enables the finding lifecycle tools: mark_remediated,
+// list_retests, set_finding_status and list_findings.
+// Remediated findings are retested after retestAfter unless the caller
+// gives another delay.
+func WithFindingTracker(store *tracker.Store, retestAfter time.Duration) ServerOption {
+	return func(o *serverOptions) {
+		o.tracker = store
+		o.retestAfter = retestAfter
+	}
+}
+
+// WithAssetRegistry enables the target_context tool, storing the context it
+// collects in registry
+func WithAssetRegistry(registry *assets.Registry, collector *assets.Collector) ServerOption {
+	return func(o *serverOptions) {
+		o.assets = registry
+		o.collector = collector
+	}
+}
+
+// WithWordlists enables the add_wordlist, list_wordlists and get_wordlist
+// tools, and lets add_template and test_template reference the wordlists
+// in payload variables
+func WithWordlists(store *wordlists.Store) ServerOption {
+	return func(o *serverOptions) {
+		o.wordlists = store
+	}
+}
+
+// WithWorkerPool enables the list_workers tool
+func WithWorkerPool(pool WorkerPool) ServerOption {
+	return func(o *serverOptions) {
+		o.workers = pool
+	}
+}
+
+// WithQuotaTracker enables the scan_usage tool reporting the resources each
+// client's scans used against its quota
+func WithQuotaTracker(quotas *scanner.QuotaTracker) ServerOption {
+	return func(o *serverOptions) {
+		o.quotas = quotas
+	}
+}
+
+// WithEncryptionKey decrypts the encrypted spill files finding resources
+// are read back from
+func WithEncryptionKey(key *encryption.Key) ServerOption {
+	return func(o *serverOptions) {
+		o.encryption = key
+	}
+}
+
+// clientName returns the tenant of the request, or else the name the MCP
+// client gave when it initialized the session, which scans are accounted to
+func clientName(ctx context.Context) string {
+	if name := tenant.FromContext(ctx); name != "" {
+		return name
+	}
+	if session, ok := server.ClientSessionFromContext(ctx).(server.SessionWithClientInfo); ok {
+		return session.GetClientInfo().Name
+	}
+	return ""
+}
+
+// scopeGuard checks the target (or targets) argument of a scan tool against
+// the fixed scope and the client roots before running the tool
+func scopeGuard(options *serverOptions, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	if options.roots == nil && options.scope.Empty() {
+		return handler
+	}
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		argMap, _ := request.Params.Arguments.(map[string]any)
+		var targets []string
+		if target, _ := argMap["target"].(string); target != "" {
+			targets = append(targets, target)
+		}
+		targets = append(targets, stringList(argMap["targets"])...)
+		if err := checkScope(ctx, options, argMap, targets); err != nil {
+			return nil, err
+		}
+		return handler(ctx, request)
+	}
+}
+
+// checkScope checks targets against the fixed scope and the client roots,
+// lifted by the allow_out_of_scope and approval arguments of argMap
+func checkScope(ctx context.Context, options *serverOptions, argMap map[string]any, targets []string) error {
+	for _, target := range targets {
+		if err := options.scope.Enforce(target); err != nil {
+			return err
+		}
+	}
+	if len(targets) == 0 || options.roots == nil {
+		return nil
+	}
+	scope, override := rootsScope(ctx, options, argMap)
+	for _, target := range targets {
+		if err := scope.Check(target, override); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rootsScope returns the scope of the client roots and the override of it
+// given by the allow_out_of_scope and approval arguments of argMap
+func rootsScope(ctx context.Context, options *serverOptions, argMap map[string]any) (*policy.Scope, policy.Override) {
+	waitCtx, cancel := context.WithTimeout(ctx, rootsWaitTimeout)
+	roots := options.roots(waitCtx)
+	cancel()
+
+	uris := make([]string, 0, len(roots))
+	for _, root := range roots {
+		uris = append(uris, root.URI)
+	}
+	allow, _ := argMap["allow_out_of_scope"].(bool)
+	approval, _ := argMap["approval"].(string)
+	return policy.NewScope(uris), policy.Override{Allow: allow, Approval: approval}
+}
+
+// crawlScope limits the URLs the crawl of a scan collects to those inside
+// the fixed scope and the client roots, like its target
+func crawlScope(ctx context.Context, options *serverOptions, request mcp.CallToolRequest) []scanner.ScanOption {
+	argMap, _ := request.Params.Arguments.(map[string]any)
+	if _, crawl := argMap["crawl_depth"]; !crawl || (options.roots == nil && options.scope.Empty()) {
+		return nil
+	}
+	roots, override := &policy.Scope{}, policy.Override{}
+	if options.roots != nil {
+		roots, override = rootsScope(ctx, options, argMap)
+	}
+	return []scanner.ScanOption{scanner.WithCrawlScope(func(url string) bool {
+		return options.scope.Contains(url) && roots.Check(url, override) == nil
+	})}
+}
+
+func NewNucleiMCPServer(service scanner.ScannerService, logger *log.Logger, tm templates.TemplateManager, opts ...ServerOption) *server.MCPServer {
+	options := &serverOptions{
+		policy:     policy.NewTemplatePolicy(policy.DefaultDeniedTags),
+		pager:      NewResultPager(DefaultPageSize, DefaultContinuationTTL),
+		localizer:  i18n.Default(),
+		violations: policy.NewViolationLog(policy.DefaultViolationLimit),
+		defaults:   DefaultScanDefaults,
+		usage:      NewUsageTracker(),
+		index:      scanner.NewTemplateIndex(""),
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
 	mcpServer := server.NewMCPServer(
 		"nuclei-scanner",
 		"1.0.0",
 		server.WithLogging(),
+		server.WithRecovery(),
+		server.WithToolHandlerMiddleware(correlate(logger)),
+		server.WithToolHandlerMiddleware(traceTools()),
+		server.WithToolHandlerMiddleware(trackUsage(options.usage)),
 	)
 
-	mcpServer.AddTool(mcp.NewTool("nuclei_scan",
-		mcp.WithDescription("Performs a Nuclei vulnerability scan on a target"),
-		mcp.WithString("target",
-			mcp.Description("Target URL or IP to scan"),
-			mcp.Required(),
-		),
-		mcp.WithString("severity",
-			mcp.Description("Minimum severity level (info, low, medium, high, critical)"),
-			mcp.DefaultString("info"),
-		),
-		mcp.WithString("protocols",
-			mcp.Description("Protocols to scan (comma-separated: http,https,tcp,etc)"),
-			mcp.DefaultString("http"),
-		),
-		mcp.WithBoolean("thread_safe",
-			mcp.Description("Use thread-safe engine for scanning"),
-		),
-		mcp.WithString("template_ids",
-			mcp.Description("Comma-separated template IDs to run (e.g. \"self-signed-ssl,nameserver-fingerprint\")"),
-		),
-		mcp.WithString("template_id",
-			mcp.Description("Single template ID to run (alternative to template_ids)"),
-		),
-	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		return HandleNucleiScanTool(ctx, request, service, logger)
-	})
+	mcpServer.AddTool(mcp.NewTool("nuclei_scan",
+		mcp.WithDescription("Performs a Nuclei vulnerability scan on a target"),
+		mcp.WithString("target",
+			mcp.Description("Target URL or IP to scan"),
+			mcp.Required(),
+		),
+		mcp.WithString("severity",
+			mcp.Description("Minimum severity level (info, low, medium, high, critical). Defaults to the server's scanner.defaults.severity."),
+		),
+		mcp.WithArray("protocols",
+			mcp.Description("Template protocols to run ("+strings.Join(scanner.SupportedProtocols(), ", ")+"). https is an alias of http: http templates scan https:// targets too. Defaults to the server's scanner.defaults.protocols."),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithArray("tags",
+			mcp.Description("Template tags to run. Defaults to the server's scanner.defaults.tags."),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithArray("exclude_tags",
+			mcp.Description("Template tags to leave out. Defaults to the server's nuclei.default_filters.exclude_tags; pass an empty list to lift them."),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithArray("exclude_ids",
+			mcp.Description("Template IDs to leave out. Defaults to the server's nuclei.default_filters.exclude_ids; pass an empty list to lift them."),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithArray("exclude_severities",
+			mcp.Description("Template severities to leave out. Defaults to the server's nuclei.default_filters.exclude_severities; pass an empty list to lift them."),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithArray("authors",
+			mcp.Description("Only run templates by these authors. Defaults to the server's nuclei.default_filters.authors."),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithNumber("rate_limit",
+			mcp.Description("Maximum requests per second. Defaults to the server's scanner.defaults.rate_limit."),
+		),
+		mcp.WithBoolean("thread_safe",
+			mcp.Description("Use the thread-safe engine, which runs alongside other scans (default). Set to false to use the standard engine; standard scans run one at a time."),
+			mcp.DefaultBool(true),
+		),
+		mcp.WithArray("template_ids",
+			mcp.Description("Template IDs to run (e.g. [\"self-signed-ssl\", \"nameserver-fingerprint\"])"),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithString("template_id",
+			mcp.Description("Single template ID to run (alternative to template_ids)"),
+		),
+		mcp.WithArray("inline_templates",
+			mcp.Description("Template YAML contents to run instead of the template library, to test a hypothesis without saving a template. They are compiled in a temporary directory for this scan only and discarded after it; the profile's severity, protocol, tag and author defaults do not apply to them, while exclusions and the template policy do."),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithBoolean("allow_code_templates",
+			mcp.Description("Run code protocol templates (including flow templates using code). Code templates execute commands on the server host and must be enabled in server config."),
+		),
+		mcp.WithBoolean("allow_unsafe",
+			mcp.Description("Run templates with tags denied by policy (dos, intrusive, fuzz by default). Requires approval."),
+		),
+		mcp.WithString("approval",
+			mcp.Description("Who approved running denied templates, scanning out of scope or scanning the server's own host, and why (e.g. a ticket ID). Required with allow_unsafe, allow_out_of_scope and allow_self_target."),
+		),
+		mcp.WithBoolean("allow_out_of_scope",
+			mcp.Description("Scan a target outside the roots provided by the client. Requires approval."),
+		),
+		mcp.WithBoolean("allow_self_target",
+			mcp.Description("Scan a target that is or resolves to the server's own host, such as localhost. Requires approval."),
+		),
+		mcp.WithArray("extractors",
+			mcp.Description("Extractors run against the target's HTTP response; extracted values are returned alongside findings. Presets: "+strings.Join(scanner.PresetNames(), ", ")+"."),
+			mcp.Items(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"name":       map[string]any{"type": "string", "description": "Label for the extracted values"},
+					"type":       map[string]any{"type": "string", "enum": []string{scanner.ExtractorRegex, scanner.ExtractorJSON, scanner.ExtractorPreset}},
+					"expression": map[string]any{"type": "string", "description": "Regex, JSON path (e.g. .version) or preset name"},
+					"part":       map[string]any{"type": "string", "description": "Response part: body (default), header or response"},
+					"group":      map[string]any{"type": "number", "description": "Regex capture group to return"},
+				},
+				"required": []string{"type", "expression"},
+			}),
+		),
+		mcp.WithBoolean("passive",
+			mcp.Description("Low-impact mode: only DNS and SSL templates, one at a time under a strict rate limit. Defaults to the server's scanner.passive_by_default setting."),
+		),
+		mcp.WithString("priority",
+			mcp.Description("Scan queue priority: interactive (default) scans start before waiting background scans. Use background for scheduled or bulk sweeps."),
+			mcp.Enum(scanner.PriorityInteractive.String(), scanner.PriorityBackground.String()),
+		),
+		mcp.WithBoolean("dry_run",
+			mcp.Description("Do not scan: load the templates the scan would run and estimate its requests and duration from the rate limit and the run times of the templates in past scans. Use it to check the cost of a large scan before running it."),
+		),
+		mcp.WithString("strategy",
+			mcp.Description("Set to severity_tiers to split the scan into parallel sub-scans per severity tier (critical/high, then medium/low, then info). With a progress token, each tier's findings are sent as a progress notification as soon as the tier completes, before the long tail finishes. Needs the thread-safe engine."),
+			mcp.Enum(scanner.StrategySeverityTiers),
+		),
+		mcp.WithBoolean("stop_on_first_match",
+			mcp.Description("End the scan at its first finding and return it right away, for yes/no exposure checks. The remaining templates are not run."),
+		),
+		mcp.WithBoolean("cluster",
+			mcp.Description("Group findings with the same root cause (the same template and matcher on different endpoints) into one finding listing the affected endpoints"),
+		),
+		mcp.WithString("stop_at_severity",
+			mcp.Description("End the scan at its first finding of this severity or above and return right away, e.g. critical to answer whether a host is critically exposed. The remaining templates are not run."),
+			mcp.Enum("info", "low", "medium", "high", "critical"),
+		),
+		mcp.WithString("template_timeout",
+			mcp.Description("Stop any template still running against the target after this duration, such as 30s or 2m, so one slow template cannot take up the scan. Capped at the server's scanner.safety.template_timeout."),
+		),
+		mcp.WithNumber("max_template_requests",
+			mcp.Description("Skip templates that would send more requests than this to the target, such as large fuzzing or brute force templates. Capped at the server's scanner.safety.max_template_requests."),
+		),
+		mcp.WithObject("labels",
+			mcp.Description("Labels stored with the scan result and included in its exports, for filtering downstream (e.g. {\"environment\": \"staging\", \"ticket\": \"SEC-123\"})"),
+			mcp.AdditionalProperties(map[string]any{"type": "string"}),
+		),
+		mcp.WithObject("variables",
+			mcp.Description("Variables templates refer to, such as {{username}}, for credentialed scans (e.g. {\"username\": \"admin\", \"password\": \"secret://db-admin\"}). Reference server-side secrets with secret://name instead of passing credentials; their values are never returned."),
+			mcp.AdditionalProperties(map[string]any{"type": "string"}),
+		),
+		mcp.WithString("address_family",
+			mcp.Description("IP versions HTTP templates connect to the target's host name over: v4, v6, or dual to run them over both on dual-stack hosts. Omit to use the address the resolver returns. IPv6 targets may be given bare (2001:db8::1), bracketed with a port ([2001:db8::1]:8443) or with a zone (fe80::1%eth0)."),
+			mcp.Enum("v4", "v6", "dual"),
+		),
+		mcp.WithString("sni",
+			mcp.Description("TLS server name to send instead of the target's host, for example the site's domain when scanning its origin IP behind a CDN or WAF"),
+		),
+		mcp.WithString("host_header",
+			mcp.Description("Host header HTTP templates send, with an optional port, while connecting to the target (e.g. target https://203.0.113.10 with host_header www.example.com to check whether findings are reachable on the origin directly). Also sent as the TLS server name unless sni is set."),
+		),
+		mcp.WithBoolean("detect_waf",
+			mcp.Description("Fingerprint the WAF in front of the target before scanning and flag findings whose responses look like its block pages. Unless passive, sends a request with attack patterns to check whether the WAF blocks them, in which case an empty result does not mean the target is not vulnerable."),
+		),
+		mcp.WithNumber("crawl_depth",
+			mcp.Description(fmt.Sprintf("Crawl the target's links this many pages deep (at most %d) before scanning, and run HTTP templates against each URL found on the target's host too, for coverage beyond the base URL. Not allowed in passive mode.", scanner.MaxCrawlDepth)),
+		),
+		mcp.WithNumber("crawl_limit",
+			mcp.Description(fmt.Sprintf("Maximum URLs the crawl collects (default %d, at most %d)", scanner.DefaultCrawlLimit, scanner.MaxCrawlLimit)),
+		),
+		mcp.WithBoolean("probe_http_versions",
+			mcp.Description("Also report whether the target negotiates HTTP/2 and advertises HTTP/3 (QUIC) in its Alt-Svc header, to tell whether templates need a newer protocol stack. Sends one extra HTTP request; not allowed in passive mode."),
+		),
+		mcp.WithNumber("page_size",
+			mcp.Description(fmt.Sprintf("Findings returned in this response (default %d). Remaining findings are fetched with fetch_more_results.", DefaultPageSize)),
+		),
+		mcp.WithString("format",
+			mcp.Description("Result format: text, or json for a machine-readable object. Defaults to the server's scanner.defaults.format."),
+			mcp.Enum(FormatText, FormatJSON),
+		),
+	), structuredErrors(recordViolations(options, elicitTarget(options, scopeGuard(options, inlineTemplateGuard(options, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return HandleNucleiScanTool(ctx, request, service, logger, options.pager, options.defaults, crawlScope(ctx, options, request)...)
+	}))))))
+
+	multiScanner := scanner.NewMultiScanner(service, options.concurrency)
+	mcpServer.AddTool(mcp.NewTool("nuclei_scan_targets",
+		mcp.WithDescription("Scans many targets in parallel, limited per host and overall, and returns the findings grouped by host."),
+		mcp.WithArray("targets",
+			mcp.Description("Target URLs or IPs to scan"),
+			mcp.Items(map[string]any{"type": "string"}),
+			mcp.Required(),
+		),
+		mcp.WithString("severity",
+			mcp.Description("Minimum severity level (info, low, medium, high, critical). Defaults to the server's scanner.defaults.severity."),
+		),
+		mcp.WithArray("protocols",
+			mcp.Description("Template protocols to run ("+strings.Join(scanner.SupportedProtocols(), ", ")+"). https is an alias of http: http templates scan https:// targets too. Defaults to the server's scanner.defaults.protocols."),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithArray("tags",
+			mcp.Description("Template tags to run. Defaults to the server's scanner.defaults.tags."),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithArray("exclude_tags",
+			mcp.Description("Template tags to leave out. Defaults to the server's nuclei.default_filters.exclude_tags; pass an empty list to lift them."),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithArray("exclude_ids",
+			mcp.Description("Template IDs to leave out. Defaults to the server's nuclei.default_filters.exclude_ids; pass an empty list to lift them."),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithArray("exclude_severities",
+			mcp.Description("Template severities to leave out. Defaults to the server's nuclei.default_filters.exclude_severities; pass an empty list to lift them."),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithArray("authors",
+			mcp.Description("Only run templates by these authors. Defaults to the server's nuclei.default_filters.authors."),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithNumber("rate_limit",
+			mcp.Description("Maximum requests per second. Defaults to the server's scanner.defaults.rate_limit."),
+		),
+		mcp.WithArray("template_ids",
+			mcp.Description("Template IDs to run"),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithBoolean("passive",
+			mcp.Description("Low-impact mode: only DNS and SSL templates, one at a time under a strict rate limit. Defaults to the server's scanner.passive_by_default setting."),
+		),
+		mcp.WithString("priority",
+			mcp.Description("Scan queue priority: interactive (default) scans start before waiting background scans. Use background for scheduled or bulk sweeps."),
+			mcp.Enum(scanner.PriorityInteractive.String(), scanner.PriorityBackground.String()),
+		),
+		mcp.WithBoolean("allow_out_of_scope",
+			mcp.Description("Scan targets outside the roots provided by the client. Requires approval."),
+		),
+		mcp.WithBoolean("allow_self_target",
+			mcp.Description("Scan targets that are or resolve to the server's own host, such as localhost. Requires approval."),
+		),
+		mcp.WithString("approval",
+			mcp.Description("Who approved scanning out of scope or scanning the server's own host, and why (e.g. a ticket ID). Required with allow_out_of_scope and allow_self_target."),
+		),
+		mcp.WithObject("labels",
+			mcp.Description("Labels stored with the scan result and included in its exports, for filtering downstream (e.g. {\"environment\": \"staging\", \"ticket\": \"SEC-123\"})"),
+			mcp.AdditionalProperties(map[string]any{"type": "string"}),
+		),
+		mcp.WithObject("variables",
+			mcp.Description("Variables templates refer to, such as {{username}}, for credentialed scans (e.g. {\"username\": \"admin\", \"password\": \"secret://db-admin\"}). Reference server-side secrets with secret://name instead of passing credentials; their values are never returned."),
+			mcp.AdditionalProperties(map[string]any{"type": "string"}),
+		),
+		mcp.WithString("address_family",
+			mcp.Description("IP versions HTTP templates connect to the target's host name over: v4, v6, or dual to run them over both on dual-stack hosts. Omit to use the address the resolver returns. IPv6 targets may be given bare (2001:db8::1), bracketed with a port ([2001:db8::1]:8443) or with a zone (fe80::1%eth0)."),
+			mcp.Enum("v4", "v6", "dual"),
+		),
+		mcp.WithString("sni",
+			mcp.Description("TLS server name to send instead of the target's host, for example the site's domain when scanning its origin IP behind a CDN or WAF"),
+		),
+		mcp.WithString("host_header",
+			mcp.Description("Host header HTTP templates send, with an optional port, while connecting to the target (e.g. target https://203.0.113.10 with host_header www.example.com to check whether findings are reachable on the origin directly). Also sent as the TLS server name unless sni is set."),
+		),
+	), structuredErrors(recordViolations(options, scopeGuard(options, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return HandleMultiScanTool(ctx, request, multiScanner, options.defaults)
+	}))))
+
+	mcpServer.AddTool(mcp.NewTool("cloud_scan",
+		mcp.WithDescription("Scans cloud endpoints for cloud and Kubernetes misconfigurations, such as exposed cluster dashboards and APIs, instance metadata endpoints and public storage buckets, and returns the findings tagged by cloud provider."),
+		mcp.WithArray("targets",
+			mcp.Description("Cloud endpoints to scan: cluster API servers, dashboards, bucket URLs or load balancer hosts"),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithBoolean("from_assets",
+			mcp.Description("Also scan the hosts of the asset registry and the names certificate transparency logs hold for them"),
+		),
+		mcp.WithString("severity",
+			mcp.Description("Minimum severity level (info, low, medium, high, critical). Defaults to the server's scanner.defaults.severity."),
+		),
+		mcp.WithArray("tags",
+			mcp.Description("Template tags to run instead of the cloud preset ("+strings.Join(scanner.CloudTags, ", ")+")"),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithNumber("rate_limit",
+			mcp.Description("Maximum requests per second. Defaults to the server's scanner.defaults.rate_limit."),
+		),
+		mcp.WithArray("template_ids",
+			mcp.Description("Template IDs to run"),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithString("priority",
+			mcp.Description("Scan queue priority: interactive (default) scans start before waiting background scans. Use background for scheduled or bulk sweeps."),
+			mcp.Enum(scanner.PriorityInteractive.String(), scanner.PriorityBackground.String()),
+		),
+		mcp.WithBoolean("allow_out_of_scope",
+			mcp.Description("Scan targets outside the roots provided by the client. Requires approval."),
+		),
+		mcp.WithBoolean("allow_self_target",
+			mcp.Description("Scan targets that are or resolve to the server's own host, such as localhost. Requires approval."),
+		),
+		mcp.WithString("approval",
+			mcp.Description("Who approved scanning out of scope or scanning the server's own host, and why (e.g. a ticket ID). Required with allow_out_of_scope and allow_self_target."),
+		),
+		mcp.WithObject("labels",
+			mcp.Description("Labels stored with the scan result and included in its exports (e.g. {\"environment\": \"production\"})"),
+			mcp.AdditionalProperties(map[string]any{"type": "string"}),
+		),
+	), structuredErrors(recordViolations(options, cloudTargets(options.assets, scopeGuard(options, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return HandleCloudScanTool(ctx, request, multiScanner, options.defaults)
+	})))))
+
+	mcpServer.AddTool(mcp.NewTool("scan_openapi",
+		mcp.WithDescription("Scans the operations of an OpenAPI 3 or Swagger 2.0 document with their methods, paths and parameters, for far better API coverage than scanning the base URL. HTTP templates run against each operation, or with fuzz, DAST templates fuzz each operation's parameters, headers and body."),
+		mcp.WithString("spec",
+			mcp.Description("The OpenAPI or Swagger document, in JSON or YAML. Alternative to spec_url."),
+		),
+		mcp.WithString("spec_url",
+			mcp.Description("URL the server fetches the OpenAPI or Swagger document from, checked like a scan target. Redirects to other hosts are refused. Alternative to spec."),
+		),
+		mcp.WithString("target",
+			mcp.Description("Base URL or host the operations are sent to. The document's server path is appended unless the target has a path. Defaults to the document's first server URL."),
+		),
+		mcp.WithObject("parameters",
+			mcp.Description("Values of the document's parameters and security schemes, such as an API key or Authorization header (e.g. {\"api_key\": \"secret://payments-api\", \"userId\": \"42\"}). Security schemes the API requires must be set. Other parameters default to the examples in the document."),
+			mcp.AdditionalProperties(map[string]any{"type": "string"}),
+		),
+		mcp.WithBoolean("fuzz",
+			mcp.Description("Run DAST fuzzing templates against each operation instead of regular HTTP templates. Fuzzing templates tagged fuzz need allow_unsafe."),
+		),
+		mcp.WithString("severity",
+			mcp.Description("Minimum severity level (info, low, medium, high, critical). Defaults to the server's scanner.defaults.severity."),
+		),
+		mcp.WithArray("tags",
+			mcp.Description("Template tags to run. Defaults to the server's scanner.defaults.tags."),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithArray("template_ids",
+			mcp.Description("Template IDs to run"),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithNumber("rate_limit",
+			mcp.Description("Maximum requests per second. Defaults to the server's scanner.defaults.rate_limit."),
+		),
+		mcp.WithBoolean("allow_unsafe",
+			mcp.Description("Run templates with tags denied by policy (dos, intrusive, fuzz by default). Requires approval."),
+		),
+		mcp.WithString("approval",
+			mcp.Description("Who approved running denied templates, scanning out of scope or scanning the server's own host, and why (e.g. a ticket ID). Required with allow_unsafe, allow_out_of_scope and allow_self_target."),
+		),
+		mcp.WithBoolean("allow_out_of_scope",
+			mcp.Description("Scan a target outside the roots provided by the client. Requires approval."),
+		),
+		mcp.WithBoolean("allow_self_target",
+			mcp.Description("Scan a target that is or resolves to the server's own host, such as localhost. Requires approval."),
+		),
+		mcp.WithString("template_timeout",
+			mcp.Description("Stop any template still running against an operation after this duration, such as 30s or 2m. Capped at the server's scanner.safety.template_timeout."),
+		),
+		mcp.WithNumber("max_template_requests",
+			mcp.Description("Skip templates that would send more requests than this to each operation. Capped at the server's scanner.safety.max_template_requests."),
+		),
+		mcp.WithObject("labels",
+			mcp.Description("Labels stored with the scan result and included in its exports (e.g. {\"environment\": \"staging\"})"),
+			mcp.AdditionalProperties(map[string]any{"type": "string"}),
+		),
+		mcp.WithNumber("page_size",
+			mcp.Description(fmt.Sprintf("Findings returned in this response (default %d). Remaining findings are fetched with fetch_more_results.", DefaultPageSize)),
+		),
+		mcp.WithString("format",
+			mcp.Description("Result format: text, or json for a machine-readable object. Defaults to the server's scanner.defaults.format."),
+			mcp.Enum(FormatText, FormatJSON),
+		),
+	), structuredErrors(recordViolations(options, openAPIInput(options, service, scopeGuard(options, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return HandleOpenAPIScanTool(ctx, request, service, logger, options.pager, options.defaults)
+	})))))
+
+	mcpServer.AddTool(mcp.NewTool("fetch_more_results",
+		mcp.WithDescription("Fetches the next page of findings from a scan whose results were split across responses."),
+		mcp.WithString("continuation_token", mcp.Description("Token returned by nuclei_scan or a previous fetch_more_results call."), mcp.Required()),
+		mcp.WithNumber("page_size", mcp.Description(fmt.Sprintf("Findings to return (default %d).", DefaultPageSize))),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return HandleFetchMoreResults(ctx, request, options.pager)
+	})
+
+	if reporter, ok := service.(scanner.ProgressReporter); ok {
+		mcpServer.AddTool(mcp.NewTool("scan_status",
+			mcp.WithDescription("Reports the scans running on the server with their templates done, percent complete and estimated remaining time, estimated from the run times of their templates in past scans. Use it to decide whether to wait for a scan or come back later."),
+			mcp.WithString("target", mcp.Description("Only report scans of this target")),
+			mcp.WithString("correlation_id", mcp.Description("Only report the scan of this request")),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return HandleScanStatus(ctx, request, reporter)
+		})
+	}
+
+	mcpServer.AddTool(mcp.NewTool("basic_scan",
+		mcp.WithDescription("Performs a basic Nuclei vulnerability scan on a target without requiring template IDs"),
+		mcp.WithString("target",
+			mcp.Description("Target URL or IP to scan"),
+			mcp.Required(),
+		),
+		mcp.WithBoolean("allow_out_of_scope",
+			mcp.Description("Scan a target outside the roots provided by the client. Requires approval."),
+		),
+		mcp.WithString("approval",
+			mcp.Description("Who approved scanning an out-of-scope target and why. Required with allow_out_of_scope."),
+		),
+	), structuredErrors(recordViolations(options, elicitTarget(options, scopeGuard(options, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return HandleBasicScanTool(ctx, request, service, logger)
+	})))))
+
+	mcpServer.AddResource(mcp.NewResource("vulnerabilities", "Recent Vulnerability Reports"),
+		func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			return HandleVulnerabilityResource(ctx, request, service, logger)
+		})
+
+	mcpServer.AddResourceTemplate(mcp.NewResourceTemplate("finding://{fingerprint}", "Finding Detail",
+		mcp.WithTemplateDescription("Full detail of a cached finding linked from scan results: evidence request and response, curl command, remediation and references"),
+		mcp.WithTemplateMIMEType("application/json"),
+	), func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		return HandleFindingResource(ctx, request, service, options.encryption, options.tracker)
+	})
+
+	mcpServer.AddResourceTemplate(mcp.NewResourceTemplate("evidence://{fingerprint}/{name}", "Finding Evidence",
+		mcp.WithTemplateDescription("Evidence of a cached finding as a base64 blob: request, response, body (with the response's content type) or har, as listed in the finding's evidence"),
+	), func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		return HandleEvidenceResource(ctx, request, service, options.encryption)
+	})
+
+	mcpServer.AddTool(mcp.NewTool("cross_reference",
+		mcp.WithDescription("Lists the local templates and the cached findings related to a CVE or CWE, for pivoting during incident response: templates classified with it (or, for CVEs, named after it) and findings of those templates or classified with it, most recent first, with their tracked status."),
+		mcp.WithString("id", mcp.Description("CVE or CWE ID, e.g. CVE-2021-44228 or CWE-79"), mcp.Required()),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return HandleCrossReference(ctx, request, service, options.index, xrefDirs(options), options.tracker)
+	})
+
+	mcpServer.AddTool(mcp.NewTool("next_steps",
+		mcp.WithDescription("Recommends follow-up templates for a scanned target from the technologies its cached findings revealed, such as the WordPress plugin and CVE templates after WordPress was detected. Templates are matched by tag, most severe first, and those that already reported on the target are left out. Returns the tags to pass to nuclei_scan to run them."),
+		mcp.WithString("target", mcp.Description("Scan target with cached results, as passed to nuclei_scan"), mcp.Required()),
+		mcp.WithNumber("limit", mcp.Description(fmt.Sprintf("Maximum number of templates to recommend (default %d)", DefaultNextStepsLimit))),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return HandleNextSteps(ctx, request, service, options.index, xrefDirs(options))
+	})
+
+	mcpServer.AddTool(mcp.NewTool("compare_environments",
+		mcp.WithDescription("Compares the cached findings of two environments of the same application, such as staging and production, told apart by a scan label. Findings match by template and path whatever the host, and the findings only one environment has are listed."),
+		mcp.WithArray("environments",
+			mcp.Description("The two values of the environment label to compare, e.g. [\"staging\", \"production\"]"),
+			mcp.Items(map[string]any{"type": "string"}),
+			mcp.Required(),
+		),
+		mcp.WithString("label", mcp.Description("Scan label holding the environment (default "+DefaultEnvironmentLabel+")")),
+		mcp.WithObject("labels",
+			mcp.Description("Only compare scans carrying these labels too, such as the application (e.g. {\"app\": \"shop\"})"),
+			mcp.AdditionalProperties(map[string]any{"type": "string"}),
+		),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return HandleCompareEnvironments(ctx, request, service)
+	})
+
+	mcpServer.AddResourceTemplate(mcp.NewResourceTemplate("xref://{id}", "CVE and CWE Cross Reference",
+		mcp.WithTemplateDescription("Local templates and cached findings related to a CVE or CWE ID, as returned by cross_reference"),
+		mcp.WithTemplateMIMEType("application/json"),
+	), func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		return HandleCrossReferenceResource(ctx, request, service, options.index, xrefDirs(options), options.tracker)
+	})
+
+	mcpServer.AddResource(mcp.NewResource("catalog", "Template Catalog Statistics",
+		mcp.WithResourceDescription("Summary of the templates scans load: counts by severity, protocol and tag, the newest templates and the templates of the latest CVEs, for judging coverage before choosing scan filters"),
+		mcp.WithMIMEType("application/json"),
+	), func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		return HandleCatalogResource(ctx, request, options.index, xrefDirs(options))
+	})
+
+	mcpServer.AddResource(mcp.NewResource("trends", "Finding Severity Trends",
+		mcp.WithResourceDescription("Daily counts of findings by severity per target, with whether each target is improving or worsening"),
+		mcp.WithMIMEType("application/json"),
+	), func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		return HandleTrendsResource(ctx, request, service)
+	})
+
+	mcpServer.AddTool(mcp.NewTool("finding_trends",
+		mcp.WithDescription("Returns time-series counts of findings by severity per target from cached scan results, and whether each target is improving or worsening since its previous interval."),
+		mcp.WithString("target", mcp.Description("Only return the trend of this target")),
+		mcp.WithString("interval", mcp.Description("Width of each point as a duration such as 1h or 168h (default 24h)")),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return HandleFindingTrends(ctx, request, service)
+	})
+
+	mcpServer.AddResource(mcp.NewResource("usage", "Tool Usage Statistics",
+		mcp.WithResourceDescription("Calls, error rates, error codes and latency percentiles of each tool since the server started, most called first, showing how agents use the server and where failures concentrate"),
+		mcp.WithMIMEType("application/json"),
+	), func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		return HandleUsageResource(ctx, request, options.usage)
+	})
+
+	mcpServer.AddResource(mcp.NewResource("dashboard", "Executive Dashboard",
+		mcp.WithResourceDescription("Self-contained HTML page summarizing findings by severity, the most vulnerable hosts, recent scans and policy violations"),
+		mcp.WithMIMEType("text/html"),
+	), func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		return HandleDashboardResource(ctx, request, service, options.violations)
+	})
+
+	summarizer := triage.NewSummarizer(options.sampler, triage.WithLocalizer(options.localizer))
+	mcpServer.AddTool(mcp.NewTool("summarize_findings",
+		mcp.WithDescription("Summarizes cached scan results into an executive summary and prioritized next actions. Uses the client's model through MCP sampling when supported, otherwise a server-generated summary."),
+		mcp.WithString("target", mcp.Description("Only summarize results for this target")),
+		mcp.WithNumber("max_findings", mcp.Description("Maximum findings sent to the model, most severe first (default 50)")),
+		mcp.WithString("language", mcp.Description("Summary language ("+strings.Join(i18n.Supported(), ", ")+"); defaults to report.language")),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return HandleSummarizeFindings(ctx, request, service, summarizer)
+	})
+
+	mcpServer.AddTool(mcp.NewTool("generate_report",
+		mcp.WithDescription("Generates a Markdown report of cached scan results with localized section headers and severity labels."),
+		mcp.WithString("target", mcp.Description("Only include results for this target")),
+		mcp.WithString("language", mcp.Description("Report language ("+strings.Join(i18n.Supported(), ", ")+"); defaults to report.language")),
+		mcp.WithBoolean("cluster", mcp.Description("List findings with the same root cause once, with their affected endpoints")),
+		mcp.WithObject("labels", mcp.Description("Only include results of scans carrying all of these labels"), mcp.AdditionalProperties(map[string]any{"type": "string"})),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return HandleGenerateReport(ctx, request, service, report.NewGenerator(options.localizer))
+	})
+
+	mcpServer.AddTool(mcp.NewTool("compliance_summary",
+		mcp.WithDescription("Maps the cached findings of each target to OWASP Top 10, PCI DSS and NIST SP 800-53 controls by their CWE IDs and tags, with the findings, severities and templates under each control."),
+		mcp.WithString("target", mcp.Description("Only summarize this target")),
+		mcp.WithString("framework", mcp.Description("Only report this framework"), mcp.Enum(compliance.Frameworks...)),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return HandleComplianceSummary(ctx, request, service)
+	})
+
+	mcpServer.AddTool(mcp.NewTool("add_template",
+		mcp.WithDescription("Adds a new Nuclei template."),
+		mcp.WithString("name", mcp.Description("The name of the template file."), mcp.Required()),
+		mcp.WithString("content", mcp.Description("The content of the template file, in YAML or JSON. Content in another format than the name's extension (.yaml, .yml or .json) is converted; a name without an extension gets the content's."), mcp.Required()),
+		mcp.WithBoolean("allow_unsafe", mcp.Description("Allow a template with tags denied by policy (dos, intrusive, fuzz by default). Requires approval.")),
+		mcp.WithString("approval", mcp.Description("Who approved adding a denied template and why. Required with allow_unsafe.")),
+		mcp.WithString("signature", mcp.Description("Detached minisign signature of the content, when the server verifies minisign signatures.")),
+	), recordViolations(options, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return HandleAddTemplate(ctx, request, tm, options.policy, options.verifier, options.wordlists)
+	}))
+
+	mcpServer.AddTool(mcp.NewTool("list_templates",
+		mcp.WithDescription("Lists all available Nuclei templates."),
+		mcp.WithString("query", mcp.Description("Only list templates whose name, namespace, ID or a tag contains this text")),
+		mcp.WithBoolean("details", mcp.Description("Return the indexed metadata of each template as JSON: ID, tags, namespace, SHA-256 hash and parse status")),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return HandleListTemplates(ctx, request, tm)
+	})
+
+	mcpServer.AddTool(mcp.NewTool("get_template",
+		mcp.WithDescription("Gets the content of a specific Nuclei template."),
+		mcp.WithString("name", mcp.Description("The name of the template file."), mcp.Required()),
+		mcp.WithString("format", mcp.Description("Convert the template to this format instead of returning it as stored"), mcp.Enum(templates.FormatYAML, templates.FormatJSON)),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return HandleGetTemplate(ctx, request, tm)
+	})
+
+	mcpServer.AddTool(mcp.NewTool("get_engine_template",
+		mcp.WithDescription("Gets a template by its nuclei ID from any template directory scans load, official or custom, as JSON with its content, file path and source directory. When several directories hold the ID, the first one wins, as in the catalog, and the others are listed as shadowed."),
+		mcp.WithString("id", mcp.Description("The template's nuclei ID, e.g. CVE-2021-44228"), mcp.Required()),
+		mcp.WithString("format", mcp.Description("Convert the template to this format instead of returning it as stored"), mcp.Enum(templates.FormatYAML, templates.FormatJSON)),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return HandleGetEngineTemplate(ctx, request, options.index, xrefDirs(options))
+	})
+
+	mcpServer.AddTool(mcp.NewTool("template_history",
+		mcp.WithDescription("Lists the saved versions of a custom template as JSON: version number, SHA-256 hash, size, when it was saved and which version the template file currently matches. A version is saved every time the template is added or updated."),
+		mcp.WithString("name", mcp.Description("The name of the template file."), mcp.Required()),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return HandleTemplateHistory(ctx, request, tm)
+	})
+
+	mcpServer.AddTool(mcp.NewTool("diff_template",
+		mcp.WithDescription("Shows a unified diff between two versions of a custom template, by default between the template as it is now and its previous version."),
+		mcp.WithString("name", mcp.Description("The name of the template file."), mcp.Required()),
+		mcp.WithNumber("from", mcp.Description("Version to diff from. Defaults to the newest version before to with a different content.")),
+		mcp.WithNumber("to", mcp.Description("Version to diff to. Defaults to the template as it is now.")),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return HandleDiffTemplate(ctx, request, tm)
+	})
+
+	mcpServer.AddTool(mcp.NewTool("rollback_template",
+		mcp.WithDescription("Restores a saved version of a custom template. The replaced content stays in the template's history, so a rollback can itself be rolled back."),
+		mcp.WithString("name", mcp.Description("The name of the template file."), mcp.Required()),
+		mcp.WithNumber("version", mcp.Description("The version to restore, as listed by template_history."), mcp.Required()),
+		mcp.WithBoolean("allow_unsafe", mcp.Description("Allow restoring a template with tags denied by policy. Requires approval.")),
+		mcp.WithString("approval", mcp.Description("Who approved restoring a denied template and why. Required with allow_unsafe.")),
+	), recordViolations(options, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return HandleRollbackTemplate(ctx, request, tm, options.policy)
+	}))
+
+	mcpServer.AddTool(mcp.NewTool("test_template",
+		mcp.WithDescription("Runs a Nuclei template against a built-in sandbox HTTP server with canned responses and returns the match results, without touching real targets."),
+		mcp.WithString("content", mcp.Description("Template YAML to test (alternative to name).")),
+		mcp.WithString("name", mcp.Description("Name of a saved custom template to test (alternative to content).")),
+		mcp.WithArray("responses",
+			mcp.Description("Canned responses served by the sandbox. The first response whose method and path match the request is returned; unmatched requests get a 404. Defaults to an empty 200 for every request."),
+			mcp.Items(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"method":  map[string]any{"type": "string", "description": "HTTP method to match (empty matches any)"},
+					"path":    map[string]any{"type": "string", "description": "Request path to match (empty matches any)"},
+					"status":  map[string]any{"type": "number", "description": "Status code (default 200)"},
+					"headers": map[string]any{"type": "object", "description": "Response headers", "additionalProperties": map[string]any{"type": "string"}},
+					"body":    map[string]any{"type": "string", "description": "Response body"},
+				},
+			}),
+		),
+		mcp.WithBoolean("trace",
+			mcp.Description("Include a matcher trace showing which matchers and extractors evaluated true/false and what they extracted for every served response"),
+		),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return HandleTestTemplate(ctx, request, tm, options.wordlists)
+	})
+
+	mcpServer.AddTool(mcp.NewTool("self_test",
+		mcp.WithDescription("Verifies the scan pipeline after install: scans a built-in local test server with a bundled template suite and reports pass/fail for the engine, template matching and extraction, false positives and the result cache."),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return HandleSelfTest(ctx, request, service)
+	})
+
+	mcpServer.AddTool(mcp.NewTool("benchmark",
+		mcp.WithDescription("Measures scan throughput on this server: runs a synthetic scan against a built-in local test server at each combination of concurrency and rate limit, and reports requests/sec, scans/min and the scan latency distribution, to help tune scanner.global_concurrency, scanner.queue.slots and rate limits for the host."),
+		mcp.WithArray("concurrency",
+			mcp.Description("Numbers of scans to run at once (default 1, 2, 4 and 8)"),
+			mcp.Items(map[string]any{"type": "integer"}),
+		),
+		mcp.WithArray("rate_limits",
+			mcp.Description("Requests per second of each scan to measure; 0 keeps nuclei's default (the default)"),
+			mcp.Items(map[string]any{"type": "integer"}),
+		),
+		mcp.WithNumber("scans",
+			mcp.Description(fmt.Sprintf("Scans to run for each setting (default %d)", benchmark.DefaultScans)),
+		),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return HandleBenchmark(ctx, request, service)
+	})
+
+	if options.workspace != nil {
+		ws := options.workspace
+
+		mcpServer.AddTool(mcp.NewTool("backup_workspace",
+			mcp.WithDescription("Exports cached scan results, custom templates, exclusion rules, scheduled scans and the statuses and risk acceptances of tracked findings into a single archive for migration or disaster recovery."),
+			mcp.WithString("path", mcp.Description("Path of the archive (.tar.gz) to write, relative to the server's export directory."), mcp.Required()),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return HandleBackupWorkspace(ctx, request, ws)
+		})
+
+		mcpServer.AddTool(mcp.NewTool("restore_workspace",
+			mcp.WithDescription("Restores cached scan results, custom templates, exclusion rules, scheduled scans and tracked findings from a workspace archive. Exclusion rules and scheduled scans are merged into the existing ones."),
+			mcp.WithString("path", mcp.Description("Path of the archive to restore, relative to the server's export directory."), mcp.Required()),
+			mcp.WithBoolean("overwrite", mcp.Description("Replace existing templates, results and tracked findings with the same name")),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return HandleRestoreWorkspace(ctx, request, ws)
+		})
+
+		mcpServer.AddTool(mcp.NewTool("export_templates",
+			mcp.WithDescription("Packages templates with their metadata (ID, tags, format, SHA-256 hash, source) and detached signatures into a portable bundle that another nuclei-mcp instance can import with import_templates, or load as an offline template bundle. Without names or query, every custom template is exported."),
+			mcp.WithString("path", mcp.Description("Path of the bundle (.tar.gz) to write, relative to the server's export directory."), mcp.Required()),
+			mcp.WithArray("names", mcp.Description("Templates to export, as listed by list_templates"), mcp.Items(map[string]any{"type": "string"})),
+			mcp.WithString("query", mcp.Description("Export the templates whose name, namespace, ID or a tag contains this text")),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return HandleExportTemplates(ctx, request, ws)
+		})
+
+		mcpServer.AddTool(mcp.NewTool("import_templates",
+			mcp.WithDescription("Imports the templates of a bundle written by export_templates into the custom templates directory. Templates are checked against their manifest hashes, the template policy and signature verification before any is imported."),
+			mcp.WithString("path", mcp.Description("Path of the bundle, relative to the server's export directory."), mcp.Required()),
+			mcp.WithString("namespace", mcp.Description("Directory of the custom templates directory to import into, such as the team the bundle comes from")),
+			mcp.WithBoolean("overwrite", mcp.Description("Replace existing templates with the same name")),
+			mcp.WithBoolean("allow_unsafe", mcp.Description("Allow templates with tags denied by policy. Requires approval.")),
+			mcp.WithString("approval", mcp.Description("Who approved importing denied templates and why. Required with allow_unsafe.")),
+		), recordViolations(options, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return HandleImportTemplates(ctx, request, ws, options.policy, options.verifier)
+		}))
+	}
+
+	if options.exclusions != nil {
+		store := options.exclusions
+
+		mcpServer.AddTool(mcp.NewTool("add_exclusion",
+			mcp.WithDescription("Stops templates from running against matching targets in every future scan, for known-noisy template/target combinations."),
+			mcp.WithString("target", mcp.Description("Target pattern; * matches any characters. Patterns with a scheme (https://app.example.com/*) match the whole target, others match its host (*.example.com)."), mcp.Required()),
+			mcp.WithArray("template_ids", mcp.Description("Template IDs to exclude"), mcp.Items(map[string]any{"type": "string"})),
+			mcp.WithArray("tags", mcp.Description("Template tags to exclude"), mcp.Items(map[string]any{"type": "string"})),
+			mcp.WithString("reason", mcp.Description("Why the combination is excluded (e.g. a false positive ticket)")),
+			mcp.WithString("expires", mcp.Description("When the rule stops applying: a duration such as 720h, a date such as 2025-12-31 or an RFC 3339 time. Omit for a permanent rule.")),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return HandleAddExclusion(ctx, request, store)
+		})
+
+		mcpServer.AddTool(mcp.NewTool("list_exclusions",
+			mcp.WithDescription("Lists the template exclusion rules applied at scan time."),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return HandleListExclusions(ctx, request, store)
+		})
+
+		mcpServer.AddTool(mcp.NewTool("remove_exclusion",
+			mcp.WithDescription("Removes a template exclusion rule."),
+			mcp.WithString("id", mcp.Description("ID of the rule, as returned by add_exclusion or list_exclusions."), mcp.Required()),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return HandleRemoveExclusion(ctx, request, store)
+		})
+
+		mcpServer.AddTool(mcp.NewTool("export_exclusions",
+			mcp.WithDescription("Exports the template exclusion rules as a YAML file of target patterns, template IDs, tags, reasons and expiries, to keep triage decisions under version control."),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return HandleExportExclusions(ctx, request, store)
+		})
+
+		mcpServer.AddTool(mcp.NewTool("import_exclusions",
+			mcp.WithDescription("Imports template exclusion rules from a YAML file written by export_exclusions. Rules excluding the same templates and tags on the same target pattern as a stored one update its reason and expiry."),
+			mcp.WithString("content", mcp.Description("The YAML exclusions file"), mcp.Required()),
+			mcp.WithBoolean("replace", mcp.Description("Remove the stored rules missing from the file, making the file the source of truth")),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return HandleImportExclusions(ctx, request, store)
+		})
+	}
+
+	if options.tracker != nil {
+		store := options.tracker
+
+		mcpServer.AddTool(mcp.NewTool("mark_remediated",
+			mcp.WithDescription("Marks a cached finding as remediated and schedules a scan with its template to verify the fix. The finding is reopened if the retest finds it again."),
+			mcp.WithString("fingerprint", mcp.Description("Fingerprint of the finding, or its finding:// URI"), mcp.Required()),
+			mcp.WithString("retest_after", mcp.Description(fmt.Sprintf("Delay before the retest as a duration such as 30m or 48h (default %s)", options.retestAfter))),
+			mcp.WithString("note", mcp.Description("How the finding was fixed, such as a ticket or release")),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return HandleMarkRemediated(ctx, request, service, store, options.retestAfter, options.encryption)
+		})
+
+		mcpServer.AddTool(mcp.NewTool("list_retests",
+			mcp.WithDescription("Lists remediated findings with their scheduled or completed retest, most recently updated first."),
+			mcp.WithString("result", mcp.Description("Only list retests with this result"),
+				mcp.Enum(tracker.RetestPending, tracker.RetestFixed, tracker.RetestPresent, tracker.RetestFailed)),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return HandleListRetests(ctx, request, store)
+		})
+
+		statuses := make([]string, len(tracker.Statuses))
+		for i, status := range tracker.Statuses {
+			statuses[i] = string(status)
+		}
+		mcpServer.AddTool(mcp.NewTool("set_finding_status",
+			mcp.WithDescription("Moves a cached finding through its lifecycle: new, triaged, accepted-risk, remediated or reopened. Setting remediated schedules a retest like mark_remediated."),
+			mcp.WithString("fingerprint", mcp.Description("Fingerprint of the finding, or its finding:// URI"), mcp.Required()),
+			mcp.WithString("status", mcp.Description("New status of the finding"), mcp.Enum(statuses...), mcp.Required()),
+			mcp.WithString("note", mcp.Description("Why the status changed, such as a ticket or who accepted the risk")),
+			mcp.WithString("accepted_until", mcp.Description("For accepted-risk, when the acceptance expires and the finding is reopened: a duration such as 2160h, a date such as 2025-12-31 or an RFC 3339 time. Without it the acceptance never expires.")),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return HandleSetFindingStatus(ctx, request, service, store, options.retestAfter, options.encryption)
+		})
+
+		mcpServer.AddTool(mcp.NewTool("list_expiring_acceptances",
+			mcp.WithDescription("Lists accepted risks whose acceptance expires soon, soonest first, and the findings already reopened because their acceptance expired, for review before they resurface."),
+			mcp.WithString("within", mcp.Description("How far ahead to look as a duration such as 168h (default 720h)")),
+			mcp.WithBoolean("include_expired", mcp.Description("Also list the findings reopened by an expired acceptance (default true)")),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return HandleListExpiringAcceptances(ctx, request, store)
+		})
+
+		mcpServer.AddTool(mcp.NewTool("list_findings",
+			mcp.WithDescription("Lists cached findings with their lifecycle status, most severe first. Findings nobody has set a status on are new."),
+			mcp.WithArray("status", mcp.Description("Only list findings with these statuses"), mcp.Items(map[string]any{"type": "string", "enum": statuses})),
+			mcp.WithArray("severity", mcp.Description("Only list findings with these severities"), mcp.Items(map[string]any{"type": "string"})),
+			mcp.WithString("target", mcp.Description("Only list findings of this scan target")),
+			mcp.WithString("template_id", mcp.Description("Only list findings of this template")),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return HandleListFindings(ctx, request, service, store)
+		})
+	}
+
+	if options.wordlists != nil {
+		lists := options.wordlists
+
+		mcpServer.AddTool(mcp.NewTool("add_wordlist",
+			mcp.WithDescription("Stores a wordlist for fuzzing templates. Templates added with add_template reference it in a payload variable as wordlist:<name>, for example `paths: wordlist:common-paths`."),
+			mcp.WithString("name", mcp.Description("Wordlist name: letters, digits, '.', '_' and '-'"), mcp.Required()),
+			mcp.WithArray("entries", mcp.Description("Wordlist entries"), mcp.Items(map[string]any{"type": "string"})),
+			mcp.WithString("content", mcp.Description("Wordlist entries, one per line (alternative to entries)")),
+			mcp.WithBoolean("overwrite", mcp.Description("Replace an existing wordlist with the same name")),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return HandleAddWordlist(ctx, request, lists)
+		})
+
+		mcpServer.AddTool(mcp.NewTool("list_wordlists",
+			mcp.WithDescription("Lists the stored wordlists with their number of entries."),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return HandleListWordlists(ctx, request, lists)
+		})
+
+		mcpServer.AddTool(mcp.NewTool("get_wordlist",
+			mcp.WithDescription("Gets the entries of a stored wordlist."),
+			mcp.WithString("name", mcp.Description("Wordlist name"), mcp.Required()),
+			mcp.WithNumber("limit", mcp.Description(fmt.Sprintf("Entries to return (default %d)", defaultWordlistPreview))),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return HandleGetWordlist(ctx, request, lists)
+		})
+	}
+
+	if options.assets != nil {
+		registry, collector := options.assets, options.collector
+
+		mcpServer.AddTool(mcp.NewTool("target_context",
+			mcp.WithDescription("Collects recon context for a target and stores it in the asset registry: its resolved addresses with their reverse DNS names, and the subject, issuer and SANs of the certificate it serves. Optionally lists the names certificate transparency logs hold for its domain."),
+			mcp.WithString("target", mcp.Description("Host or URL; the certificate is read from the URL's port for https URLs, else 443"), mcp.Required()),
+			mcp.WithBoolean("certificate_transparency", mcp.Description("Also look up the names logged for the domain and its subdomains on crt.sh")),
+			mcp.WithString("approval", mcp.Description("Who approved collecting context on a target out of scope, and why. Required with allow_out_of_scope.")),
+			mcp.WithBoolean("allow_out_of_scope", mcp.Description("Collect context on a target outside the roots provided by the client. Requires approval.")),
+		), structuredErrors(recordViolations(options, scopeGuard(options, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return HandleTargetContext(ctx, request, registry, collector)
+		}))))
+
+		mcpServer.AddTool(mcp.NewTool("js_recon",
+			mcp.WithDescription("Fetches the scripts a page loads from its host and returns, as JSON, the API endpoints they call and the keys and tokens embedded in them, with the findings of nuclei's token exposure templates run against each script. The analysis is stored in the host's asset."),
+			mcp.WithString("target", mcp.Description("URL of the page whose scripts are analysed, or of a single .js file"), mcp.Required()),
+			mcp.WithString("severity", mcp.Description("Minimum severity of the exposure templates (info, low, medium, high, critical). Defaults to the server's scanner.defaults.severity.")),
+			mcp.WithArray("tags", mcp.Description("Template tags to run against each script instead of "+strings.Join(jsReconTags, ", ")), mcp.Items(map[string]any{"type": "string"})),
+			mcp.WithNumber("rate_limit", mcp.Description("Maximum requests per second. Defaults to the server's scanner.defaults.rate_limit.")),
+			mcp.WithString("approval", mcp.Description("Who approved analysing a target out of scope, and why. Required with allow_out_of_scope.")),
+			mcp.WithBoolean("allow_out_of_scope", mcp.Description("Analyse a target outside the roots provided by the client. Requires approval.")),
+		), structuredErrors(recordViolations(options, scopeGuard(options, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return HandleJSRecon(ctx, request, registry, collector, multiScanner, options.defaults)
+		}))))
+	}
+
+	if options.workers != nil {
+		pool := options.workers
+
+		mcpServer.AddTool(mcp.NewTool("list_workers",
+			mcp.WithDescription("Lists the scan workers with their health, capacity and running, completed and failed jobs."),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return HandleListWorkers(ctx, request, pool)
+		})
+	}
+
+	if options.quotas != nil {
+		quotas := options.quotas
+
+		mcpServer.AddTool(mcp.NewTool("scan_usage",
+			mcp.WithDescription("Reports the scans, requests, bytes, scan time and CPU time each client used in its current quota period, with its quota."),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return HandleScanUsage(ctx, request, quotas)
+		})
+	}
+
+	if options.maintenance != nil {
+		maintenance := options.maintenance
+
+		mcpServer.AddTool(mcp.NewTool("pause_scanning",
+			mcp.WithDescription("Pauses scanning for maintenance such as template updates: new scans are refused with a MAINTENANCE error until resume_scanning, while scans already running or queued finish. Cached results are still returned. Reports the scans still active."),
+			mcp.WithString("reason", mcp.Description("Why scanning is paused, included in the error of refused scans")),
+			mcp.WithNumber("wait_seconds", mcp.Description("Wait up to this many seconds for the active scans to finish before returning (at most 1800)")),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return HandlePauseScanning(ctx, request, maintenance)
+		})
+
+		mcpServer.AddTool(mcp.NewTool("resume_scanning",
+			mcp.WithDescription("Resumes scanning paused by pause_scanning."),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return HandleResumeScanning(ctx, request, maintenance)
+		})
+	}
+
+	if options.quarantine != nil {
+		quarantine := options.quarantine
+
+		mcpServer.AddTool(mcp.NewTool("list_quarantined_templates",
+			mcp.WithDescription("Lists the templates left out of every scan after erroring at runtime in several scans in a row, as JSON with their error count, last error and when they were quarantined. Errors the target causes, such as refused connections or timeouts, are not counted."),
+			mcp.WithBoolean("watched", mcp.Description("Also list the templates that errored in fewer scans in a row than the threshold")),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return HandleListQuarantinedTemplates(ctx, request, quarantine)
+		})
+
+		mcpServer.AddTool(mcp.NewTool("unquarantine_template",
+			mcp.WithDescription("Lets a quarantined template run in scans again, such as after fixing it, and resets its error count."),
+			mcp.WithString("id", mcp.Description("The template's nuclei ID, as listed by list_quarantined_templates"), mcp.Required()),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return HandleUnquarantineTemplate(ctx, request, quarantine)
+		})
+	}
+
+	if options.blackouts != nil {
+		blackouts := options.blackouts
+
+		mcpServer.AddTool(mcp.NewTool("next_scan_window",
+			mcp.WithDescription("Reports when scheduled scans may run next, as JSON: the blackout in force (such as a deploy freeze or holiday from the blackout calendar), the start of the next window free of blackouts and when the following blackout ends it, and the upcoming blackouts. Only scheduled scans are suspended during blackouts; scans requested by tools still run."),
+			mcp.WithString("after", mcp.Description("RFC 3339 time to look for a window from (default now)")),
+			mcp.WithString("duration", mcp.Description("Length the window must have, such as \"2h\" for a long scan (default any)")),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return HandleNextScanWindow(ctx, request, blackouts)
+		})
+	}
+
+	if options.updater != nil {
+		updater := options.updater
+
+		mcpServer.AddTool(mcp.NewTool("engine_info",
+			mcp.WithDescription("Reports the embedded Nuclei engine version, the installed templates release and whether it matches the pinned version."),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return HandleEngineInfo(ctx, request, updater)
+		})
+
+		mcpServer.AddTool(mcp.NewTool("engine_update",
+			mcp.WithDescription("Downloads a nuclei-templates release into the templates directory. Defaults to the version pinned in config."),
+			mcp.WithString("version", mcp.Description("Templates release tag to install (e.g. v10.1.5). Must match the pinned version when one is configured.")),
+		), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return HandleEngineUpdate(ctx, request, updater)
+		})
+
+		if options.updateCheck {
+			mcpServer.AddTool(mcp.NewTool("check_updates",
+				mcp.WithDescription("Checks GitHub for a newer nuclei-templates release or nuclei engine version than the ones installed, with a summary of their release notes and how to install them. Nothing is downloaded."),
+			), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				return HandleCheckUpdates(ctx, request, updater)
+			})
+		}
+	}
+
+	return mcpServer
+}
+
+func HandleNucleiScanTool(
+	ctx context.Context,
+	request mcp.CallToolRequest,
+	service scanner.ScannerService,
+	_ *log.Logger,
+	pager *ResultPager,
+	defaults ScanDefaults,
+	extra ...scanner.ScanOption,
+) (*mcp.CallToolResult, error) {
+	argMap, ok := request.Params.Arguments.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid arguments format")
+	}
+
+	target, ok := argMap["target"].(string)
+	if !ok || target == "" {
+		return nil, errInvalidTarget
+	}
+
+	inline, err := inlineTemplates(argMap["inline_templates"])
+	if err != nil {
+		return nil, err
+	}
+	if len(inline) > 0 {
+		defaults = defaults.forInline()
+	}
+
+	requestedFormat, _ := argMap["format"].(string)
+	format, err := defaults.format(requestedFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	severity, protocols, scanOpts := defaults.Resolve(argMap)
+	if len(inline) > 0 {
+		scanOpts = append(scanOpts, scanner.WithInlineTemplates(inline...))
+	}
+
+	threadSafe := true
+	if value, ok := argMap["thread_safe"].(bool); ok {
+		threadSafe = value
+	}
+
+	templateIDs := stringList(argMap["template_ids"])
+
+	if id, ok := argMap["template_id"].(string); ok && id != "" {
+		templateIDs = append(templateIDs, id)
+	}
+
+	if allowCode, _ := argMap["allow_code_templates"].(bool); allowCode {
+		scanOpts = append(scanOpts, scanner.WithCodeTemplates())
+	}
+	if allowUnsafe, _ := argMap["allow_unsafe"].(bool); allowUnsafe {
+		approval, _ := argMap["approval"].(string)
+		scanOpts = append(scanOpts, scanner.WithUnsafeTemplates(approval))
+	}
+	if allowSelf, _ := argMap["allow_self_target"].(bool); allowSelf {
+		approval, _ := argMap["approval"].(string)
+		scanOpts = append(scanOpts, scanner.WithSelfTargetAllowed(approval))
+	}
+	if rawExtractors, ok := argMap["extractors"]; ok {
+		extractors, err := parseExtractors(rawExtractors)
+		if err != nil {
+			return nil, err
+		}
+		scanOpts = append(scanOpts, scanner.WithExtractors(extractors...))
+	}
+	if passive, ok := argMap["passive"].(bool); ok {
+		scanOpts = append(scanOpts, scanner.WithPassive(passive))
+	}
+	if raw, ok := argMap["priority"].(string); ok {
+		priority, err := scanner.ParsePriority(raw)
+		if err != nil {
+			return nil, err
+		}
+		scanOpts = append(scanOpts, scanner.WithPriority(priority))
+	}
+	if stop, _ := argMap["stop_on_first_match"].(bool); stop {
+		scanOpts = append(scanOpts, scanner.WithStopOnFirstMatch())
+	}
+	if level, ok := argMap["stop_at_severity"].(string); ok {
+		scanOpts = append(scanOpts, scanner.WithStopAtSeverity(level))
+	}
+	if raw, _ := argMap["template_timeout"].(string); raw != "" {
+		timeout, err := time.ParseDuration(raw)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid template_timeout: %s", raw)
+		}
+		scanOpts = append(scanOpts, scanner.WithTemplateTimeout(timeout))
+	}
+	if maxRequests, ok := argMap["max_template_requests"].(float64); ok {
+		if maxRequests < 1 {
+			return nil, fmt.Errorf("invalid max_template_requests: must be at least 1, got %g", maxRequests)
+		}
+		scanOpts = append(scanOpts, scanner.WithMaxTemplateRequests(int(maxRequests)))
+	}
+	if probe, _ := argMap["probe_http_versions"].(bool); probe {
+		scanOpts = append(scanOpts, scanner.WithHTTPVersionProbe())
+	}
+	if detect, _ := argMap["detect_waf"].(bool); detect {
+		scanOpts = append(scanOpts, scanner.WithWAFDetection())
+	}
+	if depth, ok := argMap["crawl_depth"].(float64); ok {
+		if depth < 1 {
+			return nil, fmt.Errorf("invalid crawl_depth: must be at least 1, got %g", depth)
+		}
+		limit, _ := argMap["crawl_limit"].(float64)
+		if limit < 0 {
+			return nil, fmt.Errorf("invalid crawl_limit: must not be negative, got %g", limit)
+		}
+		scanOpts = append(scanOpts, scanner.WithCrawl(int(depth), int(limit)))
+	}
+	if raw, ok := argMap["labels"]; ok {
+		labels, err := labelsArg(raw)
+		if err != nil {
+			return nil, err
+		}
+		scanOpts = append(scanOpts, scanner.WithLabels(labels))
+	}
+	if raw, ok := argMap["variables"]; ok {
+		vars, err := variablesArg(raw)
+		if err != nil {
+			return nil, err
+		}
+		scanOpts = append(scanOpts, scanner.WithVariables(vars))
+	}
+	if raw, _ := argMap["address_family"].(string); raw != "" {
+		family, err := scanner.ParseAddressFamily(raw)
+		if err != nil {
+			return nil, err
+		}
+		scanOpts = append(scanOpts, scanner.WithAddressFamily(family))
+	}
+	hostOpts, err := hostOverrideArgs(argMap)
+	if err != nil {
+		return nil, err
+	}
+	scanOpts = append(scanOpts, hostOpts...)
+	scanOpts = append(scanOpts, extra...)
+	scanOpts = append(scanOpts, scanner.WithClient(clientName(ctx)), scanner.WithCorrelationID(correlation.FromContext(ctx)), scanner.WithTraceParent(ctx))
+
+	strategy, _ := argMap["strategy"].(string)
+	if strategy != "" && strategy != scanner.StrategySeverityTiers {
+		return nil, fmt.Errorf("%w %q, use %s", scanner.ErrInvalidStrategy, strategy, scanner.StrategySeverityTiers)
+	}
+	if strategy != "" && !threadSafe {
+		return nil, fmt.Errorf("%w: %s needs the thread-safe engine", scanner.ErrInvalidStrategy, strategy)
+	}
+
+	if dryRun, _ := argMap["dry_run"].(bool); dryRun {
+		previewer, ok := service.(scanner.Previewer)
+		if !ok {
+			return nil, scanner.ErrPreviewUnsupported
+		}
+		preview, err := previewer.Preview(ctx, target, severity, protocols, templateIDs, scanOpts...)
+		if err != nil {
+			return nil, err
+		}
+		if format == FormatJSON {
+			previewJSON, err := json.Marshal(preview)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal scan preview: %w", err)
+			}
+			return mcp.NewToolResultText(string(previewJSON)), nil
+		}
+		return mcp.NewToolResultText(formatPreview(preview)), nil
+	}
+
+	var result cache.ScanResult
+	if strategy == scanner.StrategySeverityTiers {
+		result, err = scanner.TieredScan(ctx, service, target, severity, protocols, templateIDs, tierNotifier(ctx, request, target, severity), scanOpts...)
+	} else if threadSafe {
+		result, err = service.ThreadSafeScan(ctx, target, severity, protocols, templateIDs, scanOpts...)
+	} else {
+		result, err = service.Scan(target, severity, protocols, templateIDs, scanOpts...)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	_, span := telemetry.Tracer().Start(ctx, "format_results")
+	defer span.End()
+
+	clustered, _ := argMap["cluster"].(bool)
+	pageSize, _ := argMap["page_size"].(float64)
+	page := Page{Target: target, Findings: result.Findings, Total: len(result.Findings)}
+	if clustered {
+		clusters := report.ClusterFindings(result.Findings)
+		page = clusterPage(target, clusters)
+		if pager != nil {
+			page = pager.FirstClusters(target, clusters, int(pageSize))
+		}
+	} else if pager != nil && len(result.Findings) > 0 {
+		page = pager.First(target, result.Findings, int(pageSize))
+	}
+
+	if format == FormatJSON {
+		return jsonScanResult(page, result)
+	}
+
+	var responseText string
+	if len(result.Findings) == 0 && unreachable(result.Stats) {
+		responseText = fmt.Sprintf("No vulnerabilities found for target: %s, but it could not be reached, so it was not scanned. See the request failures below.", target)
+	} else if len(result.Findings) == 0 {
+		responseText = fmt.Sprintf("No vulnerabilities found for target: %s", target)
+	} else {
+		responseText = fmt.Sprintf("Found %d vulnerabilities for target: %s\n\n", len(result.Findings), target)
+		if clustered {
+			responseText = fmt.Sprintf("Found %d vulnerabilities with %d root causes for target: %s\n\n", len(result.Findings), page.Total, target)
+		}
+		responseText += formatPage(page)
+	}
+
+	if result.SpilledFindings > 0 {
+		responseText += fmt.Sprintf("\n\n%d more findings beyond the in-memory limit were written to a spill file on the server and are not listed.\n", result.SpilledFindings)
+	}
+
+	if len(result.Labels) > 0 {
+		responseText += "\n\nLabels: " + scanner.FormatLabels(result.Labels) + "\n"
+	}
+
+	if result.StoppedEarly {
+		responseText += "\n\nScan stopped at its first finding at the requested severity; the remaining templates were not run.\n"
+	}
+
+	if len(result.Extractions) > 0 {
+		responseText += "\n\nExtracted values:\n"
+		for _, extraction := range result.Extractions {
+			responseText += fmt.Sprintf("- %s (%s): %s\n", extraction.Name, extraction.URL, strings.Join(extraction.Values, ", "))
+		}
+	}
+
+	if len(result.Hosts) > 0 {
+		responseText += "\n\nHosting:\n" + formatHosts(result.Hosts)
+	}
+
+	if len(result.APIOperations) > 0 {
+		responseText += fmt.Sprintf("\n\nAPI operations scanned (%d):\n", len(result.APIOperations))
+		for _, operation := range result.APIOperations {
+			responseText += "- " + operation + "\n"
+		}
+	}
+
+	if len(result.CrawledURLs) > 0 {
+		responseText += fmt.Sprintf("\n\nCrawled URLs scanned (%d):\n", len(result.CrawledURLs))
+		for _, crawled := range result.CrawledURLs {
+			responseText += "- " + crawled + "\n"
+		}
+	}
+
+	if versions := result.HTTPVersions; versions != nil {
+		responseText += "\n\nHTTP versions: " + formatHTTPVersions(versions) + "\n"
+	}
+
+	if waf := result.WAF; waf != nil {
+		responseText += "\n\nWAF: " + formatWAF(waf) + "\n"
+		for _, uri := range waf.Interfered {
+			responseText += fmt.Sprintf("- %s: response looks like a WAF block page, verify the finding\n", uri)
+		}
+	}
+
+	if since := result.UnchangedSince; since != nil {
+		responseText += fmt.Sprintf("\n\nTarget unchanged since %s: the findings are from the scan at that time, and the target was last checked for changes at %s.\n", since.Format(time.RFC3339), result.ScanTime.Format(time.RFC3339))
+	}
+
+	if stats := result.Stats; stats != nil {
+		responseText += fmt.Sprintf("\n\nScan stats: %s wall time, %s CPU time", stats.WallTime.Round(time.Millisecond), stats.CPUTime.Round(time.Millisecond))
+		if stats.Requests > 0 {
+			responseText += fmt.Sprintf(", %d requests (%d failed), %d bytes sent, %d bytes received", stats.Requests, stats.Errors, stats.BytesSent, stats.BytesReceived)
+		}
+		responseText += "\n"
+	}
+
+	if stats := result.Stats; stats != nil && stats.Templates != nil {
+		responseText += "\n\nTemplates: " + formatTemplateStats(stats.Templates) + "\n"
+		if stats.Templates.Loaded == 0 {
+			responseText += "- No template matched the scan's filters, so nothing was run. Check its severity, tags and protocols against the catalog resource.\n"
+		}
+	}
+
+	if failures := formatFailures(result.Stats); failures != "" {
+		responseText += "\n\nRequest failures:\n" + failures
+	}
+
+	if stats := result.Stats; stats != nil && len(stats.TimedOutTemplates)+len(stats.SkippedTemplates) > 0 {
+		responseText += "\n\nTemplate limits:\n"
+		if len(stats.TimedOutTemplates) > 0 {
+			responseText += fmt.Sprintf("- Stopped at the template timeout: %s\n", strings.Join(stats.TimedOutTemplates, ", "))
+		}
+		if len(stats.SkippedTemplates) > 0 {
+			responseText += fmt.Sprintf("- Skipped for exceeding the request limit: %s\n", strings.Join(stats.SkippedTemplates, ", "))
+		}
+	}
+
+	if result.Stats != nil && len(result.Stats.Adjustments) > 0 {
+		responseText += "\n\nAdaptive tuning:\n"
+		for _, adjustment := range result.Stats.Adjustments {
+			responseText += fmt.Sprintf("- %s: %s, rate limit reduced to %d requests/s and concurrency to %d\n", adjustment.Host, adjustment.Reason, adjustment.RateLimit, adjustment.Concurrency)
+		}
+	}
+
+	return mcp.NewToolResultText(responseText), nil
+}
+
+// jsonScanResult renders a page of the findings of result as a JSON object,
+// with the continuation token when more pages remain
+func jsonScanResult(page Page, result cache.ScanResult) (*mcp.CallToolResult, error) {
+	type jsonFinding struct {
+		Name              string   `json:"name"`
+		TemplateID        string   `json:"template_id"`
+		Severity          string   `json:"severity"`
+		Description       string   `json:"description,omitempty"`
+		Host              string   `json:"host"`
+		Matched           string   `json:"matched,omitempty"`
+		Resource          string   `json:"resource"`
+		AffectedEndpoints []string `json:"affected_endpoints,omitempty"`
+		WAFInterference   bool     `json:"waf_interference,omitempty"`
+	}
+	response := struct {
+		Target            string              `json:"target"`
+		Total             int                 `json:"total"`
+		Offset            int                 `json:"offset"`
+		Findings          []jsonFinding       `json:"findings"`
+		ContinuationToken string              `json:"continuation_token,omitempty"`
+		Extractions       []cache.Extraction  `json:"extractions,omitempty"`
+		Stats             *cache.ScanStats    `json:"stats,omitempty"`
+		Hosts             []cache.HostInfo    `json:"hosts,omitempty"`
+		StoppedEarly      bool                `json:"stopped_early,omitempty"`
+		Labels            map[string]string   `json:"labels,omitempty"`
+		HTTPVersions      *cache.HTTPVersions `json:"http_versions,omitempty"`
+		WAF               *cache.WAFDetection `json:"waf,omitempty"`
+		APIOperations     []string            `json:"api_operations,omitempty"`
+		CrawledURLs       []string            `json:"crawled_urls,omitempty"`
+	}{
+		Target:            page.Target,
+		Total:             page.Total,
+		Offset:            page.Offset,
+		Findings:          make([]jsonFinding, 0, len(page.Findings)),
+		ContinuationToken: page.NextToken,
+		Extractions:       result.Extractions,
+		Stats:             result.Stats,
+		Hosts:             result.Hosts,
+		StoppedEarly:      result.StoppedEarly,
+		Labels:            result.Labels,
+		HTTPVersions:      result.HTTPVersions,
+		WAF:               result.WAF,
+		APIOperations:     result.APIOperations,
+		CrawledURLs:       result.CrawledURLs,
+	}
+	interfered := map[string]bool{}
+	if result.WAF != nil {
+		for _, uri := range result.WAF.Interfered {
+			interfered[uri] = true
+		}
+	}
+	for i, finding := range page.Findings {
+		var endpoints []string
+		if page.Endpoints != nil && len(page.Endpoints[i]) > 1 {
+			endpoints = page.Endpoints[i]
+		}
+		response.Findings = append(response.Findings, jsonFinding{
+			Name:              finding.Info.Name,
+			TemplateID:        finding.TemplateID,
+			Severity:          finding.Info.SeverityHolder.Severity.String(),
+			Description:       finding.Info.Description,
+			Host:              finding.Host,
+			Matched:           finding.Matched,
+			Resource:          cache.FindingURI(finding),
+			AffectedEndpoints: endpoints,
+			WAFInterference:   interfered[cache.FindingURI(finding)],
+		})
+	}
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal scan result: %w", err)
+	}
+	return mcp.NewToolResultText(string(responseJSON)), nil
+}
+
+// formatHTTPVersions describes the HTTP versions a target supports
+func formatHTTPVersions(versions *cache.HTTPVersions) string {
+	if versions.Error != "" {
+		return "probe failed: " + versions.Error
+	}
+	text := "HTTP/2 not negotiated"
+	if versions.HTTP2 {
+		text = "HTTP/2 supported"
+	}
+	if versions.HTTP3 {
+		return text + ", HTTP/3 advertised (Alt-Svc: " + versions.AltSvc + ")"
+	}
+	return text + ", HTTP/3 not advertised"
+}
+
+// formatWAF describes the WAF in front of a target and what it means for
+// the scan's findings
+func formatWAF(waf *cache.WAFDetection) string {
+	switch {
+	case waf.Error != "":
+		return "detection failed: " + waf.Error
+	case !waf.Detected:
+		return "none detected"
+	}
+	name := waf.Name
+	if name == "" {
+		name = "unidentified WAF"
+	}
+	text := fmt.Sprintf("%s detected (%s)", name, strings.Join(waf.Evidence, ", "))
+	if waf.Blocking {
+		text += "; it blocks requests with attack patterns, so template requests may have been blocked and a missing finding does not mean the target is not vulnerable"
+	}
+	return text
+}
+
+// unreachable reports whether every request of a scan, or connection
+// attempt when it sent none, failed
+func unreachable(stats *cache.ScanStats) bool {
+	if stats == nil || len(stats.Failures) == 0 {
+		return false
+	}
+	for _, host := range stats.Failures {
+		if !host.Unreachable {
+			return false
+		}
+	}
+	return true
+}
+
+// formatPreview renders the estimate of a scan that was not run
+func formatPreview(preview scanner.ScanPreview) string {
+	responseText := fmt.Sprintf("Dry run for target: %s, nothing was sent\n\n", preview.Target)
+	responseText += fmt.Sprintf("Templates: %d of %d available loaded", preview.Templates, preview.Available)
+	if len(preview.Excluded) > 0 {
+		responseText += "; excluded: " + formatReasons(preview.Excluded)
+	}
+	responseText += "\n"
+	if len(preview.Protocols) > 0 {
+		protocols := make([]string, 0, len(preview.Protocols))
+		for protocol, count := range preview.Protocols {
+			protocols = append(protocols, fmt.Sprintf("%d %s", count, protocol))
+		}
+		sort.Strings(protocols)
+		responseText += "By protocol: " + strings.Join(protocols, ", ") + "\n"
+	}
+	responseText += fmt.Sprintf("Estimated requests: %d\n", preview.Requests)
+	responseText += fmt.Sprintf("Estimated duration: %s (%s at %d requests/s, %s from the run times of past scans at concurrency %d; %d of %d templates have a history)\n",
+		previewDuration(preview.EstimatedSeconds), previewDuration(preview.RateLimitedSeconds), preview.RateLimit,
+		previewDuration(preview.HistorySeconds), preview.Concurrency, preview.TemplatesWithHistory, preview.Templates-len(preview.SkippedTemplates))
+	if len(preview.SkippedTemplates) > 0 {
+		responseText += fmt.Sprintf("Skipped for exceeding the request limit: %s\n", strings.Join(preview.SkippedTemplates, ", "))
+	}
+	if len(preview.Heaviest) > 0 {
+		responseText += "\nMost requests:\n"
+		for _, template := range preview.Heaviest {
+			responseText += fmt.Sprintf("- %s (%s): %d requests\n", template.ID, template.Protocol, template.Requests)
+		}
+	}
+	if preview.Templates == 0 {
+		responseText += "\nNo template matched the scan's filters, so the scan would fail. Check its severity, tags and protocols against the catalog resource.\n"
+	}
+	return responseText
+}
+
+// previewDuration renders estimated seconds as a duration
+func previewDuration(seconds float64) string {
+	return (time.Duration(seconds * float64(time.Second))).Round(time.Second).String()
+}
+
+// formatTemplateStats renders how many of the available templates a scan
+// loaded and ran, with the reasons for the others
+func formatTemplateStats(templateStats *cache.TemplateStats) string {
+	responseText := fmt.Sprintf("%d of %d available loaded, %d executed", templateStats.Loaded, templateStats.Available, templateStats.Executed)
+	if len(templateStats.Excluded) > 0 {
+		responseText += "; excluded: " + formatReasons(templateStats.Excluded)
+	}
+	if len(templateStats.Skipped) > 0 {
+		responseText += "; skipped: " + formatReasons(templateStats.Skipped)
+	}
+	return responseText
+}
+
+// formatReasons renders template counts by reason
+func formatReasons(reasons map[string]int) string {
+	names := make([]string, 0, len(reasons))
+	for reason := range reasons {
+		names = append(names, reason)
+	}
+	sort.Strings(names)
+	for i, reason := range names {
+		names[i] = fmt.Sprintf("%d %s", reasons[reason], strings.ReplaceAll(reason, "_", " "))
+	}
+	return strings.Join(names, ", ")
+}
+
+// formatFailures renders the failed requests of each host by cause, most
+// frequent first
+func formatFailures(stats *cache.ScanStats) string {
+	if stats == nil {
+		return ""
+	}
+	var responseText string
+	for _, host := range stats.Failures {
+		if host.Failed == 0 {
+			continue
+		}
+		causes := make([]string, 0, len(host.Causes))
+		for cause := range host.Causes {
+			causes = append(causes, cause)
+		}
+		sort.Slice(causes, func(i, j int) bool {
+			if host.Causes[causes[i]] != host.Causes[causes[j]] {
+				return host.Causes[causes[i]] > host.Causes[causes[j]]
+			}
+			return causes[i] < causes[j]
+		})
+		for i, cause := range causes {
+			causes[i] = fmt.Sprintf("%d %s", host.Causes[cause], strings.ReplaceAll(cause, "_", " "))
+		}
+		responseText += fmt.Sprintf("- %s: %d of %d failed (%s)", host.Host, host.Failed, host.Requests, strings.Join(causes, ", "))
+		if host.Unreachable {
+			responseText += ", unreachable"
+		}
+		responseText += "\n"
+		if host.Example != "" {
+			responseText += fmt.Sprintf("  e.g. %s\n", host.Example)
+		}
+	}
+	return responseText
+}
+
+// formatHosts renders the addresses of scanned hosts with their
+// autonomous system and location
+func formatHosts(hosts []cache.HostInfo) string {
+	var responseText string
+	for _, host := range hosts {
+		if host.Error != "" {
+			responseText += fmt.Sprintf("- %s: not resolved (%s)\n", host.Host, host.Error)
+			continue
+		}
+		for _, address := range host.Addresses {
+			var details []string
+			if address.Private {
+				details = append(details, "private")
+			}
+			if address.ASN != 0 {
+				details = append(details, fmt.Sprintf("AS%d %s", address.ASN, address.ASOrg))
+			}
+			if location := strings.Trim(address.City+", "+address.Country, ", "); location != "" {
+				details = append(details, location)
+			}
+			responseText += fmt.Sprintf("- %s: %s", host.Host, address.IP)
+			if len(details) > 0 {
+				responseText += " (" + strings.Join(details, "; ") + ")"
+			}
+			responseText += "\n"
+		}
+	}
+	return responseText
+}
+
+// formatPage renders a page of findings, numbered by their position in the
+// full results, with a continuation hint when more pages remain
+func formatPage(page Page) string {
+	var responseText string
+	for i, finding := range page.Findings {
+		responseText += fmt.Sprintf("Finding #%d:\n", page.Offset+i+1)
+		responseText += fmt.Sprintf("- Name: %s\n", finding.Info.Name)
+		responseText += fmt.Sprintf("- Severity: %s\n", finding.Info.SeverityHolder.Severity.String())
+		responseText += fmt.Sprintf("- Description: %s\n", finding.Info.Description)
+		responseText += fmt.Sprintf("- URL: %s\n", finding.Host)
+		if page.Endpoints != nil && len(page.Endpoints[i]) > 1 {
+			responseText += fmt.Sprintf("- Affected endpoints (%d): %s\n", len(page.Endpoints[i]), strings.Join(page.Endpoints[i], ", "))
+		}
+		responseText += fmt.Sprintf("- Details: %s\n\n", cache.FindingURI(finding))
+	}
+	if page.NextToken != "" {
+		responseText += fmt.Sprintf("Showing findings %d-%d of %d. Call fetch_more_results with continuation_token %q for the rest.\n",
+			page.Offset+1, page.Offset+len(page.Findings), page.Total, page.NextToken)
+	}
+	return responseText
+}
+
+func HandleFetchMoreResults(_ context.Context, request mcp.CallToolRequest, pager *ResultPager) (*mcp.CallToolResult, error) {
+	argMap, ok := request.Params.Arguments.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid arguments format")
+	}
+
+	token, ok := argMap["continuation_token"].(string)
+	if !ok || token == "" {
+		return nil, fmt.Errorf("invalid or missing continuation_token parameter")
+	}
+	pageSize, _ := argMap["page_size"].(float64)
+
+	page, err := pager.Next(token, int(pageSize))
+	if err != nil {
+		return nil, err
+	}
+
+	responseText := fmt.Sprintf("Findings %d-%d of %d for target: %s\n\n", page.Offset+1, page.Offset+len(page.Findings), page.Total, page.Target)
+	responseText += formatPage(page)
+	if page.NextToken == "" {
+		responseText += "No more results.\n"
+	}
+
+	return mcp.NewToolResultText(responseText), nil
+}
+
+func HandleScanStatus(_ context.Context, request mcp.CallToolRequest, reporter scanner.ProgressReporter) (*mcp.CallToolResult, error) {
+	argMap, _ := request.Params.Arguments.(map[string]any)
+	target, _ := argMap["target"].(string)
+	correlationID, _ := argMap["correlation_id"].(string)
+
+	scans := []scanner.ScanProgress{}
+	for _, scan := range reporter.RunningScans() {
+		if (target == "" || scan.Target == target) && (correlationID == "" || scan.CorrelationID == correlationID) {
+			scans = append(scans, scan)
+		}
+	}
+
+	responseJSON, err := json.Marshal(map[string]any{"scans": scans})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal scan status: %w", err)
+	}
+
+	return mcp.NewToolResultText(string(responseJSON)), nil
+}
+
+func HandleMultiScanTool(ctx context.Context, request mcp.CallToolRequest, multiScanner *scanner.MultiScanner, defaults ScanDefaults) (*mcp.CallToolResult, error) {
+	argMap, ok := request.Params.Arguments.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid arguments format")
+	}
+
+	targets := stringList(argMap["targets"])
+	if len(targets) == 0 {
+		return nil, errInvalidTargets
+	}
+
+	severity, protocols, scanOpts := defaults.Resolve(argMap)
+
+	// Refuse invalid protocols once instead of failing every target
+	protocols, err := scanner.NormalizeProtocols(protocols)
+	if err != nil {
+		return nil, err
+	}
+
+	templateIDs := stringList(argMap["template_ids"])
+
+	if passive, ok := argMap["passive"].(bool); ok {
+		scanOpts = append(scanOpts, scanner.WithPassive(passive))
+	}
+	if raw, ok := argMap["priority"].(string); ok {
+		priority, err := scanner.ParsePriority(raw)
+		if err != nil {
+			return nil, err
+		}
+		scanOpts = append(scanOpts, scanner.WithPriority(priority))
+	}
+	if allowSelf, _ := argMap["allow_self_target"].(bool); allowSelf {
+		approval, _ := argMap["approval"].(string)
+		scanOpts = append(scanOpts, scanner.WithSelfTargetAllowed(approval))
+	}
+	if raw, ok := argMap["labels"]; ok {
+		labels, err := labelsArg(raw)
+		if err != nil {
+			return nil, err
+		}
+		scanOpts = append(scanOpts, scanner.WithLabels(labels))
+	}
+	if raw, ok := argMap["variables"]; ok {
+		vars, err := variablesArg(raw)
+		if err != nil {
+			return nil, err
+		}
+		scanOpts = append(scanOpts, scanner.WithVariables(vars))
+	}
+	if raw, _ := argMap["address_family"].(string); raw != "" {
+		family, err := scanner.ParseAddressFamily(raw)
+		if err != nil {
+			return nil, err
+		}
+		scanOpts = append(scanOpts, scanner.WithAddressFamily(family))
+	}
+	hostOpts, err := hostOverrideArgs(argMap)
+	if err != nil {
+		return nil, err
+	}
+	scanOpts = append(scanOpts, hostOpts...)
+	scanOpts = append(scanOpts, scanner.WithClient(clientName(ctx)))
+
+	hosts := multiScanner.Scan(ctx, targets, severity, protocols, templateIDs, scanOpts...)
+
+	scanned, failed, total := 0, 0, 0
+	for _, host := range hosts {
+		for _, target := range host.Targets {
+			scanned++
+			if target.Err != nil {
+				failed++
+			}
+		}
+		total += host.Findings()
+	}
+
+	limits := multiScanner.Limits()
+	responseText := fmt.Sprintf("Scanned %d targets on %d hosts (%d failed), found %d vulnerabilities. Concurrency: %d per host, %d overall.\n",
+		scanned, len(hosts), failed, total, limits.Host, limits.Global)
+	for _, host := range hosts {
+		responseText += fmt.Sprintf("\nHost: %s (%d findings)\n", host.Host, host.Findings())
+		for _, target := range host.Targets {
+			if target.Err != nil {
+				responseText += fmt.Sprintf("- %s: scan failed [%s]: %v\n", target.Target, ErrorCodeOf(target.Err), target.Err)
+				continue
+			}
+			responseText += fmt.Sprintf("- %s: %d findings\n", target.Target, len(target.Result.Findings))
+			for _, finding := range target.Result.Findings {
+				responseText += fmt.Sprintf("  - %s (%s) at %s: %s\n", finding.Info.Name, finding.Info.SeverityHolder.Severity.String(), finding.Host, cache.FindingURI(finding))
+			}
+		}
+	}
+
+	return mcp.NewToolResultText(responseText), nil
+}
+
+// labelsArg converts a labels tool argument, an object of string values or
+// key=value strings, into scan labels
+func labelsArg(raw any) (map[string]string, error) {
+	object, ok := raw.(map[string]any)
+	if !ok {
+		return scanner.ParseLabels(stringList(raw))
+	}
+	labels := make(map[string]string, len(object))
+	for key, value := range object {
+		switch value := value.(type) {
+		case string:
+			labels[key] = value
+		case float64, bool:
+			labels[key] = fmt.Sprint(value)
+		default:
+			return nil, fmt.Errorf("%w value for %s, use a string", scanner.ErrInvalidLabel, key)
+		}
+	}
+	if err := scanner.ValidateLabels(labels); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+// variablesArg converts a variables tool argument, an object of string
+// values or name=value strings, into scan variables
+func variablesArg(raw any) (map[string]string, error) {
+	object, ok := raw.(map[string]any)
+	if !ok {
+		return scanner.ParseVariables(stringList(raw))
+	}
+	vars := make(map[string]string, len(object))
+	for name, value := range object {
+		switch value := value.(type) {
+		case string:
+			vars[name] = value
+		case float64, bool:
+			vars[name] = fmt.Sprint(value)
+		default:
+			return nil, fmt.Errorf("%w value for %s, use a string", scanner.ErrInvalidVariable, name)
+		}
+	}
+	if err := scanner.ValidateVariables(vars); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+// hostOverrideArgs converts the sni and host_header tool arguments into
+// scan options
+func hostOverrideArgs(argMap map[string]any) ([]scanner.ScanOption, error) {
+	sni, _ := argMap["sni"].(string)
+	hostHeader, _ := argMap["host_header"].(string)
+	sni, hostHeader = strings.TrimSpace(sni), strings.TrimSpace(hostHeader)
+	if err := scanner.ValidateHostOverrides(sni, hostHeader); err != nil {
+		return nil, err
+	}
+	var opts []scanner.ScanOption
+	if sni != "" {
+		opts = append(opts, scanner.WithSNI(sni))
+	}
+	if hostHeader != "" {
+		opts = append(opts, scanner.WithHostHeader(hostHeader))
+	}
+	return opts, nil
+}
+
+// stringList converts an array tool argument into its non-empty strings. A
+// comma-separated string is accepted as well, for clients written against
+// the earlier string schemas.
+func stringList(raw any) []string {
+	var items []any
+	switch value := raw.(type) {
+	case []any:
+		items = value
+	case []string:
+		for _, item := range value {
+			items = append(items, item)
+		}
+	case string:
+		for _, item := range strings.Split(value, ",") {
+			items = append(items, item)
+		}
+	}
+	list := make([]string, 0, len(items))
+	for _, item := range items {
+		if value, ok := item.(string); ok && strings.TrimSpace(value) != "" {
+			list = append(list, strings.TrimSpace(value))
+		}
+	}
+	return list
+}
+
+// parseExtractors converts the extractors tool argument into scanner extractors
+func parseExtractors(raw any) ([]scanner.Extractor, error) {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid extractors parameter: %w", err)
+	}
+	var extractors []scanner.Extractor
+	if err := json.Unmarshal(data, &extractors); err != nil {
+		return nil, fmt.Errorf("invalid extractors parameter: %w", err)
+	}
+	return extractors, nil
+}
+
+func HandleBasicScanTool(
+	_ context.Context,
+	request mcp.CallToolRequest,
+	service scanner.ScannerService,
+	logger *log.Logger,
+) (*mcp.CallToolResult, error) {
+	argMap, ok := request.Params.Arguments.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid arguments format")
+	}
+
+	target, ok := argMap["target"].(string)
+	if !ok || target == "" {
+		return nil, errInvalidTarget
+	}
+
+	result, err := service.BasicScan(target)
+	if err != nil {
+		logger.Printf("Basic scan failed: %v", err)
+		return nil, err
+	}
+
+	type SimplifiedFinding struct {
+		Name        string `json:"name"`
+		Severity    string `json:"severity"`
+		Description string `json:"description"`
+		URL         string `json:"url"`
+		Resource    string `json:"resource"`
+	}
+
+	simplifiedFindings := make([]SimplifiedFinding, 0, len(result.Findings))
+	for _, finding := range result.Findings {
+		simplifiedFindings = append(simplifiedFindings, SimplifiedFinding{
+			Name:        finding.Info.Name,
+			Severity:    finding.Info.SeverityHolder.Severity.String(),
+			Description: finding.Info.Description,
+			URL:         finding.Host,
+			Resource:    cache.FindingURI(finding),
+		})
+	}
+
+	response := map[string]interface{}{
+		"target":         result.Target,
+		"scan_time":      result.ScanTime.Format(time.RFC3339),
+		"findings_count": len(result.Findings),
+		"findings":       simplifiedFindings,
+	}
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		logger.Printf("Failed to marshal response: %v", err)
+		return nil, err
+	}
+
+	return mcp.NewToolResultText(string(responseJSON)), nil
+}
+
+func HandleVulnerabilityResource(
+	_ context.Context,
+	_ mcp.ReadResourceRequest,
+	service scanner.ScannerService,
+	_ *log.Logger,
+) ([]mcp.ResourceContents, error) {
+	results := service.GetAll()
+
+	var recentScans []map[string]interface{}
+	for _, result := range results {
+		scanInfo := map[string]interface{}{
+			"target":    result.Target,
+			"scan_time": result.ScanTime.Format(time.RFC3339),
+			"findings":  len(result.Findings),
+		}
+
+		if len(result.Findings) > 0 {
+			var sampleFindings []map[string]string
+
+			count := min(5, len(result.Findings))
+			for i := 0; i < count; i++ {
+				finding := result.Findings[i]
+				sampleFindings = append(sampleFindings, map[string]string{
+					"name":        finding.Info.Name,
+					"severity":    finding.Info.SeverityHolder.Severity.String(),
+					"description": finding.Info.Description,
+					"url":         finding.Host,
+				})
+			}
+			scanInfo["sample_findings"] = sampleFindings
+		}
+
+		recentScans = append(recentScans, scanInfo)
+	}
+
+	report := map[string]interface{}{
+		"timestamp":    time.Now().Format(time.RFC3339),
+		"recent_scans": recentScans,
+		"total_scans":  len(recentScans),
+	}
+
+	reportJSON, err := json.Marshal(report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal report: %w", err)
+	}
+
+	return []mcp.ResourceContents{
+			mcp.TextResourceContents{
+				URI:      "vulnerabilities",
+				MIMEType: "application/json",
+				Text:     string(reportJSON),
+			},
+		},
+		nil
+}
+
+func HandleDashboardResource(_ context.Context, _ mcp.ReadResourceRequest, service scanner.ScannerService, violations *policy.ViolationLog) ([]mcp.ResourceContents, error) {
+	var recent []policy.Violation
+	if violations != nil {
+		recent = violations.Recent()
+	}
+
+	dashboard, err := report.Dashboard(service.GetAll(), recent, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to render dashboard: %w", err)
+	}
+
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      "dashboard",
+			MIMEType: "text/html",
+			Text:     dashboard,
+		},
+	}, nil
+}
+
+func HandleTrendsResource(_ context.Context, _ mcp.ReadResourceRequest, service scanner.ScannerService) ([]mcp.ResourceContents, error) {
+	trendsJSON, err := json.Marshal(trends.Build(service.GetAll(), "", trends.DefaultInterval))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal trends: %w", err)
+	}
+
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      "trends",
+			MIMEType: "application/json",
+			Text:     string(trendsJSON),
+		},
+	}, nil
+}
+
+func HandleFindingTrends(_ context.Context, request mcp.CallToolRequest, service scanner.ScannerService) (*mcp.CallToolResult, error) {
+	argMap, _ := request.Params.Arguments.(map[string]any)
+	target, _ := argMap["target"].(string)
+
+	interval := trends.DefaultInterval
+	if raw, _ := argMap["interval"].(string); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid interval: %s", raw)
+		}
+		interval = parsed
+	}
+
+	trendsJSON, err := json.Marshal(trends.Build(service.GetAll(), target, interval))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal trends: %w", err)
+	}
+
+	return mcp.NewToolResultText(string(trendsJSON)), nil
+}
+
+func min(x, y int) int {
+	if x < y {
+		return x
+	}
+	return y
+}
+
+func HandleSummarizeFindings(ctx context.Context, request mcp.CallToolRequest, service scanner.ScannerService, summarizer *triage.Summarizer) (*mcp.CallToolResult, error) {
+	argMap, _ := request.Params.Arguments.(map[string]any)
+	target, _ := argMap["target"].(string)
+	maxFindings, _ := argMap["max_findings"].(float64)
+
+	if language, _ := argMap["language"].(string); language != "" {
+		localizer, err := i18n.New(language)
+		if err != nil {
+			return nil, err
+		}
+		summarizer = summarizer.Localized(localizer)
+	}
+
+	results := filterResults(service.GetAll(), target)
+	summary, err := summarizer.Summarize(ctx, results, int(maxFindings))
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize findings: %w", err)
+	}
+
+	summaryJSON, err := json.Marshal(summary)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal summary: %w", err)
+	}
+
+	return mcp.NewToolResultText(string(summaryJSON)), nil
+}
+
+func HandleGenerateReport(_ context.Context, request mcp.CallToolRequest, service scanner.ScannerService, generator *report.Generator) (*mcp.CallToolResult, error) {
+	argMap, _ := request.Params.Arguments.(map[string]any)
+	target, _ := argMap["target"].(string)
+
+	if language, _ := argMap["language"].(string); language != "" {
+		localizer, err := i18n.New(language)
+		if err != nil {
+			return nil, err
+		}
+		generator = report.NewGenerator(localizer)
+	}
+	if clustered, _ := argMap["cluster"].(bool); clustered {
+		generator = generator.Clustered()
+	}
+
+	results := filterResults(service.GetAll(), target)
+	if target != "" && len(results) == 0 {
+		return nil, fmt.Errorf("no cached scan results for target: %s", target)
+	}
+	if raw, ok := argMap["labels"]; ok {
+		labels, err := labelsArg(raw)
+		if err != nil {
+			return nil, err
+		}
+		labelled := results[:0:0]
+		for _, result := range results {
+			if scanner.HasLabels(result.Labels, labels) {
+				labelled = append(labelled, result)
+			}
+		}
+		if len(labelled) == 0 {
+			return nil, fmt.Errorf("no cached scan results labelled %s", scanner.FormatLabels(labels))
+		}
+		results = labelled
+	}
+
+	return mcp.NewToolResultText(generator.Markdown(results, time.Now())), nil
+}
+
+func HandleComplianceSummary(_ context.Context, request mcp.CallToolRequest, service scanner.ScannerService) (*mcp.CallToolResult, error) {
+	argMap, _ := request.Params.Arguments.(map[string]any)
+	target, _ := argMap["target"].(string)
+	framework, _ := argMap["framework"].(string)
+	if framework != "" && !compliance.ValidFramework(framework) {
+		return nil, fmt.Errorf("unsupported framework %q, use %s", framework, strings.Join(compliance.Frameworks, ", "))
+	}
+
+	summaries := compliance.Summarize(service.GetAll(), target, framework)
+	if target != "" && len(summaries) == 0 {
+		return nil, fmt.Errorf("no cached scan results for target: %s", target)
+	}
+
+	summaryJSON, err := json.Marshal(summaries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal compliance summary: %w", err)
+	}
+
+	return mcp.NewToolResultText(string(summaryJSON)), nil
+}
+
+// filterResults keeps the results for target, or all results if target is
+// empty
+func filterResults(results []cache.ScanResult, target string) []cache.ScanResult {
+	if target == "" {
+		return results
+	}
+	filtered := results[:0:0]
+	for _, result := range results {
+		if result.Target == target {
+			filtered = append(filtered, result)
+		}
+	}
+	return filtered
+}
+
+// HandleAddTemplate saves a custom template. Wordlist references in its
+// payloads are replaced with the entries of the wordlists when lists is set.
+func HandleAddTemplate(_ context.Context, request mcp.CallToolRequest, tm templates.TemplateManager, templatePolicy *policy.TemplatePolicy, verifier *templates.Verifier, lists *wordlists.Store) (*mcp.CallToolResult, error) {
+	argMap, ok := request.Params.Arguments.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid arguments format")
+	}
+
+	name, ok := argMap["name"].(string)
+	if !ok || name == "" {
+		return nil, fmt.Errorf("invalid or missing name parameter")
+	}
+
+	content, ok := argMap["content"].(string)
+	if !ok || content == "" {
+		return nil, fmt.Errorf("invalid or missing content parameter")
+	}
+
+	if templatePolicy != nil {
+		allowUnsafe, _ := argMap["allow_unsafe"].(bool)
+		approval, _ := argMap["approval"].(string)
+		if err := templatePolicy.CheckTemplate([]byte(content), policy.Override{Allow: allowUnsafe, Approval: approval}); err != nil {
+			return nil, err
+		}
+	}
+
+	var warning string
+	signature, _ := argMap["signature"].(string)
+	verified := false
+	if verifier != nil {
+		if err := verifier.Verify([]byte(content), []byte(signature)); err != nil {
+			if verifier.Enforced() {
+				return nil, fmt.Errorf("template rejected by %s signature verification: %w", verifier.Mode(), err)
+			}
+			warning = fmt.Sprintf(" Warning: %s signature verification failed: %v", verifier.Mode(), err)
+		} else {
+			verified = true
+		}
+	}
+
+	// JSON templates are checked and resolved as YAML and stored in the
+	// format of their file name
+	format := templates.DetectFormat([]byte(content))
+	if base := name[strings.LastIndex(name, "/")+1:]; !strings.Contains(base, ".") {
+		name += "." + format
+	}
+	saved := []byte(content)
+	if format == templates.FormatJSON {
+		converted, err := templates.ConvertTemplate(saved, templates.FormatYAML)
+		if err != nil {
+			return nil, err
+		}
+		saved = converted
+	}
+	if lists != nil {
+		resolved, used, err := lists.Resolve(saved)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve wordlists: %w", err)
+		}
+		saved = resolved
+		if len(used) > 0 {
+			warning += fmt.Sprintf(" Wordlists inlined: %s.", strings.Join(used, ", "))
+		}
+	}
+
+	if target := templates.FormatOf(name); target == templates.FormatJSON || (target == templates.FormatYAML && format == templates.FormatJSON) {
+		if format != target {
+			warning += fmt.Sprintf(" Converted from %s to %s.", strings.ToUpper(format), strings.ToUpper(target))
+		}
+		converted, err := templates.ConvertTemplate(saved, target)
+		if err != nil {
+			return nil, err
+		}
+		saved = converted
+	}
+
+	if err := tm.AddTemplate(name, saved); err != nil {
+		return nil, fmt.Errorf("failed to add template: %w", err)
+	}
+	// A verified detached signature is kept next to the template, so that
+	// export_templates can bundle it, unless the content was changed
+	if verified && signature != "" && string(saved) == content {
+		if err := tm.AddTemplate(name+templates.MinisignExtension, []byte(signature)); err != nil {
+			return nil, fmt.Errorf("failed to save template signature: %w", err)
+		}
+	}
+
+	return mcp.NewToolResultText(fmt.Sprintf("Template '%s' added successfully.%s", name, warning)), nil
+}
+
+func HandleListTemplates(_ context.Context, request mcp.CallToolRequest, tm templates.TemplateManager) (*mcp.CallToolResult, error) {
+	argMap, _ := request.Params.Arguments.(map[string]any)
+	query, _ := argMap["query"].(string)
+	details, _ := argMap["details"].(bool)
+
+	var templateFiles []string
+	if query == "" && !details {
+		var err error
+		if templateFiles, err = tm.ListTemplates(); err != nil {
+			return nil, fmt.Errorf("failed to list templates: %w", err)
+		}
+	} else {
+		catalog, err := tm.Catalog()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list templates: %w", err)
+		}
+		matched := make([]templates.TemplateInfo, 0, len(catalog))
+		for _, template := range catalog {
+			if query == "" || template.Matches(query) {
+				matched = append(matched, template)
+				templateFiles = append(templateFiles, template.Name)
+			}
+		}
+		if details {
+			catalogJSON, err := json.Marshal(matched)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal templates: %w", err)
+			}
+			return mcp.NewToolResultText(string(catalogJSON)), nil
+		}
+	}
+
+	if len(templateFiles) == 0 {
+		return mcp.NewToolResultText("No custom templates found."), nil
+	}
+
+	return mcp.NewToolResultText(fmt.Sprintf("Available templates:\n- %s", strings.Join(templateFiles, "\n- "))), nil
+}
 
-	mcpServer.AddTool(mcp.NewTool("basic_scan",
-		mcp.WithDescription("Performs a basic Nuclei vulnerability scan on a target without requiring template IDs"),
-		mcp.WithString("target",
-			mcp.Description("Target URL or IP to scan"),
-			mcp.Required(),
-		),
-	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		return HandleBasicScanTool(ctx, request, service, logger)
-	})
+func HandleGetTemplate(_ context.Context, request mcp.CallToolRequest, tm templates.TemplateManager) (*mcp.CallToolResult, error) {
+	argMap, ok := request.Params.Arguments.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid arguments format")
+	}
 
-	mcpServer.AddResource(mcp.NewResource("vulnerabilities", "Recent Vulnerability Reports"),
-		func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
-			return HandleVulnerabilityResource(ctx, request, service, logger)
-		})
+	name, ok := argMap["name"].(string)
+	if !ok || name == "" {
+		return nil, fmt.Errorf("invalid or missing name parameter")
+	}
 
-	mcpServer.AddTool(mcp.NewTool("add_template",
-		mcp.WithDescription("Adds a new Nuclei template."),
-		mcp.WithString("name", mcp.Description("The name of the template file."), mcp.Required()),
-		mcp.WithString("content", mcp.Description("The content of the template file."), mcp.Required()),
-	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		return HandleAddTemplate(ctx, request, tm)
-	})
+	content, err := tm.GetTemplate(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
 
-	mcpServer.AddTool(mcp.NewTool("list_templates",
-		mcp.WithDescription("Lists all available Nuclei templates."),
-	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		return HandleListTemplates(ctx, request, tm)
-	})
+	if format, _ := argMap["format"].(string); format != "" && format != templates.DetectFormat(content) {
+		if content, err = templates.ConvertTemplate(content, format); err != nil {
+			return nil, err
+		}
+	}
 
-	mcpServer.AddTool(mcp.NewTool("get_template",
-		mcp.WithDescription("Gets the content of a specific Nuclei template."),
-		mcp.WithString("name", mcp.Description("The name of the template file."), mcp.Required()),
-	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		return HandleGetTemplate(ctx, request, tm)
-	})
+	return mcp.NewToolResultText(string(content)), nil
+}
 
-	return mcpServer
+func HandleBackupWorkspace(_ context.Context, request mcp.CallToolRequest, ws *workspace.Workspace) (*mcp.CallToolResult, error) {
+	argMap, ok := request.Params.Arguments.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid arguments format")
+	}
+
+	path, ok := argMap["path"].(string)
+	if !ok || path == "" {
+		return nil, fmt.Errorf("invalid or missing path parameter")
+	}
+
+	manifest, err := ws.ExportFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to back up workspace: %w", err)
+	}
+	archivePath, _ := ws.Path(path)
+
+	return mcp.NewToolResultText(fmt.Sprintf("Workspace exported to '%s' (%d results, %d templates, %d exclusion rules, %d tracked findings, %d scheduled scans).",
+		archivePath, manifest.Results, manifest.Templates, manifest.Exclusions, manifest.TrackedFindings, manifest.Schedules)), nil
 }
 
-func HandleNucleiScanTool(
-	ctx context.Context,
-	request mcp.CallToolRequest,
-	service scanner.ScannerService,
-	_ *log.Logger,
-) (*mcp.CallToolResult, error) {
+func HandleRestoreWorkspace(_ context.Context, request mcp.CallToolRequest, ws *workspace.Workspace) (*mcp.CallToolResult, error) {
 	argMap, ok := request.Params.Arguments.(map[string]any)
 	if !ok {
 		return nil, fmt.Errorf("invalid arguments format")
 	}
 
-	target, ok := argMap["target"].(string)
-	if !ok || target == "" {
-		return nil, fmt.Errorf("invalid or missing target parameter")
+	path, ok := argMap["path"].(string)
+	if !ok || path == "" {
+		return nil, fmt.Errorf("invalid or missing path parameter")
 	}
 
-	severity, _ := argMap["severity"].(string)
-	if severity == "" {
-		severity = "info"
+	overwrite, _ := argMap["overwrite"].(bool)
+
+	manifest, err := ws.RestoreFile(path, workspace.RestoreOptions{Overwrite: overwrite})
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore workspace: %w", err)
 	}
+	archivePath, _ := ws.Path(path)
+
+	return mcp.NewToolResultText(fmt.Sprintf("Workspace restored from '%s' (archive created %s, %d results, %d templates, %d exclusion rules, %d tracked findings, %d scheduled scans).",
+		archivePath, manifest.CreatedAt.Format(time.RFC3339), manifest.Results, manifest.Templates, manifest.Exclusions, manifest.TrackedFindings, manifest.Schedules)), nil
+}
 
-	protocols, _ := argMap["protocols"].(string)
-	if protocols == "" {
-		protocols = "http,https"
+// HandleExportTemplates packages templates into a bundle another instance
+// can import with import_templates
+func HandleExportTemplates(_ context.Context, request mcp.CallToolRequest, ws *workspace.Workspace) (*mcp.CallToolResult, error) {
+	argMap, _ := request.Params.Arguments.(map[string]any)
+	path, ok := argMap["path"].(string)
+	if !ok || path == "" {
+		return nil, fmt.Errorf("invalid or missing path parameter")
 	}
+	query, _ := argMap["query"].(string)
 
-	threadSafe, _ := argMap["thread_safe"].(bool)
+	manifest, err := ws.ExportTemplates(path, stringList(argMap["names"]), query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export templates: %w", err)
+	}
 
-	var templateIDs []string
-	if ids, ok := argMap["template_ids"].(string); ok && ids != "" {
-		templateIDs = strings.Split(ids, ",")
+	signed := 0
+	for _, template := range manifest.Templates {
+		if template.Signed {
+			signed++
+		}
 	}
+	archivePath, _ := ws.Path(path)
+	return mcp.NewToolResultText(fmt.Sprintf("Exported %d templates (%d with detached signatures) to '%s'.", len(manifest.Templates), signed, archivePath)), nil
+}
 
-	if id, ok := argMap["template_id"].(string); ok && id != "" {
-		templateIDs = append(templateIDs, id)
+// HandleImportTemplates adds the templates of a bundle to the custom
+// templates directory. Every template goes through the template policy and
+// signature verification like add_template.
+func HandleImportTemplates(_ context.Context, request mcp.CallToolRequest, ws *workspace.Workspace, templatePolicy *policy.TemplatePolicy, verifier *templates.Verifier) (*mcp.CallToolResult, error) {
+	argMap, _ := request.Params.Arguments.(map[string]any)
+	path, ok := argMap["path"].(string)
+	if !ok || path == "" {
+		return nil, fmt.Errorf("invalid or missing path parameter")
 	}
 
-	var result cache.ScanResult
-	var err error
+	opts := workspace.ImportOptions{Verifier: verifier}
+	opts.Namespace, _ = argMap["namespace"].(string)
+	opts.Overwrite, _ = argMap["overwrite"].(bool)
+	if templatePolicy != nil {
+		allowUnsafe, _ := argMap["allow_unsafe"].(bool)
+		approval, _ := argMap["approval"].(string)
+		opts.Check = func(_ string, content []byte) error {
+			return templatePolicy.CheckTemplate(content, policy.Override{Allow: allowUnsafe, Approval: approval})
+		}
+	}
 
-	if threadSafe {
-		result, err = service.ThreadSafeScan(ctx, target, severity, protocols, templateIDs)
-	} else {
-		result, err = service.Scan(target, severity, protocols, templateIDs)
+	result, err := ws.ImportTemplates(path, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import templates: %w", err)
 	}
 
+	resultJSON, err := json.Marshal(result)
 	if err != nil {
-		return nil, fmt.Errorf("scan failed: %w", err)
+		return nil, fmt.Errorf("failed to marshal import result: %w", err)
 	}
+	return mcp.NewToolResultText(string(resultJSON)), nil
+}
 
-	var responseText string
-	if len(result.Findings) == 0 {
-		responseText = fmt.Sprintf("No vulnerabilities found for target: %s", target)
+func HandleTestTemplate(ctx context.Context, request mcp.CallToolRequest, tm templates.TemplateManager, lists *wordlists.Store) (*mcp.CallToolResult, error) {
+	argMap, ok := request.Params.Arguments.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid arguments format")
+	}
+
+	var content []byte
+	if text, ok := argMap["content"].(string); ok && text != "" {
+		content = []byte(text)
+	} else if name, ok := argMap["name"].(string); ok && name != "" {
+		saved, err := tm.GetTemplate(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get template: %w", err)
+		}
+		content = saved
 	} else {
-		responseText = fmt.Sprintf("Found %d vulnerabilities for target: %s\n\n", len(result.Findings), target)
+		return nil, fmt.Errorf("either content or name parameter is required")
+	}
 
-		for i, finding := range result.Findings {
-			responseText += fmt.Sprintf("Finding #%d:\n", i+1)
-			responseText += fmt.Sprintf("- Name: %s\n", finding.Info.Name)
-			responseText += fmt.Sprintf("- Severity: %s\n", finding.Info.SeverityHolder.Severity.String())
-			responseText += fmt.Sprintf("- Description: %s\n", finding.Info.Description)
-			responseText += fmt.Sprintf("- URL: %s\n\n", finding.Host)
+	var responses []sandbox.Response
+	if raw, ok := argMap["responses"]; ok && raw != nil {
+		data, err := json.Marshal(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid responses parameter: %w", err)
+		}
+		if err := json.Unmarshal(data, &responses); err != nil {
+			return nil, fmt.Errorf("invalid responses parameter: %w", err)
 		}
 	}
 
-	return mcp.NewToolResultText(responseText), nil
+	trace, _ := argMap["trace"].(bool)
+
+	if lists != nil {
+		resolved, _, err := lists.Resolve(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve wordlists: %w", err)
+		}
+		content = resolved
+	}
+
+	result, err := sandbox.Run(ctx, content, sandbox.Options{Responses: responses, Trace: trace})
+	if err != nil {
+		return nil, fmt.Errorf("template test failed: %w", err)
+	}
+
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal result: %w", err)
+	}
+
+	return mcp.NewToolResultText(string(resultJSON)), nil
 }
 
-func HandleBasicScanTool(
-	_ context.Context,
-	request mcp.CallToolRequest,
-	service scanner.ScannerService,
-	logger *log.Logger,
-) (*mcp.CallToolResult, error) {
+func HandleSelfTest(ctx context.Context, _ mcp.CallToolRequest, service scanner.ScannerService) (*mcp.CallToolResult, error) {
+	result, err := selftest.Run(ctx, service)
+	if err != nil {
+		return nil, fmt.Errorf("self-test failed: %w", err)
+	}
+
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal result: %w", err)
+	}
+
+	return mcp.NewToolResultText(string(resultJSON)), nil
+}
+
+func HandleAddExclusion(_ context.Context, request mcp.CallToolRequest, store *exclusions.Store) (*mcp.CallToolResult, error) {
 	argMap, ok := request.Params.Arguments.(map[string]any)
 	if !ok {
 		return nil, fmt.Errorf("invalid arguments format")
 	}
 
-	target, ok := argMap["target"].(string)
-	if !ok || target == "" {
-		return nil, fmt.Errorf("invalid or missing target parameter")
+	target, _ := argMap["target"].(string)
+	reason, _ := argMap["reason"].(string)
+	var expiresAt *time.Time
+	if raw, _ := argMap["expires"].(string); raw != "" {
+		expires, err := exclusions.ParseExpiry(raw, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		expiresAt = &expires
 	}
 
-	result, err := service.BasicScan(target)
+	rule, err := store.Add(exclusions.Rule{
+		Target:      target,
+		TemplateIDs: stringList(argMap["template_ids"]),
+		Tags:        stringList(argMap["tags"]),
+		Reason:      reason,
+		ExpiresAt:   expiresAt,
+	})
 	if err != nil {
-		logger.Printf("Basic scan failed: %v", err)
-		return nil, err
+		return nil, fmt.Errorf("failed to add exclusion: %w", err)
 	}
 
-	type SimplifiedFinding struct {
-		Name        string `json:"name"`
-		Severity    string `json:"severity"`
-		Description string `json:"description"`
-		URL         string `json:"url"`
+	ruleJSON, err := json.Marshal(rule)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal exclusion: %w", err)
 	}
 
-	simplifiedFindings := make([]SimplifiedFinding, 0, len(result.Findings))
-	for _, finding := range result.Findings {
-		simplifiedFindings = append(simplifiedFindings, SimplifiedFinding{
-			Name:        finding.Info.Name,
-			Severity:    finding.Info.SeverityHolder.Severity.String(),
-			Description: finding.Info.Description,
-			URL:         finding.Host,
-		})
-	}
+	return mcp.NewToolResultText(string(ruleJSON)), nil
+}
 
-	response := map[string]interface{}{
-		"target":         result.Target,
-		"scan_time":      result.ScanTime.Format(time.RFC3339),
-		"findings_count": len(result.Findings),
-		"findings":       simplifiedFindings,
+func HandleListExclusions(_ context.Context, _ mcp.CallToolRequest, store *exclusions.Store) (*mcp.CallToolResult, error) {
+	rulesJSON, err := json.Marshal(store.List())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal exclusions: %w", err)
 	}
 
-	responseJSON, err := json.Marshal(response)
+	return mcp.NewToolResultText(string(rulesJSON)), nil
+}
+
+func HandleExportExclusions(_ context.Context, _ mcp.CallToolRequest, store *exclusions.Store) (*mcp.CallToolResult, error) {
+	data, err := store.Export()
 	if err != nil {
-		logger.Printf("Failed to marshal response: %v", err)
 		return nil, err
 	}
 
-	return mcp.NewToolResultText(string(responseJSON)), nil
+	return mcp.NewToolResultText(string(data)), nil
 }
 
-func HandleVulnerabilityResource(
-	_ context.Context,
-	_ mcp.ReadResourceRequest,
-	service scanner.ScannerService,
-	_ *log.Logger,
-) ([]mcp.ResourceContents, error) {
-	results := service.GetAll()
-
-	var recentScans []map[string]interface{}
-	for _, result := range results {
-		scanInfo := map[string]interface{}{
-			"target":    result.Target,
-			"scan_time": result.ScanTime.Format(time.RFC3339),
-			"findings":  len(result.Findings),
-		}
-
-		if len(result.Findings) > 0 {
-			var sampleFindings []map[string]string
+func HandleImportExclusions(_ context.Context, request mcp.CallToolRequest, store *exclusions.Store) (*mcp.CallToolResult, error) {
+	argMap, ok := request.Params.Arguments.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid arguments format")
+	}
 
-			count := min(5, len(result.Findings))
-			for i := 0; i < count; i++ {
-				finding := result.Findings[i]
-				sampleFindings = append(sampleFindings, map[string]string{
-					"name":        finding.Info.Name,
-					"severity":    finding.Info.SeverityHolder.Severity.String(),
-					"description": finding.Info.Description,
-					"url":         finding.Host,
-				})
-			}
-			scanInfo["sample_findings"] = sampleFindings
-		}
+	content, _ := argMap["content"].(string)
+	if strings.TrimSpace(content) == "" {
+		return nil, fmt.Errorf("invalid or missing content parameter")
+	}
+	replace, _ := argMap["replace"].(bool)
 
-		recentScans = append(recentScans, scanInfo)
+	summary, err := store.Import([]byte(content), replace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import exclusions: %w", err)
 	}
 
-	report := map[string]interface{}{
-		"timestamp":    time.Now().Format(time.RFC3339),
-		"recent_scans": recentScans,
-		"total_scans":  len(recentScans),
+	summaryJSON, err := json.Marshal(summary)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal import summary: %w", err)
 	}
 
-	reportJSON, err := json.Marshal(report)
+	return mcp.NewToolResultText(string(summaryJSON)), nil
+}
+
+func HandleListWorkers(_ context.Context, _ mcp.CallToolRequest, pool WorkerPool) (*mcp.CallToolResult, error) {
+	workersJSON, err := json.Marshal(pool.Workers())
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal report: %w", err)
+		return nil, fmt.Errorf("failed to marshal workers: %w", err)
 	}
 
-	return []mcp.ResourceContents{
-			mcp.TextResourceContents{
-				URI:      "vulnerabilities",
-				MIMEType: "application/json",
-				Text:     string(reportJSON),
-			},
-		},
-		nil
+	return mcp.NewToolResultText(string(workersJSON)), nil
 }
 
-func min(x, y int) int {
-	if x < y {
-		return x
+func HandleScanUsage(_ context.Context, _ mcp.CallToolRequest, quotas *scanner.QuotaTracker) (*mcp.CallToolResult, error) {
+	responseJSON, err := json.Marshal(quotas.Usage())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal scan usage: %w", err)
 	}
-	return y
+
+	return mcp.NewToolResultText(string(responseJSON)), nil
 }
 
-func HandleAddTemplate(_ context.Context, request mcp.CallToolRequest, tm templates.TemplateManager) (*mcp.CallToolResult, error) {
+func HandleRemoveExclusion(_ context.Context, request mcp.CallToolRequest, store *exclusions.Store) (*mcp.CallToolResult, error) {
 	argMap, ok := request.Params.Arguments.(map[string]any)
 	if !ok {
 		return nil, fmt.Errorf("invalid arguments format")
 	}
 
-	name, ok := argMap["name"].(string)
-	if !ok || name == "" {
-		return nil, fmt.Errorf("invalid or missing name parameter")
+	id, ok := argMap["id"].(string)
+	if !ok || id == "" {
+		return nil, fmt.Errorf("invalid or missing id parameter")
 	}
 
-	content, ok := argMap["content"].(string)
-	if !ok || content == "" {
-		return nil, fmt.Errorf("invalid or missing content parameter")
+	if err := store.Remove(id); err != nil {
+		return nil, err
 	}
 
-	if err := tm.AddTemplate(name, []byte(content)); err != nil {
-		return nil, fmt.Errorf("failed to add template: %w", err)
+	return mcp.NewToolResultText(fmt.Sprintf("Exclusion '%s' removed.", id)), nil
+}
+
+func HandleEngineInfo(_ context.Context, _ mcp.CallToolRequest, updater *engine.Updater) (*mcp.CallToolResult, error) {
+	infoJSON, err := json.Marshal(updater.Info())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal engine info: %w", err)
 	}
 
-	return mcp.NewToolResultText(fmt.Sprintf("Template '%s' added successfully.", name)), nil
+	return mcp.NewToolResultText(string(infoJSON)), nil
 }
 
-func HandleListTemplates(_ context.Context, _ mcp.CallToolRequest, tm templates.TemplateManager) (*mcp.CallToolResult, error) {
-	templateFiles, err := tm.ListTemplates()
+func HandleCheckUpdates(ctx context.Context, _ mcp.CallToolRequest, updater *engine.Updater) (*mcp.CallToolResult, error) {
+	check, err := updater.CheckUpdates(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list templates: %w", err)
+		return nil, err
 	}
 
-	if len(templateFiles) == 0 {
-		return mcp.NewToolResultText("No custom templates found."), nil
+	checkJSON, err := json.Marshal(check)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal update check: %w", err)
 	}
 
-	return mcp.NewToolResultText(fmt.Sprintf("Available templates:\n- %s", strings.Join(templateFiles, "\n- "))), nil
+	return mcp.NewToolResultText(string(checkJSON)), nil
 }
 
-func HandleGetTemplate(_ context.Context, request mcp.CallToolRequest, tm templates.TemplateManager) (*mcp.CallToolResult, error) {
+func HandleEngineUpdate(ctx context.Context, request mcp.CallToolRequest, updater *engine.Updater) (*mcp.CallToolResult, error) {
 	argMap, ok := request.Params.Arguments.(map[string]any)
 	if !ok {
 		return nil, fmt.Errorf("invalid arguments format")
 	}
 
-	name, ok := argMap["name"].(string)
-	if !ok || name == "" {
-		return nil, fmt.Errorf("invalid or missing name parameter")
-	}
+	version, _ := argMap["version"].(string)
 
-	content, err := tm.GetTemplate(name)
+	installed, err := updater.Update(ctx, version)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get template: %w", err)
+		return nil, fmt.Errorf("engine update failed: %w", err)
 	}
 
-	return mcp.NewToolResultText(string(content)), nil
+	return mcp.NewToolResultText(fmt.Sprintf("Installed nuclei-templates %s into '%s'.", installed, updater.TemplatesDir)), nil
 }
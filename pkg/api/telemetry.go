@@ -0,0 +1,37 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"nuclei-mcp/pkg/correlation"
+	"nuclei-mcp/pkg/telemetry"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// errToolResult marks the span of a tool call that returned an error result
+var errToolResult = errors.New("tool returned an error result")
+
+// traceTools records a span for each tool call, the parent of the spans of
+// the scans it runs
+func traceTools() server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			ctx, span := telemetry.Tracer().Start(ctx, "tool "+request.Params.Name, trace.WithAttributes(
+				attribute.String("mcp.tool", request.Params.Name),
+				attribute.String(correlation.MetaKey, correlation.FromContext(ctx)),
+			))
+			result, err := next(ctx, request)
+			status := err
+			if err == nil && result != nil && result.IsError {
+				status = errToolResult
+			}
+			telemetry.End(span, status)
+			return result, err
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"nuclei-mcp/pkg/policy"
+	"nuclei-mcp/pkg/templates"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// HandleTemplateHistory lists the saved versions of a custom template
+func HandleTemplateHistory(_ context.Context, request mcp.CallToolRequest, tm templates.TemplateManager) (*mcp.CallToolResult, error) {
+	argMap, _ := request.Params.Arguments.(map[string]any)
+	name, ok := argMap["name"].(string)
+	if !ok || name == "" {
+		return nil, fmt.Errorf("invalid or missing name parameter")
+	}
+
+	history, err := tm.History(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template history: %w", err)
+	}
+	if len(history) == 0 {
+		return mcp.NewToolResultText(fmt.Sprintf("Template '%s' has no saved versions.", name)), nil
+	}
+
+	historyJSON, err := json.Marshal(history)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal template history: %w", err)
+	}
+	return mcp.NewToolResultText(string(historyJSON)), nil
+}
+
+// HandleDiffTemplate returns a unified diff between two versions of a
+// custom template. to defaults to the template as it is now and from to the
+// newest earlier version with a different content.
+func HandleDiffTemplate(_ context.Context, request mcp.CallToolRequest, tm templates.TemplateManager) (*mcp.CallToolResult, error) {
+	argMap, _ := request.Params.Arguments.(map[string]any)
+	name, ok := argMap["name"].(string)
+	if !ok || name == "" {
+		return nil, fmt.Errorf("invalid or missing name parameter")
+	}
+	history, err := tm.History(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template history: %w", err)
+	}
+
+	var to []byte
+	toLabel := name + " (current)"
+	older := history
+	if version, ok := argMap["to"].(float64); ok {
+		if to, err = tm.GetVersion(name, int(version)); err != nil {
+			return nil, fmt.Errorf("failed to get template version: %w", err)
+		}
+		toLabel = fmt.Sprintf("%s@v%d", name, int(version))
+		older = older[:0:0]
+		for _, saved := range history {
+			if saved.Version < int(version) {
+				older = append(older, saved)
+			}
+		}
+	} else if to, err = tm.GetTemplate(name); err != nil {
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+
+	var from []byte
+	var fromLabel string
+	if version, ok := argMap["from"].(float64); ok {
+		if from, err = tm.GetVersion(name, int(version)); err != nil {
+			return nil, fmt.Errorf("failed to get template version: %w", err)
+		}
+		fromLabel = fmt.Sprintf("%s@v%d", name, int(version))
+	} else {
+		for i := len(older) - 1; i >= 0 && fromLabel == ""; i-- {
+			content, err := tm.GetVersion(name, older[i].Version)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get template version: %w", err)
+			}
+			if !bytes.Equal(content, to) {
+				from, fromLabel = content, fmt.Sprintf("%s@v%d", name, older[i].Version)
+			}
+		}
+		if fromLabel == "" {
+			return mcp.NewToolResultText(fmt.Sprintf("Template '%s' has no earlier version to compare with.", name)), nil
+		}
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(from)),
+		B:        difflib.SplitLines(string(to)),
+		FromFile: fromLabel,
+		ToFile:   toLabel,
+		Context:  3,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff template versions: %w", err)
+	}
+	if diff == "" {
+		return mcp.NewToolResultText(fmt.Sprintf("%s and %s are identical.", fromLabel, toLabel)), nil
+	}
+	return mcp.NewToolResultText(diff), nil
+}
+
+// HandleRollbackTemplate restores a saved version of a custom template. The
+// template as it was before the rollback stays in its history, and the
+// restored content is checked against the template policy like a new one.
+func HandleRollbackTemplate(_ context.Context, request mcp.CallToolRequest, tm templates.TemplateManager, templatePolicy *policy.TemplatePolicy) (*mcp.CallToolResult, error) {
+	argMap, _ := request.Params.Arguments.(map[string]any)
+	name, ok := argMap["name"].(string)
+	if !ok || name == "" {
+		return nil, fmt.Errorf("invalid or missing name parameter")
+	}
+	version, ok := argMap["version"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("invalid or missing version parameter")
+	}
+
+	content, err := tm.GetVersion(name, int(version))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template version: %w", err)
+	}
+	if templatePolicy != nil {
+		allowUnsafe, _ := argMap["allow_unsafe"].(bool)
+		approval, _ := argMap["approval"].(string)
+		if err := templatePolicy.CheckTemplate(content, policy.Override{Allow: allowUnsafe, Approval: approval}); err != nil {
+			return nil, err
+		}
+	}
+	if err := tm.AddTemplate(name, content); err != nil {
+		return nil, fmt.Errorf("failed to roll back template: %w", err)
+	}
+
+	return mcp.NewToolResultText(fmt.Sprintf("Template '%s' rolled back to version %d.", name, int(version))), nil
+}
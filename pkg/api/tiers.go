@@ -0,0 +1,64 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// maxTierFindings is the number of findings listed in a tier notification
+const maxTierFindings = 10
+
+// tierNotifier returns the callback of a tiered scan reporting each
+// completed tier to the client as a progress notification, or nil when the
+// request carries no progress token
+func tierNotifier(ctx context.Context, request mcp.CallToolRequest, target string, severity string) func(scanner.TierResult) {
+	if request.Params.Meta == nil || request.Params.Meta.ProgressToken == nil {
+		return nil
+	}
+	mcpServer := server.ServerFromContext(ctx)
+	if mcpServer == nil {
+		return nil
+	}
+	token := request.Params.Meta.ProgressToken
+	total := len(scanner.SeverityTiers(severity))
+	done := 0
+
+	return func(tier scanner.TierResult) {
+		done++
+		_ = mcpServer.SendNotificationToClient(ctx, "notifications/progress", map[string]any{
+			"progressToken": token,
+			"progress":      done,
+			"total":         total,
+			"message":       tierMessage(target, tier),
+		})
+	}
+}
+
+// tierMessage summarizes the findings of a completed tier
+func tierMessage(target string, tier scanner.TierResult) string {
+	findings := tier.Result.Findings
+	switch {
+	case tier.Err != nil && !errors.Is(tier.Err, scanner.ErrNoTemplates):
+		return fmt.Sprintf("%s tier of %s failed: %v", tier.Tier.Name, target, tier.Err)
+	case len(findings) == 0:
+		return fmt.Sprintf("%s tier of %s done: no findings", tier.Tier.Name, target)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s tier of %s done: %d findings", tier.Tier.Name, target, len(findings))
+	for i, finding := range findings {
+		if i == maxTierFindings {
+			fmt.Fprintf(&b, "\n... and %d more", len(findings)-maxTierFindings)
+			break
+		}
+		fmt.Fprintf(&b, "\n- [%s] %s (%s) at %s", finding.Info.SeverityHolder.Severity, finding.Info.Name, finding.TemplateID, finding.Matched)
+	}
+	return b.String()
+}
@@ -0,0 +1,317 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/encryption"
+	"nuclei-mcp/pkg/exclusions"
+	"nuclei-mcp/pkg/scanner"
+	"nuclei-mcp/pkg/tracker"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// findingSeverityRank orders severities from most to least urgent
+var findingSeverityRank = map[string]int{
+	"critical": 0,
+	"high":     1,
+	"medium":   2,
+	"low":      3,
+	"info":     4,
+	"unknown":  5,
+}
+
+// TrackedFinding is a cached finding with its lifecycle status, as listed
+// by list_findings
+type TrackedFinding struct {
+	Fingerprint string         `json:"fingerprint"`
+	Resource    string         `json:"resource"`
+	Target      string         `json:"target"`
+	TemplateID  string         `json:"template_id"`
+	Name        string         `json:"name"`
+	Severity    string         `json:"severity"`
+	Host        string         `json:"host"`
+	Matched     string         `json:"matched,omitempty"`
+	ScanTime    string         `json:"scan_time"`
+	Status      tracker.Status `json:"status"`
+	Note        string         `json:"note,omitempty"`
+	UpdatedAt   string         `json:"updated_at,omitempty"`
+	// AcceptedUntil is when an accepted risk expires, or expired
+	AcceptedUntil string `json:"accepted_until,omitempty"`
+}
+
+// HandleMarkRemediated marks a cached finding as remediated and schedules
+// a scan verifying the fix after the retest delay
+func HandleMarkRemediated(_ context.Context, request mcp.CallToolRequest, service scanner.ScannerService, store *tracker.Store, retestAfter time.Duration, key *encryption.Key) (*mcp.CallToolResult, error) {
+	argMap, ok := request.Params.Arguments.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid arguments format")
+	}
+
+	fingerprint, _ := argMap["fingerprint"].(string)
+	fingerprint = strings.TrimPrefix(strings.TrimSpace(fingerprint), cache.FindingScheme)
+	if fingerprint == "" {
+		return nil, fmt.Errorf("invalid or missing fingerprint parameter")
+	}
+	if raw, _ := argMap["retest_after"].(string); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("invalid retest_after: %s", raw)
+		}
+		retestAfter = parsed
+	}
+	note, _ := argMap["note"].(string)
+
+	result, finding, found := cache.FindFinding(service.GetAll(), fingerprint, key)
+	if !found {
+		return nil, fmt.Errorf("finding %s not found in cached scan results", fingerprint)
+	}
+
+	record, err := store.MarkRemediated(tracker.Record{
+		Fingerprint: fingerprint,
+		Target:      result.Target,
+		TemplateID:  finding.TemplateID,
+		Host:        finding.Host,
+		Note:        note,
+	}, retestAfter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark finding remediated: %w", err)
+	}
+
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal finding record: %w", err)
+	}
+
+	return mcp.NewToolResultText(string(recordJSON)), nil
+}
+
+func HandleListRetests(_ context.Context, request mcp.CallToolRequest, store *tracker.Store) (*mcp.CallToolResult, error) {
+	argMap, _ := request.Params.Arguments.(map[string]any)
+	resultFilter, _ := argMap["result"].(string)
+
+	records := []tracker.Record{}
+	for _, record := range store.List() {
+		if record.Retest == nil || (resultFilter != "" && record.Retest.Result != resultFilter) {
+			continue
+		}
+		records = append(records, record)
+	}
+
+	recordsJSON, err := json.Marshal(records)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal retests: %w", err)
+	}
+
+	return mcp.NewToolResultText(string(recordsJSON)), nil
+}
+
+// HandleSetFindingStatus moves a cached finding to another lifecycle status
+func HandleSetFindingStatus(ctx context.Context, request mcp.CallToolRequest, service scanner.ScannerService, store *tracker.Store, retestAfter time.Duration, key *encryption.Key) (*mcp.CallToolResult, error) {
+	argMap, ok := request.Params.Arguments.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid arguments format")
+	}
+
+	fingerprint, _ := argMap["fingerprint"].(string)
+	fingerprint = strings.TrimPrefix(strings.TrimSpace(fingerprint), cache.FindingScheme)
+	if fingerprint == "" {
+		return nil, fmt.Errorf("invalid or missing fingerprint parameter")
+	}
+	name, _ := argMap["status"].(string)
+	status, err := tracker.ParseStatus(name)
+	if err != nil {
+		return nil, err
+	}
+	if status == tracker.StatusRemediated {
+		// Remediated findings are always retested
+		return HandleMarkRemediated(ctx, request, service, store, retestAfter, key)
+	}
+	note, _ := argMap["note"].(string)
+	var acceptedUntil *time.Time
+	if raw, _ := argMap["accepted_until"].(string); strings.TrimSpace(raw) != "" {
+		if status != tracker.StatusAcceptedRisk {
+			return nil, fmt.Errorf("accepted_until only applies to the %s status", tracker.StatusAcceptedRisk)
+		}
+		expires, err := exclusions.ParseExpiry(raw, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		acceptedUntil = &expires
+	}
+
+	result, finding, found := cache.FindFinding(service.GetAll(), fingerprint, key)
+	if !found {
+		return nil, fmt.Errorf("finding %s not found in cached scan results", fingerprint)
+	}
+
+	record, err := store.SetStatus(tracker.Record{
+		Fingerprint:   fingerprint,
+		Target:        result.Target,
+		TemplateID:    finding.TemplateID,
+		Host:          finding.Host,
+		Note:          note,
+		AcceptedUntil: acceptedUntil,
+	}, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set finding status: %w", err)
+	}
+
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal finding record: %w", err)
+	}
+
+	return mcp.NewToolResultText(string(recordJSON)), nil
+}
+
+// DefaultAcceptanceWindow is how far ahead list_expiring_acceptances looks
+// for expiring risk acceptances unless told otherwise
+const DefaultAcceptanceWindow = 30 * 24 * time.Hour
+
+// ExpiringAcceptance is a risk acceptance listed by
+// list_expiring_acceptances
+type ExpiringAcceptance struct {
+	tracker.Record
+	// Expired is set once the acceptance expired and the finding was
+	// reopened
+	Expired bool `json:"expired"`
+}
+
+// HandleListExpiringAcceptances lists the risk acceptances expiring within
+// a window, and the findings already reopened by an expired acceptance, so
+// they can be reviewed before they resurface
+func HandleListExpiringAcceptances(_ context.Context, request mcp.CallToolRequest, store *tracker.Store) (*mcp.CallToolResult, error) {
+	argMap, _ := request.Params.Arguments.(map[string]any)
+	within := DefaultAcceptanceWindow
+	if raw, _ := argMap["within"].(string); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("invalid within: %s", raw)
+		}
+		within = parsed
+	}
+	includeExpired := true
+	if include, ok := argMap["include_expired"].(bool); ok {
+		includeExpired = include
+	}
+
+	acceptances := []ExpiringAcceptance{}
+	for _, record := range store.Acceptances(time.Now(), within) {
+		expired := record.Status != tracker.StatusAcceptedRisk
+		if expired && !includeExpired {
+			continue
+		}
+		acceptances = append(acceptances, ExpiringAcceptance{Record: record, Expired: expired})
+	}
+
+	acceptancesJSON, err := json.Marshal(acceptances)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal risk acceptances: %w", err)
+	}
+
+	return mcp.NewToolResultText(string(acceptancesJSON)), nil
+}
+
+// HandleListFindings lists the cached findings with their lifecycle status,
+// most severe first, filtered by status, severity, target and template.
+// A finding found by several scans is listed once, from its latest scan.
+func HandleListFindings(_ context.Context, request mcp.CallToolRequest, service scanner.ScannerService, store *tracker.Store) (*mcp.CallToolResult, error) {
+	argMap, _ := request.Params.Arguments.(map[string]any)
+	target, _ := argMap["target"].(string)
+	templateID, _ := argMap["template_id"].(string)
+
+	statuses := map[tracker.Status]bool{}
+	for _, name := range stringList(argMap["status"]) {
+		status, err := tracker.ParseStatus(name)
+		if err != nil {
+			return nil, err
+		}
+		statuses[status] = true
+	}
+	severities := map[string]bool{}
+	for _, name := range stringList(argMap["severity"]) {
+		name = strings.ToLower(name)
+		if _, ok := findingSeverityRank[name]; !ok {
+			return nil, fmt.Errorf("invalid severity: %s", name)
+		}
+		severities[name] = true
+	}
+
+	type latest struct {
+		result  cache.ScanResult
+		finding *TrackedFinding
+		rank    int
+	}
+	byFingerprint := map[string]latest{}
+	for _, result := range service.GetAll() {
+		if target != "" && result.Target != target {
+			continue
+		}
+		for _, finding := range result.Findings {
+			fingerprint := cache.Fingerprint(finding)
+			if seen, ok := byFingerprint[fingerprint]; ok && !result.ScanTime.After(seen.result.ScanTime) {
+				continue
+			}
+			sev := strings.ToLower(finding.Info.SeverityHolder.Severity.String())
+			rank, ok := findingSeverityRank[sev]
+			if !ok {
+				sev, rank = "unknown", findingSeverityRank["unknown"]
+			}
+			if (len(severities) > 0 && !severities[sev]) || (templateID != "" && finding.TemplateID != templateID) {
+				continue
+			}
+			byFingerprint[fingerprint] = latest{result: result, rank: rank, finding: &TrackedFinding{
+				Fingerprint: fingerprint,
+				Resource:    cache.FindingScheme + fingerprint,
+				Target:      result.Target,
+				TemplateID:  finding.TemplateID,
+				Name:        finding.Info.Name,
+				Severity:    sev,
+				Host:        finding.Host,
+				Matched:     finding.Matched,
+				ScanTime:    result.ScanTime.Format(time.RFC3339),
+				Status:      tracker.StatusNew,
+			}}
+		}
+	}
+
+	var entries []latest
+	for fingerprint, entry := range byFingerprint {
+		if record, ok := store.Get(fingerprint); ok {
+			entry.finding.Status = record.Status
+			entry.finding.Note = record.Note
+			entry.finding.UpdatedAt = record.UpdatedAt.Format(time.RFC3339)
+			if record.AcceptedUntil != nil {
+				entry.finding.AcceptedUntil = record.AcceptedUntil.Format(time.RFC3339)
+			}
+		}
+		if len(statuses) > 0 && !statuses[entry.finding.Status] {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].rank != entries[j].rank {
+			return entries[i].rank < entries[j].rank
+		}
+		return entries[i].finding.Fingerprint < entries[j].finding.Fingerprint
+	})
+
+	findings := make([]*TrackedFinding, 0, len(entries))
+	for _, entry := range entries {
+		findings = append(findings, entry.finding)
+	}
+	findingsJSON, err := json.Marshal(findings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal findings: %w", err)
+	}
+
+	return mcp.NewToolResultText(string(findingsJSON)), nil
+}
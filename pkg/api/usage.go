@@ -0,0 +1,247 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Limits of tool usage statistics
+const (
+	// usageSamples is how many recent latencies of each tool are kept for
+	// its latency percentiles
+	usageSamples = 512
+	// maxUsageError bounds the length of the last error kept per tool
+	maxUsageError = 300
+)
+
+// CodeOther counts the tool errors in usage statistics that carry no error
+// code
+const CodeOther ErrorCode = "OTHER"
+
+// UsageLatency summarizes the latencies of a tool's calls in milliseconds.
+// Percentiles cover the most recent calls.
+type UsageLatency struct {
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P95  float64 `json:"p95"`
+	Max  float64 `json:"max"`
+}
+
+// ToolUsage is how a tool was called since the server started
+type ToolUsage struct {
+	Name      string  `json:"name"`
+	Calls     int     `json:"calls"`
+	Errors    int     `json:"errors"`
+	ErrorRate float64 `json:"error_rate"`
+	// ErrorCodes counts the errors by their code, CodeOther for errors
+	// without one
+	ErrorCodes map[ErrorCode]int `json:"error_codes,omitempty"`
+	LastError  string            `json:"last_error,omitempty"`
+	Latency    UsageLatency      `json:"latency_ms"`
+	LastCalled time.Time         `json:"last_called"`
+}
+
+// UsageStats is how the tools of the server were called since it started,
+// most called first
+type UsageStats struct {
+	Since     time.Time   `json:"since"`
+	Calls     int         `json:"calls"`
+	Errors    int         `json:"errors"`
+	ErrorRate float64     `json:"error_rate"`
+	Tools     []ToolUsage `json:"tools"`
+}
+
+// toolStats accumulates the calls of a tool
+type toolStats struct {
+	calls      int
+	errors     int
+	codes      map[ErrorCode]int
+	lastError  string
+	total      time.Duration
+	max        time.Duration
+	samples    []time.Duration
+	next       int
+	lastCalled time.Time
+}
+
+// UsageTracker counts the calls of each tool with their errors and
+// latencies, in memory since the server started
+type UsageTracker struct {
+	started time.Time
+
+	mu    sync.Mutex
+	tools map[string]*toolStats
+}
+
+// NewUsageTracker creates an empty usage tracker
+func NewUsageTracker() *UsageTracker {
+	return &UsageTracker{started: time.Now(), tools: make(map[string]*toolStats)}
+}
+
+// WithUsageTracker records tool usage in usage, read through the usage
+// resource. Defaults to a tracker of the server alone.
+func WithUsageTracker(usage *UsageTracker) ServerOption {
+	return func(o *serverOptions) {
+		o.usage = usage
+	}
+}
+
+// Record counts a call of the named tool that took latency and returned
+// result and err
+func (u *UsageTracker) Record(name string, latency time.Duration, result *mcp.CallToolResult, err error) {
+	code, message, failed := usageError(result, err)
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	stats, ok := u.tools[name]
+	if !ok {
+		stats = &toolStats{codes: make(map[ErrorCode]int)}
+		u.tools[name] = stats
+	}
+	stats.calls++
+	stats.total += latency
+	stats.max = max(stats.max, latency)
+	stats.lastCalled = time.Now()
+	if len(stats.samples) < usageSamples {
+		stats.samples = append(stats.samples, latency)
+	} else {
+		stats.samples[stats.next] = latency
+		stats.next = (stats.next + 1) % usageSamples
+	}
+	if failed {
+		stats.errors++
+		stats.codes[code]++
+		if len(message) > maxUsageError {
+			message = message[:maxUsageError] + "..."
+		}
+		stats.lastError = message
+	}
+}
+
+// usageError reports whether a call failed, with its error code and
+// message. Error results of scan tools carry their code in a ToolError.
+func usageError(result *mcp.CallToolResult, err error) (ErrorCode, string, bool) {
+	if err != nil {
+		code := ErrorCodeOf(err)
+		if code == CodeScanFailed {
+			code = CodeOther
+		}
+		return code, err.Error(), true
+	}
+	if result == nil || !result.IsError {
+		return "", "", false
+	}
+	var message string
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			message = text.Text
+			break
+		}
+	}
+	var toolError ToolError
+	if json.Unmarshal([]byte(message), &toolError) == nil && toolError.Code != "" {
+		return toolError.Code, toolError.Message, true
+	}
+	return CodeOther, message, true
+}
+
+// Stats returns the usage of each tool called so far
+func (u *UsageTracker) Stats() UsageStats {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	stats := UsageStats{Since: u.started, Tools: make([]ToolUsage, 0, len(u.tools))}
+	for name, tool := range u.tools {
+		usage := ToolUsage{
+			Name:       name,
+			Calls:      tool.calls,
+			Errors:     tool.errors,
+			ErrorRate:  ratio(tool.errors, tool.calls),
+			LastError:  tool.lastError,
+			LastCalled: tool.lastCalled,
+			Latency: UsageLatency{
+				Mean: milliseconds(tool.total / time.Duration(tool.calls)),
+				P50:  milliseconds(percentile(tool.samples, 0.5)),
+				P95:  milliseconds(percentile(tool.samples, 0.95)),
+				Max:  milliseconds(tool.max),
+			},
+		}
+		if len(tool.codes) > 0 {
+			usage.ErrorCodes = make(map[ErrorCode]int, len(tool.codes))
+			for code, count := range tool.codes {
+				usage.ErrorCodes[code] = count
+			}
+		}
+		stats.Calls += tool.calls
+		stats.Errors += tool.errors
+		stats.Tools = append(stats.Tools, usage)
+	}
+	stats.ErrorRate = ratio(stats.Errors, stats.Calls)
+	sort.Slice(stats.Tools, func(i, j int) bool {
+		if stats.Tools[i].Calls != stats.Tools[j].Calls {
+			return stats.Tools[i].Calls > stats.Tools[j].Calls
+		}
+		return stats.Tools[i].Name < stats.Tools[j].Name
+	})
+	return stats
+}
+
+// ratio returns part of total rounded to four decimals, zero without total
+func ratio(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(part)/float64(total)*10000) / 10000
+}
+
+// milliseconds converts d to milliseconds rounded to a microsecond
+func milliseconds(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(time.Microsecond)) / 1000
+}
+
+// percentile returns the p-th percentile of samples by the nearest rank
+func percentile(samples []time.Duration, p float64) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	return sorted[min(max(rank, 1), len(sorted))-1]
+}
+
+// trackUsage records the latency and outcome of each tool call in usage
+func trackUsage(usage *UsageTracker) server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			started := time.Now()
+			result, err := next(ctx, request)
+			usage.Record(request.Params.Name, time.Since(started), result, err)
+			return result, err
+		}
+	}
+}
+
+// HandleUsageResource returns the usage statistics of the server's tools
+func HandleUsageResource(_ context.Context, request mcp.ReadResourceRequest, usage *UsageTracker) ([]mcp.ResourceContents, error) {
+	usageJSON, err := json.Marshal(usage.Stats())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal tool usage: %w", err)
+	}
+
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      request.Params.URI,
+			MIMEType: "application/json",
+			Text:     string(usageJSON),
+		},
+	}, nil
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"nuclei-mcp/pkg/wordlists"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// defaultWordlistPreview is the number of entries get_wordlist returns by
+// default
+const defaultWordlistPreview = 100
+
+// HandleAddWordlist stores a wordlist that fuzzing templates reference by
+// name
+func HandleAddWordlist(_ context.Context, request mcp.CallToolRequest, store *wordlists.Store) (*mcp.CallToolResult, error) {
+	argMap, ok := request.Params.Arguments.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid arguments format")
+	}
+
+	name, _ := argMap["name"].(string)
+	if name == "" {
+		return nil, fmt.Errorf("invalid or missing name parameter")
+	}
+	entries := stringList(argMap["entries"])
+	if content, _ := argMap["content"].(string); content != "" {
+		entries = append(entries, content)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("either entries or content parameter is required")
+	}
+	overwrite, _ := argMap["overwrite"].(bool)
+
+	list, err := store.Add(name, entries, overwrite)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add wordlist: %w", err)
+	}
+
+	listJSON, err := json.Marshal(list)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal wordlist: %w", err)
+	}
+
+	return mcp.NewToolResultText(string(listJSON)), nil
+}
+
+func HandleListWordlists(_ context.Context, _ mcp.CallToolRequest, store *wordlists.Store) (*mcp.CallToolResult, error) {
+	lists, err := store.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list wordlists: %w", err)
+	}
+
+	listsJSON, err := json.Marshal(lists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal wordlists: %w", err)
+	}
+
+	return mcp.NewToolResultText(string(listsJSON)), nil
+}
+
+// HandleGetWordlist returns the entries of a wordlist, up to limit
+func HandleGetWordlist(_ context.Context, request mcp.CallToolRequest, store *wordlists.Store) (*mcp.CallToolResult, error) {
+	argMap, ok := request.Params.Arguments.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid arguments format")
+	}
+
+	name, _ := argMap["name"].(string)
+	if name == "" {
+		return nil, fmt.Errorf("invalid or missing name parameter")
+	}
+	limit := defaultWordlistPreview
+	if raw, ok := argMap["limit"].(float64); ok && raw > 0 {
+		limit = int(raw)
+	}
+
+	entries, err := store.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	response := struct {
+		Name      string   `json:"name"`
+		Total     int      `json:"total"`
+		Entries   []string `json:"entries"`
+		Truncated bool     `json:"truncated,omitempty"`
+	}{Name: name, Total: len(entries), Entries: entries}
+	if len(entries) > limit {
+		response.Entries, response.Truncated = entries[:limit], true
+	}
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal wordlist: %w", err)
+	}
+
+	return mcp.NewToolResultText(string(responseJSON)), nil
+}
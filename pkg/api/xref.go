@@ -0,0 +1,224 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/scanner"
+	"nuclei-mcp/pkg/tracker"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	nucleiconfig "github.com/projectdiscovery/nuclei/v3/pkg/catalog/config"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+)
+
+var (
+	cvePattern = regexp.MustCompile(`^CVE-\d{4}-\d{4,}$`)
+	cwePattern = regexp.MustCompile(`^CWE-\d+$`)
+)
+
+// WithTemplateDirs sets the template directories cross_reference searches
+// in addition to the default nuclei templates directory
+func WithTemplateDirs(dirs ...string) ServerOption {
+	return func(o *serverOptions) {
+		o.templateDirs = dirs
+	}
+}
+
+// WithTemplateIndex sets the template index the template directories are
+// read through (an in-memory index by default)
+func WithTemplateIndex(idx *scanner.TemplateIndex) ServerOption {
+	return func(o *serverOptions) {
+		if idx != nil {
+			o.index = idx
+		}
+	}
+}
+
+// CrossReference lists the local templates and cached findings related to a
+// CVE or CWE
+type CrossReference struct {
+	ID        string         `json:"id"`
+	Kind      string         `json:"kind"`
+	Templates []xrefTemplate `json:"templates"`
+	Findings  []xrefFinding  `json:"findings"`
+}
+
+type xrefTemplate struct {
+	ID   string   `json:"id"`
+	Path string   `json:"path"`
+	Tags []string `json:"tags,omitempty"`
+	CVEs []string `json:"cve_ids,omitempty"`
+	CWEs []string `json:"cwe_ids,omitempty"`
+}
+
+type xrefFinding struct {
+	Target     string    `json:"target"`
+	TemplateID string    `json:"template_id"`
+	Name       string    `json:"name"`
+	Severity   string    `json:"severity"`
+	Host       string    `json:"host"`
+	Matched    string    `json:"matched,omitempty"`
+	ScanTime   time.Time `json:"scan_time"`
+	Status     string    `json:"status,omitempty"`
+	Resource   string    `json:"resource"`
+}
+
+// crossReference looks up id, a CVE or CWE ID, in the templates index reads
+// under dirs
+// and the findings of results. Findings match by their classification or by
+// being reported by a matching template. The latest scan of each finding
+// is listed, most recent first.
+func crossReference(id string, index *scanner.TemplateIndex, dirs []string, results []cache.ScanResult, findings *tracker.Store) (CrossReference, error) {
+	id = strings.ToUpper(strings.TrimSpace(id))
+	xref := CrossReference{ID: id, Templates: []xrefTemplate{}, Findings: []xrefFinding{}}
+	switch {
+	case cvePattern.MatchString(id):
+		xref.Kind = "cve"
+	case cwePattern.MatchString(id):
+		xref.Kind = "cwe"
+	default:
+		return CrossReference{}, fmt.Errorf("invalid id %q, use a CVE (e.g. CVE-2021-44228) or CWE (e.g. CWE-79) ID", id)
+	}
+
+	matchingTemplates := map[string]bool{}
+	for _, template := range index.Templates(dirs...) {
+		ids := template.CWEs
+		if xref.Kind == "cve" {
+			ids = append([]string{strings.ToUpper(template.ID)}, template.CVEs...)
+		}
+		if !slices.Contains(ids, id) {
+			continue
+		}
+		matchingTemplates[template.ID] = true
+		xref.Templates = append(xref.Templates, xrefTemplate{
+			ID:   template.ID,
+			Path: template.Path,
+			Tags: template.Tags,
+			CVEs: template.CVEs,
+			CWEs: template.CWEs,
+		})
+	}
+	sort.Slice(xref.Templates, func(i, j int) bool {
+		return xref.Templates[i].Path < xref.Templates[j].Path
+	})
+
+	results = append([]cache.ScanResult(nil), results...)
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].ScanTime.After(results[j].ScanTime)
+	})
+	seen := map[string]bool{}
+	for _, result := range results {
+		for _, finding := range result.Findings {
+			if finding == nil || !(matchingTemplates[finding.TemplateID] || classifiedAs(finding, xref.Kind, id)) {
+				continue
+			}
+			fingerprint := cache.Fingerprint(finding)
+			if seen[fingerprint] {
+				continue
+			}
+			seen[fingerprint] = true
+			entry := xrefFinding{
+				Target:     result.Target,
+				TemplateID: finding.TemplateID,
+				Name:       finding.Info.Name,
+				Severity:   finding.Info.SeverityHolder.Severity.String(),
+				Host:       finding.Host,
+				Matched:    finding.Matched,
+				ScanTime:   result.ScanTime,
+				Resource:   cache.FindingURI(finding),
+			}
+			if findings != nil {
+				entry.Status = string(findings.StatusOf(fingerprint))
+			}
+			xref.Findings = append(xref.Findings, entry)
+		}
+	}
+	return xref, nil
+}
+
+// classifiedAs reports whether the classification of a finding's template
+// lists id
+func classifiedAs(finding *output.ResultEvent, kind string, id string) bool {
+	if strings.EqualFold(finding.TemplateID, id) {
+		return true
+	}
+	classification := finding.Info.Classification
+	if classification == nil {
+		return false
+	}
+	ids := classification.CWEID.ToSlice()
+	if kind == "cve" {
+		ids = classification.CVEID.ToSlice()
+	}
+	for _, classified := range ids {
+		if strings.EqualFold(strings.TrimSpace(classified), id) {
+			return true
+		}
+	}
+	return false
+}
+
+// xrefDirs returns the template directories cross_reference searches
+func xrefDirs(options *serverOptions) []string {
+	var dirs []string
+	if defaultDir := nucleiconfig.DefaultConfig.TemplatesDirectory; defaultDir != "" {
+		dirs = append(dirs, defaultDir)
+	}
+	return append(dirs, options.templateDirs...)
+}
+
+// HandleCrossReference lists the templates and findings related to a CVE or
+// CWE ID
+func HandleCrossReference(_ context.Context, request mcp.CallToolRequest, service scanner.ScannerService, index *scanner.TemplateIndex, dirs []string, findings *tracker.Store) (*mcp.CallToolResult, error) {
+	argMap, ok := request.Params.Arguments.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid arguments format")
+	}
+
+	id, ok := argMap["id"].(string)
+	if !ok || id == "" {
+		return nil, fmt.Errorf("invalid or missing id parameter")
+	}
+
+	xref, err := crossReference(id, index, dirs, service.GetAll(), findings)
+	if err != nil {
+		return nil, err
+	}
+
+	xrefJSON, err := json.Marshal(xref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal cross reference: %w", err)
+	}
+
+	return mcp.NewToolResultText(string(xrefJSON)), nil
+}
+
+// HandleCrossReferenceResource serves xref://{id}
+func HandleCrossReferenceResource(_ context.Context, request mcp.ReadResourceRequest, service scanner.ScannerService, index *scanner.TemplateIndex, dirs []string, findings *tracker.Store) ([]mcp.ResourceContents, error) {
+	id := strings.TrimPrefix(request.Params.URI, "xref://")
+	xref, err := crossReference(id, index, dirs, service.GetAll(), findings)
+	if err != nil {
+		return nil, err
+	}
+
+	xrefJSON, err := json.Marshal(xref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal cross reference: %w", err)
+	}
+
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      request.Params.URI,
+			MIMEType: "application/json",
+			Text:     string(xrefJSON),
+		},
+	}, nil
+}
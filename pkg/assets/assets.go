@@ -0,0 +1,175 @@
+package assets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"nuclei-mcp/pkg/encryption"
+)
+
+// Asset is the recon context collected for a host: its addresses with
+// their reverse DNS names, the certificate it serves and the names
+// certificate transparency logs hold for its domain
+type Asset struct {
+	Host        string       `json:"host"`
+	Addresses   []Address    `json:"addresses,omitempty"`
+	Certificate *Certificate `json:"certificate,omitempty"`
+	// CTNames are the names logged in certificates for the host and its
+	// subdomains; kept from the last lookup that requested them
+	CTNames     []string   `json:"ct_names,omitempty"`
+	CTCheckedAt *time.Time `json:"ct_checked_at,omitempty"`
+	// JavaScript is what js_recon found in the host's scripts; kept until
+	// they are analysed again
+	JavaScript *JavaScript `json:"javascript,omitempty"`
+	// Errors holds the failure of each lookup (dns, tls, ct) that failed
+	Errors    map[string]string `json:"errors,omitempty"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// Address is a resolved address of a host
+type Address struct {
+	IP         string   `json:"ip"`
+	ReverseDNS []string `json:"reverse_dns,omitempty"`
+}
+
+// Certificate is the leaf certificate a host serves
+type Certificate struct {
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+	// SANs are the DNS names and IP addresses the certificate is valid for
+	SANs []string `json:"sans,omitempty"`
+}
+
+// Registry keeps the collected assets in a JSON file, one per host
+type Registry struct {
+	path string
+	key  *encryption.Key
+
+	mu     sync.RWMutex
+	assets map[string]Asset
+}
+
+// RegistryOption configures a Registry
+type RegistryOption func(*Registry)
+
+// WithEncryptionKey encrypts the file with key. A plain file is read and
+// encrypted at the next change.
+func WithEncryptionKey(key *encryption.Key) RegistryOption {
+	return func(r *Registry) {
+		r.key = key
+	}
+}
+
+// Open loads the assets stored at path. A missing file holds no assets.
+func Open(path string, opts ...RegistryOption) (*Registry, error) {
+	r := &Registry{path: path, assets: map[string]Asset{}}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read asset registry: %w", err)
+	}
+	if encryption.Sealed(data) {
+		if data, err = r.key.Open(data); err != nil {
+			return nil, fmt.Errorf("failed to decrypt asset registry %s: %w", path, err)
+		}
+	}
+	var assets []Asset
+	if err := json.Unmarshal(data, &assets); err != nil {
+		return nil, fmt.Errorf("failed to parse asset registry %s: %w", path, err)
+	}
+	for _, asset := range assets {
+		r.assets[asset.Host] = asset
+	}
+	return r, nil
+}
+
+// Get returns the asset of host
+func (r *Registry) Get(host string) (Asset, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	asset, ok := r.assets[strings.ToLower(host)]
+	return asset, ok
+}
+
+// List returns the assets sorted by host
+func (r *Registry) List() []Asset {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	assets := make([]Asset, 0, len(r.assets))
+	for _, asset := range r.assets {
+		assets = append(assets, asset)
+	}
+	sort.Slice(assets, func(i, j int) bool { return assets[i].Host < assets[j].Host })
+	return assets
+}
+
+// Put stores asset, replacing the previous asset of its host. Certificate
+// transparency names and the JavaScript analysis of the previous asset are
+// kept when asset was collected without them.
+func (r *Registry) Put(asset Asset) (Asset, error) {
+	asset.Host = strings.ToLower(strings.TrimSpace(asset.Host))
+	if asset.Host == "" {
+		return Asset{}, fmt.Errorf("asset host is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if existing, ok := r.assets[asset.Host]; ok && asset.CTCheckedAt == nil {
+		asset.CTNames, asset.CTCheckedAt = existing.CTNames, existing.CTCheckedAt
+	}
+	if existing, ok := r.assets[asset.Host]; ok && asset.JavaScript == nil {
+		asset.JavaScript = existing.JavaScript
+	}
+
+	assets := make([]Asset, 0, len(r.assets)+1)
+	for host, existing := range r.assets {
+		if host != asset.Host {
+			assets = append(assets, existing)
+		}
+	}
+	assets = append(assets, asset)
+	sort.Slice(assets, func(i, j int) bool { return assets[i].Host < assets[j].Host })
+	if err := r.save(assets); err != nil {
+		return Asset{}, err
+	}
+	r.assets[asset.Host] = asset
+	return asset, nil
+}
+
+func (r *Registry) save(assets []Asset) error {
+	data, err := json.MarshalIndent(assets, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode asset registry: %w", err)
+	}
+	if r.key != nil {
+		data = r.key.Seal(data)
+	}
+	if dir := filepath.Dir(r.path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create asset registry directory: %w", err)
+		}
+	}
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write asset registry: %w", err)
+	}
+	if err := os.Rename(tmp, r.path); err != nil {
+		return fmt.Errorf("failed to write asset registry: %w", err)
+	}
+	return nil
+}
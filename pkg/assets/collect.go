@@ -0,0 +1,264 @@
+package assets
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"nuclei-mcp/pkg/policy"
+)
+
+// DefaultCTLogURL is the crt.sh instance queried for certificate
+// transparency names
+const DefaultCTLogURL = "https://crt.sh"
+
+// DefaultTimeout bounds each lookup of a collection
+const DefaultTimeout = 10 * time.Second
+
+// Resolver resolves host names and addresses
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+}
+
+// Collector gathers the recon context of targets
+type Collector struct {
+	resolver Resolver
+	client   *http.Client
+	ctLogURL string
+	timeout  time.Duration
+	egress   *policy.EgressPolicy
+	self     *policy.SelfTargetGuard
+}
+
+// Option configures a Collector
+type Option func(*Collector)
+
+// WithResolver replaces the system resolver
+func WithResolver(resolver Resolver) Option {
+	return func(c *Collector) {
+		c.resolver = resolver
+	}
+}
+
+// WithCTLogURL queries another crt.sh compatible service for certificate
+// transparency names
+func WithCTLogURL(ctLogURL string) Option {
+	return func(c *Collector) {
+		if ctLogURL != "" {
+			c.ctLogURL = strings.TrimRight(ctLogURL, "/")
+		}
+	}
+}
+
+// WithTimeout bounds each lookup of a collection
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Collector) {
+		if timeout > 0 {
+			c.timeout = timeout
+		}
+	}
+}
+
+// WithTargetPolicy checks the pages and scripts JavaScript fetches, and
+// every redirect they follow, against the egress policy and the self-target
+// guard of scans. Either may be nil.
+func WithTargetPolicy(egress *policy.EgressPolicy, self *policy.SelfTargetGuard) Option {
+	return func(c *Collector) {
+		c.egress, c.self = egress, self
+	}
+}
+
+// NewCollector creates a collector
+func NewCollector(opts ...Option) *Collector {
+	c := &Collector{
+		resolver: net.DefaultResolver,
+		ctLogURL: DefaultCTLogURL,
+		timeout:  DefaultTimeout,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.client = &http.Client{Timeout: c.timeout}
+	return c
+}
+
+// Collect resolves the host of target and the reverse DNS names of its
+// addresses, and reads the certificate served on the target's TLS port.
+// With ct, the names logged for the host's domain are looked up too.
+// Failed lookups are recorded in the asset rather than returned.
+func (c *Collector) Collect(ctx context.Context, target string, ct bool) Asset {
+	host, port := hostPort(target)
+	asset := Asset{Host: host, UpdatedAt: time.Now().UTC()}
+	fail := func(lookup string, err error) {
+		if asset.Errors == nil {
+			asset.Errors = map[string]string{}
+		}
+		asset.Errors[lookup] = err.Error()
+	}
+
+	addresses, err := c.addresses(ctx, host)
+	if err != nil {
+		fail("dns", err)
+	}
+	asset.Addresses = addresses
+
+	// Read the certificate from the first resolved address, so the host
+	// is not resolved again by another resolver
+	dialHost := host
+	if len(addresses) > 0 {
+		dialHost = addresses[0].IP
+	}
+	if cert, err := c.certificate(ctx, host, dialHost, port); err != nil {
+		fail("tls", err)
+	} else {
+		asset.Certificate = cert
+	}
+
+	if ct && net.ParseIP(host) == nil {
+		names, err := c.ctNames(ctx, host)
+		if err != nil {
+			fail("ct", err)
+		} else {
+			checked := time.Now().UTC()
+			asset.CTNames, asset.CTCheckedAt = names, &checked
+		}
+	}
+	return asset
+}
+
+func (c *Collector) addresses(ctx context.Context, host string) ([]Address, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var ips []string
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []string{ip.String()}
+	} else {
+		addrs, err := c.resolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP.String())
+		}
+	}
+
+	addresses := make([]Address, 0, len(ips))
+	for _, ip := range ips {
+		address := Address{IP: ip}
+		// Addresses without a PTR record are common; not an error
+		if names, err := c.resolver.LookupAddr(ctx, ip); err == nil {
+			for _, name := range names {
+				address.ReverseDNS = append(address.ReverseDNS, strings.TrimSuffix(name, "."))
+			}
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, nil
+}
+
+// certificate reads the leaf certificate served for host on dialHost:port.
+// The certificate is not verified: self-signed and expired certificates
+// are context too.
+func (c *Collector) certificate(ctx context.Context, host, dialHost, port string) (*Certificate, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	config := &tls.Config{InsecureSkipVerify: true}
+	if net.ParseIP(host) == nil {
+		config.ServerName = host
+	}
+	dialer := &tls.Dialer{Config: config}
+	address := net.JoinHostPort(dialHost, port)
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", address, err)
+	}
+	defer conn.Close()
+
+	certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificate served on %s", address)
+	}
+	leaf := certs[0]
+	cert := &Certificate{
+		Subject:   leaf.Subject.String(),
+		Issuer:    leaf.Issuer.String(),
+		NotBefore: leaf.NotBefore.UTC(),
+		NotAfter:  leaf.NotAfter.UTC(),
+		SANs:      append([]string(nil), leaf.DNSNames...),
+	}
+	for _, ip := range leaf.IPAddresses {
+		cert.SANs = append(cert.SANs, ip.String())
+	}
+	return cert, nil
+}
+
+// ctNames returns the distinct names logged in certificates for domain and
+// its subdomains
+func (c *Collector) ctNames(ctx context.Context, domain string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	query := url.Values{"q": {"%." + domain}, "output": {"json"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.ctLogURL+"/?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create certificate transparency request: %w", err)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query certificate transparency logs: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("certificate transparency logs returned %s", resp.Status)
+	}
+
+	var entries []struct {
+		NameValue string `json:"name_value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("failed to parse certificate transparency response: %w", err)
+	}
+
+	seen := map[string]bool{}
+	names := []string{}
+	for _, entry := range entries {
+		for _, name := range strings.Split(entry.NameValue, "\n") {
+			name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "*."))
+			if name == "" || seen[name] || (name != domain && !strings.HasSuffix(name, "."+domain)) {
+				continue
+			}
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// hostPort returns the lowercased host of target and the port its TLS
+// certificate is read from: the target's port for https URLs, else 443
+func hostPort(target string) (string, string) {
+	raw := strings.TrimSpace(target)
+	if !strings.Contains(raw, "://") {
+		raw = "//" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return strings.ToLower(strings.TrimSpace(target)), "443"
+	}
+	port := "443"
+	if u.Port() != "" && (u.Scheme == "https" || u.Scheme == "") {
+		port = u.Port()
+	}
+	return strings.ToLower(u.Hostname()), port
+}
@@ -0,0 +1,241 @@
+package assets
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"nuclei-mcp/pkg/policy"
+)
+
+const (
+	// maxScripts bounds the scripts fetched from a page
+	maxScripts = 50
+	// maxScriptSize bounds the bytes read of a page or script
+	maxScriptSize = 5 << 20
+	// maxEndpoints bounds the endpoints kept for a host
+	maxEndpoints = 500
+	// maxRedirects bounds the redirects followed fetching a page or script
+	maxRedirects = 5
+)
+
+// JavaScript is what the scripts a host serves reveal: the API endpoints
+// they call and the keys and tokens embedded in them
+type JavaScript struct {
+	// Files are the scripts analysed
+	Files     []string `json:"files"`
+	Endpoints []string `json:"endpoints,omitempty"`
+	Secrets   []Secret `json:"secrets,omitempty"`
+	// Errors holds the failure of each script that could not be fetched
+	Errors    map[string]string `json:"errors,omitempty"`
+	CheckedAt time.Time         `json:"checked_at"`
+}
+
+// Secret is a key or token found in a script. Only its first characters
+// are kept.
+type Secret struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+	File  string `json:"file"`
+}
+
+var (
+	scriptSrcPattern = regexp.MustCompile(`(?i)<script[^>]+src\s*=\s*["']([^"']+)["']`)
+	// Quoted absolute URLs and root-relative paths
+	absoluteURLPattern  = regexp.MustCompile("[\"'`](https?://[^\"'`\\s<>]+)[\"'`]")
+	relativePathPattern = regexp.MustCompile("[\"'`](/[A-Za-z0-9_\\-.~/{}:$]*[A-Za-z][A-Za-z0-9_\\-.~/{}:$?=&%]*)[\"'`]")
+	// Paths of static files are not endpoints
+	staticPattern = regexp.MustCompile(`(?i)\.(?:png|jpe?g|gif|svg|ico|webp|css|woff2?|ttf|eot|map)(?:\?|$)`)
+)
+
+// secretPatterns are the kinds of keys and tokens looked for in scripts
+var secretPatterns = []struct {
+	kind    string
+	pattern *regexp.Regexp
+}{
+	{"aws-access-key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"google-api-key", regexp.MustCompile(`\bAIza[0-9A-Za-z_\-]{35}\b`)},
+	{"github-token", regexp.MustCompile(`\bgh[pousr]_[0-9A-Za-z]{36}\b`)},
+	{"slack-token", regexp.MustCompile(`\bxox[abprs]-[0-9A-Za-z\-]{10,}\b`)},
+	{"stripe-key", regexp.MustCompile(`\b[rs]k_live_[0-9A-Za-z]{20,}\b`)},
+	{"jwt", regexp.MustCompile(`\beyJ[0-9A-Za-z_\-]{10,}\.eyJ[0-9A-Za-z_\-]{10,}\.[0-9A-Za-z_\-]{10,}`)},
+	{"private-key", regexp.MustCompile(`-----BEGIN (?:RSA |EC |DSA |OPENSSH )?PRIVATE KEY-----`)},
+}
+
+// JavaScript fetches the page at target and the scripts it loads from the
+// target's host, and extracts the endpoints and secrets
+// they contain. A target ending in .js is analysed as the only script.
+// Scripts that fail to load are recorded in the result rather than
+// returned.
+func (c *Collector) JavaScript(ctx context.Context, target string) (*JavaScript, error) {
+	raw := strings.TrimSpace(target)
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	page, err := url.Parse(raw)
+	if err != nil || page.Hostname() == "" {
+		return nil, fmt.Errorf("invalid target %q", target)
+	}
+
+	var scripts []string
+	if strings.HasSuffix(strings.ToLower(page.Path), ".js") {
+		scripts = []string{page.String()}
+	} else {
+		body, err := c.fetch(ctx, page.String())
+		if err != nil {
+			return nil, err
+		}
+		scripts = scriptURLs(page, body)
+	}
+
+	js := &JavaScript{Files: []string{}, CheckedAt: time.Now().UTC()}
+	endpoints := map[string]bool{}
+	secrets := map[string]bool{}
+	for _, script := range scripts {
+		body, err := c.fetch(ctx, script)
+		if err != nil {
+			if js.Errors == nil {
+				js.Errors = map[string]string{}
+			}
+			js.Errors[script] = err.Error()
+			continue
+		}
+		js.Files = append(js.Files, script)
+		for _, endpoint := range scriptEndpoints(body) {
+			endpoints[endpoint] = true
+		}
+		for _, secret := range scriptSecrets(body) {
+			if key := secret.Kind + secret.Value; !secrets[key] {
+				secrets[key] = true
+				secret.Value, secret.File = maskSecret(secret.Value), script
+				js.Secrets = append(js.Secrets, secret)
+			}
+		}
+	}
+
+	for endpoint := range endpoints {
+		js.Endpoints = append(js.Endpoints, endpoint)
+	}
+	sort.Strings(js.Endpoints)
+	if len(js.Endpoints) > maxEndpoints {
+		js.Endpoints = js.Endpoints[:maxEndpoints]
+	}
+	return js, nil
+}
+
+// fetch returns the body of the page or script at target, after checking
+// it against the collector's target policy. Redirects are only followed on
+// target's host, and are checked too.
+func (c *Collector) fetch(ctx context.Context, target string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request for %s: %w", target, err)
+	}
+	if err := c.checkTarget(ctx, req.URL); err != nil {
+		return "", err
+	}
+	client := *c.client
+	client.CheckRedirect = func(redirect *http.Request, via []*http.Request) error {
+		if !strings.EqualFold(redirect.URL.Host, req.URL.Host) {
+			return fmt.Errorf("refused redirect to another host, %s", redirect.URL.Redacted())
+		}
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		return c.checkTarget(ctx, redirect.URL)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch %s: %w", target, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s returned %s", target, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxScriptSize))
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", target, err)
+	}
+	return string(body), nil
+}
+
+// checkTarget checks u against the collector's egress policy and
+// self-target guard
+func (c *Collector) checkTarget(ctx context.Context, u *url.URL) error {
+	if err := c.egress.CheckTarget(ctx, u.String()); err != nil {
+		return err
+	}
+	return c.self.Check(ctx, u.String(), policy.Override{})
+}
+
+// scriptURLs returns the distinct scripts the page loads from its host.
+// Scripts of other hosts, such as CDNs, may be out of scope.
+func scriptURLs(page *url.URL, body string) []string {
+	host := strings.ToLower(page.Hostname())
+	seen := map[string]bool{}
+	var scripts []string
+	for _, match := range scriptSrcPattern.FindAllStringSubmatch(body, -1) {
+		src, err := page.Parse(strings.TrimSpace(match[1]))
+		if err != nil || (src.Scheme != "http" && src.Scheme != "https") {
+			continue
+		}
+		if strings.ToLower(src.Hostname()) != host {
+			continue
+		}
+		src.Fragment = ""
+		if script := src.String(); !seen[script] {
+			seen[script] = true
+			scripts = append(scripts, script)
+		}
+		if len(scripts) == maxScripts {
+			break
+		}
+	}
+	return scripts
+}
+
+// scriptEndpoints returns the URLs and root-relative paths quoted in a
+// script, except those of static files
+func scriptEndpoints(body string) []string {
+	var endpoints []string
+	for _, pattern := range []*regexp.Regexp{absoluteURLPattern, relativePathPattern} {
+		for _, match := range pattern.FindAllStringSubmatch(body, -1) {
+			endpoint := match[1]
+			if strings.HasPrefix(endpoint, "//") || staticPattern.MatchString(endpoint) {
+				continue
+			}
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return endpoints
+}
+
+// scriptSecrets returns the keys and tokens in a script
+func scriptSecrets(body string) []Secret {
+	var secrets []Secret
+	for _, secret := range secretPatterns {
+		for _, value := range secret.pattern.FindAllString(body, -1) {
+			secrets = append(secrets, Secret{Kind: secret.kind, Value: value})
+		}
+	}
+	return secrets
+}
+
+// maskSecret keeps the first characters of a secret, enough to tell
+// which key it is
+func maskSecret(value string) string {
+	keep := 8
+	if len(value) <= 2*keep {
+		keep = len(value) / 4
+	}
+	return value[:keep] + strings.Repeat("*", len(value)-keep)
+}
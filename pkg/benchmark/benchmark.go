@@ -0,0 +1,269 @@
+package benchmark
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"nuclei-mcp/pkg/policy"
+	"nuclei-mcp/pkg/sandbox"
+	"nuclei-mcp/pkg/scanner"
+)
+
+// suite holds the benchmark templates
+//
+//go:embed templates/*.yaml
+var suite embed.FS
+
+// DefaultScans is the number of scans run for each setting
+const DefaultScans = 8
+
+// DefaultConcurrency are the concurrency levels measured when none are given
+var DefaultConcurrency = []int{1, 2, 4, 8}
+
+// responses serve a page matched by the benchmark template for every path
+var responses = []sandbox.Response{
+	{Status: http.StatusOK, Headers: map[string]string{"Content-Type": "text/html"},
+		Body: "<html><body>nuclei-mcp-benchmark</body></html>"},
+}
+
+// Setting is a combination of scan settings to measure
+type Setting struct {
+	// Concurrency is the number of scans run at once
+	Concurrency int `json:"concurrency"`
+	// RateLimit caps the requests per second of each scan; zero keeps
+	// nuclei's default
+	RateLimit int `json:"rate_limit"`
+}
+
+// Options configure a benchmark run
+type Options struct {
+	// Concurrency and RateLimits are combined into the measured settings
+	Concurrency []int
+	RateLimits  []int
+	// Scans is the number of scans run for each setting
+	Scans int
+}
+
+// Latency summarizes the durations of the scans of a setting
+type Latency struct {
+	Min string `json:"min"`
+	P50 string `json:"p50"`
+	P90 string `json:"p90"`
+	P99 string `json:"p99"`
+	Max string `json:"max"`
+}
+
+// Measurement is the outcome of the scans of one setting
+type Measurement struct {
+	Setting
+	Scans             int     `json:"scans"`
+	Failed            int     `json:"failed"`
+	Error             string  `json:"error,omitempty"`
+	Requests          int     `json:"requests"`
+	Duration          string  `json:"duration"`
+	RequestsPerSecond float64 `json:"requests_per_second"`
+	ScansPerMinute    float64 `json:"scans_per_minute"`
+	Latency           Latency `json:"latency"`
+}
+
+// Report is the outcome of a benchmark run
+type Report struct {
+	Templates    int           `json:"templates"`
+	Measurements []Measurement `json:"measurements"`
+	// Best is the setting with the highest request throughput
+	Best *Setting `json:"best,omitempty"`
+}
+
+// Settings returns the combinations of the concurrency levels and rate
+// limits of opts, with the defaults for those not given
+func (opts Options) Settings() []Setting {
+	concurrency := opts.Concurrency
+	if len(concurrency) == 0 {
+		concurrency = DefaultConcurrency
+	}
+	rateLimits := opts.RateLimits
+	if len(rateLimits) == 0 {
+		rateLimits = []int{0}
+	}
+	var settings []Setting
+	for _, c := range concurrency {
+		for _, r := range rateLimits {
+			settings = append(settings, Setting{Concurrency: c, RateLimit: r})
+		}
+	}
+	return settings
+}
+
+// Validate checks the settings and scan count of opts
+func (opts Options) Validate() error {
+	for _, c := range opts.Concurrency {
+		if c < 1 {
+			return fmt.Errorf("concurrency must be at least 1, got %d", c)
+		}
+	}
+	for _, r := range opts.RateLimits {
+		if r < 0 {
+			return fmt.Errorf("rate limit must not be negative, got %d", r)
+		}
+	}
+	if opts.Scans < 0 {
+		return fmt.Errorf("scans must not be negative, got %d", opts.Scans)
+	}
+	return nil
+}
+
+// Run scans an in-process test server with a synthetic template through
+// service at each setting of opts, measuring request throughput and scan
+// latency. The scans go through the service's queue, quotas and engine
+// like any other background scan, so the results reflect its configuration
+// and the host's hardware.
+func Run(ctx context.Context, service scanner.ScannerService, opts Options) (Report, error) {
+	if err := opts.Validate(); err != nil {
+		return Report{}, err
+	}
+	scans := opts.Scans
+	if scans == 0 {
+		scans = DefaultScans
+	}
+
+	dir, err := os.MkdirTemp("", "nuclei-mcp-benchmark-")
+	if err != nil {
+		return Report{}, fmt.Errorf("failed to create benchmark directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	templates, err := fs.Glob(suite, "templates/*.yaml")
+	if err != nil {
+		return Report{}, err
+	}
+	for _, name := range templates {
+		content, err := suite.ReadFile(name)
+		if err != nil {
+			return Report{}, err
+		}
+		if err := os.WriteFile(filepath.Join(dir, filepath.Base(name)), content, 0644); err != nil {
+			return Report{}, fmt.Errorf("failed to write benchmark template: %w", err)
+		}
+	}
+
+	report := Report{Templates: len(templates)}
+	best := -1.0
+	for _, setting := range opts.Settings() {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		measurement := measure(ctx, service, dir, setting, scans)
+		report.Measurements = append(report.Measurements, measurement)
+		if measurement.Failed == 0 && measurement.RequestsPerSecond > best {
+			best = measurement.RequestsPerSecond
+			chosen := setting
+			report.Best = &chosen
+		}
+	}
+	return report, nil
+}
+
+// measure runs scans scans of the templates in dir at setting against a
+// fresh test server
+func measure(ctx context.Context, service scanner.ScannerService, dir string, setting Setting, scans int) Measurement {
+	srv := sandbox.NewServer(responses)
+	defer srv.Close()
+
+	measurement := Measurement{Setting: setting, Scans: scans}
+	durations := make([]time.Duration, 0, scans)
+	var mu sync.Mutex
+	var firstErr error
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for worker := 0; worker < setting.Concurrency; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range jobs {
+				scanStart := time.Now()
+				// The test server runs in-process, so the scans target the
+				// server's own host on purpose
+				_, err := service.ThreadSafeScan(ctx, srv.URL(), "", "http", nil,
+					scanner.WithTemplateSources(dir),
+					scanner.WithPassive(false),
+					scanner.WithRateLimit(setting.RateLimit),
+					scanner.WithPriority(scanner.PriorityBackground),
+					scanner.WithFreshResult(),
+					scanner.WithSelfTargetAllowed("benchmark"),
+				)
+				elapsed := time.Since(scanStart)
+
+				mu.Lock()
+				if err != nil {
+					measurement.Failed++
+					if firstErr == nil {
+						firstErr = err
+					}
+				} else {
+					durations = append(durations, elapsed)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := 0; i < scans; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	measurement.Requests = len(srv.Requests())
+	measurement.Duration = elapsed.Round(time.Millisecond).String()
+	if seconds := elapsed.Seconds(); seconds > 0 {
+		measurement.RequestsPerSecond = round(float64(measurement.Requests) / seconds)
+		measurement.ScansPerMinute = round(float64(len(durations)) / seconds * 60)
+	}
+	measurement.Latency = latency(durations)
+	if firstErr != nil {
+		measurement.Error = firstErr.Error()
+		if errors.Is(firstErr, policy.ErrDenied) {
+			measurement.Error += " (the benchmark server listens on a loopback address, which policy.egress.deny_private blocks)"
+		}
+	}
+	return measurement
+}
+
+// latency summarizes durations with nearest-rank percentiles
+func latency(durations []time.Duration) Latency {
+	if len(durations) == 0 {
+		return Latency{}
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	percentile := func(p float64) string {
+		rank := int(math.Ceil(p/100*float64(len(durations)))) - 1
+		if rank < 0 {
+			rank = 0
+		}
+		return durations[rank].Round(time.Millisecond).String()
+	}
+	return Latency{
+		Min: percentile(0),
+		P50: percentile(50),
+		P90: percentile(90),
+		P99: percentile(99),
+		Max: durations[len(durations)-1].Round(time.Millisecond).String(),
+	}
+}
+
+// round rounds v to two decimals
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,416 @@
+package bridge
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	requestIDPrefix = "nuclei-mcp-"
+
+	methodInitialize        = "initialize"
+	methodInitialized       = "notifications/initialized"
+	methodRootsListChanged  = "notifications/roots/list_changed"
+	methodRootsList         = "roots/list"
+	methodCreateMessage     = "sampling/createMessage"
+	methodElicit            = "elicitation/create"
+	defaultRootsListTimeout = 10 * time.Second
+)
+
+// ErrUnsupported is returned when the client did not declare the capability a
+// request needs
+var ErrUnsupported = errors.New("client does not support this capability")
+
+// message is the subset of a JSON-RPC message the bridge inspects
+type message struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+type response struct {
+	result json.RawMessage
+	err    error
+}
+
+// RequestHandler answers a client request on behalf of the MCP server
+type RequestHandler func(ctx context.Context, params json.RawMessage) (any, error)
+
+// handledRequest is a client request method answered by the bridge
+type handledRequest struct {
+	capability string
+	handler    RequestHandler
+}
+
+// Bridge sits between the stdio streams and the MCP server so the server can
+// send requests to the client (roots, sampling, elicitation), which the stdio
+// transport does not support, and answer client requests the server does not
+// implement (completions). Client messages are passed through to the server
+// unchanged, except responses to the bridge's own requests and the requests
+// the bridge answers.
+type Bridge struct {
+	in      io.Reader
+	reader  *io.PipeReader
+	forward *io.PipeWriter
+	writer  *lockedWriter
+
+	nextID  atomic.Int64
+	pending sync.Map // request ID -> chan response
+
+	handlers map[string]handledRequest
+	// initializeID is the ID of the client's initialize request, whose
+	// result gets the capabilities of the handled requests
+	initializeID json.RawMessage
+
+	mu           sync.RWMutex
+	capabilities map[string]json.RawMessage
+	roots        []mcp.Root
+	rootsWaiters []chan struct{}
+	rootsFetched bool
+}
+
+// NewBridge creates a bridge reading client messages from in and writing
+// server messages to out. Call Start before serving.
+func NewBridge(in io.Reader, out io.Writer) *Bridge {
+	reader, forward := io.Pipe()
+	b := &Bridge{
+		in:       in,
+		reader:   reader,
+		forward:  forward,
+		handlers: map[string]handledRequest{},
+	}
+	b.writer = &lockedWriter{w: out, rewrite: b.advertise}
+	return b
+}
+
+// HandleRequest answers client requests for method with handler instead of
+// forwarding them to the MCP server. A non-empty capability is added to the
+// server capabilities in the initialize result. Register handlers before
+// calling Start.
+func (b *Bridge) HandleRequest(method string, capability string, handler RequestHandler) {
+	b.handlers[method] = handledRequest{capability: capability, handler: handler}
+}
+
+// Reader returns the stream of client messages for the MCP server
+func (b *Bridge) Reader() io.Reader {
+	return b.reader
+}
+
+// Writer returns the stream the MCP server writes its messages to
+func (b *Bridge) Writer() io.Writer {
+	return b.writer
+}
+
+// Start reads client messages until the input is closed or ctx is done
+func (b *Bridge) Start(ctx context.Context) {
+	go b.read(ctx)
+}
+
+func (b *Bridge) read(ctx context.Context) {
+	reader := bufio.NewReader(b.in)
+	for ctx.Err() == nil {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 && !b.intercept(ctx, line) {
+			if _, werr := b.forward.Write(line); werr != nil {
+				return
+			}
+		}
+		if err != nil {
+			b.forward.CloseWithError(err)
+			b.failPending(fmt.Errorf("client connection closed"))
+			return
+		}
+	}
+	b.forward.Close()
+}
+
+// intercept inspects a client message and reports whether it was consumed by
+// the bridge
+func (b *Bridge) intercept(ctx context.Context, line []byte) bool {
+	var msg message
+	if err := json.Unmarshal(line, &msg); err != nil {
+		return false
+	}
+
+	switch msg.Method {
+	case "":
+		var id string
+		if json.Unmarshal(msg.ID, &id) != nil || !strings.HasPrefix(id, requestIDPrefix) {
+			return false
+		}
+		if ch, ok := b.pending.LoadAndDelete(id); ok {
+			resp := response{result: msg.Result}
+			if msg.Error != nil {
+				resp.err = fmt.Errorf("client error %d: %s", msg.Error.Code, msg.Error.Message)
+			}
+			ch.(chan response) <- resp
+		}
+		return true
+	case methodInitialize:
+		var params struct {
+			Capabilities map[string]json.RawMessage `json:"capabilities"`
+		}
+		if json.Unmarshal(msg.Params, &params) == nil {
+			b.mu.Lock()
+			b.capabilities = params.Capabilities
+			b.initializeID = msg.ID
+			b.mu.Unlock()
+		}
+	case methodInitialized:
+		if b.Supports("roots") {
+			go b.refreshRoots(ctx)
+		} else {
+			b.setRoots(nil)
+		}
+	case methodRootsListChanged:
+		go b.refreshRoots(ctx)
+		return true
+	}
+	if handled, ok := b.handlers[msg.Method]; ok && len(msg.ID) > 0 {
+		go b.answer(ctx, msg, handled.handler)
+		return true
+	}
+	return false
+}
+
+// answer runs the handler of a client request and writes its response
+func (b *Bridge) answer(ctx context.Context, msg message, handler RequestHandler) {
+	reply := map[string]any{"jsonrpc": mcp.JSONRPC_VERSION, "id": msg.ID}
+	if result, err := handler(ctx, msg.Params); err != nil {
+		reply["error"] = map[string]any{"code": mcp.INTERNAL_ERROR, "message": err.Error()}
+	} else {
+		reply["result"] = result
+	}
+
+	data, err := json.Marshal(reply)
+	if err != nil {
+		data, _ = json.Marshal(map[string]any{"jsonrpc": mcp.JSONRPC_VERSION, "id": msg.ID,
+			"error": map[string]any{"code": mcp.INTERNAL_ERROR, "message": fmt.Sprintf("failed to encode %s response: %v", msg.Method, err)}})
+	}
+	_, _ = b.writer.Write(append(data, '\n'))
+}
+
+// advertise adds the capabilities of the handled requests to the server's
+// initialize result. Other messages are returned unchanged.
+func (b *Bridge) advertise(line []byte) []byte {
+	b.mu.Lock()
+	id := b.initializeID
+	b.mu.Unlock()
+	if len(id) == 0 || len(b.handlers) == 0 {
+		return line
+	}
+
+	var msg map[string]json.RawMessage
+	if json.Unmarshal(line, &msg) != nil || string(msg["id"]) != string(id) || msg["result"] == nil {
+		return line
+	}
+	var result map[string]json.RawMessage
+	if json.Unmarshal(msg["result"], &result) != nil {
+		return line
+	}
+	capabilities := map[string]json.RawMessage{}
+	if raw, ok := result["capabilities"]; ok && json.Unmarshal(raw, &capabilities) != nil {
+		return line
+	}
+	for _, handled := range b.handlers {
+		if handled.capability != "" {
+			capabilities[handled.capability] = json.RawMessage("{}")
+		}
+	}
+
+	var err error
+	if result["capabilities"], err = json.Marshal(capabilities); err != nil {
+		return line
+	}
+	if msg["result"], err = json.Marshal(result); err != nil {
+		return line
+	}
+	patched, err := json.Marshal(msg)
+	if err != nil {
+		return line
+	}
+
+	b.mu.Lock()
+	b.initializeID = nil
+	b.mu.Unlock()
+	return append(patched, '\n')
+}
+
+// Supports reports whether the client declared the named capability
+// (e.g. roots, sampling, elicitation) during initialization
+func (b *Bridge) Supports(capability string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	_, ok := b.capabilities[capability]
+	return ok
+}
+
+// Request sends a request to the client and decodes its result into result
+func (b *Bridge) Request(ctx context.Context, method string, params any, result any) error {
+	id := fmt.Sprintf("%s%d", requestIDPrefix, b.nextID.Add(1))
+	ch := make(chan response, 1)
+	b.pending.Store(id, ch)
+	defer b.pending.Delete(id)
+
+	data, err := json.Marshal(map[string]any{
+		"jsonrpc": mcp.JSONRPC_VERSION,
+		"id":      id,
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %w", method, err)
+	}
+	if _, err := b.writer.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to send %s request: %w", method, err)
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case resp := <-ch:
+		if resp.err != nil {
+			return resp.err
+		}
+		if result == nil {
+			return nil
+		}
+		if err := json.Unmarshal(resp.result, result); err != nil {
+			return fmt.Errorf("invalid %s response: %w", method, err)
+		}
+		return nil
+	}
+}
+
+// CreateMessage asks the client to sample an LLM completion
+func (b *Bridge) CreateMessage(ctx context.Context, params mcp.CreateMessageParams) (*mcp.CreateMessageResult, error) {
+	if !b.Supports("sampling") {
+		return nil, fmt.Errorf("sampling: %w", ErrUnsupported)
+	}
+	var result mcp.CreateMessageResult
+	if err := b.Request(ctx, methodCreateMessage, params, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ElicitationResult is the client's answer to an elicitation request
+type ElicitationResult struct {
+	// Action is accept, decline or cancel
+	Action  string         `json:"action"`
+	Content map[string]any `json:"content,omitempty"`
+}
+
+// Accepted reports whether the user accepted and answered the request
+func (r ElicitationResult) Accepted() bool {
+	return r.Action == "accept"
+}
+
+// Elicit asks the user, through the client, for the values described by the
+// JSON schema of a flat object
+func (b *Bridge) Elicit(ctx context.Context, message string, schema map[string]any) (*ElicitationResult, error) {
+	if !b.Supports("elicitation") {
+		return nil, fmt.Errorf("elicitation: %w", ErrUnsupported)
+	}
+	var result ElicitationResult
+	if err := b.Request(ctx, methodElicit, map[string]any{
+		"message":         message,
+		"requestedSchema": schema,
+	}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (b *Bridge) failPending(err error) {
+	b.pending.Range(func(key, value any) bool {
+		b.pending.Delete(key)
+		value.(chan response) <- response{err: err}
+		return true
+	})
+}
+
+// Roots returns the roots last reported by the client
+func (b *Bridge) Roots() []mcp.Root {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return append([]mcp.Root(nil), b.roots...)
+}
+
+// WaitForRoots blocks until the roots have been fetched after initialization
+// (or the client turned out not to support roots) or ctx is done
+func (b *Bridge) WaitForRoots(ctx context.Context) []mcp.Root {
+	b.mu.Lock()
+	if b.rootsFetched {
+		b.mu.Unlock()
+		return b.Roots()
+	}
+	ch := make(chan struct{})
+	b.rootsWaiters = append(b.rootsWaiters, ch)
+	b.mu.Unlock()
+
+	select {
+	case <-ch:
+	case <-ctx.Done():
+	}
+	return b.Roots()
+}
+
+func (b *Bridge) refreshRoots(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, defaultRootsListTimeout)
+	defer cancel()
+
+	var result mcp.ListRootsResult
+	if err := b.Request(ctx, methodRootsList, map[string]any{}, &result); err != nil {
+		b.setRoots(b.Roots())
+		return
+	}
+	b.setRoots(result.Roots)
+}
+
+func (b *Bridge) setRoots(roots []mcp.Root) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.roots = roots
+	b.rootsFetched = true
+	for _, ch := range b.rootsWaiters {
+		close(ch)
+	}
+	b.rootsWaiters = nil
+}
+
+// lockedWriter serializes writes so bridge requests and server responses are
+// never interleaved on the same line. rewrite may replace each written
+// message.
+type lockedWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	rewrite func([]byte) []byte
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.rewrite == nil {
+		return l.w.Write(p)
+	}
+	if _, err := l.w.Write(l.rewrite(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -1,7 +1,9 @@
 package cache
 
 import (
+	"errors"
 	"log"
+	"os"
 	"sync"
 	"time"
 
@@ -10,25 +12,201 @@ import (
 
 // ScanResult represents the result of a nuclei scan
 type ScanResult struct {
-	Target   string                `json:"target"`
-	ScanTime time.Time             `json:"scan_time"`
-	Findings []*output.ResultEvent `json:"findings"`
+	Target      string                `json:"target"`
+	ScanTime    time.Time             `json:"scan_time"`
+	Findings    []*output.ResultEvent `json:"findings"`
+	Extractions []Extraction          `json:"extractions,omitempty"`
+	// SpillFile holds the full records (JSON lines) of the findings beyond
+	// the scanner's in-memory limit, which are left out of Findings and
+	// counted by SpilledFindings. The cache removes the file when the
+	// result leaves it.
+	SpillFile string `json:"spill_file,omitempty"`
+	// SpillFiles are the spill files of the sub-scans a merged result
+	// combines, such as the tiers of a tiered scan. They belong to the
+	// cached results of the sub-scans.
+	SpillFiles      []string `json:"spill_files,omitempty"`
+	SpilledFindings int      `json:"spilled_findings,omitempty"`
+	// Stats records the resources the scan used and its adaptive tuning
+	// adjustments
+	Stats *ScanStats `json:"stats,omitempty"`
+	// Hosts describes where the scanned hosts are hosted, when enrichment
+	// is enabled
+	Hosts []HostInfo `json:"hosts,omitempty"`
+	// CorrelationID ties the result to the tool call that ran the scan
+	CorrelationID string `json:"correlation_id,omitempty"`
+	// StoppedEarly is set when the scan ended at its first finding at the
+	// requested severity, without running its remaining templates
+	StoppedEarly bool `json:"stopped_early,omitempty"`
+	// Labels are the key/value pairs the scan was labelled with
+	Labels map[string]string `json:"labels,omitempty"`
+	// HTTPVersions are the HTTP versions the target supports, when the
+	// scan probed them
+	HTTPVersions *HTTPVersions `json:"http_versions,omitempty"`
+	// WAF describes the WAF in front of the target, when the scan detected
+	// it
+	WAF *WAFDetection `json:"waf,omitempty"`
+	// APIOperations are the operations of the OpenAPI document the scan
+	// ran against, as their method and URL
+	APIOperations []string `json:"api_operations,omitempty"`
+	// CrawledURLs are the URLs the scan's crawl found on the target, which
+	// its HTTP templates ran against too
+	CrawledURLs []string `json:"crawled_urls,omitempty"`
+	// TargetFingerprint identifies the content of the target's response, by
+	// its ETag or a hash of its body, when the scan checked for changes
+	TargetFingerprint string `json:"target_fingerprint,omitempty"`
+	// UnchangedSince is the time of the scan whose findings the result
+	// carries, when later checks found the target unchanged and did not
+	// scan it again
+	UnchangedSince *time.Time `json:"unchanged_since,omitempty"`
 }
 
-// ResultCache caches scan results
+// HTTPVersions describes the HTTP versions a target supports
+type HTTPVersions struct {
+	// HTTP2 is set when the target negotiated HTTP/2
+	HTTP2 bool `json:"http2"`
+	// HTTP3 is set when the target advertises HTTP/3 (QUIC) in its
+	// Alt-Svc header
+	HTTP3 bool `json:"http3"`
+	// AltSvc is the Alt-Svc header the target responded with
+	AltSvc string `json:"alt_svc,omitempty"`
+	// Error is set when the target could not be probed
+	Error string `json:"error,omitempty"`
+}
+
+// WAFDetection describes the WAF in front of a target
+type WAFDetection struct {
+	// Detected is set when a WAF was fingerprinted or blocked the probe
+	// request
+	Detected bool `json:"detected"`
+	// Name is the fingerprinted WAF, empty when it could not be identified
+	Name string `json:"name,omitempty"`
+	// Evidence lists what identified the WAF, such as its headers or
+	// cookies
+	Evidence []string `json:"evidence,omitempty"`
+	// Blocking is set when the WAF blocked a request carrying attack
+	// patterns, so it may have blocked template requests too
+	Blocking bool `json:"blocking,omitempty"`
+	// Interfered are the resource URIs of findings whose responses look
+	// like WAF block pages
+	Interfered []string `json:"interfered,omitempty"`
+	// Error is set when the detection failed
+	Error string `json:"error,omitempty"`
+}
+
+// HostInfo holds the addresses a scanned host resolved to
+type HostInfo struct {
+	Host      string        `json:"host"`
+	Addresses []AddressInfo `json:"addresses,omitempty"`
+	// Error is set when the host could not be resolved
+	Error string `json:"error,omitempty"`
+}
+
+// AddressInfo describes an address of a scanned host: whether it is
+// private, its autonomous system and its location
+type AddressInfo struct {
+	IP          string `json:"ip"`
+	Private     bool   `json:"private,omitempty"`
+	ASN         uint   `json:"asn,omitempty"`
+	ASOrg       string `json:"as_org,omitempty"`
+	Country     string `json:"country,omitempty"`
+	CountryCode string `json:"country_code,omitempty"`
+	City        string `json:"city,omitempty"`
+}
+
+// ScanStats records how a scan ran
+type ScanStats struct {
+	// WallTime is the time the scan ran, after waiting in the scan queue
+	WallTime time.Duration `json:"wall_time_ns"`
+	// CPUTime is the CPU time of the process while the scan ran, which
+	// includes scans running alongside it
+	CPUTime time.Duration `json:"cpu_time_ns"`
+	// Requests and Errors count the requests sent and failed, and
+	// BytesSent and BytesReceived their size
+	Requests      int   `json:"requests,omitempty"`
+	Errors        int   `json:"errors,omitempty"`
+	BytesSent     int64 `json:"bytes_sent,omitempty"`
+	BytesReceived int64 `json:"bytes_received,omitempty"`
+	// Adjustments lists the reductions in effect, oldest first: the one the
+	// scan started with, if any, then those made during the scan
+	Adjustments []Adjustment `json:"adjustments,omitempty"`
+	// TimedOutTemplates were stopped by the template timeout, and
+	// SkippedTemplates not run for exceeding the template request limit
+	TimedOutTemplates []string `json:"timed_out_templates,omitempty"`
+	SkippedTemplates  []string `json:"skipped_templates,omitempty"`
+	// Failures counts the requests to each host the scan sent any to and
+	// why those that failed did, telling a target without findings from
+	// one the scan could not reach. A scan that sent no requests lists its
+	// failed attempts to connect to the target instead.
+	Failures []HostFailures `json:"failures,omitempty"`
+	// Templates accounts for the scan's templates, so an empty scan can be
+	// told apart from one whose filters matched nothing it could run
+	Templates *TemplateStats `json:"templates,omitempty"`
+}
+
+// TemplateStats counts the templates of a scan from those available to
+// those it ran
+type TemplateStats struct {
+	// Available is the number of template files in the scan's sources
+	Available int `json:"available"`
+	// Loaded templates passed the scan's filters and nuclei's checks
+	Loaded   int `json:"loaded"`
+	Executed int `json:"executed"`
+	// Excluded counts the available templates that were not loaded, and
+	// Skipped the loaded ones that did not run, by reason
+	Excluded map[string]int `json:"excluded,omitempty"`
+	Skipped  map[string]int `json:"skipped,omitempty"`
+}
+
+// HostFailures counts the requests to a host and the failed ones by cause,
+// such as connection_refused or dns
+type HostFailures struct {
+	Host     string         `json:"host"`
+	Requests int            `json:"requests"`
+	Failed   int            `json:"failed"`
+	Causes   map[string]int `json:"causes,omitempty"`
+	// Unreachable is set when every request to the host failed
+	Unreachable bool `json:"unreachable,omitempty"`
+	// Example is the first error of the host
+	Example string `json:"example,omitempty"`
+}
+
+// Adjustment is a reduction of the rate limit and concurrency of a host
+type Adjustment struct {
+	Time time.Time `json:"time"`
+	Host string    `json:"host"`
+	// Reason explains the reduction, such as "9 of 20 requests failed"
+	Reason string `json:"reason"`
+	// RateLimit is the new requests per second
+	RateLimit int `json:"rate_limit"`
+	// Concurrency is the new number of templates run at once
+	Concurrency int `json:"concurrency"`
+}
+
+// Extraction holds the values collected by a scan extractor
+type Extraction struct {
+	Name   string   `json:"name"`
+	URL    string   `json:"url"`
+	Values []string `json:"values"`
+}
+
+// ResultCache caches scan results. Expired results stay available to Peek,
+// except those with a spill file, which are dropped with their file.
 type ResultCache struct {
 	cache  map[string]ScanResult
 	expiry time.Duration
 	lock   sync.RWMutex
 	logger *log.Logger
+	// spilled are the keys of the results with a spill file
+	spilled map[string]bool
 }
 
 // NewResultCache creates a new result cache
 func NewResultCache(expiry time.Duration, logger *log.Logger) *ResultCache {
 	return &ResultCache{
-		cache:  make(map[string]ScanResult),
-		expiry: expiry,
-		logger: logger,
+		cache:   make(map[string]ScanResult),
+		expiry:  expiry,
+		logger:  logger,
+		spilled: make(map[string]bool),
 	}
 }
 
@@ -57,10 +235,32 @@ func (c *ResultCache) Set(key string, result ScanResult) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
+	previous, found := c.cache[key]
 	c.cache[key] = result
+	delete(c.spilled, key)
+	if result.SpillFile != "" {
+		c.spilled[key] = true
+	}
+	if found && previous.SpillFile != result.SpillFile {
+		c.removeSpillFile(previous.SpillFile)
+	}
+	c.dropExpiredSpills()
+	if result.CorrelationID != "" {
+		c.logger.Printf("[%s] Cache entry set: %s", result.CorrelationID, key)
+		return
+	}
 	c.logger.Printf("Cache entry set: %s", key)
 }
 
+// Peek retrieves a result from the cache even when it expired
+func (c *ResultCache) Peek(key string) (ScanResult, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	result, found := c.cache[key]
+	return result, found
+}
+
 // GetAll returns a copy of all items in the cache.
 func (c *ResultCache) GetAll() []ScanResult {
 	c.lock.RLock()
@@ -72,3 +272,59 @@ func (c *ResultCache) GetAll() []ScanResult {
 	}
 	return results
 }
+
+// Snapshot returns a copy of all cache entries keyed by their cache key.
+func (c *ResultCache) Snapshot() map[string]ScanResult {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	entries := make(map[string]ScanResult, len(c.cache))
+	for key, result := range c.cache {
+		entries[key] = result
+	}
+	return entries
+}
+
+// Close removes the results with a spill file and their files, such as on
+// shutdown
+func (c *ResultCache) Close() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for key := range c.spilled {
+		result := c.cache[key]
+		delete(c.cache, key)
+		delete(c.spilled, key)
+		c.removeSpillFile(result.SpillFile)
+	}
+}
+
+// dropExpiredSpills removes the expired results with a spill file and their
+// files
+func (c *ResultCache) dropExpiredSpills() {
+	for key := range c.spilled {
+		result := c.cache[key]
+		if time.Since(result.ScanTime) <= c.expiry {
+			continue
+		}
+		delete(c.cache, key)
+		delete(c.spilled, key)
+		c.removeSpillFile(result.SpillFile)
+		c.logger.Printf("Cache entry expired, spill file removed: %s", key)
+	}
+}
+
+// removeSpillFile removes a spill file no cached result refers to anymore
+func (c *ResultCache) removeSpillFile(path string) {
+	if path == "" {
+		return
+	}
+	for key := range c.spilled {
+		if c.cache[key].SpillFile == path {
+			return
+		}
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		c.logger.Printf("Failed to remove spill file %s: %v", path, err)
+	}
+}
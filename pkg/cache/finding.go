@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"strings"
+
+	"nuclei-mcp/pkg/encryption"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+)
+
+// FindingScheme is the URI scheme of finding resources
+const FindingScheme = "finding://"
+
+// Fingerprint identifies a finding by its template, matcher, extractor,
+// host and matched location, so the same finding keeps its fingerprint
+// across scans
+func Fingerprint(finding *output.ResultEvent) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		finding.TemplateID, finding.MatcherName, finding.ExtractorName, finding.Host, finding.Matched,
+	}, "|")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// FindingURI returns the finding://{fingerprint} URI of a finding
+func FindingURI(finding *output.ResultEvent) string {
+	return FindingScheme + Fingerprint(finding)
+}
+
+// FindFinding returns the most recent finding with fingerprint in results and
+// the scan result holding it. Findings beyond the scanner's in-memory limit
+// are read back from the spill file of their result while it exists; key
+// decrypts encrypted spill files and may be nil.
+func FindFinding(results []ScanResult, fingerprint string, key *encryption.Key) (ScanResult, *output.ResultEvent, bool) {
+	var (
+		found   ScanResult
+		finding *output.ResultEvent
+	)
+	for _, result := range results {
+		if finding != nil && !result.ScanTime.After(found.ScanTime) {
+			continue
+		}
+		if candidate, ok := findIn(result, fingerprint, key); ok {
+			found, finding = result, candidate
+		}
+	}
+	return found, finding, finding != nil
+}
+
+// findIn looks up the finding with fingerprint in result, then in its spill
+// files
+func findIn(result ScanResult, fingerprint string, key *encryption.Key) (*output.ResultEvent, bool) {
+	for _, candidate := range result.Findings {
+		if Fingerprint(candidate) == fingerprint {
+			return candidate, true
+		}
+	}
+	if result.SpilledFindings == 0 {
+		return nil, false
+	}
+	for _, path := range append([]string{result.SpillFile}, result.SpillFiles...) {
+		if path == "" {
+			continue
+		}
+		if finding, ok := readSpilled(path, fingerprint, key); ok {
+			return finding, true
+		}
+	}
+	return nil, false
+}
+
+// readSpilled looks up the full record of a finding in a spill file. Lines
+// that are not JSON are decrypted with key.
+func readSpilled(path string, fingerprint string, key *encryption.Key) (*output.ResultEvent, bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, false
+	}
+	defer file.Close()
+
+	lines := bufio.NewScanner(file)
+	lines.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for lines.Scan() {
+		record := lines.Bytes()
+		if !bytes.HasPrefix(record, []byte("{")) {
+			opened, err := key.OpenLine(record)
+			if err != nil {
+				continue
+			}
+			record = opened
+		}
+		var event output.ResultEvent
+		if json.Unmarshal(record, &event) != nil {
+			continue
+		}
+		if Fingerprint(&event) == fingerprint {
+			return &event, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,361 @@
+package compliance
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"nuclei-mcp/pkg/cache"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+)
+
+// Compliance frameworks findings are mapped to
+const (
+	OWASP = "owasp-top-10"
+	PCI   = "pci-dss"
+	NIST  = "nist-800-53"
+)
+
+// Frameworks lists the frameworks in the order they are reported
+var Frameworks = []string{OWASP, PCI, NIST}
+
+// frameworkNames are the titles of the frameworks
+var frameworkNames = map[string]string{
+	OWASP: "OWASP Top 10 (2021)",
+	PCI:   "PCI DSS v4.0",
+	NIST:  "NIST SP 800-53 Rev. 5",
+}
+
+// Control is a requirement of a compliance framework
+type Control struct {
+	Framework string `json:"framework"`
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+}
+
+// category is an OWASP Top 10 category and the controls of the other
+// frameworks it falls under
+type category struct {
+	control Control
+	pci     []Control
+	nist    []Control
+}
+
+var categories = map[string]category{
+	"A01": {
+		control: Control{OWASP, "A01:2021", "Broken Access Control"},
+		pci:     []Control{{PCI, "7.2", "Access to system components and data is appropriately defined and assigned"}},
+		nist:    []Control{{NIST, "AC-3", "Access Enforcement"}, {NIST, "AC-6", "Least Privilege"}},
+	},
+	"A02": {
+		control: Control{OWASP, "A02:2021", "Cryptographic Failures"},
+		pci:     []Control{{PCI, "4.2.1", "Strong cryptography protects data during transmission"}},
+		nist:    []Control{{NIST, "SC-8", "Transmission Confidentiality and Integrity"}, {NIST, "SC-13", "Cryptographic Protection"}},
+	},
+	"A03": {
+		control: Control{OWASP, "A03:2021", "Injection"},
+		pci:     []Control{{PCI, "6.2.4", "Software engineering techniques prevent common software attacks"}},
+		nist:    []Control{{NIST, "SI-10", "Information Input Validation"}},
+	},
+	"A04": {
+		control: Control{OWASP, "A04:2021", "Insecure Design"},
+		pci:     []Control{{PCI, "6.2.4", "Software engineering techniques prevent common software attacks"}},
+		nist:    []Control{{NIST, "SA-8", "Security and Privacy Engineering Principles"}},
+	},
+	"A05": {
+		control: Control{OWASP, "A05:2021", "Security Misconfiguration"},
+		pci:     []Control{{PCI, "2.2", "System components are configured and managed securely"}},
+		nist:    []Control{{NIST, "CM-6", "Configuration Settings"}, {NIST, "CM-7", "Least Functionality"}},
+	},
+	"A06": {
+		control: Control{OWASP, "A06:2021", "Vulnerable and Outdated Components"},
+		pci:     []Control{{PCI, "6.3.3", "Known vulnerabilities are addressed by installing security patches"}},
+		nist:    []Control{{NIST, "SI-2", "Flaw Remediation"}, {NIST, "RA-5", "Vulnerability Monitoring and Scanning"}},
+	},
+	"A07": {
+		control: Control{OWASP, "A07:2021", "Identification and Authentication Failures"},
+		pci:     []Control{{PCI, "8.3", "Strong authentication for users and administrators is established and managed"}},
+		nist:    []Control{{NIST, "IA-2", "Identification and Authentication (Organizational Users)"}, {NIST, "IA-5", "Authenticator Management"}},
+	},
+	"A08": {
+		control: Control{OWASP, "A08:2021", "Software and Data Integrity Failures"},
+		pci:     []Control{{PCI, "6.2.4", "Software engineering techniques prevent common software attacks"}},
+		nist:    []Control{{NIST, "SI-7", "Software, Firmware, and Information Integrity"}},
+	},
+	"A09": {
+		control: Control{OWASP, "A09:2021", "Security Logging and Monitoring Failures"},
+		pci:     []Control{{PCI, "10.2", "Audit logs are implemented to support the detection of anomalies and suspicious activity"}},
+		nist:    []Control{{NIST, "AU-2", "Event Logging"}, {NIST, "AU-12", "Audit Record Generation"}},
+	},
+	"A10": {
+		control: Control{OWASP, "A10:2021", "Server-Side Request Forgery (SSRF)"},
+		pci:     []Control{{PCI, "6.2.4", "Software engineering techniques prevent common software attacks"}},
+		nist:    []Control{{NIST, "SC-7", "Boundary Protection"}},
+	},
+}
+
+// cweCategories maps CWE IDs to the OWASP Top 10 category listing them
+var cweCategories = byCWE(map[string][]int{
+	"A01": {22, 23, 35, 59, 200, 201, 219, 264, 275, 276, 284, 285, 352, 359, 377, 402, 425, 441, 497, 538, 540, 548, 552, 566, 601, 639, 651, 668, 706, 862, 863, 913, 922, 1275},
+	"A02": {261, 296, 310, 319, 321, 322, 323, 324, 325, 326, 327, 328, 329, 330, 331, 335, 336, 337, 338, 340, 347, 523, 720, 757, 759, 760, 780, 818, 916},
+	"A03": {20, 74, 75, 77, 78, 79, 80, 83, 87, 88, 89, 90, 91, 93, 94, 95, 96, 97, 98, 99, 113, 116, 138, 184, 470, 471, 564, 610, 643, 644, 652, 917},
+	"A04": {73, 183, 209, 213, 235, 256, 257, 266, 269, 280, 311, 312, 313, 316, 419, 430, 434, 444, 451, 472, 501, 522, 525, 539, 579, 598, 602, 642, 646, 650, 653, 656, 657, 799, 807, 840, 841, 927, 1021, 1173},
+	"A05": {2, 11, 13, 15, 16, 260, 315, 520, 526, 537, 541, 547, 611, 614, 756, 776, 942, 1004, 1032, 1174},
+	"A06": {937, 1035, 1104},
+	"A07": {255, 259, 287, 288, 290, 294, 295, 297, 300, 302, 304, 306, 307, 346, 384, 521, 613, 620, 640, 798, 940, 1216},
+	"A08": {345, 353, 426, 494, 502, 565, 784, 829, 830, 915},
+	"A09": {117, 223, 532, 778},
+	"A10": {918},
+})
+
+func byCWE(categories map[string][]int) map[int]string {
+	byCWE := make(map[int]string)
+	for owasp, cwes := range categories {
+		for _, cwe := range cwes {
+			byCWE[cwe] = owasp
+		}
+	}
+	return byCWE
+}
+
+// tagCategories maps template tags to OWASP Top 10 categories, for
+// templates without a CWE classification
+var tagCategories = map[string]string{
+	"lfi":             "A01",
+	"traversal":       "A01",
+	"redirect":        "A01",
+	"unauth":          "A01",
+	"exposure":        "A01",
+	"disclosure":      "A01",
+	"idor":            "A01",
+	"ssl":             "A02",
+	"tls":             "A02",
+	"crypto":          "A02",
+	"sqli":            "A03",
+	"xss":             "A03",
+	"rce":             "A03",
+	"injection":       "A03",
+	"ssti":            "A03",
+	"crlf":            "A03",
+	"cmdi":            "A03",
+	"fileupload":      "A04",
+	"misconfig":       "A05",
+	"config":          "A05",
+	"headers":         "A05",
+	"xxe":             "A05",
+	"takeover":        "A05",
+	"debug":           "A05",
+	"cve":             "A06",
+	"eol":             "A06",
+	"outdated":        "A06",
+	"default-login":   "A07",
+	"auth-bypass":     "A07",
+	"weak-auth":       "A07",
+	"deserialization": "A08",
+	"ssrf":            "A10",
+}
+
+// Map returns the controls a finding falls under: the OWASP Top 10
+// categories of its CWE IDs or, without them, of its tags, and the PCI DSS
+// and NIST controls of those categories. Findings with a CVE ID also fall
+// under vulnerable components.
+func Map(finding *output.ResultEvent) []Control {
+	owasp := map[string]bool{}
+	if classification := finding.Info.Classification; classification != nil {
+		for _, raw := range classification.CWEID.ToSlice() {
+			id, err := strconv.Atoi(strings.TrimPrefix(strings.ToLower(strings.TrimSpace(raw)), "cwe-"))
+			if err == nil && cweCategories[id] != "" {
+				owasp[cweCategories[id]] = true
+			}
+		}
+		if len(classification.CVEID.ToSlice()) > 0 {
+			owasp["A06"] = true
+		}
+	}
+	if len(owasp) == 0 {
+		for _, tag := range finding.Info.Tags.ToSlice() {
+			if category, ok := tagCategories[strings.ToLower(tag)]; ok {
+				owasp[category] = true
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(owasp))
+	for key := range owasp {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var controls []Control
+	seen := map[Control]bool{}
+	add := func(control Control) {
+		if !seen[control] {
+			seen[control] = true
+			controls = append(controls, control)
+		}
+	}
+	for _, key := range keys {
+		add(categories[key].control)
+	}
+	for _, key := range keys {
+		for _, control := range categories[key].pci {
+			add(control)
+		}
+	}
+	for _, key := range keys {
+		for _, control := range categories[key].nist {
+			add(control)
+		}
+	}
+	return controls
+}
+
+// ControlSummary counts the findings of a target under a control
+type ControlSummary struct {
+	Control
+	Findings   int            `json:"findings"`
+	BySeverity map[string]int `json:"by_severity"`
+	Templates  []string       `json:"templates"`
+}
+
+// FrameworkSummary lists the controls of a framework with findings
+type FrameworkSummary struct {
+	Framework string           `json:"framework"`
+	Name      string           `json:"name"`
+	Controls  []ControlSummary `json:"controls"`
+}
+
+// Summary maps the findings of a target to compliance controls
+type Summary struct {
+	Target     string             `json:"target"`
+	Findings   int                `json:"findings"`
+	Frameworks []FrameworkSummary `json:"frameworks"`
+	// Unmapped counts findings that fall under no control, such as
+	// informational technology detections
+	Unmapped int `json:"unmapped"`
+}
+
+// Summarize maps the findings of results to compliance controls, one
+// summary per target, sorted by target. Findings reported by several scans
+// are counted once. An empty target includes every target; a framework
+// other than empty only reports that framework.
+func Summarize(results []cache.ScanResult, target string, framework string) []Summary {
+	type targetFindings struct {
+		findings []*output.ResultEvent
+		seen     map[string]bool
+	}
+	byTarget := map[string]*targetFindings{}
+	for _, result := range results {
+		if target != "" && result.Target != target {
+			continue
+		}
+		t := byTarget[result.Target]
+		if t == nil {
+			t = &targetFindings{seen: map[string]bool{}}
+			byTarget[result.Target] = t
+		}
+		for _, finding := range result.Findings {
+			if finding == nil {
+				continue
+			}
+			if fingerprint := cache.Fingerprint(finding); !t.seen[fingerprint] {
+				t.seen[fingerprint] = true
+				t.findings = append(t.findings, finding)
+			}
+		}
+	}
+
+	summaries := make([]Summary, 0, len(byTarget))
+	for name, t := range byTarget {
+		summary := SummarizeFindings(t.findings, framework)
+		summary.Target = name
+		summaries = append(summaries, summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Target < summaries[j].Target
+	})
+	return summaries
+}
+
+// SummarizeFindings maps findings to compliance controls. A framework other
+// than empty only reports that framework.
+func SummarizeFindings(findings []*output.ResultEvent, framework string) Summary {
+	summary := Summary{}
+	controls := map[Control]*ControlSummary{}
+	templates := map[Control]map[string]bool{}
+	for _, finding := range findings {
+		if finding == nil {
+			continue
+		}
+		summary.Findings++
+		mapped := Map(finding)
+		if len(mapped) == 0 {
+			summary.Unmapped++
+			continue
+		}
+		severity := strings.ToLower(finding.Info.SeverityHolder.Severity.String())
+		for _, control := range mapped {
+			if framework != "" && control.Framework != framework {
+				continue
+			}
+			c := controls[control]
+			if c == nil {
+				c = &ControlSummary{Control: control, BySeverity: map[string]int{}}
+				controls[control] = c
+				templates[control] = map[string]bool{}
+			}
+			c.Findings++
+			c.BySeverity[severity]++
+			if !templates[control][finding.TemplateID] {
+				templates[control][finding.TemplateID] = true
+				c.Templates = append(c.Templates, finding.TemplateID)
+			}
+		}
+	}
+
+	for _, name := range Frameworks {
+		if framework != "" && name != framework {
+			continue
+		}
+		frameworkSummary := FrameworkSummary{Framework: name, Name: frameworkNames[name], Controls: []ControlSummary{}}
+		for control, c := range controls {
+			if control.Framework == name {
+				sort.Strings(c.Templates)
+				frameworkSummary.Controls = append(frameworkSummary.Controls, *c)
+			}
+		}
+		sort.Slice(frameworkSummary.Controls, func(i, j int) bool {
+			return controlLess(frameworkSummary.Controls[i].ID, frameworkSummary.Controls[j].ID)
+		})
+		summary.Frameworks = append(summary.Frameworks, frameworkSummary)
+	}
+	return summary
+}
+
+// ValidFramework reports whether framework is one of Frameworks
+func ValidFramework(framework string) bool {
+	_, ok := frameworkNames[framework]
+	return ok
+}
+
+// controlLess orders control IDs by their numeric parts, so 6.2.4 sorts
+// before 10.2 and SC-8 before SC-13
+func controlLess(a, b string) bool {
+	split := func(id string) []string {
+		return strings.FieldsFunc(id, func(r rune) bool { return r == '.' || r == '-' || r == ':' })
+	}
+	pa, pb := split(a), split(b)
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		if pa[i] == pb[i] {
+			continue
+		}
+		na, errA := strconv.Atoi(pa[i])
+		nb, errB := strconv.Atoi(pb[i])
+		if errA == nil && errB == nil {
+			return na < nb
+		}
+		return pa[i] < pb[i]
+	}
+	return len(pa) < len(pb)
+}
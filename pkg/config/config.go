@@ -1,8 +1,11 @@
 package config
 
 import (
+	"strings"
 	"time"
 
+	"nuclei-mcp/pkg/paths"
+
 	"github.com/spf13/viper"
 )
 
@@ -10,11 +13,192 @@ type Config struct {
 	Server  ServerConfig  `mapstructure:"server"`
 	Cache   CacheConfig   `mapstructure:"cache"`
 	Logging LoggingConfig `mapstructure:"logging"`
+	Nuclei  NucleiConfig  `mapstructure:"nuclei"`
+	Scanner ScannerConfig `mapstructure:"scanner"`
+	Policy  PolicyConfig  `mapstructure:"policy"`
+	Report  ReportConfig  `mapstructure:"report"`
+	// Distributed sends scans to worker instances
+	Distributed DistributedConfig `mapstructure:"distributed"`
+	// Encryption encrypts findings and exported archives at rest
+	Encryption EncryptionConfig `mapstructure:"encryption"`
+	// Findings tracks the lifecycle status and retests of findings
+	Findings FindingsConfig `mapstructure:"findings"`
+	// Enrichment describes where scanned hosts are hosted
+	Enrichment EnrichmentConfig `mapstructure:"enrichment"`
+	// Assets stores the recon context collected by target_context
+	Assets AssetsConfig `mapstructure:"assets"`
+	// Wordlists are referenced by name in the payloads of fuzzing templates
+	Wordlists WordlistsConfig `mapstructure:"wordlists"`
+	// Telemetry exports traces of tool calls and scans
+	Telemetry TelemetryConfig `mapstructure:"telemetry"`
+	// Secrets are referenced by name in scan variables as secret://name
+	Secrets map[string]SecretConfig `mapstructure:"secrets"`
+	// Notifications alert on the findings of completed scans
+	Notifications NotificationsConfig `mapstructure:"notifications"`
+	// Schedules run scans of key targets in the background
+	Schedules SchedulesConfig `mapstructure:"schedules"`
+}
+
+type SchedulesConfig struct {
+	// Interval is how often due scans are looked for
+	Interval time.Duration `mapstructure:"interval"`
+	// WarmMargin is how long before its cached result expires a
+	// cache-warming scan runs
+	WarmMargin time.Duration         `mapstructure:"warm_margin"`
+	Scans      []ScheduledScanConfig `mapstructure:"scans"`
+	// BlackoutCalendar is an iCal (.ics) or YAML file of blackouts, such as
+	// deploy freezes and holidays, suspending scheduled scans
+	BlackoutCalendar string `mapstructure:"blackout_calendar"`
+	// File stores the scheduled scans restored from workspace archives,
+	// which run alongside Scans
+	File string `mapstructure:"file"`
+}
+
+type ScheduledScanConfig struct {
+	Target string `mapstructure:"target"`
+	// Severity, Protocols and Tags default to the scan profile, like the
+	// arguments of nuclei_scan
+	Severity  string   `mapstructure:"severity"`
+	Protocols string   `mapstructure:"protocols"`
+	Tags      []string `mapstructure:"tags"`
+	// Every is the time between runs
+	Every time.Duration `mapstructure:"every"`
+	// WarmCache runs the scan just before its cached result expires instead
+	// of every Every
+	WarmCache bool `mapstructure:"warm_cache"`
+	// SkipUnchanged keeps the previous result instead of scanning when the
+	// target's response did not change since
+	SkipUnchanged bool `mapstructure:"skip_unchanged"`
+}
+
+type NotificationsConfig struct {
+	// MinSeverity is the least severe finding that triggers an alert
+	MinSeverity string      `mapstructure:"min_severity"`
+	Email       EmailConfig `mapstructure:"email"`
+	// Webhooks receive alerts as JSON, Slack messages or Teams cards
+	Webhooks []WebhookConfig `mapstructure:"webhooks"`
+}
+
+type WebhookConfig struct {
+	// URL is the endpoint alerts are posted to
+	URL string `mapstructure:"url"`
+	// URLEnv names the environment variable holding the URL when URL is
+	// empty, as chat webhook URLs embed their credential
+	URLEnv string `mapstructure:"url_env"`
+	// Format is json, slack or teams
+	Format string `mapstructure:"format"`
+}
+
+type EmailConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Host    string `mapstructure:"host"`
+	// Port defaults to 465 with tls and 587 otherwise
+	Port int `mapstructure:"port"`
+	// TLS is starttls, tls (implicit TLS) or none
+	TLS  string   `mapstructure:"tls"`
+	From string   `mapstructure:"from"`
+	To   []string `mapstructure:"to"`
+	// Username enables PLAIN authentication
+	Username string `mapstructure:"username"`
+	// PasswordEnv names the environment variable holding the password
+	PasswordEnv string `mapstructure:"password_env"`
+}
+
+type SecretConfig struct {
+	// Env names the environment variable holding the secret
+	Env string `mapstructure:"env"`
+	// File is read when Env is unset, such as a secret rendered by a vault
+	// agent
+	File string `mapstructure:"file"`
+}
+
+type TelemetryConfig struct {
+	// OTLPEndpoint is the OTLP/HTTP URL of the collector spans are sent
+	// to; empty disables tracing
+	OTLPEndpoint string `mapstructure:"otlp_endpoint"`
+}
+
+type WordlistsConfig struct {
+	Dir string `mapstructure:"dir"`
+	// MaxEntries caps the entries of a wordlist
+	MaxEntries int `mapstructure:"max_entries"`
+}
+
+type AssetsConfig struct {
+	// RegistryFile stores the collected context of each host
+	RegistryFile string `mapstructure:"registry_file"`
+	// CTLogURL is the crt.sh compatible service queried for certificate
+	// transparency names
+	CTLogURL string `mapstructure:"ct_log_url"`
+	// Timeout bounds each DNS, TLS and certificate transparency lookup
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+type EnrichmentConfig struct {
+	// Enabled attaches the resolved addresses of scanned hosts to scan
+	// results
+	Enabled bool `mapstructure:"enabled"`
+	// ASNDatabase is a local MaxMind DB with autonomous systems, such as
+	// GeoLite2-ASN.mmdb; optional
+	ASNDatabase string `mapstructure:"asn_database"`
+	// GeoIPDatabase is a local MaxMind DB with locations, such as
+	// GeoLite2-City.mmdb; optional
+	GeoIPDatabase string `mapstructure:"geoip_database"`
+}
+
+type FindingsConfig struct {
+	// TrackerFile stores the status and retests of tracked findings
+	TrackerFile string `mapstructure:"tracker_file"`
+	// RetestAfter is the default delay before a remediated finding is
+	// scanned again to verify the fix
+	RetestAfter time.Duration `mapstructure:"retest_after"`
+	// RetestInterval is how often due retests are looked for
+	RetestInterval time.Duration `mapstructure:"retest_interval"`
+}
+
+type EncryptionConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// KeyEnv names the environment variable holding the base64 or hex
+	// AES-256 key
+	KeyEnv string `mapstructure:"key_env"`
+	// KeyFile is read when KeyEnv is unset, such as a secret written by a
+	// KMS agent
+	KeyFile string `mapstructure:"key_file"`
 }
 
 type ServerConfig struct {
 	Name    string `mapstructure:"name"`
 	Version string `mapstructure:"version"`
+	// Elicitation asks the client to clarify ambiguous scan parameters
+	// instead of silently applying defaults
+	Elicitation bool `mapstructure:"elicitation"`
+	// PageSize is the number of findings per tool response; the rest are
+	// fetched with fetch_more_results
+	PageSize int `mapstructure:"page_size"`
+	// Listen serves MCP over streamable HTTP on this address instead of
+	// stdio; requires Tenants
+	Listen string `mapstructure:"listen"`
+	// Tenants are the clients of the HTTP server, each with its own token,
+	// workspace, rate limit and scope
+	Tenants []TenantConfig `mapstructure:"tenants"`
+	// ExportDir holds the archives of backup_workspace and restore_workspace
+	// given by relative path
+	ExportDir string `mapstructure:"export_dir"`
+}
+
+type TenantConfig struct {
+	Name string `mapstructure:"name"`
+	// Token authenticates the tenant's requests as a bearer token
+	Token string `mapstructure:"token"`
+	// Workspace is the directory holding the tenant's custom templates and
+	// exclusion rules; defaults to tenants/<name> in the data directory
+	Workspace string `mapstructure:"workspace"`
+	// RateLimit caps the requests per second of the tenant's scans; zero
+	// leaves the scan's own rate limit
+	RateLimit int `mapstructure:"rate_limit"`
+	// Scope lists the hosts and URL prefixes the tenant may scan; empty
+	// allows any target
+	Scope []string `mapstructure:"scope"`
 }
 
 type CacheConfig struct {
@@ -23,22 +207,336 @@ type CacheConfig struct {
 
 type LoggingConfig struct {
 	Path string `mapstructure:"path"`
+	// Console mirrors logs to stdout when serving over HTTP; over stdio,
+	// logs always go to stderr
+	Console bool `mapstructure:"console"`
+}
+
+type NucleiConfig struct {
+	// TemplatesVersion pins the nuclei-templates release installed by engine_update
+	TemplatesVersion string `mapstructure:"templates_version"`
+	// UpdateCheck enables the check_updates tool, which queries GitHub for
+	// newer templates and engine releases
+	UpdateCheck bool `mapstructure:"update_check"`
+	// TemplatesDir holds the custom templates managed by add_template
+	TemplatesDir string `mapstructure:"templates_dir"`
+	// BundlesDir is where offline template bundles are extracted
+	BundlesDir string `mapstructure:"bundles_dir"`
+	// TemplateBundles are loaded into their own namespace at startup
+	TemplateBundles []TemplateBundleConfig `mapstructure:"template_bundles"`
+	// SignatureVerification checks signatures of bundled and added templates
+	SignatureVerification SignatureVerificationConfig `mapstructure:"signature_verification"`
+	// TemplateDirs are further template directories, highest priority
+	// first, read after the custom templates directory
+	TemplateDirs []TemplateDirConfig `mapstructure:"template_dirs"`
+	// DefaultFilters select the templates of every scan whose caller does
+	// not pass the same filter
+	DefaultFilters TemplateFiltersConfig `mapstructure:"default_filters"`
+}
+
+type TemplateFiltersConfig struct {
+	// Tags are run on top of scanner.defaults.tags
+	Tags        []string `mapstructure:"tags"`
+	ExcludeTags []string `mapstructure:"exclude_tags"`
+	// IDs are run when a scan names no template IDs
+	IDs        []string `mapstructure:"ids"`
+	ExcludeIDs []string `mapstructure:"exclude_ids"`
+	Authors    []string `mapstructure:"authors"`
+	// Severities replace scanner.defaults.severity when set
+	Severities        []string `mapstructure:"severities"`
+	ExcludeSeverities []string `mapstructure:"exclude_severities"`
+}
+
+type TemplateDirConfig struct {
+	Name string `mapstructure:"name"`
+	Path string `mapstructure:"path"`
+}
+
+type SignatureVerificationConfig struct {
+	// Mode is "nuclei" (embedded digest), "minisign" (detached .minisig) or
+	// empty to disable verification
+	Mode string `mapstructure:"mode"`
+	// PublicKey is a minisign public key, or an extra PEM certificate for
+	// nuclei mode; either inline or a file path
+	PublicKey string `mapstructure:"public_key"`
+	// Enforce rejects unsigned or modified templates instead of warning
+	Enforce bool `mapstructure:"enforce"`
+}
+
+type TemplateBundleConfig struct {
+	Name   string `mapstructure:"name"`
+	Source string `mapstructure:"source"`
+}
+
+type ScannerConfig struct {
+	// AllowCodeTemplates lets callers opt in to code protocol templates per scan
+	AllowCodeTemplates bool `mapstructure:"allow_code_templates"`
+	// PassiveByDefault limits scans to DNS and SSL templates unless a
+	// caller opts out
+	PassiveByDefault bool `mapstructure:"passive_by_default"`
+	// PassiveRateLimit is the requests per second of passive scans
+	PassiveRateLimit int `mapstructure:"passive_rate_limit"`
+	// Preload parses and compiles the template set at startup
+	Preload bool `mapstructure:"preload"`
+	// TemplateCache reuses parsed templates across scans until the template
+	// directories change on disk
+	TemplateCache bool `mapstructure:"template_cache"`
+	// TemplateIndex keeps the metadata of every template file in the cache
+	// directory, so filtered scans only load the files they can match
+	TemplateIndex bool `mapstructure:"template_index"`
+	// HostConcurrency limits concurrent scans of the same host in
+	// multi-target scans
+	HostConcurrency int `mapstructure:"host_concurrency"`
+	// GlobalConcurrency limits concurrent scans in multi-target scans
+	GlobalConcurrency int `mapstructure:"global_concurrency"`
+	// ResultBuffer is the number of findings buffered before a scan waits
+	// for them to be stored
+	ResultBuffer int `mapstructure:"result_buffer"`
+	// SpillThreshold is the number of findings per scan kept in full in
+	// memory; later findings are written in full to SpillDir
+	SpillThreshold int    `mapstructure:"spill_threshold"`
+	SpillDir       string `mapstructure:"spill_dir"`
+	// EngineTimeout bounds engine creation and template loading per scan
+	EngineTimeout time.Duration `mapstructure:"engine_timeout"`
+	// ForceHTTP2 makes HTTP templates attempt HTTP/2 instead of HTTP/1.1
+	ForceHTTP2 bool `mapstructure:"force_http2"`
+	// ExclusionsFile stores the per-target template exclusion rules
+	ExclusionsFile string `mapstructure:"exclusions_file"`
+	// Defaults is the scan profile applied to arguments scan callers omit
+	Defaults ScanDefaultsConfig `mapstructure:"defaults"`
+	// Adaptive slows scans down on hosts whose requests keep failing
+	Adaptive AdaptiveConfig `mapstructure:"adaptive"`
+	// Quarantine leaves templates that keep erroring at runtime out of
+	// scans
+	Quarantine QuarantineConfig `mapstructure:"quarantine"`
+	// Queue bounds the scans run at once and orders waiting scans by
+	// priority
+	Queue QueueConfig `mapstructure:"queue"`
+	// Quotas limit the resources the scans of each MCP client use
+	Quotas QuotasConfig `mapstructure:"quotas"`
+	// Safety bounds the connections and responses of every scan
+	Safety SafetyConfig `mapstructure:"safety"`
+}
+
+type SafetyConfig struct {
+	// MaxConnectionLifetime caps how long a request may hold a connection;
+	// zero leaves the engine defaults
+	MaxConnectionLifetime time.Duration `mapstructure:"max_connection_lifetime"`
+	// MaxBodyRead is the most bytes read of a response body; zero leaves
+	// the engine default
+	MaxBodyRead int `mapstructure:"max_body_read"`
+	// TemplateTimeout bounds how long each template runs against a target;
+	// zero leaves templates unbounded
+	TemplateTimeout time.Duration `mapstructure:"template_timeout"`
+	// MaxTemplateRequests skips templates sending more requests than this
+	// to a target; zero runs every template
+	MaxTemplateRequests int `mapstructure:"max_template_requests"`
+}
+
+type AdaptiveConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// ErrorRate is the share of failed requests, between 0 and 1, above
+	// which a host's rate limit and concurrency are halved
+	ErrorRate float64 `mapstructure:"error_rate"`
+	// Window is the number of requests the error rate is measured over
+	Window int `mapstructure:"window"`
+	// MinRateLimit is the lowest requests per second a host is reduced to
+	MinRateLimit int `mapstructure:"min_rate_limit"`
+}
+
+type QuarantineConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Threshold is the number of scans in a row a template errors in
+	// before it is quarantined
+	Threshold int `mapstructure:"threshold"`
+}
+
+type QueueConfig struct {
+	// Slots is the number of scans run at once; zero disables the queue
+	Slots int `mapstructure:"slots"`
+	// Policy is strict (interactive scans always first) or fair (a waiting
+	// background scan gets every slot after InteractiveWeight interactive
+	// ones)
+	Policy            string `mapstructure:"policy"`
+	InteractiveWeight int    `mapstructure:"interactive_weight"`
+}
+
+type QuotasConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Period is how long usage is counted before it resets
+	Period time.Duration `mapstructure:"period"`
+	// Default applies to clients without a quota of their own
+	Default QuotaConfig `mapstructure:"default"`
+	// Clients set the quota of MCP clients by the name they initialize
+	// with
+	Clients []ClientQuotaConfig `mapstructure:"clients"`
+}
+
+// QuotaConfig limits the scans of a client per period; zero is unlimited
+type QuotaConfig struct {
+	Scans    int           `mapstructure:"scans"`
+	Requests int           `mapstructure:"requests"`
+	Bytes    int64         `mapstructure:"bytes"`
+	WallTime time.Duration `mapstructure:"wall_time"`
+	CPUTime  time.Duration `mapstructure:"cpu_time"`
+}
+
+type ClientQuotaConfig struct {
+	Client      string `mapstructure:"client"`
+	QuotaConfig `mapstructure:",squash"`
+}
+
+type ScanDefaultsConfig struct {
+	// Severity is the minimum severity; empty runs any severity
+	Severity  string   `mapstructure:"severity"`
+	Protocols string   `mapstructure:"protocols"`
+	Tags      []string `mapstructure:"tags"`
+	// RateLimit caps requests per second; 0 leaves nuclei's default
+	RateLimit int `mapstructure:"rate_limit"`
+	// Format of nuclei_scan results: text or json
+	Format string `mapstructure:"format"`
+}
+
+type DistributedConfig struct {
+	// Workers receive the scans of this instance when set
+	Workers []WorkerConfig `mapstructure:"workers"`
+	// Token authenticates the coordinator to its workers; required for
+	// both
+	Token string `mapstructure:"token"`
+	// HealthInterval is how often the coordinator checks its workers
+	HealthInterval time.Duration `mapstructure:"health_interval"`
+	// Listen is the address "nuclei-mcp worker" serves jobs on
+	Listen string `mapstructure:"listen"`
+	// Capacity is the number of jobs a worker runs at once
+	Capacity int `mapstructure:"capacity"`
+	// TLSCert and TLSKey are the PEM certificate and key "nuclei-mcp
+	// worker" serves jobs with over HTTPS
+	TLSCert string `mapstructure:"tls_cert"`
+	TLSKey  string `mapstructure:"tls_key"`
+	// CAFile is a PEM bundle of the CAs the coordinator trusts for worker
+	// certificates, in addition to the system's
+	CAFile string `mapstructure:"ca_file"`
+}
+
+type WorkerConfig struct {
+	Name string `mapstructure:"name"`
+	URL  string `mapstructure:"url"`
+	// Capacity is the number of jobs sent to the worker at once; 0 uses
+	// the capacity the worker reports
+	Capacity int `mapstructure:"capacity"`
+}
+
+type PolicyConfig struct {
+	// DeniedTags blocks templates with these tags unless explicitly approved
+	DeniedTags []string     `mapstructure:"denied_tags"`
+	Egress     EgressConfig `mapstructure:"egress"`
+	// SelfTargetGuard refuses scans of the server's own host unless
+	// allow_self_target and an approval are given
+	SelfTargetGuard bool `mapstructure:"self_target_guard"`
+}
+
+type EgressConfig struct {
+	// DenyPrivate blocks RFC1918, loopback and link-local addresses
+	DenyPrivate bool `mapstructure:"deny_private"`
+	// DenyMetadata blocks cloud metadata endpoints such as 169.254.169.254
+	DenyMetadata bool     `mapstructure:"deny_metadata"`
+	DenyCIDRs    []string `mapstructure:"deny_cidrs"`
+	// AllowedPorts restricts target ports; empty allows any port
+	AllowedPorts []int `mapstructure:"allowed_ports"`
+}
+
+type ReportConfig struct {
+	// Language of generated reports and summaries (en, es, de, ja)
+	Language string `mapstructure:"language"`
 }
 
 func LoadConfig(path string) (config Config, err error) {
+	v, err := newViper(path)
+	if err != nil {
+		return
+	}
+
+	err = v.Unmarshal(&config)
+	return
+}
+
+// newViper reads the config file in path over the defaults. Every setting
+// can be overridden by an environment variable named after its key, such as
+// SCANNER_PRELOAD for scanner.preload.
+func newViper(path string) (*viper.Viper, error) {
 	// Create a new viper instance to avoid global state issues
 	v := viper.New()
 	v.AddConfigPath(path)
 	v.SetConfigName("config")
 	v.SetConfigType("yaml")
 
-	v.AutomaticEnv()
+	// Files without a configured location go to the per-user directories
+	// of the OS
+	v.SetDefault("server.page_size", 50)
+	v.SetDefault("server.export_dir", paths.ExportsDir())
+	v.SetDefault("logging.path", paths.LogFile())
+	v.SetDefault("nuclei.templates_dir", paths.TemplatesDir())
+	v.SetDefault("nuclei.bundles_dir", paths.BundlesDir())
+	v.SetDefault("scanner.passive_rate_limit", 5)
+	v.SetDefault("scanner.template_cache", true)
+	v.SetDefault("scanner.template_index", true)
+	v.SetDefault("scanner.host_concurrency", 2)
+	v.SetDefault("scanner.global_concurrency", 10)
+	v.SetDefault("scanner.result_buffer", 1024)
+	v.SetDefault("scanner.spill_threshold", 5000)
+	v.SetDefault("scanner.engine_timeout", "2m")
+	v.SetDefault("scanner.exclusions_file", paths.DataFile("exclusions.json"))
+	v.SetDefault("scanner.defaults.severity", "info")
+	v.SetDefault("scanner.defaults.protocols", "http,https")
+	v.SetDefault("scanner.defaults.format", "text")
+	v.SetDefault("scanner.adaptive.error_rate", 0.3)
+	v.SetDefault("scanner.adaptive.window", 20)
+	v.SetDefault("scanner.adaptive.min_rate_limit", 1)
+	v.SetDefault("scanner.quarantine.enabled", true)
+	v.SetDefault("scanner.quarantine.threshold", 3)
+	v.SetDefault("scanner.queue.slots", 10)
+	v.SetDefault("scanner.queue.policy", "fair")
+	v.SetDefault("scanner.queue.interactive_weight", 4)
+	v.SetDefault("scanner.quotas.period", "24h")
+	v.SetDefault("scanner.safety.max_connection_lifetime", "30s")
+	v.SetDefault("scanner.safety.max_body_read", 10485760)
+	v.SetDefault("scanner.safety.template_timeout", "0s")
+	v.SetDefault("scanner.safety.max_template_requests", 0)
+	v.SetDefault("policy.denied_tags", []string{"dos", "intrusive", "fuzz"})
+	v.SetDefault("policy.egress.deny_metadata", true)
+	v.SetDefault("policy.self_target_guard", true)
+	v.SetDefault("report.language", "en")
+	v.SetDefault("distributed.health_interval", "15s")
+	v.SetDefault("distributed.listen", ":8765")
+	v.SetDefault("distributed.capacity", 4)
+	v.SetDefault("encryption.key_env", "NUCLEI_MCP_ENCRYPTION_KEY")
+	v.SetDefault("findings.tracker_file", paths.DataFile("findings.json"))
+	v.SetDefault("findings.retest_after", "72h")
+	v.SetDefault("findings.retest_interval", "1m")
+	v.SetDefault("schedules.interval", "1m")
+	v.SetDefault("schedules.warm_margin", "5m")
+	v.SetDefault("schedules.file", paths.DataFile("schedules.json"))
+	v.SetDefault("assets.registry_file", paths.DataFile("assets.json"))
+	v.SetDefault("assets.ct_log_url", "https://crt.sh")
+	v.SetDefault("assets.timeout", "10s")
+	v.SetDefault("wordlists.dir", paths.DataFile("wordlists"))
+	v.SetDefault("wordlists.max_entries", 100000)
+	v.SetDefault("notifications.min_severity", "high")
+	v.SetDefault("notifications.email.tls", "starttls")
+	v.SetDefault("notifications.email.password_env", "NUCLEI_MCP_SMTP_PASSWORD")
 
-	err = v.ReadInConfig()
-	if err != nil {
-		return
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+	// Bind every key so settings missing from the file and defaults can
+	// still be set from the environment
+	for _, key := range Keys() {
+		if err := v.BindEnv(key); err != nil {
+			return nil, err
+		}
 	}
 
-	err = v.Unmarshal(&config)
-	return
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	return v, nil
 }
@@ -0,0 +1,392 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// redacted replaces secret values in Inspection.Settings
+const redacted = "REDACTED"
+
+// secretKeys are key name fragments whose values are never printed
+var secretKeys = []string{"password", "secret", "token", "credential"}
+
+// Inspection is the effective configuration loaded from a config file and
+// the environment
+type Inspection struct {
+	// File is the config file that was read
+	File   string
+	Config Config
+	// Settings are the effective settings by key, with secrets redacted
+	Settings map[string]any
+	// UnknownKeys are keys in the config file that match no setting
+	UnknownKeys []string
+}
+
+// Inspect loads the configuration in path like LoadConfig and reports the
+// effective settings and the config file keys that are not recognized
+func Inspect(path string) (Inspection, error) {
+	v, err := newViper(path)
+	if err != nil {
+		return Inspection{}, err
+	}
+
+	inspection := Inspection{File: v.ConfigFileUsed()}
+	if err := v.Unmarshal(&inspection.Config); err != nil {
+		return inspection, err
+	}
+	inspection.Settings = settingsOf(reflect.ValueOf(inspection.Config), "")
+
+	// Read the file alone so defaults and environment variables don't hide
+	// misspelled keys
+	file := viper.New()
+	file.SetConfigFile(inspection.File)
+	if err := file.ReadInConfig(); err != nil {
+		return inspection, err
+	}
+	for _, key := range file.AllKeys() {
+		if !knownKey(key) {
+			inspection.UnknownKeys = append(inspection.UnknownKeys, key)
+		}
+	}
+	sort.Strings(inspection.UnknownKeys)
+	return inspection, nil
+}
+
+// Validate reports settings whose values are out of range
+func (c Config) Validate() []error {
+	var errs []error
+	nonNegative := map[string]int{
+		"server.page_size":                     c.Server.PageSize,
+		"scanner.passive_rate_limit":           c.Scanner.PassiveRateLimit,
+		"scanner.host_concurrency":             c.Scanner.HostConcurrency,
+		"scanner.global_concurrency":           c.Scanner.GlobalConcurrency,
+		"scanner.result_buffer":                c.Scanner.ResultBuffer,
+		"scanner.spill_threshold":              c.Scanner.SpillThreshold,
+		"scanner.defaults.rate_limit":          c.Scanner.Defaults.RateLimit,
+		"scanner.adaptive.window":              c.Scanner.Adaptive.Window,
+		"scanner.adaptive.min_rate_limit":      c.Scanner.Adaptive.MinRateLimit,
+		"scanner.queue.slots":                  c.Scanner.Queue.Slots,
+		"scanner.queue.interactive_weight":     c.Scanner.Queue.InteractiveWeight,
+		"scanner.safety.max_body_read":         c.Scanner.Safety.MaxBodyRead,
+		"scanner.safety.max_template_requests": c.Scanner.Safety.MaxTemplateRequests,
+		"distributed.capacity":                 c.Distributed.Capacity,
+	}
+	for _, key := range sortedKeys(nonNegative) {
+		if nonNegative[key] < 0 {
+			errs = append(errs, fmt.Errorf("%s: must not be negative, got %d", key, nonNegative[key]))
+		}
+	}
+	if c.Cache.Expiry < 0 {
+		errs = append(errs, fmt.Errorf("cache.expiry: must not be negative, got %s", c.Cache.Expiry))
+	}
+	if c.Scanner.EngineTimeout < 0 {
+		errs = append(errs, fmt.Errorf("scanner.engine_timeout: must not be negative, got %s", c.Scanner.EngineTimeout))
+	}
+	if c.Scanner.Safety.MaxConnectionLifetime < 0 {
+		errs = append(errs, fmt.Errorf("scanner.safety.max_connection_lifetime: must not be negative, got %s", c.Scanner.Safety.MaxConnectionLifetime))
+	}
+	if c.Scanner.Safety.TemplateTimeout < 0 {
+		errs = append(errs, fmt.Errorf("scanner.safety.template_timeout: must not be negative, got %s", c.Scanner.Safety.TemplateTimeout))
+	}
+	if c.Scanner.Adaptive.ErrorRate < 0 || c.Scanner.Adaptive.ErrorRate >= 1 {
+		errs = append(errs, fmt.Errorf("scanner.adaptive.error_rate: must be at least 0 and below 1, got %g", c.Scanner.Adaptive.ErrorRate))
+	}
+	if c.Scanner.Quarantine.Enabled && c.Scanner.Quarantine.Threshold < 1 {
+		errs = append(errs, fmt.Errorf("scanner.quarantine.threshold: must be at least 1, got %d", c.Scanner.Quarantine.Threshold))
+	}
+	switch c.Scanner.Queue.Policy {
+	case "", "strict", "fair":
+	default:
+		errs = append(errs, fmt.Errorf("scanner.queue.policy: must be strict or fair, got %q", c.Scanner.Queue.Policy))
+	}
+	if c.Schedules.Interval < 0 {
+		errs = append(errs, fmt.Errorf("schedules.interval: must not be negative, got %s", c.Schedules.Interval))
+	}
+	if c.Schedules.WarmMargin < 0 {
+		errs = append(errs, fmt.Errorf("schedules.warm_margin: must not be negative, got %s", c.Schedules.WarmMargin))
+	}
+	for i, scan := range c.Schedules.Scans {
+		switch {
+		case strings.TrimSpace(scan.Target) == "":
+			errs = append(errs, fmt.Errorf("schedules.scans[%d]: target is required", i))
+		case scan.WarmCache && c.Cache.Expiry <= 0:
+			errs = append(errs, fmt.Errorf("schedules.scans[%d]: warm_cache needs a positive cache.expiry", i))
+		case !scan.WarmCache && scan.Every <= 0:
+			errs = append(errs, fmt.Errorf("schedules.scans[%d]: every must be positive unless warm_cache is set, got %s", i, scan.Every))
+		}
+	}
+	if (c.Distributed.TLSCert == "") != (c.Distributed.TLSKey == "") {
+		errs = append(errs, fmt.Errorf("distributed.tls_cert, distributed.tls_key: set both to serve jobs over HTTPS"))
+	}
+	if strings.TrimSpace(c.Logging.Path) == "" {
+		errs = append(errs, fmt.Errorf("logging.path: must not be empty"))
+	}
+
+	names := map[string]bool{}
+	for i, bundle := range c.Nuclei.TemplateBundles {
+		switch {
+		case bundle.Name == "" || bundle.Source == "":
+			errs = append(errs, fmt.Errorf("nuclei.template_bundles[%d]: name and source are required", i))
+		case names[bundle.Name]:
+			errs = append(errs, fmt.Errorf("nuclei.template_bundles[%d]: duplicate bundle name %q", i, bundle.Name))
+		}
+		names[bundle.Name] = true
+	}
+
+	filters := c.Nuclei.DefaultFilters
+	for _, field := range []struct {
+		key        string
+		severities []string
+	}{{"severities", filters.Severities}, {"exclude_severities", filters.ExcludeSeverities}} {
+		for _, severity := range field.severities {
+			switch strings.ToLower(strings.TrimSpace(severity)) {
+			case "info", "low", "medium", "high", "critical", "unknown":
+			default:
+				errs = append(errs, fmt.Errorf("nuclei.default_filters.%s: must be info, low, medium, high, critical or unknown, got %q", field.key, severity))
+			}
+		}
+	}
+
+	dirs := map[string]bool{"custom": true}
+	for i, dir := range c.Nuclei.TemplateDirs {
+		switch {
+		case dir.Name == "" || dir.Path == "":
+			errs = append(errs, fmt.Errorf("nuclei.template_dirs[%d]: name and path are required", i))
+		case strings.Contains(dir.Name, ":"):
+			errs = append(errs, fmt.Errorf("nuclei.template_dirs[%d]: name %q must not contain ':'", i, dir.Name))
+		case dirs[dir.Name]:
+			errs = append(errs, fmt.Errorf("nuclei.template_dirs[%d]: duplicate or reserved name %q", i, dir.Name))
+		}
+		dirs[dir.Name] = true
+	}
+
+	workers := map[string]bool{}
+	for i, worker := range c.Distributed.Workers {
+		switch {
+		case worker.Name == "" || worker.URL == "":
+			errs = append(errs, fmt.Errorf("distributed.workers[%d]: name and url are required", i))
+		case workers[worker.Name]:
+			errs = append(errs, fmt.Errorf("distributed.workers[%d]: duplicate worker name %q", i, worker.Name))
+		case worker.Capacity < 0:
+			errs = append(errs, fmt.Errorf("distributed.workers[%d]: capacity must not be negative, got %d", i, worker.Capacity))
+		}
+		workers[worker.Name] = true
+	}
+	if len(c.Distributed.Workers) > 0 && strings.TrimSpace(c.Distributed.Token) == "" {
+		errs = append(errs, fmt.Errorf("distributed.token: required when workers are configured"))
+	}
+
+	tenants := map[string]bool{}
+	tokens := map[string]bool{}
+	for i, tenant := range c.Server.Tenants {
+		switch {
+		case tenant.Name == "" || tenant.Token == "":
+			errs = append(errs, fmt.Errorf("server.tenants[%d]: name and token are required", i))
+		case tenants[tenant.Name]:
+			errs = append(errs, fmt.Errorf("server.tenants[%d]: duplicate tenant name %q", i, tenant.Name))
+		case tokens[tenant.Token]:
+			errs = append(errs, fmt.Errorf("server.tenants[%d]: token is used by another tenant", i))
+		case tenant.RateLimit < 0:
+			errs = append(errs, fmt.Errorf("server.tenants[%d]: rate_limit must not be negative, got %d", i, tenant.RateLimit))
+		}
+		tenants[tenant.Name] = true
+		tokens[tenant.Token] = true
+	}
+	if c.Server.Listen != "" && len(c.Server.Tenants) == 0 {
+		errs = append(errs, fmt.Errorf("server.tenants: required when server.listen is set"))
+	}
+
+	if c.Scanner.Quotas.Default.negative() {
+		errs = append(errs, fmt.Errorf("scanner.quotas.default: limits must not be negative"))
+	}
+	clients := map[string]bool{}
+	for i, client := range c.Scanner.Quotas.Clients {
+		key := strings.ToLower(strings.TrimSpace(client.Client))
+		switch {
+		case key == "":
+			errs = append(errs, fmt.Errorf("scanner.quotas.clients[%d]: client is required", i))
+		case clients[key]:
+			errs = append(errs, fmt.Errorf("scanner.quotas.clients[%d]: duplicate client %q", i, client.Client))
+		case client.negative():
+			errs = append(errs, fmt.Errorf("scanner.quotas.clients[%d]: limits must not be negative", i))
+		}
+		clients[key] = true
+	}
+	if c.Scanner.Quotas.Period < 0 {
+		errs = append(errs, fmt.Errorf("scanner.quotas.period: must not be negative, got %s", c.Scanner.Quotas.Period))
+	}
+
+	if c.Findings.RetestAfter < 0 {
+		errs = append(errs, fmt.Errorf("findings.retest_after: must not be negative, got %s", c.Findings.RetestAfter))
+	}
+	if c.Findings.RetestInterval < 0 {
+		errs = append(errs, fmt.Errorf("findings.retest_interval: must not be negative, got %s", c.Findings.RetestInterval))
+	}
+
+	if c.Assets.Timeout < 0 {
+		errs = append(errs, fmt.Errorf("assets.timeout: must not be negative, got %s", c.Assets.Timeout))
+	}
+	if c.Wordlists.MaxEntries < 0 {
+		errs = append(errs, fmt.Errorf("wordlists.max_entries: must not be negative, got %d", c.Wordlists.MaxEntries))
+	}
+	if c.Assets.CTLogURL != "" {
+		if u, err := url.Parse(c.Assets.CTLogURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("assets.ct_log_url: must be an http(s) URL, got %q", c.Assets.CTLogURL))
+		}
+	}
+	if c.Telemetry.OTLPEndpoint != "" {
+		if u, err := url.Parse(c.Telemetry.OTLPEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("telemetry.otlp_endpoint: must be an http(s) URL, got %q", c.Telemetry.OTLPEndpoint))
+		}
+	}
+
+	switch strings.ToLower(c.Notifications.MinSeverity) {
+	case "", "info", "low", "medium", "high", "critical":
+	default:
+		errs = append(errs, fmt.Errorf("notifications.min_severity: must be info, low, medium, high or critical, got %q", c.Notifications.MinSeverity))
+	}
+	if email := c.Notifications.Email; email.Enabled {
+		if strings.TrimSpace(email.Host) == "" || strings.TrimSpace(email.From) == "" || len(email.To) == 0 {
+			errs = append(errs, fmt.Errorf("notifications.email: host, from and to are required when enabled"))
+		}
+		switch strings.ToLower(email.TLS) {
+		case "", "starttls", "tls", "none":
+		default:
+			errs = append(errs, fmt.Errorf("notifications.email.tls: must be starttls, tls or none, got %q", email.TLS))
+		}
+		if email.Port < 0 || email.Port > 65535 {
+			errs = append(errs, fmt.Errorf("notifications.email.port: invalid port %d", email.Port))
+		}
+	}
+
+	for i, webhook := range c.Notifications.Webhooks {
+		if strings.TrimSpace(webhook.URL) == "" && strings.TrimSpace(webhook.URLEnv) == "" {
+			errs = append(errs, fmt.Errorf("notifications.webhooks[%d]: url or url_env is required", i))
+		}
+		switch strings.ToLower(webhook.Format) {
+		case "", "json", "slack", "teams":
+		default:
+			errs = append(errs, fmt.Errorf("notifications.webhooks[%d]: format must be json, slack or teams, got %q", i, webhook.Format))
+		}
+	}
+
+	for _, port := range c.Policy.Egress.AllowedPorts {
+		if port < 1 || port > 65535 {
+			errs = append(errs, fmt.Errorf("policy.egress.allowed_ports: invalid port %d", port))
+		}
+	}
+	return errs
+}
+
+// Keys returns the keys of every setting, such as "scanner.preload"
+func Keys() []string {
+	return structKeys(reflect.TypeOf(Config{}), "")
+}
+
+func structKeys(t reflect.Type, prefix string) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("mapstructure")
+		if name == "" || name == "-" {
+			continue
+		}
+		key := prefix + name
+		if field.Type.Kind() == reflect.Struct {
+			keys = append(keys, structKeys(field.Type, key+".")...)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// knownKey reports whether a config file key is a setting or a section
+// holding settings
+func knownKey(key string) bool {
+	for _, known := range Keys() {
+		if known == key || strings.HasPrefix(known, key+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// settingsOf converts a config struct into settings keyed like the config
+// file, with durations written as strings and secrets redacted
+func settingsOf(value reflect.Value, prefix string) map[string]any {
+	settings := map[string]any{}
+	for i := 0; i < value.NumField(); i++ {
+		name := value.Type().Field(i).Tag.Get("mapstructure")
+		if name == ",squash" {
+			for key, setting := range settingsOf(value.Field(i), prefix) {
+				settings[key] = setting
+			}
+			continue
+		}
+		if name == "" || name == "-" {
+			continue
+		}
+		settings[name] = settingOf(value.Field(i), prefix+name)
+	}
+	return settings
+}
+
+func settingOf(value reflect.Value, key string) any {
+	if duration, ok := value.Interface().(time.Duration); ok {
+		return duration.String()
+	}
+	switch value.Kind() {
+	case reflect.Struct:
+		return settingsOf(value, key+".")
+	case reflect.Slice:
+		items := make([]any, value.Len())
+		for i := range items {
+			items[i] = settingOf(value.Index(i), key)
+		}
+		return items
+	}
+	return redact(key, value.Interface())
+}
+
+// redact hides the values of secret keys and the credentials of URLs
+func redact(key string, value any) any {
+	name := strings.ToLower(key[strings.LastIndex(key, ".")+1:])
+	for _, secret := range secretKeys {
+		if strings.Contains(name, secret) {
+			if value == nil || value == "" {
+				return value
+			}
+			return redacted
+		}
+	}
+
+	if text, ok := value.(string); ok {
+		if u, err := url.Parse(text); err == nil && u.User != nil {
+			u.User = url.User(redacted)
+			return u.String()
+		}
+	}
+	return value
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// negative reports whether any limit of the quota is below zero
+func (q QuotaConfig) negative() bool {
+	return q.Scans < 0 || q.Requests < 0 || q.Bytes < 0 || q.WallTime < 0 || q.CPUTime < 0
+}
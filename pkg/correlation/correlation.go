@@ -0,0 +1,43 @@
+package correlation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+)
+
+// MetaKey is the _meta field of tool calls and results carrying the
+// correlation ID
+const MetaKey = "correlation_id"
+
+// validID matches IDs accepted from clients, so they are safe to log
+var validID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+type contextKey struct{}
+
+// New returns a random correlation ID
+func New() string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "00000000"
+	}
+	return hex.EncodeToString(id)
+}
+
+// Valid reports whether id can be used as a correlation ID: 1 to 64
+// letters, digits, '.', '_', ':' or '-'
+func Valid(id string) bool {
+	return validID.MatchString(id)
+}
+
+// NewContext returns a context carrying id
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the correlation ID of ctx, or ""
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
@@ -0,0 +1,576 @@
+package distributed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/correlation"
+	"nuclei-mcp/pkg/policy"
+	"nuclei-mcp/pkg/scanner"
+	"nuclei-mcp/pkg/telemetry"
+)
+
+// DefaultHealthInterval is how often the coordinator checks its workers
+const DefaultHealthInterval = 15 * time.Second
+
+// ErrNoWorkers is returned when no healthy worker can take a job
+var ErrNoWorkers = errors.New("no healthy scan worker available")
+
+// Worker is a worker instance the coordinator sends jobs to
+type Worker struct {
+	Name string
+	// URL is the base URL of the worker, such as https://10.0.0.5:8765
+	URL string
+	// Capacity is the number of jobs sent to the worker at once; zero uses
+	// the capacity the worker reports
+	Capacity int
+}
+
+// WorkerStatus reports the health and load of a worker
+type WorkerStatus struct {
+	Name      string    `json:"name"`
+	URL       string    `json:"url,omitempty"`
+	Healthy   bool      `json:"healthy"`
+	Capacity  int       `json:"capacity"`
+	Active    int       `json:"active"`
+	Completed int64     `json:"completed"`
+	Failed    int64     `json:"failed"`
+	LastSeen  time.Time `json:"last_seen,omitempty"`
+	// Error is the last health check or transport error of an unhealthy
+	// worker
+	Error string `json:"error,omitempty"`
+}
+
+// remoteWorker is a worker and the coordinator's view of it
+type remoteWorker struct {
+	Worker
+	status WorkerStatus
+}
+
+// Coordinator is a scanner service that sends scans to workers as jobs and
+// stores their results in the local cache, so results, reports and trends
+// cover every worker. Basic scans and scans of coordinator-local template
+// files run on the local service. A job whose worker fails or is busy is
+// sent to another worker. The coordinator checks targets against its own
+// egress policy and self-target guard, and applies quotas and change
+// detection itself, since the workers see neither its clients nor its
+// cache.
+type Coordinator struct {
+	local            scanner.ScannerService
+	cache            scanner.CacheInterface
+	console          scanner.LoggerInterface
+	token            string
+	client           *http.Client
+	healthInterval   time.Duration
+	passiveByDefault bool
+	queue            *scanner.ScanQueue
+	maintenance      *scanner.Maintenance
+	quotas           *scanner.QuotaTracker
+	notifier         scanner.ResultNotifier
+	egress           *policy.EgressPolicy
+	selfGuard        *policy.SelfTargetGuard
+	jobs             atomic.Int64
+
+	// mu guards the worker states; changed is closed and replaced whenever
+	// a worker frees up or becomes healthy
+	mu      sync.Mutex
+	workers []*remoteWorker
+	changed chan struct{}
+}
+
+// CoordinatorOption configures a coordinator
+type CoordinatorOption func(*Coordinator)
+
+// WithHealthInterval sets how often Run checks the workers
+func WithHealthInterval(interval time.Duration) CoordinatorOption {
+	return func(c *Coordinator) {
+		if interval > 0 {
+			c.healthInterval = interval
+		}
+	}
+}
+
+// WithPassiveByDefault makes jobs passive unless a scan opts out, like the
+// local scanner service's option
+func WithPassiveByDefault(enabled bool) CoordinatorOption {
+	return func(c *Coordinator) {
+		c.passiveByDefault = enabled
+	}
+}
+
+// WithScanQueue sends jobs through queue, so interactive scans are sent
+// before waiting background scans
+func WithScanQueue(queue *scanner.ScanQueue) CoordinatorOption {
+	return func(c *Coordinator) {
+		c.queue = queue
+	}
+}
+
+// WithQuotas accounts jobs to the client that requested them and refuses
+// jobs beyond the client's quota, using the usage reported by the workers
+func WithQuotas(quotas *scanner.QuotaTracker) CoordinatorOption {
+	return func(c *Coordinator) {
+		c.quotas = quotas
+	}
+}
+
+// WithMaintenance refuses new jobs while m is paused
+func WithMaintenance(m *scanner.Maintenance) CoordinatorOption {
+	return func(c *Coordinator) {
+		c.maintenance = m
+	}
+}
+
+// WithResultNotifier tells notifier of the results of completed jobs, like
+// the local scanner service's option
+func WithResultNotifier(notifier scanner.ResultNotifier) CoordinatorOption {
+	return func(c *Coordinator) {
+		c.notifier = notifier
+	}
+}
+
+// WithTargetPolicy refuses targets the egress policy denies and scans of
+// the coordinator's own host, before the coordinator sends any request to
+// them. Either may be nil.
+func WithTargetPolicy(egress *policy.EgressPolicy, self *policy.SelfTargetGuard) CoordinatorOption {
+	return func(c *Coordinator) {
+		c.egress, c.selfGuard = egress, self
+	}
+}
+
+// WithHTTPClient sets the client used to reach the workers
+func WithHTTPClient(client *http.Client) CoordinatorOption {
+	return func(c *Coordinator) {
+		c.client = client
+	}
+}
+
+// NewCoordinator creates a coordinator for the workers. Workers are assumed
+// healthy until a job or health check to them fails.
+func NewCoordinator(local scanner.ScannerService, resultCache scanner.CacheInterface, console scanner.LoggerInterface, token string, workers []Worker, opts ...CoordinatorOption) (*Coordinator, error) {
+	if strings.TrimSpace(token) == "" {
+		return nil, fmt.Errorf("a coordinator needs a token shared with its workers")
+	}
+	c := &Coordinator{
+		local:          local,
+		cache:          resultCache,
+		console:        console,
+		token:          token,
+		client:         &http.Client{},
+		healthInterval: DefaultHealthInterval,
+		changed:        make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	names := map[string]bool{}
+	for _, worker := range workers {
+		switch {
+		case worker.Name == "" || worker.URL == "":
+			return nil, fmt.Errorf("scan worker needs a name and a URL")
+		case names[worker.Name]:
+			return nil, fmt.Errorf("duplicate scan worker name %q", worker.Name)
+		}
+		if err := checkWorkerURL(worker.URL); err != nil {
+			return nil, err
+		}
+		names[worker.Name] = true
+		worker.URL = strings.TrimSuffix(worker.URL, "/")
+		c.workers = append(c.workers, &remoteWorker{
+			Worker: worker,
+			status: WorkerStatus{Name: worker.Name, URL: worker.URL, Healthy: true, Capacity: capacityOf(worker.Capacity)},
+		})
+	}
+	if len(c.workers) == 0 {
+		return nil, fmt.Errorf("a coordinator needs at least one scan worker")
+	}
+	return c, nil
+}
+
+// checkWorkerURL refuses worker URLs that would send the token, jobs and
+// findings in clear over the network: only https URLs, or http URLs of the
+// coordinator's own host, are accepted
+func checkWorkerURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid scan worker URL %q", raw)
+	}
+	switch u.Scheme {
+	case "https":
+		return nil
+	case "http":
+		if host := u.Hostname(); host == "localhost" || isLoopback(host) {
+			return nil
+		}
+		return fmt.Errorf("scan worker URL %s must use https, serve the worker with distributed.tls_cert and distributed.tls_key", raw)
+	}
+	return fmt.Errorf("invalid scan worker URL %q, use https", raw)
+}
+
+func isLoopback(host string) bool {
+	addr, err := netip.ParseAddr(host)
+	return err == nil && addr.IsLoopback()
+}
+
+func capacityOf(capacity int) int {
+	if capacity > 0 {
+		return capacity
+	}
+	return DefaultCapacity
+}
+
+// Workers returns the status of every worker
+func (c *Coordinator) Workers() []WorkerStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	statuses := make([]WorkerStatus, 0, len(c.workers))
+	for _, w := range c.workers {
+		statuses = append(statuses, w.status)
+	}
+	return statuses
+}
+
+// Run checks the health of the workers every health interval until ctx is
+// done
+func (c *Coordinator) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.healthInterval)
+	defer ticker.Stop()
+	for {
+		c.CheckHealth(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckHealth asks every worker for its status
+func (c *Coordinator) CheckHealth(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, w := range c.workers {
+		wg.Add(1)
+		go func(w *remoteWorker) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, c.healthInterval)
+			defer cancel()
+			var reported WorkerStatus
+			err := c.call(ctx, w, http.MethodGet, HealthPath, nil, &reported)
+
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			if err != nil {
+				if w.status.Healthy {
+					c.console.Log("Scan worker %s is unhealthy: %v", w.Name, err)
+				}
+				w.status.Healthy, w.status.Error = false, err.Error()
+				return
+			}
+			if !w.status.Healthy {
+				c.console.Log("Scan worker %s is healthy again", w.Name)
+			}
+			w.status.Healthy, w.status.Error, w.status.LastSeen = true, "", time.Now()
+			if w.Capacity == 0 && reported.Capacity > 0 {
+				w.status.Capacity = reported.Capacity
+			}
+			c.notify()
+		}(w)
+	}
+	wg.Wait()
+}
+
+// notify wakes up jobs waiting for a worker. c.mu must be held.
+func (c *Coordinator) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// acquire reserves a slot on the least loaded healthy worker not in
+// skipped, waiting for a slot when all of them are busy
+func (c *Coordinator) acquire(ctx context.Context, skipped map[*remoteWorker]bool) (*remoteWorker, error) {
+	for {
+		c.mu.Lock()
+		var best *remoteWorker
+		available := false
+		for _, w := range c.workers {
+			if !w.status.Healthy || skipped[w] {
+				continue
+			}
+			available = true
+			if w.status.Active < w.status.Capacity && (best == nil || w.status.Active < best.status.Active) {
+				best = w
+			}
+		}
+		if best != nil {
+			best.status.Active++
+			c.mu.Unlock()
+			return best, nil
+		}
+		changed := c.changed
+		c.mu.Unlock()
+		if !available {
+			return nil, ErrNoWorkers
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// release frees the slot of a job sent to w. A worker that could not be
+// reached is marked unhealthy until its next successful health check.
+func (c *Coordinator) release(w *remoteWorker, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w.status.Active--
+	var unreachable *unreachableError
+	switch {
+	case errors.As(err, &unreachable):
+		w.status.Healthy, w.status.Error = false, err.Error()
+	case errors.Is(err, errBusy):
+	case err != nil:
+		w.status.Failed++
+	default:
+		w.status.Completed++
+		w.status.LastSeen = time.Now()
+	}
+	c.notify()
+}
+
+// unreachableError is a job or health check that did not reach the worker
+type unreachableError struct{ err error }
+
+func (e *unreachableError) Error() string { return e.err.Error() }
+func (e *unreachableError) Unwrap() error { return e.err }
+
+// errBusy is returned when a worker refuses a job for lack of capacity
+var errBusy = errors.New("worker is busy")
+
+// call sends a request to a worker and decodes its JSON answer into out
+func (c *Coordinator) call(ctx context.Context, w *remoteWorker, method, path string, body any, out any) error {
+	var payload io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, w.URL+path, payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+	telemetry.Inject(ctx, req.Header)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return &unreachableError{err}
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusServiceUnavailable:
+		return errBusy
+	case resp.StatusCode >= 500:
+		return &unreachableError{fmt.Errorf("worker answered %s", resp.Status)}
+	case resp.StatusCode != http.StatusOK:
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("worker %s refused the request: %s: %s", w.Name, resp.Status, strings.TrimSpace(string(message)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return &unreachableError{fmt.Errorf("invalid worker answer: %w", err)}
+	}
+	return nil
+}
+
+// dispatch sends the job to workers until one of them runs it
+func (c *Coordinator) dispatch(ctx context.Context, job Job) (cache.ScanResult, error) {
+	skipped := map[*remoteWorker]bool{}
+	for {
+		w, err := c.acquire(ctx, skipped)
+		if err != nil {
+			return cache.ScanResult{}, err
+		}
+
+		var answer JobResult
+		err = c.call(ctx, w, http.MethodPost, JobsPath, job, &answer)
+		if err == nil && answer.Error != nil {
+			err = answer.Error.err(w.Name)
+		}
+		c.release(w, err)
+
+		var unreachable *unreachableError
+		if errors.Is(err, errBusy) || errors.As(err, &unreachable) {
+			c.console.Log("Job %s could not run on worker %s (%v), trying another worker", job.ID, w.Name, err)
+			skipped[w] = true
+			continue
+		}
+		if err != nil {
+			return cache.ScanResult{}, err
+		}
+		if answer.Result == nil {
+			return cache.ScanResult{}, fmt.Errorf("worker %s returned no result for job %s", w.Name, job.ID)
+		}
+		c.console.Log("Job %s completed on worker %s", job.ID, w.Name)
+		return *answer.Result, nil
+	}
+}
+
+func (c *Coordinator) CreateCacheKey(target string, severity string, protocols string) string {
+	return c.local.CreateCacheKey(target, severity, protocols)
+}
+
+// Scan runs the scan on a worker
+func (c *Coordinator) Scan(target string, severity string, protocols string, templateIDs []string, opts ...scanner.ScanOption) (cache.ScanResult, error) {
+	return c.ThreadSafeScan(context.Background(), target, severity, protocols, templateIDs, opts...)
+}
+
+// ThreadSafeScan runs the scan on a worker and stores its result in the
+// local cache
+func (c *Coordinator) ThreadSafeScan(ctx context.Context, target string, severity string, protocols string, templateIDs []string, opts ...scanner.ScanOption) (cache.ScanResult, error) {
+	opts = append([]scanner.ScanOption{scanner.WithCorrelationID(correlation.FromContext(ctx))}, opts...)
+	scanOpts := scanner.ScanOptions{Passive: c.passiveByDefault}
+	for _, opt := range opts {
+		opt(&scanOpts)
+	}
+	if len(scanOpts.TemplateSources) > 0 || len(scanOpts.InlineTemplates) > 0 || (scanOpts.CrawlDepth > 0 && scanOpts.CrawlScope != nil) {
+		// Template files given by path and inline templates only exist on
+		// this host, and the scope of a crawl cannot be sent to a worker
+		c.console.Log("Scanning %s on the coordinator: its templates or crawl scope cannot be sent to workers", target)
+		return c.local.ThreadSafeScan(ctx, target, severity, protocols, templateIDs, opts...)
+	}
+
+	if err := c.checkTarget(ctx, target, scanOpts); err != nil {
+		c.console.Log("Scan of %s refused: %v", target, err)
+		return cache.ScanResult{}, err
+	}
+
+	job := newJob(target, severity, protocols, templateIDs, scanOpts)
+	cacheKey := job.cacheKey(c.local.CreateCacheKey(target, severity, protocols))
+	if result, found := c.cache.Get(cacheKey); found && !scanOpts.Fresh {
+		c.console.Log("Returning cached scan result for %s (%d findings)", target, len(result.Findings))
+		return result, nil
+	}
+
+	// The coordinator checks for changes itself, as the workers do not see
+	// its cache
+	var fingerprint string
+	if scanOpts.SkipUnchanged && !scanOpts.Passive {
+		client := scanner.NewTargetClient(scanner.TargetClientOptions{Egress: c.egress, RateLimit: scanOpts.RateLimit})
+		result, checked, unchanged := scanner.CheckUnchanged(ctx, c.console, client, c.cache, cacheKey, target)
+		client.CloseIdleConnections()
+		if unchanged {
+			return result, nil
+		}
+		fingerprint = checked
+	}
+
+	done, err := c.maintenance.Start()
+	if err != nil {
+		c.console.Log("Scan of %s refused: %v", target, err)
+		return cache.ScanResult{}, err
+	}
+	defer done()
+
+	if err := c.quotas.Check(scanOpts.Client); err != nil {
+		c.console.Log("Scan of %s refused: %v", target, err)
+		return cache.ScanResult{}, err
+	}
+
+	if c.queue != nil {
+		release, err := c.queue.Acquire(ctx, scanOpts.Priority)
+		if err != nil {
+			return cache.ScanResult{}, err
+		}
+		defer release()
+	}
+
+	job.ID = fmt.Sprintf("job-%d", c.jobs.Add(1))
+	c.console.Log("Sending job %s: %s scan of %s", job.ID, scanOpts.Priority, target)
+	if job.CorrelationID != "" {
+		c.console.Log("Job %s runs the scan of request %s", job.ID, job.CorrelationID)
+	}
+	result, err := c.dispatch(ctx, job)
+	if err != nil {
+		c.console.Log("Job %s failed: %v", job.ID, err)
+		return cache.ScanResult{}, err
+	}
+	c.quotas.Record(scanOpts.Client, result.Stats)
+	result.TargetFingerprint = fingerprint
+	c.cache.Set(cacheKey, result)
+	if c.notifier != nil {
+		c.notifier.Notify(result)
+	}
+	return result, nil
+}
+
+// checkTarget applies the egress policy and self-target guard to target, as
+// the local service does, before the coordinator fingerprints it
+func (c *Coordinator) checkTarget(ctx context.Context, target string, scanOpts scanner.ScanOptions) error {
+	target = policy.NormalizeTarget(target)
+	if err := c.egress.CheckTarget(ctx, target); err != nil {
+		return err
+	}
+	if c.selfGuard == nil {
+		return nil
+	}
+	return c.selfGuard.Check(ctx, target, policy.Override{Allow: scanOpts.AllowSelfTarget, Approval: scanOpts.Approval})
+}
+
+// RunningScans reports the progress of the scans running on the local
+// service; jobs sent to workers are reported by list_workers
+func (c *Coordinator) RunningScans() []scanner.ScanProgress {
+	if reporter, ok := c.local.(scanner.ProgressReporter); ok {
+		return reporter.RunningScans()
+	}
+	return nil
+}
+
+// Preview estimates a scan with the local service, whose templates the
+// workers share
+func (c *Coordinator) Preview(ctx context.Context, target string, severity string, protocols string, templateIDs []string, opts ...scanner.ScanOption) (scanner.ScanPreview, error) {
+	previewer, ok := c.local.(scanner.Previewer)
+	if !ok {
+		return scanner.ScanPreview{}, scanner.ErrPreviewUnsupported
+	}
+	return previewer.Preview(ctx, target, severity, protocols, templateIDs, opts...)
+}
+
+// FetchOpenAPI fetches an OpenAPI document with the local service
+func (c *Coordinator) FetchOpenAPI(ctx context.Context, specURL string, opts ...scanner.ScanOption) ([]byte, error) {
+	fetcher, ok := c.local.(scanner.OpenAPIFetcher)
+	if !ok {
+		return nil, fmt.Errorf("%w: this scanner cannot fetch spec_url, pass spec", scanner.ErrInvalidOpenAPI)
+	}
+	return fetcher.FetchOpenAPI(ctx, specURL, opts...)
+}
+
+// BasicScan runs the basic scan locally
+func (c *Coordinator) BasicScan(target string) (cache.ScanResult, error) {
+	return c.local.BasicScan(target)
+}
+
+func (c *Coordinator) GetAll() []cache.ScanResult {
+	return c.cache.GetAll()
+}
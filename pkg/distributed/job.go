@@ -0,0 +1,281 @@
+// Package distributed runs scans on worker instances: a coordinator sends
+// scan jobs to workers over HTTP, stores their results and tracks their
+// health, so scanning scales beyond one host. Jobs are JSON over HTTPS
+// rather than gRPC or NATS messages, so a worker is a plain nuclei-mcp
+// instance and no broker or generated code is needed; the coordinator
+// does the queueing, health checks and retries a broker would.
+package distributed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/policy"
+	"nuclei-mcp/pkg/scanner"
+	"nuclei-mcp/pkg/secrets"
+)
+
+// Paths of the worker HTTP API
+const (
+	JobsPath   = "/v1/jobs"
+	HealthPath = "/v1/health"
+)
+
+// Job is a scan a coordinator sends to a worker. It carries the scan options
+// as resolved by the coordinator, so its passive default applies.
+type Job struct {
+	ID            string              `json:"id"`
+	Target        string              `json:"target"`
+	Severity      string              `json:"severity,omitempty"`
+	Protocols     string              `json:"protocols,omitempty"`
+	TemplateIDs   []string            `json:"template_ids,omitempty"`
+	Tags          []string            `json:"tags,omitempty"`
+	Passive       bool                `json:"passive"`
+	RateLimit     int                 `json:"rate_limit,omitempty"`
+	CodeTemplates bool                `json:"code_templates,omitempty"`
+	AllowUnsafe   bool                `json:"allow_unsafe,omitempty"`
+	AllowSelf     bool                `json:"allow_self_target,omitempty"`
+	Approval      string              `json:"approval,omitempty"`
+	Extractors    []scanner.Extractor `json:"extractors,omitempty"`
+	Priority      string              `json:"priority,omitempty"`
+	Fresh         bool                `json:"fresh,omitempty"`
+	StopAt        string              `json:"stop_at,omitempty"`
+	// TemplateTimeout and MaxTemplateRequests are the per-template limits
+	// the scan asked for; workers lower them to their own safety limits
+	TemplateTimeout     time.Duration     `json:"template_timeout,omitempty"`
+	MaxTemplateRequests int               `json:"max_template_requests,omitempty"`
+	CorrelationID       string            `json:"correlation_id,omitempty"`
+	Labels              map[string]string `json:"labels,omitempty"`
+	// Variables carry secret:// references, which workers resolve from
+	// their own secrets
+	Variables         map[string]string `json:"variables,omitempty"`
+	ProbeHTTPVersions bool              `json:"probe_http_versions,omitempty"`
+	AddressFamily     string            `json:"address_family,omitempty"`
+	SNI               string            `json:"sni,omitempty"`
+	HostHeader        string            `json:"host_header,omitempty"`
+	DetectWAF         bool              `json:"detect_waf,omitempty"`
+	OpenAPI           []byte            `json:"openapi,omitempty"`
+	// APIParameters carry secret:// references like Variables
+	APIParameters map[string]string `json:"api_parameters,omitempty"`
+	Fuzz          bool              `json:"fuzz,omitempty"`
+	CrawlDepth    int               `json:"crawl_depth,omitempty"`
+	CrawlLimit    int               `json:"crawl_limit,omitempty"`
+	// ExcludeTags, ExcludeIDs, ExcludeSeverities and Authors are the
+	// template filters of the scan on top of the worker's exclusion rules
+	ExcludeTags       []string `json:"exclude_tags,omitempty"`
+	ExcludeIDs        []string `json:"exclude_ids,omitempty"`
+	ExcludeSeverities []string `json:"exclude_severities,omitempty"`
+	Authors           []string `json:"authors,omitempty"`
+}
+
+// JobResult is a worker's answer to a job: the scan result or its error
+type JobResult struct {
+	ID     string            `json:"id"`
+	Worker string            `json:"worker"`
+	Result *cache.ScanResult `json:"result,omitempty"`
+	Error  *JobError         `json:"error,omitempty"`
+}
+
+// JobError describes a failed scan. Kind is the message of the scanner or
+// policy error the failure wraps, if any, so the coordinator can restore it.
+type JobError struct {
+	Kind    string `json:"kind,omitempty"`
+	Message string `json:"message"`
+}
+
+// errorKinds are the errors whose identity survives the trip from a worker
+// to the coordinator, so scan tools report the same error codes
+var errorKinds = []error{
+	scanner.ErrEngineInit,
+	scanner.ErrEngineTimeout,
+	scanner.ErrNoTemplates,
+	scanner.ErrNoTargets,
+	scanner.ErrScanPanic,
+	scanner.ErrInvalidProtocol,
+	scanner.ErrInvalidSeverity,
+	scanner.ErrQuotaExceeded,
+	scanner.ErrMaintenance,
+	scanner.ErrInvalidVariable,
+	scanner.ErrInvalidAddressFamily,
+	scanner.ErrInvalidHostOverride,
+	scanner.ErrInvalidOpenAPI,
+	scanner.ErrInvalidAPIParameter,
+	secrets.ErrUnknownSecret,
+	policy.ErrDenied,
+	policy.ErrInvalidTarget,
+	context.DeadlineExceeded,
+}
+
+// newJob describes a scan with the given options
+func newJob(target, severity, protocols string, templateIDs []string, scanOpts scanner.ScanOptions) Job {
+	if len(templateIDs) == 0 {
+		templateIDs = scanOpts.DefaultTemplateIDs
+	}
+	return Job{
+		Target:              target,
+		Severity:            severity,
+		Protocols:           protocols,
+		TemplateIDs:         templateIDs,
+		Tags:                scanOpts.Tags,
+		Passive:             scanOpts.Passive,
+		RateLimit:           scanOpts.RateLimit,
+		CodeTemplates:       scanOpts.CodeTemplates,
+		AllowUnsafe:         scanOpts.AllowUnsafe,
+		AllowSelf:           scanOpts.AllowSelfTarget,
+		Approval:            scanOpts.Approval,
+		Extractors:          scanOpts.Extractors,
+		Priority:            scanOpts.Priority.String(),
+		Fresh:               scanOpts.Fresh,
+		StopAt:              scanOpts.StopAt,
+		TemplateTimeout:     scanOpts.TemplateTimeout,
+		MaxTemplateRequests: scanOpts.MaxTemplateRequests,
+		CorrelationID:       scanOpts.CorrelationID,
+		Labels:              scanOpts.Labels,
+		Variables:           scanOpts.Variables,
+		ProbeHTTPVersions:   scanOpts.ProbeHTTPVersions,
+		AddressFamily:       string(scanOpts.AddressFamily),
+		SNI:                 scanOpts.SNI,
+		HostHeader:          scanOpts.HostHeader,
+		DetectWAF:           scanOpts.DetectWAF,
+		OpenAPI:             scanOpts.OpenAPI,
+		APIParameters:       scanOpts.APIParameters,
+		Fuzz:                scanOpts.Fuzz,
+		CrawlDepth:          scanOpts.CrawlDepth,
+		CrawlLimit:          scanOpts.CrawlLimit,
+		ExcludeTags:         scanOpts.ExcludeTags,
+		ExcludeIDs:          scanOpts.ExcludeIDs,
+		ExcludeSeverities:   scanOpts.ExcludeSeverities,
+		Authors:             scanOpts.Authors,
+	}
+}
+
+// scanOptions returns the options that run the job's scan
+func (j Job) scanOptions() []scanner.ScanOption {
+	opts := []scanner.ScanOption{scanner.WithPassive(j.Passive), scanner.WithCorrelationID(j.CorrelationID), scanner.WithLabels(j.Labels), scanner.WithVariables(j.Variables)}
+	if priority, err := scanner.ParsePriority(j.Priority); err == nil {
+		opts = append(opts, scanner.WithPriority(priority))
+	}
+	if len(j.Tags) > 0 {
+		opts = append(opts, scanner.WithTags(j.Tags...))
+	}
+	if j.RateLimit > 0 {
+		opts = append(opts, scanner.WithRateLimit(j.RateLimit))
+	}
+	if j.CodeTemplates {
+		opts = append(opts, scanner.WithCodeTemplates())
+	}
+	if j.AllowUnsafe {
+		opts = append(opts, scanner.WithUnsafeTemplates(j.Approval))
+	}
+	if j.AllowSelf {
+		opts = append(opts, scanner.WithSelfTargetAllowed(j.Approval))
+	}
+	if len(j.Extractors) > 0 {
+		opts = append(opts, scanner.WithExtractors(j.Extractors...))
+	}
+	if j.Fresh {
+		opts = append(opts, scanner.WithFreshResult())
+	}
+	if j.StopAt != "" {
+		opts = append(opts, scanner.WithStopAtSeverity(j.StopAt))
+	}
+	if j.TemplateTimeout > 0 {
+		opts = append(opts, scanner.WithTemplateTimeout(j.TemplateTimeout))
+	}
+	if j.MaxTemplateRequests > 0 {
+		opts = append(opts, scanner.WithMaxTemplateRequests(j.MaxTemplateRequests))
+	}
+	if j.ProbeHTTPVersions {
+		opts = append(opts, scanner.WithHTTPVersionProbe())
+	}
+	if family, err := scanner.ParseAddressFamily(j.AddressFamily); err == nil {
+		opts = append(opts, scanner.WithAddressFamily(family))
+	}
+	if j.SNI != "" {
+		opts = append(opts, scanner.WithSNI(j.SNI))
+	}
+	if j.HostHeader != "" {
+		opts = append(opts, scanner.WithHostHeader(j.HostHeader))
+	}
+	if j.DetectWAF {
+		opts = append(opts, scanner.WithWAFDetection())
+	}
+	if len(j.OpenAPI) > 0 {
+		opts = append(opts, scanner.WithOpenAPI(j.OpenAPI, j.APIParameters))
+	}
+	if j.Fuzz {
+		opts = append(opts, scanner.WithFuzzing())
+	}
+	if j.CrawlDepth > 0 {
+		opts = append(opts, scanner.WithCrawl(j.CrawlDepth, j.CrawlLimit))
+	}
+	if len(j.ExcludeTags) > 0 {
+		opts = append(opts, scanner.WithExcludedTags(j.ExcludeTags...))
+	}
+	if len(j.ExcludeIDs) > 0 {
+		opts = append(opts, scanner.WithExcludedIDs(j.ExcludeIDs...))
+	}
+	if len(j.ExcludeSeverities) > 0 {
+		opts = append(opts, scanner.WithExcludedSeverities(j.ExcludeSeverities...))
+	}
+	if len(j.Authors) > 0 {
+		opts = append(opts, scanner.WithAuthors(j.Authors...))
+	}
+	return opts
+}
+
+// cacheKey identifies the job's scan regardless of its ID, so the
+// coordinator caches remote results like local ones
+func (j Job) cacheKey(base string) string {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s|%v|%s|%v|%d|%v|%v|%s|%s|%s|%d|%s|%s|%v|%s|%s|%s|%v|%s|%s|%v|%d/%d", strings.Join(j.TemplateIDs, ","), j.Passive, strings.Join(j.Tags, ","), j.CodeTemplates, j.RateLimit, j.AllowUnsafe, j.Extractors, j.Approval, j.StopAt, j.TemplateTimeout, j.MaxTemplateRequests, scanner.FormatLabels(j.Labels), scanner.FormatLabels(j.Variables), j.ProbeHTTPVersions, j.AddressFamily, j.SNI, j.HostHeader, j.DetectWAF, j.OpenAPI, scanner.FormatLabels(j.APIParameters), j.Fuzz, j.CrawlDepth, j.CrawlLimit)
+	if len(j.ExcludeTags) > 0 || len(j.ExcludeIDs) > 0 || len(j.ExcludeSeverities) > 0 || len(j.Authors) > 0 {
+		fmt.Fprintf(h, "|%s|%s|%s|%s", strings.Join(j.ExcludeTags, ","), strings.Join(j.ExcludeIDs, ","), strings.Join(j.ExcludeSeverities, ","), strings.Join(j.Authors, ","))
+	}
+	return fmt.Sprintf("%s:job=%x", base, h.Sum64())
+}
+
+// jobError describes err for the coordinator
+func jobError(err error) *JobError {
+	jobErr := &JobError{Message: err.Error()}
+	for _, kind := range errorKinds {
+		if errors.Is(err, kind) {
+			jobErr.Kind = kind.Error()
+			break
+		}
+	}
+	return jobErr
+}
+
+// remoteError is a scan error reported by a worker. It unwraps to the
+// scanner or policy error it was reported as.
+type remoteError struct {
+	worker  string
+	message string
+	kind    error
+}
+
+func (e *remoteError) Error() string {
+	return fmt.Sprintf("worker %s: %s", e.worker, e.message)
+}
+
+func (e *remoteError) Unwrap() error {
+	return e.kind
+}
+
+// err restores the error a worker reported
+func (e *JobError) err(worker string) error {
+	remote := &remoteError{worker: worker, message: e.Message}
+	for _, kind := range errorKinds {
+		if kind.Error() == e.Kind {
+			remote.kind = kind
+			break
+		}
+	}
+	return remote
+}
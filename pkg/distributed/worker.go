@@ -0,0 +1,152 @@
+package distributed
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"nuclei-mcp/pkg/scanner"
+	"nuclei-mcp/pkg/telemetry"
+)
+
+// DefaultCapacity is the number of jobs a worker runs at once
+const DefaultCapacity = 4
+
+// WorkerHandler serves scan jobs from a coordinator over HTTP and runs them
+// as thread-safe scans of the worker's scanner service, with the worker's own
+// egress policy, denied tags and exclusions. Requests must carry the shared
+// token as a bearer token.
+type WorkerHandler struct {
+	service scanner.ScannerService
+	console scanner.LoggerInterface
+	token   string
+	name    string
+	slots   chan struct{}
+
+	completed atomic.Int64
+	failed    atomic.Int64
+	mux       *http.ServeMux
+}
+
+// WorkerOption configures a worker handler
+type WorkerOption func(*WorkerHandler)
+
+// WithWorkerName sets the name the worker reports in results and health
+// checks
+func WithWorkerName(name string) WorkerOption {
+	return func(w *WorkerHandler) {
+		w.name = name
+	}
+}
+
+// WithCapacity sets how many jobs the worker runs at once. Jobs beyond it
+// are refused as busy, and the coordinator sends them to another worker.
+func WithCapacity(capacity int) WorkerOption {
+	return func(w *WorkerHandler) {
+		if capacity > 0 {
+			w.slots = make(chan struct{}, capacity)
+		}
+	}
+}
+
+// NewWorkerHandler creates the HTTP handler of a worker. The token is
+// required: a worker runs scans for anyone who holds it.
+func NewWorkerHandler(service scanner.ScannerService, console scanner.LoggerInterface, token string, opts ...WorkerOption) (*WorkerHandler, error) {
+	if strings.TrimSpace(token) == "" {
+		return nil, fmt.Errorf("a worker needs a token shared with its coordinator")
+	}
+	w := &WorkerHandler{
+		service: service,
+		console: console,
+		token:   token,
+		name:    "worker",
+		slots:   make(chan struct{}, DefaultCapacity),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	w.mux = http.NewServeMux()
+	w.mux.HandleFunc("GET "+HealthPath, w.handleHealth)
+	w.mux.HandleFunc("POST "+JobsPath, w.handleJob)
+	return w, nil
+}
+
+func (w *WorkerHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if !authorized(r, w.token) {
+		http.Error(rw, "invalid or missing token", http.StatusUnauthorized)
+		return
+	}
+	w.mux.ServeHTTP(rw, r)
+}
+
+// Status reports the worker's capacity and jobs
+func (w *WorkerHandler) Status() WorkerStatus {
+	return WorkerStatus{
+		Name:      w.name,
+		Healthy:   true,
+		Capacity:  cap(w.slots),
+		Active:    len(w.slots),
+		Completed: w.completed.Load(),
+		Failed:    w.failed.Load(),
+	}
+}
+
+func (w *WorkerHandler) handleHealth(rw http.ResponseWriter, r *http.Request) {
+	writeJSON(rw, http.StatusOK, w.Status())
+}
+
+func (w *WorkerHandler) handleJob(rw http.ResponseWriter, r *http.Request) {
+	var job Job
+	if err := json.NewDecoder(r.Body).Decode(&job); err != nil {
+		http.Error(rw, fmt.Sprintf("invalid job: %v", err), http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(job.Target) == "" {
+		http.Error(rw, "invalid job: missing target", http.StatusBadRequest)
+		return
+	}
+
+	select {
+	case w.slots <- struct{}{}:
+		defer func() { <-w.slots }()
+	default:
+		http.Error(rw, "worker is busy", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.console.Log("Running job %s: scan of %s", job.ID, job.Target)
+	answer := JobResult{ID: job.ID, Worker: w.name}
+	result, err := w.service.ThreadSafeScan(telemetry.Extract(r.Context(), r.Header), job.Target, job.Severity, job.Protocols, job.TemplateIDs, job.scanOptions()...)
+	if err != nil {
+		w.failed.Add(1)
+		w.console.Log("Job %s failed: %v", job.ID, err)
+		answer.Error = jobError(err)
+		writeJSON(rw, http.StatusOK, answer)
+		return
+	}
+
+	// Spilled findings stay on the worker
+	if result.SpillFile != "" {
+		w.console.Log("Job %s: %d findings beyond the spill threshold are kept in %s on the worker", job.ID, result.SpilledFindings, result.SpillFile)
+		result.SpillFile = ""
+	}
+	w.completed.Add(1)
+	answer.Result = &result
+	writeJSON(rw, http.StatusOK, answer)
+}
+
+// authorized reports whether r carries token as its bearer token
+func authorized(r *http.Request, token string) bool {
+	given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+func writeJSON(rw http.ResponseWriter, status int, v any) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	_ = json.NewEncoder(rw).Encode(v)
+}
@@ -0,0 +1,117 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeySize is the size of an AES-256 key in bytes
+const KeySize = 32
+
+// magic prefixes sealed data so encrypted files are recognized on read
+var magic = []byte("NMCPENC1")
+
+// ErrNoKey is returned when encrypted data is read without a key
+var ErrNoKey = errors.New("data is encrypted and no encryption key is configured")
+
+// Key seals and opens data with AES-256-GCM
+type Key struct {
+	aead cipher.AEAD
+}
+
+// ParseKey decodes a 32-byte key given as base64 or hex
+func ParseKey(encoded string) (*Key, error) {
+	encoded = strings.TrimSpace(encoded)
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(raw) != KeySize {
+		if raw, err = hex.DecodeString(encoded); err != nil || len(raw) != KeySize {
+			return nil, fmt.Errorf("encryption key must be %d bytes, base64 or hex encoded", KeySize)
+		}
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{aead: aead}, nil
+}
+
+// Load reads the key from the environment variable env, or else from the
+// file at path, such as a secret written by a KMS agent
+func Load(env, path string) (*Key, error) {
+	if env != "" {
+		if value := os.Getenv(env); value != "" {
+			return ParseKey(value)
+		}
+	}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read encryption key: %w", err)
+		}
+		return ParseKey(string(data))
+	}
+	return nil, fmt.Errorf("no encryption key set in the environment or a key file")
+}
+
+// Sealed reports whether data was produced by Seal
+func Sealed(data []byte) bool {
+	return bytes.HasPrefix(data, magic)
+}
+
+// Seal encrypts plaintext under a random nonce
+func (k *Key) Seal(plaintext []byte) []byte {
+	nonce := make([]byte, k.aead.NonceSize())
+	_, _ = rand.Read(nonce)
+	sealed := append(append([]byte(nil), magic...), nonce...)
+	return k.aead.Seal(sealed, nonce, plaintext, magic)
+}
+
+// Open decrypts data produced by Seal. A nil key fails with ErrNoKey.
+func (k *Key) Open(data []byte) ([]byte, error) {
+	if !Sealed(data) {
+		return nil, fmt.Errorf("data is not encrypted")
+	}
+	if k == nil {
+		return nil, ErrNoKey
+	}
+	data = data[len(magic):]
+	if len(data) < k.aead.NonceSize() {
+		return nil, fmt.Errorf("encrypted data is truncated")
+	}
+	plaintext, err := k.aead.Open(nil, data[:k.aead.NonceSize()], data[k.aead.NonceSize():], magic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data, wrong key or corrupted: %w", err)
+	}
+	return plaintext, nil
+}
+
+// SealLine encrypts one line of a line-oriented file, such as a JSON lines
+// record, into a base64 line
+func (k *Key) SealLine(line []byte) []byte {
+	sealed := k.Seal(line)
+	encoded := make([]byte, base64.StdEncoding.EncodedLen(len(sealed)))
+	base64.StdEncoding.Encode(encoded, sealed)
+	return encoded
+}
+
+// OpenLine decrypts a line written by SealLine
+func (k *Key) OpenLine(line []byte) ([]byte, error) {
+	sealed := make([]byte, base64.StdEncoding.DecodedLen(len(line)))
+	n, err := base64.StdEncoding.Decode(sealed, line)
+	if err != nil {
+		return nil, fmt.Errorf("data is not encrypted")
+	}
+	return k.Open(sealed[:n])
+}
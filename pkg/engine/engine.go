@@ -0,0 +1,222 @@
+package engine
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"nuclei-mcp/pkg/scanner"
+
+	nucleiconfig "github.com/projectdiscovery/nuclei/v3/pkg/catalog/config"
+)
+
+// DefaultReleaseURL is the download location of tagged nuclei-templates releases
+const DefaultReleaseURL = "https://github.com/projectdiscovery/nuclei-templates/archive/refs/tags/%s.tar.gz"
+
+var versionPattern = regexp.MustCompile(`^v\d+\.\d+\.\d+$`)
+
+// Info describes the embedded nuclei engine and the installed templates
+type Info struct {
+	EngineVersion      string `json:"engine_version"`
+	TemplatesVersion   string `json:"templates_version"`
+	TemplatesDirectory string `json:"templates_directory"`
+	PinnedVersion      string `json:"pinned_templates_version,omitempty"`
+	PinSatisfied       bool   `json:"pin_satisfied"`
+}
+
+// Updater reports engine information and installs pinned template releases
+type Updater struct {
+	TemplatesDir  string
+	PinnedVersion string
+	ReleaseURL    string
+	// ReleasesURL is the release API queried by CheckUpdates
+	ReleasesURL string
+	client      *http.Client
+	checkClient *http.Client
+}
+
+// NewUpdater creates a new updater. An empty templatesDir uses the nuclei
+// default templates directory.
+func NewUpdater(templatesDir string, pinnedVersion string) *Updater {
+	if templatesDir == "" {
+		templatesDir = nucleiconfig.DefaultConfig.TemplatesDirectory
+	}
+	return &Updater{
+		TemplatesDir:  templatesDir,
+		PinnedVersion: pinnedVersion,
+		ReleaseURL:    DefaultReleaseURL,
+		ReleasesURL:   DefaultReleasesURL,
+		client:        &http.Client{Timeout: 10 * time.Minute},
+		checkClient:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Info returns the engine version and installed templates version
+func (u *Updater) Info() Info {
+	installed := nucleiconfig.DefaultConfig.TemplateVersion
+	return Info{
+		EngineVersion:      nucleiconfig.Version,
+		TemplatesVersion:   installed,
+		TemplatesDirectory: u.TemplatesDir,
+		PinnedVersion:      u.PinnedVersion,
+		PinSatisfied:       u.PinnedVersion == "" || u.PinnedVersion == installed,
+	}
+}
+
+// Update downloads the given nuclei-templates release and replaces the
+// templates directory with it. An empty version installs the pinned version.
+// Requests for a version other than the pinned one are rejected.
+func (u *Updater) Update(ctx context.Context, version string) (string, error) {
+	if version == "" {
+		version = u.PinnedVersion
+	}
+	if version == "" {
+		return "", fmt.Errorf("no templates version given and none pinned in config (nuclei.templates_version)")
+	}
+	if u.PinnedVersion != "" && version != u.PinnedVersion {
+		return "", fmt.Errorf("templates are pinned to %s, refusing to install %s", u.PinnedVersion, version)
+	}
+	if !versionPattern.MatchString(version) {
+		return "", fmt.Errorf("invalid templates version %q, expected a release tag like v10.1.0", version)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf(u.ReleaseURL, version), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create download request: %w", err)
+	}
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to download templates %s: %w", version, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download templates %s: unexpected status %s", version, resp.Status)
+	}
+
+	// Extract next to the templates directory and swap it in at once, so
+	// scans never load a half-written tree and templates removed upstream
+	// do not linger
+	parent := filepath.Dir(u.TemplatesDir)
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return "", fmt.Errorf("failed to create templates directory: %w", err)
+	}
+	staging, err := os.MkdirTemp(parent, "."+filepath.Base(u.TemplatesDir)+"-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+	if err := os.Chmod(staging, 0755); err != nil {
+		return "", fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	if err := extractRelease(resp.Body, staging); err != nil {
+		return "", err
+	}
+	if err := replaceDir(staging, u.TemplatesDir); err != nil {
+		return "", err
+	}
+
+	if u.TemplatesDir == nucleiconfig.DefaultConfig.TemplatesDirectory {
+		if err := nucleiconfig.DefaultConfig.SetTemplatesVersion(version); err != nil {
+			return "", fmt.Errorf("failed to record templates version: %w", err)
+		}
+	}
+
+	return version, nil
+}
+
+// replaceDir moves staging to dir while no scan engine runs, and removes the
+// previous contents of dir
+func replaceDir(staging, dir string) error {
+	previous := staging + ".previous"
+	err := func() error {
+		unlock := scanner.LockEngines()
+		defer unlock()
+		if err := os.Rename(dir, previous); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to replace templates directory: %w", err)
+		}
+		if err := os.Rename(staging, dir); err != nil {
+			_ = os.Rename(previous, dir)
+			return fmt.Errorf("failed to replace templates directory: %w", err)
+		}
+		return nil
+	}()
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(previous); err != nil {
+		return fmt.Errorf("failed to remove previous templates: %w", err)
+	}
+	return nil
+}
+
+// extractRelease unpacks a GitHub source tarball into dir, stripping the
+// top-level directory of the archive
+func extractRelease(r io.Reader, dir string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("invalid templates archive: %w", err)
+	}
+	defer gz.Close()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create templates directory: %w", err)
+	}
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read templates archive: %w", err)
+		}
+
+		parts := strings.SplitN(filepath.ToSlash(header.Name), "/", 2)
+		if len(parts) < 2 || parts[1] == "" {
+			continue
+		}
+		target := filepath.Join(dir, filepath.FromSlash(parts[1]))
+		if !strings.HasPrefix(target, filepath.Clean(dir)+string(os.PathSeparator)) {
+			return fmt.Errorf("invalid path in templates archive: %s", header.Name)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return fmt.Errorf("failed to create %s: %w", target, err)
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return fmt.Errorf("failed to create %s: %w", filepath.Dir(target), err)
+			}
+			if err := writeFile(target, tr); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func writeFile(path string, r io.Reader) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, r); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
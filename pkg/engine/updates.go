@@ -0,0 +1,170 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	nucleiconfig "github.com/projectdiscovery/nuclei/v3/pkg/catalog/config"
+	"golang.org/x/mod/semver"
+)
+
+// DefaultReleasesURL is the GitHub API location of the latest release of a
+// repository
+const DefaultReleasesURL = "https://api.github.com/repos/%s/releases/latest"
+
+const (
+	// TemplatesRepository publishes the nuclei-templates releases
+	TemplatesRepository = "projectdiscovery/nuclei-templates"
+	// EngineRepository publishes the nuclei releases the SDK is taken from
+	EngineRepository = "projectdiscovery/nuclei"
+)
+
+// maxReleaseNotes is the number of release note lines kept in a summary
+const maxReleaseNotes = 10
+
+// maxReleaseNoteLength truncates long release note lines
+const maxReleaseNoteLength = 200
+
+// ComponentUpdate compares an installed component with its latest release
+type ComponentUpdate struct {
+	Installed       string    `json:"installed"`
+	Latest          string    `json:"latest,omitempty"`
+	UpdateAvailable bool      `json:"update_available"`
+	ReleaseURL      string    `json:"release_url,omitempty"`
+	PublishedAt     time.Time `json:"published_at,omitempty"`
+	ReleaseNotes    []string  `json:"release_notes,omitempty"`
+	// Hint says how to install the update
+	Hint  string `json:"hint,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// UpdateCheck reports whether newer templates or a newer engine are released
+type UpdateCheck struct {
+	Templates ComponentUpdate `json:"templates"`
+	Engine    ComponentUpdate `json:"engine"`
+}
+
+type githubRelease struct {
+	TagName     string    `json:"tag_name"`
+	HTMLURL     string    `json:"html_url"`
+	Body        string    `json:"body"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// CheckUpdates asks GitHub for the latest nuclei-templates and nuclei
+// releases and compares them with the installed templates and the embedded
+// engine. It is the only network call of the updater besides Update, and
+// fails only when neither release can be fetched.
+func (u *Updater) CheckUpdates(ctx context.Context) (UpdateCheck, error) {
+	info := u.Info()
+	check := UpdateCheck{
+		Templates: u.checkComponent(ctx, TemplatesRepository, info.TemplatesVersion),
+		Engine:    u.checkComponent(ctx, EngineRepository, info.EngineVersion),
+	}
+	if check.Templates.Error != "" && check.Engine.Error != "" {
+		return check, fmt.Errorf("failed to check for updates: %s", check.Templates.Error)
+	}
+
+	if check.Templates.UpdateAvailable {
+		if u.PinnedVersion != "" {
+			check.Templates.Hint = fmt.Sprintf("Templates are pinned to %s; set nuclei.templates_version to %s to install it with engine_update", u.PinnedVersion, check.Templates.Latest)
+		} else {
+			check.Templates.Hint = fmt.Sprintf("Install it with engine_update (version %s)", check.Templates.Latest)
+		}
+	}
+	if check.Engine.UpdateAvailable {
+		check.Engine.Hint = fmt.Sprintf("The engine is built into the server; rebuild nuclei-mcp with github.com/projectdiscovery/nuclei/v3@%s", check.Engine.Latest)
+	}
+	return check, nil
+}
+
+// checkComponent compares installed with the latest release of repository
+func (u *Updater) checkComponent(ctx context.Context, repository string, installed string) ComponentUpdate {
+	update := ComponentUpdate{Installed: installed}
+	release, err := u.latestRelease(ctx, repository)
+	if err != nil {
+		update.Error = err.Error()
+		return update
+	}
+	update.Latest = release.TagName
+	update.ReleaseURL = release.HTMLURL
+	update.PublishedAt = release.PublishedAt
+	update.UpdateAvailable = newerVersion(release.TagName, installed)
+	if update.UpdateAvailable {
+		update.ReleaseNotes = summarizeReleaseNotes(release.Body)
+	}
+	return update
+}
+
+// latestRelease fetches the latest release of repository
+func (u *Updater) latestRelease(ctx context.Context, repository string) (githubRelease, error) {
+	var release githubRelease
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf(u.ReleasesURL, repository), nil)
+	if err != nil {
+		return release, fmt.Errorf("failed to create release request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", "nuclei-mcp/"+nucleiconfig.Version)
+
+	resp, err := u.checkClient.Do(req)
+	if err != nil {
+		return release, fmt.Errorf("failed to fetch the latest %s release: %w", repository, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return release, fmt.Errorf("failed to fetch the latest %s release: unexpected status %s", repository, resp.Status)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&release); err != nil {
+		return release, fmt.Errorf("failed to decode the latest %s release: %w", repository, err)
+	}
+	if !semver.IsValid(release.TagName) {
+		return release, fmt.Errorf("unexpected %s release tag %q", repository, release.TagName)
+	}
+	return release, nil
+}
+
+// newerVersion reports whether latest is newer than installed. Anything is
+// newer than a missing or unparseable installed version.
+func newerVersion(latest string, installed string) bool {
+	if !semver.IsValid(installed) {
+		return true
+	}
+	return semver.Compare(latest, installed) > 0
+}
+
+// summarizeReleaseNotes keeps the headings and list items of a release's
+// Markdown notes, without their markup
+func summarizeReleaseNotes(body string) []string {
+	var notes []string
+	skipped := 0
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		heading := strings.HasPrefix(line, "#")
+		item := strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ")
+		if !heading && !item {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimLeft(line, "#-* "))
+		line = strings.NewReplacer("**", "", "`", "").Replace(line)
+		if line == "" {
+			continue
+		}
+		if len(notes) == maxReleaseNotes {
+			skipped++
+			continue
+		}
+		if runes := []rune(line); len(runes) > maxReleaseNoteLength {
+			line = string(runes[:maxReleaseNoteLength]) + "..."
+		}
+		notes = append(notes, line)
+	}
+	if skipped > 0 {
+		notes = append(notes, fmt.Sprintf("(%d more lines in the release notes)", skipped))
+	}
+	return notes
+}
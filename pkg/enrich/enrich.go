@@ -0,0 +1,117 @@
+package enrich
+
+import (
+	"context"
+	"net"
+
+	"nuclei-mcp/pkg/cache"
+)
+
+// Resolver resolves host names to IP addresses
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// Enricher describes where scanned hosts are hosted: their IP addresses
+// and, from local MaxMind databases, the autonomous system and location of
+// each address
+type Enricher struct {
+	resolver Resolver
+	asn      *DB
+	geo      *DB
+}
+
+// Option configures an Enricher
+type Option func(*Enricher)
+
+// WithResolver replaces the system resolver
+func WithResolver(resolver Resolver) Option {
+	return func(e *Enricher) {
+		e.resolver = resolver
+	}
+}
+
+// WithASNDatabase looks up the autonomous system of addresses in db, such
+// as GeoLite2-ASN
+func WithASNDatabase(db *DB) Option {
+	return func(e *Enricher) {
+		e.asn = db
+	}
+}
+
+// WithGeoDatabase looks up the country and city of addresses in db, such
+// as GeoLite2-City or GeoLite2-Country
+func WithGeoDatabase(db *DB) Option {
+	return func(e *Enricher) {
+		e.geo = db
+	}
+}
+
+// New creates an enricher
+func New(opts ...Option) *Enricher {
+	e := &Enricher{resolver: net.DefaultResolver}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Enrich resolves host and describes each of its addresses. Lookup
+// failures are recorded in the result rather than returned, so enrichment
+// never fails a scan.
+func (e *Enricher) Enrich(ctx context.Context, host string) cache.HostInfo {
+	info := cache.HostInfo{Host: host}
+
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		addrs, err := e.resolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			info.Error = err.Error()
+			return info
+		}
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+	}
+
+	for _, ip := range ips {
+		info.Addresses = append(info.Addresses, e.describe(ip))
+	}
+	return info
+}
+
+func (e *Enricher) describe(ip net.IP) cache.AddressInfo {
+	address := cache.AddressInfo{
+		IP:      ip.String(),
+		Private: ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast(),
+	}
+	if e.asn != nil {
+		if record, err := e.asn.Lookup(ip); err == nil && record != nil {
+			address.ASN = uintValue(record["autonomous_system_number"])
+			address.ASOrg, _ = record["autonomous_system_organization"].(string)
+		}
+	}
+	if e.geo != nil {
+		if record, err := e.geo.Lookup(ip); err == nil && record != nil {
+			address.CountryCode, _ = lookup(record, "country", "iso_code").(string)
+			address.Country, _ = lookup(record, "country", "names", "en").(string)
+			address.City, _ = lookup(record, "city", "names", "en").(string)
+		}
+	}
+	return address
+}
+
+// lookup follows path through nested maps
+func lookup(record map[string]any, path ...string) any {
+	var value any = record
+	for _, key := range path {
+		m, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = m[key]
+	}
+	return value
+}
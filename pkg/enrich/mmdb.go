@@ -0,0 +1,319 @@
+package enrich
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net"
+	"os"
+)
+
+// metadataMarker precedes the metadata section at the end of a MaxMind DB
+var metadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// dataSectionSeparator is the size of the zero bytes between the search
+// tree and the data section
+const dataSectionSeparator = 16
+
+// DB is a MaxMind DB (MMDB) file, such as GeoLite2-ASN or GeoLite2-City,
+// read into memory. Only lookups are supported.
+type DB struct {
+	buf        []byte
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	dataStart  uint
+	ipv4Start  uint
+}
+
+// OpenDB reads the MaxMind DB at path
+func OpenDB(path string) (*DB, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	db, err := newDB(buf)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MaxMind DB %s: %w", path, err)
+	}
+	return db, nil
+}
+
+func newDB(buf []byte) (*DB, error) {
+	marker := bytes.LastIndex(buf, metadataMarker)
+	if marker < 0 {
+		return nil, fmt.Errorf("metadata not found")
+	}
+	metaStart := uint(marker + len(metadataMarker))
+	raw, _, err := (&decoder{buf: buf[metaStart:]}).decode(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode metadata: %w", err)
+	}
+	metadata, ok := raw.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("metadata is not a map")
+	}
+
+	db := &DB{
+		buf:        buf,
+		nodeCount:  uintValue(metadata["node_count"]),
+		recordSize: uintValue(metadata["record_size"]),
+		ipVersion:  uintValue(metadata["ip_version"]),
+	}
+	switch db.recordSize {
+	case 24, 28, 32:
+	default:
+		return nil, fmt.Errorf("unsupported record size %d", db.recordSize)
+	}
+	treeSize := db.nodeCount * db.recordSize / 4
+	db.dataStart = treeSize + dataSectionSeparator
+	if db.dataStart > metaStart {
+		return nil, fmt.Errorf("search tree exceeds file size")
+	}
+
+	// IPv4 addresses are looked up under ::/96 in IPv6 databases
+	if db.ipVersion == 6 {
+		node := uint(0)
+		for i := 0; i < 96 && node < db.nodeCount; i++ {
+			node = db.record(node, 0)
+		}
+		db.ipv4Start = node
+	}
+	return db, nil
+}
+
+// Lookup returns the record of the network holding ip, or nil when the
+// database has none
+func (db *DB) Lookup(ip net.IP) (map[string]any, error) {
+	bits := ip.To16()
+	node := uint(0)
+	bitCount := 128
+	if ip4 := ip.To4(); ip4 != nil {
+		bits, bitCount = ip4, 32
+		if db.ipVersion == 6 {
+			node = db.ipv4Start
+		}
+	} else if db.ipVersion == 4 {
+		return nil, nil
+	}
+	if bits == nil {
+		return nil, fmt.Errorf("invalid IP address %v", ip)
+	}
+
+	for i := 0; i < bitCount && node < db.nodeCount; i++ {
+		bit := (bits[i/8] >> (7 - uint(i%8))) & 1
+		node = db.record(node, uint(bit))
+	}
+	if node <= db.nodeCount {
+		return nil, nil
+	}
+
+	offset := node - db.nodeCount - dataSectionSeparator
+	raw, _, err := (&decoder{buf: db.buf[db.dataStart:]}).decode(offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode record of %v: %w", ip, err)
+	}
+	record, _ := raw.(map[string]any)
+	return record, nil
+}
+
+// record returns the left (bit 0) or right (bit 1) record of node
+func (db *DB) record(node uint, bit uint) uint {
+	switch db.recordSize {
+	case 24:
+		b := db.buf[node*6+bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		b := db.buf[node*7:]
+		if bit == 0 {
+			return uint(b[3]&0xF0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0F)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		return uint(binary.BigEndian.Uint32(db.buf[node*8+bit*4:]))
+	}
+}
+
+// MaxMind DB data types
+const (
+	typeExtended = iota
+	typePointer
+	typeString
+	typeDouble
+	typeBytes
+	typeUint16
+	typeUint32
+	typeMap
+	typeInt32
+	typeUint64
+	typeUint128
+	typeArray
+	typeContainer
+	typeEndMarker
+	typeBool
+	typeFloat
+)
+
+// decoder reads values from a MaxMind DB data section
+type decoder struct {
+	buf []byte
+}
+
+// decode returns the value at offset and the offset following it
+func (d *decoder) decode(offset uint) (any, uint, error) {
+	typeNum, size, offset, err := d.control(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	if typeNum == typePointer {
+		pointer, next, err := d.pointer(size, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		value, _, err := d.decode(pointer)
+		return value, next, err
+	}
+	return d.value(typeNum, size, offset)
+}
+
+// control reads a control byte, returning the type and size of the value
+// and the offset of its payload
+func (d *decoder) control(offset uint) (typeNum int, size uint, next uint, err error) {
+	if offset >= uint(len(d.buf)) {
+		return 0, 0, 0, fmt.Errorf("offset %d beyond data section", offset)
+	}
+	ctrl := d.buf[offset]
+	offset++
+	typeNum = int(ctrl >> 5)
+	if typeNum == typeExtended {
+		if offset >= uint(len(d.buf)) {
+			return 0, 0, 0, fmt.Errorf("truncated extended type")
+		}
+		typeNum = 7 + int(d.buf[offset])
+		offset++
+	}
+	if typeNum == typePointer {
+		return typeNum, uint(ctrl), offset, nil
+	}
+
+	size = uint(ctrl & 0x1F)
+	if size >= 29 {
+		extra := size - 28
+		if offset+extra > uint(len(d.buf)) {
+			return 0, 0, 0, fmt.Errorf("truncated size")
+		}
+		n := uintFrom(d.buf[offset : offset+extra])
+		offset += extra
+		switch extra {
+		case 1:
+			size = 29 + n
+		case 2:
+			size = 285 + n
+		default:
+			size = 65821 + n
+		}
+	}
+	return typeNum, size, offset, nil
+}
+
+// pointer resolves a pointer whose control byte is ctrl
+func (d *decoder) pointer(ctrl uint, offset uint) (uint, uint, error) {
+	size := (ctrl>>3)&0x3 + 1
+	if offset+size > uint(len(d.buf)) {
+		return 0, 0, fmt.Errorf("truncated pointer")
+	}
+	b := d.buf[offset : offset+size]
+	var pointer uint
+	switch size {
+	case 1:
+		pointer = (ctrl&0x7)<<8 | uint(b[0])
+	case 2:
+		pointer = ((ctrl&0x7)<<16 | uintFrom(b)) + 2048
+	case 3:
+		pointer = ((ctrl&0x7)<<24 | uintFrom(b)) + 526336
+	default:
+		pointer = uintFrom(b)
+	}
+	return pointer, offset + size, nil
+}
+
+func (d *decoder) value(typeNum int, size uint, offset uint) (any, uint, error) {
+	switch typeNum {
+	case typeMap:
+		m := make(map[string]any, size)
+		for i := uint(0); i < size; i++ {
+			key, next, err := d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				return nil, 0, fmt.Errorf("map key is not a string")
+			}
+			value, next, err := d.decode(next)
+			if err != nil {
+				return nil, 0, err
+			}
+			m[name] = value
+			offset = next
+		}
+		return m, offset, nil
+	case typeArray:
+		values := make([]any, 0, size)
+		for i := uint(0); i < size; i++ {
+			value, next, err := d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			values = append(values, value)
+			offset = next
+		}
+		return values, offset, nil
+	case typeBool:
+		return size != 0, offset, nil
+	}
+
+	if offset+size > uint(len(d.buf)) {
+		return nil, 0, fmt.Errorf("value beyond data section")
+	}
+	payload := d.buf[offset : offset+size]
+	next := offset + size
+	switch typeNum {
+	case typeString:
+		return string(payload), next, nil
+	case typeBytes:
+		return append([]byte(nil), payload...), next, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, fmt.Errorf("invalid double size %d", size)
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(payload)), next, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, fmt.Errorf("invalid float size %d", size)
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(payload))), next, nil
+	case typeUint16, typeUint32, typeUint64:
+		return uint64(uintFrom(payload)), next, nil
+	case typeInt32:
+		return int64(int32(uintFrom(payload))), next, nil
+	case typeUint128:
+		// Not used by the GeoIP and ASN databases; kept as raw bytes
+		return append([]byte(nil), payload...), next, nil
+	}
+	return nil, 0, fmt.Errorf("unsupported data type %d", typeNum)
+}
+
+func uintFrom(b []byte) uint {
+	var n uint
+	for _, c := range b {
+		n = n<<8 | uint(c)
+	}
+	return n
+}
+
+func uintValue(value any) uint {
+	n, _ := value.(uint64)
+	return uint(n)
+}
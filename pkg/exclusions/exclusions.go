@@ -0,0 +1,222 @@
+package exclusions
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rule stops the listed templates from running against matching targets
+type Rule struct {
+	ID string `json:"id"`
+	// Target is a glob pattern where * matches any characters. A pattern
+	// with a scheme (https://app.example.com/*) is matched against the whole
+	// target; otherwise against the target's host (*.example.com).
+	Target      string    `json:"target"`
+	TemplateIDs []string  `json:"template_ids,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	Reason      string    `json:"reason,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	// ExpiresAt ends the rule; expired rules are kept but no longer match
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Expired reports whether the rule has expired at now
+func (r Rule) Expired(now time.Time) bool {
+	return r.ExpiresAt != nil && !now.Before(*r.ExpiresAt)
+}
+
+// Store keeps exclusion rules in a JSON file
+type Store struct {
+	path string
+
+	mu       sync.RWMutex
+	rules    []Rule
+	patterns map[string]*regexp.Regexp
+}
+
+// Open loads the rules stored at path. A missing file holds no rules.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, patterns: map[string]*regexp.Regexp{}}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read exclusions: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.rules); err != nil {
+		return nil, fmt.Errorf("failed to parse exclusions %s: %w", path, err)
+	}
+	for _, rule := range s.rules {
+		s.patterns[rule.Target] = compilePattern(rule.Target)
+	}
+	return s, nil
+}
+
+// Add validates and stores a rule, assigning its ID and creation time
+func (s *Store) Add(rule Rule) (Rule, error) {
+	rule, err := validate(rule)
+	if err != nil {
+		return Rule{}, err
+	}
+	if rule.Expired(time.Now()) {
+		return Rule{}, fmt.Errorf("exclusion expiry %s is in the past", rule.ExpiresAt.Format(time.RFC3339))
+	}
+	rule.ID = newID()
+	rule.CreatedAt = time.Now().UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rules := append(append([]Rule(nil), s.rules...), rule)
+	if err := s.save(rules); err != nil {
+		return Rule{}, err
+	}
+	s.rules = rules
+	s.patterns[rule.Target] = compilePattern(rule.Target)
+	return rule, nil
+}
+
+// Remove deletes the rule with the given ID
+func (s *Store) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rules := make([]Rule, 0, len(s.rules))
+	for _, rule := range s.rules {
+		if rule.ID != id {
+			rules = append(rules, rule)
+		}
+	}
+	if len(rules) == len(s.rules) {
+		return fmt.Errorf("exclusion not found: %s", id)
+	}
+	if err := s.save(rules); err != nil {
+		return err
+	}
+	s.rules = rules
+	return nil
+}
+
+// List returns the stored rules in creation order
+func (s *Store) List() []Rule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Rule(nil), s.rules...)
+}
+
+// Match returns the template IDs and tags excluded for target by the rules
+// that have not expired, sorted and without duplicates
+func (s *Store) Match(target string) (templateIDs []string, tags []string) {
+	if s == nil {
+		return nil, nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	for _, rule := range s.rules {
+		if !rule.Expired(now) && matches(s.patterns[rule.Target], rule.Target, target) {
+			templateIDs = append(templateIDs, rule.TemplateIDs...)
+			tags = append(tags, rule.Tags...)
+		}
+	}
+	return normalize(templateIDs, false), normalize(tags, true)
+}
+
+// validate normalizes the patterns of a rule and checks it excludes
+// something
+func validate(rule Rule) (Rule, error) {
+	rule.Target = strings.TrimSpace(rule.Target)
+	if rule.Target == "" {
+		return Rule{}, fmt.Errorf("exclusion target pattern is required")
+	}
+	rule.TemplateIDs = normalize(rule.TemplateIDs, false)
+	rule.Tags = normalize(rule.Tags, true)
+	if len(rule.TemplateIDs) == 0 && len(rule.Tags) == 0 {
+		return Rule{}, fmt.Errorf("exclusion needs at least one template ID or tag")
+	}
+	if rule.ExpiresAt != nil {
+		expires := rule.ExpiresAt.UTC()
+		rule.ExpiresAt = &expires
+	}
+	return rule, nil
+}
+
+func (s *Store) save(rules []Rule) error {
+	data, err := json.MarshalIndent(rules, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode exclusions: %w", err)
+	}
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create exclusions directory: %w", err)
+		}
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write exclusions: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to write exclusions: %w", err)
+	}
+	return nil
+}
+
+// compilePattern turns a glob pattern into a case-insensitive regexp
+func compilePattern(pattern string) *regexp.Regexp {
+	quoted := strings.ReplaceAll(regexp.QuoteMeta(strings.ToLower(pattern)), `\*`, ".*")
+	return regexp.MustCompile("^" + quoted + "$")
+}
+
+func matches(pattern *regexp.Regexp, raw string, target string) bool {
+	if pattern == nil {
+		return false
+	}
+	target = strings.ToLower(strings.TrimSpace(target))
+	if strings.Contains(raw, "://") {
+		return pattern.MatchString(target) || pattern.MatchString(strings.TrimSuffix(target, "/"))
+	}
+	host := target
+	if !strings.Contains(host, "://") {
+		host = "//" + host
+	}
+	if u, err := url.Parse(host); err == nil && u.Hostname() != "" {
+		return pattern.MatchString(u.Hostname())
+	}
+	return pattern.MatchString(target)
+}
+
+func normalize(values []string, lower bool) []string {
+	seen := map[string]bool{}
+	var normalized []string
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if lower {
+			value = strings.ToLower(value)
+		}
+		if value != "" && !seen[value] {
+			seen[value] = true
+			normalized = append(normalized, value)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+func newID() string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,170 @@
+package exclusions
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileHeader starts exported rule files
+const fileHeader = `# nuclei-mcp exclusion rules. Each rule stops its template_ids and tags
+# from running against targets matching its target pattern until it
+# expires. Import with import_exclusions or "nuclei-mcp exclusions import".
+`
+
+// File is the YAML document exclusion rules are exported to and imported
+// from, reviewable and kept under version control
+type File struct {
+	Exclusions []FileRule `yaml:"exclusions"`
+}
+
+// FileRule is a rule in an exclusions file. Rules are identified by their
+// target and what they exclude rather than by ID, so files stay readable.
+type FileRule struct {
+	Target      string     `yaml:"target"`
+	TemplateIDs []string   `yaml:"template_ids,omitempty,flow"`
+	Tags        []string   `yaml:"tags,omitempty,flow"`
+	Reason      string     `yaml:"reason,omitempty"`
+	ExpiresAt   *time.Time `yaml:"expires_at,omitempty"`
+}
+
+// ImportSummary counts the rules an import added, updated and removed
+type ImportSummary struct {
+	Added   int `json:"added"`
+	Updated int `json:"updated"`
+	// Removed are the rules missing from the file, when replacing
+	Removed int `json:"removed"`
+}
+
+// Export encodes the stored rules, expired ones included, as a YAML
+// exclusions file
+func (s *Store) Export() ([]byte, error) {
+	file := File{Exclusions: []FileRule{}}
+	for _, rule := range s.List() {
+		file.Exclusions = append(file.Exclusions, FileRule{
+			Target:      rule.Target,
+			TemplateIDs: rule.TemplateIDs,
+			Tags:        rule.Tags,
+			Reason:      rule.Reason,
+			ExpiresAt:   rule.ExpiresAt,
+		})
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(fileHeader)
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(file); err != nil {
+		return nil, fmt.Errorf("failed to encode exclusions: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode exclusions: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Import stores the rules of a YAML exclusions file. A rule with the same
+// target, template IDs and tags as a stored one updates its reason and
+// expiry; others are added. With replace, stored rules missing from the
+// file are removed. The file is validated as a whole before any rule is
+// stored.
+func (s *Store) Import(data []byte, replace bool) (ImportSummary, error) {
+	var file File
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return ImportSummary{}, fmt.Errorf("failed to parse exclusions file: %w", err)
+	}
+
+	imported := make([]Rule, 0, len(file.Exclusions))
+	seen := map[string]bool{}
+	for i, entry := range file.Exclusions {
+		rule, err := validate(Rule{
+			Target:      entry.Target,
+			TemplateIDs: entry.TemplateIDs,
+			Tags:        entry.Tags,
+			Reason:      strings.TrimSpace(entry.Reason),
+			ExpiresAt:   entry.ExpiresAt,
+		})
+		if err != nil {
+			return ImportSummary{}, fmt.Errorf("invalid exclusion %d: %w", i+1, err)
+		}
+		if key := ruleKey(rule); !seen[key] {
+			seen[key] = true
+			imported = append(imported, rule)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var summary ImportSummary
+	stored := map[string]int{}
+	rules := make([]Rule, 0, len(s.rules)+len(imported))
+	for _, rule := range s.rules {
+		if replace && !seen[ruleKey(rule)] {
+			summary.Removed++
+			continue
+		}
+		stored[ruleKey(rule)] = len(rules)
+		rules = append(rules, rule)
+	}
+	now := time.Now().UTC()
+	for _, rule := range imported {
+		if i, ok := stored[ruleKey(rule)]; ok {
+			if rules[i].Reason != rule.Reason || !sameExpiry(rules[i].ExpiresAt, rule.ExpiresAt) {
+				rules[i].Reason, rules[i].ExpiresAt = rule.Reason, rule.ExpiresAt
+				summary.Updated++
+			}
+			continue
+		}
+		rule.ID, rule.CreatedAt = newID(), now
+		rules = append(rules, rule)
+		summary.Added++
+	}
+
+	if err := s.save(rules); err != nil {
+		return ImportSummary{}, err
+	}
+	s.rules = rules
+	for _, rule := range rules {
+		if _, ok := s.patterns[rule.Target]; !ok {
+			s.patterns[rule.Target] = compilePattern(rule.Target)
+		}
+	}
+	return summary, nil
+}
+
+// ParseExpiry parses when a rule expires: a duration from now such as
+// 720h, a date such as 2025-12-31 (its start, in UTC) or an RFC 3339 time
+func ParseExpiry(raw string, now time.Time) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if duration, err := time.ParseDuration(raw); err == nil {
+		if duration <= 0 {
+			return time.Time{}, fmt.Errorf("invalid expiry %q: must be in the future", raw)
+		}
+		return now.Add(duration).UTC(), nil
+	}
+	if date, err := time.Parse(time.DateOnly, raw); err == nil {
+		return date, nil
+	}
+	if expires, err := time.Parse(time.RFC3339, raw); err == nil {
+		return expires.UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid expiry %q: use a duration such as 720h, a date such as 2025-12-31 or an RFC 3339 time", raw)
+}
+
+// ruleKey identifies a rule by its target and what it excludes
+func ruleKey(rule Rule) string {
+	return strings.ToLower(rule.Target) + "\x00" + strings.Join(rule.TemplateIDs, ",") + "\x00" + strings.Join(rule.Tags, ",")
+}
+
+func sameExpiry(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
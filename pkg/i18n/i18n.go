@@ -0,0 +1,257 @@
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Supported languages
+const (
+	English  = "en"
+	Spanish  = "es"
+	German   = "de"
+	Japanese = "ja"
+)
+
+// Localizer translates report and summary text into one language
+type Localizer struct {
+	language string
+	messages map[string]string
+}
+
+// New creates a localizer for the given language code (e.g. "es" or
+// "de-AT"). An empty language selects English.
+func New(language string) (*Localizer, error) {
+	code := strings.ToLower(strings.TrimSpace(language))
+	if code == "" {
+		code = English
+	}
+	if base, _, ok := strings.Cut(strings.ReplaceAll(code, "_", "-"), "-"); ok {
+		code = base
+	}
+
+	messages, ok := catalogs[code]
+	if !ok {
+		return nil, fmt.Errorf("unsupported report language %q, supported: %s", language, strings.Join(Supported(), ", "))
+	}
+	return &Localizer{language: code, messages: messages}, nil
+}
+
+// Default returns the English localizer
+func Default() *Localizer {
+	return &Localizer{language: English, messages: catalogs[English]}
+}
+
+// Supported returns the supported language codes
+func Supported() []string {
+	codes := make([]string, 0, len(catalogs))
+	for code := range catalogs {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Language returns the language code
+func (l *Localizer) Language() string {
+	return l.language
+}
+
+// LanguageName returns the English name of the language, for instructing a
+// model which language to answer in
+func (l *Localizer) LanguageName() string {
+	return catalogs[English]["language."+l.language]
+}
+
+// T returns the translation of key formatted with args, falling back to
+// English and then to the key itself
+func (l *Localizer) T(key string, args ...any) string {
+	message, ok := l.messages[key]
+	if !ok {
+		if message, ok = catalogs[English][key]; !ok {
+			message = key
+		}
+	}
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+// Severity returns the localized label of a nuclei severity name
+func (l *Localizer) Severity(severity string) string {
+	key := "severity." + strings.ToLower(strings.TrimSpace(severity))
+	if _, ok := catalogs[English][key]; !ok {
+		key = "severity.unknown"
+	}
+	return l.T(key)
+}
+
+var catalogs = map[string]map[string]string{
+	English: {
+		"language.en": "English",
+		"language.es": "Spanish",
+		"language.de": "German",
+		"language.ja": "Japanese",
+
+		"severity.critical": "Critical",
+		"severity.high":     "High",
+		"severity.medium":   "Medium",
+		"severity.low":      "Low",
+		"severity.info":     "Info",
+		"severity.unknown":  "Unknown",
+
+		"report.title":        "Vulnerability Scan Report",
+		"report.generated":    "Generated",
+		"report.overview":     "Overview",
+		"report.targets":      "Targets scanned",
+		"report.total":        "Total findings",
+		"report.by_severity":  "Findings by severity",
+		"report.findings":     "Findings",
+		"report.target":       "Target",
+		"report.scan_time":    "Scan time",
+		"report.labels":       "Labels",
+		"report.no_findings":  "No findings.",
+		"report.severity":     "Severity",
+		"report.name":         "Name",
+		"report.template":     "Template",
+		"report.url":          "URL",
+		"report.description":  "Description",
+		"report.extracted":    "Extracted values",
+		"report.reproduce":    "Reproduce",
+		"report.affected":     "Affected endpoints (%d)",
+		"report.compliance":   "Compliance",
+		"report.control":      "Control",
+		"report.unmapped":     "Findings not mapped to a control: %d",
+		"summary.executive":   "Executive summary",
+		"summary.actions":     "Prioritized next actions",
+		"summary.none":        "No findings across %d scanned targets.",
+		"summary.none_action": "No remediation needed; rescan after changes.",
+		"summary.counts":      "%d findings across %d targets (%s).",
+		"summary.urgent":      "Critical or high severity issues need prompt remediation.",
+		"summary.medium":      "No critical or high severity issues; medium severity issues should be scheduled for remediation.",
+		"summary.low":         "Only low severity or informational findings were reported.",
+		"summary.action":      "Address %s on %s",
+	},
+	Spanish: {
+		"severity.critical": "Crítica",
+		"severity.high":     "Alta",
+		"severity.medium":   "Media",
+		"severity.low":      "Baja",
+		"severity.info":     "Informativa",
+		"severity.unknown":  "Desconocida",
+
+		"report.title":        "Informe de análisis de vulnerabilidades",
+		"report.generated":    "Generado",
+		"report.overview":     "Resumen general",
+		"report.targets":      "Objetivos analizados",
+		"report.total":        "Hallazgos totales",
+		"report.by_severity":  "Hallazgos por severidad",
+		"report.findings":     "Hallazgos",
+		"report.target":       "Objetivo",
+		"report.scan_time":    "Fecha del análisis",
+		"report.labels":       "Etiquetas",
+		"report.no_findings":  "Sin hallazgos.",
+		"report.severity":     "Severidad",
+		"report.name":         "Nombre",
+		"report.template":     "Plantilla",
+		"report.url":          "URL",
+		"report.description":  "Descripción",
+		"report.extracted":    "Valores extraídos",
+		"report.reproduce":    "Reproducir",
+		"report.affected":     "Endpoints afectados (%d)",
+		"report.compliance":   "Cumplimiento normativo",
+		"report.control":      "Control",
+		"report.unmapped":     "Hallazgos sin control asociado: %d",
+		"summary.executive":   "Resumen ejecutivo",
+		"summary.actions":     "Próximas acciones priorizadas",
+		"summary.none":        "Sin hallazgos en %d objetivos analizados.",
+		"summary.none_action": "No se requiere remediación; vuelva a analizar tras los cambios.",
+		"summary.counts":      "%d hallazgos en %d objetivos (%s).",
+		"summary.urgent":      "Los problemas de severidad crítica o alta requieren una remediación inmediata.",
+		"summary.medium":      "No hay problemas de severidad crítica o alta; conviene planificar la remediación de los de severidad media.",
+		"summary.low":         "Solo se informaron hallazgos de severidad baja o informativos.",
+		"summary.action":      "Corregir %s en %s",
+	},
+	German: {
+		"severity.critical": "Kritisch",
+		"severity.high":     "Hoch",
+		"severity.medium":   "Mittel",
+		"severity.low":      "Niedrig",
+		"severity.info":     "Information",
+		"severity.unknown":  "Unbekannt",
+
+		"report.title":        "Bericht zum Schwachstellenscan",
+		"report.generated":    "Erstellt",
+		"report.overview":     "Überblick",
+		"report.targets":      "Gescannte Ziele",
+		"report.total":        "Befunde gesamt",
+		"report.by_severity":  "Befunde nach Schweregrad",
+		"report.findings":     "Befunde",
+		"report.target":       "Ziel",
+		"report.scan_time":    "Scanzeitpunkt",
+		"report.labels":       "Labels",
+		"report.no_findings":  "Keine Befunde.",
+		"report.severity":     "Schweregrad",
+		"report.name":         "Name",
+		"report.template":     "Template",
+		"report.url":          "URL",
+		"report.description":  "Beschreibung",
+		"report.extracted":    "Extrahierte Werte",
+		"report.reproduce":    "Reproduzieren",
+		"report.affected":     "Betroffene Endpunkte (%d)",
+		"report.compliance":   "Compliance",
+		"report.control":      "Kontrolle",
+		"report.unmapped":     "Befunde ohne zugeordnete Kontrolle: %d",
+		"summary.executive":   "Management-Zusammenfassung",
+		"summary.actions":     "Priorisierte nächste Schritte",
+		"summary.none":        "Keine Befunde bei %d gescannten Zielen.",
+		"summary.none_action": "Keine Maßnahmen erforderlich; nach Änderungen erneut scannen.",
+		"summary.counts":      "%d Befunde bei %d Zielen (%s).",
+		"summary.urgent":      "Kritische oder hohe Befunde müssen umgehend behoben werden.",
+		"summary.medium":      "Keine kritischen oder hohen Befunde; mittlere Befunde sollten zur Behebung eingeplant werden.",
+		"summary.low":         "Es wurden nur niedrige oder informative Befunde gemeldet.",
+		"summary.action":      "%s auf %s beheben",
+	},
+	Japanese: {
+		"severity.critical": "緊急",
+		"severity.high":     "高",
+		"severity.medium":   "中",
+		"severity.low":      "低",
+		"severity.info":     "情報",
+		"severity.unknown":  "不明",
+
+		"report.title":        "脆弱性スキャンレポート",
+		"report.generated":    "作成日時",
+		"report.overview":     "概要",
+		"report.targets":      "スキャン対象数",
+		"report.total":        "検出件数",
+		"report.by_severity":  "深刻度別の検出件数",
+		"report.findings":     "検出結果",
+		"report.target":       "対象",
+		"report.scan_time":    "スキャン日時",
+		"report.labels":       "ラベル",
+		"report.no_findings":  "検出なし。",
+		"report.severity":     "深刻度",
+		"report.name":         "名称",
+		"report.template":     "テンプレート",
+		"report.url":          "URL",
+		"report.description":  "説明",
+		"report.extracted":    "抽出値",
+		"report.reproduce":    "再現手順",
+		"report.affected":     "影響を受けるエンドポイント (%d)",
+		"report.compliance":   "コンプライアンス",
+		"report.control":      "管理策",
+		"report.unmapped":     "管理策に対応付けられない検出事項: %d",
+		"summary.executive":   "エグゼクティブサマリー",
+		"summary.actions":     "優先対応事項",
+		"summary.none":        "スキャンした%d件の対象で検出はありません。",
+		"summary.none_action": "対応は不要です。変更後に再スキャンしてください。",
+		"summary.counts":      "%[2]d件の対象で%[1]d件を検出しました(%[3]s)。",
+		"summary.urgent":      "深刻度が緊急または高の問題は速やかな対応が必要です。",
+		"summary.medium":      "緊急・高の問題はありません。中の問題は対応を計画してください。",
+		"summary.low":         "深刻度が低または情報レベルの検出のみです。",
+		"summary.action":      "%[2]s の %[1]s に対応する",
+	},
+}
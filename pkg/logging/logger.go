@@ -18,6 +18,12 @@ type ConsoleLogger struct {
 
 // NewConsoleLogger creates a new console logger that writes to both file and stdout
 func NewConsoleLogger(logPath string) (*ConsoleLogger, error) {
+	return NewConsoleLoggerWithOutput(logPath, os.Stdout)
+}
+
+// NewConsoleLoggerWithOutput creates a console logger that writes to both
+// file and console, for commands that keep stdout for their own output
+func NewConsoleLoggerWithOutput(logPath string, console io.Writer) (*ConsoleLogger, error) {
 	// Create log directory if it doesn't exist
 	logDir := filepath.Dir(logPath)
 	if err := os.MkdirAll(logDir, 0755); err != nil {
@@ -30,8 +36,8 @@ func NewConsoleLogger(logPath string) (*ConsoleLogger, error) {
 		return nil, fmt.Errorf("failed to open log file: %v", err)
 	}
 
-	// Create multi-writer to write to both file and stdout
-	multiWriter := io.MultiWriter(file, os.Stdout)
+	// Create multi-writer to write to both file and console
+	multiWriter := io.MultiWriter(file, console)
 	logger := log.New(multiWriter, "", log.LstdFlags)
 
 	return &ConsoleLogger{
@@ -0,0 +1,25 @@
+package logging
+
+import (
+	"io"
+	"os"
+)
+
+// ServerOutput returns where a server mirrors its log file. Over stdio,
+// stdout carries the MCP stream, so logs always go to stderr; over HTTP,
+// console mirrors them to stdout instead.
+func ServerOutput(stdio, console bool) io.Writer {
+	if !stdio && console {
+		return os.Stdout
+	}
+	return os.Stderr
+}
+
+// ReserveStdout hands stdout to the stdio transport: it returns the
+// process's stdout and points os.Stdout at stderr, so stray writes of the
+// server or its libraries cannot corrupt the MCP stream
+func ReserveStdout() *os.File {
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+	return stdout
+}
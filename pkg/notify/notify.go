@@ -0,0 +1,190 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/model/types/severity"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+)
+
+// DefaultSendTimeout bounds how long a sink may take to deliver a message
+const DefaultSendTimeout = 30 * time.Second
+
+// Message is a notification as sent by a sink
+type Message struct {
+	Subject string `json:"subject"`
+	// Text is the plain text body
+	Text string `json:"text"`
+
+	// The alert itself, for sinks that lay it out on their own, such as
+	// chat cards
+	Target   string            `json:"target"`
+	ScanTime time.Time         `json:"scan_time"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	// Findings are the findings alerted on, most severe first
+	Findings []Finding `json:"findings"`
+}
+
+// Finding is a finding in an alert
+type Finding struct {
+	Severity   string `json:"severity"`
+	Name       string `json:"name"`
+	TemplateID string `json:"template_id"`
+	// Matched is where the finding matched, or its host
+	Matched string `json:"matched"`
+	// Resource is the finding:// URI of the full finding
+	Resource string `json:"resource"`
+}
+
+// Sink delivers notifications, such as by email
+type Sink interface {
+	// Name identifies the sink in logs
+	Name() string
+	Send(ctx context.Context, message Message) error
+}
+
+// severityLevels ranks the severities alerts can start at
+var severityLevels = map[string]severity.Severity{
+	"info":     severity.Info,
+	"low":      severity.Low,
+	"medium":   severity.Medium,
+	"high":     severity.High,
+	"critical": severity.Critical,
+}
+
+// ParseSeverity parses the least severe finding alerted on
+func ParseSeverity(name string) (severity.Severity, error) {
+	level, ok := severityLevels[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return severity.Undefined, fmt.Errorf("invalid severity %q, use info, low, medium, high or critical", name)
+	}
+	return level, nil
+}
+
+// Alerter sends an alert to its sinks as soon as a scan reports findings
+// at or above its minimum severity. Alerts are sent in the background, so
+// a slow mail server never holds up a scan.
+type Alerter struct {
+	sinks       []Sink
+	minSeverity severity.Severity
+	console     scanner.LoggerInterface
+	timeout     time.Duration
+	sending     sync.WaitGroup
+}
+
+// NewAlerter creates an alerter sending to sinks the findings at or above
+// minSeverity
+func NewAlerter(sinks []Sink, minSeverity severity.Severity, console scanner.LoggerInterface) *Alerter {
+	return &Alerter{sinks: sinks, minSeverity: minSeverity, console: console, timeout: DefaultSendTimeout}
+}
+
+// Notify sends an alert of the findings of result at or above the minimum
+// severity, if it has any
+func (a *Alerter) Notify(result cache.ScanResult) {
+	message, ok := a.Message(result)
+	if !ok {
+		return
+	}
+	for _, sink := range a.sinks {
+		a.sending.Add(1)
+		go func(sink Sink) {
+			defer a.sending.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+			defer cancel()
+			if err := sink.Send(ctx, message); err != nil {
+				a.console.Log("Failed to send %s alert for %s: %v", sink.Name(), result.Target, err)
+				return
+			}
+			a.console.Log("Sent %s alert for %s", sink.Name(), result.Target)
+		}(sink)
+	}
+}
+
+// Wait waits for the alerts being sent, each bounded by the send timeout
+func (a *Alerter) Wait() {
+	a.sending.Wait()
+}
+
+// Message returns the alert of the findings of result at or above the
+// minimum severity, most severe first, and false when there are none
+func (a *Alerter) Message(result cache.ScanResult) (Message, bool) {
+	var findings []*output.ResultEvent
+	for _, finding := range result.Findings {
+		if finding == nil {
+			continue
+		}
+		// Unknown ranks above critical but is not more severe
+		if level := finding.Info.SeverityHolder.Severity; level >= a.minSeverity && level <= severity.Critical {
+			findings = append(findings, finding)
+		}
+	}
+	if len(findings) == 0 {
+		return Message{}, false
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Info.SeverityHolder.Severity > findings[j].Info.SeverityHolder.Severity
+	})
+
+	counts := map[severity.Severity]int{}
+	for _, finding := range findings {
+		counts[finding.Info.SeverityHolder.Severity]++
+	}
+	var summary []string
+	for level := severity.Critical; level >= a.minSeverity && level > severity.Undefined; level-- {
+		if counts[level] > 0 {
+			summary = append(summary, fmt.Sprintf("%d %s", counts[level], level))
+		}
+	}
+
+	noun := "findings"
+	if len(findings) == 1 {
+		noun = "finding"
+	}
+	subject := fmt.Sprintf("[nuclei-mcp] %d %s on %s (%s)", len(findings), noun, result.Target, strings.Join(summary, ", "))
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "The scan of %s at %s reported %d %s at or above %s severity.\n", result.Target, result.ScanTime.Format(time.RFC3339), len(findings), noun, a.minSeverity)
+	if labels := labelList(result.Labels); labels != "" {
+		fmt.Fprintf(&text, "Labels: %s\n", labels)
+	}
+	message := Message{Subject: subject, Target: result.Target, ScanTime: result.ScanTime, Labels: result.Labels}
+	for _, finding := range findings {
+		matched := finding.Matched
+		if matched == "" {
+			matched = finding.Host
+		}
+		entry := Finding{
+			Severity:   finding.Info.SeverityHolder.Severity.String(),
+			Name:       finding.Info.Name,
+			TemplateID: finding.TemplateID,
+			Matched:    matched,
+			Resource:   cache.FindingURI(finding),
+		}
+		message.Findings = append(message.Findings, entry)
+		fmt.Fprintf(&text, "\n[%s] %s (%s)\n  %s\n  %s\n", entry.Severity, entry.Name, entry.TemplateID, entry.Matched, entry.Resource)
+	}
+	message.Text = text.String()
+	return message, true
+}
+
+// labelList formats labels as key=value pairs sorted by key
+func labelList(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + "=" + labels[key]
+	}
+	return strings.Join(pairs, ", ")
+}
@@ -0,0 +1,188 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TLS modes of an SMTP connection
+const (
+	// SMTPStartTLS upgrades a plain connection with STARTTLS and refuses
+	// servers that do not offer it
+	SMTPStartTLS = "starttls"
+	// SMTPTLS connects over TLS from the start, usually on port 465
+	SMTPTLS = "tls"
+	// SMTPPlain sends without TLS, such as to a local relay
+	SMTPPlain = "none"
+)
+
+// SMTPConfig is the mail server alerts are sent through and who gets them
+type SMTPConfig struct {
+	Host string
+	// Port defaults to 465 with TLS and 587 otherwise
+	Port int
+	// TLS is SMTPStartTLS (default), SMTPTLS or SMTPPlain
+	TLS  string
+	From string
+	To   []string
+	// Username and Password authenticate with PLAIN auth when Username is
+	// set. Go's PLAIN auth refuses to send them unencrypted, except to
+	// localhost.
+	Username string
+	Password string
+}
+
+// SMTPSink sends notifications by email
+type SMTPSink struct {
+	cfg  SMTPConfig
+	from *mail.Address
+	to   []*mail.Address
+}
+
+// NewSMTPSink validates cfg and creates a sink sending through its server
+func NewSMTPSink(cfg SMTPConfig) (*SMTPSink, error) {
+	cfg.Host = strings.TrimSpace(cfg.Host)
+	if cfg.Host == "" {
+		return nil, fmt.Errorf("smtp host is required")
+	}
+	switch cfg.TLS = strings.ToLower(strings.TrimSpace(cfg.TLS)); cfg.TLS {
+	case "":
+		cfg.TLS = SMTPStartTLS
+	case SMTPStartTLS, SMTPTLS, SMTPPlain:
+	default:
+		return nil, fmt.Errorf("invalid smtp tls mode %q, use starttls, tls or none", cfg.TLS)
+	}
+	if cfg.Port == 0 {
+		cfg.Port = 587
+		if cfg.TLS == SMTPTLS {
+			cfg.Port = 465
+		}
+	}
+	if cfg.Port < 0 || cfg.Port > 65535 {
+		return nil, fmt.Errorf("invalid smtp port %d", cfg.Port)
+	}
+
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid smtp from address %q: %w", cfg.From, err)
+	}
+	s := &SMTPSink{cfg: cfg, from: from}
+	for _, raw := range cfg.To {
+		to, err := mail.ParseAddress(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid smtp to address %q: %w", raw, err)
+		}
+		s.to = append(s.to, to)
+	}
+	if len(s.to) == 0 {
+		return nil, fmt.Errorf("at least one smtp to address is required")
+	}
+	return s, nil
+}
+
+// Name identifies the sink in logs
+func (s *SMTPSink) Name() string {
+	return "email"
+}
+
+// Send mails message to the recipients
+func (s *SMTPSink) Send(ctx context.Context, message Message) error {
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to smtp server %s: %w", addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	tlsConfig := &tls.Config{ServerName: s.cfg.Host, MinVersion: tls.VersionTLS12}
+	if s.cfg.TLS == SMTPTLS {
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	client, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start smtp session with %s: %w", addr, err)
+	}
+	defer client.Close()
+
+	if s.cfg.TLS == SMTPStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("smtp server %s does not offer STARTTLS", addr)
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("failed to start tls with %s: %w", addr, err)
+		}
+	}
+	if s.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)); err != nil {
+			return fmt.Errorf("failed to authenticate with %s: %w", addr, err)
+		}
+	}
+
+	if err := client.Mail(s.from.Address); err != nil {
+		return fmt.Errorf("smtp server %s refused sender: %w", addr, err)
+	}
+	for _, to := range s.to {
+		if err := client.Rcpt(to.Address); err != nil {
+			return fmt.Errorf("smtp server %s refused recipient %s: %w", addr, to.Address, err)
+		}
+	}
+	body, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	if _, err := body.Write(s.compose(message, time.Now())); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	if err := body.Close(); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return client.Quit()
+}
+
+// compose encodes message as a plain text email
+func (s *SMTPSink) compose(message Message, now time.Time) []byte {
+	to := make([]string, len(s.to))
+	for i, address := range s.to {
+		to[i] = address.String()
+	}
+	id := make([]byte, 12)
+	_, _ = rand.Read(id)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", s.from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@%s>\r\n", hex.EncodeToString(id), domainOf(s.from.Address))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	for _, line := range strings.Split(strings.TrimRight(message.Text, "\n"), "\n") {
+		buf.WriteString(strings.TrimRight(line, "\r"))
+		buf.WriteString("\r\n")
+	}
+	return buf.Bytes()
+}
+
+// domainOf returns the domain of an email address
+func domainOf(address string) string {
+	if at := strings.LastIndex(address, "@"); at >= 0 {
+		return address[at+1:]
+	}
+	return "localhost"
+}
@@ -0,0 +1,228 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"nuclei-mcp/pkg/report"
+)
+
+// Payload formats of a webhook
+const (
+	// FormatJSON posts the message as plain JSON
+	FormatJSON = "json"
+	// FormatSlack posts a Slack Block Kit message, for Slack incoming
+	// webhooks
+	FormatSlack = "slack"
+	// FormatTeams posts an Adaptive Card, for Microsoft Teams incoming
+	// webhooks and workflows
+	FormatTeams = "teams"
+)
+
+// maxCardFindings caps the findings laid out in a chat card, keeping it
+// within Slack's block limit and readable in a channel
+const maxCardFindings = 20
+
+// WebhookSink posts notifications to an HTTP endpoint
+type WebhookSink struct {
+	url    string
+	format string
+	client *http.Client
+}
+
+// NewWebhookSink creates a sink posting to rawURL in format: FormatJSON
+// (default), FormatSlack or FormatTeams
+func NewWebhookSink(rawURL, format string) (*WebhookSink, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		// The URL is not echoed, as chat webhook URLs embed their credential
+		return nil, fmt.Errorf("invalid webhook url: must be an http(s) URL")
+	}
+	switch format = strings.ToLower(strings.TrimSpace(format)); format {
+	case "":
+		format = FormatJSON
+	case FormatJSON, FormatSlack, FormatTeams:
+	default:
+		return nil, fmt.Errorf("invalid webhook format %q, use json, slack or teams", format)
+	}
+	return &WebhookSink{url: u.String(), format: format, client: &http.Client{Timeout: DefaultSendTimeout}}, nil
+}
+
+// Name identifies the sink in logs
+func (s *WebhookSink) Name() string {
+	if s.format == FormatJSON {
+		return "webhook"
+	}
+	return s.format
+}
+
+// Send posts message in the sink's format
+func (s *WebhookSink) Send(ctx context.Context, message Message) error {
+	var payload any = message
+	switch s.format {
+	case FormatSlack:
+		payload = slackMessage(message)
+	case FormatTeams:
+		payload = teamsMessage(message)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s payload: %w", s.Name(), err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// Leave the URL out of the error, like in NewWebhookSink
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("failed to post to webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// alertSeverity returns the severity of the most severe finding of message
+func alertSeverity(message Message) string {
+	if len(message.Findings) == 0 {
+		return "info"
+	}
+	return message.Findings[0].Severity
+}
+
+// cardFindings returns the findings laid out in a card and how many were
+// left out
+func cardFindings(message Message) ([]Finding, int) {
+	if len(message.Findings) <= maxCardFindings {
+		return message.Findings, 0
+	}
+	return message.Findings[:maxCardFindings], len(message.Findings) - maxCardFindings
+}
+
+// slackMessage lays message out in Block Kit, in an attachment colored by
+// the most severe finding
+func slackMessage(message Message) map[string]any {
+	fields := []map[string]any{
+		{"type": "mrkdwn", "text": "*Target*\n" + slackEscape(message.Target)},
+		{"type": "mrkdwn", "text": "*Scanned*\n" + message.ScanTime.UTC().Format(time.RFC3339)},
+	}
+	if labels := labelList(message.Labels); labels != "" {
+		fields = append(fields, map[string]any{"type": "mrkdwn", "text": "*Labels*\n" + slackEscape(labels)})
+	}
+	blocks := []map[string]any{
+		{"type": "header", "text": map[string]any{"type": "plain_text", "text": truncate(message.Subject, 150)}},
+		{"type": "section", "fields": fields},
+		{"type": "divider"},
+	}
+
+	findings, more := cardFindings(message)
+	for _, finding := range findings {
+		blocks = append(blocks, map[string]any{
+			"type": "section",
+			"text": map[string]any{"type": "mrkdwn", "text": fmt.Sprintf("*[%s]* %s (`%s`)\n%s\n`%s`",
+				strings.ToUpper(finding.Severity), slackEscape(finding.Name), slackEscape(finding.TemplateID), slackEscape(finding.Matched), finding.Resource)},
+		})
+	}
+	if more > 0 {
+		blocks = append(blocks, map[string]any{
+			"type":     "context",
+			"elements": []map[string]any{{"type": "mrkdwn", "text": fmt.Sprintf("and %d more; list them with list_findings", more)}},
+		})
+	}
+
+	return map[string]any{
+		// Shown in notifications and clients without Block Kit
+		"text": message.Subject,
+		"attachments": []map[string]any{{
+			"color":  report.SeverityColors[alertSeverity(message)],
+			"blocks": blocks,
+		}},
+	}
+}
+
+// slackEscape escapes the characters Slack's mrkdwn reserves
+func slackEscape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+// teamsColors are the Adaptive Card colors of each severity; cards only
+// offer a few named colors
+var teamsColors = map[string]string{
+	"critical": "attention",
+	"high":     "attention",
+	"medium":   "warning",
+	"low":      "accent",
+	"info":     "default",
+}
+
+// teamsMessage lays message out as an Adaptive Card, with the title and
+// each finding's severity colored
+func teamsMessage(message Message) map[string]any {
+	facts := []map[string]any{
+		{"title": "Target", "value": message.Target},
+		{"title": "Scanned", "value": message.ScanTime.UTC().Format(time.RFC3339)},
+	}
+	if labels := labelList(message.Labels); labels != "" {
+		facts = append(facts, map[string]any{"title": "Labels", "value": labels})
+	}
+	body := []map[string]any{
+		{"type": "TextBlock", "text": message.Subject, "weight": "bolder", "size": "medium", "wrap": true, "color": teamsColors[alertSeverity(message)]},
+		{"type": "FactSet", "facts": facts},
+	}
+
+	findings, more := cardFindings(message)
+	for _, finding := range findings {
+		body = append(body, map[string]any{
+			"type":      "Container",
+			"separator": true,
+			"items": []map[string]any{
+				{"type": "TextBlock", "text": fmt.Sprintf("**[%s]** %s (%s)", strings.ToUpper(finding.Severity), finding.Name, finding.TemplateID), "wrap": true, "color": teamsColors[finding.Severity]},
+				{"type": "TextBlock", "text": finding.Matched, "wrap": true, "spacing": "none"},
+				{"type": "TextBlock", "text": finding.Resource, "wrap": true, "isSubtle": true, "size": "small", "spacing": "none"},
+			},
+		})
+	}
+	if more > 0 {
+		body = append(body, map[string]any{"type": "TextBlock", "text": fmt.Sprintf("and %d more; list them with list_findings", more), "isSubtle": true, "wrap": true})
+	}
+
+	return map[string]any{
+		"type": "message",
+		"attachments": []map[string]any{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content": map[string]any{
+				"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+				"type":    "AdaptiveCard",
+				"version": "1.4",
+				"body":    body,
+			},
+		}},
+	}
+}
+
+// truncate shortens text to at most n runes
+func truncate(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n-1]) + "…"
+}
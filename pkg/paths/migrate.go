@@ -0,0 +1,103 @@
+package paths
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Move relocates a file or directory from a previous default location
+type Move struct {
+	From string
+	To   string
+}
+
+// Migrate moves each From that exists to its To, unless To already exists,
+// and returns the moves made. Moves across file systems fall back to copy
+// and remove.
+func Migrate(moves []Move) ([]Move, error) {
+	var done []Move
+	for _, move := range moves {
+		if due, err := move.due(); err != nil {
+			return done, err
+		} else if !due {
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(move.To), 0755); err != nil {
+			return done, fmt.Errorf("failed to create %s: %w", filepath.Dir(move.To), err)
+		}
+		if err := os.Rename(move.From, move.To); err != nil {
+			if err := copyTree(move.From, move.To); err != nil {
+				os.RemoveAll(move.To)
+				return done, fmt.Errorf("failed to migrate %s to %s: %w", move.From, move.To, err)
+			}
+			if err := os.RemoveAll(move.From); err != nil {
+				return done, fmt.Errorf("failed to remove %s after migrating it: %w", move.From, err)
+			}
+		}
+		done = append(done, move)
+	}
+	return done, nil
+}
+
+// Pending returns the moves Migrate would make, without making them
+func Pending(moves []Move) []Move {
+	var pending []Move
+	for _, move := range moves {
+		if due, err := move.due(); err == nil && due {
+			pending = append(pending, move)
+		}
+	}
+	return pending
+}
+
+// due reports whether From exists and To does not
+func (m Move) due() (bool, error) {
+	if filepath.Clean(m.From) == filepath.Clean(m.To) {
+		return false, nil
+	}
+	if _, err := os.Lstat(m.From); err != nil {
+		return false, nil
+	}
+	if _, err := os.Lstat(m.To); err == nil {
+		return false, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return false, fmt.Errorf("failed to migrate %s: %w", m.From, err)
+	}
+	return true, nil
+}
+
+// copyTree copies the file or directory src to dst
+func copyTree(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		}
+
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
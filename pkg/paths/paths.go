@@ -0,0 +1,131 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// AppName names the server's per-user directories
+const AppName = "nuclei-mcp"
+
+// ConfigFile is the name of the configuration file
+const ConfigFile = "config.yaml"
+
+// ConfigDir returns the per-user directory of the configuration file:
+// %AppData%\nuclei-mcp on Windows, ~/Library/Application Support/nuclei-mcp
+// on macOS and $XDG_CONFIG_HOME/nuclei-mcp or ~/.config/nuclei-mcp
+// elsewhere. Without a home directory, it falls back to the working
+// directory.
+func ConfigDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "."
+	}
+	return filepath.Join(dir, AppName)
+}
+
+// DataDir returns the per-user directory of the state the server keeps,
+// such as custom templates, exclusion rules and tracked findings:
+// $XDG_DATA_HOME/nuclei-mcp or ~/.local/share/nuclei-mcp on Unix systems
+// other than macOS, and the config directory on Windows and macOS, where
+// configuration and data share a directory
+func DataDir() string {
+	switch runtime.GOOS {
+	case "windows", "darwin", "ios", "plan9":
+		return ConfigDir()
+	}
+	if dir := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, AppName)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return "."
+	}
+	return filepath.Join(home, ".local", "share", AppName)
+}
+
+// CacheDir returns the per-user directory for files the server can recreate,
+// such as logs and extracted template bundles: %LocalAppData%\nuclei-mcp on
+// Windows, ~/Library/Caches/nuclei-mcp on macOS and $XDG_CACHE_HOME/nuclei-mcp
+// or ~/.cache/nuclei-mcp elsewhere. Without a home directory, it falls back
+// to the system temp directory.
+func CacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), AppName)
+	}
+	return filepath.Join(dir, AppName)
+}
+
+// LogFile returns the default log file
+func LogFile() string {
+	return filepath.Join(CacheDir(), "logs", "nuclei_mcp.log")
+}
+
+// BundlesDir returns the default directory offline template bundles are
+// extracted into
+func BundlesDir() string {
+	return filepath.Join(CacheDir(), "bundles")
+}
+
+// TemplateIndexFile returns the file the template index is kept in
+func TemplateIndexFile() string {
+	return filepath.Join(CacheDir(), "template-index.json")
+}
+
+// TemplateTimingsFile returns the file the run times of templates are kept
+// in
+func TemplateTimingsFile() string {
+	return filepath.Join(CacheDir(), "template-timings.json")
+}
+
+// TemplateQuarantineFile returns the file the runtime errors of templates
+// and the quarantined templates are kept in
+func TemplateQuarantineFile() string {
+	return filepath.Join(CacheDir(), "template-quarantine.json")
+}
+
+// TemplatesDir returns the default directory of the custom templates
+// managed by add_template
+func TemplatesDir() string {
+	return filepath.Join(DataDir(), "nuclei-templates")
+}
+
+// ExportsDir returns the default directory relative workspace archive paths
+// are resolved against
+func ExportsDir() string {
+	return filepath.Join(DataDir(), "exports")
+}
+
+// TenantsDir returns the default directory of the workspaces of tenants
+func TenantsDir() string {
+	return filepath.Join(DataDir(), "tenants")
+}
+
+// DataFile returns the default location of a state file named name
+func DataFile(name string) string {
+	return filepath.Join(DataDir(), name)
+}
+
+// InstallDir returns the directory of the server's executable, or "" when
+// it cannot be determined
+func InstallDir() string {
+	exe, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	return filepath.Dir(exe)
+}
+
+// FindConfigDir returns the directory the configuration file is read from:
+// the working directory when it holds one, else the config directory
+func FindConfigDir() string {
+	if _, err := os.Stat(ConfigFile); err == nil {
+		return "."
+	}
+	return ConfigDir()
+}
@@ -0,0 +1,247 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// PrivateRanges are the RFC1918, loopback, shared and link-local ranges of
+// the server's own networks
+var PrivateRanges = []string{
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"::1/128",
+	"fc00::/7",
+	"fe80::/10",
+}
+
+// MetadataRanges are the cloud instance metadata endpoints (AWS, GCP, Azure,
+// Alibaba Cloud)
+var MetadataRanges = []string{
+	"169.254.0.0/16",
+	"168.63.129.16/32",
+	"100.100.100.200/32",
+	"fd00:ec2::254/128",
+}
+
+// EgressOptions configures an egress policy
+type EgressOptions struct {
+	// DenyPrivate blocks connections to PrivateRanges
+	DenyPrivate bool
+	// DenyMetadata blocks connections to MetadataRanges
+	DenyMetadata bool
+	// DenyCIDRs blocks additional IPs or CIDR ranges
+	DenyCIDRs []string
+	// AllowedPorts restricts target ports; empty allows any port
+	AllowedPorts []int
+}
+
+// EgressPolicy decides which addresses a scan may connect to, so the server
+// cannot be used to reach its own infrastructure
+type EgressPolicy struct {
+	denied []netip.Prefix
+	ports  []int
+}
+
+// NewEgressPolicy creates an egress policy. It returns nil when the options
+// impose no restriction.
+func NewEgressPolicy(opts EgressOptions) (*EgressPolicy, error) {
+	var cidrs []string
+	if opts.DenyPrivate {
+		cidrs = append(cidrs, PrivateRanges...)
+	}
+	if opts.DenyMetadata {
+		cidrs = append(cidrs, MetadataRanges...)
+	}
+	cidrs = append(cidrs, opts.DenyCIDRs...)
+
+	p := &EgressPolicy{}
+	for _, cidr := range cidrs {
+		prefix, err := parsePrefix(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, fmt.Errorf("invalid egress deny range %q: %w", cidr, err)
+		}
+		p.denied = append(p.denied, prefix)
+	}
+	for _, port := range opts.AllowedPorts {
+		if port < 1 || port > 65535 {
+			return nil, fmt.Errorf("invalid egress port %d", port)
+		}
+		p.ports = append(p.ports, port)
+	}
+
+	if len(p.denied) == 0 && len(p.ports) == 0 {
+		return nil, nil
+	}
+	return p, nil
+}
+
+func parsePrefix(cidr string) (netip.Prefix, error) {
+	if strings.Contains(cidr, "/") {
+		prefix, err := netip.ParsePrefix(cidr)
+		return prefix.Masked(), err
+	}
+	addr, err := netip.ParseAddr(cidr)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// DenyList returns the denied ranges in CIDR notation
+func (p *EgressPolicy) DenyList() []string {
+	if p == nil {
+		return nil
+	}
+	list := make([]string, 0, len(p.denied))
+	for _, prefix := range p.denied {
+		list = append(list, prefix.String())
+	}
+	return list
+}
+
+// AllowedPorts returns the allowed target ports; empty means any port
+func (p *EgressPolicy) AllowedPorts() []int {
+	if p == nil {
+		return nil
+	}
+	return append([]int(nil), p.ports...)
+}
+
+// nat64Prefix and sixToFourPrefix carry an IPv4 address inside an IPv6
+// address, which reaches the IPv4 host through a translating gateway
+var (
+	nat64Prefix     = netip.MustParsePrefix("64:ff9b::/96")
+	sixToFourPrefix = netip.MustParsePrefix("2002::/16")
+)
+
+// CheckAddress returns an error when the address is in a denied range.
+// Zones are ignored, and IPv4 addresses embedded in IPv4-mapped, NAT64 and
+// 6to4 addresses are checked as IPv4 addresses too.
+func (p *EgressPolicy) CheckAddress(addr netip.Addr) error {
+	if p == nil {
+		return nil
+	}
+	addr = addr.Unmap().WithZone("")
+	candidates := []netip.Addr{addr}
+	if embedded, ok := embeddedIPv4(addr); ok {
+		candidates = append(candidates, embedded)
+	}
+	for _, candidate := range candidates {
+		for _, prefix := range p.denied {
+			if prefix.Contains(candidate) {
+				return deny("egress to %s is denied by the egress policy (%s)", addr, prefix)
+			}
+		}
+	}
+	return nil
+}
+
+// embeddedIPv4 returns the IPv4 address a NAT64 or 6to4 address carries
+func embeddedIPv4(addr netip.Addr) (netip.Addr, bool) {
+	bytes := addr.As16()
+	switch {
+	case !addr.Is6():
+		return netip.Addr{}, false
+	case nat64Prefix.Contains(addr):
+		return netip.AddrFrom4([4]byte(bytes[12:16])), true
+	case sixToFourPrefix.Contains(addr):
+		return netip.AddrFrom4([4]byte(bytes[2:6])), true
+	}
+	return netip.Addr{}, false
+}
+
+// CheckPort returns an error when the port is not allowed
+func (p *EgressPolicy) CheckPort(port int) error {
+	if p == nil || len(p.ports) == 0 || slices.Contains(p.ports, port) {
+		return nil
+	}
+	return deny("egress to port %d is denied by the egress policy", port)
+}
+
+// CheckTarget resolves the target's host and checks its addresses and port.
+// A target without scheme or port must allow port 80 or 443. Unresolvable
+// hosts pass, since the scan cannot connect to them either.
+func (p *EgressPolicy) CheckTarget(ctx context.Context, target string) error {
+	if p == nil {
+		return nil
+	}
+	u, ok := parseTarget(strings.TrimSpace(target))
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrInvalidTarget, target)
+	}
+
+	switch {
+	case u.Port() != "":
+		port, err := strconv.Atoi(u.Port())
+		if err != nil {
+			return fmt.Errorf("%w port: %s", ErrInvalidTarget, target)
+		}
+		if err := p.CheckPort(port); err != nil {
+			return err
+		}
+	case u.Scheme == "https":
+		if err := p.CheckPort(443); err != nil {
+			return err
+		}
+	case u.Scheme == "http":
+		if err := p.CheckPort(80); err != nil {
+			return err
+		}
+	default:
+		if p.CheckPort(80) != nil && p.CheckPort(443) != nil {
+			return deny("egress to ports 80 and 443 is denied by the egress policy")
+		}
+	}
+
+	host := u.Hostname()
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return p.CheckAddress(addr)
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if err := p.CheckAddress(addr); err != nil {
+			return fmt.Errorf("%s resolves to %s: %w", host, addr.Unmap(), err)
+		}
+	}
+	return nil
+}
+
+// DialControl checks the address a connection is about to be made to,
+// after its host was resolved, against the denied ranges and allowed ports.
+// Set as a net.Dialer's Control, it keeps a host from resolving to a denied
+// address between the check of a target and the connection to it.
+func (p *EgressPolicy) DialControl(_, address string, _ syscall.RawConn) error {
+	if p == nil {
+		return nil
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidTarget, address)
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidTarget, address)
+	}
+	if err := p.CheckAddress(addr); err != nil {
+		return err
+	}
+	number, err := strconv.Atoi(port)
+	if err != nil {
+		return fmt.Errorf("%w port: %s", ErrInvalidTarget, address)
+	}
+	return p.CheckPort(number)
+}
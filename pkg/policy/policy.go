@@ -0,0 +1,133 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultDeniedTags are the template tags blocked unless explicitly approved
+var DefaultDeniedTags = []string{"dos", "intrusive", "fuzz"}
+
+var (
+	// ErrDenied matches errors of targets and templates refused by policy
+	ErrDenied = errors.New("denied by policy")
+	// ErrInvalidTarget matches errors of targets that cannot be parsed
+	ErrInvalidTarget = errors.New("invalid target")
+)
+
+// deniedError keeps the message of a policy refusal while matching ErrDenied
+type deniedError struct {
+	message string
+}
+
+func (e *deniedError) Error() string {
+	return e.message
+}
+
+func (e *deniedError) Is(target error) bool {
+	return target == ErrDenied
+}
+
+func deny(format string, args ...any) error {
+	return &deniedError{message: fmt.Sprintf(format, args...)}
+}
+
+// Override is an explicit request to bypass the template policy
+type Override struct {
+	// Allow requests that denied templates be permitted
+	Allow bool
+	// Approval records who approved the override and why (e.g. a ticket ID)
+	Approval string
+}
+
+// Granted reports whether the override is complete
+func (o Override) Granted() bool {
+	return o.Allow && strings.TrimSpace(o.Approval) != ""
+}
+
+// TemplatePolicy blocks templates carrying denied tags
+type TemplatePolicy struct {
+	deniedTags []string
+}
+
+// NewTemplatePolicy creates a policy denying the given tags
+func NewTemplatePolicy(deniedTags []string) *TemplatePolicy {
+	normalized := make([]string, 0, len(deniedTags))
+	for _, tag := range deniedTags {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			normalized = append(normalized, tag)
+		}
+	}
+	return &TemplatePolicy{deniedTags: normalized}
+}
+
+// DeniedTags returns the tags blocked by the policy
+func (p *TemplatePolicy) DeniedTags() []string {
+	return append([]string(nil), p.deniedTags...)
+}
+
+// Denied returns the tags from the given list that the policy blocks
+func (p *TemplatePolicy) Denied(tags []string) []string {
+	var denied []string
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		for _, deniedTag := range p.deniedTags {
+			if tag == deniedTag {
+				denied = append(denied, tag)
+				break
+			}
+		}
+	}
+	return denied
+}
+
+// CheckTemplate rejects template content carrying denied tags unless the
+// override is granted. Content that cannot be parsed is rejected, since its
+// tags cannot be checked.
+func (p *TemplatePolicy) CheckTemplate(content []byte, override Override) error {
+	tags, err := TemplateTags(content)
+	if err != nil {
+		return deny("template cannot be checked against the policy: %v", err)
+	}
+
+	denied := p.Denied(tags)
+	if len(denied) == 0 || override.Granted() {
+		return nil
+	}
+
+	return deny("template is tagged %s which is blocked by policy; set allow_unsafe and provide an approval to override",
+		strings.Join(denied, ", "))
+}
+
+// TemplateTags extracts info.tags from template content. Tags may be given as
+// a comma-separated string or a list.
+func TemplateTags(content []byte) ([]string, error) {
+	var header struct {
+		Info struct {
+			Tags interface{} `yaml:"tags"`
+		} `yaml:"info"`
+	}
+	if err := yaml.Unmarshal(content, &header); err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	var tags []string
+	switch value := header.Info.Tags.(type) {
+	case string:
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	case []interface{}:
+		for _, item := range value {
+			if tag, ok := item.(string); ok && strings.TrimSpace(tag) != "" {
+				tags = append(tags, strings.TrimSpace(tag))
+			}
+		}
+	}
+	return tags, nil
+}
@@ -0,0 +1,123 @@
+package policy
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Scope restricts scan targets to a set of hosts and URL prefixes
+type Scope struct {
+	entries []scopeEntry
+}
+
+type scopeEntry struct {
+	raw  string
+	host string
+	port string
+	path string
+}
+
+// NewScope creates a scope from http(s) URLs or bare hostnames. A URL
+// without a path admits the host and its subdomains; a URL with a path admits
+// only URLs under that prefix. Other URI schemes (e.g. file://) are ignored.
+func NewScope(entries []string) *Scope {
+	scope := &Scope{}
+	for _, raw := range entries {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		u, ok := parseTarget(raw)
+		if !ok || (u.Scheme != "" && u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		scope.entries = append(scope.entries, scopeEntry{
+			raw:  raw,
+			host: canonicalHost(u.Hostname()),
+			port: u.Port(),
+			path: strings.TrimSuffix(u.EscapedPath(), "/"),
+		})
+	}
+	return scope
+}
+
+// Empty reports whether the scope has no entries and therefore admits any target
+func (s *Scope) Empty() bool {
+	return s == nil || len(s.entries) == 0
+}
+
+// Entries returns the scope entries as given
+func (s *Scope) Entries() []string {
+	if s == nil {
+		return nil
+	}
+	entries := make([]string, 0, len(s.entries))
+	for _, entry := range s.entries {
+		entries = append(entries, entry.raw)
+	}
+	return entries
+}
+
+// Contains reports whether the target is inside the scope. An empty scope
+// contains every target.
+func (s *Scope) Contains(target string) bool {
+	if s.Empty() {
+		return true
+	}
+	u, ok := parseTarget(strings.TrimSpace(target))
+	if !ok {
+		return false
+	}
+	host := canonicalHost(u.Hostname())
+	path := u.EscapedPath()
+
+	for _, entry := range s.entries {
+		if host != entry.host && !strings.HasSuffix(host, "."+entry.host) {
+			continue
+		}
+		if entry.port != "" && u.Port() != entry.port {
+			continue
+		}
+		if entry.path != "" && path != entry.path && !strings.HasPrefix(path, entry.path+"/") {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// Check returns an error when the target is outside the scope, unless the
+// override is granted
+func (s *Scope) Check(target string, override Override) error {
+	if s.Contains(target) || override.Granted() {
+		return nil
+	}
+	return deny("target %s is outside the scan scope (%s); set allow_out_of_scope and provide an approval to override",
+		target, strings.Join(s.Entries(), ", "))
+}
+
+// Enforce returns an error when the target is outside the scope. Unlike
+// Check, no override is accepted.
+func (s *Scope) Enforce(target string) error {
+	if s.Contains(target) {
+		return nil
+	}
+	return deny("target %s is outside the scan scope (%s)", target, strings.Join(s.Entries(), ", "))
+}
+
+// parseTarget parses a URL, host or host:port target. Bare IPv6 addresses
+// are accepted as well.
+func parseTarget(target string) (*url.URL, bool) {
+	if target == "" {
+		return nil, false
+	}
+	target = NormalizeTarget(target)
+	if !strings.Contains(target, "://") {
+		target = "//" + target
+	}
+	u, err := url.Parse(target)
+	if err != nil || u.Hostname() == "" {
+		return nil, false
+	}
+	return u, true
+}
@@ -0,0 +1,79 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+)
+
+// SelfTargetGuard refuses scans of the server's own host: loopback and
+// unspecified addresses, and the addresses of its network interfaces. A
+// scan of the MCP host can reach the server itself, and over stdio a
+// template hammering the host can stall the transport the client talks to.
+type SelfTargetGuard struct {
+	addrs map[netip.Addr]bool
+}
+
+// NewSelfTargetGuard creates a guard for the addresses of the host's
+// network interfaces
+func NewSelfTargetGuard() (*SelfTargetGuard, error) {
+	ifaceAddrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list interface addresses: %w", err)
+	}
+	var addrs []netip.Addr
+	for _, ifaceAddr := range ifaceAddrs {
+		if prefix, err := netip.ParsePrefix(ifaceAddr.String()); err == nil {
+			addrs = append(addrs, prefix.Addr())
+		}
+	}
+	return NewSelfTargetGuardFor(addrs...), nil
+}
+
+// NewSelfTargetGuardFor creates a guard for the given host addresses in
+// addition to loopback and unspecified addresses
+func NewSelfTargetGuardFor(addrs ...netip.Addr) *SelfTargetGuard {
+	g := &SelfTargetGuard{addrs: map[netip.Addr]bool{}}
+	for _, addr := range addrs {
+		g.addrs[addr.Unmap().WithZone("")] = true
+	}
+	return g
+}
+
+// IsSelf reports whether addr belongs to the server's host
+func (g *SelfTargetGuard) IsSelf(addr netip.Addr) bool {
+	addr = addr.Unmap().WithZone("")
+	return addr.IsLoopback() || addr.IsUnspecified() || g.addrs[addr]
+}
+
+// Check returns an error when the target's host is, or resolves to, the
+// server's own host, unless the override is granted. Unresolvable hosts
+// pass, since the scan cannot connect to them either.
+func (g *SelfTargetGuard) Check(ctx context.Context, target string, override Override) error {
+	if g == nil || override.Granted() {
+		return nil
+	}
+	u, ok := parseTarget(target)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrInvalidTarget, target)
+	}
+
+	host := u.Hostname()
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if g.IsSelf(addr) {
+			return deny("target %s is the server's own host; set allow_self_target and provide an approval to override", target)
+		}
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if g.IsSelf(addr) {
+			return deny("target %s resolves to %s, the server's own host; set allow_self_target and provide an approval to override", target, addr.Unmap())
+		}
+	}
+	return nil
+}
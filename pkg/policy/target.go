@@ -0,0 +1,44 @@
+package policy
+
+import (
+	"net/netip"
+	"strings"
+)
+
+// NormalizeTarget writes IPv6 literals the way URLs carry them: a bare
+// address, such as 2001:db8::1 or fe80::1%eth0, is bracketed and the zone
+// of a bracketed address is escaped, as in [fe80::1%25eth0]:8080. Other
+// targets are returned trimmed.
+func NormalizeTarget(target string) string {
+	target = strings.TrimSpace(target)
+	if addr, err := netip.ParseAddr(target); err == nil && addr.Is6() {
+		return bracket(addr)
+	}
+	open := strings.Index(target, "[")
+	end := strings.Index(target, "]")
+	if open < 0 || end < open {
+		return target
+	}
+	host := target[open+1 : end]
+	if zone := strings.Index(host, "%"); zone >= 0 && !strings.HasPrefix(host[zone:], "%25") {
+		host = host[:zone] + "%25" + host[zone+1:]
+	}
+	return target[:open+1] + host + target[end:]
+}
+
+// canonicalHost lowercases a host name and writes IP addresses in their
+// canonical form, so 2001:0DB8::1 and 2001:db8::1 are the same host
+func canonicalHost(host string) string {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return addr.Unmap().String()
+	}
+	return strings.ToLower(host)
+}
+
+// bracket returns the IPv6 address in brackets with its zone escaped
+func bracket(addr netip.Addr) string {
+	if zone := addr.Zone(); zone != "" {
+		return "[" + addr.WithZone("").String() + "%25" + zone + "]"
+	}
+	return "[" + addr.String() + "]"
+}
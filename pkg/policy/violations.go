@@ -0,0 +1,57 @@
+package policy
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultViolationLimit is the number of policy violations kept in memory
+const DefaultViolationLimit = 100
+
+// Violation is a tool call refused by the scope, egress or template policy
+type Violation struct {
+	Time   time.Time `json:"time"`
+	Tool   string    `json:"tool"`
+	Target string    `json:"target,omitempty"`
+	Reason string    `json:"reason"`
+}
+
+// ViolationLog keeps the most recent policy violations
+type ViolationLog struct {
+	mu         sync.Mutex
+	limit      int
+	violations []Violation
+}
+
+// NewViolationLog creates a log keeping at most limit violations
+func NewViolationLog(limit int) *ViolationLog {
+	if limit <= 0 {
+		limit = DefaultViolationLimit
+	}
+	return &ViolationLog{limit: limit}
+}
+
+// Record adds a violation, dropping the oldest one when the log is full
+func (l *ViolationLog) Record(violation Violation) {
+	if violation.Time.IsZero() {
+		violation.Time = time.Now()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.violations = append(l.violations, violation)
+	if over := len(l.violations) - l.limit; over > 0 {
+		l.violations = append([]Violation(nil), l.violations[over:]...)
+	}
+}
+
+// Recent returns the recorded violations, newest first
+func (l *ViolationLog) Recent() []Violation {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	recent := make([]Violation, len(l.violations))
+	for i, violation := range l.violations {
+		recent[len(l.violations)-1-i] = violation
+	}
+	return recent
+}
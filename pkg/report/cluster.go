@@ -0,0 +1,52 @@
+package report
+
+import (
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+)
+
+// Cluster is a group of findings with the same root cause: the same
+// template and matcher, reported on different endpoints
+type Cluster struct {
+	// Finding is the first finding of the group, standing for the others
+	Finding *output.ResultEvent
+	// Endpoints lists the matched locations of the group, in the order
+	// they were found
+	Endpoints []string
+}
+
+// ClusterFindings groups findings that share a template and matcher into
+// one cluster listing the affected endpoints, so a missing header reported
+// on 200 pages reads as one finding. Clusters keep the order of their first
+// finding; findings without siblings get a cluster of their own.
+func ClusterFindings(findings []*output.ResultEvent) []Cluster {
+	var clusters []Cluster
+	index := make(map[string]int)
+	seen := make(map[string]bool)
+	for _, finding := range findings {
+		if finding == nil {
+			continue
+		}
+		key := finding.TemplateID + "\x00" + finding.MatcherName
+		i, ok := index[key]
+		if !ok {
+			i = len(clusters)
+			index[key] = i
+			clusters = append(clusters, Cluster{Finding: finding})
+		}
+		endpoint := Endpoint(finding)
+		if !seen[key+"\x00"+endpoint] {
+			seen[key+"\x00"+endpoint] = true
+			clusters[i].Endpoints = append(clusters[i].Endpoints, endpoint)
+		}
+	}
+	return clusters
+}
+
+// Endpoint returns the location a finding matched, or its host when the
+// template did not record one
+func Endpoint(finding *output.ResultEvent) string {
+	if finding.Matched != "" {
+		return finding.Matched
+	}
+	return finding.Host
+}
@@ -0,0 +1,89 @@
+package report
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+)
+
+// skippedCurlHeaders are request headers curl sets by itself
+var skippedCurlHeaders = map[string]bool{
+	"content-length":    true,
+	"transfer-encoding": true,
+	"connection":        true,
+}
+
+// CurlCommand returns a curl command reproducing the request of an HTTP
+// finding. The command recorded by nuclei is used when there is one;
+// otherwise it is generated from the raw request, which nuclei does not
+// convert for raw, unsafe or race requests. Findings without an HTTP
+// request get an empty command.
+func CurlCommand(finding *output.ResultEvent) string {
+	if finding.CURLCommand != "" {
+		return finding.CURLCommand
+	}
+	if finding.Type != "" && finding.Type != "http" {
+		return ""
+	}
+	return curlFromRaw(finding.Request, finding.Matched)
+}
+
+// curlFromRaw converts a raw HTTP/1.x request into a curl command. matched is
+// the URL the request was sent to and supplies the scheme, and the host when
+// the request has no Host header.
+func curlFromRaw(raw string, matched string) string {
+	raw = strings.ReplaceAll(raw, "\r\n", "\n")
+	head, body, _ := strings.Cut(raw, "\n\n")
+	lines := strings.Split(head, "\n")
+
+	requestLine := strings.Fields(lines[0])
+	if len(requestLine) < 2 {
+		return ""
+	}
+	method, target := requestLine[0], requestLine[1]
+
+	base, err := url.Parse(matched)
+	if err != nil || base.Scheme == "" {
+		base = &url.URL{Scheme: "http"}
+	}
+
+	var headers []string
+	for _, line := range lines[1:] {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		switch lower := strings.ToLower(name); {
+		case lower == "host":
+			base.Host = value
+		case skippedCurlHeaders[lower]:
+		default:
+			headers = append(headers, name+": "+value)
+		}
+	}
+
+	requestURL := target
+	if !strings.Contains(target, "://") {
+		if base.Host == "" {
+			return ""
+		}
+		requestURL = base.Scheme + "://" + base.Host + target
+	}
+
+	command := []string{"curl", "-X", shellQuote(method)}
+	for _, header := range headers {
+		command = append(command, "-H", shellQuote(header))
+	}
+	if body != "" {
+		command = append(command, "--data-binary", shellQuote(body))
+	}
+	command = append(command, shellQuote(requestURL))
+	return strings.Join(command, " ")
+}
+
+// shellQuote quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,244 @@
+package report
+
+import (
+	"bytes"
+	"html/template"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/i18n"
+	"nuclei-mcp/pkg/policy"
+)
+
+const (
+	// dashboardTopHosts is the number of hosts listed as most vulnerable
+	dashboardTopHosts = 10
+	// dashboardRecentScans is the number of scans listed as recent
+	dashboardRecentScans = 15
+)
+
+// SeverityColors are the colors of each severity in the dashboard and in
+// chat alerts
+var SeverityColors = map[string]string{
+	"critical": "#7b1fa2",
+	"high":     "#d32f2f",
+	"medium":   "#f57c00",
+	"low":      "#fbc02d",
+	"info":     "#1976d2",
+	"unknown":  "#757575",
+}
+
+type dashboardSeverity struct {
+	Name    string
+	Label   string
+	Color   string
+	Count   int
+	Percent int
+}
+
+type dashboardHost struct {
+	Host       string
+	Total      int
+	BySeverity []dashboardSeverity
+}
+
+type dashboardScan struct {
+	Target   string
+	Time     string
+	Findings int
+	Worst    dashboardSeverity
+}
+
+type dashboardViolation struct {
+	Time   string
+	Tool   string
+	Target string
+	Reason string
+}
+
+type dashboardData struct {
+	Generated  string
+	Targets    int
+	Scans      int
+	Total      int
+	Severities []dashboardSeverity
+	Hosts      []dashboardHost
+	Recent     []dashboardScan
+	Violations []dashboardViolation
+}
+
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Security Dashboard</title>
+<style>
+body{font-family:-apple-system,"Segoe UI",Helvetica,Arial,sans-serif;margin:2rem;color:#212121;background:#fafafa}
+h1{margin-bottom:.25rem}
+.muted{color:#757575}
+.cards{display:flex;flex-wrap:wrap;gap:1rem;margin:1.5rem 0}
+.card{background:#fff;border-radius:6px;box-shadow:0 1px 3px rgba(0,0,0,.15);padding:1rem 1.5rem;min-width:8rem}
+.card .value{font-size:2rem;font-weight:600}
+.bar{display:flex;height:1.25rem;border-radius:4px;overflow:hidden;background:#e0e0e0;margin-bottom:1.5rem}
+table{border-collapse:collapse;width:100%;background:#fff;margin-bottom:2rem}
+th,td{text-align:left;padding:.5rem .75rem;border-bottom:1px solid #e0e0e0}
+.badge{display:inline-block;padding:0 .5rem;border-radius:3px;color:#fff;font-size:.85rem;margin-right:.25rem}
+</style>
+</head>
+<body>
+<h1>Security Dashboard</h1>
+<div class="muted">Generated {{.Generated}}</div>
+<div class="cards">
+<div class="card"><div class="muted">Targets</div><div class="value">{{.Targets}}</div></div>
+<div class="card"><div class="muted">Scans</div><div class="value">{{.Scans}}</div></div>
+<div class="card"><div class="muted">Findings</div><div class="value">{{.Total}}</div></div>
+{{range .Severities}}<div class="card"><div class="muted">{{.Label}}</div><div class="value" style="color:{{.Color}}">{{.Count}}</div></div>
+{{end}}<div class="card"><div class="muted">Policy violations</div><div class="value">{{len .Violations}}</div></div>
+</div>
+{{if .Total}}<div class="bar">{{range .Severities}}{{if .Count}}<div title="{{.Label}}: {{.Count}}" style="width:{{.Percent}}%;background:{{.Color}}"></div>{{end}}{{end}}</div>{{end}}
+
+<h2>Top vulnerable hosts</h2>
+{{if .Hosts}}<table>
+<tr><th>Host</th><th>Findings</th><th>By severity</th></tr>
+{{range .Hosts}}<tr><td>{{.Host}}</td><td>{{.Total}}</td><td>{{range .BySeverity}}<span class="badge" style="background:{{.Color}}">{{.Label}} {{.Count}}</span>{{end}}</td></tr>
+{{end}}</table>{{else}}<p class="muted">No findings.</p>{{end}}
+
+<h2>Recent scans</h2>
+{{if .Recent}}<table>
+<tr><th>Target</th><th>Scan time</th><th>Findings</th><th>Highest severity</th></tr>
+{{range .Recent}}<tr><td>{{.Target}}</td><td>{{.Time}}</td><td>{{.Findings}}</td><td>{{if .Findings}}<span class="badge" style="background:{{.Worst.Color}}">{{.Worst.Label}}</span>{{end}}</td></tr>
+{{end}}</table>{{else}}<p class="muted">No scans yet.</p>{{end}}
+
+<h2>Policy violations</h2>
+{{if .Violations}}<table>
+<tr><th>Time</th><th>Tool</th><th>Target</th><th>Reason</th></tr>
+{{range .Violations}}<tr><td>{{.Time}}</td><td>{{.Tool}}</td><td>{{.Target}}</td><td>{{.Reason}}</td></tr>
+{{end}}</table>{{else}}<p class="muted">No policy violations.</p>{{end}}
+</body>
+</html>
+`))
+
+// Dashboard renders a self-contained HTML page (in English) summarizing the
+// results and policy violations: findings by severity, the most vulnerable
+// hosts, recent scans and refused tool calls
+func Dashboard(results []cache.ScanResult, violations []policy.Violation, generatedAt time.Time) (string, error) {
+	data := dashboardData{
+		Generated: generatedAt.UTC().Format(time.RFC3339),
+		Scans:     len(results),
+	}
+
+	targets := map[string]struct{}{}
+	bySeverity := map[string]int{}
+	hosts := map[string]map[string]int{}
+	for _, result := range results {
+		targets[result.Target] = struct{}{}
+		host := hostOf(result.Target)
+		for _, finding := range result.Findings {
+			if finding == nil {
+				continue
+			}
+			severity := severityOf(finding)
+			data.Total++
+			bySeverity[severity]++
+			if hosts[host] == nil {
+				hosts[host] = map[string]int{}
+			}
+			hosts[host][severity]++
+		}
+	}
+	data.Targets = len(targets)
+	data.Severities = severities(bySeverity, data.Total)
+
+	for host, counts := range hosts {
+		entry := dashboardHost{Host: host, BySeverity: severities(counts, 0)}
+		for _, count := range counts {
+			entry.Total += count
+		}
+		data.Hosts = append(data.Hosts, entry)
+	}
+	sort.Slice(data.Hosts, func(i, j int) bool {
+		a, b := hosts[data.Hosts[i].Host], hosts[data.Hosts[j].Host]
+		if moreSevere(a, b) || moreSevere(b, a) {
+			return moreSevere(a, b)
+		}
+		return data.Hosts[i].Host < data.Hosts[j].Host
+	})
+	if len(data.Hosts) > dashboardTopHosts {
+		data.Hosts = data.Hosts[:dashboardTopHosts]
+	}
+
+	recent := append([]cache.ScanResult(nil), results...)
+	sort.SliceStable(recent, func(i, j int) bool {
+		return recent[i].ScanTime.After(recent[j].ScanTime)
+	})
+	if len(recent) > dashboardRecentScans {
+		recent = recent[:dashboardRecentScans]
+	}
+	for _, result := range recent {
+		scan := dashboardScan{Target: result.Target, Time: result.ScanTime.UTC().Format(time.RFC3339)}
+		if findings := sortedFindings(result.Findings); len(findings) > 0 {
+			scan.Findings = len(findings)
+			scan.Worst = newDashboardSeverity(severityOf(findings[0]), 0, 0)
+		}
+		data.Recent = append(data.Recent, scan)
+	}
+
+	for _, violation := range violations {
+		data.Violations = append(data.Violations, dashboardViolation{
+			Time:   violation.Time.UTC().Format(time.RFC3339),
+			Tool:   violation.Tool,
+			Target: violation.Target,
+			Reason: violation.Reason,
+		})
+	}
+
+	var b bytes.Buffer
+	err := dashboardTemplate.Execute(&b, data)
+	return b.String(), err
+}
+
+// severities lists the non-zero counts in severity order
+func severities(counts map[string]int, total int) []dashboardSeverity {
+	var severities []dashboardSeverity
+	for _, name := range severityOrder {
+		if counts[name] > 0 {
+			severities = append(severities, newDashboardSeverity(name, counts[name], total))
+		}
+	}
+	return severities
+}
+
+func newDashboardSeverity(name string, count int, total int) dashboardSeverity {
+	s := dashboardSeverity{Name: name, Label: i18n.Default().Severity(name), Color: SeverityColors[name], Count: count}
+	if total > 0 {
+		s.Percent = count * 100 / total
+	}
+	return s
+}
+
+// moreSevere reports whether a has more findings than b at the highest
+// severity where their counts differ
+func moreSevere(a, b map[string]int) bool {
+	for _, severity := range severityOrder {
+		if a[severity] != b[severity] {
+			return a[severity] > b[severity]
+		}
+	}
+	return false
+}
+
+// hostOf returns the host name of a target, or the target itself when it
+// cannot be parsed
+func hostOf(target string) string {
+	raw := target
+	if !strings.Contains(raw, "://") {
+		raw = "//" + raw
+	}
+	if u, err := url.Parse(raw); err == nil && u.Hostname() != "" {
+		return strings.ToLower(u.Hostname())
+	}
+	return target
+}
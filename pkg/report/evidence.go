@@ -0,0 +1,227 @@
+package report
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+)
+
+// Evidence is a piece of evidence of a finding served as a blob
+type Evidence struct {
+	Name     string
+	MIMEType string
+	Data     []byte
+}
+
+// FindingEvidence returns the evidence of a finding: the raw request and
+// response, the response body with its own content type (such as a
+// screenshot or a downloaded file), and for HTTP findings a HAR file of the
+// exchange. Evidence the finding does not carry is left out.
+func FindingEvidence(finding *output.ResultEvent) []Evidence {
+	var evidence []Evidence
+	if finding.Request != "" {
+		evidence = append(evidence, Evidence{Name: "request", MIMEType: "message/http", Data: []byte(finding.Request)})
+	}
+	if finding.Response != "" {
+		evidence = append(evidence, Evidence{Name: "response", MIMEType: "message/http", Data: []byte(finding.Response)})
+		if response := parseRawMessage(finding.Response); response.body != "" {
+			mimeType := response.header("Content-Type")
+			if mimeType == "" {
+				mimeType = "application/octet-stream"
+			}
+			evidence = append(evidence, Evidence{Name: "body", MIMEType: mimeType, Data: []byte(response.body)})
+		}
+	}
+	if (finding.Type == "" || finding.Type == "http") && finding.Request != "" {
+		if har, err := HAR(finding); err == nil {
+			evidence = append(evidence, Evidence{Name: "har", MIMEType: "application/json", Data: har})
+		}
+	}
+	return evidence
+}
+
+// rawMessage is a raw HTTP/1.x request or response split into its parts
+type rawMessage struct {
+	startLine []string
+	headers   [][2]string
+	body      string
+	headSize  int
+}
+
+// header returns the first value of the header name, ignoring case
+func (m rawMessage) header(name string) string {
+	for _, header := range m.headers {
+		if strings.EqualFold(header[0], name) {
+			return header[1]
+		}
+	}
+	return ""
+}
+
+// parseRawMessage splits a raw HTTP/1.x message into its start line,
+// headers and body
+func parseRawMessage(raw string) rawMessage {
+	head, body, ok := strings.Cut(raw, "\r\n\r\n")
+	if !ok {
+		head, body, _ = strings.Cut(raw, "\n\n")
+	}
+	lines := strings.Split(strings.ReplaceAll(head, "\r\n", "\n"), "\n")
+	message := rawMessage{startLine: strings.SplitN(lines[0], " ", 3), body: body, headSize: len(head)}
+	for _, line := range lines[1:] {
+		if name, value, ok := strings.Cut(line, ":"); ok {
+			message.headers = append(message.headers, [2]string{strings.TrimSpace(name), strings.TrimSpace(value)})
+		}
+	}
+	return message
+}
+
+type harLog struct {
+	Log harContent `json:"log"`
+}
+
+type harContent struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            int         `json:"time"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harPostData    `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	Size     int    `json:"size,omitempty"`
+	MIMEType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harTimings struct {
+	Send    int `json:"send"`
+	Wait    int `json:"wait"`
+	Receive int `json:"receive"`
+}
+
+// HAR returns a HAR 1.2 file holding the HTTP exchange of a finding, so the
+// evidence opens in browser developer tools and proxies
+func HAR(finding *output.ResultEvent) ([]byte, error) {
+	request := parseRawMessage(finding.Request)
+	response := parseRawMessage(finding.Response)
+
+	method, target, version := "GET", "/", "HTTP/1.1"
+	if len(request.startLine) == 3 {
+		method, target, version = request.startLine[0], request.startLine[1], request.startLine[2]
+	}
+	requestURL := target
+	if !strings.Contains(target, "://") {
+		if base, err := url.Parse(finding.Matched); err == nil && base.Host != "" {
+			host := request.header("Host")
+			if host == "" {
+				host = base.Host
+			}
+			requestURL = base.Scheme + "://" + host + target
+		}
+	}
+
+	entry := harEntry{
+		StartedDateTime: finding.Timestamp.Format(time.RFC3339Nano),
+		Time:            -1,
+		ServerIPAddress: finding.IP,
+		Request: harRequest{
+			Method:      method,
+			URL:         requestURL,
+			HTTPVersion: version,
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(request.headers),
+			QueryString: []harNameValue{},
+			HeadersSize: request.headSize,
+			BodySize:    len(request.body),
+		},
+		Response: harResponse{
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(response.headers),
+			Content:     harPostData{Size: len(response.body), MIMEType: response.header("Content-Type"), Text: response.body},
+			RedirectURL: response.header("Location"),
+			HeadersSize: response.headSize,
+			BodySize:    len(response.body),
+		},
+		Timings: harTimings{Send: -1, Wait: -1, Receive: -1},
+	}
+	if parsed, err := url.Parse(requestURL); err == nil {
+		for name, values := range parsed.Query() {
+			for _, value := range values {
+				entry.Request.QueryString = append(entry.Request.QueryString, harNameValue{Name: name, Value: value})
+			}
+		}
+	}
+	if request.body != "" {
+		entry.Request.PostData = &harPostData{MIMEType: request.header("Content-Type"), Text: request.body}
+	}
+	if finding.Response == "" {
+		entry.Response.HeadersSize, entry.Response.BodySize = -1, -1
+	} else if len(response.startLine) >= 2 {
+		entry.Response.HTTPVersion = response.startLine[0]
+		entry.Response.Status, _ = strconv.Atoi(response.startLine[1])
+		if len(response.startLine) == 3 {
+			entry.Response.StatusText = response.startLine[2]
+		}
+	}
+
+	return json.MarshalIndent(harLog{Log: harContent{
+		Version: "1.2",
+		Creator: harCreator{Name: "nuclei-scanner", Version: "1.0.0"},
+		Entries: []harEntry{entry},
+	}}, "", "  ")
+}
+
+func harHeaders(headers [][2]string) []harNameValue {
+	values := make([]harNameValue, 0, len(headers))
+	for _, header := range headers {
+		values = append(values, harNameValue{Name: header[0], Value: header[1]})
+	}
+	return values
+}
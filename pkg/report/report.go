@@ -0,0 +1,202 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/compliance"
+	"nuclei-mcp/pkg/i18n"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+)
+
+// severityOrder lists severities from most to least urgent
+var severityOrder = []string{"critical", "high", "medium", "low", "info", "unknown"}
+
+// Generator renders scan results as a Markdown report
+type Generator struct {
+	localizer *i18n.Localizer
+	clustered bool
+}
+
+// NewGenerator creates a report generator writing in the localizer's
+// language. A nil localizer writes English reports.
+func NewGenerator(localizer *i18n.Localizer) *Generator {
+	if localizer == nil {
+		localizer = i18n.Default()
+	}
+	return &Generator{localizer: localizer}
+}
+
+// Language returns the report language code
+func (g *Generator) Language() string {
+	return g.localizer.Language()
+}
+
+// Clustered returns a generator listing findings with the same root cause
+// once, with their affected endpoints
+func (g *Generator) Clustered() *Generator {
+	clustered := *g
+	clustered.clustered = true
+	return &clustered
+}
+
+// Markdown renders a report of the given results, most severe findings first
+func (g *Generator) Markdown(results []cache.ScanResult, generatedAt time.Time) string {
+	l := g.localizer
+	results = append([]cache.ScanResult(nil), results...)
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Target < results[j].Target
+	})
+
+	total := 0
+	bySeverity := map[string]int{}
+	for _, result := range results {
+		for _, finding := range result.Findings {
+			if finding != nil {
+				total++
+				bySeverity[severityOf(finding)]++
+			}
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", l.T("report.title"))
+	fmt.Fprintf(&b, "%s: %s\n\n", l.T("report.generated"), generatedAt.UTC().Format(time.RFC3339))
+
+	fmt.Fprintf(&b, "## %s\n\n", l.T("report.overview"))
+	fmt.Fprintf(&b, "- %s: %d\n", l.T("report.targets"), len(results))
+	fmt.Fprintf(&b, "- %s: %d\n\n", l.T("report.total"), total)
+
+	fmt.Fprintf(&b, "### %s\n\n", l.T("report.by_severity"))
+	fmt.Fprintf(&b, "| %s | %s |\n|---|---|\n", l.T("report.severity"), l.T("report.findings"))
+	for _, severity := range severityOrder {
+		if bySeverity[severity] > 0 {
+			fmt.Fprintf(&b, "| %s | %d |\n", l.Severity(severity), bySeverity[severity])
+		}
+	}
+
+	g.writeCompliance(&b, results)
+
+	fmt.Fprintf(&b, "\n## %s\n", l.T("report.findings"))
+	for _, result := range results {
+		fmt.Fprintf(&b, "\n### %s: %s\n\n", l.T("report.target"), result.Target)
+		fmt.Fprintf(&b, "%s: %s\n\n", l.T("report.scan_time"), result.ScanTime.UTC().Format(time.RFC3339))
+		if len(result.Labels) > 0 {
+			fmt.Fprintf(&b, "%s: %s\n\n", l.T("report.labels"), formatLabels(result.Labels))
+		}
+
+		clusters := g.clusters(sortedFindings(result.Findings))
+		if len(clusters) == 0 {
+			b.WriteString(l.T("report.no_findings") + "\n")
+		}
+		for i, cluster := range clusters {
+			finding := cluster.Finding
+			fmt.Fprintf(&b, "#### %d. %s\n\n", i+1, finding.Info.Name)
+			fmt.Fprintf(&b, "- %s: %s\n", l.T("report.severity"), l.Severity(severityOf(finding)))
+			fmt.Fprintf(&b, "- %s: %s\n", l.T("report.template"), finding.TemplateID)
+			if len(cluster.Endpoints) > 1 {
+				fmt.Fprintf(&b, "- %s:\n", l.T("report.affected", len(cluster.Endpoints)))
+				for _, endpoint := range cluster.Endpoints {
+					fmt.Fprintf(&b, "  - %s\n", endpoint)
+				}
+			} else {
+				fmt.Fprintf(&b, "- %s: %s\n", l.T("report.url"), finding.Matched)
+			}
+			if description := strings.TrimSpace(finding.Info.Description); description != "" {
+				fmt.Fprintf(&b, "- %s: %s\n", l.T("report.description"), description)
+			}
+			if curl := CurlCommand(finding); curl != "" {
+				fmt.Fprintf(&b, "- %s:\n\n```sh\n%s\n```\n", l.T("report.reproduce"), curl)
+			}
+			b.WriteString("\n")
+		}
+
+		if len(result.Extractions) > 0 {
+			fmt.Fprintf(&b, "%s:\n", l.T("report.extracted"))
+			for _, extraction := range result.Extractions {
+				fmt.Fprintf(&b, "- %s: %s\n", extraction.Name, strings.Join(extraction.Values, ", "))
+			}
+		}
+	}
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+// writeCompliance lists the compliance controls the findings of results
+// fall under, per framework
+func (g *Generator) writeCompliance(b *strings.Builder, results []cache.ScanResult) {
+	l := g.localizer
+	var findings []*output.ResultEvent
+	for _, result := range results {
+		findings = append(findings, result.Findings...)
+	}
+	summary := compliance.SummarizeFindings(findings, "")
+	if summary.Findings == summary.Unmapped {
+		return
+	}
+
+	fmt.Fprintf(b, "\n### %s\n", l.T("report.compliance"))
+	for _, framework := range summary.Frameworks {
+		fmt.Fprintf(b, "\n**%s**\n\n", framework.Name)
+		fmt.Fprintf(b, "| %s | %s |\n|---|---|\n", l.T("report.control"), l.T("report.findings"))
+		for _, control := range framework.Controls {
+			fmt.Fprintf(b, "| %s %s | %d |\n", control.ID, control.Name, control.Findings)
+		}
+	}
+	if summary.Unmapped > 0 {
+		fmt.Fprintf(b, "\n%s\n", l.T("report.unmapped", summary.Unmapped))
+	}
+}
+
+// clusters groups findings by root cause when the generator is clustered,
+// otherwise lists each finding on its own
+func (g *Generator) clusters(findings []*output.ResultEvent) []Cluster {
+	if g.clustered {
+		return ClusterFindings(findings)
+	}
+	clusters := make([]Cluster, 0, len(findings))
+	for _, finding := range findings {
+		clusters = append(clusters, Cluster{Finding: finding, Endpoints: []string{Endpoint(finding)}})
+	}
+	return clusters
+}
+
+// formatLabels lists labels as key=value pairs sorted by key
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, fmt.Sprintf("`%s=%s`", key, value))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
+
+func sortedFindings(findings []*output.ResultEvent) []*output.ResultEvent {
+	sorted := make([]*output.ResultEvent, 0, len(findings))
+	for _, finding := range findings {
+		if finding != nil {
+			sorted = append(sorted, finding)
+		}
+	}
+	rank := make(map[string]int, len(severityOrder))
+	for i, severity := range severityOrder {
+		rank[severity] = i
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return rank[severityOf(sorted[i])] < rank[severityOf(sorted[j])]
+	})
+	return sorted
+}
+
+func severityOf(finding *output.ResultEvent) string {
+	name := strings.ToLower(finding.Info.SeverityHolder.Severity.String())
+	for _, severity := range severityOrder {
+		if name == severity {
+			return name
+		}
+	}
+	return "unknown"
+}
@@ -0,0 +1,167 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"nuclei-mcp/pkg/cache"
+)
+
+// sarifSchema is the SARIF 2.1.0 JSON schema location
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+// sarifLevels maps nuclei severities to SARIF result levels
+var sarifLevels = map[string]string{
+	"critical": "error",
+	"high":     "error",
+	"medium":   "warning",
+	"low":      "note",
+	"info":     "note",
+	"unknown":  "none",
+}
+
+// sarifSecurityScores maps nuclei severities to the security-severity
+// scores code scanning tools rank findings by
+var sarifSecurityScores = map[string]string{
+	"critical": "9.5",
+	"high":     "8.0",
+	"medium":   "5.5",
+	"low":      "2.0",
+	"info":     "0.0",
+	"unknown":  "0.0",
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string            `json:"id"`
+	Name                 string            `json:"name,omitempty"`
+	ShortDescription     sarifMessage      `json:"shortDescription"`
+	FullDescription      *sarifMessage     `json:"fullDescription,omitempty"`
+	DefaultConfiguration sarifRuleConfig   `json:"defaultConfiguration"`
+	Properties           sarifRuleProperty `json:"properties"`
+}
+
+type sarifRuleConfig struct {
+	Level string `json:"level"`
+}
+
+type sarifRuleProperty struct {
+	Tags             []string `json:"tags,omitempty"`
+	SecuritySeverity string   `json:"security-severity"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID     string                 `json:"ruleId"`
+	Level      string                 `json:"level"`
+	Message    sarifMessage           `json:"message"`
+	Locations  []sarifLocation        `json:"locations"`
+	Properties *sarifResultProperties `json:"properties,omitempty"`
+}
+
+// sarifResultProperties carries the labels of the scan that reported a
+// result
+type sarifResultProperties struct {
+	Labels map[string]string `json:"labels"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// SARIF renders scan results as a SARIF 2.1.0 log with one rule per
+// template, so findings can be uploaded to code scanning dashboards.
+// engineVersion is reported as the tool driver version when set.
+func SARIF(results []cache.ScanResult, engineVersion string) ([]byte, error) {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "nuclei",
+			Version:        engineVersion,
+			InformationURI: "https://github.com/projectdiscovery/nuclei",
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+
+	rules := map[string]bool{}
+	for _, result := range results {
+		for _, finding := range sortedFindings(result.Findings) {
+			severity := severityOf(finding)
+			if !rules[finding.TemplateID] {
+				rules[finding.TemplateID] = true
+				rule := sarifRule{
+					ID:                   finding.TemplateID,
+					Name:                 finding.Info.Name,
+					ShortDescription:     sarifMessage{Text: finding.Info.Name},
+					DefaultConfiguration: sarifRuleConfig{Level: sarifLevels[severity]},
+					Properties: sarifRuleProperty{
+						Tags:             finding.Info.Tags.ToSlice(),
+						SecuritySeverity: sarifSecurityScores[severity],
+					},
+				}
+				if finding.Info.Description != "" {
+					rule.FullDescription = &sarifMessage{Text: finding.Info.Description}
+				}
+				run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+			}
+
+			location := finding.Matched
+			if location == "" {
+				location = finding.Host
+			}
+			if location == "" {
+				location = result.Target
+			}
+			message := fmt.Sprintf("%s (%s) at %s", finding.Info.Name, severity, location)
+			if finding.MatcherName != "" {
+				message += fmt.Sprintf(" [%s]", finding.MatcherName)
+			}
+			sarif := sarifResult{
+				RuleID:  finding.TemplateID,
+				Level:   sarifLevels[severity],
+				Message: sarifMessage{Text: message},
+				Locations: []sarifLocation{{
+					PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: location}},
+				}},
+			}
+			if len(result.Labels) > 0 {
+				sarif.Properties = &sarifResultProperties{Labels: result.Labels}
+			}
+			run.Results = append(run.Results, sarif)
+		}
+	}
+
+	return json.MarshalIndent(sarifLog{Schema: sarifSchema, Version: "2.1.0", Runs: []sarifRun{run}}, "", "  ")
+}
@@ -0,0 +1,235 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"nuclei-mcp/pkg/scanner"
+	"nuclei-mcp/pkg/templates"
+
+	nuclei "github.com/projectdiscovery/nuclei/v3/lib"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	nucleitemplates "github.com/projectdiscovery/nuclei/v3/pkg/templates"
+)
+
+// Response is a canned HTTP response served by the sandbox server
+type Response struct {
+	Method  string            `json:"method,omitempty"`
+	Path    string            `json:"path,omitempty"`
+	Status  int               `json:"status,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+}
+
+// Request records a request received by the sandbox server
+type Request struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Status int    `json:"status"`
+}
+
+// exchange pairs a received request with the response that was served
+type exchange struct {
+	request  Request
+	url      string
+	response Response
+}
+
+// Server is an in-process HTTP server that answers with canned responses
+type Server struct {
+	server    *httptest.Server
+	responses []Response
+	exchanges []exchange
+	mu        sync.Mutex
+}
+
+// NewServer starts a sandbox server serving the given responses. A response
+// matches when its method and path are empty or equal to the request's; the
+// first match wins. Unmatched requests receive a 404.
+func NewServer(responses []Response) *Server {
+	s := &Server{responses: responses}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// URL returns the base URL of the server
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// Requests returns the requests received so far
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	requests := make([]Request, 0, len(s.exchanges))
+	for _, ex := range s.exchanges {
+		requests = append(requests, ex.request)
+	}
+	return requests
+}
+
+// Close shuts the server down
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	resp := Response{Status: http.StatusNotFound, Body: "404 page not found\n"}
+	for _, candidate := range s.responses {
+		if candidate.Method != "" && !strings.EqualFold(candidate.Method, r.Method) {
+			continue
+		}
+		if candidate.Path != "" && candidate.Path != r.URL.Path && candidate.Path != r.URL.RequestURI() {
+			continue
+		}
+		resp = candidate
+		break
+	}
+	if resp.Status == 0 {
+		resp.Status = http.StatusOK
+	}
+
+	s.mu.Lock()
+	s.exchanges = append(s.exchanges, exchange{
+		request:  Request{Method: r.Method, Path: r.URL.RequestURI(), Status: resp.Status},
+		url:      s.server.URL + r.URL.RequestURI(),
+		response: resp,
+	})
+	s.mu.Unlock()
+
+	for name, value := range resp.Headers {
+		w.Header().Set(name, value)
+	}
+	w.WriteHeader(resp.Status)
+	_, _ = w.Write([]byte(resp.Body))
+}
+
+// Match describes a single template match against the sandbox server
+type Match struct {
+	TemplateID       string   `json:"template_id"`
+	MatcherName      string   `json:"matcher_name,omitempty"`
+	ExtractorName    string   `json:"extractor_name,omitempty"`
+	MatchedAt        string   `json:"matched_at"`
+	ExtractedResults []string `json:"extracted_results,omitempty"`
+}
+
+// Result is the outcome of running a template in the sandbox
+type Result struct {
+	TemplateID string    `json:"template_id"`
+	Matched    bool      `json:"matched"`
+	Matches    []Match   `json:"matches"`
+	Requests   []Request `json:"requests"`
+	Trace      []Trace   `json:"trace,omitempty"`
+}
+
+// Options controls a sandbox run
+type Options struct {
+	// Responses served by the sandbox server. When empty the server answers
+	// every request with an empty 200.
+	Responses []Response
+	// Trace evaluates every matcher and extractor against every served
+	// response and reports the individual outcomes.
+	Trace bool
+}
+
+// httpOnly reports whether template only sends HTTP requests, the only ones
+// the sandbox server answers
+func httpOnly(template *nucleitemplates.Template) bool {
+	others := len(template.RequestsDNS) + len(template.RequestsFile) + len(template.RequestsHeadless) + len(template.RequestsNetwork) +
+		len(template.RequestsSSL) + len(template.RequestsWebsocket) + len(template.RequestsWHOIS) + len(template.RequestsCode) +
+		len(template.RequestsJavascript) + len(template.Workflows)
+	return len(template.RequestsHTTP) > 0 && others == 0
+}
+
+// Run executes the template content against a sandbox server. Only HTTP
+// templates run, so they cannot reach hosts other than the sandbox.
+func Run(ctx context.Context, template []byte, opts Options) (Result, error) {
+	responses := opts.Responses
+	if len(responses) == 0 {
+		responses = []Response{{Status: http.StatusOK}}
+	}
+
+	srv := NewServer(responses)
+	defer srv.Close()
+
+	dir, err := os.MkdirTemp("", "nuclei-mcp-sandbox-")
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to create sandbox directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	templatePath := filepath.Join(dir, "template."+templates.DetectFormat(template))
+	if err := os.WriteFile(templatePath, template, 0644); err != nil {
+		return Result{}, fmt.Errorf("failed to write template: %w", err)
+	}
+
+	// Closing the engine resets nuclei's shared protocol state, which must
+	// not happen under running scans
+	unlock := scanner.LockEngines()
+	defer unlock()
+
+	ne, err := nuclei.NewNucleiEngineCtx(ctx,
+		nuclei.DisableUpdateCheck(),
+		nuclei.WithTemplatesOrWorkflows(nuclei.TemplateSources{Templates: []string{templatePath}}),
+	)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to create nuclei engine: %w", err)
+	}
+	defer ne.Close()
+
+	if err := ne.LoadAllTemplates(); err != nil {
+		return Result{}, fmt.Errorf("failed to load template: %w", err)
+	}
+
+	loaded := ne.GetTemplates()
+	if len(loaded) == 0 {
+		return Result{}, fmt.Errorf("template could not be loaded, check that it is valid nuclei syntax")
+	}
+	// Other protocols name their own hosts rather than the sandbox server
+	if !httpOnly(loaded[0]) {
+		return Result{}, fmt.Errorf("the sandbox only runs HTTP templates, %s uses the %s protocol", loaded[0].ID, loaded[0].Type())
+	}
+
+	ne.LoadTargets([]string{srv.URL()}, false)
+
+	result := Result{
+		TemplateID: loaded[0].ID,
+		Matches:    []Match{},
+	}
+	var resultMutex sync.Mutex
+
+	err = ne.ExecuteCallbackWithCtx(ctx, func(event *output.ResultEvent) {
+		resultMutex.Lock()
+		defer resultMutex.Unlock()
+		result.Matched = true
+		result.Matches = append(result.Matches, Match{
+			TemplateID:       event.TemplateID,
+			MatcherName:      event.MatcherName,
+			ExtractorName:    event.ExtractorName,
+			MatchedAt:        event.Matched,
+			ExtractedResults: event.ExtractedResults,
+		})
+	})
+	if err != nil {
+		return Result{}, fmt.Errorf("template execution failed: %w", err)
+	}
+
+	result.Requests = srv.Requests()
+	if opts.Trace {
+		result.Trace = traceTemplate(loaded[0], srv.exchangesSnapshot())
+	}
+	return result, nil
+}
+
+func (s *Server) exchangesSnapshot() []exchange {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]exchange(nil), s.exchanges...)
+}
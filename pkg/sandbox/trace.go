@@ -0,0 +1,169 @@
+package sandbox
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/operators/matchers"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	protocolhttp "github.com/projectdiscovery/nuclei/v3/pkg/protocols/http"
+	"github.com/projectdiscovery/nuclei/v3/pkg/templates"
+)
+
+// Trace is the evaluation of every HTTP block of a template against one
+// response served by the sandbox
+type Trace struct {
+	Request Request      `json:"request"`
+	Blocks  []BlockTrace `json:"blocks"`
+}
+
+// BlockTrace is the evaluation of the operators of one HTTP block
+type BlockTrace struct {
+	Block      int              `json:"block"`
+	Condition  string           `json:"matchers_condition"`
+	Matched    bool             `json:"matched"`
+	Matchers   []MatcherTrace   `json:"matchers"`
+	Extractors []ExtractorTrace `json:"extractors,omitempty"`
+}
+
+// MatcherTrace is the outcome of a single matcher
+type MatcherTrace struct {
+	Index    int      `json:"index"`
+	Name     string   `json:"name,omitempty"`
+	Type     string   `json:"type"`
+	Part     string   `json:"part"`
+	Negative bool     `json:"negative,omitempty"`
+	Internal bool     `json:"internal,omitempty"`
+	Matched  bool     `json:"matched"`
+	Snippets []string `json:"snippets,omitempty"`
+}
+
+// ExtractorTrace is the outcome of a single extractor
+type ExtractorTrace struct {
+	Index    int      `json:"index"`
+	Name     string   `json:"name,omitempty"`
+	Type     string   `json:"type"`
+	Part     string   `json:"part"`
+	Internal bool     `json:"internal,omitempty"`
+	Values   []string `json:"values"`
+}
+
+// traceTemplate evaluates the compiled HTTP operators of the template against
+// every served exchange. Exchanges are not attributed to a specific block, so
+// each block is evaluated against every response.
+func traceTemplate(template *templates.Template, exchanges []exchange) []Trace {
+	traces := make([]Trace, 0, len(exchanges))
+	for _, ex := range exchanges {
+		data := responseToDSLMap(ex)
+
+		trace := Trace{Request: ex.request, Blocks: []BlockTrace{}}
+		for i, request := range template.RequestsHTTP {
+			if request.CompiledOperators == nil {
+				continue
+			}
+			trace.Blocks = append(trace.Blocks, traceBlock(i, request, data))
+		}
+		traces = append(traces, trace)
+	}
+	return traces
+}
+
+func traceBlock(index int, request *protocolhttp.Request, data output.InternalEvent) BlockTrace {
+	operators := request.CompiledOperators
+	condition := operators.GetMatchersCondition()
+
+	block := BlockTrace{
+		Block:     index,
+		Condition: conditionName(condition),
+		Matchers:  []MatcherTrace{},
+		Matched:   condition == matchers.ANDCondition && len(operators.Matchers) > 0,
+	}
+
+	for i, matcher := range operators.Matchers {
+		matched, snippets := request.Match(data, matcher)
+		block.Matchers = append(block.Matchers, MatcherTrace{
+			Index:    i,
+			Name:     matcher.Name,
+			Type:     matcher.GetType().String(),
+			Part:     partName(matcher.Part),
+			Negative: matcher.Negative,
+			Internal: matcher.Internal,
+			Matched:  matched,
+			Snippets: snippets,
+		})
+
+		if condition == matchers.ANDCondition {
+			block.Matched = block.Matched && matched
+		} else {
+			block.Matched = block.Matched || matched
+		}
+	}
+
+	for i, extractor := range operators.Extractors {
+		extracted := request.Extract(data, extractor)
+		values := make([]string, 0, len(extracted))
+		for value := range extracted {
+			values = append(values, value)
+		}
+		sort.Strings(values)
+
+		block.Extractors = append(block.Extractors, ExtractorTrace{
+			Index:    i,
+			Name:     extractor.Name,
+			Type:     extractor.GetType().String(),
+			Part:     partName(extractor.Part),
+			Internal: extractor.Internal,
+			Values:   values,
+		})
+	}
+
+	return block
+}
+
+// responseToDSLMap builds the data map nuclei's HTTP protocol matches against
+func responseToDSLMap(ex exchange) output.InternalEvent {
+	var headers strings.Builder
+	names := make([]string, 0, len(ex.response.Headers))
+	for name := range ex.response.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		headers.WriteString(fmt.Sprintf("%s: %s\r\n", http.CanonicalHeaderKey(name), ex.response.Headers[name]))
+	}
+
+	rawResponse := fmt.Sprintf("HTTP/1.1 %d %s\r\n%s\r\n%s",
+		ex.response.Status, http.StatusText(ex.response.Status), headers.String(), ex.response.Body)
+
+	data := output.InternalEvent{}
+	for name, value := range ex.response.Headers {
+		data[strings.ToLower(strings.ReplaceAll(strings.TrimSpace(name), "-", "_"))] = value
+	}
+	data["host"] = ex.url
+	data["matched"] = ex.url
+	data["type"] = "http"
+	data["status_code"] = ex.response.Status
+	data["body"] = ex.response.Body
+	data["all_headers"] = headers.String()
+	data["header"] = headers.String()
+	data["response"] = rawResponse
+	data["content_length"] = len(ex.response.Body)
+	data["duration"] = 0.0
+	return data
+}
+
+func conditionName(condition matchers.ConditionType) string {
+	if condition == matchers.ANDCondition {
+		return "and"
+	}
+	return "or"
+}
+
+func partName(part string) string {
+	if part == "" {
+		return "body"
+	}
+	return part
+}
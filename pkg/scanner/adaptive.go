@@ -0,0 +1,326 @@
+package scanner
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+
+	nuclei "github.com/projectdiscovery/nuclei/v3/lib"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	"github.com/projectdiscovery/nuclei/v3/pkg/templates"
+	"github.com/projectdiscovery/ratelimit"
+)
+
+const (
+	// DefaultAdaptiveErrorRate is the share of failed requests above which
+	// adaptive tuning slows a host down
+	DefaultAdaptiveErrorRate = 0.3
+	// DefaultAdaptiveWindow is the number of requests the error rate is
+	// measured over
+	DefaultAdaptiveWindow = 20
+	// DefaultAdaptiveMinRateLimit is the lowest requests per second adaptive
+	// tuning reduces a host to
+	DefaultAdaptiveMinRateLimit = 1
+
+	// defaultRateLimit is nuclei's requests per second when a scan sets none
+	defaultRateLimit = 150
+)
+
+// AdaptiveTuning configures how scans slow down on hosts that fail requests
+type AdaptiveTuning struct {
+	// ErrorRate is the share of failed requests, between 0 and 1, above
+	// which the rate limit and concurrency of the host are halved
+	ErrorRate float64
+	// Window is the number of requests the error rate is measured over
+	Window int
+	// MinRateLimit is the lowest requests per second a host is reduced to
+	MinRateLimit int
+}
+
+// WithAdaptiveTuning monitors the requests of scans and halves the rate limit
+// and concurrency for a host whose requests keep failing or timing out, so
+// scans do not knock over fragile targets. The reduced settings apply to the
+// rest of the scan and to later scans of the host for the lifetime of the
+// service.
+func WithAdaptiveTuning(enabled bool, tuning AdaptiveTuning) ServiceOption {
+	return func(s *scannerServiceImpl) {
+		if !enabled {
+			s.adaptive = nil
+			return
+		}
+		if tuning.ErrorRate <= 0 || tuning.ErrorRate >= 1 {
+			tuning.ErrorRate = DefaultAdaptiveErrorRate
+		}
+		if tuning.Window < 1 {
+			tuning.Window = DefaultAdaptiveWindow
+		}
+		if tuning.MinRateLimit < 1 {
+			tuning.MinRateLimit = DefaultAdaptiveMinRateLimit
+		}
+		s.adaptive = &adaptiveTuner{AdaptiveTuning: tuning, hosts: map[string]cache.Adjustment{}}
+	}
+}
+
+// adaptiveTuner keeps the latest adjustment of each host
+type adaptiveTuner struct {
+	AdaptiveTuning
+
+	mu    sync.Mutex
+	hosts map[string]cache.Adjustment
+}
+
+// lookup returns the adjustment in effect for the host of target, or nil
+func (t *adaptiveTuner) lookup(target string) *cache.Adjustment {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if adjustment, ok := t.hosts[TargetHost(target)]; ok {
+		return &adjustment
+	}
+	return nil
+}
+
+func (t *adaptiveTuner) record(adjustment cache.Adjustment) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.hosts[adjustment.Host] = adjustment
+}
+
+// scanRateLimit returns the requests per second of a scan: the passive
+// rate limit for passive scans, else the scan's rate limit or nuclei's
+// default, lowered by adaptive tuning
+func (s *scannerServiceImpl) scanRateLimit(scanOpts ScanOptions) int {
+	if scanOpts.Passive {
+		passiveRateLimit := s.passiveRateLimit
+		if s.maxRateLimit > 0 && s.maxRateLimit < passiveRateLimit {
+			passiveRateLimit = s.maxRateLimit
+		}
+		return scanOpts.tunedRateLimit(passiveRateLimit)
+	}
+	if rateLimit := scanOpts.tunedRateLimit(scanOpts.RateLimit); rateLimit > 0 {
+		return rateLimit
+	}
+	return defaultRateLimit
+}
+
+// tunedRateLimit returns the lower of rateLimit (zero being nuclei's
+// default) and the host's reduced rate limit
+func (o ScanOptions) tunedRateLimit(rateLimit int) int {
+	if o.tuning == nil {
+		return rateLimit
+	}
+	if rateLimit == 0 || o.tuning.RateLimit < rateLimit {
+		return o.tuning.RateLimit
+	}
+	return rateLimit
+}
+
+// concurrencyOption lowers the number of templates run at once
+func concurrencyOption(concurrency int) nuclei.NucleiSDKOptions {
+	return func(e *nuclei.NucleiEngine) error {
+		opts := e.Options()
+		if concurrency < opts.TemplateThreads {
+			opts.TemplateThreads = concurrency
+		}
+		return nil
+	}
+}
+
+// scanMonitor receives nuclei's per-request trace and dumps as the engine's
+// output writer, or through a monitorRelay for thread-safe engines. It counts the requests of a scan and their bytes, and when
+// adaptive tuning is enabled reduces the engine's rate limit when too many
+// requests of a window fail. Results are still delivered by the engine's
+// callback.
+type scanMonitor struct {
+	output.Writer
+	tuner   *adaptiveTuner
+	console LoggerInterface
+	host    string
+
+	mu            sync.Mutex
+	limiter       *ratelimit.Limiter
+	rateLimit     int
+	concurrency   int
+	requests      int
+	errors        int
+	bytesSent     int64
+	bytesReceived int64
+	window        int
+	windowErrors  int
+	failures      failureCounter
+	adjustments   []cache.Adjustment
+}
+
+// monitor returns a monitor for a scan of target, tuned by tuner when it is
+// not nil
+func (t *adaptiveTuner) monitor(target string, scanOpts ScanOptions, console LoggerInterface) *scanMonitor {
+	m := &scanMonitor{Writer: output.NewMultiWriter(), console: console, host: TargetHost(target), failures: failureCounter{}}
+	if t == nil {
+		return m
+	}
+	m.tuner = t
+	if scanOpts.tuning != nil {
+		m.adjustments = append(m.adjustments, *scanOpts.tuning)
+	}
+	return m
+}
+
+// attach takes the rate limit and concurrency the engine starts with
+func (m *scanMonitor) attach(ne *nuclei.NucleiEngine) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.limiter = ne.GetExecuterOptions().RateLimiter
+	m.rateLimit = defaultRateLimit
+	if m.limiter != nil && m.limiter.GetLimit() < math.MaxUint32 {
+		m.rateLimit = int(m.limiter.GetLimit())
+	}
+	m.concurrency = ne.Options().TemplateThreads
+}
+
+// tuned reports whether the scan starts at the reduced settings of its host
+func (m *scanMonitor) tuned() bool {
+	return m != nil && len(m.adjustments) > 0
+}
+
+// watch has the templates a thread-safe engine compiled report to the
+// monitor, and takes the rate limit and concurrency they run at
+func (m *scanMonitor) watch(ne *nuclei.NucleiEngine) {
+	if m == nil {
+		return
+	}
+	relayTemplates(ne.GetTemplates(), &monitorRelay{monitor: m})
+	m.attach(ne)
+}
+
+// monitorRelay passes the requests and traffic the templates of a
+// thread-safe engine report to the monitor of the scan running on them
+type monitorRelay struct {
+	mu      sync.Mutex
+	monitor *scanMonitor
+}
+
+// set routes the relayed requests to monitor, or drops them when it is nil
+func (r *monitorRelay) set(monitor *scanMonitor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.monitor = monitor
+}
+
+func (r *monitorRelay) current() *scanMonitor {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.monitor
+}
+
+// relayWriter is the output writer of a template that also reports to a
+// monitor relay
+type relayWriter struct {
+	output.Writer
+	relay *monitorRelay
+}
+
+// relayTemplates has loaded report their requests and traffic to relay.
+// Thread-safe engines take no output writer, and their scans run the
+// templates compiled when the engine loaded them, so the writer of each
+// template is wrapped instead.
+func relayTemplates(loaded []*templates.Template, relay *monitorRelay) {
+	for _, template := range loaded {
+		if template.Options == nil || template.Options.Output == nil {
+			continue
+		}
+		if _, relayed := template.Options.Output.(*relayWriter); !relayed {
+			template.Options.Output = &relayWriter{Writer: template.Options.Output, relay: relay}
+		}
+	}
+}
+
+func (w *relayWriter) Request(templateID, url, requestType string, err error) {
+	w.Writer.Request(templateID, url, requestType, err)
+	if monitor := w.relay.current(); monitor != nil {
+		monitor.Request(templateID, url, requestType, err)
+	}
+}
+
+func (w *relayWriter) WriteStoreDebugData(host, templateID, eventType string, data string) {
+	w.Writer.WriteStoreDebugData(host, templateID, eventType, data)
+	if monitor := w.relay.current(); monitor != nil {
+		monitor.WriteStoreDebugData(host, templateID, eventType, data)
+	}
+}
+
+// Request counts each request nuclei sends and whether and why it failed.
+// nuclei reports failed requests here rather than to its failure callback,
+// which only runs with matcher status output.
+func (m *scanMonitor) Request(templateID, url, requestType string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests++
+	m.window++
+	m.failures.count(url, err)
+	if err != nil {
+		m.errors++
+		m.windowErrors++
+	}
+	if m.tuner == nil || m.window < m.tuner.Window {
+		return
+	}
+
+	failed, total := m.windowErrors, m.window
+	m.window, m.windowErrors = 0, 0
+	if float64(failed)/float64(total) > m.tuner.ErrorRate {
+		m.reduce(fmt.Sprintf("%d of %d requests failed", failed, total))
+	}
+}
+
+// reduce halves the rate limit and concurrency, down to the minimum. The
+// running engine's rate limit changes at once; the concurrency applies to
+// later scans of the host.
+func (m *scanMonitor) reduce(reason string) {
+	rateLimit := max(m.rateLimit/2, m.tuner.MinRateLimit)
+	rateLimit = min(rateLimit, m.rateLimit)
+	concurrency := max(m.concurrency/2, 1)
+	if rateLimit == m.rateLimit && concurrency == m.concurrency {
+		return
+	}
+	m.rateLimit, m.concurrency = rateLimit, concurrency
+	if m.limiter != nil {
+		m.limiter.SetLimit(uint(rateLimit))
+	}
+
+	adjustment := cache.Adjustment{
+		Time:        time.Now(),
+		Host:        m.host,
+		Reason:      reason,
+		RateLimit:   rateLimit,
+		Concurrency: concurrency,
+	}
+	m.adjustments = append(m.adjustments, adjustment)
+	m.tuner.record(adjustment)
+	m.console.Log("Adaptive tuning: %s on %s, rate limit reduced to %d requests/s and concurrency to %d", reason, m.host, rateLimit, concurrency)
+}
+
+// stats returns the request counts, failures, traffic and adjustments of
+// the scan
+func (m *scanMonitor) stats() *cache.ScanStats {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return &cache.ScanStats{
+		Requests:      m.requests,
+		Errors:        m.errors,
+		BytesSent:     m.bytesSent,
+		BytesReceived: m.bytesReceived,
+		Adjustments:   append([]cache.Adjustment(nil), m.adjustments...),
+		Failures:      m.failures.summary(),
+	}
+}
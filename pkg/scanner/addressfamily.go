@@ -0,0 +1,105 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// AddressFamily selects the IP versions a scan connects to a host name over
+type AddressFamily string
+
+const (
+	// AddressFamilyAny connects over the address the resolver returns first
+	AddressFamilyAny AddressFamily = ""
+	// AddressFamilyV4 connects over IPv4 only
+	AddressFamilyV4 AddressFamily = "v4"
+	// AddressFamilyV6 connects over IPv6 only
+	AddressFamilyV6 AddressFamily = "v6"
+	// AddressFamilyDual runs HTTP templates over both IPv4 and IPv6, for
+	// dual-stack hosts that serve each differently
+	AddressFamilyDual AddressFamily = "dual"
+)
+
+var addressFamilies = []AddressFamily{AddressFamilyV4, AddressFamilyV6, AddressFamilyDual}
+
+// ParseAddressFamily parses v4, v6 or dual; an empty name leaves the choice
+// to the resolver
+func ParseAddressFamily(name string) (AddressFamily, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return AddressFamilyAny, nil
+	}
+	for _, family := range addressFamilies {
+		if AddressFamily(name) == family {
+			return family, nil
+		}
+	}
+	return AddressFamilyAny, fmt.Errorf("%w %q, use v4, v6 or dual", ErrInvalidAddressFamily, name)
+}
+
+// WithAddressFamily connects to the target's host name over the given IP
+// versions. HTTP templates connect to the chosen address; other protocols
+// connect over the address the resolver returns.
+func WithAddressFamily(family AddressFamily) ScanOption {
+	return func(o *ScanOptions) {
+		o.AddressFamily = family
+	}
+}
+
+// resolveAddresses returns the addresses the HTTP templates of a scan of
+// target connect to, or nil to let the engine resolve the target. Targets
+// given as an IP address must match the family.
+func resolveAddresses(ctx context.Context, target string, family AddressFamily) ([]string, error) {
+	if family == AddressFamilyAny {
+		return nil, nil
+	}
+	host := TargetHost(target)
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addr = addr.Unmap()
+		switch {
+		case family == AddressFamilyV4 && !addr.Is4():
+			return nil, fmt.Errorf("%w: %s is not an IPv4 address", ErrNoTargets, host)
+		case family == AddressFamilyV6 && !addr.Is6():
+			return nil, fmt.Errorf("%w: %s is not an IPv6 address", ErrNoTargets, host)
+		}
+		return nil, nil
+	}
+
+	var addresses []string
+	lookup := func(network string) {
+		if addrs, err := net.DefaultResolver.LookupNetIP(ctx, network, host); err == nil && len(addrs) > 0 {
+			addresses = append(addresses, addrs[0].Unmap().String())
+		}
+	}
+	if family != AddressFamilyV6 {
+		lookup("ip4")
+	}
+	if family != AddressFamilyV4 {
+		lookup("ip6")
+	}
+	if len(addresses) == 0 {
+		version := map[AddressFamily]string{AddressFamilyV4: "IPv4", AddressFamilyV6: "IPv6", AddressFamilyDual: "IP"}[family]
+		return nil, fmt.Errorf("%w: %s has no %s address", ErrNoTargets, host, version)
+	}
+	return addresses, nil
+}
+
+type scanAddressesKey struct{}
+
+// withScanAddresses returns ctx carrying the addresses HTTP templates of the
+// scan connect to
+func withScanAddresses(ctx context.Context, addresses []string) context.Context {
+	if len(addresses) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, scanAddressesKey{}, addresses)
+}
+
+// scanAddressesFrom returns the addresses of the scan running ctx
+func scanAddressesFrom(ctx context.Context) []string {
+	addresses, _ := ctx.Value(scanAddressesKey{}).([]string)
+	return addresses
+}
@@ -0,0 +1,115 @@
+package scanner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+)
+
+// Limits of the request fingerprinting a target
+const (
+	fingerprintTimeout = 10 * time.Second
+	// fingerprintMaxBody is the most bytes of the response body hashed
+	fingerprintMaxBody = 1 << 20
+)
+
+// etagPrefix marks fingerprints taken from a response's ETag
+const etagPrefix = "etag:"
+
+// WithSkipUnchanged checks whether the target's response changed since the
+// cached result of the same scan, even an expired one, and returns that
+// result instead of scanning when it did not
+func WithSkipUnchanged() ScanOption {
+	return func(o *ScanOptions) {
+		o.SkipUnchanged = true
+	}
+}
+
+// resultPeeker is implemented by caches that return expired results
+type resultPeeker interface {
+	Peek(key string) (cache.ScanResult, bool)
+}
+
+// TargetFingerprint requests target, or https://target for a bare host, with
+// client and identifies its response by its status and ETag, or else by its status,
+// Last-Modified header and a hash of its body. With the previous
+// fingerprint of an ETag, the request is conditional and a 304 response
+// keeps it. Redirects are not followed.
+func TargetFingerprint(ctx context.Context, client *http.Client, target string, previous string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, fingerprintTimeout)
+	defer cancel()
+
+	base := target
+	if !strings.Contains(base, "://") {
+		base = "https://" + base
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to fingerprint target: %w", err)
+	}
+	if etag, ok := strings.CutPrefix(previous, etagPrefix); ok {
+		if _, etag, ok = strings.Cut(etag, ":"); ok {
+			req.Header.Set("If-None-Match", etag)
+		}
+	}
+	resp, err := withoutRedirects(client).Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fingerprint target: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && req.Header.Get("If-None-Match") != "" {
+		return previous, nil
+	}
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		return fmt.Sprintf("%s%d:%s", etagPrefix, resp.StatusCode, etag), nil
+	}
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%d\n%s\n", resp.StatusCode, resp.Header.Get("Last-Modified"))
+	if _, err := io.Copy(hash, io.LimitReader(resp.Body, fingerprintMaxBody)); err != nil {
+		return "", fmt.Errorf("failed to fingerprint target: %w", err)
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// CheckUnchanged fingerprints target with client for a scan with
+// SkipUnchanged. When the
+// fingerprint matches that of the result cached under key, even an expired
+// one, the result is stored again as checked now and returned with true.
+// Otherwise the fingerprint is returned for the result of the scan that
+// follows; it is empty when the target could not be fingerprinted.
+func CheckUnchanged(ctx context.Context, console LoggerInterface, client *http.Client, results CacheInterface, key string, target string) (cache.ScanResult, string, bool) {
+	var previous cache.ScanResult
+	var found bool
+	if peeker, ok := results.(resultPeeker); ok {
+		previous, found = peeker.Peek(key)
+	} else {
+		previous, found = results.Get(key)
+	}
+
+	fingerprint, err := TargetFingerprint(ctx, client, target, previous.TargetFingerprint)
+	if err != nil {
+		console.Log("Change detection for %s failed, scanning it: %v", target, err)
+		return cache.ScanResult{}, "", false
+	}
+	if !found || previous.TargetFingerprint != fingerprint {
+		return cache.ScanResult{}, fingerprint, false
+	}
+
+	since := previous.ScanTime
+	if previous.UnchangedSince != nil {
+		since = *previous.UnchangedSince
+	}
+	previous.UnchangedSince = &since
+	previous.ScanTime = time.Now()
+	results.Set(key, previous)
+	console.Log("Target %s unchanged since %s, returning its previous result (%d findings)", target, since.Format(time.RFC3339), len(previous.Findings))
+	return previous, fingerprint, true
+}
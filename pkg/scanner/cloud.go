@@ -0,0 +1,60 @@
+package scanner
+
+import (
+	"strings"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+)
+
+// CloudTags are the template tags of the cloud and Kubernetes exposure
+// preset: exposed cluster dashboards and APIs, instance metadata endpoints
+// and public storage buckets
+var CloudTags = []string{
+	"cloud", "kubernetes", "k8s", "kubelet", "etcd", "docker", "helm",
+	"aws", "amazon", "s3", "azure", "gcp", "google-cloud", "alibaba", "aliyun",
+	"digitalocean", "oracle", "bucket", "metadata",
+}
+
+// cloudProviders maps the template tags naming a provider to it, in the
+// order they are looked up
+var cloudProviders = []struct {
+	provider string
+	tags     []string
+	// domains are the host suffixes of the provider's services
+	domains []string
+}{
+	{"aws", []string{"aws", "amazon", "s3"}, []string{".amazonaws.com", ".cloudfront.net", ".awsapps.com"}},
+	{"azure", []string{"azure", "microsoft-azure"}, []string{".windows.net", ".azurewebsites.net", ".azure.com", ".azurecr.io", ".azureedge.net"}},
+	{"gcp", []string{"gcp", "google-cloud", "gcloud"}, []string{".googleapis.com", ".appspot.com", ".run.app", ".cloudfunctions.net"}},
+	{"alibaba", []string{"alibaba", "aliyun"}, []string{".aliyuncs.com"}},
+	{"digitalocean", []string{"digitalocean"}, []string{".digitaloceanspaces.com", ".ondigitalocean.app"}},
+	{"oracle", []string{"oracle", "oci"}, []string{".oraclecloud.com"}},
+	{"kubernetes", []string{"kubernetes", "k8s", "kubelet", "etcd", "helm"}, nil},
+}
+
+// CloudProvider returns the cloud provider a finding concerns, from the
+// host it was found on or else the tags of its template: aws, azure, gcp,
+// alibaba, digitalocean or oracle, kubernetes for cluster findings of no
+// provider, or empty when unknown
+func CloudProvider(finding *output.ResultEvent) string {
+	tags := map[string]bool{}
+	for _, tag := range finding.Info.Tags.ToSlice() {
+		tags[strings.ToLower(tag)] = true
+	}
+	host := strings.ToLower(TargetHost(finding.Host))
+	for _, provider := range cloudProviders {
+		for _, domain := range provider.domains {
+			if strings.HasSuffix(host, domain) {
+				return provider.provider
+			}
+		}
+	}
+	for _, provider := range cloudProviders {
+		for _, tag := range provider.tags {
+			if tags[tag] {
+				return provider.provider
+			}
+		}
+	}
+	return ""
+}
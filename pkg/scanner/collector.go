@@ -0,0 +1,209 @@
+package scanner
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+
+	"nuclei-mcp/pkg/encryption"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+)
+
+const (
+	// DefaultResultBuffer is the number of findings buffered between nuclei's
+	// result callback and the collector before the callback blocks
+	DefaultResultBuffer = 1024
+	// DefaultSpillThreshold is the number of findings kept in full in memory;
+	// findings beyond it are written to a spill file
+	DefaultSpillThreshold = 5000
+)
+
+// WithResultBuffer sets how many findings are buffered before nuclei's result
+// callback blocks, slowing the scan down instead of growing memory
+func WithResultBuffer(size int) ServiceOption {
+	return func(s *scannerServiceImpl) {
+		if size > 0 {
+			s.resultBuffer = size
+		}
+	}
+}
+
+// WithSpillThreshold keeps the first threshold findings of a scan in memory
+// and writes later findings to a file in dir (the system temp directory when
+// empty). The result only counts the spilled findings; the file is removed
+// when the result leaves the cache.
+func WithSpillThreshold(threshold int, dir string) ServiceOption {
+	return func(s *scannerServiceImpl) {
+		if threshold > 0 {
+			s.spillThreshold = threshold
+		}
+		s.spillDir = dir
+	}
+}
+
+// WithEncryptionKey encrypts the full findings written to spill files, one
+// record per line, so findings are not stored on disk in plain text
+func WithEncryptionKey(key *encryption.Key) ServiceOption {
+	return func(s *scannerServiceImpl) {
+		s.encryption = key
+	}
+}
+
+// findingCollector receives findings from nuclei through a bounded channel
+// and stores them from a single writer goroutine, so result callbacks do not
+// contend on a lock and a flood of findings applies backpressure to the scan
+type findingCollector struct {
+	console   LoggerInterface
+	id        string
+	threshold int
+	spillDir  string
+	key       *encryption.Key
+
+	mu     sync.RWMutex
+	closed bool
+	events chan *output.ResultEvent
+	done   chan struct{}
+
+	findings  []*output.ResultEvent
+	spilled   int
+	spillPath string
+	spill     *os.File
+	writer    *bufio.Writer
+	encoder   *json.Encoder
+	err       error
+	// kept is set once the spill file was handed to the result cache
+	kept bool
+}
+
+// newCollector starts a collector for one scan, logging to console and
+// tagging findings with the scan's correlation ID
+func (s *scannerServiceImpl) newCollector(console LoggerInterface, id string) *findingCollector {
+	c := &findingCollector{
+		console:   console,
+		id:        id,
+		threshold: s.spillThreshold,
+		spillDir:  s.spillDir,
+		key:       s.encryption,
+		events:    make(chan *output.ResultEvent, s.resultBuffer),
+		done:      make(chan struct{}),
+	}
+	go c.run()
+	return c
+}
+
+// collect is the nuclei result callback. It blocks while the buffer is full.
+func (c *findingCollector) collect(event *output.ResultEvent) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.closed || event == nil {
+		return
+	}
+	c.events <- event
+}
+
+func (c *findingCollector) run() {
+	defer close(c.done)
+	for event := range c.events {
+		c.store(event)
+	}
+}
+
+// store keeps one finding. A malformed finding that panics is dropped so
+// the collector keeps draining the buffer.
+func (c *findingCollector) store(event *output.ResultEvent) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			c.console.Log("Dropped malformed finding: %v", recovered)
+		}
+	}()
+
+	correlate(event, c.id)
+	c.console.Log("Found vulnerability: %s (%s) on %s", event.Info.Name, event.Info.SeverityHolder.Severity.String(), event.Host)
+	if len(c.findings) < c.threshold || c.err != nil {
+		c.findings = append(c.findings, event)
+		return
+	}
+	if err := c.spillEvent(event); err != nil {
+		c.err = err
+		c.console.Log("Failed to spill findings to disk, keeping them in memory: %v", err)
+		c.findings = append(c.findings, event)
+		return
+	}
+	c.spilled++
+}
+
+func (c *findingCollector) spillEvent(event *output.ResultEvent) error {
+	if c.spill == nil {
+		pattern := "nuclei-mcp-findings-*.jsonl"
+		if c.key != nil {
+			pattern += ".enc"
+		}
+		file, err := os.CreateTemp(c.spillDir, pattern)
+		if err != nil {
+			return err
+		}
+		c.spill = file
+		c.spillPath = file.Name()
+		c.writer = bufio.NewWriter(file)
+		c.encoder = json.NewEncoder(c.writer)
+		c.console.Log("More than %d findings, spilling full findings to %s", c.threshold, c.spillPath)
+	}
+	if c.key == nil {
+		return c.encoder.Encode(event)
+	}
+	record, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if _, err := c.writer.Write(append(c.key.SealLine(record), '\n')); err != nil {
+		return err
+	}
+	return nil
+}
+
+// finish stops collecting and returns the findings kept in memory, the spill
+// file path, empty when nothing was spilled, and the number of findings
+// spilled to it. Findings reported after finish are dropped.
+func (c *findingCollector) finish() ([]*output.ResultEvent, string, int) {
+	c.mu.Lock()
+	if !c.closed {
+		c.closed = true
+		close(c.events)
+	}
+	c.mu.Unlock()
+	<-c.done
+
+	if c.spill == nil {
+		return c.findings, c.spillPath, c.spilled
+	}
+	err := c.writer.Flush()
+	if closeErr := c.spill.Close(); err == nil {
+		err = closeErr
+	}
+	c.spill = nil
+	if err != nil {
+		c.console.Log("Failed to write spill file %s, some findings may be missing: %v", c.spillPath, err)
+	}
+	return c.findings, c.spillPath, c.spilled
+}
+
+// keep hands the spill file over to the result cache, which removes it
+// when the result leaves the cache
+func (c *findingCollector) keep() {
+	c.kept = true
+}
+
+// release finishes the collector and removes the spill file of a scan
+// whose result was not cached
+func (c *findingCollector) release() {
+	c.finish()
+	if c.kept || c.spillPath == "" {
+		return
+	}
+	if err := os.Remove(c.spillPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		c.console.Log("Failed to remove spill file %s: %v", c.spillPath, err)
+	}
+}
@@ -0,0 +1,58 @@
+package scanner
+
+import (
+	"context"
+
+	"nuclei-mcp/pkg/correlation"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+)
+
+// WithCorrelationID tags the logs, findings and result of the scan with the
+// correlation ID of the request that started it. ThreadSafeScan takes the ID
+// of its context when none is given.
+func WithCorrelationID(id string) ScanOption {
+	return func(o *ScanOptions) {
+		if id != "" {
+			o.CorrelationID = id
+		}
+	}
+}
+
+// correlatedLogger prefixes the lines it logs with a correlation ID
+type correlatedLogger struct {
+	LoggerInterface
+	id string
+}
+
+func (l correlatedLogger) Log(format string, v ...interface{}) {
+	l.LoggerInterface.Log("[%s] "+format, append([]interface{}{l.id}, v...)...)
+}
+
+// withCorrelation returns console prefixing its lines with id, or console
+// itself without an id
+func withCorrelation(console LoggerInterface, id string) LoggerInterface {
+	if id == "" {
+		return console
+	}
+	return correlatedLogger{LoggerInterface: console, id: id}
+}
+
+// logger returns the service's logger tagged with the correlation ID of ctx
+func (s *scannerServiceImpl) logger(ctx context.Context) LoggerInterface {
+	return withCorrelation(s.console, correlation.FromContext(ctx))
+}
+
+// correlate records id in the metadata of a finding, copying the metadata
+// since nuclei may share it between findings
+func correlate(event *output.ResultEvent, id string) {
+	if id == "" {
+		return
+	}
+	metadata := make(map[string]interface{}, len(event.Metadata)+1)
+	for key, value := range event.Metadata {
+		metadata[key] = value
+	}
+	metadata[correlation.MetaKey] = id
+	event.Metadata = metadata
+}
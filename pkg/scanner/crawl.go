@@ -0,0 +1,224 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultCrawlLimit is the number of URLs a crawl collects unless the
+	// scan sets another
+	DefaultCrawlLimit = 100
+	// MaxCrawlDepth and MaxCrawlLimit cap the crawl of a scan
+	MaxCrawlDepth = 5
+	MaxCrawlLimit = 1000
+	// crawlTimeout bounds the crawl of a scan
+	crawlTimeout = 2 * time.Minute
+	// maxCrawlPageSize bounds the bytes of a page searched for links
+	maxCrawlPageSize = 2 << 20
+)
+
+var (
+	linkPattern = regexp.MustCompile(`(?i)\b(?:href|src|action)\s*=\s*["']([^"']+)["']`)
+	// Static files have no paths for templates to probe
+	staticPathPattern = regexp.MustCompile(`(?i)\.(?:png|jpe?g|gif|svg|ico|webp|bmp|css|woff2?|ttf|eot|otf|map|mp4|webm|mp3|pdf|zip)$`)
+)
+
+// WithCrawl crawls the target's links up to depth pages away before the
+// scan and runs its HTTP templates against each URL found on the target's
+// host too, up to limit URLs (DefaultCrawlLimit when zero)
+func WithCrawl(depth, limit int) ScanOption {
+	return func(o *ScanOptions) {
+		o.CrawlDepth, o.CrawlLimit = depth, limit
+	}
+}
+
+// WithCrawlScope limits the URLs the crawl of the scan collects to those
+// inScope accepts, such as the URLs inside the scope its target was checked
+// against
+func WithCrawlScope(inScope func(url string) bool) ScanOption {
+	return func(o *ScanOptions) {
+		o.CrawlScope = inScope
+	}
+}
+
+// checkCrawl validates the crawl settings of a scan and applies their
+// default and caps
+func checkCrawl(scanOpts *ScanOptions) error {
+	if scanOpts.CrawlDepth == 0 {
+		return nil
+	}
+	switch {
+	case scanOpts.CrawlDepth < 0 || scanOpts.CrawlLimit < 0:
+		return fmt.Errorf("invalid crawl: depth and limit must not be negative")
+	case len(scanOpts.OpenAPI) > 0:
+		return fmt.Errorf("a scan of API operations cannot crawl the target")
+	}
+	scanOpts.CrawlDepth = min(scanOpts.CrawlDepth, MaxCrawlDepth)
+	if scanOpts.CrawlLimit == 0 {
+		scanOpts.CrawlLimit = DefaultCrawlLimit
+	}
+	scanOpts.CrawlLimit = min(scanOpts.CrawlLimit, MaxCrawlLimit)
+	return nil
+}
+
+// Crawl follows the links of target's pages with client, breadth first, up
+// to depth pages away and returns the distinct URLs on target's host they
+// lead to that allow accepts, at most limit; a nil allow accepts them all.
+// Links to static files are skipped, only HTML pages are searched for
+// links, and redirects are followed to accepted URLs only.
+func Crawl(ctx context.Context, client *http.Client, target string, depth, limit int, allow func(url string) bool) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, crawlTimeout)
+	defer cancel()
+
+	base := target
+	if !strings.Contains(base, "://") {
+		base = "https://" + base
+	}
+	start, err := url.Parse(base)
+	if err != nil || start.Host == "" {
+		return nil, fmt.Errorf("failed to crawl %s: invalid URL", target)
+	}
+	start.Fragment = ""
+	host := strings.ToLower(start.Host)
+	accepted := func(link *url.URL) bool {
+		return strings.ToLower(link.Host) == host && (allow == nil || allow(link.String()))
+	}
+	crawler := *client
+	crawler.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if !accepted(req.URL) || len(via) >= 5 {
+			return http.ErrUseLastResponse
+		}
+		return nil
+	}
+	defer crawler.CloseIdleConnections()
+
+	seen := map[string]bool{start.String(): true}
+	var found []string
+	level := []*url.URL{start}
+	for d := 0; d < depth && len(level) > 0 && len(found) < limit; d++ {
+		var next []*url.URL
+		for _, page := range level {
+			links, err := crawlPage(ctx, &crawler, page)
+			if err != nil {
+				if page == start {
+					return nil, fmt.Errorf("failed to crawl %s: %w", target, err)
+				}
+				continue
+			}
+			for _, link := range links {
+				if seen[link.String()] || !accepted(link) {
+					continue
+				}
+				seen[link.String()] = true
+				found = append(found, link.String())
+				next = append(next, link)
+				if len(found) == limit {
+					break
+				}
+			}
+			if len(found) == limit || ctx.Err() != nil {
+				break
+			}
+		}
+		level = next
+	}
+	sort.Strings(found)
+	return found, nil
+}
+
+// crawlPage returns the http(s) links of page, resolved against it,
+// without their fragments and except those of static files
+func crawlPage(ctx context.Context, client *http.Client, page *url.URL) ([]*url.URL, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, page.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if !strings.Contains(strings.ToLower(resp.Header.Get("Content-Type")), "html") {
+		return nil, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCrawlPageSize))
+	if err != nil {
+		return nil, err
+	}
+
+	var links []*url.URL
+	for _, match := range linkPattern.FindAllStringSubmatch(string(body), -1) {
+		link, err := resp.Request.URL.Parse(strings.TrimSpace(match[1]))
+		if err != nil || (link.Scheme != "http" && link.Scheme != "https") || staticPathPattern.MatchString(link.Path) {
+			continue
+		}
+		link.Fragment = ""
+		links = append(links, link)
+	}
+	return links, nil
+}
+
+// crawl crawls the target when the scan asked for it, under the egress
+// policy and at the rate limit of the scan, and returns the URLs found and
+// the requests sent. URLs outside the crawl scope of the scan, or matched
+// by exclusion rules its target is not, are left out. A failed crawl is
+// logged and the scan runs against the target alone.
+func (s *scannerServiceImpl) crawl(ctx context.Context, console LoggerInterface, target string, scanOpts ScanOptions) ([]string, int) {
+	if scanOpts.CrawlDepth == 0 {
+		return nil, 0
+	}
+	allow := func(url string) bool {
+		if scanOpts.CrawlScope != nil && !scanOpts.CrawlScope(url) {
+			return false
+		}
+		if s.exclusions == nil {
+			return true
+		}
+		ids, tags := s.exclusions.Match(url)
+		return isSubset(ids, scanOpts.excludedIDs) && isSubset(tags, scanOpts.excludedTags)
+	}
+	client := s.targetClient(scanOpts, false)
+	urls, err := Crawl(ctx, client, target, scanOpts.CrawlDepth, scanOpts.CrawlLimit, allow)
+	if err != nil {
+		console.Log("Crawl of %s failed: %v", target, err)
+		return nil, sentRequests(client)
+	}
+	console.Log("Crawl of %s found %d URLs", target, len(urls))
+	return urls, sentRequests(client)
+}
+
+// isSubset reports whether every value is in set
+func isSubset(values, set []string) bool {
+	for _, value := range values {
+		if !slices.Contains(set, value) {
+			return false
+		}
+	}
+	return true
+}
+
+type crawledURLsKey struct{}
+
+// withCrawledURLs returns ctx carrying the URLs the scan's crawl found to
+// the templates the scan runs
+func withCrawledURLs(ctx context.Context, urls []string) context.Context {
+	if len(urls) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, crawledURLsKey{}, urls)
+}
+
+// crawledURLsFrom returns the crawled URLs of the scan running ctx
+func crawledURLsFrom(ctx context.Context) []string {
+	urls, _ := ctx.Value(crawledURLsKey{}).([]string)
+	return urls
+}
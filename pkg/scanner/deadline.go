@@ -0,0 +1,143 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"nuclei-mcp/pkg/telemetry"
+
+	nuclei "github.com/projectdiscovery/nuclei/v3/lib"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// DefaultEngineTimeout bounds engine creation and template loading
+const DefaultEngineTimeout = 2 * time.Minute
+
+// WithEngineTimeout bounds how long a scan waits for its engine to be created
+// and its templates loaded, so a hung template load or update check fails the
+// scan instead of blocking it indefinitely
+func WithEngineTimeout(timeout time.Duration) ServiceOption {
+	return func(s *scannerServiceImpl) {
+		if timeout > 0 {
+			s.engineTimeout = timeout
+		}
+	}
+}
+
+// withEngineDeadline runs create until it returns, the timeout passes or ctx
+// is done. An engine that is created after the caller gave up is discarded.
+// create gets ctx itself, not a context bound to the deadline, since engines
+// keep using it for the scan.
+func withEngineDeadline[T any](ctx context.Context, timeout time.Duration, create func(context.Context) (T, error), discard func(T)) (T, error) {
+	type created struct {
+		engine T
+		err    error
+	}
+	done := make(chan created, 1)
+	go func() {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				done <- created{err: panicError(recovered)}
+			}
+		}()
+		engine, err := create(ctx)
+		done <- created{engine, err}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	var zero T
+	var err error
+	select {
+	case c := <-done:
+		return c.engine, c.err
+	case <-timer.C:
+		err = fmt.Errorf("%w after %s; template loading or the update check may be hanging (see scanner.engine_timeout)", ErrEngineTimeout, timeout)
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	go func() {
+		if c := <-done; c.err == nil {
+			discard(c.engine)
+		}
+	}()
+	return zero, err
+}
+
+// newEngine creates an engine for target and loads its templates within the
+// engine timeout
+func (s *scannerServiceImpl) newEngine(ctx context.Context, target string, options []nuclei.NucleiSDKOptions) (ne *nuclei.NucleiEngine, err error) {
+	ctx, span := startSpan(ctx, "engine.create")
+	defer func() { telemetry.End(span, err) }()
+
+	ne, err = withEngineDeadline(ctx, s.engineTimeout, func(ctx context.Context) (*nuclei.NucleiEngine, error) {
+		ne, err := nuclei.NewNucleiEngineCtx(ctx, options...)
+		if err != nil {
+			s.console.Log("Failed to create nuclei engine: %v", err)
+			return nil, engineInitError(err)
+		}
+		s.useTemplateCache(ne)
+
+		ne.LoadTargets([]string{target}, true)
+
+		_, load := startSpan(ctx, "templates.load")
+		before := loaderStats()
+		err = ne.LoadAllTemplates()
+		telemetry.End(load, err)
+		if err != nil {
+			ne.Close()
+			s.console.Log("Failed to load templates: %v", err)
+			return nil, engineInitError(err)
+		}
+		wrapTemplates(ne.GetTemplates())
+		scanProgressFrom(ctx).expect(ne.GetTemplates())
+		scanProgressFrom(ctx).exclude(before)
+		return ne, nil
+	}, func(ne *nuclei.NucleiEngine) {
+		// The caller gave up and released the engine lock
+		engineLock.Lock()
+		defer engineLock.Unlock()
+		ne.Close()
+	})
+	if errors.Is(err, ErrEngineTimeout) {
+		s.console.Log("Failed to create nuclei engine: %v", err)
+	}
+	return ne, err
+}
+
+// newThreadSafeEngine creates a thread-safe engine within the engine timeout.
+// The engine's templates are loaded up front, so that per-template limits
+// and progress apply to them and the scan knows what it will run; the scan
+// then runs the same compiled templates, which report to monitor when it is
+// not nil.
+func (s *scannerServiceImpl) newThreadSafeEngine(ctx context.Context, options []nuclei.NucleiSDKOptions, monitor *scanMonitor) (ne *nuclei.ThreadSafeNucleiEngine, err error) {
+	_, span := startSpan(ctx, "engine.create", attribute.Bool("engine.thread_safe", true))
+	defer func() { telemetry.End(span, err) }()
+
+	return withEngineDeadline(ctx, s.engineTimeout, func(ctx context.Context) (*nuclei.ThreadSafeNucleiEngine, error) {
+		var base *nuclei.NucleiEngine
+		options = append(options[:len(options):len(options)], captureEngine(&base))
+		ne, err := nuclei.NewThreadSafeNucleiEngineCtx(ctx, options...)
+		if err != nil {
+			return nil, engineInitError(err)
+		}
+		_, load := startSpan(ctx, "templates.load")
+		before := loaderStats()
+		err = ne.GlobalLoadAllTemplates()
+		telemetry.End(load, err)
+		if err != nil {
+			// The caller holds the engine lock until it returns
+			go closeShared(ne)
+			return nil, engineInitError(err)
+		}
+		wrapTemplates(base.GetTemplates())
+		monitor.watch(base)
+		scanProgressFrom(ctx).expect(base.GetTemplates())
+		scanProgressFrom(ctx).exclude(before)
+		return ne, nil
+	}, closeShared)
+}
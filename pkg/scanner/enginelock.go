@@ -0,0 +1,58 @@
+package scanner
+
+import (
+	"context"
+	"sync"
+
+	"nuclei-mcp/pkg/telemetry"
+
+	nuclei "github.com/projectdiscovery/nuclei/v3/lib"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+)
+
+// engineLock guards nuclei's process-wide protocol state. Closing a
+// non-thread-safe engine resets that state under every other running engine,
+// so those engines run exclusively, while thread-safe engines share the lock.
+var engineLock sync.RWMutex
+
+// LockEngines waits for the running scan engines to finish and keeps new
+// ones from starting until the returned function is called. Engines that
+// are not thread-safe and run outside the scanner service, such as the
+// template sandbox, must hold it.
+func LockEngines() (unlock func()) {
+	engineLock.Lock()
+	return engineLock.Unlock
+}
+
+// closeShared closes a thread-safe engine once no other engine is running.
+// Closing any engine resets nuclei's shared protocol state, which would pull
+// the dialer from under the thread-safe scans still running. Callers must
+// not hold the engine lock.
+func closeShared(ne *nuclei.ThreadSafeNucleiEngine) {
+	engineLock.Lock()
+	defer engineLock.Unlock()
+	ne.Close()
+}
+
+// executeExclusive creates a non-thread-safe engine for target and runs it
+// while no other engine is running. A non-nil monitor receives the engine's
+// request trace and traffic. Cancelling ctx stops the scan.
+func (s *scannerServiceImpl) executeExclusive(ctx context.Context, target string, options []nuclei.NucleiSDKOptions, callback func(*output.ResultEvent), monitor *scanMonitor) error {
+	engineLock.Lock()
+	defer engineLock.Unlock()
+
+	if monitor != nil {
+		options = append(options[:len(options):len(options)], nuclei.UseOutputWriter(monitor), trafficOption())
+	}
+	ne, err := s.newEngine(ctx, target, options)
+	if err != nil {
+		return err
+	}
+	defer ne.Close()
+	monitor.attach(ne)
+
+	_, span := startSpan(ctx, "scan.execute")
+	err = ne.ExecuteCallbackWithCtx(ctx, callback)
+	telemetry.End(span, err)
+	return err
+}
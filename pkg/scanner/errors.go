@@ -0,0 +1,99 @@
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+
+	nuclei "github.com/projectdiscovery/nuclei/v3/lib"
+)
+
+var (
+	// ErrEngineInit is returned when a scan engine cannot be created or its
+	// templates cannot be loaded
+	ErrEngineInit = errors.New("scan engine initialization failed")
+	// ErrEngineTimeout is returned when a scan engine is not ready in time
+	ErrEngineTimeout = errors.New("scan engine creation timed out")
+	// ErrNoTemplates is returned when no template matches a scan's filters
+	ErrNoTemplates = errors.New("no templates match the scan filters")
+	// ErrNoTargets is returned when nuclei could not load the scan target
+	ErrNoTargets = errors.New("target could not be loaded")
+	// ErrScanPanic is returned when a scan panicked, for example on a
+	// malformed response
+	ErrScanPanic = errors.New("scan panicked")
+	// ErrInvalidProtocol is returned when a scan filters by a protocol that
+	// is not a nuclei template protocol type
+	ErrInvalidProtocol = errors.New("unknown protocol")
+	// ErrInvalidPriority is returned for a scan priority that is not
+	// interactive or background
+	ErrInvalidPriority = errors.New("unknown priority")
+	// ErrInvalidStrategy is returned for a scan strategy other than
+	// severity_tiers, or one the scan's engine does not support
+	ErrInvalidStrategy = errors.New("unsupported scan strategy")
+	// ErrInvalidSeverity is returned for a severity threshold that is not
+	// info, low, medium, high or critical
+	ErrInvalidSeverity = errors.New("unknown severity")
+	// ErrInvalidLabel is returned for a scan label with an invalid key or
+	// value
+	ErrInvalidLabel = errors.New("invalid label")
+	// ErrInvalidVariable is returned for a scan variable with an invalid
+	// name or value
+	ErrInvalidVariable = errors.New("invalid variable")
+	// ErrInvalidAddressFamily is returned for an address family other than
+	// v4, v6 or dual
+	ErrInvalidAddressFamily = errors.New("unknown address family")
+	// ErrInvalidHostOverride is returned for an SNI or Host header override
+	// that is not a host name
+	ErrInvalidHostOverride = errors.New("invalid host override")
+	// ErrInvalidOpenAPI is returned for an OpenAPI document that cannot be
+	// parsed or has no operations to scan
+	ErrInvalidOpenAPI = errors.New("invalid OpenAPI document")
+	// ErrInvalidAPIParameter is returned for an API parameter with an
+	// invalid name or value, or a security parameter the API requires but
+	// the scan does not set
+	ErrInvalidAPIParameter = errors.New("invalid API parameter")
+	// ErrInvalidInlineTemplate is returned for inline template content
+	// that is not a template with an ID, or repeats another's ID
+	ErrInvalidInlineTemplate = errors.New("invalid inline template")
+	// ErrMaintenance is returned for scans requested while scanning is
+	// paused for maintenance
+	ErrMaintenance = errors.New("scanning is paused for maintenance")
+	// ErrQuotaExceeded is returned when the client of a scan used up its
+	// resource quota
+	ErrQuotaExceeded = errors.New("scan quota exceeded")
+	// ErrPreviewUnsupported is returned for dry runs on a scanner that
+	// cannot preview scans
+	ErrPreviewUnsupported = errors.New("scan previews are not supported by this scanner")
+)
+
+// executionError maps nuclei's execution errors to the scanner's errors
+func executionError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, nuclei.ErrNoTemplatesAvailable):
+		return ErrNoTemplates
+	case errors.Is(err, nuclei.ErrNoTargetsAvailable):
+		return ErrNoTargets
+	}
+	return err
+}
+
+// engineInitError marks err as an engine initialization failure
+func engineInitError(err error) error {
+	return fmt.Errorf("%w: %w", ErrEngineInit, err)
+}
+
+// panicError converts a recovered panic value into an error
+func panicError(recovered any) error {
+	return fmt.Errorf("%w: %v", ErrScanPanic, recovered)
+}
+
+// recoverScan recovers a panic in a scan of target, logs it with its stack
+// and stores it in err. It must be deferred directly.
+func (s *scannerServiceImpl) recoverScan(target string, err *error) {
+	if recovered := recover(); recovered != nil {
+		s.console.Log("Recovered from panic while scanning %s: %v\n%s", target, recovered, debug.Stack())
+		*err = panicError(recovered)
+	}
+}
@@ -0,0 +1,226 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"nuclei-mcp/pkg/cache"
+
+	nuclei "github.com/projectdiscovery/nuclei/v3/lib"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ExtractorRegex extracts regular expression matches
+	ExtractorRegex = "regex"
+	// ExtractorJSON extracts values using jq-style JSON paths
+	ExtractorJSON = "json"
+	// ExtractorPreset uses one of the built-in Presets
+	ExtractorPreset = "preset"
+)
+
+// extractorTemplateID is the ID of the template generated for scan extractors
+const extractorTemplateID = "nuclei-mcp-extractors"
+
+// Extractor collects data from the target's HTTP response during a scan
+type Extractor struct {
+	// Name labels the extracted values; defaults to the preset name or
+	// extractor-<n>
+	Name string `json:"name,omitempty"`
+	// Type is regex, json or preset
+	Type string `json:"type"`
+	// Expression is the regex, the JSON path (e.g. .version) or the preset name
+	Expression string `json:"expression"`
+	// Part is the response part to extract from: body (default), header or response
+	Part string `json:"part,omitempty"`
+	// Group is the regex capture group to return (0 returns the whole match)
+	Group int `json:"group,omitempty"`
+}
+
+// Presets are ready-made extractors for common data collection
+var Presets = map[string]Extractor{
+	"server-banner": {Type: ExtractorRegex, Expression: `(?i)server:\s*([^\r\n]+)`, Part: "header", Group: 1},
+	"powered-by":    {Type: ExtractorRegex, Expression: `(?i)x-powered-by:\s*([^\r\n]+)`, Part: "header", Group: 1},
+	"versions":      {Type: ExtractorRegex, Expression: `\bv?\d+\.\d+(?:\.\d+){0,2}\b`},
+	"jwt":           {Type: ExtractorRegex, Expression: `eyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`, Part: "response"},
+	"emails":        {Type: ExtractorRegex, Expression: `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`},
+	"html-title":    {Type: ExtractorRegex, Expression: `(?is)<title[^>]*>(.*?)</title>`, Group: 1},
+}
+
+// PresetNames returns the names of the built-in extractor presets
+func PresetNames() []string {
+	names := make([]string, 0, len(Presets))
+	for name := range Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolveExtractors validates extractors, expands presets and assigns names
+func resolveExtractors(extractors []Extractor) ([]Extractor, error) {
+	resolved := make([]Extractor, 0, len(extractors))
+	for i, extractor := range extractors {
+		if extractor.Type == ExtractorPreset {
+			preset, ok := Presets[extractor.Expression]
+			if !ok {
+				return nil, fmt.Errorf("unknown extractor preset %q, available: %s", extractor.Expression, strings.Join(PresetNames(), ", "))
+			}
+			name := extractor.Name
+			if name == "" {
+				name = extractor.Expression
+			}
+			extractor = preset
+			extractor.Name = name
+		}
+
+		if extractor.Expression == "" {
+			return nil, fmt.Errorf("extractor %d has no expression", i+1)
+		}
+		switch extractor.Type {
+		case ExtractorRegex:
+			compiled, err := regexp.Compile(extractor.Expression)
+			if err != nil {
+				return nil, fmt.Errorf("extractor %d has an invalid regex: %w", i+1, err)
+			}
+			if extractor.Group < 0 || extractor.Group > compiled.NumSubexp() {
+				return nil, fmt.Errorf("extractor %d has no capture group %d", i+1, extractor.Group)
+			}
+		case ExtractorJSON:
+		default:
+			return nil, fmt.Errorf("extractor %d has unknown type %q, expected regex, json or preset", i+1, extractor.Type)
+		}
+
+		switch extractor.Part {
+		case "":
+			extractor.Part = "body"
+		case "body", "header", "response":
+		default:
+			return nil, fmt.Errorf("extractor %d has unknown part %q, expected body, header or response", i+1, extractor.Part)
+		}
+
+		if extractor.Name == "" {
+			extractor.Name = fmt.Sprintf("extractor-%d", i+1)
+		}
+		resolved = append(resolved, extractor)
+	}
+	return resolved, nil
+}
+
+// extractorTemplate renders the extractors as a nuclei HTTP template that
+// requests the target once and reports everything the extractors collect
+func extractorTemplate(extractors []Extractor) ([]byte, error) {
+	type templateExtractor struct {
+		Type  string   `yaml:"type"`
+		Name  string   `yaml:"name"`
+		Part  string   `yaml:"part"`
+		Regex []string `yaml:"regex,omitempty"`
+		Group int      `yaml:"group,omitempty"`
+		JSON  []string `yaml:"json,omitempty"`
+	}
+
+	rendered := make([]templateExtractor, 0, len(extractors))
+	for _, extractor := range extractors {
+		te := templateExtractor{Type: extractor.Type, Name: extractor.Name, Part: extractor.Part}
+		if extractor.Type == ExtractorJSON {
+			te.JSON = []string{extractor.Expression}
+		} else {
+			te.Regex = []string{extractor.Expression}
+			te.Group = extractor.Group
+		}
+		rendered = append(rendered, te)
+	}
+
+	return yaml.Marshal(map[string]any{
+		"id": extractorTemplateID,
+		"info": map[string]any{
+			"name":     "Scan extractors",
+			"author":   "nuclei-mcp",
+			"severity": "info",
+		},
+		"http": []map[string]any{{
+			"method":     "GET",
+			"path":       []string{"{{BaseURL}}"},
+			"extractors": rendered,
+		}},
+	})
+}
+
+// RunExtractors requests the target and returns the values collected by the
+// given extractors. Extra SDK options (such as egress restrictions) are
+// applied to the engine.
+func RunExtractors(ctx context.Context, target string, extractors []Extractor, extra ...nuclei.NucleiSDKOptions) ([]cache.Extraction, error) {
+	resolved, err := resolveExtractors(extractors)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := extractorTemplate(resolved)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render extractors: %w", err)
+	}
+
+	dir, err := os.MkdirTemp("", "nuclei-mcp-extractors-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create extractors directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	templatePath := filepath.Join(dir, extractorTemplateID+".yaml")
+	if err := os.WriteFile(templatePath, content, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write extractors template: %w", err)
+	}
+
+	options := append([]nuclei.NucleiSDKOptions{
+		nuclei.DisableUpdateCheck(),
+		nuclei.WithTemplatesOrWorkflows(nuclei.TemplateSources{Templates: []string{templatePath}}),
+	}, extra...)
+
+	engineLock.Lock()
+	defer engineLock.Unlock()
+
+	ne, err := nuclei.NewNucleiEngineCtx(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create nuclei engine: %w", err)
+	}
+	defer ne.Close()
+
+	if err := ne.LoadAllTemplates(); err != nil {
+		return nil, fmt.Errorf("failed to load extractors template: %w", err)
+	}
+	if len(ne.GetTemplates()) == 0 {
+		return nil, fmt.Errorf("extractors template could not be loaded")
+	}
+
+	// URLs are requested as given; bare hosts are probed for a web server
+	ne.LoadTargets([]string{target}, !strings.Contains(target, "://"))
+
+	extractions := []cache.Extraction{}
+	var extractionsMutex sync.Mutex
+
+	err = ne.ExecuteCallbackWithCtx(ctx, func(event *output.ResultEvent) {
+		if len(event.ExtractedResults) == 0 {
+			return
+		}
+		extractionsMutex.Lock()
+		defer extractionsMutex.Unlock()
+		extractions = append(extractions, cache.Extraction{
+			Name:   event.ExtractorName,
+			URL:    event.Matched,
+			Values: event.ExtractedResults,
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("extraction failed: %w", err)
+	}
+
+	sort.Slice(extractions, func(i, j int) bool { return extractions[i].Name < extractions[j].Name })
+	return extractions, nil
+}
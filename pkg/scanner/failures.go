@@ -0,0 +1,180 @@
+package scanner
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+)
+
+// Causes of failed requests, as counted in a host's failures
+const (
+	FailureConnectionRefused = "connection_refused"
+	// FailureConnectionReset: the connection was reset or closed without
+	// a response
+	FailureConnectionReset = "connection_reset"
+	FailureDNS             = "dns"
+	FailureTLS             = "tls"
+	FailureTimeout         = "timeout"
+	// FailureUnreachable: no route to the host or its network
+	FailureUnreachable = "unreachable"
+	FailureOther       = "other"
+)
+
+// reachabilityTimeout bounds each connection attempt of a reachability
+// check
+const reachabilityTimeout = 5 * time.Second
+
+// failureMessages are the texts nuclei's wrapped errors carry for each
+// cause, for errors that no longer unwrap to the network error
+var failureMessages = []struct {
+	cause    string
+	messages []string
+}{
+	{FailureDNS, []string{"no such host", "no address found for host", "could not resolve host", "server misbehaving"}},
+	{FailureConnectionRefused, []string{"connection refused"}},
+	{FailureTLS, []string{"tls:", "x509:", "handshake"}},
+	{FailureConnectionReset, []string{"connection reset", "broken pipe", "eof", "server closed"}},
+	{FailureUnreachable, []string{"no route to host", "network is unreachable", "host is down"}},
+	{FailureTimeout, []string{"timeout", "deadline exceeded", "timed out"}},
+}
+
+// FailureCause classifies why a request failed
+func FailureCause(err error) string {
+	var dnsErr *net.DNSError
+	var certErr *x509.UnknownAuthorityError
+	var hostErr x509.HostnameError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &dnsErr):
+		return FailureDNS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return FailureConnectionRefused
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return FailureConnectionReset
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH):
+		return FailureUnreachable
+	case errors.As(err, &certErr), errors.As(err, &hostErr):
+		return FailureTLS
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return FailureTimeout
+	}
+
+	text := strings.ToLower(err.Error())
+	for _, failure := range failureMessages {
+		for _, message := range failure.messages {
+			if strings.Contains(text, message) {
+				return failure.cause
+			}
+		}
+	}
+	return FailureOther
+}
+
+// failureCounter counts the requests of a scan to each host and why those
+// that failed did
+type failureCounter map[string]*cache.HostFailures
+
+// count records a request to the host of url
+func (c failureCounter) count(url string, err error) {
+	host := TargetHost(url)
+	failures, ok := c[host]
+	if !ok {
+		failures = &cache.HostFailures{Host: host, Causes: map[string]int{}}
+		c[host] = failures
+	}
+	failures.Requests++
+	if err == nil {
+		return
+	}
+	failures.Failed++
+	failures.Causes[FailureCause(err)]++
+	if failures.Example == "" {
+		failures.Example = err.Error()
+	}
+}
+
+// summary returns the requests and failures of each host, by host
+func (c failureCounter) summary() []cache.HostFailures {
+	var summary []cache.HostFailures
+	for _, failures := range c {
+		entry := *failures
+		entry.Causes = make(map[string]int, len(failures.Causes))
+		for cause, count := range failures.Causes {
+			entry.Causes[cause] = count
+		}
+		entry.Unreachable = entry.Requests > 0 && entry.Failed == entry.Requests
+		summary = append(summary, entry)
+	}
+	sort.Slice(summary, func(i, j int) bool {
+		return summary[i].Host < summary[j].Host
+	})
+	return summary
+}
+
+// mergeFailures adds the failures of a sub-scan to those of the scan
+func mergeFailures(total []cache.HostFailures, failures []cache.HostFailures) []cache.HostFailures {
+	counter := failureCounter{}
+	for _, list := range [][]cache.HostFailures{total, failures} {
+		for _, entry := range list {
+			merged, ok := counter[entry.Host]
+			if !ok {
+				merged = &cache.HostFailures{Host: entry.Host, Causes: map[string]int{}}
+				counter[entry.Host] = merged
+			}
+			if merged.Example == "" {
+				merged.Example = entry.Example
+			}
+			merged.Requests += entry.Requests
+			merged.Failed += entry.Failed
+			for cause, count := range entry.Causes {
+				merged.Causes[cause] += count
+			}
+		}
+	}
+	return counter.summary()
+}
+
+// checkReachable connects to the port target is scanned on, or to 443 and
+// 80 when it has none, for scans that sent no requests: nuclei drops a
+// target whose HTTP probe fails without sending any. It connects to the
+// first of addresses when the scan pins them. The failed attempts are
+// returned when none connected.
+func checkReachable(ctx context.Context, target string, addresses []string) []cache.HostFailures {
+	host, ports := TargetHost(target), []string{"443", "80"}
+	if u, err := url.Parse(target); err == nil && strings.Contains(target, "://") {
+		switch {
+		case u.Port() != "":
+			ports = []string{u.Port()}
+		case u.Scheme == "http":
+			ports = []string{"80"}
+		case u.Scheme == "https":
+			ports = []string{"443"}
+		}
+	} else if _, port, err := net.SplitHostPort(target); err == nil {
+		ports = []string{port}
+	}
+	if len(addresses) > 0 {
+		host = addresses[0]
+	}
+
+	counter := failureCounter{}
+	dialer := &net.Dialer{Timeout: reachabilityTimeout}
+	for _, port := range ports {
+		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		counter.count(target, err)
+	}
+	return counter.summary()
+}
@@ -0,0 +1,89 @@
+package scanner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/model/types/severity"
+)
+
+// WithExcludedTags leaves templates carrying any of the given tags out of
+// the scan, on top of the denied tags and exclusion rules
+func WithExcludedTags(tags ...string) ScanOption {
+	return func(o *ScanOptions) {
+		o.ExcludeTags = appendTrimmed(o.ExcludeTags, tags)
+	}
+}
+
+// WithExcludedIDs leaves templates with any of the given IDs out of the
+// scan
+func WithExcludedIDs(ids ...string) ScanOption {
+	return func(o *ScanOptions) {
+		o.ExcludeIDs = appendTrimmed(o.ExcludeIDs, ids)
+	}
+}
+
+// WithAuthors limits the scan to templates by any of the given authors
+func WithAuthors(authors ...string) ScanOption {
+	return func(o *ScanOptions) {
+		o.Authors = appendTrimmed(o.Authors, authors)
+	}
+}
+
+// WithExcludedSeverities leaves templates of the given severities out of
+// the scan
+func WithExcludedSeverities(severities ...string) ScanOption {
+	return func(o *ScanOptions) {
+		o.ExcludeSeverities = appendTrimmed(o.ExcludeSeverities, severities)
+	}
+}
+
+// WithDefaultTemplateIDs runs the templates with the given IDs when the scan
+// names none itself
+func WithDefaultTemplateIDs(ids ...string) ScanOption {
+	return func(o *ScanOptions) {
+		o.DefaultTemplateIDs = appendTrimmed(o.DefaultTemplateIDs, ids)
+	}
+}
+
+// appendTrimmed appends the non-empty values to list
+func appendTrimmed(list []string, values []string) []string {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+// ValidateSeverities checks that each of severities is a nuclei severity
+func ValidateSeverities(severities []string) error {
+	for _, value := range severities {
+		if err := (&severity.Severities{}).Set(value); err != nil {
+			return fmt.Errorf("%w %q, use info, low, medium, high, critical or unknown", ErrInvalidSeverity, value)
+		}
+	}
+	return nil
+}
+
+// templateIDs returns the template IDs the scan runs: those it names, or
+// else its default template IDs
+func (o ScanOptions) templateIDs(templateIDs []string) []string {
+	if len(templateIDs) > 0 {
+		return templateIDs
+	}
+	return o.DefaultTemplateIDs
+}
+
+// filterKey is the part of a scan's cache key for its author and exclusion
+// filters
+func (o ScanOptions) filterKey() string {
+	var key string
+	if len(o.Authors) > 0 {
+		key += ":authors=" + strings.Join(o.Authors, ",")
+	}
+	if len(o.ExcludeTags) > 0 || len(o.ExcludeIDs) > 0 || len(o.ExcludeSeverities) > 0 {
+		key += fmt.Sprintf(":without=%s/%s/%s", strings.Join(o.ExcludeTags, ","), strings.Join(o.ExcludeIDs, ","), strings.Join(o.ExcludeSeverities, ","))
+	}
+	return key
+}
@@ -0,0 +1,130 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/projectdiscovery/fastdialer/fastdialer"
+)
+
+// serverNamePattern matches the host names a TLS client may send as SNI
+var serverNamePattern = regexp.MustCompile(`^[A-Za-z0-9_]([A-Za-z0-9_.-]{0,251}[A-Za-z0-9_.])?$`)
+
+// WithSNI sends name as the TLS server name of the scan's connections
+// instead of the target's host, for example to reach the site of a CDN
+// customer on its origin IP
+func WithSNI(name string) ScanOption {
+	return func(o *ScanOptions) {
+		o.SNI = strings.TrimSpace(name)
+	}
+}
+
+// WithHostHeader sends host, with an optional port, as the Host header of
+// the scan's HTTP requests. The requests still connect to the target, so
+// a target given as an origin IP is scanned as the site it serves.
+func WithHostHeader(host string) ScanOption {
+	return func(o *ScanOptions) {
+		o.HostHeader = strings.TrimSpace(host)
+	}
+}
+
+// ValidateHostOverrides checks that sni is a host name and hostHeader a
+// host with an optional port; empty values are not overridden
+func ValidateHostOverrides(sni string, hostHeader string) error {
+	if sni != "" && !serverNamePattern.MatchString(sni) {
+		return fmt.Errorf("%w: sni %q, use a host name", ErrInvalidHostOverride, sni)
+	}
+	if hostHeader != "" {
+		u, err := url.Parse("//" + hostHeader)
+		if err != nil || u.Host != hostHeader || u.Hostname() == "" || u.User != nil {
+			return fmt.Errorf("%w: host_header %q, use a host with an optional port", ErrInvalidHostOverride, hostHeader)
+		}
+	}
+	return nil
+}
+
+// hostOverride is the TLS server name and Host header of a running scan
+type hostOverride struct {
+	sni  string
+	host string
+}
+
+type hostOverrideKey struct{}
+
+// newHostOverride returns the host override of a scan, or nil when it has
+// none
+func newHostOverride(scanOpts ScanOptions) *hostOverride {
+	if scanOpts.SNI == "" && scanOpts.HostHeader == "" {
+		return nil
+	}
+	return &hostOverride{sni: scanOpts.SNI, host: scanOpts.HostHeader}
+}
+
+// originAddresses returns the addresses HTTP templates connect to when the
+// scan overrides the Host header: those of its address family, or else the
+// target's first address. The request URL then carries the Host header.
+func originAddresses(ctx context.Context, target string, override *hostOverride, addresses []string) ([]string, error) {
+	if override == nil || override.host == "" || len(addresses) > 0 {
+		return addresses, nil
+	}
+	host := TargetHost(target)
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return []string{addr.Unmap().String()}, nil
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil || len(addrs) == 0 {
+		return nil, fmt.Errorf("%w: %s has no address", ErrNoTargets, host)
+	}
+	return []string{addrs[0].Unmap().String()}, nil
+}
+
+// withHostOverride returns ctx carrying override to the templates the scan
+// runs
+func withHostOverride(ctx context.Context, override *hostOverride) context.Context {
+	if override == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, hostOverrideKey{}, override)
+}
+
+// hostOverrideFrom returns the host override of the scan running ctx
+func hostOverrideFrom(ctx context.Context) *hostOverride {
+	override, _ := ctx.Value(hostOverrideKey{}).(*hostOverride)
+	return override
+}
+
+// context returns ctx making the connections dialled with it send the
+// overridden server name
+func (o *hostOverride) context(ctx context.Context) context.Context {
+	if o == nil || o.sni == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, fastdialer.SniName, o.sni)
+}
+
+// input returns the template input with its host replaced by the Host
+// header, keeping the target's port unless the header has its own
+func (o *hostOverride) input(input string) string {
+	if o == nil || o.host == "" {
+		return input
+	}
+	raw := input
+	if !strings.Contains(raw, "://") {
+		raw = "//" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return input
+	}
+	port := u.Port()
+	u.Host = o.host
+	if _, _, err := net.SplitHostPort(o.host); err != nil && port != "" {
+		u.Host = o.host + ":" + port
+	}
+	return strings.TrimPrefix(u.String(), "//")
+}
@@ -0,0 +1,93 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+
+	nuclei "github.com/projectdiscovery/nuclei/v3/lib"
+)
+
+// httpVersionsTimeout bounds the HTTP version probe of a scan
+const httpVersionsTimeout = 10 * time.Second
+
+// WithForceHTTP2 makes HTTP templates attempt HTTP/2 instead of HTTP/1.1.
+// nuclei shares its HTTP clients across the process, so this is a setting
+// of the service rather than of a scan.
+func WithForceHTTP2(force bool) ServiceOption {
+	return func(s *scannerServiceImpl) {
+		s.forceHTTP2 = force
+	}
+}
+
+// WithHTTPVersionProbe reports the HTTP versions the target supports with
+// the scan result: whether it negotiates HTTP/2 and whether it advertises
+// HTTP/3 (QUIC) in its Alt-Svc header
+func WithHTTPVersionProbe() ScanOption {
+	return func(o *ScanOptions) {
+		o.ProbeHTTPVersions = true
+	}
+}
+
+// http2Option makes the engine's HTTP clients attempt HTTP/2
+func http2Option() nuclei.NucleiSDKOptions {
+	return func(e *nuclei.NucleiEngine) error {
+		e.Options().ForceAttemptHTTP2 = true
+		return nil
+	}
+}
+
+// ProbeHTTPVersions requests target, or https://target for a bare host,
+// with client and reads the HTTP/3 endpoints it advertises. The client
+// should offer HTTP/2, as target clients created with HTTP2 do. Redirects
+// are not followed.
+func ProbeHTTPVersions(ctx context.Context, client *http.Client, target string) (*cache.HTTPVersions, error) {
+	ctx, cancel := context.WithTimeout(ctx, httpVersionsTimeout)
+	defer cancel()
+
+	url := target
+	if !strings.Contains(url, "://") {
+		url = "https://" + url
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP version probe: %w", err)
+	}
+	resp, err := withoutRedirects(client).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to probe HTTP versions: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	versions := &cache.HTTPVersions{HTTP2: resp.ProtoMajor == 2, AltSvc: resp.Header.Get("Alt-Svc")}
+	for _, service := range strings.Split(versions.AltSvc, ",") {
+		protocol, _, _ := strings.Cut(strings.TrimSpace(service), "=")
+		if protocol == "h3" || strings.HasPrefix(protocol, "h3-") {
+			versions.HTTP3 = true
+		}
+	}
+	return versions, nil
+}
+
+// probeHTTPVersions adds the HTTP versions of the target to result when the
+// scan asked for them. A failed probe is recorded in them and does not fail
+// the scan.
+func (s *scannerServiceImpl) probeHTTPVersions(ctx context.Context, console LoggerInterface, target string, scanOpts ScanOptions, result *cache.ScanResult) {
+	if !scanOpts.ProbeHTTPVersions {
+		return
+	}
+	client := s.targetClient(scanOpts, true)
+	defer client.CloseIdleConnections()
+	versions, err := ProbeHTTPVersions(ctx, client, target)
+	if err != nil {
+		console.Log("HTTP version probe of %s failed: %v", target, err)
+		versions = &cache.HTTPVersions{Error: err.Error()}
+	}
+	result.HTTPVersions = versions
+}
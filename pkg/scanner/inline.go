@@ -0,0 +1,83 @@
+package scanner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// WithInlineTemplates runs the scan with the given template contents only,
+// instead of the configured template directories. The templates are
+// written to a temporary directory for the scan and removed after it, so
+// they never join the template library, its timings or its quarantine.
+func WithInlineTemplates(contents ...string) ScanOption {
+	return func(o *ScanOptions) {
+		for _, content := range contents {
+			if strings.TrimSpace(content) != "" {
+				o.InlineTemplates = append(o.InlineTemplates, content)
+			}
+		}
+	}
+}
+
+// inlineTemplateIDs returns the IDs of inline templates, rejecting content
+// that is not a template with an ID and IDs given twice
+func inlineTemplateIDs(contents []string) ([]string, error) {
+	ids := make([]string, 0, len(contents))
+	seen := map[string]bool{}
+	for i, content := range contents {
+		var header struct {
+			ID string `yaml:"id"`
+		}
+		// YAML is a superset of JSON, so this reads both
+		if err := yaml.Unmarshal([]byte(content), &header); err != nil {
+			return nil, fmt.Errorf("%w %d: %w", ErrInvalidInlineTemplate, i+1, err)
+		}
+		id := strings.TrimSpace(header.ID)
+		if id == "" {
+			return nil, fmt.Errorf("%w %d: missing id", ErrInvalidInlineTemplate, i+1)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("%w %d: id %s is used by another inline template", ErrInvalidInlineTemplate, i+1, id)
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// inlineKey keys the scan's inline templates by a hash of their contents
+func (o ScanOptions) inlineKey() string {
+	if len(o.InlineTemplates) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(strings.Join(o.InlineTemplates, "\x00")))
+	return ":inline=" + hex.EncodeToString(sum[:8])
+}
+
+// writeInlineTemplates writes the scan's inline templates to a temporary
+// directory added to the scan's template sources, returning the
+// function removing it
+func (o *ScanOptions) writeInlineTemplates() (func(), error) {
+	if len(o.InlineTemplates) == 0 {
+		return func() {}, nil
+	}
+	dir, err := os.MkdirTemp("", "nuclei-mcp-inline-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create inline templates directory: %w", err)
+	}
+	for i, content := range o.InlineTemplates {
+		path := filepath.Join(dir, fmt.Sprintf("inline-%d.yaml", i+1))
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			os.RemoveAll(dir)
+			return nil, fmt.Errorf("failed to write inline template: %w", err)
+		}
+	}
+	o.TemplateSources = append(o.TemplateSources, dir)
+	return func() { os.RemoveAll(dir) }, nil
+}
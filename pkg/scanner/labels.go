@@ -0,0 +1,85 @@
+package scanner
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxLabelValue is the longest label value, in bytes
+const maxLabelValue = 256
+
+// labelKeyPattern matches label keys: a letter or digit followed by up to
+// 62 letters, digits, dots, dashes, underscores or slashes
+var labelKeyPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/-]{0,62}$`)
+
+// WithLabels labels the scan with key/value pairs, such as
+// environment=staging or ticket=SEC-123, that are stored with its result.
+// Labels of earlier WithLabels options are kept unless overwritten.
+func WithLabels(labels map[string]string) ScanOption {
+	return func(o *ScanOptions) {
+		if len(labels) == 0 {
+			return
+		}
+		merged := make(map[string]string, len(o.Labels)+len(labels))
+		for key, value := range o.Labels {
+			merged[key] = value
+		}
+		for key, value := range labels {
+			merged[key] = value
+		}
+		o.Labels = merged
+	}
+}
+
+// ValidateLabels checks label keys against labelKeyPattern and that values
+// fit on one line of at most maxLabelValue bytes
+func ValidateLabels(labels map[string]string) error {
+	for key, value := range labels {
+		if !labelKeyPattern.MatchString(key) {
+			return fmt.Errorf("%w key %q, use letters, digits, '.', '-', '_' or '/'", ErrInvalidLabel, key)
+		}
+		if len(value) > maxLabelValue || strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("%w value for %s, use one line of at most %d bytes", ErrInvalidLabel, key, maxLabelValue)
+		}
+	}
+	return nil
+}
+
+// ParseLabels parses key=value pairs into labels
+func ParseLabels(pairs []string) (map[string]string, error) {
+	labels := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w %q, use key=value", ErrInvalidLabel, pair)
+		}
+		labels[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	if err := ValidateLabels(labels); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+// FormatLabels returns labels as comma-separated key=value pairs sorted by
+// key
+func FormatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// HasLabels reports whether labels include every key/value pair of want
+func HasLabels(labels map[string]string, want map[string]string) bool {
+	for key, value := range want {
+		if got, ok := labels[key]; !ok || got != value {
+			return false
+		}
+	}
+	return true
+}
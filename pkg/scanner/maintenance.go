@@ -0,0 +1,143 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Maintenance pauses scanning for template updates or host maintenance.
+// While paused, new scans are refused with ErrMaintenance and scans that
+// already started, including those waiting in the scan queue, run to
+// completion. Cached results are still served.
+type Maintenance struct {
+	mu     sync.Mutex
+	paused bool
+	reason string
+	since  time.Time
+	active int
+	// drained is closed when the last active scan ends
+	drained chan struct{}
+}
+
+// MaintenanceStatus reports whether scanning is paused and the scans still
+// draining
+type MaintenanceStatus struct {
+	Paused bool       `json:"paused"`
+	Reason string     `json:"reason,omitempty"`
+	Since  *time.Time `json:"since,omitempty"`
+	// Active counts the scans started before the pause that are still
+	// running or waiting in the scan queue
+	Active  int  `json:"active_scans"`
+	Drained bool `json:"drained"`
+}
+
+// NewMaintenance creates a maintenance switch with scanning enabled
+func NewMaintenance() *Maintenance {
+	drained := make(chan struct{})
+	close(drained)
+	return &Maintenance{drained: drained}
+}
+
+// WithMaintenance refuses new scans while m is paused
+func WithMaintenance(m *Maintenance) ServiceOption {
+	return func(s *scannerServiceImpl) {
+		s.maintenance = m
+	}
+}
+
+// Start admits a new scan and returns the function that ends it, or
+// ErrMaintenance while scanning is paused. A nil Maintenance admits every
+// scan.
+func (m *Maintenance) Start() (done func(), err error) {
+	if m == nil {
+		return func() {}, nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.paused {
+		return nil, m.pausedError()
+	}
+	if m.active == 0 {
+		m.drained = make(chan struct{})
+	}
+	m.active++
+	var once sync.Once
+	return func() { once.Do(m.end) }, nil
+}
+
+func (m *Maintenance) end() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.active--
+	if m.active == 0 {
+		close(m.drained)
+	}
+}
+
+// pausedError describes the pause. m.mu must be held.
+func (m *Maintenance) pausedError() error {
+	err := fmt.Errorf("%w since %s", ErrMaintenance, m.since.UTC().Format(time.RFC3339))
+	if m.reason != "" {
+		err = fmt.Errorf("%w: %s", err, m.reason)
+	}
+	return err
+}
+
+// Pause refuses new scans until Resume. Pausing again updates the reason.
+func (m *Maintenance) Pause(reason string) MaintenanceStatus {
+	m.mu.Lock()
+	if !m.paused {
+		m.paused = true
+		m.since = time.Now()
+	}
+	m.reason = reason
+	m.mu.Unlock()
+	return m.Status()
+}
+
+// Resume admits new scans again
+func (m *Maintenance) Resume() MaintenanceStatus {
+	m.mu.Lock()
+	m.paused = false
+	m.reason = ""
+	m.since = time.Time{}
+	m.mu.Unlock()
+	return m.Status()
+}
+
+// Status reports whether scanning is paused and the active scans
+func (m *Maintenance) Status() MaintenanceStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	status := MaintenanceStatus{Paused: m.paused, Reason: m.reason, Active: m.active, Drained: m.active == 0}
+	if m.paused {
+		since := m.since
+		status.Since = &since
+	}
+	return status
+}
+
+// Wait blocks until no scan is active or ctx is done
+func (m *Maintenance) Wait(ctx context.Context) error {
+	m.mu.Lock()
+	drained := m.drained
+	m.mu.Unlock()
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// startScan admits a scan of target unless scanning is paused
+func (s *scannerServiceImpl) startScan(console LoggerInterface, target string) (func(), error) {
+	done, err := s.maintenance.Start()
+	if err != nil {
+		console.Log("Scan of %s refused: %v", target, err)
+		return nil, err
+	}
+	return done, nil
+}
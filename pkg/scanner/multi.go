@@ -0,0 +1,166 @@
+package scanner
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+
+	"nuclei-mcp/pkg/cache"
+)
+
+const (
+	// DefaultHostConcurrency is the number of targets on the same host
+	// scanned at once
+	DefaultHostConcurrency = 2
+	// DefaultGlobalConcurrency is the number of targets scanned at once
+	DefaultGlobalConcurrency = 10
+)
+
+// Concurrency limits how many targets a multi-target scan runs at once
+type Concurrency struct {
+	// Host is the maximum number of concurrent scans per host
+	Host int
+	// Global is the maximum number of concurrent scans overall
+	Global int
+}
+
+// TargetResult is the outcome of scanning one target
+type TargetResult struct {
+	Target string
+	Result cache.ScanResult
+	Err    error
+}
+
+// HostResult groups the target results of one host
+type HostResult struct {
+	Host    string
+	Targets []TargetResult
+}
+
+// Findings returns the number of findings across the host's targets
+func (h HostResult) Findings() int {
+	total := 0
+	for _, target := range h.Targets {
+		total += len(target.Result.Findings)
+	}
+	return total
+}
+
+// MultiScanner scans many targets in parallel through a ScannerService's
+// thread-safe scans, bounded by per-host and global concurrency limits
+type MultiScanner struct {
+	service ScannerService
+	limits  Concurrency
+}
+
+// NewMultiScanner creates a multi-target scanner. Limits below 1 use the
+// defaults.
+func NewMultiScanner(service ScannerService, limits Concurrency) *MultiScanner {
+	if limits.Host < 1 {
+		limits.Host = DefaultHostConcurrency
+	}
+	if limits.Global < 1 {
+		limits.Global = DefaultGlobalConcurrency
+	}
+	return &MultiScanner{service: service, limits: limits}
+}
+
+// Limits returns the concurrency limits
+func (m *MultiScanner) Limits() Concurrency {
+	return m.limits
+}
+
+// Scan scans the targets and returns their results grouped by host, in the
+// order hosts first appear in targets. A failed target does not stop the
+// others; its error is recorded in its TargetResult.
+func (m *MultiScanner) Scan(ctx context.Context, targets []string, severity string, protocols string, templateIDs []string, opts ...ScanOption) []HostResult {
+	var hosts []HostResult
+	hostIndex := map[string]int{}
+	hostSlots := map[string]chan struct{}{}
+	globalSlots := make(chan struct{}, m.limits.Global)
+
+	type job struct {
+		host, target int
+		slots        chan struct{}
+	}
+	var jobs []job
+	for _, target := range targets {
+		target = strings.TrimSpace(target)
+		if target == "" {
+			continue
+		}
+		host := TargetHost(target)
+		i, ok := hostIndex[host]
+		if !ok {
+			i = len(hosts)
+			hostIndex[host] = i
+			hosts = append(hosts, HostResult{Host: host})
+			hostSlots[host] = make(chan struct{}, m.limits.Host)
+		}
+		jobs = append(jobs, job{host: i, target: len(hosts[i].Targets), slots: hostSlots[host]})
+		hosts[i].Targets = append(hosts[i].Targets, TargetResult{Target: target})
+	}
+
+	var wg sync.WaitGroup
+	for _, j := range jobs {
+		wg.Add(1)
+		go func(j job) {
+			defer wg.Done()
+			result := &hosts[j.host].Targets[j.target]
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					result.Err = panicError(recovered)
+				}
+			}()
+
+			// Take the host slot first so scans waiting on a busy host do not
+			// hold global slots other hosts could use
+			if err := acquire(ctx, j.slots); err != nil {
+				result.Err = err
+				return
+			}
+			defer func() { <-j.slots }()
+			if err := acquire(ctx, globalSlots); err != nil {
+				result.Err = err
+				return
+			}
+			defer func() { <-globalSlots }()
+
+			result.Result, result.Err = m.service.ThreadSafeScan(ctx, result.Target, severity, protocols, templateIDs, opts...)
+		}(j)
+	}
+	wg.Wait()
+
+	return hosts
+}
+
+// acquire takes a slot, giving up when ctx is done
+func acquire(ctx context.Context, slots chan struct{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	select {
+	case slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TargetHost returns the lowercased host name of a target URL, host:port or
+// bare host
+func TargetHost(target string) string {
+	raw := strings.TrimSpace(target)
+	if !strings.Contains(raw, "://") {
+		raw = "//" + raw
+	}
+	if u, err := url.Parse(raw); err == nil && u.Hostname() != "" {
+		return strings.ToLower(u.Hostname())
+	}
+	if host, _, err := net.SplitHostPort(target); err == nil {
+		return strings.ToLower(host)
+	}
+	return strings.ToLower(strings.TrimSpace(target))
+}
@@ -0,0 +1,456 @@
+package scanner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi2"
+	"github.com/getkin/kin-openapi/openapi2conv"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/projectdiscovery/nuclei/v3/pkg/input/formats"
+	"github.com/projectdiscovery/nuclei/v3/pkg/input/formats/openapi"
+	"github.com/projectdiscovery/nuclei/v3/pkg/input/types"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// maxOpenAPISize bounds the size of an OpenAPI document, in bytes
+	maxOpenAPISize = 8 << 20
+	// maxAPIOperations bounds the requests generated from an OpenAPI
+	// document, each of which every HTTP template of the scan runs against
+	maxAPIOperations = 500
+	// openAPIFetchTimeout bounds how long fetching an OpenAPI document takes
+	openAPIFetchTimeout = 30 * time.Second
+)
+
+// WithOpenAPI runs the scan's HTTP templates against the operations of an
+// OpenAPI 3 or Swagger 2.0 document, in JSON or YAML, sent to the scan
+// target with their methods, paths and example parameters. parameters set
+// the values of the document's parameters and security schemes, such as
+// api_key or Authorization; like variables, a value of the form
+// secret://name references a configured secret.
+func WithOpenAPI(spec []byte, parameters map[string]string) ScanOption {
+	return func(o *ScanOptions) {
+		o.OpenAPI = spec
+		o.APIParameters = parameters
+	}
+}
+
+// WithFuzzing runs nuclei's DAST templates, which fuzz the parameters,
+// headers and bodies of the operations of the scan's OpenAPI document,
+// instead of its regular HTTP templates
+func WithFuzzing() ScanOption {
+	return func(o *ScanOptions) {
+		o.Fuzz = true
+	}
+}
+
+// OpenAPIFetcher is implemented by scanner services that fetch OpenAPI
+// documents under the egress policy and self-target guard of their scans
+type OpenAPIFetcher interface {
+	FetchOpenAPI(ctx context.Context, specURL string, opts ...ScanOption) ([]byte, error)
+}
+
+// maxOpenAPIRedirects bounds the redirects followed when fetching an
+// OpenAPI document
+const maxOpenAPIRedirects = 5
+
+// FetchOpenAPI downloads the OpenAPI document at specURL, which must pass
+// the checks of a scan target. Redirects are followed on the same host
+// only, and each is checked again; of opts, only WithSelfTargetAllowed
+// applies.
+func (s *scannerServiceImpl) FetchOpenAPI(ctx context.Context, specURL string, opts ...ScanOption) ([]byte, error) {
+	u, err := url.Parse(specURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: spec_url %q, use an http or https URL", ErrInvalidOpenAPI, specURL)
+	}
+	var scanOpts ScanOptions
+	for _, opt := range opts {
+		opt(&scanOpts)
+	}
+	check := func(u *url.URL) error {
+		if err := s.egress.CheckTarget(ctx, u.String()); err != nil {
+			s.logger(ctx).Log("Fetch of OpenAPI document %s refused: %v", u.Redacted(), err)
+			return err
+		}
+		return s.checkSelfTarget(ctx, u.String(), scanOpts)
+	}
+	if err := check(u); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, openAPIFetchTimeout)
+	defer cancel()
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if !strings.EqualFold(req.URL.Host, u.Host) {
+				return fmt.Errorf("%w: spec_url redirects to another host, %s; pass that URL instead", ErrInvalidOpenAPI, req.URL.Redacted())
+			}
+			if len(via) >= maxOpenAPIRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxOpenAPIRedirects)
+			}
+			return check(req.URL)
+		},
+	}
+	defer client.CloseIdleConnections()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch OpenAPI document: %w", err)
+	}
+	req.Header.Set("Accept", "application/json, application/yaml;q=0.9, */*;q=0.8")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch OpenAPI document: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch OpenAPI document: %s answered %s", u.Redacted(), resp.Status)
+	}
+	spec, err := io.ReadAll(io.LimitReader(resp.Body, maxOpenAPISize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch OpenAPI document: %w", err)
+	}
+	if len(spec) > maxOpenAPISize {
+		return nil, fmt.Errorf("%w: larger than %d bytes", ErrInvalidOpenAPI, maxOpenAPISize)
+	}
+	return spec, nil
+}
+
+// OpenAPITarget returns the first absolute server URL of an OpenAPI
+// document, the target its operations are scanned on when none is given
+func OpenAPITarget(spec []byte) (string, error) {
+	schema, err := parseOpenAPI(spec)
+	if err != nil {
+		return "", err
+	}
+	for _, server := range schema.Servers {
+		if u, err := url.Parse(serverURL(server)); err == nil && u.Host != "" && (u.Scheme == "http" || u.Scheme == "https") {
+			return u.String(), nil
+		}
+	}
+	return "", fmt.Errorf("%w: the document has no absolute server URL, pass a target", ErrInvalidOpenAPI)
+}
+
+// ValidateAPIParameters checks that the names of API parameters are
+// header, query or body parameter names and that their values fit on one
+// line of at most maxVariableValue bytes
+func ValidateAPIParameters(params map[string]string) error {
+	for name, value := range params {
+		if name == "" || len(name) > 128 || strings.ContainsAny(name, " \t\r\n:=") {
+			return fmt.Errorf("%w name %q", ErrInvalidAPIParameter, name)
+		}
+		if len(value) > maxVariableValue || strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("%w value for %s, use one line of at most %d bytes", ErrInvalidAPIParameter, name, maxVariableValue)
+		}
+	}
+	return nil
+}
+
+// checkOpenAPI validates the OpenAPI document of a scan and its parameters,
+// and that the secrets they reference are configured, without reading them
+func (s *scannerServiceImpl) checkOpenAPI(scanOpts ScanOptions) error {
+	if len(scanOpts.OpenAPI) == 0 {
+		if scanOpts.Fuzz || len(scanOpts.APIParameters) > 0 {
+			return fmt.Errorf("%w: fuzzing and API parameters need an OpenAPI document", ErrInvalidOpenAPI)
+		}
+		return nil
+	}
+	if len(scanOpts.OpenAPI) > maxOpenAPISize {
+		return fmt.Errorf("%w: larger than %d bytes", ErrInvalidOpenAPI, maxOpenAPISize)
+	}
+	if _, err := parseOpenAPI(scanOpts.OpenAPI); err != nil {
+		return err
+	}
+	if err := ValidateAPIParameters(scanOpts.APIParameters); err != nil {
+		return err
+	}
+	return s.checkSecrets(scanOpts.APIParameters)
+}
+
+// openAPIKey identifies the OpenAPI document of a scan in its cache key
+func openAPIKey(spec []byte) string {
+	sum := sha256.Sum256(spec)
+	return hex.EncodeToString(sum[:8])
+}
+
+// parseOpenAPI parses an OpenAPI 3 or Swagger 2.0 document, converting the
+// latter to OpenAPI 3. External references are not followed.
+func parseOpenAPI(spec []byte) (*openapi3.T, error) {
+	var version struct {
+		Swagger string `yaml:"swagger"`
+		OpenAPI string `yaml:"openapi"`
+	}
+	// YAML is a superset of JSON, so this reads both
+	if err := yaml.Unmarshal(spec, &version); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidOpenAPI, err)
+	}
+
+	loader := openapi3.NewLoader()
+	var schema *openapi3.T
+	switch {
+	case strings.HasPrefix(version.OpenAPI, "3."):
+		var err error
+		if schema, err = loader.LoadFromData(spec); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidOpenAPI, err)
+		}
+	case strings.HasPrefix(version.Swagger, "2."):
+		var document any
+		if err := yaml.Unmarshal(spec, &document); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidOpenAPI, err)
+		}
+		data, err := json.Marshal(document)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidOpenAPI, err)
+		}
+		var v2 openapi2.T
+		if err := json.Unmarshal(data, &v2); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidOpenAPI, err)
+		}
+		if schema, err = openapi2conv.ToV3(&v2); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidOpenAPI, err)
+		}
+		if err := loader.ResolveRefsIn(schema, nil); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidOpenAPI, err)
+		}
+	default:
+		return nil, fmt.Errorf("%w: not an OpenAPI 3 or Swagger 2.0 document", ErrInvalidOpenAPI)
+	}
+	if schema.Paths == nil || schema.Paths.Len() == 0 {
+		return nil, fmt.Errorf("%w: the document has no paths", ErrInvalidOpenAPI)
+	}
+	return schema, nil
+}
+
+// serverURL returns the URL of server with its variables set to their
+// defaults
+func serverURL(server *openapi3.Server) string {
+	raw := server.URL
+	for name, variable := range server.Variables {
+		if variable != nil {
+			raw = strings.ReplaceAll(raw, "{"+name+"}", variable.Default)
+		}
+	}
+	return raw
+}
+
+// apiBaseURL returns the URL the operations of a document are sent to: the
+// scan target, followed by the path of the document's first server unless
+// the target has a path of its own
+func apiBaseURL(target string, servers openapi3.Servers) (string, error) {
+	raw := target
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("%w: target %q is not a URL or host", ErrInvalidOpenAPI, target)
+	}
+	u.RawQuery, u.Fragment = "", ""
+	if strings.Trim(u.Path, "/") == "" && len(servers) > 0 {
+		if server, err := url.Parse(serverURL(servers[0])); err == nil {
+			u.Path = server.Path
+		}
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	return u.String(), nil
+}
+
+// apiRequests are the requests generated from the OpenAPI document of a
+// running scan
+type apiRequests struct {
+	requests []*types.RequestResponse
+	fuzz     bool
+	// params are the resolved API parameters, whose secrets are redacted
+	// from findings
+	params *scanVariables
+}
+
+type apiRequestsKey struct{}
+
+// newAPIRequests reads the secrets referenced by the API parameters of a
+// scan and generates the requests of its OpenAPI document for target,
+// returning nil when the scan has no document
+func (s *scannerServiceImpl) newAPIRequests(target string, scanOpts ScanOptions) (*apiRequests, error) {
+	if len(scanOpts.OpenAPI) == 0 {
+		return nil, nil
+	}
+	params, err := s.resolveVariables(scanOpts.APIParameters)
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]any
+	if params != nil {
+		values = params.values
+	}
+	requests, err := generateAPIRequests(scanOpts.OpenAPI, target, values, scanOpts.Fuzz)
+	if err != nil {
+		return nil, err
+	}
+	return &apiRequests{requests: requests, fuzz: scanOpts.Fuzz, params: params}, nil
+}
+
+// generateAPIRequests generates a request for each operation of an OpenAPI
+// document, sent to target. Regular templates only use the request URL, so
+// operations sharing their URL are scanned once unless fuzzing.
+func generateAPIRequests(spec []byte, target string, values map[string]any, fuzz bool) ([]*types.RequestResponse, error) {
+	schema, err := parseOpenAPI(spec)
+	if err != nil {
+		return nil, err
+	}
+	base, err := apiBaseURL(target, schema.Servers)
+	if err != nil {
+		return nil, err
+	}
+	schema.Servers = openapi3.Servers{{URL: base}}
+	if schema.Components == nil {
+		schema.Components = &openapi3.Components{}
+	}
+
+	if err := inlineSecurity(schema, values); err != nil {
+		return nil, err
+	}
+
+	var requests []*types.RequestResponse
+	seen := map[string]bool{}
+	err = openapi.GenerateRequestsFromSchema(schema, formats.InputFormatOptions{Variables: values, SkipFormatValidation: true}, func(rr *types.RequestResponse) bool {
+		if id := rr.ID(); !seen[id] {
+			seen[id] = true
+			requests = append(requests, rr)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidOpenAPI, err)
+	}
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("%w: no requests could be generated from the document's operations", ErrInvalidOpenAPI)
+	}
+	// GET sorts before the other methods of an URL, so it is the one kept
+	sort.Slice(requests, func(i, j int) bool {
+		a, b := operationName(requests[i]), operationName(requests[j])
+		if urlA, urlB := requests[i].URL.String(), requests[j].URL.String(); urlA != urlB {
+			return urlA < urlB
+		}
+		if getA, getB := strings.HasPrefix(a, http.MethodGet+" "), strings.HasPrefix(b, http.MethodGet+" "); getA != getB {
+			return getA
+		}
+		return a < b
+	})
+	if !fuzz {
+		unique := requests[:0]
+		for i, rr := range requests {
+			if i == 0 || rr.URL.String() != requests[i-1].URL.String() {
+				unique = append(unique, rr)
+			}
+		}
+		requests = unique
+	}
+	if len(requests) > maxAPIOperations {
+		requests = requests[:maxAPIOperations]
+	}
+	return requests, nil
+}
+
+// inlineSecurity adds the parameters of the security schemes each
+// operation requires, such as an API key header, to its parameters. nuclei
+// skips the security parameters it generates itself, which have no schema,
+// and silently generates nothing when one has no value.
+func inlineSecurity(schema *openapi3.T, values map[string]any) error {
+	missing := map[string]bool{}
+	for _, item := range schema.Paths.Map() {
+		for _, op := range item.Operations() {
+			requirements := &schema.Security
+			if op.Security != nil {
+				requirements = op.Security
+			}
+			op.Security = nil
+			if len(*requirements) == 0 {
+				continue
+			}
+			params, err := openapi.GetGlobalParamsForSecurityRequirement(schema, requirements)
+			if err != nil {
+				return fmt.Errorf("%w: %w", ErrInvalidOpenAPI, err)
+			}
+			for _, param := range params {
+				if _, ok := values[param.Value.Name]; !ok {
+					missing[param.Value.Name] = true
+					continue
+				}
+				param.Value.Schema = openapi3.NewStringSchema().NewRef()
+				op.Parameters = append(op.Parameters, param)
+			}
+		}
+	}
+	schema.Security = nil
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: the API requires %s, set them in the scan's API parameters", ErrInvalidAPIParameter, strings.Join(sortedKeys(missing), ", "))
+	}
+	return nil
+}
+
+// operationName describes a generated request as its method and URL
+func operationName(rr *types.RequestResponse) string {
+	method := http.MethodGet
+	if rr.Request != nil && rr.Request.Method != "" {
+		method = rr.Request.Method
+	}
+	return method + " " + rr.URL.String()
+}
+
+// withAPIRequests returns ctx carrying the requests of the scan's OpenAPI
+// document to the templates the scan runs
+func withAPIRequests(ctx context.Context, requests *apiRequests) context.Context {
+	if requests == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, apiRequestsKey{}, requests)
+}
+
+// apiRequestsFrom returns the OpenAPI requests of the scan running ctx
+func apiRequestsFrom(ctx context.Context) *apiRequests {
+	requests, _ := ctx.Value(apiRequestsKey{}).(*apiRequests)
+	return requests
+}
+
+// fuzzing reports whether the scan runs DAST templates, which only engines
+// created with its options load
+func (a *apiRequests) fuzzing() bool {
+	return a != nil && a.fuzz
+}
+
+// operations lists the scanned operations as their method and URL, with
+// secret parameter values redacted
+func (a *apiRequests) operations() []string {
+	if a == nil {
+		return nil
+	}
+	operations := make([]string, 0, len(a.requests))
+	for _, rr := range a.requests {
+		name := operationName(rr)
+		if a.params != nil && a.params.redact != nil {
+			name = a.params.redact.Replace(name)
+		}
+		operations = append(operations, name)
+	}
+	return operations
+}
+
+// redacting wraps a result callback to replace the secret API parameter
+// values in findings with their secret:// references
+func (a *apiRequests) redacting(callback func(*output.ResultEvent)) func(*output.ResultEvent) {
+	if a == nil {
+		return callback
+	}
+	return a.params.redacting(callback)
+}
@@ -0,0 +1,446 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/policy"
+
+	nuclei "github.com/projectdiscovery/nuclei/v3/lib"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// PassiveProtocols are the template protocols passive scans are limited
+	// to: DNS lookups and TLS handshakes, which do not exercise the target's
+	// application
+	PassiveProtocols = "dns,ssl"
+	// DefaultPassiveRateLimit is the requests per second of passive scans
+	DefaultPassiveRateLimit = 5
+)
+
+// ServiceOption configures the scanner service
+type ServiceOption func(*scannerServiceImpl)
+
+// WithTemplateDirs adds template directories (such as offline bundles) that
+// are scanned in addition to the default nuclei templates directory
+func WithTemplateDirs(dirs ...string) ServiceOption {
+	return func(s *scannerServiceImpl) {
+		s.templateDirs = append(s.templateDirs, dirs...)
+	}
+}
+
+// WithShadowedTemplates excludes template files from every scan of the
+// template directories, such as templates overridden by a template with the
+// same ID in a higher priority directory. shadowed is called whenever a scan
+// engine loads its templates.
+func WithShadowedTemplates(shadowed func() []string) ServiceOption {
+	return func(s *scannerServiceImpl) {
+		s.shadowed = shadowed
+	}
+}
+
+// WithCodeTemplatesAllowed permits scans to opt in to code protocol templates.
+// Code templates execute commands on the scanning host, so this should only be
+// enabled when the server runs in an isolated environment.
+func WithCodeTemplatesAllowed(allowed bool) ServiceOption {
+	return func(s *scannerServiceImpl) {
+		s.allowCodeTemplates = allowed
+	}
+}
+
+// WithDeniedTags excludes templates carrying any of the given tags from every
+// scan unless the scan is explicitly approved with WithUnsafeTemplates
+func WithDeniedTags(tags []string) ServiceOption {
+	return func(s *scannerServiceImpl) {
+		s.deniedTags = append(s.deniedTags, tags...)
+	}
+}
+
+// WithPassiveByDefault makes scans passive unless a scan opts out with
+// WithPassive(false), a safe default for agents without explicit
+// authorization levels
+func WithPassiveByDefault(enabled bool) ServiceOption {
+	return func(s *scannerServiceImpl) {
+		s.passiveByDefault = enabled
+	}
+}
+
+// WithPassiveRateLimit sets the requests per second of passive scans
+func WithPassiveRateLimit(requestsPerSecond int) ServiceOption {
+	return func(s *scannerServiceImpl) {
+		if requestsPerSecond > 0 {
+			s.passiveRateLimit = requestsPerSecond
+		}
+	}
+}
+
+// WithMaxRateLimit caps the requests per second of every scan, including
+// scans that set no rate limit of their own
+func WithMaxRateLimit(requestsPerSecond int) ServiceOption {
+	return func(s *scannerServiceImpl) {
+		if requestsPerSecond > 0 {
+			s.maxRateLimit = requestsPerSecond
+		}
+	}
+}
+
+// ExclusionMatcher returns the template IDs and tags excluded for a target
+type ExclusionMatcher interface {
+	Match(target string) (templateIDs []string, tags []string)
+}
+
+// WithExclusions skips the templates excluded for a target by persistent
+// exclusion rules, so known-noisy template/target combinations stop
+// reporting without suppressing the templates everywhere
+func WithExclusions(exclusions ExclusionMatcher) ServiceOption {
+	return func(s *scannerServiceImpl) {
+		s.exclusions = exclusions
+	}
+}
+
+// HostEnricher describes where a scanned host is hosted
+type HostEnricher interface {
+	Enrich(ctx context.Context, host string) cache.HostInfo
+}
+
+// WithHostEnricher attaches a description of the scan target's host and of
+// the hosts of its findings to scan results
+func WithHostEnricher(enricher HostEnricher) ServiceOption {
+	return func(s *scannerServiceImpl) {
+		s.enricher = enricher
+	}
+}
+
+// ResultNotifier is told of each completed scan, such as to alert on its
+// findings. Notify must not block the scan.
+type ResultNotifier interface {
+	Notify(result cache.ScanResult)
+}
+
+// WithResultNotifier tells notifier of the results of completed scans;
+// results served from the cache are not notified again
+func WithResultNotifier(notifier ResultNotifier) ServiceOption {
+	return func(s *scannerServiceImpl) {
+		s.notifier = notifier
+	}
+}
+
+// WithEgressPolicy restricts the addresses and ports scans may connect to.
+// Targets are checked before a scan starts, and the denied ranges are passed
+// to nuclei's dialer so every connection a template makes is checked too.
+func WithEgressPolicy(egress *policy.EgressPolicy) ServiceOption {
+	return func(s *scannerServiceImpl) {
+		s.egress = egress
+	}
+}
+
+// egressOption adds the egress deny ranges to the engine's exclude list, which
+// nuclei's dialer enforces for each resolved address it connects to
+func egressOption(egress *policy.EgressPolicy) nuclei.NucleiSDKOptions {
+	return func(e *nuclei.NucleiEngine) error {
+		opts := e.Options()
+		opts.ExcludeTargets = append(opts.ExcludeTargets, egress.DenyList()...)
+		return nil
+	}
+}
+
+// ScanOptions holds optional per-scan settings
+type ScanOptions struct {
+	// CodeTemplates enables code protocol templates (including flow templates
+	// using code requests) for this scan
+	CodeTemplates bool
+	// AllowUnsafe lifts the denied tags exclusion for this scan
+	AllowUnsafe bool
+	// CorrelationID ties the scan's logs, findings and result to the request
+	// that started it
+	CorrelationID string
+	// AllowSelfTarget lifts the self-target guard for this scan
+	AllowSelfTarget bool
+	// Approval records who approved running denied templates or scanning
+	// the server's own host, and why
+	Approval string
+	// Extractors collect data from the target's HTTP response
+	Extractors []Extractor
+	// Passive limits the scan to PassiveProtocols templates with a strict
+	// rate limit
+	Passive bool
+	// Tags limits the scan to templates carrying any of these tags
+	Tags []string
+	// ExcludeTags, ExcludeIDs and ExcludeSeverities leave templates out of
+	// the scan, on top of the denied tags and exclusion rules
+	ExcludeTags       []string
+	ExcludeIDs        []string
+	ExcludeSeverities []string
+	// Authors limits the scan to templates by any of these authors
+	Authors []string
+	// DefaultTemplateIDs are run when the scan names no template IDs
+	DefaultTemplateIDs []string
+	// RateLimit caps the requests per second of the scan; zero leaves
+	// nuclei's default. Passive scans use the passive rate limit instead.
+	RateLimit int
+	// TemplateSources replaces the configured template directories with
+	// these template files and directories for the scan
+	TemplateSources []string
+	// InlineTemplates are template contents run instead of the configured
+	// template directories, written to a temporary directory for the scan
+	InlineTemplates []string
+	// Priority orders the scan in the service's scan queue
+	Priority Priority
+	// Client names who requested the scan, for quota accounting
+	Client string
+	// Fresh scans again instead of returning a cached result; the new
+	// result replaces the cached one
+	Fresh bool
+	// StopAt ends the scan at its first finding of this severity or above,
+	// or at its first finding at all with StopAny; empty runs every template
+	StopAt string
+	// TemplateTimeout bounds how long each template runs against the
+	// target; zero leaves templates unbounded
+	TemplateTimeout time.Duration
+	// MaxTemplateRequests skips templates sending more requests than this
+	// to the target; zero runs every template
+	MaxTemplateRequests int
+	// Labels are key/value pairs, such as environment=staging, stored with
+	// the scan's result for filtering its exports
+	Labels map[string]string
+	// Variables set template variables for the scan; secret:// values
+	// reference configured secrets, which are read only when it runs
+	Variables map[string]string
+	// ProbeHTTPVersions reports whether the target supports HTTP/2 and
+	// advertises HTTP/3 with the scan result
+	ProbeHTTPVersions bool
+	// AddressFamily selects whether HTTP templates connect to the target's
+	// host over IPv4, IPv6 or both
+	AddressFamily AddressFamily
+	// SNI is the TLS server name the scan sends instead of the target's
+	// host; empty sends the target's
+	SNI string
+	// HostHeader is the Host header HTTP templates send while connecting to
+	// the target, for scanning a site on its origin IP; empty sends the
+	// target's
+	HostHeader string
+	// DetectWAF fingerprints the WAF in front of the target before the scan
+	// and marks findings whose responses look like its block pages
+	DetectWAF bool
+	// OpenAPI is an OpenAPI 3 or Swagger 2.0 document whose operations the
+	// scan's HTTP templates run against instead of the target alone
+	OpenAPI []byte
+	// APIParameters set the values of the OpenAPI document's parameters
+	// and security schemes; secret:// values reference configured secrets
+	APIParameters map[string]string
+	// Fuzz runs DAST templates fuzzing the OpenAPI document's operations
+	// instead of regular HTTP templates
+	Fuzz bool
+	// CrawlDepth crawls the target's links this many pages deep before the
+	// scan, running its HTTP templates against the URLs found too; zero
+	// does not crawl
+	CrawlDepth int
+	// CrawlLimit bounds the URLs a crawl collects
+	CrawlLimit int
+	// CrawlScope accepts the crawled URLs the scan may run against, such as
+	// those inside the scope its target was checked against; nil accepts
+	// every URL on the target's host
+	CrawlScope func(url string) bool
+	// SkipUnchanged returns the cached result of the same scan, even when
+	// expired, instead of scanning when the target's response is unchanged
+	// since
+	SkipUnchanged bool
+
+	// excludedIDs and excludedTags are set from the exclusion rules
+	// matching the scan target
+	excludedIDs  []string
+	excludedTags []string
+	// tuning is the adaptive tuning adjustment in effect for the scan target
+	tuning *cache.Adjustment
+	// traceParent is the span Scan, which takes no context, starts its
+	// span under
+	traceParent trace.SpanContext
+}
+
+// ScanOption configures a single scan
+type ScanOption func(*ScanOptions)
+
+// WithCodeTemplates enables code protocol templates for the scan. The scan
+// fails if code templates are not allowed for the service.
+func WithCodeTemplates() ScanOption {
+	return func(o *ScanOptions) {
+		o.CodeTemplates = true
+	}
+}
+
+// WithUnsafeTemplates lifts the denied tags exclusion for the scan. The
+// approval (e.g. a ticket ID or approver) is required and logged.
+func WithUnsafeTemplates(approval string) ScanOption {
+	return func(o *ScanOptions) {
+		o.AllowUnsafe = true
+		o.Approval = approval
+	}
+}
+
+// WithExtractors collects values from the target's HTTP response alongside
+// the findings, without writing a custom template
+func WithExtractors(extractors ...Extractor) ScanOption {
+	return func(o *ScanOptions) {
+		o.Extractors = append(o.Extractors, extractors...)
+	}
+}
+
+// WithPassive turns passive mode on or off for the scan, overriding the
+// service default
+func WithPassive(enabled bool) ScanOption {
+	return func(o *ScanOptions) {
+		o.Passive = enabled
+	}
+}
+
+// WithTags limits the scan to templates carrying any of the given tags
+func WithTags(tags ...string) ScanOption {
+	return func(o *ScanOptions) {
+		for _, tag := range tags {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				o.Tags = append(o.Tags, tag)
+			}
+		}
+	}
+}
+
+// WithRateLimit caps the requests per second of the scan
+func WithRateLimit(requestsPerSecond int) ScanOption {
+	return func(o *ScanOptions) {
+		if requestsPerSecond > 0 {
+			o.RateLimit = requestsPerSecond
+		}
+	}
+}
+
+// WithTemplateSources runs the scan with templates from the given files and
+// directories only, instead of the configured template directories
+func WithTemplateSources(paths ...string) ScanOption {
+	return func(o *ScanOptions) {
+		o.TemplateSources = append(o.TemplateSources, paths...)
+	}
+}
+
+// WithPriority sets the scan's priority in the scan queue; scans are
+// interactive by default
+func WithPriority(priority Priority) ScanOption {
+	return func(o *ScanOptions) {
+		o.Priority = priority
+	}
+}
+
+// WithFreshResult scans again even when a result for the same scan is
+// cached, such as to verify a fix
+func WithFreshResult() ScanOption {
+	return func(o *ScanOptions) {
+		o.Fresh = true
+	}
+}
+
+// resolveScanOptions applies the scan options and checks them against the
+// service configuration, and applies the exclusion rules of target
+func (s *scannerServiceImpl) resolveScanOptions(target string, opts []ScanOption) (ScanOptions, error) {
+	scanOpts := ScanOptions{Passive: s.passiveByDefault}
+	for _, opt := range opts {
+		opt(&scanOpts)
+	}
+	console := withCorrelation(s.console, scanOpts.CorrelationID)
+
+	if scanOpts.CodeTemplates && !s.allowCodeTemplates {
+		return ScanOptions{}, fmt.Errorf("code templates are disabled, enable scanner.allow_code_templates in config to use them")
+	}
+
+	if err := ValidateSeverities(scanOpts.ExcludeSeverities); err != nil {
+		return ScanOptions{}, err
+	}
+
+	if _, err := inlineTemplateIDs(scanOpts.InlineTemplates); err != nil {
+		return ScanOptions{}, err
+	}
+
+	if scanOpts.Passive {
+		switch {
+		case scanOpts.CodeTemplates:
+			return ScanOptions{}, fmt.Errorf("code templates cannot run in passive mode")
+		case scanOpts.AllowUnsafe:
+			return ScanOptions{}, fmt.Errorf("denied templates cannot run in passive mode")
+		case len(scanOpts.Extractors) > 0:
+			return ScanOptions{}, fmt.Errorf("extractors send HTTP requests and cannot run in passive mode")
+		case scanOpts.ProbeHTTPVersions:
+			return ScanOptions{}, fmt.Errorf("the HTTP version probe sends an HTTP request and cannot run in passive mode")
+		case len(scanOpts.OpenAPI) > 0:
+			return ScanOptions{}, fmt.Errorf("API operations are scanned with HTTP templates, which cannot run in passive mode")
+		case scanOpts.CrawlDepth > 0:
+			return ScanOptions{}, fmt.Errorf("crawling sends HTTP requests and cannot run in passive mode")
+		case scanOpts.SkipUnchanged:
+			return ScanOptions{}, fmt.Errorf("change detection sends an HTTP request and cannot run in passive mode")
+		}
+	}
+
+	if scanOpts.AllowUnsafe {
+		if strings.TrimSpace(scanOpts.Approval) == "" {
+			return ScanOptions{}, fmt.Errorf("running denied templates requires an approval")
+		}
+		console.Log("Denied template tags lifted for scan, approval: %s", scanOpts.Approval)
+	}
+
+	if scanOpts.AllowSelfTarget && strings.TrimSpace(scanOpts.Approval) == "" {
+		return ScanOptions{}, fmt.Errorf("scanning the server's own host requires an approval")
+	}
+
+	if err := checkStopAt(scanOpts.StopAt); err != nil {
+		return ScanOptions{}, err
+	}
+
+	if err := s.checkVariables(scanOpts.Variables); err != nil {
+		return ScanOptions{}, err
+	}
+
+	if err := ValidateHostOverrides(scanOpts.SNI, scanOpts.HostHeader); err != nil {
+		return ScanOptions{}, err
+	}
+
+	if err := s.checkOpenAPI(scanOpts); err != nil {
+		return ScanOptions{}, err
+	}
+
+	if err := checkCrawl(&scanOpts); err != nil {
+		return ScanOptions{}, err
+	}
+
+	if len(scanOpts.Extractors) > 0 {
+		extractors, err := resolveExtractors(scanOpts.Extractors)
+		if err != nil {
+			return ScanOptions{}, err
+		}
+		scanOpts.Extractors = extractors
+	}
+
+	scanOpts.TemplateTimeout = capLimit(scanOpts.TemplateTimeout, s.safety.TemplateTimeout)
+	scanOpts.MaxTemplateRequests = capLimit(scanOpts.MaxTemplateRequests, s.safety.MaxTemplateRequests)
+
+	if s.maxRateLimit > 0 && (scanOpts.RateLimit == 0 || scanOpts.RateLimit > s.maxRateLimit) {
+		scanOpts.RateLimit = s.maxRateLimit
+	}
+
+	if scanOpts.Passive {
+		console.Log("Passive scan: limited to %s templates at %d requests/s", PassiveProtocols, s.passiveRateLimit)
+	}
+
+	if s.exclusions != nil {
+		scanOpts.excludedIDs, scanOpts.excludedTags = s.exclusions.Match(target)
+		if len(scanOpts.excludedIDs) > 0 || len(scanOpts.excludedTags) > 0 {
+			console.Log("Exclusion rules for %s: skipping templates %v and tags %v", target, scanOpts.excludedIDs, scanOpts.excludedTags)
+		}
+	}
+
+	if scanOpts.tuning = s.adaptive.lookup(target); scanOpts.tuning != nil {
+		console.Log("Adaptive tuning: scanning %s at %d requests/s and concurrency %d", scanOpts.tuning.Host, scanOpts.tuning.RateLimit, scanOpts.tuning.Concurrency)
+	}
+
+	return scanOpts, nil
+}
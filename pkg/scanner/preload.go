@@ -0,0 +1,211 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"nuclei-mcp/pkg/telemetry"
+
+	nuclei "github.com/projectdiscovery/nuclei/v3/lib"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	"github.com/projectdiscovery/nuclei/v3/pkg/protocols/common/protocolinit"
+	"github.com/projectdiscovery/nuclei/v3/pkg/protocols/common/protocolstate"
+	"github.com/projectdiscovery/nuclei/v3/pkg/types"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Preloader is implemented by scanner services that can warm up their scan
+// engine ahead of the first scan
+type Preloader interface {
+	Preload(ctx context.Context) error
+}
+
+// warmEngine is a long-lived thread-safe engine whose template parser already
+// holds the parsed and compiled template set. It runs one scan at a time so
+// results can be routed to the scan that produced them.
+type warmEngine struct {
+	engine  *nuclei.ThreadSafeNucleiEngine
+	base    *nuclei.NucleiEngine
+	options *types.Options
+	// relay passes the requests of the running scan to its monitor
+	relay monitorRelay
+
+	busy    sync.Mutex
+	mu      sync.Mutex
+	collect func(*output.ResultEvent)
+}
+
+// Preload creates a thread-safe engine and parses and compiles the full
+// template set, so thread-safe scans skip template loading. Scans started
+// while the warm engine is busy use a fresh engine as before. Every scan on
+// the warm engine starts from its options, so it never enables code
+// templates; scans that opt in to them use a fresh engine. Preloading an
+// already warm service does nothing.
+func (s *scannerServiceImpl) Preload(ctx context.Context) (err error) {
+	s.warmMu.RLock()
+	warm := s.warm != nil
+	s.warmMu.RUnlock()
+	if warm {
+		return nil
+	}
+	ctx, span := startSpan(ctx, "engine.preload")
+	defer func() { telemetry.End(span, err) }()
+
+	start := time.Now()
+	w := &warmEngine{}
+	var base *nuclei.NucleiEngine
+
+	options := []nuclei.NucleiSDKOptions{
+		nuclei.DisableUpdateCheck(),
+		egressOption(s.egress),
+		safetyOption(s.safety),
+		trafficOption(),
+		func(e *nuclei.NucleiEngine) error {
+			w.options = e.Options()
+			return nil
+		},
+		captureEngine(&base),
+	}
+	options = append(options, s.templateSourceOptions(nil)...)
+
+	engineLock.RLock()
+	_, create := startSpan(ctx, "engine.create", attribute.Bool("engine.warm", true))
+	engine, err := nuclei.NewThreadSafeNucleiEngineCtx(ctx, options...)
+	telemetry.End(create, err)
+	if err != nil {
+		engineLock.RUnlock()
+		return fmt.Errorf("failed to create scan engine: %w", err)
+	}
+	engine.GlobalResultCallback(w.dispatch)
+	_, load := startSpan(ctx, "templates.load", attribute.Bool("engine.warm", true))
+	err = engine.GlobalLoadAllTemplates()
+	telemetry.End(load, err)
+	engineLock.RUnlock()
+	if err != nil {
+		closeShared(engine)
+		return fmt.Errorf("failed to load templates: %w", err)
+	}
+	// Warm scans run the compiled templates, so they honour template limits
+	// and report their requests to the monitor of the scan
+	wrapTemplates(base.GetTemplates())
+	relayTemplates(base.GetTemplates(), &w.relay)
+	w.engine, w.base = engine, base
+
+	s.warmMu.Lock()
+	if s.warm != nil {
+		s.warmMu.Unlock()
+		closeShared(engine)
+		return nil
+	}
+	s.warm = w
+	s.warmMu.Unlock()
+
+	s.console.Log("Scan engine preloaded in %s", time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+func (w *warmEngine) dispatch(event *output.ResultEvent) {
+	w.mu.Lock()
+	collect := w.collect
+	w.mu.Unlock()
+	if collect != nil {
+		collect(event)
+	}
+}
+
+// execute runs a scan on the warm engine. Other engines reset nuclei's shared
+// protocol state when they close, so it is restored before the scan. The
+// monitor may lower the engine's rate limit during the scan; it is restored
+// afterwards.
+func (w *warmEngine) execute(ctx context.Context, target string, options []nuclei.NucleiSDKOptions, callback func(*output.ResultEvent), monitor *scanMonitor) error {
+	if protocolstate.ShouldInit() {
+		if err := protocolinit.Init(w.options); err != nil {
+			return fmt.Errorf("failed to initialize protocols: %w", err)
+		}
+	}
+
+	w.mu.Lock()
+	w.collect = callback
+	w.mu.Unlock()
+	w.relay.set(monitor)
+	if limiter := w.base.GetExecuterOptions().RateLimiter; limiter != nil {
+		defer limiter.SetLimit(limiter.GetLimit())
+	}
+	monitor.attach(w.base)
+	defer func() {
+		w.mu.Lock()
+		w.collect = nil
+		w.mu.Unlock()
+		w.relay.set(nil)
+	}()
+
+	return w.engine.ExecuteNucleiWithOptsCtx(ctx, []string{target}, options...)
+}
+
+// executeThreadSafe runs a scan on the warm engine when it is idle, and on a
+// fresh thread-safe engine otherwise. A non-nil monitor receives the
+// requests of the scan's templates.
+func (s *scannerServiceImpl) executeThreadSafe(ctx context.Context, target string, options []nuclei.NucleiSDKOptions, callback func(*output.ResultEvent), monitor *scanMonitor) error {
+	ne, err := s.runThreadSafe(ctx, target, options, callback, monitor)
+	if ne != nil {
+		closeShared(ne)
+	}
+	return err
+}
+
+// runThreadSafe runs a scan alongside the other thread-safe scans and
+// returns the fresh engine it created, if any, for the caller to close once
+// it released the engine lock
+func (s *scannerServiceImpl) runThreadSafe(ctx context.Context, target string, options []nuclei.NucleiSDKOptions, callback func(*output.ResultEvent), monitor *scanMonitor) (*nuclei.ThreadSafeNucleiEngine, error) {
+	engineLock.RLock()
+	defer engineLock.RUnlock()
+
+	s.warmMu.RLock()
+	w := s.warm
+	s.warmMu.RUnlock()
+	// The warm engine loads no DAST or code templates, and runs at its own
+	// rate limit rather than a host's reduced one
+	if w != nil && !apiRequestsFrom(ctx).fuzzing() && !codeTemplatesFrom(ctx) && !monitor.tuned() && w.busy.TryLock() {
+		defer w.busy.Unlock()
+		ctx, span := startSpan(ctx, "scan.execute", attribute.Bool("engine.warm", true))
+		err := w.execute(ctx, target, options, callback, monitor)
+		telemetry.End(span, err)
+		return nil, err
+	}
+
+	// The engine's templates dump their traffic for the monitor to count
+	if monitor != nil {
+		options = append(options[:len(options):len(options)], trafficOption())
+	}
+	ne, err := s.newThreadSafeEngine(ctx, options, monitor)
+	if err != nil {
+		s.console.Log("Failed to create thread-safe nuclei engine: %v", err)
+		return nil, err
+	}
+
+	ne.GlobalResultCallback(callback)
+	// Thread-safe engines load the scan's templates as part of executing it
+	ctx, span := startSpan(ctx, "scan.execute")
+	err = ne.ExecuteNucleiWithOptsCtx(ctx, []string{target}, options...)
+	telemetry.End(span, err)
+	return ne, err
+}
+
+type codeTemplatesKey struct{}
+
+// withCodeTemplates marks the scan running ctx as enabling code templates
+func withCodeTemplates(ctx context.Context, enabled bool) context.Context {
+	if !enabled {
+		return ctx
+	}
+	return context.WithValue(ctx, codeTemplatesKey{}, true)
+}
+
+// codeTemplatesFrom reports whether the scan running ctx enables code
+// templates
+func codeTemplatesFrom(ctx context.Context) bool {
+	enabled, _ := ctx.Value(codeTemplatesKey{}).(bool)
+	return enabled
+}
@@ -0,0 +1,156 @@
+package scanner
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"nuclei-mcp/pkg/policy"
+
+	nuclei "github.com/projectdiscovery/nuclei/v3/lib"
+)
+
+// previewHeaviest is the number of templates sending the most requests a
+// scan preview lists
+const previewHeaviest = 10
+
+// Previewer is implemented by scanner services that estimate the cost of a
+// scan without running it
+type Previewer interface {
+	Preview(ctx context.Context, target string, severity string, protocols string, templateIDs []string, opts ...ScanOption) (ScanPreview, error)
+}
+
+// ScanPreview estimates the requests and duration of a scan from the
+// templates its filters load, so its cost can be checked before it runs
+type ScanPreview struct {
+	Target string `json:"target"`
+	// Available and Templates count the template files of the scan's
+	// sources and those its filters load; Excluded counts the others by
+	// reason, as in the templates of scan stats
+	Available int            `json:"available"`
+	Templates int            `json:"templates"`
+	Excluded  map[string]int `json:"excluded,omitempty"`
+	// Protocols counts the loaded templates by protocol
+	Protocols map[string]int `json:"protocols,omitempty"`
+	// SkippedTemplates would be skipped for exceeding the scan's request
+	// limit, and are not counted in Requests
+	SkippedTemplates []string `json:"skipped_templates,omitempty"`
+	// Requests is the number of requests the templates send to the target.
+	// Requests to the URLs a crawl finds or to OpenAPI operations come on
+	// top of it.
+	Requests    int `json:"requests"`
+	RateLimit   int `json:"rate_limit"`
+	Concurrency int `json:"concurrency"`
+	// EstimatedSeconds is the longer of RateLimitedSeconds, the time the
+	// requests take at the rate limit, and HistorySeconds, the run times of
+	// the templates in past scans spread over the concurrency
+	EstimatedSeconds   float64 `json:"estimated_seconds"`
+	RateLimitedSeconds float64 `json:"rate_limited_seconds"`
+	HistorySeconds     float64 `json:"history_seconds"`
+	// TemplatesWithHistory counts the templates with past run times; the
+	// others are assumed to take as long as the mean of those, or a second
+	TemplatesWithHistory int               `json:"templates_with_history"`
+	Heaviest             []PreviewTemplate `json:"heaviest"`
+}
+
+// PreviewTemplate is a template of a scan preview with its requests
+type PreviewTemplate struct {
+	ID       string `json:"id"`
+	Protocol string `json:"protocol"`
+	Requests int    `json:"requests"`
+}
+
+// Preview loads the templates a scan would run, without sending anything
+// to the target, and estimates its requests and duration
+func (s *scannerServiceImpl) Preview(ctx context.Context, target string, severity string, protocols string, templateIDs []string, opts ...ScanOption) (ScanPreview, error) {
+	target = policy.NormalizeTarget(target)
+	scanOpts, err := s.resolveScanOptions(target, opts)
+	if err != nil {
+		return ScanPreview{}, err
+	}
+	if protocols, err = NormalizeProtocols(protocols); err != nil {
+		return ScanPreview{}, err
+	}
+
+	removeInline, err := scanOpts.writeInlineTemplates()
+	if err != nil {
+		return ScanPreview{}, err
+	}
+	defer removeInline()
+
+	progress := &scanProgress{target: target, excluded: make(map[string]int)}
+	engineLock.Lock()
+	defer engineLock.Unlock()
+	ne, err := s.newEngine(withScanProgress(ctx, progress), target, s.buildOptions(severity, protocols, templateIDs, scanOpts))
+	if err != nil {
+		return ScanPreview{}, executionError(err)
+	}
+	defer ne.Close()
+
+	templateStats := progress.templateStats(s.availableTemplates(scanOpts), false)
+	preview := ScanPreview{
+		Target:      target,
+		Available:   templateStats.Available,
+		Templates:   templateStats.Loaded,
+		Excluded:    templateStats.Excluded,
+		Protocols:   map[string]int{},
+		RateLimit:   previewRateLimit(ne),
+		Concurrency: max(ne.Options().TemplateThreads, 1),
+		Heaviest:    []PreviewTemplate{},
+	}
+
+	var ids []string
+	var loaded []PreviewTemplate
+	for _, template := range ne.GetTemplates() {
+		if template.Executer == nil || template.CompiledWorkflow != nil {
+			continue
+		}
+		entry := PreviewTemplate{ID: template.ID, Protocol: template.Type().String(), Requests: template.Executer.Requests()}
+		preview.Protocols[entry.Protocol]++
+		if scanOpts.MaxTemplateRequests > 0 && entry.Requests > scanOpts.MaxTemplateRequests {
+			preview.SkippedTemplates = append(preview.SkippedTemplates, entry.ID)
+			continue
+		}
+		preview.Requests += entry.Requests
+		ids = append(ids, entry.ID)
+		loaded = append(loaded, entry)
+	}
+	sort.Strings(preview.SkippedTemplates)
+
+	sort.Slice(loaded, func(i, j int) bool {
+		if loaded[i].Requests != loaded[j].Requests {
+			return loaded[i].Requests > loaded[j].Requests
+		}
+		return loaded[i].ID < loaded[j].ID
+	})
+	preview.Heaviest = append(preview.Heaviest, loaded[:min(len(loaded), previewHeaviest)]...)
+
+	estimates := s.timings.estimates(ids)
+	preview.TemplatesWithHistory = len(estimates)
+	var known, total time.Duration
+	for _, estimate := range estimates {
+		known += estimate
+	}
+	fallback := defaultTemplateEstimate
+	if len(estimates) > 0 {
+		fallback = known / time.Duration(len(estimates))
+	}
+	total = known + fallback*time.Duration(len(ids)-len(estimates))
+
+	preview.RateLimitedSeconds = float64(preview.Requests) / float64(preview.RateLimit)
+	preview.HistorySeconds = total.Seconds() / float64(preview.Concurrency)
+	preview.EstimatedSeconds = max(preview.RateLimitedSeconds, preview.HistorySeconds)
+	return preview, nil
+}
+
+// previewRateLimit returns the requests per second the engine sends
+func previewRateLimit(ne *nuclei.NucleiEngine) int {
+	opts := ne.Options()
+	if opts.RateLimit <= 0 {
+		return defaultRateLimit
+	}
+	if opts.RateLimitDuration > time.Second {
+		return max(int(time.Duration(opts.RateLimit)*time.Second/opts.RateLimitDuration), 1)
+	}
+	return opts.RateLimit
+}
@@ -0,0 +1,411 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/templates"
+)
+
+// defaultTemplateEstimate is the run time assumed for templates without
+// recorded run times, until templates of the scan complete
+const defaultTemplateEstimate = time.Second
+
+// timingWindow is the number of recent runs a template's mean run time
+// follows
+const timingWindow = 10
+
+// Scan stages reported by ScanProgress
+const (
+	StageLoading = "loading"
+	StageRunning = "running"
+)
+
+// ProgressReporter is implemented by scanner services that report the
+// progress of their running scans
+type ProgressReporter interface {
+	RunningScans() []ScanProgress
+}
+
+// ScanProgress is the state of a running scan and its estimated completion
+type ScanProgress struct {
+	CorrelationID string    `json:"correlation_id,omitempty"`
+	Target        string    `json:"target"`
+	StartedAt     time.Time `json:"started_at"`
+	// Stage is loading until the first template runs, then running
+	Stage            string  `json:"stage"`
+	ElapsedSeconds   float64 `json:"elapsed_seconds"`
+	TemplatesTotal   int     `json:"templates_total,omitempty"`
+	TemplatesDone    int     `json:"templates_done"`
+	TemplatesRunning int     `json:"templates_running"`
+	// PercentComplete and EstimatedRemainingSeconds weigh each template by
+	// its recorded run time. They are missing while templates load, and
+	// for scans on the preloaded engine, whose templates are only known as
+	// they run.
+	PercentComplete           *float64 `json:"percent_complete,omitempty"`
+	EstimatedRemainingSeconds *float64 `json:"estimated_remaining_seconds,omitempty"`
+	// TemplatesWithHistory is the number of the scan's templates with
+	// recorded run times; the others are assumed to take as long as the
+	// templates of the scan that completed
+	TemplatesWithHistory int `json:"templates_with_history,omitempty"`
+}
+
+// TemplateTimings persists the mean run time of each template against a
+// target, so the progress of scans can be estimated from past scans. Like
+// a template index, it can be shared by the scanner services of the server
+// and its tenants.
+type TemplateTimings struct {
+	file string
+
+	mu      sync.Mutex
+	loaded  bool
+	timings map[string]*templateTiming
+}
+
+type templateTiming struct {
+	Mean time.Duration `json:"mean_ns"`
+	Runs int           `json:"runs"`
+}
+
+// NewTemplateTimings creates timings stored in file. The file is read on
+// first use; a missing or unreadable file starts empty.
+func NewTemplateTimings(file string) *TemplateTimings {
+	return &TemplateTimings{file: file}
+}
+
+// WithTemplateTimings estimates the progress of scans from the run times of
+// their templates in past scans, and records the run times of completed
+// scans
+func WithTemplateTimings(timings *TemplateTimings) ServiceOption {
+	return func(s *scannerServiceImpl) {
+		s.timings = timings
+	}
+}
+
+// load reads the timings file. The caller holds t.mu.
+func (t *TemplateTimings) load() {
+	t.loaded = true
+	t.timings = make(map[string]*templateTiming)
+	data, err := os.ReadFile(t.file)
+	if err != nil {
+		return
+	}
+	var stored map[string]*templateTiming
+	if err := json.Unmarshal(data, &stored); err == nil && stored != nil {
+		t.timings = stored
+	}
+}
+
+// estimates returns the mean run times recorded for ids
+func (t *TemplateTimings) estimates(ids []string) map[string]time.Duration {
+	estimates := make(map[string]time.Duration)
+	if t == nil {
+		return estimates
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.loaded {
+		t.load()
+	}
+	for _, id := range ids {
+		if timing, ok := t.timings[id]; ok {
+			estimates[id] = timing.Mean
+		}
+	}
+	return estimates
+}
+
+// observe records the run times of a completed scan and saves the timings
+func (t *TemplateTimings) observe(runs map[string]time.Duration) error {
+	if t == nil || len(runs) == 0 {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.loaded {
+		t.load()
+	}
+	for id, run := range runs {
+		timing, ok := t.timings[id]
+		if !ok {
+			timing = &templateTiming{}
+			t.timings[id] = timing
+		}
+		timing.Runs++
+		timing.Mean += (run - timing.Mean) / time.Duration(min(timing.Runs, timingWindow))
+	}
+
+	data, err := json.Marshal(t.timings)
+	if err != nil {
+		return fmt.Errorf("failed to encode template timings: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(t.file), 0755); err != nil {
+		return fmt.Errorf("failed to create template timings directory: %w", err)
+	}
+	tmp := t.file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write template timings: %w", err)
+	}
+	if err := os.Rename(tmp, t.file); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write template timings: %w", err)
+	}
+	return nil
+}
+
+// scanProgress follows the templates of a running scan
+type scanProgress struct {
+	correlationID string
+	target        string
+	started       time.Time
+	timings       *TemplateTimings
+
+	mu sync.Mutex
+	// expected lists the IDs of the scan's templates, nil when unknown
+	expected  []string
+	estimates map[string]time.Duration
+	firstRun  time.Time
+	running   map[string]time.Time
+	done      map[string]time.Duration
+	skipped   map[string]bool
+	// excluded counts the templates the engine's loader left out, by
+	// reason
+	excluded map[string]int
+	// failed holds the first runtime error of the templates that errored
+	failed    map[string]string
+	completed bool
+	// inline scans run templates outside the library, whose runs are not
+	// recorded
+	inline bool
+}
+
+type scanProgressKey struct{}
+
+// withScanProgress returns ctx carrying progress to the templates the scan
+// runs
+func withScanProgress(ctx context.Context, progress *scanProgress) context.Context {
+	return context.WithValue(ctx, scanProgressKey{}, progress)
+}
+
+// scanProgressFrom returns the progress of the scan running ctx, or nil
+func scanProgressFrom(ctx context.Context) *scanProgress {
+	progress, _ := ctx.Value(scanProgressKey{}).(*scanProgress)
+	return progress
+}
+
+// expect records the templates the scan runs, once its engine loaded them
+func (p *scanProgress) expect(loaded []*templates.Template) {
+	if p == nil {
+		return
+	}
+	// An engine that loaded no templates expects none rather than unknown
+	ids := []string{}
+	for _, template := range loaded {
+		if template.Executer != nil && template.CompiledWorkflow == nil {
+			ids = append(ids, template.ID)
+		}
+	}
+	estimates := p.timings.estimates(ids)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.expected = ids
+	p.estimates = estimates
+}
+
+// start records that a template started and returns the function recording
+// that it finished
+func (p *scanProgress) start(id string) func() {
+	if p == nil {
+		return func() {}
+	}
+	now := time.Now()
+	p.mu.Lock()
+	if p.firstRun.IsZero() {
+		p.firstRun = now
+	}
+	p.running[id] = now
+	p.mu.Unlock()
+	return func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		delete(p.running, id)
+		p.done[id] = time.Since(now)
+	}
+}
+
+// skip records that a template was skipped by the request limit
+func (p *scanProgress) skip(id string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.skipped[id] = true
+}
+
+// complete marks the scan as run to completion, so its run times are
+// recorded
+func (p *scanProgress) complete() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.completed = true
+}
+
+// snapshot reports the progress of the scan at now. Templates run in
+// parallel, so the remaining run time of the templates is divided by the
+// parallelism the scan achieved so far.
+func (p *scanProgress) snapshot(now time.Time) ScanProgress {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	status := ScanProgress{
+		CorrelationID:    p.correlationID,
+		Target:           p.target,
+		StartedAt:        p.started,
+		Stage:            StageLoading,
+		ElapsedSeconds:   now.Sub(p.started).Seconds(),
+		TemplatesTotal:   len(p.expected),
+		TemplatesDone:    len(p.done) + len(p.skipped),
+		TemplatesRunning: len(p.running),
+	}
+	if p.firstRun.IsZero() {
+		return status
+	}
+	status.Stage = StageRunning
+	if p.expected == nil {
+		return status
+	}
+
+	var busy, observed time.Duration
+	for _, run := range p.done {
+		busy += run
+		observed += run
+	}
+	for _, started := range p.running {
+		busy += now.Sub(started)
+	}
+	parallelism := 1.0
+	if elapsed := now.Sub(p.firstRun); elapsed > 0 {
+		parallelism = max(busy.Seconds()/elapsed.Seconds(), 1)
+	}
+	fallback := defaultTemplateEstimate
+	if len(p.done) > 0 {
+		fallback = observed / time.Duration(len(p.done))
+	}
+
+	var total, remaining time.Duration
+	for _, id := range p.expected {
+		estimate, ok := p.estimates[id]
+		if ok {
+			status.TemplatesWithHistory++
+		} else {
+			estimate = fallback
+		}
+		_, done := p.done[id]
+		if done || p.skipped[id] {
+			total += estimate
+			continue
+		}
+		left := estimate
+		if started, ok := p.running[id]; ok {
+			// A template running past its estimate is assumed half done
+			ran := now.Sub(started)
+			if ran >= estimate {
+				estimate = 2 * ran
+			}
+			left = estimate - ran
+		}
+		total += estimate
+		remaining += left
+	}
+
+	percent := 100.0
+	if total > 0 {
+		percent = float64(total-remaining) / float64(total) * 100
+	}
+	seconds := remaining.Seconds() / parallelism
+	status.PercentComplete = &percent
+	status.EstimatedRemainingSeconds = &seconds
+	return status
+}
+
+// trackScan starts following a scan of target
+func (s *scannerServiceImpl) trackScan(target string, scanOpts ScanOptions) *scanProgress {
+	progress := &scanProgress{
+		correlationID: scanOpts.CorrelationID,
+		target:        target,
+		started:       time.Now(),
+		timings:       s.timings,
+		running:       make(map[string]time.Time),
+		done:          make(map[string]time.Duration),
+		skipped:       make(map[string]bool),
+		excluded:      make(map[string]int),
+		failed:        make(map[string]string),
+		inline:        len(scanOpts.InlineTemplates) > 0,
+	}
+	s.progressMu.Lock()
+	s.running[progress] = struct{}{}
+	s.progressMu.Unlock()
+	return progress
+}
+
+// finishScan stops following a scan, counts the runtime errors of its
+// templates towards their quarantine and records their run times when it
+// ran to completion; stopped scans cut templates short. The templates of
+// inline scans are left out of both.
+func (s *scannerServiceImpl) finishScan(progress *scanProgress) {
+	s.progressMu.Lock()
+	delete(s.running, progress)
+	s.progressMu.Unlock()
+
+	progress.mu.Lock()
+	completed := progress.completed
+	runs := progress.done
+	failed := progress.failed
+	progress.mu.Unlock()
+
+	if progress.inline {
+		return
+	}
+	quarantined, err := s.quarantine.observe(runs, failed)
+	if err != nil {
+		s.console.Log("Failed to save template quarantine: %v", err)
+	}
+	for _, id := range quarantined {
+		s.console.Log("Template %s quarantined after erroring in %d scans in a row: %s", id, s.quarantine.Threshold(), failed[id])
+	}
+	if !completed {
+		return
+	}
+	if err := s.timings.observe(runs); err != nil {
+		s.console.Log("Failed to save template timings: %v", err)
+	}
+}
+
+// RunningScans reports the progress of the scans running on the service,
+// oldest first
+func (s *scannerServiceImpl) RunningScans() []ScanProgress {
+	s.progressMu.Lock()
+	running := make([]*scanProgress, 0, len(s.running))
+	for progress := range s.running {
+		running = append(running, progress)
+	}
+	s.progressMu.Unlock()
+
+	now := time.Now()
+	scans := make([]ScanProgress, 0, len(running))
+	for _, progress := range running {
+		scans = append(scans, progress.snapshot(now))
+	}
+	sort.Slice(scans, func(i, j int) bool {
+		return scans[i].StartedAt.Before(scans[j].StartedAt)
+	})
+	return scans
+}
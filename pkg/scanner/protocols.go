@@ -0,0 +1,66 @@
+package scanner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	templateTypes "github.com/projectdiscovery/nuclei/v3/pkg/templates/types"
+)
+
+// protocolAliases maps protocol names callers commonly use to nuclei's
+// template protocol types. HTTP templates scan both http:// and https://
+// targets; the scheme comes from the target, not the protocol filter.
+var protocolAliases = map[string]string{
+	"https":   "http",
+	"network": "tcp",
+	"js":      "javascript",
+}
+
+// SupportedProtocols returns the template protocol types scans can be
+// filtered by
+func SupportedProtocols() []string {
+	return templateTypes.SupportedProtocolsStrings()
+}
+
+// ProtocolAliases returns the alternative protocol names accepted by
+// NormalizeProtocols, sorted
+func ProtocolAliases() []string {
+	aliases := make([]string, 0, len(protocolAliases))
+	for alias := range protocolAliases {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	return aliases
+}
+
+// NormalizeProtocols validates a comma-separated protocol filter against
+// nuclei's template protocol types, resolving aliases such as https and
+// dropping duplicates. An empty filter stays empty and matches every
+// protocol.
+func NormalizeProtocols(protocols string) (string, error) {
+	supported := map[string]bool{}
+	for _, protocol := range SupportedProtocols() {
+		supported[protocol] = true
+	}
+
+	var normalized []string
+	seen := map[string]bool{}
+	for _, protocol := range strings.Split(protocols, ",") {
+		protocol = strings.ToLower(strings.TrimSpace(protocol))
+		if protocol == "" {
+			continue
+		}
+		if alias, ok := protocolAliases[protocol]; ok {
+			protocol = alias
+		}
+		if !supported[protocol] {
+			return "", fmt.Errorf("%w %q, supported protocols: %s", ErrInvalidProtocol, protocol, strings.Join(SupportedProtocols(), ", "))
+		}
+		if !seen[protocol] {
+			seen[protocol] = true
+			normalized = append(normalized, protocol)
+		}
+	}
+	return strings.Join(normalized, ","), nil
+}
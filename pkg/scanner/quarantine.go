@@ -0,0 +1,226 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultQuarantineThreshold is the number of scans in a row a template
+// errors in before it is quarantined
+const DefaultQuarantineThreshold = 3
+
+// QuarantinedTemplate is a template erroring at runtime, quarantined once
+// it errored in Threshold scans in a row
+type QuarantinedTemplate struct {
+	ID string `json:"id"`
+	// Errors is the number of scans in a row the template errored in
+	Errors      int       `json:"errors"`
+	LastError   string    `json:"last_error"`
+	LastErrorAt time.Time `json:"last_error_at"`
+	// QuarantinedAt is when the template was left out of scans, nil while
+	// it is only being watched
+	QuarantinedAt *time.Time `json:"quarantined_at,omitempty"`
+}
+
+// TemplateQuarantine leaves templates out of scans once they errored at
+// runtime in a number of scans in a row, so one broken template does not
+// degrade every scan. Errors the target causes, such as refused connections
+// or timeouts, are not counted. Like template timings, it is stored in a
+// file and can be shared by the scanner services of the server and its
+// tenants.
+type TemplateQuarantine struct {
+	file      string
+	threshold int
+
+	mu        sync.Mutex
+	loaded    bool
+	templates map[string]*QuarantinedTemplate
+}
+
+// NewTemplateQuarantine creates a quarantine stored in file, quarantining
+// templates after threshold scans in a row with errors. The file is read on
+// first use; a missing or unreadable file starts empty.
+func NewTemplateQuarantine(file string, threshold int) *TemplateQuarantine {
+	if threshold < 1 {
+		threshold = DefaultQuarantineThreshold
+	}
+	return &TemplateQuarantine{file: file, threshold: threshold}
+}
+
+// WithTemplateQuarantine counts the runtime errors of the templates of
+// scans and leaves quarantined templates out of them
+func WithTemplateQuarantine(quarantine *TemplateQuarantine) ServiceOption {
+	return func(s *scannerServiceImpl) {
+		s.quarantine = quarantine
+	}
+}
+
+// load reads the quarantine file. The caller holds q.mu.
+func (q *TemplateQuarantine) load() {
+	q.loaded = true
+	q.templates = make(map[string]*QuarantinedTemplate)
+	data, err := os.ReadFile(q.file)
+	if err != nil {
+		return
+	}
+	var stored map[string]*QuarantinedTemplate
+	if err := json.Unmarshal(data, &stored); err == nil && stored != nil {
+		q.templates = stored
+	}
+}
+
+// List returns the quarantined templates and, with watched, the templates
+// that errored fewer times in a row than the threshold, by ID
+func (q *TemplateQuarantine) List(watched bool) []QuarantinedTemplate {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.loaded {
+		q.load()
+	}
+	list := []QuarantinedTemplate{}
+	for _, template := range q.templates {
+		if watched || template.QuarantinedAt != nil {
+			list = append(list, *template)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// Threshold returns the number of scans in a row a template errors in
+// before it is quarantined
+func (q *TemplateQuarantine) Threshold() int {
+	return q.threshold
+}
+
+// Release lets a template run in scans again and resets its error count,
+// reporting whether it was quarantined or watched
+func (q *TemplateQuarantine) Release(id string) (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.loaded {
+		q.load()
+	}
+	if _, ok := q.templates[id]; !ok {
+		return false, nil
+	}
+	delete(q.templates, id)
+	return true, q.save()
+}
+
+// ids returns the IDs of the quarantined templates
+func (q *TemplateQuarantine) ids() []string {
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.loaded {
+		q.load()
+	}
+	var ids []string
+	for id, template := range q.templates {
+		if template.QuarantinedAt != nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// observe records the templates a scan ran and the errors of those that
+// failed, returning the templates it quarantined. A template that ran
+// without error is no longer watched.
+func (q *TemplateQuarantine) observe(ran map[string]time.Duration, failed map[string]string) ([]string, error) {
+	if q == nil || len(ran) == 0 {
+		return nil, nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.loaded {
+		q.load()
+	}
+
+	now := time.Now()
+	changed := false
+	var quarantined []string
+	for id := range ran {
+		message, errored := failed[id]
+		template, watched := q.templates[id]
+		if !errored {
+			if watched && template.QuarantinedAt == nil {
+				delete(q.templates, id)
+				changed = true
+			}
+			continue
+		}
+		if !watched {
+			template = &QuarantinedTemplate{ID: id}
+			q.templates[id] = template
+		}
+		template.Errors++
+		template.LastError = message
+		template.LastErrorAt = now
+		if template.QuarantinedAt == nil && template.Errors >= q.threshold {
+			template.QuarantinedAt = &now
+			quarantined = append(quarantined, id)
+		}
+		changed = true
+	}
+	sort.Strings(quarantined)
+	if !changed {
+		return nil, nil
+	}
+	return quarantined, q.save()
+}
+
+// save writes the quarantine file. The caller holds q.mu.
+func (q *TemplateQuarantine) save() error {
+	data, err := json.Marshal(q.templates)
+	if err != nil {
+		return fmt.Errorf("failed to encode template quarantine: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(q.file), 0755); err != nil {
+		return fmt.Errorf("failed to create template quarantine directory: %w", err)
+	}
+	tmp := q.file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write template quarantine: %w", err)
+	}
+	if err := os.Rename(tmp, q.file); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write template quarantine: %w", err)
+	}
+	return nil
+}
+
+// templateFault reports whether a template's execution error is the
+// template's own rather than the target's or the scan's, such as a refused
+// connection, a timeout or a cancelled scan
+func templateFault(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	return FailureCause(err) == FailureOther
+}
+
+// fail records that a template errored at runtime
+func (p *scanProgress) fail(id string, err error) {
+	if p == nil || !templateFault(err) {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.failed[id]; !ok {
+		p.failed[id] = err.Error()
+	}
+}
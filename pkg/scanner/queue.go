@@ -0,0 +1,239 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultQueueSlots is the number of scans the scan queue runs at once
+	DefaultQueueSlots = 10
+	// DefaultInteractiveWeight is the number of interactive scans the fair
+	// queue policy starts for each waiting background scan
+	DefaultInteractiveWeight = 4
+)
+
+// Priority orders scans waiting in the scan queue
+type Priority int
+
+const (
+	// PriorityInteractive is the priority of on-demand scans, such as tool
+	// calls an agent waits for. It is the default.
+	PriorityInteractive Priority = iota
+	// PriorityBackground is the priority of scheduled and bulk scans, which
+	// give way to interactive scans
+	PriorityBackground
+)
+
+var priorityNames = []string{"interactive", "background"}
+
+func (p Priority) String() string {
+	if p < 0 || int(p) >= len(priorityNames) {
+		return fmt.Sprintf("priority(%d)", int(p))
+	}
+	return priorityNames[p]
+}
+
+// ParsePriority parses a priority name; an empty name is interactive
+func ParsePriority(name string) (Priority, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return PriorityInteractive, nil
+	}
+	for i, known := range priorityNames {
+		if name == known {
+			return Priority(i), nil
+		}
+	}
+	return PriorityInteractive, fmt.Errorf("%w %q, use %s", ErrInvalidPriority, name, strings.Join(priorityNames, " or "))
+}
+
+// QueuePolicy decides which priority gets a free slot when scans of both
+// priorities are waiting
+type QueuePolicy string
+
+const (
+	// QueueStrict always starts waiting interactive scans first, so
+	// background scans only run while no interactive scan waits
+	QueueStrict QueuePolicy = "strict"
+	// QueueFair starts interactive scans first but gives a waiting
+	// background scan every slot after the interactive weight, so
+	// background scans keep a fair share under interactive load
+	QueueFair QueuePolicy = "fair"
+)
+
+// ScanQueue bounds the number of scans running at once. Scans beyond it
+// wait, and a freed slot goes to a waiting interactive scan before waiting
+// background scans, as the policy allows. Running scans are not
+// interrupted.
+type ScanQueue struct {
+	slots             int
+	policy            QueuePolicy
+	interactiveWeight int
+
+	mu      sync.Mutex
+	running int
+	// streak counts the interactive scans started in a row while a
+	// background scan was waiting
+	streak  int
+	waiting [2][]*queuedScan
+}
+
+// queuedScan is a scan waiting for a slot; ready is closed when it gets one
+type queuedScan struct {
+	ready chan struct{}
+}
+
+// QueueStats reports the load of the scan queue
+type QueueStats struct {
+	Slots       int         `json:"slots"`
+	Running     int         `json:"running"`
+	Interactive int         `json:"interactive_waiting"`
+	Background  int         `json:"background_waiting"`
+	Policy      QueuePolicy `json:"policy"`
+}
+
+// NewScanQueue creates a scan queue running at most slots scans at once.
+// Slots below 1 and an unknown policy or weight use the defaults.
+func NewScanQueue(slots int, policy QueuePolicy, interactiveWeight int) *ScanQueue {
+	if slots < 1 {
+		slots = DefaultQueueSlots
+	}
+	if policy != QueueStrict {
+		policy = QueueFair
+	}
+	if interactiveWeight < 1 {
+		interactiveWeight = DefaultInteractiveWeight
+	}
+	return &ScanQueue{slots: slots, policy: policy, interactiveWeight: interactiveWeight}
+}
+
+// WithScanQueue runs the service's scans through queue, so interactive scans
+// do not wait behind background scans
+func WithScanQueue(queue *ScanQueue) ServiceOption {
+	return func(s *scannerServiceImpl) {
+		s.queue = queue
+	}
+}
+
+// Acquire waits for a slot for a scan of the given priority and returns the
+// function that frees it. It gives up when ctx is done.
+func (q *ScanQueue) Acquire(ctx context.Context, priority Priority) (release func(), err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if priority != PriorityBackground {
+		priority = PriorityInteractive
+	}
+
+	q.mu.Lock()
+	if q.running < q.slots && len(q.waiting[PriorityInteractive]) == 0 && len(q.waiting[PriorityBackground]) == 0 {
+		q.running++
+		q.mu.Unlock()
+		return q.release, nil
+	}
+	scan := &queuedScan{ready: make(chan struct{})}
+	q.waiting[priority] = append(q.waiting[priority], scan)
+	q.mu.Unlock()
+
+	select {
+	case <-scan.ready:
+		return q.release, nil
+	case <-ctx.Done():
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	select {
+	case <-scan.ready:
+		// The slot was handed over while giving up; pass it on
+		q.running--
+		q.next()
+	default:
+		q.remove(priority, scan)
+	}
+	return nil, ctx.Err()
+}
+
+// Stats returns the number of running and waiting scans
+func (q *ScanQueue) Stats() QueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return QueueStats{
+		Slots:       q.slots,
+		Running:     q.running,
+		Interactive: len(q.waiting[PriorityInteractive]),
+		Background:  len(q.waiting[PriorityBackground]),
+		Policy:      q.policy,
+	}
+}
+
+func (q *ScanQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.running--
+	q.next()
+}
+
+// next starts waiting scans while slots are free. q.mu must be held.
+func (q *ScanQueue) next() {
+	for q.running < q.slots {
+		priority, ok := q.pick()
+		if !ok {
+			return
+		}
+		scan := q.waiting[priority][0]
+		q.waiting[priority] = q.waiting[priority][1:]
+		q.running++
+		close(scan.ready)
+	}
+}
+
+// pick returns the priority whose oldest waiting scan starts next
+func (q *ScanQueue) pick() (Priority, bool) {
+	interactive := len(q.waiting[PriorityInteractive]) > 0
+	background := len(q.waiting[PriorityBackground]) > 0
+	switch {
+	case !background:
+		q.streak = 0
+		return PriorityInteractive, interactive
+	case !interactive:
+		q.streak = 0
+		return PriorityBackground, true
+	case q.policy == QueueFair && q.streak >= q.interactiveWeight:
+		q.streak = 0
+		return PriorityBackground, true
+	}
+	q.streak++
+	return PriorityInteractive, true
+}
+
+// remove drops a scan that gave up waiting. q.mu must be held.
+func (q *ScanQueue) remove(priority Priority, scan *queuedScan) {
+	for i, waiting := range q.waiting[priority] {
+		if waiting == scan {
+			q.waiting[priority] = append(q.waiting[priority][:i], q.waiting[priority][i+1:]...)
+			return
+		}
+	}
+}
+
+// acquireSlot takes a queue slot for a scan of target, logging when the scan
+// has to wait. A service without a queue runs scans at once.
+func (s *scannerServiceImpl) acquireSlot(ctx context.Context, target string, priority Priority) (func(), error) {
+	if s.queue == nil {
+		return func() {}, nil
+	}
+	console := s.logger(ctx)
+	if stats := s.queue.Stats(); stats.Running >= stats.Slots {
+		console.Log("Scan of %s queued (%s priority): %d scans running, %d interactive and %d background waiting", target, priority, stats.Running, stats.Interactive, stats.Background)
+	}
+	release, err := s.queue.Acquire(ctx, priority)
+	if err != nil {
+		console.Log("Scan of %s gave up waiting in the scan queue: %v", target, err)
+		return nil, err
+	}
+	return release, nil
+}
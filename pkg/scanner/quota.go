@@ -0,0 +1,181 @@
+package scanner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+)
+
+const (
+	// DefaultQuotaPeriod is how long client usage is counted before it
+	// resets
+	DefaultQuotaPeriod = 24 * time.Hour
+	// AnonymousClient names the usage of scans that do not name a client
+	AnonymousClient = "anonymous"
+)
+
+// Quota limits the resources a client's scans use per quota period. Zero
+// fields are unlimited.
+type Quota struct {
+	Scans    int           `json:"scans,omitempty"`
+	Requests int           `json:"requests,omitempty"`
+	Bytes    int64         `json:"bytes,omitempty"`
+	WallTime time.Duration `json:"wall_time_ns,omitempty"`
+	CPUTime  time.Duration `json:"cpu_time_ns,omitempty"`
+}
+
+// ClientUsage is the resources a client's scans used in the current quota
+// period, and the client's quota
+type ClientUsage struct {
+	Client      string        `json:"client"`
+	PeriodStart time.Time     `json:"period_start"`
+	Scans       int           `json:"scans"`
+	Requests    int           `json:"requests"`
+	Bytes       int64         `json:"bytes"`
+	WallTime    time.Duration `json:"wall_time_ns"`
+	CPUTime     time.Duration `json:"cpu_time_ns"`
+	Quota       Quota         `json:"quota"`
+}
+
+// exceeded names the first limit of quota the usage reached, or returns ""
+func (u ClientUsage) exceeded(quota Quota) string {
+	switch {
+	case quota.Scans > 0 && u.Scans >= quota.Scans:
+		return fmt.Sprintf("%d of %d scans", u.Scans, quota.Scans)
+	case quota.Requests > 0 && u.Requests >= quota.Requests:
+		return fmt.Sprintf("%d of %d requests", u.Requests, quota.Requests)
+	case quota.Bytes > 0 && u.Bytes >= quota.Bytes:
+		return fmt.Sprintf("%d of %d bytes", u.Bytes, quota.Bytes)
+	case quota.WallTime > 0 && u.WallTime >= quota.WallTime:
+		return fmt.Sprintf("%s of %s scan time", u.WallTime.Round(time.Second), quota.WallTime)
+	case quota.CPUTime > 0 && u.CPUTime >= quota.CPUTime:
+		return fmt.Sprintf("%s of %s CPU time", u.CPUTime.Round(time.Second), quota.CPUTime)
+	}
+	return ""
+}
+
+// QuotaTracker accounts the resources used by the scans of each client and
+// refuses scans of clients that used up their quota in the current period.
+// Running scans are not interrupted, so a client can exceed its quota by
+// the scans it started before reaching it.
+type QuotaTracker struct {
+	period       time.Duration
+	defaultQuota Quota
+	clients      map[string]Quota
+
+	mu    sync.Mutex
+	usage map[string]*ClientUsage
+}
+
+// NewQuotaTracker creates a tracker applying defaultQuota to clients without
+// a quota of their own. Client names are matched case-insensitively. A
+// period of zero uses DefaultQuotaPeriod.
+func NewQuotaTracker(period time.Duration, defaultQuota Quota, clients map[string]Quota) *QuotaTracker {
+	if period <= 0 {
+		period = DefaultQuotaPeriod
+	}
+	t := &QuotaTracker{period: period, defaultQuota: defaultQuota, clients: map[string]Quota{}, usage: map[string]*ClientUsage{}}
+	for client, quota := range clients {
+		t.clients[clientKey(client)] = quota
+	}
+	return t
+}
+
+// WithQuotas accounts scans to the client named by WithClient and refuses
+// scans beyond the client's quota
+func WithQuotas(quotas *QuotaTracker) ServiceOption {
+	return func(s *scannerServiceImpl) {
+		s.quotas = quotas
+	}
+}
+
+// WithClient names the client a scan is accounted to
+func WithClient(client string) ScanOption {
+	return func(o *ScanOptions) {
+		o.Client = client
+	}
+}
+
+// clientKey normalizes a client name; unnamed clients share AnonymousClient
+func clientKey(client string) string {
+	if client = strings.ToLower(strings.TrimSpace(client)); client != "" {
+		return client
+	}
+	return AnonymousClient
+}
+
+// Check returns ErrQuotaExceeded when the client used up its quota in the
+// current period
+func (t *QuotaTracker) Check(client string) error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	usage := t.current(clientKey(client))
+	if limit := usage.exceeded(usage.Quota); limit != "" {
+		return fmt.Errorf("%w: client %s used %s since %s", ErrQuotaExceeded, usage.Client, limit, usage.PeriodStart.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// Record adds the resources of a finished scan to the client's usage
+func (t *QuotaTracker) Record(client string, stats *cache.ScanStats) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	usage := t.current(clientKey(client))
+	usage.Scans++
+	if stats != nil {
+		usage.Requests += stats.Requests
+		usage.Bytes += stats.BytesSent + stats.BytesReceived
+		usage.WallTime += stats.WallTime
+		usage.CPUTime += stats.CPUTime
+	}
+}
+
+// Usage returns the usage of every client in its current period, by client
+// name
+func (t *QuotaTracker) Usage() []ClientUsage {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	usages := make([]ClientUsage, 0, len(t.usage))
+	for client := range t.usage {
+		usages = append(usages, *t.current(client))
+	}
+	sort.Slice(usages, func(i, j int) bool { return usages[i].Client < usages[j].Client })
+	return usages
+}
+
+// current returns the usage of client, starting a new period when the last
+// one ended. t.mu must be held.
+func (t *QuotaTracker) current(client string) *ClientUsage {
+	usage, ok := t.usage[client]
+	if !ok || time.Since(usage.PeriodStart) >= t.period {
+		quota, ok := t.clients[client]
+		if !ok {
+			quota = t.defaultQuota
+		}
+		usage = &ClientUsage{Client: client, PeriodStart: time.Now(), Quota: quota}
+		t.usage[client] = usage
+	}
+	return usage
+}
+
+// checkQuota refuses a scan of target when its client used up its quota
+func (s *scannerServiceImpl) checkQuota(target, client string) error {
+	if err := s.quotas.Check(client); err != nil {
+		s.console.Log("Scan of %s refused: %v", target, err)
+		return err
+	}
+	return nil
+}
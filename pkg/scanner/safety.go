@@ -0,0 +1,66 @@
+package scanner
+
+import (
+	"time"
+
+	nuclei "github.com/projectdiscovery/nuclei/v3/lib"
+	"github.com/projectdiscovery/nuclei/v3/pkg/types"
+)
+
+// SafetyLimits bound the connections and responses of every scan, so that
+// templates holding connections open, such as request smuggling and
+// slow-protocol checks, cannot pin the server's sockets indefinitely
+type SafetyLimits struct {
+	// MaxConnectionLifetime caps how long a request may take, from dialing
+	// to reading the last byte of the response. It caps every engine
+	// timeout; zero leaves the engine defaults.
+	MaxConnectionLifetime time.Duration
+	// MaxBodyRead is the most bytes read of a response body, overriding the
+	// max-size of templates; zero leaves the engine default
+	MaxBodyRead int
+	// TemplateTimeout bounds how long each template may run against the
+	// target, so one slow template cannot take up the scan; scans may set
+	// a lower timeout. Zero leaves templates unbounded.
+	TemplateTimeout time.Duration
+	// MaxTemplateRequests skips templates that would send more requests
+	// than this to the target; scans may set a lower limit. Zero runs every
+	// template.
+	MaxTemplateRequests int
+}
+
+// WithSafetyLimits applies limits to the connections and responses of
+// every scan
+func WithSafetyLimits(limits SafetyLimits) ServiceOption {
+	return func(s *scannerServiceImpl) {
+		s.safety = limits
+	}
+}
+
+// safetyOption sets the engine's response read size and caps its timeouts
+// at the maximum connection lifetime
+func safetyOption(limits SafetyLimits) nuclei.NucleiSDKOptions {
+	return func(e *nuclei.NucleiEngine) error {
+		opts := e.Options()
+		if limits.MaxBodyRead > 0 {
+			opts.ResponseReadSize = limits.MaxBodyRead
+			if opts.ResponseSaveSize > limits.MaxBodyRead {
+				opts.ResponseSaveSize = limits.MaxBodyRead
+			}
+		}
+		if lifetime := limits.MaxConnectionLifetime; lifetime > 0 {
+			timeouts := types.NewTimeoutVariant(opts.Timeout)
+			for _, timeout := range []*time.Duration{
+				&timeouts.DialTimeout,
+				&timeouts.TcpReadTimeout,
+				&timeouts.HttpResponseHeaderTimeout,
+				&timeouts.HttpTimeout,
+				&timeouts.JsCompilerExecutionTimeout,
+				&timeouts.CodeExecutionTimeout,
+			} {
+				*timeout = min(*timeout, lifetime)
+			}
+			opts.SetTimeouts(timeouts)
+		}
+		return nil
+	}
+}
@@ -10,8 +10,13 @@ import (
 	"time"
 
 	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/correlation"
+	"nuclei-mcp/pkg/encryption"
+	"nuclei-mcp/pkg/policy"
+	"nuclei-mcp/pkg/secrets"
 
 	nuclei "github.com/projectdiscovery/nuclei/v3/lib"
+	nucleiconfig "github.com/projectdiscovery/nuclei/v3/pkg/catalog/config"
 	"github.com/projectdiscovery/nuclei/v3/pkg/output"
 )
 
@@ -29,67 +34,234 @@ type LoggerInterface interface {
 }
 
 type scannerServiceImpl struct {
-	cache   CacheInterface
-	console LoggerInterface
+	cache              CacheInterface
+	console            LoggerInterface
+	templateDirs       []string
+	shadowed           func() []string
+	allowCodeTemplates bool
+	deniedTags         []string
+	egress             *policy.EgressPolicy
+	selfGuard          *policy.SelfTargetGuard
+	passiveByDefault   bool
+	passiveRateLimit   int
+	maxRateLimit       int
+	templates          *templateCache
+	index              *TemplateIndex
+	resultBuffer       int
+	spillThreshold     int
+	spillDir           string
+	encryption         *encryption.Key
+	enricher           HostEnricher
+	notifier           ResultNotifier
+	engineTimeout      time.Duration
+	safety             SafetyLimits
+	exclusions         ExclusionMatcher
+	adaptive           *adaptiveTuner
+	queue              *ScanQueue
+	maintenance        *Maintenance
+	secrets            *secrets.Store
+	forceHTTP2         bool
+	quotas             *QuotaTracker
+	timings            *TemplateTimings
+	quarantine         *TemplateQuarantine
+
+	progressMu sync.Mutex
+	running    map[*scanProgress]struct{}
+
+	warmMu sync.RWMutex
+	warm   *warmEngine
 }
 
 type ScannerService interface {
 	CreateCacheKey(target string, severity string, protocols string) string
-	Scan(target string, severity string, protocols string, templateIDs []string) (cache.ScanResult, error)
-	ThreadSafeScan(ctx context.Context, target string, severity string, protocols string, templateIDs []string) (cache.ScanResult, error)
+	Scan(target string, severity string, protocols string, templateIDs []string, opts ...ScanOption) (cache.ScanResult, error)
+	ThreadSafeScan(ctx context.Context, target string, severity string, protocols string, templateIDs []string, opts ...ScanOption) (cache.ScanResult, error)
 	BasicScan(target string) (cache.ScanResult, error)
 	GetAll() []cache.ScanResult
 }
 
 // NewScannerService creates a new scanner service
-func NewScannerService(cache CacheInterface, console LoggerInterface) ScannerService {
-	return &scannerServiceImpl{
-		cache:   cache,
-		console: console,
+func NewScannerService(cache CacheInterface, console LoggerInterface, opts ...ServiceOption) ScannerService {
+	s := &scannerServiceImpl{
+		cache:            cache,
+		console:          console,
+		passiveRateLimit: DefaultPassiveRateLimit,
+		templates:        &templateCache{},
+		resultBuffer:     DefaultResultBuffer,
+		spillThreshold:   DefaultSpillThreshold,
+		engineTimeout:    DefaultEngineTimeout,
+		running:          make(map[*scanProgress]struct{}),
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *scannerServiceImpl) CreateCacheKey(target string, severity string, protocols string) string {
 	return fmt.Sprintf("%s:%s:%s", target, severity, protocols)
 }
 
-func (s *scannerServiceImpl) Scan(target string, severity string, protocols string, templateIDs []string) (cache.ScanResult, error) {
-
+// scanCacheKey creates the cache key for a scan including its options
+func (s *scannerServiceImpl) scanCacheKey(target string, severity string, protocols string, templateIDs []string, scanOpts ScanOptions) string {
 	cacheKey := s.CreateCacheKey(target, severity, protocols)
+	templateIDs = scanOpts.templateIDs(templateIDs)
 	if len(templateIDs) > 0 {
 		cacheKey += ":" + strings.Join(templateIDs, ",")
 	}
-
-	if result, found := s.cache.Get(cacheKey); found {
-		s.console.Log("Returning cached scan result for %s (%d findings)", target, len(result.Findings))
-		return result, nil
+	if scanOpts.CodeTemplates {
+		cacheKey += ":code"
 	}
+	if scanOpts.AllowUnsafe {
+		cacheKey += ":unsafe"
+	}
+	if scanOpts.Passive {
+		cacheKey += ":passive"
+	}
+	if len(scanOpts.Tags) > 0 {
+		cacheKey += ":tags=" + strings.Join(scanOpts.Tags, ",")
+	}
+	if scanOpts.RateLimit > 0 && !scanOpts.Passive {
+		cacheKey += fmt.Sprintf(":rl=%d", scanOpts.RateLimit)
+	}
+	if len(scanOpts.TemplateSources) > 0 {
+		cacheKey += ":src=" + strings.Join(scanOpts.TemplateSources, ",")
+	}
+	cacheKey += scanOpts.inlineKey()
+	for _, extractor := range scanOpts.Extractors {
+		cacheKey += fmt.Sprintf(":x=%s/%s/%s/%d/%s", extractor.Name, extractor.Type, extractor.Part, extractor.Group, extractor.Expression)
+	}
+	if len(scanOpts.excludedIDs) > 0 || len(scanOpts.excludedTags) > 0 {
+		cacheKey += fmt.Sprintf(":excl=%s/%s", strings.Join(scanOpts.excludedIDs, ","), strings.Join(scanOpts.excludedTags, ","))
+	}
+	if scanOpts.StopAt != "" {
+		cacheKey += ":stop=" + scanOpts.StopAt
+	}
+	if scanOpts.TemplateTimeout > 0 {
+		cacheKey += ":tt=" + scanOpts.TemplateTimeout.String()
+	}
+	if scanOpts.MaxTemplateRequests > 0 {
+		cacheKey += fmt.Sprintf(":tmr=%d", scanOpts.MaxTemplateRequests)
+	}
+	if len(scanOpts.Labels) > 0 {
+		cacheKey += ":labels=" + FormatLabels(scanOpts.Labels)
+	}
+	// Secrets are keyed by their references, never their values
+	if len(scanOpts.Variables) > 0 {
+		cacheKey += ":vars=" + FormatLabels(scanOpts.Variables)
+	}
+	if scanOpts.ProbeHTTPVersions {
+		cacheKey += ":httpv"
+	}
+	if scanOpts.AddressFamily != AddressFamilyAny {
+		cacheKey += ":af=" + string(scanOpts.AddressFamily)
+	}
+	if scanOpts.SNI != "" || scanOpts.HostHeader != "" {
+		cacheKey += fmt.Sprintf(":sni=%s:host=%s", scanOpts.SNI, strings.ToLower(scanOpts.HostHeader))
+	}
+	if scanOpts.DetectWAF {
+		cacheKey += ":waf"
+	}
+	if len(scanOpts.OpenAPI) > 0 {
+		cacheKey += ":openapi=" + openAPIKey(scanOpts.OpenAPI)
+	}
+	if len(scanOpts.APIParameters) > 0 {
+		cacheKey += ":params=" + FormatLabels(scanOpts.APIParameters)
+	}
+	if scanOpts.Fuzz {
+		cacheKey += ":fuzz"
+	}
+	if scanOpts.CrawlDepth > 0 {
+		cacheKey += fmt.Sprintf(":crawl=%d/%d", scanOpts.CrawlDepth, scanOpts.CrawlLimit)
+	}
+	return cacheKey + scanOpts.filterKey()
+}
 
-	s.console.Log("Starting new scan for target: %s", target)
-
+// buildOptions creates the nuclei SDK options shared by all scan modes
+func (s *scannerServiceImpl) buildOptions(severity string, protocols string, templateIDs []string, scanOpts ScanOptions) []nuclei.NucleiSDKOptions {
 	options := []nuclei.NucleiSDKOptions{
 		nuclei.DisableUpdateCheck(),
+		egressOption(s.egress),
+		safetyOption(s.safety),
 	}
+	templateIDs = scanOpts.templateIDs(templateIDs)
 
-	if severity != "" || protocols != "" || len(templateIDs) > 0 {
+	if scanOpts.CodeTemplates {
+		options = append(options, nuclei.EnableCodeTemplates())
+	}
+
+	if scanOpts.Fuzz {
+		options = append(options, nuclei.DASTMode())
+	}
+
+	if s.forceHTTP2 {
+		options = append(options, http2Option())
+	}
+
+	if scanOpts.Passive {
+		protocols = PassiveProtocols
+		options = append(options,
+			nuclei.WithGlobalRateLimit(s.scanRateLimit(scanOpts), time.Second),
+			nuclei.WithConcurrency(nuclei.Concurrency{
+				TemplateConcurrency:           1,
+				HostConcurrency:               1,
+				HeadlessHostConcurrency:       1,
+				HeadlessTemplateConcurrency:   1,
+				JavascriptTemplateConcurrency: 1,
+				TemplatePayloadConcurrency:    1,
+				ProbeConcurrency:              1,
+			}),
+		)
+	} else if scanOpts.tunedRateLimit(scanOpts.RateLimit) > 0 {
+		options = append(options, nuclei.WithGlobalRateLimit(s.scanRateLimit(scanOpts), time.Second))
+	}
+
+	if scanOpts.tuning != nil {
+		options = append(options, concurrencyOption(scanOpts.tuning.Concurrency))
+	}
+
+	excludeTags := s.deniedTags
+	if scanOpts.AllowUnsafe {
+		excludeTags = nil
+	}
+	excludeTags = append(append(append([]string(nil), excludeTags...), scanOpts.excludedTags...), scanOpts.ExcludeTags...)
+	excludeIDs := append(append([]string(nil), scanOpts.excludedIDs...), scanOpts.ExcludeIDs...)
+	// Inline templates are not in the library the quarantine keeps
+	if quarantined := s.quarantine.ids(); len(quarantined) > 0 && len(scanOpts.InlineTemplates) == 0 {
+		s.console.Log("Skipping quarantined templates: %v", quarantined)
+		excludeIDs = append(excludeIDs, quarantined...)
+	}
+
+	if severity != "" || protocols != "" || len(templateIDs) > 0 || len(scanOpts.Tags) > 0 || len(excludeTags) > 0 || len(excludeIDs) > 0 ||
+		len(scanOpts.Authors) > 0 || len(scanOpts.ExcludeSeverities) > 0 {
 		filters := nuclei.TemplateFilters{}
 
+		if len(scanOpts.Tags) > 0 {
+			filters.Tags = scanOpts.Tags
+		}
+
+		if len(excludeTags) > 0 {
+			filters.ExcludeTags = excludeTags
+		}
+
+		if len(excludeIDs) > 0 {
+			filters.ExcludeIDs = excludeIDs
+		}
+
+		if len(scanOpts.Authors) > 0 {
+			filters.Authors = scanOpts.Authors
+		}
+
+		if len(scanOpts.ExcludeSeverities) > 0 {
+			filters.ExcludeSeverities = strings.Join(scanOpts.ExcludeSeverities, ",")
+		}
+
 		if severity != "" {
 			filters.Severity = severity
 		}
 
 		if protocols != "" {
-			protocolsList := strings.Split(protocols, ",")
-			var validProtocols []string
-			for _, p := range protocolsList {
-				p = strings.TrimSpace(p)
-				if p != "https" {
-					validProtocols = append(validProtocols, p)
-				}
-			}
-			if len(validProtocols) > 0 {
-				filters.ProtocolTypes = strings.Join(validProtocols, ",")
-			}
+			filters.ProtocolTypes = protocols
 		}
 
 		if len(templateIDs) > 0 {
@@ -99,132 +271,270 @@ func (s *scannerServiceImpl) Scan(target string, severity string, protocols stri
 		options = append(options, nuclei.WithTemplateFilters(filters))
 	}
 
-	ne, err := nuclei.NewNucleiEngineCtx(context.Background(), options...)
-	if err != nil {
-		s.console.Log("Failed to create nuclei engine: %v", err)
-		return cache.ScanResult{}, err
+	if len(scanOpts.TemplateSources) > 0 {
+		options = append(options, nuclei.WithTemplatesOrWorkflows(nuclei.TemplateSources{Templates: scanOpts.TemplateSources}))
+	} else {
+		options = append(options, s.templateSourceOptions(s.indexedTemplates(severity, protocols, templateIDs, scanOpts.Tags))...)
 	}
-	defer ne.Close()
 
-	ne.LoadTargets([]string{target}, true)
+	return options
+}
 
-	if err := ne.LoadAllTemplates(); err != nil {
-		s.console.Log("Failed to load templates: %v", err)
-		return cache.ScanResult{}, err
+// templateSourceOptions adds the configured template directories to the
+// default templates directory, or loads only the given template files of
+// those directories
+func (s *scannerServiceImpl) templateSourceOptions(files []string) []nuclei.NucleiSDKOptions {
+	if len(s.templateDirs) == 0 && len(files) == 0 {
+		return nil
 	}
-
-	var findings []*output.ResultEvent
-	var findingsMutex sync.Mutex
-
-	callback := func(event *output.ResultEvent) {
-		findingsMutex.Lock()
-		defer findingsMutex.Unlock()
-		findings = append(findings, event)
-		s.console.Log("Found vulnerability: %s (%s) on %s", event.Info.Name, event.Info.SeverityHolder.Severity.String(), event.Host)
+	sources := files
+	if len(sources) == 0 {
+		if defaultDir := nucleiconfig.DefaultConfig.TemplatesDirectory; defaultDir != "" {
+			if _, err := os.Stat(defaultDir); err == nil {
+				sources = append(sources, defaultDir)
+			}
+		}
+		sources = append(sources, s.templateDirs...)
 	}
-
-	err = ne.ExecuteWithCallback(callback)
-	if err != nil {
-		s.console.Log("Scan failed: %v", err)
-		return cache.ScanResult{}, err
+	options := []nuclei.NucleiSDKOptions{nuclei.WithTemplatesOrWorkflows(nuclei.TemplateSources{Templates: sources})}
+	if s.shadowed != nil {
+		options = append(options, func(e *nuclei.NucleiEngine) error {
+			opts := e.Options()
+			opts.ExcludedTemplates = append(opts.ExcludedTemplates, s.shadowed()...)
+			return nil
+		})
 	}
+	return options
+}
 
-	result := cache.ScanResult{
-		Target:   target,
-		Findings: findings,
-		ScanTime: time.Now(),
-	}
+// scanMode is how a scan runs its engine: exclusively, or alongside the
+// other thread-safe scans
+type scanMode struct {
+	threadSafe bool
+	// name names the scan in its log lines
+	name    string
+	execute func(s *scannerServiceImpl, ctx context.Context, target string, options []nuclei.NucleiSDKOptions, callback func(*output.ResultEvent), monitor *scanMonitor) error
+}
 
-	s.cache.Set(cacheKey, result)
+var (
+	exclusiveScan  = scanMode{name: "Scan", execute: (*scannerServiceImpl).executeExclusive}
+	threadSafeScan = scanMode{threadSafe: true, name: "Thread-safe scan", execute: (*scannerServiceImpl).executeThreadSafe}
+)
 
-	s.console.Log("Scan completed for %s, found %d vulnerabilities", target, len(findings))
+func (s *scannerServiceImpl) Scan(target string, severity string, protocols string, templateIDs []string, opts ...ScanOption) (cache.ScanResult, error) {
+	return s.scan(context.Background(), exclusiveScan, target, severity, protocols, templateIDs, opts)
+}
 
-	return result, nil
+func (s *scannerServiceImpl) ThreadSafeScan(ctx context.Context, target string, severity string, protocols string, templateIDs []string, opts ...ScanOption) (cache.ScanResult, error) {
+	return s.scan(ctx, threadSafeScan, target, severity, protocols, templateIDs, opts)
 }
 
-func (s *scannerServiceImpl) ThreadSafeScan(ctx context.Context, target string, severity string, protocols string, templateIDs []string) (cache.ScanResult, error) {
-	// Create cache key
-	cacheKey := s.CreateCacheKey(target, severity, protocols)
-	if len(templateIDs) > 0 {
-		cacheKey += ":" + strings.Join(templateIDs, ",")
+// scan checks, prepares and runs a scan of target in mode, and assembles,
+// caches and reports its result
+func (s *scannerServiceImpl) scan(ctx context.Context, mode scanMode, target string, severity string, protocols string, templateIDs []string, opts []ScanOption) (result cache.ScanResult, err error) {
+	defer s.recoverScan(target, &err)
+	target = policy.NormalizeTarget(target)
+
+	scanOpts, err := s.resolveScanOptions(target, append([]ScanOption{WithCorrelationID(correlation.FromContext(ctx))}, opts...))
+	if err != nil {
+		return cache.ScanResult{}, err
 	}
+	ctx, span := startScanSpan(correlation.NewContext(ctx, scanOpts.CorrelationID), target, mode.threadSafe, scanOpts)
+	defer func() { endScanSpan(span, result, err) }()
+	console := s.logger(ctx)
 
-	if result, found := s.cache.Get(cacheKey); found {
-		s.console.Log("Returning cached scan result for %s (%d findings)", target, len(result.Findings))
-		return result, nil
+	if protocols, err = NormalizeProtocols(protocols); err != nil {
+		return cache.ScanResult{}, err
 	}
 
-	s.console.Log("Starting new thread-safe scan for target: %s", target)
+	if err := s.egress.CheckTarget(ctx, target); err != nil {
+		console.Log("Scan of %s refused: %v", target, err)
+		return cache.ScanResult{}, err
+	}
 
-	options := []nuclei.NucleiSDKOptions{
-		nuclei.DisableUpdateCheck(),
+	if err := s.checkSelfTarget(ctx, target, scanOpts); err != nil {
+		return cache.ScanResult{}, err
 	}
 
-	if severity != "" || protocols != "" || len(templateIDs) > 0 {
-		filters := nuclei.TemplateFilters{}
+	cacheKey := s.scanCacheKey(target, severity, protocols, templateIDs, scanOpts)
 
-		if severity != "" {
-			filters.Severity = severity
-		}
+	if result, found := s.cache.Get(cacheKey); found && !scanOpts.Fresh {
+		console.Log("Returning cached scan result for %s (%d findings)", target, len(result.Findings))
+		markCached(span)
+		return result, nil
+	}
 
-		if protocols != "" {
-			protocolsList := strings.Split(protocols, ",")
-			var validProtocols []string
-			for _, p := range protocolsList {
-				p = strings.TrimSpace(p)
-				if p != "https" {
-					validProtocols = append(validProtocols, p)
-				}
-			}
-			if len(validProtocols) > 0 {
-				filters.ProtocolTypes = strings.Join(validProtocols, ",")
-			}
+	var fingerprint string
+	if scanOpts.SkipUnchanged {
+		var unchanged bool
+		client := s.targetClient(scanOpts, false)
+		result, fingerprint, unchanged = CheckUnchanged(ctx, console, client, s.cache, cacheKey, target)
+		client.CloseIdleConnections()
+		if unchanged {
+			markCached(span)
+			return result, nil
 		}
+	}
 
-		if len(templateIDs) > 0 {
-			filters.IDs = templateIDs
-		}
+	done, err := s.startScan(console, target)
+	if err != nil {
+		return cache.ScanResult{}, err
+	}
+	defer done()
 
-		options = append(options, nuclei.WithTemplateFilters(filters))
+	if err := s.checkQuota(target, scanOpts.Client); err != nil {
+		return cache.ScanResult{}, err
 	}
 
-	ne, err := nuclei.NewThreadSafeNucleiEngineCtx(ctx, options...)
+	release, err := s.acquireSlot(ctx, target, scanOpts.Priority)
 	if err != nil {
-		s.console.Log("Failed to create thread-safe nuclei engine: %v", err)
 		return cache.ScanResult{}, err
 	}
-	defer ne.Close()
+	defer release()
 
-	var findings []*output.ResultEvent
-	var findingsMutex sync.Mutex
+	if mode.threadSafe {
+		console.Log("Starting new thread-safe scan for target: %s", target)
+	} else {
+		console.Log("Starting new scan for target: %s", target)
+	}
+	usage := startUsage()
 
-	ne.GlobalResultCallback(func(event *output.ResultEvent) {
-		findingsMutex.Lock()
-		defer findingsMutex.Unlock()
-		findings = append(findings, event)
-		s.console.Log("Found vulnerability: %s (%s) on %s", event.Info.Name, event.Info.SeverityHolder.Severity.String(), event.Host)
-	})
+	removeInline, err := scanOpts.writeInlineTemplates()
+	if err != nil {
+		console.Log("Scan of %s failed: %v", target, err)
+		return cache.ScanResult{}, err
+	}
+	defer removeInline()
+	options := s.buildOptions(severity, protocols, templateIDs, scanOpts)
 
-	err = ne.ExecuteNucleiWithOptsCtx(ctx, []string{target}, options...)
+	vars, err := s.resolveVariables(scanOpts.Variables)
 	if err != nil {
-		s.console.Log("Thread-safe scan failed: %v", err)
+		console.Log("Scan of %s failed: %v", target, err)
 		return cache.ScanResult{}, err
 	}
+	api, err := s.newAPIRequests(target, scanOpts)
+	if err != nil {
+		console.Log("Scan of %s failed: %v", target, err)
+		return cache.ScanResult{}, err
+	}
+
+	override := newHostOverride(scanOpts)
+	addresses, err := resolveAddresses(ctx, target, scanOpts.AddressFamily)
+	if err == nil {
+		addresses, err = originAddresses(ctx, target, override, addresses)
+	}
+	if err != nil {
+		console.Log("Scan of %s failed: %v", target, err)
+		return cache.ScanResult{}, err
+	}
+	waf := s.detectWAF(ctx, console, target, scanOpts)
+	crawled, crawlRequests := s.crawl(ctx, console, target, scanOpts)
+
+	collector := s.newCollector(console, scanOpts.CorrelationID)
+	defer collector.release()
+
+	// Only the execution is cancelled by an early stop
+	caps := newTemplateCaps(scanOpts)
+	progress := s.trackScan(target, scanOpts)
+	defer s.finishScan(progress)
+	execCtx, stop := newStopper(withScanProgress(withTemplateCaps(withHostOverride(withScanAddresses(withCrawledURLs(withAPIRequests(withScanVariables(withCodeTemplates(ctx, scanOpts.CodeTemplates), vars), api), crawled), addresses), override), caps), progress), scanOpts.StopAt)
+	defer stop.release()
+
+	monitor := s.adaptive.monitor(target, scanOpts, console)
+	if err := mode.execute(s, execCtx, target, options, stop.wrap(console, api.redacting(vars.redacting(collector.collect))), monitor); err != nil {
+		console.Log("%s failed: %v", mode.name, err)
+		return cache.ScanResult{}, s.explainNoTemplates(executionError(err), progress, scanOpts)
+	}
 
-	result := cache.ScanResult{
-		Target:   target,
-		Findings: findings,
-		ScanTime: time.Now(),
+	stats := monitor.stats()
+	if stats.Requests == 0 {
+		stats.Failures = checkReachable(ctx, target, addresses)
 	}
+	findings, spillFile, spilled := collector.finish()
+	result = cache.ScanResult{
+		Target:            target,
+		Findings:          findings,
+		SpillFile:         spillFile,
+		SpilledFindings:   spilled,
+		ScanTime:          time.Now(),
+		Stats:             usage.record(stats),
+		CorrelationID:     scanOpts.CorrelationID,
+		StoppedEarly:      stop.stoppedEarly(),
+		Labels:            scanOpts.Labels,
+		WAF:               waf,
+		APIOperations:     api.operations(),
+		CrawledURLs:       crawled,
+		TargetFingerprint: fingerprint,
+	}
+	markWAFInterference(waf, findings)
+	if !result.StoppedEarly && ctx.Err() == nil {
+		progress.complete()
+	}
+	result.Stats.Templates = progress.templateStats(s.availableTemplates(scanOpts), result.StoppedEarly)
+	caps.record(console, result.Stats)
+	// The crawl's requests count towards the client's quota
+	quotaStats := *result.Stats
+	quotaStats.Requests += crawlRequests
+	s.quotas.Record(scanOpts.Client, &quotaStats)
+
+	if len(scanOpts.Extractors) > 0 {
+		if result.Extractions, err = RunExtractors(ctx, target, scanOpts.Extractors, egressOption(s.egress)); err != nil {
+			console.Log("Extraction failed: %v", err)
+			return cache.ScanResult{}, err
+		}
+	}
+	s.probeHTTPVersions(ctx, console, target, scanOpts, &result)
+	result.Hosts = s.enrichHosts(ctx, target, findings)
 
+	collector.keep()
 	s.cache.Set(cacheKey, result)
+	s.notify(result)
 
-	s.console.Log("Thread-safe scan completed for %s, found %d vulnerabilities", target, len(findings))
+	console.Log("%s completed for %s, found %d vulnerabilities", mode.name, target, len(findings)+spilled)
 
 	return result, nil
 }
 
-func (s *scannerServiceImpl) BasicScan(target string) (cache.ScanResult, error) {
+// notify tells the notifier, if any, of a completed scan
+func (s *scannerServiceImpl) notify(result cache.ScanResult) {
+	if s.notifier != nil {
+		s.notifier.Notify(result)
+	}
+}
+
+// enrichHosts describes the host of target and the other hosts findings
+// were reported on, when an enricher is set
+func (s *scannerServiceImpl) enrichHosts(ctx context.Context, target string, findings []*output.ResultEvent) []cache.HostInfo {
+	if s.enricher == nil {
+		return nil
+	}
+	hosts := []string{TargetHost(target)}
+	seen := map[string]bool{hosts[0]: true}
+	for _, finding := range findings {
+		if host := TargetHost(finding.Host); host != "" && !seen[host] {
+			seen[host] = true
+			hosts = append(hosts, host)
+		}
+	}
+	var infos []cache.HostInfo
+	for _, host := range hosts {
+		infos = append(infos, s.enricher.Enrich(ctx, host))
+	}
+	return infos
+}
+
+func (s *scannerServiceImpl) BasicScan(target string) (result cache.ScanResult, err error) {
+	defer s.recoverScan(target, &err)
+
+	if err := s.egress.CheckTarget(context.Background(), target); err != nil {
+		s.console.Log("Basic scan of %s refused: %v", target, err)
+		return cache.ScanResult{}, err
+	}
+
+	if err := s.checkSelfTarget(context.Background(), target, ScanOptions{}); err != nil {
+		return cache.ScanResult{}, err
+	}
+
 	// Create cache key for basic scan
 	cacheKey := fmt.Sprintf("basic:%s", target)
 
@@ -233,6 +543,18 @@ func (s *scannerServiceImpl) BasicScan(target string) (cache.ScanResult, error)
 		return result, nil
 	}
 
+	done, err := s.startScan(s.console, target)
+	if err != nil {
+		return cache.ScanResult{}, err
+	}
+	defer done()
+
+	release, err := s.acquireSlot(context.Background(), target, PriorityInteractive)
+	if err != nil {
+		return cache.ScanResult{}, err
+	}
+	defer release()
+
 	s.console.Log("Starting new basic scan for target: %s", target)
 
 	templatesDir, err := filepath.Abs("./templates")
@@ -284,42 +606,32 @@ requests:
 			IDs:         []string{"basic-test"},
 		}),
 		nuclei.DisableUpdateCheck(),
+		egressOption(s.egress),
+		safetyOption(s.safety),
 	}
 
-	ne, err := nuclei.NewNucleiEngineCtx(context.Background(), opts...)
-	if err != nil {
-		s.console.Log("Failed to create nuclei engine: %v", err)
-		return cache.ScanResult{}, err
-	}
-	defer ne.Close()
-
-	ne.LoadTargets([]string{target}, true)
-
-	var findings []*output.ResultEvent
-	var findingsMutex sync.Mutex
+	collector := s.newCollector(s.console, "")
+	defer collector.release()
 
-	callback := func(event *output.ResultEvent) {
-		findingsMutex.Lock()
-		defer findingsMutex.Unlock()
-		findings = append(findings, event)
-		s.console.Log("Found vulnerability: %s (%s) on %s", event.Info.Name, event.Info.SeverityHolder.Severity.String(), event.Host)
-	}
-
-	err = ne.ExecuteWithCallback(callback)
-	if err != nil {
+	if err := s.executeExclusive(context.Background(), target, opts, collector.collect, nil); err != nil {
 		s.console.Log("Basic scan failed: %v", err)
-		return cache.ScanResult{}, err
+		return cache.ScanResult{}, executionError(err)
 	}
 
-	result := cache.ScanResult{
-		Target:   target,
-		Findings: findings,
-		ScanTime: time.Now(),
+	findings, spillFile, spilled := collector.finish()
+	result = cache.ScanResult{
+		Target:          target,
+		Findings:        findings,
+		SpillFile:       spillFile,
+		SpilledFindings: spilled,
+		ScanTime:        time.Now(),
 	}
 
+	collector.keep()
 	s.cache.Set(cacheKey, result)
+	s.notify(result)
 
-	s.console.Log("Basic scan completed for %s, found %d vulnerabilities", target, len(findings))
+	s.console.Log("Basic scan completed for %s, found %d vulnerabilities", target, len(findings)+spilled)
 
 	return result, nil
 }
@@ -0,0 +1,41 @@
+package scanner
+
+import (
+	"context"
+
+	"nuclei-mcp/pkg/policy"
+)
+
+// WithSelfTargetGuard refuses scans of the server's own host unless a scan
+// is allowed with WithSelfTargetAllowed
+func WithSelfTargetGuard(guard *policy.SelfTargetGuard) ServiceOption {
+	return func(s *scannerServiceImpl) {
+		s.selfGuard = guard
+	}
+}
+
+// WithSelfTargetAllowed lets the scan target the server's own host. The
+// approval (e.g. a ticket ID or approver) is required and logged.
+func WithSelfTargetAllowed(approval string) ScanOption {
+	return func(o *ScanOptions) {
+		o.AllowSelfTarget = true
+		o.Approval = approval
+	}
+}
+
+// checkSelfTarget refuses a scan of the server's own host unless the scan
+// allows it
+func (s *scannerServiceImpl) checkSelfTarget(ctx context.Context, target string, scanOpts ScanOptions) error {
+	if s.selfGuard == nil {
+		return nil
+	}
+	override := policy.Override{Allow: scanOpts.AllowSelfTarget, Approval: scanOpts.Approval}
+	if err := s.selfGuard.Check(ctx, target, override); err != nil {
+		s.logger(ctx).Log("Scan of %s refused: %v", target, err)
+		return err
+	}
+	if override.Granted() {
+		s.logger(ctx).Log("Self-target guard lifted for scan of %s, approval: %s", target, scanOpts.Approval)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/model/types/severity"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+)
+
+// StopAny is the StopAt of scans ending at their first finding, whatever its
+// severity
+const StopAny = "any"
+
+// stopLevels ranks the severities a scan can stop at
+var stopLevels = map[string]severity.Severity{
+	"info":     severity.Info,
+	"low":      severity.Low,
+	"medium":   severity.Medium,
+	"high":     severity.High,
+	"critical": severity.Critical,
+}
+
+// WithStopOnFirstMatch ends the scan at its first finding, for yes/no
+// exposure checks
+func WithStopOnFirstMatch() ScanOption {
+	return func(o *ScanOptions) {
+		o.StopAt = StopAny
+	}
+}
+
+// WithStopAtSeverity ends the scan at its first finding of the given
+// severity (info, low, medium, high or critical) or above. An empty
+// severity leaves the scan running to completion.
+func WithStopAtSeverity(level string) ScanOption {
+	return func(o *ScanOptions) {
+		if level = strings.ToLower(strings.TrimSpace(level)); level != "" {
+			o.StopAt = level
+		}
+	}
+}
+
+// checkStopAt validates the severity a scan stops at
+func checkStopAt(stopAt string) error {
+	if _, ok := stopLevels[stopAt]; stopAt != "" && stopAt != StopAny && !ok {
+		return fmt.Errorf("%w %q, use info, low, medium, high or critical", ErrInvalidSeverity, stopAt)
+	}
+	return nil
+}
+
+// stopper cancels a scan once it reports a finding at its threshold
+type stopper struct {
+	stopAt string
+	cancel context.CancelFunc
+	fired  atomic.Bool
+}
+
+// newStopper returns the context of a scan stopping at stopAt, and its
+// stopper. Without a threshold, ctx is returned as is and the stopper is
+// nil.
+func newStopper(ctx context.Context, stopAt string) (context.Context, *stopper) {
+	if stopAt == "" {
+		return ctx, nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	return ctx, &stopper{stopAt: stopAt, cancel: cancel}
+}
+
+// wrap returns the result callback of the scan: collect, followed by a
+// check of the finding against the threshold
+func (st *stopper) wrap(console LoggerInterface, collect func(*output.ResultEvent)) func(*output.ResultEvent) {
+	if st == nil {
+		return collect
+	}
+	return func(event *output.ResultEvent) {
+		collect(event)
+		if event == nil || !st.matches(event.Info.SeverityHolder.Severity) {
+			return
+		}
+		if st.fired.CompareAndSwap(false, true) {
+			console.Log("Stopping scan at %s finding: %s (%s)", event.Info.SeverityHolder.Severity, event.Info.Name, event.TemplateID)
+			st.cancel()
+		}
+	}
+}
+
+// matches reports whether a finding of level ends the scan
+func (st *stopper) matches(level severity.Severity) bool {
+	if st.stopAt == StopAny {
+		return true
+	}
+	threshold := stopLevels[st.stopAt]
+	return level >= threshold && level <= severity.Critical
+}
+
+// stoppedEarly reports whether the scan was ended early. It is safe on a
+// nil stopper.
+func (st *stopper) stoppedEarly() bool {
+	return st != nil && st.fired.Load()
+}
+
+// release frees the stopper's context
+func (st *stopper) release() {
+	if st != nil {
+		st.cancel()
+	}
+}
@@ -0,0 +1,123 @@
+package scanner
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"nuclei-mcp/pkg/policy"
+)
+
+// TargetClientOptions configures a target client
+type TargetClientOptions struct {
+	// Egress restricts the addresses and ports the client connects to
+	Egress *policy.EgressPolicy
+	// RateLimit caps the requests per second the client sends; zero is
+	// unlimited
+	RateLimit int
+	// HTTP2 offers HTTP/2 in TLS handshakes
+	HTTP2 bool
+}
+
+// NewTargetClient returns an HTTP client for the requests sent to a scan's
+// target outside the engine, such as its crawl, WAF detection, HTTP version
+// probe and change detection. Like the engine, it does not verify TLS
+// certificates. Each connection is checked against the egress policy after
+// its host was resolved, so a host answering DNS with another address after
+// the target check cannot lead the client to a denied one. Redirects are
+// not followed.
+func NewTargetClient(opts TargetClientOptions) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second, Control: opts.Egress.DialControl}
+	transport := &targetTransport{base: &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
+		TLSHandshakeTimeout: 10 * time.Second,
+		ForceAttemptHTTP2:   opts.HTTP2,
+	}}
+	if opts.RateLimit > 0 {
+		transport.interval = time.Second / time.Duration(opts.RateLimit)
+	}
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// targetTransport spaces out the requests of a target client and counts
+// them
+type targetTransport struct {
+	base     *http.Transport
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+
+	requests atomic.Int64
+}
+
+func (t *targetTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.wait(req.Context()); err != nil {
+		return nil, err
+	}
+	t.requests.Add(1)
+	return t.base.RoundTrip(req)
+}
+
+// CloseIdleConnections lets http.Client.CloseIdleConnections reach the
+// underlying transport
+func (t *targetTransport) CloseIdleConnections() {
+	t.base.CloseIdleConnections()
+}
+
+// wait blocks until the rate limit allows the next request
+func (t *targetTransport) wait(ctx context.Context) error {
+	if t.interval == 0 {
+		return nil
+	}
+	t.mu.Lock()
+	at := t.next
+	if now := time.Now(); at.Before(now) {
+		at = now
+	}
+	t.next = at.Add(t.interval)
+	t.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// withoutRedirects returns a copy of client that does not follow redirects
+func withoutRedirects(client *http.Client) *http.Client {
+	copied := *client
+	copied.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &copied
+}
+
+// sentRequests returns the requests a target client sent
+func sentRequests(client *http.Client) int {
+	if transport, ok := client.Transport.(*targetTransport); ok {
+		return int(transport.requests.Load())
+	}
+	return 0
+}
+
+// targetClient returns the client of the requests a scan sends to its
+// target outside the engine, under the service's egress policy and the
+// scan's rate limit
+func (s *scannerServiceImpl) targetClient(scanOpts ScanOptions, http2 bool) *http.Client {
+	return NewTargetClient(TargetClientOptions{Egress: s.egress, RateLimit: s.scanRateLimit(scanOpts), HTTP2: http2})
+}
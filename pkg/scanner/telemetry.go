@@ -0,0 +1,52 @@
+package scanner
+
+import (
+	"context"
+
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/correlation"
+	"nuclei-mcp/pkg/telemetry"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WithTraceParent starts the span of a Scan under the span of ctx, since
+// Scan takes no context. ThreadSafeScan uses the span of its own context.
+func WithTraceParent(ctx context.Context) ScanOption {
+	return func(o *ScanOptions) {
+		if parent := trace.SpanContextFromContext(ctx); parent.IsValid() {
+			o.traceParent = parent
+		}
+	}
+}
+
+// startScanSpan starts the span of a scan of target, under the span of ctx
+// or the trace parent of the scan options
+func startScanSpan(ctx context.Context, target string, threadSafe bool, scanOpts ScanOptions) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() && scanOpts.traceParent.IsValid() {
+		ctx = trace.ContextWithSpanContext(ctx, scanOpts.traceParent)
+	}
+	return telemetry.Tracer().Start(ctx, "scan", trace.WithAttributes(
+		attribute.String("scan.target", target),
+		attribute.Bool("scan.thread_safe", threadSafe),
+		attribute.String(correlation.MetaKey, scanOpts.CorrelationID),
+	))
+}
+
+// endScanSpan records the findings of a scan in its span and ends it
+func endScanSpan(span trace.Span, result cache.ScanResult, err error) {
+	span.SetAttributes(attribute.Int("scan.findings", len(result.Findings)))
+	telemetry.End(span, err)
+}
+
+// markCached records in span that the scan returned a cached result
+func markCached(span trace.Span) {
+	span.SetAttributes(attribute.Bool("scan.cached", true))
+}
+
+// startSpan starts a span named name under the span of ctx, for the stages
+// of a scan
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return telemetry.Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
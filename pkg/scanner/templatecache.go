@@ -0,0 +1,95 @@
+package scanner
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"path/filepath"
+	"sync"
+
+	nuclei "github.com/projectdiscovery/nuclei/v3/lib"
+	nucleiconfig "github.com/projectdiscovery/nuclei/v3/pkg/catalog/config"
+	"github.com/projectdiscovery/nuclei/v3/pkg/templates"
+)
+
+// templateCache shares parsed templates between scan engines, so scans with
+// different filters do not re-read and re-parse every template file. The
+// cache is keyed by a fingerprint of the template directories (file paths,
+// sizes and modification times) and is dropped when any template changes.
+// Compiled templates hold per-engine state and are not shared.
+type templateCache struct {
+	mu          sync.Mutex
+	fingerprint string
+	parsed      *templates.Cache
+}
+
+// parsedCache returns the parsed templates cache for the current state of
+// dirs, and whether it was reset because templates changed
+func (c *templateCache) parsedCache(dirs []string) (*templates.Cache, bool) {
+	fingerprint := fingerprintDirs(dirs)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.parsed != nil && c.fingerprint == fingerprint {
+		return c.parsed, false
+	}
+	reset := c.parsed != nil
+	c.parsed = templates.NewCache()
+	c.fingerprint = fingerprint
+	return c.parsed, reset
+}
+
+// fingerprintDirs hashes the path, size and modification time of every file
+// under dirs. Missing directories hash as empty.
+func fingerprintDirs(dirs []string) string {
+	h := fnv.New64a()
+	for _, dir := range dirs {
+		fmt.Fprintf(h, "%s\n", dir)
+		_ = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return nil
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return nil
+			}
+			fmt.Fprintf(h, "%s|%d|%d\n", path, info.Size(), info.ModTime().UnixNano())
+			return nil
+		})
+	}
+	return fmt.Sprintf("%x", h.Sum64())
+}
+
+// WithTemplateCache shares parsed templates between scans (enabled by
+// default)
+func WithTemplateCache(enabled bool) ServiceOption {
+	return func(s *scannerServiceImpl) {
+		if enabled {
+			s.templates = &templateCache{}
+		} else {
+			s.templates = nil
+		}
+	}
+}
+
+// templateCacheDirs returns the directories scans load templates from
+func (s *scannerServiceImpl) templateCacheDirs() []string {
+	var dirs []string
+	if defaultDir := nucleiconfig.DefaultConfig.TemplatesDirectory; defaultDir != "" {
+		dirs = append(dirs, defaultDir)
+	}
+	return append(dirs, s.templateDirs...)
+}
+
+// useTemplateCache makes the engine parse templates through the shared
+// cache. It must be called before the engine loads its templates.
+func (s *scannerServiceImpl) useTemplateCache(ne *nuclei.NucleiEngine) {
+	if s.templates == nil {
+		return
+	}
+	parsed, reset := s.templates.parsedCache(s.templateCacheDirs())
+	if reset {
+		s.console.Log("Templates changed on disk, template cache cleared")
+	}
+	ne.GetExecuterOptions().Parser = templates.NewParserWithParsedCache(parsed)
+}
@@ -0,0 +1,301 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+
+	nuclei "github.com/projectdiscovery/nuclei/v3/lib"
+	"github.com/projectdiscovery/nuclei/v3/pkg/input/types"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	"github.com/projectdiscovery/nuclei/v3/pkg/protocols"
+	"github.com/projectdiscovery/nuclei/v3/pkg/protocols/common/contextargs"
+	"github.com/projectdiscovery/nuclei/v3/pkg/scan"
+	"github.com/projectdiscovery/nuclei/v3/pkg/templates"
+)
+
+// WithTemplateTimeout bounds how long each template may run against the
+// target. The scan's safety limit, if lower, applies instead.
+func WithTemplateTimeout(timeout time.Duration) ScanOption {
+	return func(o *ScanOptions) {
+		if timeout > 0 {
+			o.TemplateTimeout = timeout
+		}
+	}
+}
+
+// WithMaxTemplateRequests skips templates that would send more than
+// requests requests to the target, such as large fuzzing or brute force
+// templates. The scan's safety limit, if lower, applies instead.
+func WithMaxTemplateRequests(requests int) ScanOption {
+	return func(o *ScanOptions) {
+		if requests > 0 {
+			o.MaxTemplateRequests = requests
+		}
+	}
+}
+
+// capLimit returns the lower of a scan's limit and the service's, ignoring
+// zero (unlimited) values
+func capLimit[T time.Duration | int](scan T, service T) T {
+	if scan == 0 || (service > 0 && service < scan) {
+		return service
+	}
+	return scan
+}
+
+// templateCaps are the per-template limits of a scan, and the templates
+// they stopped
+type templateCaps struct {
+	timeout     time.Duration
+	maxRequests int
+
+	mu       sync.Mutex
+	timedOut map[string]bool
+	skipped  map[string]bool
+}
+
+type templateCapsKey struct{}
+
+// newTemplateCaps returns the per-template limits of a scan, or nil when it
+// has none
+func newTemplateCaps(scanOpts ScanOptions) *templateCaps {
+	if scanOpts.TemplateTimeout <= 0 && scanOpts.MaxTemplateRequests <= 0 {
+		return nil
+	}
+	return &templateCaps{
+		timeout:     scanOpts.TemplateTimeout,
+		maxRequests: scanOpts.MaxTemplateRequests,
+		timedOut:    make(map[string]bool),
+		skipped:     make(map[string]bool),
+	}
+}
+
+// withTemplateCaps returns ctx carrying caps to the templates the scan runs
+func withTemplateCaps(ctx context.Context, caps *templateCaps) context.Context {
+	if caps == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, templateCapsKey{}, caps)
+}
+
+// templateCapsFrom returns the per-template limits of the scan running ctx
+func templateCapsFrom(ctx context.Context) *templateCaps {
+	caps, _ := ctx.Value(templateCapsKey{}).(*templateCaps)
+	return caps
+}
+
+// record adds the templates the caps stopped to stats
+func (c *templateCaps) record(console LoggerInterface, stats *cache.ScanStats) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats.TimedOutTemplates = sortedKeys(c.timedOut)
+	stats.SkippedTemplates = sortedKeys(c.skipped)
+	if len(stats.TimedOutTemplates) > 0 {
+		console.Log("Templates stopped after the %s template timeout: %v", c.timeout, stats.TimedOutTemplates)
+	}
+	if len(stats.SkippedTemplates) > 0 {
+		console.Log("Templates skipped for sending more than %d requests: %v", c.maxRequests, stats.SkippedTemplates)
+	}
+}
+
+func sortedKeys(set map[string]bool) []string {
+	if len(set) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// templateExecuter applies the per-template limits, variables, addresses,
+// host override and OpenAPI requests of the scan it runs in to a template
+// and reports its run to the scan's progress. Templates are compiled once per engine and may run in
+// scans with different limits, so all are read from the scan's context;
+// without them it runs the template as is.
+type templateExecuter struct {
+	protocols.Executer
+	id string
+	// http is set for templates sending HTTP requests, which connect to the
+	// addresses of the scan's address family, send its Host header and run
+	// against the operations of its OpenAPI document
+	http bool
+}
+
+// wrapTemplates makes loaded templates honour per-template limits and
+// report their progress. Workflows run their templates through their own
+// executers and are left alone.
+func wrapTemplates(loaded []*templates.Template) {
+	for _, template := range loaded {
+		if template.Executer == nil || template.CompiledWorkflow != nil {
+			continue
+		}
+		if _, wrapped := template.Executer.(*templateExecuter); !wrapped {
+			template.Executer = &templateExecuter{Executer: template.Executer, id: template.ID, http: len(template.RequestsHTTP) > 0}
+		}
+	}
+}
+
+func (e *templateExecuter) Execute(sc *scan.ScanContext) (bool, error) {
+	progress := scanProgressFrom(sc.Context())
+	if e.overLimit(sc) {
+		progress.skip(e.id)
+		return false, nil
+	}
+	defer progress.start(e.id)()
+	var matched bool
+	for _, in := range e.inputs(sc) {
+		bounded, done := e.bound(sc, in)
+		ok, err := e.Executer.Execute(bounded)
+		done()
+		matched = matched || ok
+		if err != nil {
+			progress.fail(e.id, err)
+			return matched, err
+		}
+	}
+	return matched, nil
+}
+
+func (e *templateExecuter) ExecuteWithResults(sc *scan.ScanContext) ([]*output.ResultEvent, error) {
+	progress := scanProgressFrom(sc.Context())
+	if e.overLimit(sc) {
+		progress.skip(e.id)
+		return nil, nil
+	}
+	defer progress.start(e.id)()
+	var results []*output.ResultEvent
+	for _, in := range e.inputs(sc) {
+		bounded, done := e.bound(sc, in)
+		found, err := e.Executer.ExecuteWithResults(bounded)
+		done()
+		results = append(results, found...)
+		if err != nil {
+			progress.fail(e.id, err)
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// overLimit reports whether the template exceeds the request limit of the
+// scan, and records it as skipped
+func (e *templateExecuter) overLimit(sc *scan.ScanContext) bool {
+	caps := templateCapsFrom(sc.Context())
+	if caps == nil || caps.maxRequests <= 0 || e.Requests() <= caps.maxRequests {
+		return false
+	}
+	caps.mu.Lock()
+	caps.skipped[e.id] = true
+	caps.mu.Unlock()
+	return true
+}
+
+// templateInput is an address and OpenAPI request or crawled URL to run a
+// template against; their zero values run it against the scan target as
+// the engine resolves it
+type templateInput struct {
+	address string
+	request *types.RequestResponse
+	url     string
+}
+
+// inputs returns the inputs to run the template against, for HTTP
+// templates on each of the scan's addresses: each request of the scan's
+// OpenAPI document, or the target and each URL its crawl found
+func (e *templateExecuter) inputs(sc *scan.ScanContext) []templateInput {
+	addresses := []string{""}
+	if found := scanAddressesFrom(sc.Context()); e.http && len(found) > 0 {
+		addresses = found
+	}
+	targets := []templateInput{{}}
+	if api := apiRequestsFrom(sc.Context()); e.http && api != nil {
+		targets = targets[:0]
+		for _, request := range api.requests {
+			targets = append(targets, templateInput{request: request})
+		}
+	}
+	if e.http {
+		for _, crawled := range crawledURLsFrom(sc.Context()) {
+			targets = append(targets, templateInput{url: crawled})
+		}
+	}
+	inputs := make([]templateInput, 0, len(targets)*len(addresses))
+	for _, target := range targets {
+		for _, address := range addresses {
+			target.address = address
+			inputs = append(inputs, target)
+		}
+	}
+	return inputs
+}
+
+// bound returns the scan context to run the template in against in,
+// bounded by the template timeout and carrying the scan's variables and
+// host override, and the function releasing it
+func (e *templateExecuter) bound(sc *scan.ScanContext, in templateInput) (*scan.ScanContext, func()) {
+	caps := templateCapsFrom(sc.Context())
+	vars := scanVariablesFrom(sc.Context())
+	override := hostOverrideFrom(sc.Context())
+	if (caps == nil || caps.timeout <= 0) && vars == nil && override == nil && in == (templateInput{}) {
+		return sc, func() {}
+	}
+
+	ctx, release := sc.Context(), func() {}
+	if caps != nil && caps.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(sc.Context(), caps.timeout)
+		release = func() {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) && sc.Context().Err() == nil {
+				caps.mu.Lock()
+				caps.timedOut[e.id] = true
+				caps.mu.Unlock()
+			}
+			cancel()
+		}
+	}
+	meta := sc.Input.MetaInput
+	if in != (templateInput{}) {
+		meta = meta.Clone()
+	}
+	if in.request != nil {
+		// Fuzzing templates send the request itself, others use its URL
+		meta.Input = in.request.URL.String()
+		meta.ReqResp = in.request.Clone()
+	}
+	if in.url != "" {
+		meta.Input = in.url
+	}
+	if in.address != "" {
+		meta.CustomIP = in.address
+		meta.Input = override.input(meta.Input)
+	}
+	input := contextargs.NewWithMetaInput(override.context(ctx), meta)
+	input.CookieJar = sc.Input.CookieJar
+	input.Merge(sc.Input.GetAll())
+	vars.apply(input)
+	bounded := scan.NewScanContext(ctx, input)
+	bounded.OnError, bounded.OnResult, bounded.OnWarning = sc.OnError, sc.OnResult, sc.OnWarning
+	return bounded, release
+}
+
+// captureEngine records the engine the options are applied to first: the
+// engine being created, not the per-scan engines of a thread-safe engine
+func captureEngine(engine **nuclei.NucleiEngine) nuclei.NucleiSDKOptions {
+	return func(e *nuclei.NucleiEngine) error {
+		if *engine == nil {
+			*engine = e
+		}
+		return nil
+	}
+}
@@ -0,0 +1,463 @@
+package scanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	nucleiconfig "github.com/projectdiscovery/nuclei/v3/pkg/catalog/config"
+	"github.com/projectdiscovery/nuclei/v3/pkg/catalog/disk"
+	"github.com/projectdiscovery/nuclei/v3/pkg/model/types/severity"
+	"github.com/projectdiscovery/nuclei/v3/pkg/templates"
+	"github.com/projectdiscovery/nuclei/v3/pkg/templates/types"
+)
+
+// templateIndexVersion is bumped when the layout of indexed templates
+// changes, so older index files are rebuilt
+const templateIndexVersion = 2
+
+// knownConfigFiles are JSON files of the official template repository that
+// nuclei never loads as templates
+var knownConfigFiles = []string{"cves.json", "contributors.json", "TEMPLATES-STATS.json"}
+
+// indexedTemplate is the metadata of a template file that scan filters
+// select on
+type indexedTemplate struct {
+	Size     int64    `json:"size"`
+	ModTime  int64    `json:"mod_time"`
+	ID       string   `json:"id,omitempty"`
+	Name     string   `json:"name,omitempty"`
+	Severity string   `json:"severity,omitempty"`
+	Protocol string   `json:"protocol,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	CVEs     []string `json:"cves,omitempty"`
+	CWEs     []string `json:"cwes,omitempty"`
+	// Invalid templates failed to parse and are left for nuclei to report
+	Invalid bool `json:"invalid,omitempty"`
+}
+
+type templateIndexFile struct {
+	Version   int                         `json:"version"`
+	Nuclei    string                      `json:"nuclei"`
+	Templates map[string]*indexedTemplate `json:"templates"`
+}
+
+// TemplateIndex persists the ID, name, severity, protocol, tags and
+// classification of every template file, keyed by path, size and modification time, so filtered
+// scans load only the template files they can match instead of parsing the
+// whole template set, including right after a restart. Files are re-indexed
+// when they change. Nuclei still applies every filter to the templates it
+// loads; the index only narrows which files it reads. An index can be
+// shared by the scanner services of the server and its tenants.
+type TemplateIndex struct {
+	file string
+
+	mu        sync.Mutex
+	loaded    bool
+	templates map[string]*indexedTemplate
+}
+
+// NewTemplateIndex creates an index stored in file. The file is read on
+// first use; a missing, unreadable or outdated file is rebuilt. An index
+// without a file is kept in memory only.
+func NewTemplateIndex(file string) *TemplateIndex {
+	return &TemplateIndex{file: file}
+}
+
+// WithTemplateIndex narrows filtered scans to the template files idx
+// selects (disabled by default)
+func WithTemplateIndex(idx *TemplateIndex) ServiceOption {
+	return func(s *scannerServiceImpl) {
+		s.index = idx
+	}
+}
+
+// load reads the index file, ignoring it when it cannot be used
+func (idx *TemplateIndex) load() {
+	idx.loaded = true
+	idx.templates = make(map[string]*indexedTemplate)
+	data, err := os.ReadFile(idx.file)
+	if err != nil {
+		return
+	}
+	var stored templateIndexFile
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return
+	}
+	if stored.Version != templateIndexVersion || stored.Nuclei != nucleiconfig.Version || stored.Templates == nil {
+		return
+	}
+	idx.templates = stored.Templates
+}
+
+// save writes the index file through a temporary file, so a crash cannot
+// leave a truncated index behind
+func (idx *TemplateIndex) save() error {
+	if idx.file == "" {
+		return nil
+	}
+	data, err := json.Marshal(templateIndexFile{
+		Version:   templateIndexVersion,
+		Nuclei:    nucleiconfig.Version,
+		Templates: idx.templates,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode template index: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(idx.file), 0755); err != nil {
+		return fmt.Errorf("failed to create template index directory: %w", err)
+	}
+	tmp := idx.file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write template index: %w", err)
+	}
+	if err := os.Rename(tmp, idx.file); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write template index: %w", err)
+	}
+	return nil
+}
+
+// refresh brings the index up to date with the template files under dirs,
+// re-indexing new and changed files with parser, and returns the paths of
+// those files and the number re-indexed
+func (idx *TemplateIndex) refresh(dirs []string, parser *templates.Parser) ([]string, int, error) {
+	if !idx.loaded {
+		idx.load()
+	}
+
+	files := make(map[string]fs.FileInfo)
+	for _, dir := range dirs {
+		if abs, err := filepath.Abs(dir); err == nil {
+			dir = abs
+		}
+		_ = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() || !isTemplateFile(path) {
+				return nil
+			}
+			if info, err := entry.Info(); err == nil {
+				files[path] = info
+			}
+			return nil
+		})
+	}
+
+	var stale []string
+	paths := make([]string, 0, len(files))
+	for path, info := range files {
+		paths = append(paths, path)
+		if t, ok := idx.templates[path]; !ok || t.Size != info.Size() || t.ModTime != info.ModTime().UnixNano() {
+			stale = append(stale, path)
+		}
+	}
+	sort.Strings(paths)
+
+	// Forget removed files under the refreshed directories; files of other
+	// directories belong to other services sharing the index
+	removed := false
+	for path := range idx.templates {
+		if _, ok := files[path]; !ok && underAny(path, dirs) {
+			delete(idx.templates, path)
+			removed = true
+		}
+	}
+
+	if len(stale) == 0 && !removed {
+		return paths, 0, nil
+	}
+	for path, t := range indexTemplates(stale, parser) {
+		info := files[path]
+		t.Size, t.ModTime = info.Size(), info.ModTime().UnixNano()
+		idx.templates[path] = t
+	}
+	return paths, len(stale), idx.save()
+}
+
+// indexTemplates parses paths in parallel and returns their metadata
+func indexTemplates(paths []string, parser *templates.Parser) map[string]*indexedTemplate {
+	catalog := disk.NewCatalog("")
+	jobs := make(chan string)
+	var mu sync.Mutex
+	indexed := make(map[string]*indexedTemplate, len(paths))
+
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range jobs {
+				t := indexTemplate(path, parser, catalog)
+				mu.Lock()
+				indexed[path] = t
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, path := range paths {
+		jobs <- path
+	}
+	close(jobs)
+	wg.Wait()
+	return indexed
+}
+
+// indexTemplate parses the template file at path with nuclei's parser
+func indexTemplate(path string, parser *templates.Parser, catalog *disk.DiskCatalog) *indexedTemplate {
+	parsed, err := parser.ParseTemplate(path, catalog)
+	if err != nil {
+		return &indexedTemplate{Invalid: true}
+	}
+	template, ok := parsed.(*templates.Template)
+	if !ok {
+		return &indexedTemplate{Invalid: true}
+	}
+	indexed := &indexedTemplate{
+		ID:       template.ID,
+		Name:     template.Info.Name,
+		Severity: template.Info.SeverityHolder.Severity.String(),
+		Protocol: template.Type().String(),
+		Tags:     template.Info.Tags.ToSlice(),
+	}
+	if classification := template.Info.Classification; classification != nil {
+		indexed.CVEs = upper(classification.CVEID.ToSlice())
+		indexed.CWEs = upper(classification.CWEID.ToSlice())
+	}
+	return indexed
+}
+
+func upper(values []string) []string {
+	for i, value := range values {
+		values[i] = strings.ToUpper(value)
+	}
+	return values
+}
+
+// isTemplateFile reports whether nuclei loads path when walking a template
+// directory
+func isTemplateFile(path string) bool {
+	if nucleiconfig.GetTemplateFormatFromExt(path) == nucleiconfig.Unknown {
+		return false
+	}
+	for _, name := range knownConfigFiles {
+		if strings.Contains(path, name) {
+			return false
+		}
+	}
+	return true
+}
+
+// underAny reports whether path is inside one of dirs
+func underAny(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		if abs, err := filepath.Abs(dir); err == nil {
+			dir = abs
+		}
+		if rel, err := filepath.Rel(dir, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// TemplateMetadata identifies a template file
+type TemplateMetadata struct {
+	ID   string
+	Name string
+	// Severity is the lower-case severity of the template's info
+	Severity string
+	Tags     []string
+	// CVEs and CWEs are the upper-case IDs of the template's classification
+	CVEs []string
+	CWEs []string
+	// Protocol is the protocol of the template's first requests, as scans
+	// are filtered by, such as http or tcp, and workflow for workflows
+	Protocol string
+	Path     string
+	ModTime  time.Time
+}
+
+// Templates brings the index up to date with the template files under dirs
+// and returns their metadata, directory by directory in the order given.
+// Files that fail to parse or have no ID are skipped, as are missing
+// directories. A nil index reads the directories into a new in-memory one.
+func (idx *TemplateIndex) Templates(dirs ...string) []TemplateMetadata {
+	if idx == nil {
+		idx = NewTemplateIndex("")
+	}
+	parser := templates.NewParser()
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	var metadata []TemplateMetadata
+	for _, dir := range dirs {
+		// The in-memory index is current even when it cannot be saved
+		paths, _, _ := idx.refresh([]string{dir}, parser)
+		for _, path := range paths {
+			t := idx.templates[path]
+			if t.Invalid || t.ID == "" {
+				continue
+			}
+			template := TemplateMetadata{
+				ID:       t.ID,
+				Name:     t.Name,
+				Severity: t.Severity,
+				Tags:     t.Tags,
+				CVEs:     t.CVEs,
+				CWEs:     t.CWEs,
+				Protocol: t.Protocol,
+				Path:     path,
+				ModTime:  time.Unix(0, t.ModTime),
+			}
+			if template.Protocol == types.InvalidProtocol.String() {
+				template.Protocol = ""
+			}
+			metadata = append(metadata, template)
+		}
+	}
+	return metadata
+}
+
+// templateSelector mirrors the nuclei filters a scan sets that the index can
+// evaluate: tags, severities, protocol types and template IDs
+type templateSelector struct {
+	tags       map[string]struct{}
+	severities map[string]struct{}
+	protocols  map[string]struct{}
+	ids        []string
+}
+
+// newTemplateSelector returns the selector of a scan's filters, or false
+// when they do not narrow the templates or nuclei would reject them
+func newTemplateSelector(severities string, protocols string, templateIDs []string, tags []string) (*templateSelector, bool) {
+	sel := &templateSelector{
+		tags:       make(map[string]struct{}),
+		severities: make(map[string]struct{}),
+		protocols:  make(map[string]struct{}),
+	}
+	for _, tag := range tags {
+		for _, value := range splitCommaTrim(tag) {
+			sel.tags[value] = struct{}{}
+		}
+	}
+	for _, id := range templateIDs {
+		sel.ids = append(sel.ids, splitCommaTrim(id)...)
+	}
+
+	parsedSeverities := severity.Severities{}
+	if err := parsedSeverities.Set(severities); err != nil {
+		return nil, false
+	}
+	for _, value := range parsedSeverities {
+		sel.severities[value.String()] = struct{}{}
+	}
+	parsedProtocols := types.ProtocolTypes{}
+	if err := parsedProtocols.Set(protocols); err != nil {
+		return nil, false
+	}
+	for _, value := range parsedProtocols {
+		sel.protocols[value.String()] = struct{}{}
+	}
+
+	narrows := len(sel.tags) > 0 || len(sel.severities) > 0 || len(sel.protocols) > 0 || len(sel.ids) > 0
+	return sel, narrows
+}
+
+// matches reports whether nuclei can select a template, following the rules
+// of its tag filter: undefined severities and unknown protocols always match
+func (sel *templateSelector) matches(t *indexedTemplate) bool {
+	if t.Invalid {
+		return true
+	}
+	if len(sel.tags) > 0 && !hasAny(sel.tags, t.Tags) {
+		return false
+	}
+	if len(sel.severities) > 0 && t.Severity != severity.Undefined.String() && t.Severity != "" {
+		if _, ok := sel.severities[t.Severity]; !ok {
+			return false
+		}
+	}
+	if len(sel.protocols) > 0 && t.Protocol != "" && t.Protocol != types.InvalidProtocol.String() {
+		if _, ok := sel.protocols[t.Protocol]; !ok {
+			return false
+		}
+	}
+	if len(sel.ids) > 0 {
+		id := strings.ToLower(t.ID)
+		for _, pattern := range sel.ids {
+			if match, err := filepath.Match(pattern, id); err == nil && match {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func hasAny(set map[string]struct{}, values []string) bool {
+	for _, value := range values {
+		if _, ok := set[value]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// splitCommaTrim splits a filter value on commas as nuclei does
+func splitCommaTrim(value string) []string {
+	if !strings.Contains(value, ",") {
+		return []string{value}
+	}
+	var values []string
+	for _, part := range strings.Split(value, ",") {
+		values = append(values, strings.TrimSpace(part))
+	}
+	return values
+}
+
+// indexedTemplates returns the template files a scan with the given filters
+// can match, or nil to load the template directories in full: without an
+// index, without narrowing filters, when the index cannot be refreshed or
+// when nothing matches, so nuclei reports the missing templates itself
+func (s *scannerServiceImpl) indexedTemplates(severities string, protocols string, templateIDs []string, tags []string) []string {
+	if s.index == nil {
+		return nil
+	}
+	sel, narrows := newTemplateSelector(severities, protocols, templateIDs, tags)
+	if !narrows {
+		return nil
+	}
+
+	parser := templates.NewParser()
+	dirs := s.templateCacheDirs()
+	if s.templates != nil {
+		// Templates parsed for the index are reused by the scan
+		parsed, _ := s.templates.parsedCache(dirs)
+		parser = templates.NewParserWithParsedCache(parsed)
+	}
+
+	s.index.mu.Lock()
+	defer s.index.mu.Unlock()
+	paths, reindexed, err := s.index.refresh(dirs, parser)
+	if reindexed > 0 {
+		s.console.Log("Indexed %d template files (%d in the template index)", reindexed, len(s.index.templates))
+	}
+	if err != nil {
+		// The in-memory index is still current
+		s.console.Log("Failed to save template index: %v", err)
+	}
+
+	var selected []string
+	for _, path := range paths {
+		if sel.matches(s.index.templates[path]) {
+			selected = append(selected, path)
+		}
+	}
+	return selected
+}
@@ -0,0 +1,216 @@
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"nuclei-mcp/pkg/cache"
+
+	nucleiconfig "github.com/projectdiscovery/nuclei/v3/pkg/catalog/config"
+	"github.com/projectdiscovery/nuclei/v3/pkg/templates"
+	"github.com/projectdiscovery/nuclei/v3/pkg/utils/stats"
+)
+
+// Reasons templates of a scan were not loaded
+const (
+	// ExcludedFilters: the template did not match the scan's severity,
+	// tags, protocols or template IDs, or is a workflow or shadowed by a
+	// template with the same ID
+	ExcludedFilters = "filters"
+	// ExcludedTags: an excluded tag or ID, such as nuclei's default
+	// exclusions or the server's exclusion rules
+	ExcludedTags    = "excluded"
+	ExcludedInvalid = "invalid"
+	// ExcludedUnsigned: an unsigned template that must be signed, such as
+	// a code template
+	ExcludedUnsigned = "unsigned"
+	// ExcludedHeadless and ExcludedCode: headless or code templates, which
+	// the server does not enable
+	ExcludedHeadless = "headless_disabled"
+	ExcludedCode     = "code_disabled"
+	// ExcludedDAST: a fuzzing template, which only loads for DAST scans
+	ExcludedDAST          = "dast_only"
+	ExcludedSelfContained = "self_contained"
+	ExcludedFile          = "file_protocol"
+)
+
+// Reasons loaded templates of a scan did not run
+const (
+	SkippedRequestLimit = "request_limit"
+	// SkippedStoppedEarly: the scan stopped at its first finding
+	SkippedStoppedEarly = "stopped_early"
+	// SkippedNotRun: nuclei did not run the template against the target,
+	// such as for a protocol the target does not serve
+	SkippedNotRun = "not_run"
+)
+
+// loaderCounters are nuclei's counters of the templates its loader leaves
+// out, by reason. They are process wide, so a scan counts their increase
+// while its engine loads.
+var loaderCounters = map[string][]string{
+	ExcludedTags:          {templates.TemplatesExcludedStats},
+	ExcludedInvalid:       {templates.SyntaxErrorStats, templates.RuntimeWarningsStats},
+	ExcludedUnsigned:      {templates.SkippedUnsignedStats, templates.SkippedRequestSignatureStats},
+	ExcludedHeadless:      {templates.ExcludedHeadlessTmplStats},
+	ExcludedCode:          {templates.ExcludedCodeTmplStats},
+	ExcludedDAST:          {templates.ExludedDastTmplStats},
+	ExcludedSelfContained: {templates.ExcludedSelfContainedStats},
+	ExcludedFile:          {templates.ExcludedFileStats},
+}
+
+// loaderStats reads nuclei's loader counters
+func loaderStats() map[string]int64 {
+	counts := make(map[string]int64, len(loaderCounters))
+	for reason, names := range loaderCounters {
+		for _, name := range names {
+			counts[reason] += stats.GetValue(name)
+		}
+	}
+	return counts
+}
+
+// exclude records the templates the engine's loader left out since before
+// was read
+func (p *scanProgress) exclude(before map[string]int64) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for reason, count := range loaderStats() {
+		if excluded := int(count - before[reason]); excluded > 0 {
+			p.excluded[reason] += excluded
+		}
+	}
+}
+
+// templateStats accounts for the templates of the scan, of the available
+// ones in its sources
+func (p *scanProgress) templateStats(available int, stoppedEarly bool) *cache.TemplateStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	templateStats := &cache.TemplateStats{
+		Available: available,
+		Executed:  len(p.done),
+		Excluded:  map[string]int{},
+		Skipped:   map[string]int{},
+	}
+	if len(p.skipped) > 0 {
+		templateStats.Skipped[SkippedRequestLimit] = len(p.skipped)
+	}
+	// The templates of scans on the preloaded engine are only known as
+	// they run
+	if p.expected == nil {
+		templateStats.Loaded = len(p.done) + len(p.skipped)
+		return templateStats
+	}
+
+	loaded := map[string]bool{}
+	for _, id := range p.expected {
+		loaded[id] = true
+	}
+	templateStats.Loaded = len(loaded)
+	if notRun := len(loaded) - len(p.done) - len(p.skipped); notRun > 0 {
+		reason := SkippedNotRun
+		if stoppedEarly {
+			reason = SkippedStoppedEarly
+		}
+		templateStats.Skipped[reason] = notRun
+	}
+
+	filtered := available - templateStats.Loaded
+	for reason, count := range p.excluded {
+		templateStats.Excluded[reason] = count
+		filtered -= count
+	}
+	if filtered > 0 {
+		templateStats.Excluded[ExcludedFilters] = filtered
+	}
+	return templateStats
+}
+
+// availableTemplates counts the template files of the scan's sources
+// before any filter applies
+func (s *scannerServiceImpl) availableTemplates(scanOpts ScanOptions) int {
+	sources := scanOpts.TemplateSources
+	if len(sources) == 0 {
+		sources = s.templateDirs
+		if defaultDir := nucleiconfig.DefaultConfig.TemplatesDirectory; defaultDir != "" {
+			sources = append([]string{defaultDir}, sources...)
+		}
+	}
+
+	files := map[string]bool{}
+	for _, source := range sources {
+		if info, err := os.Stat(source); err == nil && !info.IsDir() {
+			files[filepath.Clean(source)] = true
+			continue
+		}
+		_ = filepath.WalkDir(source, func(path string, entry fs.DirEntry, err error) error {
+			if err == nil && !entry.IsDir() && isTemplateFile(path) {
+				files[path] = true
+			}
+			return nil
+		})
+	}
+	return len(files)
+}
+
+// explainNoTemplates adds to ErrNoTemplates why the scan's templates were
+// not loaded, as a scan failing with it has no result to report them in
+func (s *scannerServiceImpl) explainNoTemplates(err error, progress *scanProgress, scanOpts ScanOptions) error {
+	if !errors.Is(err, ErrNoTemplates) {
+		return err
+	}
+	templateStats := progress.templateStats(s.availableTemplates(scanOpts), false)
+	reasons := make([]string, 0, len(templateStats.Excluded))
+	for reason, count := range templateStats.Excluded {
+		reasons = append(reasons, fmt.Sprintf("%d %s", count, reason))
+	}
+	sort.Strings(reasons)
+	if len(reasons) == 0 {
+		return fmt.Errorf("%w: none of %d available templates loaded", err, templateStats.Available)
+	}
+	return fmt.Errorf("%w: none of %d available templates loaded (excluded: %s)", err, templateStats.Available, strings.Join(reasons, ", "))
+}
+
+// mergeTemplateStats adds up the templates of sub-scans, which read the
+// same sources with different filters. Templates nuclei leaves out are left
+// out of every sub-scan that selects them, so the most any sub-scan left out
+// is kept.
+func mergeTemplateStats(total *cache.TemplateStats, templateStats *cache.TemplateStats) *cache.TemplateStats {
+	if templateStats == nil {
+		return total
+	}
+	merged := &cache.TemplateStats{Excluded: map[string]int{}, Skipped: map[string]int{}}
+	for _, t := range []*cache.TemplateStats{total, templateStats} {
+		if t == nil {
+			continue
+		}
+		merged.Available = max(merged.Available, t.Available)
+		merged.Loaded += t.Loaded
+		merged.Executed += t.Executed
+		for reason, count := range t.Excluded {
+			if reason != ExcludedFilters {
+				merged.Excluded[reason] = max(merged.Excluded[reason], count)
+			}
+		}
+		for reason, count := range t.Skipped {
+			merged.Skipped[reason] += count
+		}
+	}
+	filtered := merged.Available - merged.Loaded
+	for _, count := range merged.Excluded {
+		filtered -= count
+	}
+	if filtered > 0 {
+		merged.Excluded[ExcludedFilters] = filtered
+	}
+	return merged
+}
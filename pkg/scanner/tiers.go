@@ -0,0 +1,217 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"nuclei-mcp/pkg/cache"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+)
+
+// StrategySeverityTiers is the scan strategy running a scan as one sub-scan
+// per severity tier
+const StrategySeverityTiers = "severity_tiers"
+
+// SeverityTier is a group of severities a tiered scan runs as one sub-scan
+type SeverityTier struct {
+	Name       string
+	Severities []string
+}
+
+// DefaultSeverityTiers are the tiers of a tiered scan, most important first
+var DefaultSeverityTiers = []SeverityTier{
+	{Name: "critical/high", Severities: []string{"critical", "high"}},
+	{Name: "medium/low", Severities: []string{"medium", "low"}},
+	{Name: "info", Severities: []string{"info", "unknown"}},
+}
+
+// TierResult is the outcome of the sub-scan of one severity tier
+type TierResult struct {
+	Tier   SeverityTier
+	Result cache.ScanResult
+	Err    error
+}
+
+// SeverityTiers returns the tiers of a scan filtered by severity, a comma
+// separated list: the default tiers narrowed to the requested severities,
+// without empty tiers. An empty filter keeps every tier. A filter naming a
+// severity outside the tiers is kept as a single tier, so the scan reports
+// it as nuclei does.
+func SeverityTiers(severity string) []SeverityTier {
+	if strings.TrimSpace(severity) == "" {
+		return DefaultSeverityTiers
+	}
+	requested := map[string]bool{}
+	for _, value := range strings.Split(severity, ",") {
+		if value = strings.ToLower(strings.TrimSpace(value)); value != "" {
+			requested[value] = true
+		}
+	}
+
+	var tiers []SeverityTier
+	covered := 0
+	for _, tier := range DefaultSeverityTiers {
+		narrowed := SeverityTier{Name: tier.Name}
+		for _, value := range tier.Severities {
+			if requested[value] {
+				narrowed.Severities = append(narrowed.Severities, value)
+				covered++
+			}
+		}
+		if len(narrowed.Severities) > 0 {
+			tiers = append(tiers, narrowed)
+		}
+	}
+	if covered != len(requested) {
+		return []SeverityTier{{Name: severity, Severities: []string{severity}}}
+	}
+	return tiers
+}
+
+// TieredScan runs a thread-safe scan of target as one sub-scan per severity
+// tier. The sub-scans run in parallel; the tiers after the first wait in the
+// scan queue as background scans, so the most important tier gets a slot
+// first. onTier, when set, is called as each tier completes, so its
+// findings can be reported before the others finish. The merged result
+// lists the findings tier by tier, once each: templates without a severity
+// run in every tier. Tiers without matching templates are skipped; the scan
+// fails with the first error of a tier, or ErrNoTemplates when no tier had
+// templates.
+func TieredScan(ctx context.Context, service ScannerService, target string, severity string, protocols string, templateIDs []string, onTier func(TierResult), opts ...ScanOption) (cache.ScanResult, error) {
+	tiers := SeverityTiers(severity)
+	results := make([]TierResult, len(tiers))
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i, tier := range tiers {
+		wg.Add(1)
+		go func(i int, tier SeverityTier) {
+			defer wg.Done()
+			tierOpts := opts
+			if i > 0 {
+				tierOpts = append(append([]ScanOption(nil), opts...), WithPriority(PriorityBackground))
+			}
+			result := TierResult{Tier: tier}
+			func() {
+				defer func() {
+					if recovered := recover(); recovered != nil {
+						result.Err = panicError(recovered)
+					}
+				}()
+				result.Result, result.Err = service.ThreadSafeScan(ctx, target, strings.Join(tier.Severities, ","), protocols, templateIDs, tierOpts...)
+			}()
+			results[i] = result
+			if onTier != nil {
+				mu.Lock()
+				onTier(result)
+				mu.Unlock()
+			}
+		}(i, tier)
+	}
+	wg.Wait()
+
+	return mergeTiers(results)
+}
+
+// mergeTiers merges the results of the tiers of a scan in tier order
+func mergeTiers(tiers []TierResult) (cache.ScanResult, error) {
+	var merged cache.ScanResult
+	seen := map[string]bool{}
+	extracted := map[string]bool{}
+	interfered := map[string]bool{}
+	scanned := false
+	for _, tier := range tiers {
+		if errors.Is(tier.Err, ErrNoTemplates) {
+			continue
+		}
+		if tier.Err != nil {
+			return cache.ScanResult{}, tier.Err
+		}
+		result := tier.Result
+		if !scanned {
+			merged.Target = result.Target
+			merged.ScanTime = result.ScanTime
+			merged.CorrelationID = result.CorrelationID
+			merged.Labels = result.Labels
+			merged.HTTPVersions = result.HTTPVersions
+			if result.WAF != nil {
+				waf := *result.WAF
+				waf.Interfered = nil
+				merged.WAF = &waf
+			}
+			merged.Hosts = result.Hosts
+			merged.APIOperations = result.APIOperations
+			merged.CrawledURLs = result.CrawledURLs
+			merged.Findings = []*output.ResultEvent{}
+			scanned = true
+		}
+		if result.ScanTime.Before(merged.ScanTime) {
+			merged.ScanTime = result.ScanTime
+		}
+		// Each tier's spill file stays with its cached result
+		if result.SpillFile != "" {
+			merged.SpillFiles = append(merged.SpillFiles, result.SpillFile)
+		}
+		merged.SpillFiles = append(merged.SpillFiles, result.SpillFiles...)
+		merged.SpilledFindings += result.SpilledFindings
+		for _, finding := range result.Findings {
+			if fingerprint := cache.Fingerprint(finding); !seen[fingerprint] {
+				seen[fingerprint] = true
+				merged.Findings = append(merged.Findings, finding)
+			}
+		}
+		if merged.WAF != nil && result.WAF != nil {
+			for _, uri := range result.WAF.Interfered {
+				if !interfered[uri] {
+					interfered[uri] = true
+					merged.WAF.Interfered = append(merged.WAF.Interfered, uri)
+				}
+			}
+		}
+		for _, extraction := range result.Extractions {
+			key := extraction.Name + "|" + extraction.URL
+			if !extracted[key] {
+				extracted[key] = true
+				merged.Extractions = append(merged.Extractions, extraction)
+			}
+		}
+		merged.Stats = mergeStats(merged.Stats, result.Stats)
+		merged.StoppedEarly = merged.StoppedEarly || result.StoppedEarly
+	}
+	if !scanned {
+		return cache.ScanResult{}, ErrNoTemplates
+	}
+	return merged, nil
+}
+
+// mergeStats adds up the resources of sub-scans that ran at the same time:
+// their wall and CPU time overlap, so the longest is kept
+func mergeStats(total *cache.ScanStats, stats *cache.ScanStats) *cache.ScanStats {
+	if stats == nil {
+		return total
+	}
+	if total == nil {
+		copied := *stats
+		copied.Adjustments = append([]cache.Adjustment(nil), stats.Adjustments...)
+		copied.TimedOutTemplates = append([]string(nil), stats.TimedOutTemplates...)
+		copied.SkippedTemplates = append([]string(nil), stats.SkippedTemplates...)
+		copied.Failures = mergeFailures(nil, stats.Failures)
+		copied.Templates = mergeTemplateStats(nil, stats.Templates)
+		return &copied
+	}
+	total.WallTime = max(total.WallTime, stats.WallTime)
+	total.CPUTime = max(total.CPUTime, stats.CPUTime)
+	total.Requests += stats.Requests
+	total.Errors += stats.Errors
+	total.BytesSent += stats.BytesSent
+	total.BytesReceived += stats.BytesReceived
+	total.Adjustments = append(total.Adjustments, stats.Adjustments...)
+	total.TimedOutTemplates = append(total.TimedOutTemplates, stats.TimedOutTemplates...)
+	total.SkippedTemplates = append(total.SkippedTemplates, stats.SkippedTemplates...)
+	total.Failures = mergeFailures(total.Failures, stats.Failures)
+	total.Templates = mergeTemplateStats(total.Templates, stats.Templates)
+	return total
+}
@@ -0,0 +1,61 @@
+package scanner
+
+import (
+	"strings"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+
+	nuclei "github.com/projectdiscovery/nuclei/v3/lib"
+)
+
+// usageClock measures the wall and CPU time of a scan
+type usageClock struct {
+	start time.Time
+	cpu   time.Duration
+}
+
+// startUsage starts measuring a scan
+func startUsage() usageClock {
+	return usageClock{start: time.Now(), cpu: processCPUTime()}
+}
+
+// record adds the time since the clock started to stats, creating stats
+// when the scan has none. CPU time is that of the whole process, so it
+// includes concurrent scans.
+func (c usageClock) record(stats *cache.ScanStats) *cache.ScanStats {
+	if stats == nil {
+		stats = &cache.ScanStats{}
+	}
+	stats.WallTime = time.Since(c.start)
+	stats.CPUTime = max(processCPUTime()-c.cpu, 0)
+	return stats
+}
+
+// trafficOption has the engine pass each request and response it sends and
+// receives to its output writer, where a scan monitor counts their bytes
+func trafficOption() nuclei.NucleiSDKOptions {
+	return func(e *nuclei.NucleiEngine) error {
+		opts := e.Options()
+		opts.StoreResponse = true
+		opts.NoColor = true
+		return nil
+	}
+}
+
+// WriteStoreDebugData counts the bytes of a request or response dumped by
+// the engine. Dumps start with a header line naming the dump, such as
+// "[template] Dumped HTTP response https://example.com".
+func (m *scanMonitor) WriteStoreDebugData(host, templateID, eventType string, data string) {
+	header, body, _ := strings.Cut(data, "\n")
+	header = strings.ToLower(header)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch {
+	case strings.Contains(header, "response"):
+		m.bytesReceived += int64(len(body))
+	case strings.Contains(header, "request"):
+		m.bytesSent += int64(len(body))
+	}
+}
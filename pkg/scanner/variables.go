@@ -0,0 +1,248 @@
+package scanner
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"nuclei-mcp/pkg/secrets"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	"github.com/projectdiscovery/nuclei/v3/pkg/protocols/common/contextargs"
+)
+
+// maxVariableValue is the longest variable value, in bytes
+const maxVariableValue = 4096
+
+// variableNamePattern matches the names templates refer to variables by,
+// such as {{password}}
+var variableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,63}$`)
+
+// WithSecrets resolves the secret:// references of scan variables from
+// store. Without it, scans referencing a secret fail with
+// secrets.ErrUnknownSecret.
+func WithSecrets(store *secrets.Store) ServiceOption {
+	return func(s *scannerServiceImpl) {
+		s.secrets = store
+	}
+}
+
+// WithVariables sets the variables templates of the scan refer to, such as
+// {{username}}, like nuclei's -var flag. A value of the form
+// secret://name references a configured secret, which is read only when
+// the scan runs and is replaced by its reference in the findings.
+// Variables of earlier WithVariables options are kept unless overwritten.
+func WithVariables(vars map[string]string) ScanOption {
+	return func(o *ScanOptions) {
+		if len(vars) == 0 {
+			return
+		}
+		merged := make(map[string]string, len(o.Variables)+len(vars))
+		for name, value := range o.Variables {
+			merged[name] = value
+		}
+		for name, value := range vars {
+			merged[name] = value
+		}
+		o.Variables = merged
+	}
+}
+
+// ValidateVariables checks variable names against variableNamePattern and
+// that values fit on one line of at most maxVariableValue bytes
+func ValidateVariables(vars map[string]string) error {
+	for name, value := range vars {
+		if !variableNamePattern.MatchString(name) {
+			return fmt.Errorf("%w name %q, use letters, digits or '_'", ErrInvalidVariable, name)
+		}
+		if len(value) > maxVariableValue || strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("%w value for %s, use one line of at most %d bytes", ErrInvalidVariable, name, maxVariableValue)
+		}
+	}
+	return nil
+}
+
+// ParseVariables parses name=value pairs into scan variables
+func ParseVariables(pairs []string) (map[string]string, error) {
+	vars := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w %q, use name=value", ErrInvalidVariable, pair)
+		}
+		vars[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	if err := ValidateVariables(vars); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+// checkVariables validates the variables of a scan and that the secrets
+// they reference are configured, without reading them
+func (s *scannerServiceImpl) checkVariables(vars map[string]string) error {
+	if err := ValidateVariables(vars); err != nil {
+		return err
+	}
+	return s.checkSecrets(vars)
+}
+
+// checkSecrets checks that the secrets referenced by values are configured,
+// without reading them
+func (s *scannerServiceImpl) checkSecrets(values map[string]string) error {
+	for _, value := range values {
+		if name, ok := secrets.Reference(value); ok {
+			if err := s.secrets.Check(name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// scanVariables are the resolved template variables of a running scan
+type scanVariables struct {
+	values map[string]any
+	// redact replaces the values of the secrets used with their references
+	redact *secretRedactor
+}
+
+// basicAuthPattern matches the credentials of a Basic Authorization header
+var basicAuthPattern = regexp.MustCompile(`(?i)\bBasic\s+([A-Za-z0-9+/]+=*)`)
+
+// secretRedactor replaces secret values with their secret:// references,
+// in the forms templates send them in too: URL-encoded, JSON-escaped and
+// base64-encoded, alone or within a longer text such as the user:password
+// pair of Basic authentication
+type secretRedactor struct {
+	replacer *strings.Replacer
+}
+
+// newSecretRedactor returns a redactor of the secret values of references,
+// which maps each secret value to its reference
+func newSecretRedactor(references map[string]string) *secretRedactor {
+	var replacements []string
+	for value, reference := range references {
+		escaped, _ := json.Marshal(value)
+		for _, variant := range []string{value, url.QueryEscape(value), url.PathEscape(value), string(escaped[1 : len(escaped)-1])} {
+			replacements = append(replacements, variant, reference)
+		}
+		for _, variant := range base64Variants(value) {
+			replacements = append(replacements, variant, reference)
+		}
+	}
+	return &secretRedactor{replacer: strings.NewReplacer(replacements...)}
+}
+
+// base64Variants returns the parts of value's base64 encodings found in
+// the encoding of any text containing value, for each of the three offsets
+// value can start at within a 3-byte group. Parts shorter than 4
+// characters are left out, as they would match unrelated text.
+func base64Variants(value string) []string {
+	var variants []string
+	for offset := range 3 {
+		padded := strings.Repeat("\x00", offset) + value
+		// The first characters also encode the bytes before value, and the
+		// last group its bytes after value
+		start, end := (8*offset+5)/6, len(padded)/3*4
+		if end-start < 4 {
+			continue
+		}
+		for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding} {
+			variants = append(variants, encoding.EncodeToString([]byte(padded))[start:end])
+		}
+	}
+	return variants
+}
+
+// Replace returns text with the secrets replaced. Basic authentication
+// credentials carrying a secret are encoded again with its reference.
+func (r *secretRedactor) Replace(text string) string {
+	text = basicAuthPattern.ReplaceAllStringFunc(text, func(match string) string {
+		credentials := basicAuthPattern.FindStringSubmatch(match)[1]
+		decoded, err := base64.StdEncoding.DecodeString(credentials)
+		if err != nil {
+			return match
+		}
+		if redacted := r.replacer.Replace(string(decoded)); redacted != string(decoded) {
+			return strings.TrimSuffix(match, credentials) + base64.StdEncoding.EncodeToString([]byte(redacted))
+		}
+		return match
+	})
+	return r.replacer.Replace(text)
+}
+
+type scanVariablesKey struct{}
+
+// resolveVariables reads the secrets referenced by vars, returning nil
+// when the scan has no variables
+func (s *scannerServiceImpl) resolveVariables(vars map[string]string) (*scanVariables, error) {
+	if len(vars) == 0 {
+		return nil, nil
+	}
+	resolved := &scanVariables{values: make(map[string]any, len(vars))}
+	references := map[string]string{}
+	for name, value := range vars {
+		secret, ok := secrets.Reference(value)
+		if !ok {
+			resolved.values[name] = value
+			continue
+		}
+		secretValue, err := s.secrets.Resolve(secret)
+		if err != nil {
+			return nil, err
+		}
+		resolved.values[name] = secretValue
+		references[secretValue] = value
+	}
+	if len(references) > 0 {
+		resolved.redact = newSecretRedactor(references)
+	}
+	return resolved, nil
+}
+
+// withScanVariables returns ctx carrying vars to the templates the scan
+// runs
+func withScanVariables(ctx context.Context, vars *scanVariables) context.Context {
+	if vars == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, scanVariablesKey{}, vars)
+}
+
+// scanVariablesFrom returns the variables of the scan running ctx
+func scanVariablesFrom(ctx context.Context) *scanVariables {
+	vars, _ := ctx.Value(scanVariablesKey{}).(*scanVariables)
+	return vars
+}
+
+// apply sets the variables as arguments of the template input, which
+// nuclei evaluates templates with
+func (v *scanVariables) apply(input *contextargs.Context) {
+	if v != nil {
+		input.Merge(v.values)
+	}
+}
+
+// redacting wraps a result callback to replace the secret values in
+// findings, such as an Authorization header in the dumped request, with
+// their secret:// references, including their encoded forms
+func (v *scanVariables) redacting(callback func(*output.ResultEvent)) func(*output.ResultEvent) {
+	if v == nil || v.redact == nil {
+		return callback
+	}
+	return func(event *output.ResultEvent) {
+		event.Matched = v.redact.Replace(event.Matched)
+		event.Request = v.redact.Replace(event.Request)
+		event.Response = v.redact.Replace(event.Response)
+		event.CURLCommand = v.redact.Replace(event.CURLCommand)
+		for i, extracted := range event.ExtractedResults {
+			event.ExtractedResults[i] = v.redact.Replace(extracted)
+		}
+		callback(event)
+	}
+}
@@ -0,0 +1,277 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+)
+
+// wafTimeout bounds the WAF detection of a scan
+const wafTimeout = 15 * time.Second
+
+// wafProbeQuery is sent by the WAF detection to provoke a block: a query
+// parameter combining cross-site scripting, path traversal and SQL
+// injection patterns that rule sets block but applications ignore
+const wafProbeQuery = "nuclei_mcp_waf_probe=%3Cscript%3Ealert(1)%3C%2Fscript%3E%20..%2F..%2Fetc%2Fpasswd%20%27%20OR%201%3D1--"
+
+// wafBlockStatuses are the status codes WAFs answer blocked requests with
+var wafBlockStatuses = map[int]bool{
+	http.StatusForbidden:          true,
+	http.StatusNotAcceptable:      true,
+	419:                           true,
+	http.StatusTooManyRequests:    true,
+	http.StatusNotImplemented:     true,
+	http.StatusServiceUnavailable: true,
+}
+
+// wafSignature fingerprints a WAF by the headers, cookies and block page
+// content of its responses
+type wafSignature struct {
+	name string
+	// headers maps lowercase header names to a lowercase substring of
+	// their value; an empty substring matches any value
+	headers map[string]string
+	// cookies are lowercase prefixes of the names of cookies it sets
+	cookies []string
+	// body are lowercase substrings of its block pages
+	body []string
+}
+
+var wafSignatures = []wafSignature{
+	{
+		name:    "Cloudflare",
+		headers: map[string]string{"cf-ray": "", "server": "cloudflare"},
+		cookies: []string{"__cf_bm", "__cfduid", "cf_clearance"},
+		body:    []string{"attention required! | cloudflare", "cf-error-details"},
+	},
+	{
+		name:    "AWS WAF",
+		headers: map[string]string{"x-amzn-waf-action": ""},
+		cookies: []string{"aws-waf-token"},
+		body:    []string{"the request could not be satisfied", "request blocked."},
+	},
+	{
+		name:    "Akamai",
+		headers: map[string]string{"server": "akamaighost", "akamai-grn": ""},
+		cookies: []string{"ak_bmsc", "bm_sz"},
+		body:    []string{"you don't have permission to access", "reference&#32;&#35;"},
+	},
+	{
+		name:    "Imperva Incapsula",
+		headers: map[string]string{"x-iinfo": "", "x-cdn": "incapsula"},
+		cookies: []string{"incap_ses_", "visid_incap_"},
+		body:    []string{"incapsula incident id", "_incapsula_resource"},
+	},
+	{
+		name:    "Sucuri",
+		headers: map[string]string{"x-sucuri-id": "", "server": "sucuri"},
+		body:    []string{"sucuri website firewall"},
+	},
+	{
+		name:    "F5 BIG-IP ASM",
+		headers: map[string]string{"x-wa-info": ""},
+		cookies: []string{"ts01", "bigipserver"},
+		body:    []string{"the requested url was rejected. please consult with your administrator"},
+	},
+	{
+		name:    "Azure Front Door",
+		headers: map[string]string{"x-azure-ref": ""},
+		body:    []string{"azure application gateway", "the request is blocked."},
+	},
+	{
+		name:    "Barracuda",
+		cookies: []string{"barra_counter_session"},
+		body:    []string{"barracuda networks", "you have been blocked by the barracuda"},
+	},
+	{
+		name:    "FortiWeb",
+		cookies: []string{"fortiwafsid"},
+		body:    []string{"fortiweb", ".fgd_icon"},
+	},
+	{
+		name:    "ModSecurity",
+		headers: map[string]string{"server": "mod_security"},
+		body:    []string{"this error was generated by mod_security", "modsecurity"},
+	},
+	{
+		name: "Wordfence",
+		body: []string{"generated by wordfence", "wordfence-blocked"},
+	},
+}
+
+// WithWAFDetection fingerprints the WAF in front of the target before the
+// scan and marks the findings whose responses look like its block pages
+func WithWAFDetection() ScanOption {
+	return func(o *ScanOptions) {
+		o.DetectWAF = true
+	}
+}
+
+// wafResponse is the part of a response WAF signatures are matched against
+type wafResponse struct {
+	status  int
+	headers http.Header
+	body    string
+}
+
+// match returns the WAF whose signature matches the response and the
+// evidence it matched on, or an empty name
+func (r wafResponse) match() (string, []string) {
+	body := strings.ToLower(r.body)
+	cookies := strings.ToLower(strings.Join(r.headers.Values("Set-Cookie"), "\n"))
+	for _, signature := range wafSignatures {
+		var evidence []string
+		for header, value := range signature.headers {
+			if got := strings.ToLower(r.headers.Get(header)); got != "" && strings.Contains(got, value) {
+				evidence = append(evidence, "header "+header)
+			}
+		}
+		for _, cookie := range signature.cookies {
+			if strings.Contains(cookies, cookie) {
+				evidence = append(evidence, "cookie "+cookie)
+			}
+		}
+		for _, content := range signature.body {
+			if strings.Contains(body, content) {
+				evidence = append(evidence, "block page")
+				break
+			}
+		}
+		if len(evidence) > 0 {
+			return signature.name, evidence
+		}
+	}
+	return "", nil
+}
+
+// blocked reports whether the response looks like a WAF block page
+func (r wafResponse) blocked() bool {
+	if !wafBlockStatuses[r.status] {
+		return false
+	}
+	body := strings.ToLower(r.body)
+	for _, signature := range wafSignatures {
+		for _, content := range signature.body {
+			if strings.Contains(body, content) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// DetectWAF requests target, or https://target for a bare host, with
+// client and fingerprints the WAF in front of it. Unless passive, a second
+// request carrying attack patterns checks whether the WAF blocks them.
+// Redirects are not followed.
+func DetectWAF(ctx context.Context, client *http.Client, target string, passive bool) (*cache.WAFDetection, error) {
+	ctx, cancel := context.WithTimeout(ctx, wafTimeout)
+	defer cancel()
+
+	base := target
+	if !strings.Contains(base, "://") {
+		base = "https://" + base
+	}
+	client = withoutRedirects(client)
+
+	baseline, err := fetchWAFResponse(ctx, client, base)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect WAF: %w", err)
+	}
+	detection := &cache.WAFDetection{}
+	detection.Name, detection.Evidence = baseline.match()
+	if !passive {
+		probeURL, err := url.Parse(base)
+		if err != nil {
+			return nil, fmt.Errorf("failed to detect WAF: %w", err)
+		}
+		probeURL.RawQuery = wafProbeQuery
+		probe, err := fetchWAFResponse(ctx, client, probeURL.String())
+		if err != nil {
+			// Some WAFs drop blocked requests instead of answering them
+			detection.Blocking = true
+			detection.Evidence = append(detection.Evidence, "probe request dropped")
+		} else if probe.status != baseline.status && (wafBlockStatuses[probe.status] || probe.blocked()) {
+			detection.Blocking = true
+			detection.Evidence = append(detection.Evidence, fmt.Sprintf("probe request answered %d", probe.status))
+			if detection.Name == "" {
+				detection.Name, _ = probe.match()
+			}
+		}
+	}
+	detection.Detected = detection.Name != "" || detection.Blocking
+	return detection, nil
+}
+
+// fetchWAFResponse requests rawURL and reads the start of the response
+func fetchWAFResponse(ctx context.Context, client *http.Client, rawURL string) (wafResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return wafResponse{}, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return wafResponse{}, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	return wafResponse{status: resp.StatusCode, headers: resp.Header, body: string(body)}, nil
+}
+
+// parseWAFResponse reads the status, headers and body of a finding's dumped
+// response
+func parseWAFResponse(dump string) (wafResponse, bool) {
+	resp, err := http.ReadResponse(bufio.NewReader(strings.NewReader(dump)), nil)
+	if err != nil {
+		return wafResponse{}, false
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	return wafResponse{status: resp.StatusCode, headers: resp.Header, body: string(body)}, true
+}
+
+// detectWAF fingerprints the WAF in front of the target when the scan asked
+// for it. A failed detection is recorded in the result and does not fail
+// the scan.
+func (s *scannerServiceImpl) detectWAF(ctx context.Context, console LoggerInterface, target string, scanOpts ScanOptions) *cache.WAFDetection {
+	if !scanOpts.DetectWAF {
+		return nil
+	}
+	client := s.targetClient(scanOpts, false)
+	defer client.CloseIdleConnections()
+	detection, err := DetectWAF(ctx, client, target, scanOpts.Passive)
+	if err != nil {
+		console.Log("WAF detection for %s failed: %v", target, err)
+		return &cache.WAFDetection{Error: err.Error()}
+	}
+	if detection.Detected {
+		console.Log("WAF detected in front of %s: %s %v", target, detection.Name, detection.Evidence)
+	}
+	return detection
+}
+
+// markWAFInterference records the findings whose responses look like WAF
+// block pages, whose matches may come from the block rather than the
+// target
+func markWAFInterference(detection *cache.WAFDetection, findings []*output.ResultEvent) {
+	if detection == nil {
+		return
+	}
+	for _, finding := range findings {
+		if finding.Response == "" {
+			continue
+		}
+		if resp, ok := parseWAFResponse(finding.Response); ok && resp.blocked() {
+			detection.Interfered = append(detection.Interfered, cache.FindingURI(finding))
+		}
+	}
+}
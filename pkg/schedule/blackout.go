@@ -0,0 +1,387 @@
+package schedule
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Frequencies a blackout repeats at
+const (
+	Daily   = "daily"
+	Weekly  = "weekly"
+	Monthly = "monthly"
+	Yearly  = "yearly"
+)
+
+// Limits of blackout calendar queries
+const (
+	// maxOccurrences bounds the occurrences of a repeating blackout looked
+	// at by one query
+	maxOccurrences = 100000
+	// maxWindowSteps bounds the blackouts a window search skips past
+	maxWindowSteps = 1000
+	// upcomingBlackouts is how many upcoming blackouts a ScanWindow lists
+	upcomingBlackouts = 5
+	// upcomingHorizon is how far ahead upcoming blackouts are looked for
+	upcomingHorizon = 366 * 24 * time.Hour
+)
+
+// Blackout is a period scheduled scans are suspended in, such as a deploy
+// freeze or a holiday, optionally repeating
+type Blackout struct {
+	Name  string
+	Start time.Time
+	End   time.Time
+	// Every repeats the blackout daily, weekly, monthly or yearly; empty
+	// runs it once
+	Every string
+	// Interval repeats it every Interval periods, every period when zero
+	Interval int
+	// Count limits its occurrences; zero repeats it without limit
+	Count int
+	// Until is the last time an occurrence may start; zero repeats it
+	// without limit
+	Until time.Time
+}
+
+// Occurrence is one period of a blackout
+type Occurrence struct {
+	Name  string    `json:"name,omitempty"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// validate checks the period and repetition of the blackout
+func (b Blackout) validate() error {
+	switch {
+	case b.Start.IsZero():
+		return fmt.Errorf("start is required")
+	case !b.End.After(b.Start):
+		return fmt.Errorf("end must be after start")
+	case b.Interval < 0:
+		return fmt.Errorf("interval must not be negative, got %d", b.Interval)
+	case b.Count < 0:
+		return fmt.Errorf("count must not be negative, got %d", b.Count)
+	}
+	switch b.Every {
+	case "", Daily, Weekly, Monthly, Yearly:
+		return nil
+	}
+	return fmt.Errorf("every must be daily, weekly, monthly or yearly, got %q", b.Every)
+}
+
+// occurrence returns the n-th occurrence of the blackout. Repetitions keep
+// the wall clock times of the first across daylight saving changes.
+func (b Blackout) occurrence(n int) Occurrence {
+	step := n * max(b.Interval, 1)
+	var years, months, days int
+	switch b.Every {
+	case Daily:
+		days = step
+	case Weekly:
+		days = 7 * step
+	case Monthly:
+		months = step
+	case Yearly:
+		years = step
+	}
+	return Occurrence{Name: b.Name, Start: b.Start.AddDate(years, months, days), End: b.End.AddDate(years, months, days)}
+}
+
+// overlapping returns the occurrences of the blackout overlapping the
+// period from from to to
+func (b Blackout) overlapping(from, to time.Time) []Occurrence {
+	var occurrences []Occurrence
+	for n := 0; n < maxOccurrences; n++ {
+		if b.Count > 0 && n >= b.Count {
+			break
+		}
+		occurrence := b.occurrence(n)
+		if !occurrence.Start.Before(to) || (!b.Until.IsZero() && occurrence.Start.After(b.Until)) {
+			break
+		}
+		if occurrence.End.After(from) {
+			occurrences = append(occurrences, occurrence)
+		}
+		if b.Every == "" {
+			break
+		}
+	}
+	return occurrences
+}
+
+// ScanWindow is the next period in which scheduled scans may run
+type ScanWindow struct {
+	// At is the time the window was looked for from
+	At time.Time `json:"at"`
+	// Blackout is the blackout in force at At, if any
+	Blackout *Occurrence `json:"blackout,omitempty"`
+	// Start is when the window starts, At when scans may run now
+	Start time.Time `json:"start"`
+	// End is when the next blackout ends the window, nil when none is
+	// known within a year
+	End *time.Time `json:"end,omitempty"`
+	// Upcoming lists the next blackouts starting after At
+	Upcoming []Occurrence `json:"upcoming"`
+	// CalendarError is why the calendar file could not be read again; its
+	// previous blackouts are kept
+	CalendarError string `json:"calendar_error,omitempty"`
+}
+
+// Calendar is a blackout calendar, read from an iCal (.ics) or YAML file.
+// The file is read again when it changes, so a freeze can be added without
+// a restart.
+type Calendar struct {
+	file string
+
+	mu        sync.Mutex
+	modTime   time.Time
+	blackouts []Blackout
+	err       error
+}
+
+// LoadCalendar reads the blackout calendar in file
+func LoadCalendar(file string) (*Calendar, error) {
+	c := &Calendar{file: file}
+	if err := c.Refresh(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// NewCalendar creates a calendar of the given blackouts
+func NewCalendar(blackouts ...Blackout) *Calendar {
+	return &Calendar{blackouts: blackouts}
+}
+
+// Refresh reads the calendar file again when it changed, returning why it
+// could not be read until it can. The previous blackouts are kept meanwhile.
+func (c *Calendar) Refresh() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.refresh()
+}
+
+// refresh reads the calendar file when it changed. The caller holds c.mu.
+func (c *Calendar) refresh() error {
+	if c.file == "" {
+		return nil
+	}
+	info, err := os.Stat(c.file)
+	if err != nil {
+		c.err = fmt.Errorf("failed to read blackout calendar: %w", err)
+		return c.err
+	}
+	if info.ModTime().Equal(c.modTime) {
+		return c.err
+	}
+	c.modTime = info.ModTime()
+
+	data, err := os.ReadFile(c.file)
+	if err != nil {
+		c.err = fmt.Errorf("failed to read blackout calendar: %w", err)
+		return c.err
+	}
+	blackouts, err := ParseCalendar(data)
+	if err != nil {
+		c.err = fmt.Errorf("invalid blackout calendar %s: %w", c.file, err)
+		return c.err
+	}
+	c.blackouts, c.err = blackouts, nil
+	return nil
+}
+
+// Active returns the blackout in force at t, the one ending last when
+// several overlap
+func (c *Calendar) Active(t time.Time) (Occurrence, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.active(t, t.Add(time.Nanosecond))
+}
+
+// active returns the occurrence overlapping from to to that ends last. The
+// caller holds c.mu.
+func (c *Calendar) active(from, to time.Time) (Occurrence, bool) {
+	var found Occurrence
+	ok := false
+	for _, blackout := range c.blackouts {
+		for _, occurrence := range blackout.overlapping(from, to) {
+			if !ok || occurrence.End.After(found.End) {
+				found, ok = occurrence, true
+			}
+		}
+	}
+	return found, ok
+}
+
+// Window returns the first window from at, at least length long, in which
+// scheduled scans may run, along with the upcoming blackouts
+func (c *Calendar) Window(at time.Time, length time.Duration) (ScanWindow, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	window := ScanWindow{At: at, Start: at, Upcoming: []Occurrence{}}
+	if err := c.refresh(); err != nil {
+		window.CalendarError = err.Error()
+	}
+	if blackout, ok := c.active(at, at.Add(time.Nanosecond)); ok {
+		window.Blackout = &blackout
+	}
+
+	length = max(length, time.Nanosecond)
+	found := false
+	for step := 0; step < maxWindowSteps; step++ {
+		blackout, ok := c.active(window.Start, window.Start.Add(length))
+		if !ok {
+			found = true
+			break
+		}
+		window.Start = blackout.End
+	}
+	if !found {
+		return ScanWindow{}, fmt.Errorf("no scan window of %s found after %d blackouts", length, maxWindowSteps)
+	}
+
+	upcoming := c.upcoming(at)
+	for _, occurrence := range upcoming {
+		if !occurrence.Start.Before(window.Start) {
+			end := occurrence.Start
+			window.End = &end
+			break
+		}
+	}
+	window.Upcoming = append(window.Upcoming, upcoming[:min(len(upcoming), upcomingBlackouts)]...)
+	return window, nil
+}
+
+// upcoming returns the occurrences starting after at within the horizon,
+// by start. The caller holds c.mu.
+func (c *Calendar) upcoming(at time.Time) []Occurrence {
+	var occurrences []Occurrence
+	for _, blackout := range c.blackouts {
+		for _, occurrence := range blackout.overlapping(at, at.Add(upcomingHorizon)) {
+			if occurrence.Start.After(at) {
+				occurrences = append(occurrences, occurrence)
+			}
+		}
+	}
+	sort.Slice(occurrences, func(i, j int) bool {
+		return occurrences[i].Start.Before(occurrences[j].Start)
+	})
+	return occurrences
+}
+
+// ParseCalendar parses an iCal calendar, or a YAML calendar listing
+// blackouts
+func ParseCalendar(data []byte) ([]Blackout, error) {
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	if bytes.HasPrefix(bytes.ToUpper(trimmed), []byte("BEGIN:VCALENDAR")) {
+		return parseICal(trimmed)
+	}
+	return parseYAMLCalendar(data)
+}
+
+// yamlCalendar is a YAML blackout calendar. Times without a zone are in
+// Timezone, or the server's local time zone.
+type yamlCalendar struct {
+	Timezone  string `yaml:"timezone"`
+	Blackouts []struct {
+		Name     string `yaml:"name"`
+		Start    string `yaml:"start"`
+		End      string `yaml:"end"`
+		Every    string `yaml:"every"`
+		Interval int    `yaml:"interval"`
+		Count    int    `yaml:"count"`
+		Until    string `yaml:"until"`
+	} `yaml:"blackouts"`
+}
+
+// parseYAMLCalendar parses a YAML calendar. Dates without a time cover
+// whole days: a blackout from one date to another includes the end date.
+func parseYAMLCalendar(data []byte) ([]Blackout, error) {
+	var calendar yamlCalendar
+	if err := yaml.Unmarshal(data, &calendar); err != nil {
+		return nil, fmt.Errorf("failed to parse calendar: %w", err)
+	}
+	location := time.Local
+	if calendar.Timezone != "" {
+		var err error
+		if location, err = time.LoadLocation(calendar.Timezone); err != nil {
+			return nil, fmt.Errorf("invalid timezone %q: %w", calendar.Timezone, err)
+		}
+	}
+
+	blackouts := make([]Blackout, 0, len(calendar.Blackouts))
+	for i, entry := range calendar.Blackouts {
+		blackout := Blackout{
+			Name:     entry.Name,
+			Every:    strings.ToLower(strings.TrimSpace(entry.Every)),
+			Interval: entry.Interval,
+			Count:    entry.Count,
+		}
+		start, startDate, err := parseCalendarTime(entry.Start, location)
+		if err != nil {
+			return nil, fmt.Errorf("blackouts[%d]: invalid start: %w", i, err)
+		}
+		blackout.Start = start
+		switch {
+		case strings.TrimSpace(entry.End) != "":
+			end, endDate, err := parseCalendarTime(entry.End, location)
+			if err != nil {
+				return nil, fmt.Errorf("blackouts[%d]: invalid end: %w", i, err)
+			}
+			if endDate {
+				end = end.AddDate(0, 0, 1)
+			}
+			blackout.End = end
+		case startDate:
+			blackout.End = start.AddDate(0, 0, 1)
+		}
+		if strings.TrimSpace(entry.Until) != "" {
+			until, untilDate, err := parseCalendarTime(entry.Until, location)
+			if err != nil {
+				return nil, fmt.Errorf("blackouts[%d]: invalid until: %w", i, err)
+			}
+			if untilDate {
+				until = until.AddDate(0, 0, 1).Add(-time.Nanosecond)
+			}
+			blackout.Until = until
+		}
+		if err := blackout.validate(); err != nil {
+			return nil, fmt.Errorf("blackouts[%d]: %w", i, err)
+		}
+		blackouts = append(blackouts, blackout)
+	}
+	return blackouts, nil
+}
+
+// calendarTimeLayouts are the layouts of local times in YAML calendars
+var calendarTimeLayouts = []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02 15:04"}
+
+// parseCalendarTime parses an RFC 3339 time, a local time or a date in
+// location, reporting whether it is a date
+func parseCalendarTime(value string, location *time.Location) (time.Time, bool, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false, fmt.Errorf("missing time")
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	for _, layout := range calendarTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, location); err == nil {
+			return t, false, nil
+		}
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, location); err == nil {
+		return t, true, nil
+	}
+	return time.Time{}, false, fmt.Errorf("%q is not a date or an RFC 3339 time", value)
+}
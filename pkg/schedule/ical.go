@@ -0,0 +1,249 @@
+package schedule
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// icalProperty is a content line of an iCal calendar
+type icalProperty struct {
+	params map[string]string
+	value  string
+}
+
+// icalWeekdays maps iCal weekday codes to weekdays
+var icalWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// icalDuration matches the durations of iCal events, such as P1D or PT4H
+var icalDuration = regexp.MustCompile(`^\+?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseICal parses the events of an iCal calendar as blackouts. Cancelled
+// events and events without duration are left out. Recurrence rules repeat
+// an event at a fixed frequency; rules selecting other days than the
+// event's own are not supported, and excluded dates are ignored.
+func parseICal(data []byte) ([]Blackout, error) {
+	location := time.Local
+	var blackouts []Blackout
+	var event map[string]icalProperty
+	nested := 0
+	for _, line := range unfoldICal(data) {
+		name, property, ok := parseICalLine(line)
+		if !ok {
+			continue
+		}
+		switch {
+		case event == nil && name == "BEGIN" && strings.EqualFold(property.value, "VEVENT"):
+			event = map[string]icalProperty{}
+		case event == nil && name == "X-WR-TIMEZONE":
+			if zone, err := time.LoadLocation(property.value); err == nil {
+				location = zone
+			}
+		case event == nil:
+		case name == "BEGIN":
+			nested++
+		case name == "END" && nested > 0:
+			nested--
+		case name == "END":
+			blackout, ok, err := icalBlackout(event, location)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				blackouts = append(blackouts, blackout)
+			}
+			event = nil
+		case nested == 0:
+			if _, seen := event[name]; !seen {
+				event[name] = property
+			}
+		}
+	}
+	return blackouts, nil
+}
+
+// unfoldICal splits an iCal calendar into content lines, joining folded
+// lines
+func unfoldICal(data []byte) []string {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// parseICalLine splits a content line into its upper-cased name, its
+// parameters and its value
+func parseICalLine(line string) (string, icalProperty, bool) {
+	quoted := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			quoted = !quoted
+		} else if r == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return "", icalProperty{}, false
+	}
+	parts := strings.Split(line[:colon], ";")
+	property := icalProperty{params: map[string]string{}, value: line[colon+1:]}
+	for _, param := range parts[1:] {
+		if key, value, ok := strings.Cut(param, "="); ok {
+			property.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+		}
+	}
+	return strings.ToUpper(parts[0]), property, true
+}
+
+// icalBlackout converts an event to a blackout, reporting false for events
+// that block no time
+func icalBlackout(event map[string]icalProperty, location *time.Location) (Blackout, bool, error) {
+	name := unescapeICal(event["SUMMARY"].value)
+	if strings.EqualFold(event["STATUS"].value, "CANCELLED") {
+		return Blackout{}, false, nil
+	}
+	dtstart, ok := event["DTSTART"]
+	if !ok {
+		return Blackout{}, false, fmt.Errorf("event %q: DTSTART is required", name)
+	}
+	start, date, err := parseICalTime(dtstart, location)
+	if err != nil {
+		return Blackout{}, false, fmt.Errorf("event %q: invalid DTSTART: %w", name, err)
+	}
+
+	blackout := Blackout{Name: name, Start: start}
+	if dtend, ok := event["DTEND"]; ok {
+		if blackout.End, _, err = parseICalTime(dtend, location); err != nil {
+			return Blackout{}, false, fmt.Errorf("event %q: invalid DTEND: %w", name, err)
+		}
+	} else if duration, ok := event["DURATION"]; ok {
+		if blackout.End, err = addICalDuration(start, duration.value); err != nil {
+			return Blackout{}, false, fmt.Errorf("event %q: invalid DURATION: %w", name, err)
+		}
+	} else if date {
+		blackout.End = start.AddDate(0, 0, 1)
+	}
+	if !blackout.End.After(blackout.Start) {
+		return Blackout{}, false, nil
+	}
+
+	if rrule, ok := event["RRULE"]; ok {
+		if err := applyRRule(&blackout, rrule.value, location); err != nil {
+			return Blackout{}, false, fmt.Errorf("event %q: %w", name, err)
+		}
+	}
+	return blackout, true, nil
+}
+
+// parseICalTime parses a DATE or DATE-TIME value, in UTC, in its TZID or
+// floating in location, reporting whether it is a date
+func parseICalTime(property icalProperty, location *time.Location) (time.Time, bool, error) {
+	value := strings.TrimSpace(property.value)
+	if tzid := property.params["TZID"]; tzid != "" {
+		zone, err := time.LoadLocation(tzid)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("unknown time zone %q", tzid)
+		}
+		location = zone
+	}
+	if strings.EqualFold(property.params["VALUE"], "DATE") || len(value) == len("20060102") {
+		t, err := time.ParseInLocation("20060102", value, location)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, false, err
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, location)
+	return t, false, err
+}
+
+// addICalDuration adds an iCal duration such as P1D or PT4H30M to t
+func addICalDuration(t time.Time, duration string) (time.Time, error) {
+	match := icalDuration.FindStringSubmatch(strings.TrimSpace(duration))
+	if match == nil {
+		return time.Time{}, fmt.Errorf("%q is not a duration", duration)
+	}
+	n := make([]int, len(match))
+	for i, value := range match[1:] {
+		n[i+1], _ = strconv.Atoi(value)
+	}
+	return t.AddDate(0, 0, 7*n[1]+n[2]).Add(time.Duration(n[3])*time.Hour + time.Duration(n[4])*time.Minute + time.Duration(n[5])*time.Second), nil
+}
+
+// applyRRule repeats the blackout by an iCal recurrence rule
+func applyRRule(blackout *Blackout, rule string, location *time.Location) error {
+	for _, part := range strings.Split(rule, ";") {
+		key, value, _ := strings.Cut(part, "=")
+		key, value = strings.ToUpper(strings.TrimSpace(key)), strings.TrimSpace(value)
+		var err error
+		switch key {
+		case "":
+		case "FREQ":
+			switch strings.ToUpper(value) {
+			case "DAILY":
+				blackout.Every = Daily
+			case "WEEKLY":
+				blackout.Every = Weekly
+			case "MONTHLY":
+				blackout.Every = Monthly
+			case "YEARLY":
+				blackout.Every = Yearly
+			default:
+				return fmt.Errorf("unsupported recurrence frequency %s", value)
+			}
+		case "INTERVAL":
+			blackout.Interval, err = strconv.Atoi(value)
+		case "COUNT":
+			blackout.Count, err = strconv.Atoi(value)
+		case "UNTIL":
+			blackout.Until, _, err = parseICalTime(icalProperty{value: value}, location)
+		case "WKST":
+		// Rules naming the event's own day, month or weekday repeat it
+		// like the frequency alone
+		case "BYDAY":
+			if weekday, ok := icalWeekdays[strings.ToUpper(value)]; !ok || weekday != blackout.Start.Weekday() {
+				return fmt.Errorf("unsupported recurrence rule part BYDAY=%s", value)
+			}
+		case "BYMONTHDAY":
+			if value != strconv.Itoa(blackout.Start.Day()) {
+				return fmt.Errorf("unsupported recurrence rule part BYMONTHDAY=%s", value)
+			}
+		case "BYMONTH":
+			if value != strconv.Itoa(int(blackout.Start.Month())) {
+				return fmt.Errorf("unsupported recurrence rule part BYMONTH=%s", value)
+			}
+		default:
+			return fmt.Errorf("unsupported recurrence rule part %s", key)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid recurrence rule part %s: %w", part, err)
+		}
+	}
+	if blackout.Every == "" {
+		return fmt.Errorf("recurrence rule has no FREQ")
+	}
+	return blackout.validate()
+}
+
+// unescapeICal unescapes an iCal text value
+func unescapeICal(value string) string {
+	return strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(value)
+}
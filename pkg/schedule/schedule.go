@@ -0,0 +1,228 @@
+package schedule
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"nuclei-mcp/pkg/scanner"
+)
+
+// Defaults of the scheduler
+const (
+	// DefaultInterval is how often the scheduler looks for due scans
+	DefaultInterval = time.Minute
+	// DefaultWarmMargin is how long before its cached result expires a
+	// cache-warming scan runs
+	DefaultWarmMargin = 5 * time.Minute
+)
+
+// Scan is a scan run on a schedule
+type Scan struct {
+	Target    string
+	Severity  string
+	Protocols string
+	// Options are the scan options it runs with, such as its tags; they are
+	// part of the cache key of its result
+	Options []scanner.ScanOption
+	// Every is the time between runs
+	Every time.Duration
+	// WarmCache runs the scan just before its cached result expires instead,
+	// so scans of the target with the same options hit the cache
+	WarmCache bool
+	// SkipUnchanged keeps the previous result instead of scanning when the
+	// target's response did not change since
+	SkipUnchanged bool
+}
+
+// Scheduler runs scheduled scans in the background when they are due
+type Scheduler struct {
+	service  scanner.ScannerService
+	console  scanner.LoggerInterface
+	scans    []Scan
+	interval time.Duration
+	// expiry and margin time cache-warming scans
+	expiry time.Duration
+	margin time.Duration
+	// blackouts suspend scheduled scans
+	blackouts *Calendar
+
+	mu        sync.Mutex
+	lastRun   []time.Time
+	suspended bool
+	// calendarErr is the calendar error last logged
+	calendarErr string
+}
+
+// Option configures a Scheduler
+type Option func(*Scheduler)
+
+// WithInterval sets how often due scans are looked for
+func WithInterval(interval time.Duration) Option {
+	return func(s *Scheduler) {
+		if interval > 0 {
+			s.interval = interval
+		}
+	}
+}
+
+// WithCacheWarming times cache-warming scans to run margin before their
+// results expire from a cache keeping them for expiry
+func WithCacheWarming(expiry time.Duration, margin time.Duration) Option {
+	return func(s *Scheduler) {
+		s.expiry = expiry
+		if margin > 0 {
+			s.margin = margin
+		}
+	}
+}
+
+// WithBlackouts suspends scheduled scans during the blackouts of calendar.
+// Scans that fell due during a blackout run when it ends.
+func WithBlackouts(calendar *Calendar) Option {
+	return func(s *Scheduler) {
+		s.blackouts = calendar
+	}
+}
+
+// NewScheduler creates a scheduler running scans with service
+func NewScheduler(service scanner.ScannerService, console scanner.LoggerInterface, scans []Scan, opts ...Option) *Scheduler {
+	s := &Scheduler{
+		service:  service,
+		console:  console,
+		scans:    scans,
+		interval: DefaultInterval,
+		margin:   DefaultWarmMargin,
+		lastRun:  make([]time.Time, len(scans)),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Add schedules more scans, such as those restored from a workspace
+// archive. They are due at the next check.
+func (s *Scheduler) Add(scans ...Scan) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scans = append(s.scans, scans...)
+	s.lastRun = append(s.lastRun, make([]time.Time, len(scans))...)
+}
+
+// Run runs due scans until ctx is done
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		s.RunDue(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue runs the scans due at now, one at a time, and returns them. No
+// scan starts during a blackout, including one that begins while earlier
+// scans of the same call run.
+func (s *Scheduler) RunDue(ctx context.Context, now time.Time) []Scan {
+	if s.blackedOut(now) {
+		return nil
+	}
+	s.mu.Lock()
+	scans := append([]Scan(nil), s.scans...)
+	s.mu.Unlock()
+
+	started := time.Now()
+	var ran []Scan
+	for i, scan := range scans {
+		if ctx.Err() != nil {
+			break
+		}
+		s.mu.Lock()
+		lastRun := s.lastRun[i]
+		s.mu.Unlock()
+		if !lastRun.IsZero() && now.Sub(lastRun) < s.period(scan) {
+			continue
+		}
+		// Earlier scans may have run into a blackout
+		if len(ran) > 0 && s.blackedOut(now.Add(time.Since(started))) {
+			break
+		}
+
+		opts := append(append([]scanner.ScanOption(nil), scan.Options...), scanner.WithPriority(scanner.PriorityBackground), scanner.WithFreshResult())
+		if scan.SkipUnchanged {
+			opts = append(opts, scanner.WithSkipUnchanged())
+		}
+		result, err := s.service.ThreadSafeScan(ctx, scan.Target, scan.Severity, scan.Protocols, nil, opts...)
+		// A failed scan waits for its next period like a completed one, so
+		// an unreachable target is not scanned on every check
+		lastRun = now
+		if err != nil {
+			s.console.Log("Scheduled scan of %s failed: %v", scan.Target, err)
+		} else {
+			if !result.ScanTime.IsZero() {
+				lastRun = result.ScanTime
+			}
+			if result.UnchangedSince != nil {
+				s.console.Log("Scheduled scan of %s skipped, target unchanged", scan.Target)
+			} else {
+				s.console.Log("Scheduled scan of %s completed with %d findings", scan.Target, len(result.Findings))
+			}
+		}
+		s.mu.Lock()
+		s.lastRun[i] = lastRun
+		s.mu.Unlock()
+		ran = append(ran, scan)
+	}
+	return ran
+}
+
+// period returns the time between runs of scan. Cache-warming scans run
+// the margin before their result expires, or halfway through the expiry of
+// caches too short for the margin.
+func (s *Scheduler) period(scan Scan) time.Duration {
+	if !scan.WarmCache || s.expiry <= 0 {
+		return scan.Every
+	}
+	if s.margin < s.expiry {
+		return s.expiry - s.margin
+	}
+	return s.expiry / 2
+}
+
+// blackedOut reports whether a blackout is in force at now, logging when
+// scheduled scans are suspended and resumed
+func (s *Scheduler) blackedOut(now time.Time) bool {
+	if s.blackouts == nil {
+		return false
+	}
+	err := s.blackouts.Refresh()
+	blackout, active := s.blackouts.Active(now)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	calendarErr := ""
+	if err != nil {
+		calendarErr = err.Error()
+	}
+	if calendarErr != s.calendarErr && err != nil {
+		s.console.Log("Keeping the previous blackouts: %v", err)
+	}
+	s.calendarErr = calendarErr
+
+	switch {
+	case active && !s.suspended:
+		name := blackout.Name
+		if name == "" {
+			name = "unnamed"
+		}
+		s.console.Log("Scheduled scans suspended until %s for blackout %s", blackout.End.Format(time.RFC3339), name)
+	case !active && s.suspended:
+		s.console.Log("Scheduled scans resumed after blackout")
+	}
+	s.suspended = active
+	return active
+}
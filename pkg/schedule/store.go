@@ -0,0 +1,137 @@
+package schedule
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Definition is a scheduled scan as configured, before its arguments are
+// resolved against the scan profile. Scheduled scans are backed up and
+// restored in this form.
+type Definition struct {
+	Target        string        `json:"target"`
+	Severity      string        `json:"severity,omitempty"`
+	Protocols     string        `json:"protocols,omitempty"`
+	Tags          []string      `json:"tags,omitempty"`
+	Every         time.Duration `json:"every,omitempty"`
+	WarmCache     bool          `json:"warm_cache,omitempty"`
+	SkipUnchanged bool          `json:"skip_unchanged,omitempty"`
+}
+
+// Equal reports whether d and other schedule the same scan
+func (d Definition) Equal(other Definition) bool {
+	return reflect.DeepEqual(d, other)
+}
+
+func (d Definition) validate() error {
+	switch {
+	case strings.TrimSpace(d.Target) == "":
+		return fmt.Errorf("scheduled scan target is required")
+	case !d.WarmCache && d.Every <= 0:
+		return fmt.Errorf("scheduled scan of %s needs a positive every unless warm_cache is set", d.Target)
+	}
+	return nil
+}
+
+// Store keeps the scheduled scans restored from workspace archives in a
+// JSON file, so they keep running after a restart alongside the scans of
+// the configuration
+type Store struct {
+	path string
+
+	mu          sync.RWMutex
+	definitions []Definition
+}
+
+// OpenStore loads the scheduled scans stored at path. A missing file holds
+// no scans.
+func OpenStore(path string) (*Store, error) {
+	s := &Store{path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scheduled scans: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.definitions); err != nil {
+		return nil, fmt.Errorf("failed to parse scheduled scans %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// List returns the stored scans
+func (s *Store) List() []Definition {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Definition(nil), s.definitions...)
+}
+
+// Add stores the scans that are neither stored nor in existing, and returns
+// them. The scans are validated as a whole before any is stored.
+func (s *Store) Add(definitions []Definition, existing []Definition) ([]Definition, error) {
+	for _, definition := range definitions {
+		if err := definition.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	known := append(append([]Definition(nil), existing...), s.definitions...)
+	var added []Definition
+	for _, definition := range definitions {
+		if contains(known, definition) {
+			continue
+		}
+		known = append(known, definition)
+		added = append(added, definition)
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+
+	stored := append(append([]Definition(nil), s.definitions...), added...)
+	if err := s.save(stored); err != nil {
+		return nil, err
+	}
+	s.definitions = stored
+	return added, nil
+}
+
+func contains(definitions []Definition, definition Definition) bool {
+	for _, known := range definitions {
+		if known.Equal(definition) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Store) save(definitions []Definition) error {
+	data, err := json.MarshalIndent(definitions, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode scheduled scans: %w", err)
+	}
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create scheduled scans directory: %w", err)
+		}
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write scheduled scans: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to write scheduled scans: %w", err)
+	}
+	return nil
+}
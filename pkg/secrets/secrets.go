@@ -0,0 +1,102 @@
+// Package secrets resolves named secrets configured on the server, so scans
+// can reference credentials (secret://db-admin) without them passing
+// through the MCP client
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Scheme prefixes the values of scan variables that reference a secret
+const Scheme = "secret://"
+
+// ErrUnknownSecret is returned for a reference to a secret that is not
+// configured
+var ErrUnknownSecret = errors.New("unknown secret")
+
+// Source is where the value of a secret is read from when a scan uses it
+type Source struct {
+	// Env names the environment variable holding the secret
+	Env string
+	// File is read when Env is unset, such as a secret written by a vault
+	// agent
+	File string
+}
+
+// Store resolves secrets by name. A nil Store has no secrets.
+type Store struct {
+	sources map[string]Source
+}
+
+// NewStore creates a store of the given secrets. Names are case
+// insensitive.
+func NewStore(sources map[string]Source) *Store {
+	s := &Store{sources: make(map[string]Source, len(sources))}
+	for name, source := range sources {
+		s.sources[strings.ToLower(name)] = source
+	}
+	return s
+}
+
+// Reference returns the name of the secret value references, if it is a
+// secret:// reference
+func Reference(value string) (string, bool) {
+	if !strings.HasPrefix(value, Scheme) {
+		return "", false
+	}
+	return strings.ToLower(strings.TrimPrefix(value, Scheme)), true
+}
+
+// Names returns the names of the configured secrets, sorted
+func (s *Store) Names() []string {
+	if s == nil {
+		return nil
+	}
+	names := make([]string, 0, len(s.sources))
+	for name := range s.sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Check fails with ErrUnknownSecret unless the secret name is configured
+func (s *Store) Check(name string) error {
+	if s != nil {
+		if _, ok := s.sources[strings.ToLower(name)]; ok {
+			return nil
+		}
+	}
+	if names := s.Names(); len(names) > 0 {
+		return fmt.Errorf("%w %s, configured secrets: %s", ErrUnknownSecret, name, strings.Join(names, ", "))
+	}
+	return fmt.Errorf("%w %s, no secrets are configured", ErrUnknownSecret, name)
+}
+
+// Resolve reads the value of the secret name from its source. Trailing
+// newlines of secret files are dropped.
+func (s *Store) Resolve(name string) (string, error) {
+	if err := s.Check(name); err != nil {
+		return "", err
+	}
+	source := s.sources[strings.ToLower(name)]
+	if source.Env != "" {
+		if value := os.Getenv(source.Env); value != "" {
+			return value, nil
+		}
+	}
+	if source.File != "" {
+		data, err := os.ReadFile(source.File)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret %s: %w", name, err)
+		}
+		if value := strings.TrimRight(string(data), "\r\n"); value != "" {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("secret %s is not set in the environment or a secret file", name)
+}
@@ -0,0 +1,172 @@
+package selftest
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/policy"
+	"nuclei-mcp/pkg/sandbox"
+	"nuclei-mcp/pkg/scanner"
+)
+
+// suite holds the self-test templates
+//
+//go:embed templates/*.yaml
+var suite embed.FS
+
+// Version is the banner version served and expected to be extracted
+const Version = "1.0"
+
+// responses make the self-test server look like a small, misconfigured web
+// application: a banner, an exposed git config and a protected admin panel
+var responses = []sandbox.Response{
+	{Method: http.MethodGet, Path: "/", Status: http.StatusOK,
+		Headers: map[string]string{"Server": "nuclei-mcp-selftest/" + Version, "Content-Type": "text/html"},
+		Body:    "<html><head><title>nuclei-mcp self-test</title></head><body>It works</body></html>"},
+	{Method: http.MethodGet, Path: "/.git/config", Status: http.StatusOK,
+		Headers: map[string]string{"Content-Type": "text/plain"},
+		Body:    "[core]\n\trepositoryformatversion = 0\n\tbare = false\n"},
+	{Method: http.MethodGet, Path: "/admin", Status: http.StatusUnauthorized,
+		Headers: map[string]string{"WWW-Authenticate": `Basic realm="admin"`},
+		Body:    "Unauthorized"},
+}
+
+// expectation is the outcome a suite template must produce
+type expectation struct {
+	templateID string
+	match      bool
+	extracted  string
+}
+
+var expectations = []expectation{
+	{templateID: "selftest-status", match: true},
+	{templateID: "selftest-banner", match: true, extracted: Version},
+	{templateID: "selftest-git-config", match: true},
+	{templateID: "selftest-admin-panel", match: false},
+}
+
+// Check is the outcome of one self-test step
+type Check struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Detail string `json:"detail"`
+}
+
+// Result is the outcome of a self-test run
+type Result struct {
+	Passed   bool    `json:"passed"`
+	Target   string  `json:"target"`
+	Duration string  `json:"duration"`
+	Checks   []Check `json:"checks"`
+}
+
+// Run scans an in-process test server with the bundled template suite
+// through service, checking that the engine runs, templates match and
+// extract as expected without false positives, and the result is cached
+func Run(ctx context.Context, service scanner.ScannerService) (Result, error) {
+	dir, err := os.MkdirTemp("", "nuclei-mcp-selftest-")
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to create self-test directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	templates, err := fs.Glob(suite, "templates/*.yaml")
+	if err != nil {
+		return Result{}, err
+	}
+	for _, name := range templates {
+		content, err := suite.ReadFile(name)
+		if err != nil {
+			return Result{}, err
+		}
+		if err := os.WriteFile(filepath.Join(dir, filepath.Base(name)), content, 0644); err != nil {
+			return Result{}, fmt.Errorf("failed to write self-test template: %w", err)
+		}
+	}
+
+	srv := sandbox.NewServer(responses)
+	defer srv.Close()
+
+	result := Result{Target: srv.URL(), Passed: true}
+	start := time.Now()
+	// The test server runs in-process, so the scan targets the server's
+	// own host on purpose
+	scan, err := service.ThreadSafeScan(ctx, srv.URL(), "", "http", nil,
+		scanner.WithTemplateSources(dir),
+		scanner.WithPassive(false),
+		scanner.WithSelfTargetAllowed("self_test"),
+	)
+	result.Duration = time.Since(start).Round(time.Millisecond).String()
+
+	if err != nil {
+		detail := err.Error()
+		if errors.Is(err, policy.ErrDenied) {
+			detail += " (the self-test server listens on a loopback address, which policy.egress.deny_private blocks)"
+		}
+		result.add(Check{Name: "scan", Detail: detail})
+		return result, nil
+	}
+	result.add(Check{Name: "scan", Passed: true, Detail: fmt.Sprintf("%d templates ran, %d findings", len(templates), len(scan.Findings))})
+
+	for _, expected := range expectations {
+		result.add(checkTemplate(expected, scan))
+	}
+
+	requests := srv.Requests()
+	result.add(Check{Name: "requests", Passed: len(requests) > 0, Detail: fmt.Sprintf("self-test server received %d requests", len(requests))})
+
+	cached := slices.ContainsFunc(service.GetAll(), func(cached cache.ScanResult) bool {
+		return cached.Target == srv.URL()
+	})
+	cacheCheck := Check{Name: "cache", Passed: cached, Detail: "scan result stored in the result cache"}
+	if !cached {
+		cacheCheck.Detail = "scan result missing from the result cache"
+	}
+	result.add(cacheCheck)
+
+	return result, nil
+}
+
+// add records a check, failing the run when the check failed
+func (r *Result) add(check Check) {
+	r.Checks = append(r.Checks, check)
+	r.Passed = r.Passed && check.Passed
+}
+
+func checkTemplate(expected expectation, scan cache.ScanResult) Check {
+	check := Check{Name: "template " + expected.templateID}
+
+	var extracted []string
+	matched := false
+	for _, finding := range scan.Findings {
+		if finding.TemplateID == expected.templateID {
+			matched = true
+			extracted = append(extracted, finding.ExtractedResults...)
+		}
+	}
+
+	switch {
+	case !expected.match && matched:
+		check.Detail = "matched, but the self-test server is not vulnerable to it (false positive)"
+	case !expected.match:
+		check.Passed, check.Detail = true, "did not match, as expected"
+	case !matched:
+		check.Detail = "expected a match, got none"
+	case expected.extracted != "" && !slices.Contains(extracted, expected.extracted):
+		check.Detail = fmt.Sprintf("matched, but extracted %q instead of %q", extracted, expected.extracted)
+	case expected.extracted != "":
+		check.Passed, check.Detail = true, fmt.Sprintf("matched and extracted %q", expected.extracted)
+	default:
+		check.Passed, check.Detail = true, "matched"
+	}
+	return check
+}
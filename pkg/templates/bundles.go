@@ -0,0 +1,404 @@
+package templates
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	nucleiconfig "github.com/projectdiscovery/nuclei/v3/pkg/catalog/config"
+)
+
+const ociScheme = "oci://"
+
+// ociTimeout bounds each request to a registry, so a stalled registry fails
+// the bundle instead of hanging startup
+const ociTimeout = 2 * time.Minute
+
+var namespacePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// Bundle is an offline template bundle loaded into its own namespace
+type Bundle struct {
+	// Name is the namespace directory the bundle is extracted into
+	Name string
+	// Source is a local .tar, .tar.gz/.tgz or .zip path, or an OCI artifact
+	// reference of the form oci://registry/repository:tag
+	Source string
+}
+
+// BundleResult summarizes a loaded bundle
+type BundleResult struct {
+	Name       string   `json:"name"`
+	Dir        string   `json:"dir"`
+	Templates  int      `json:"templates"`
+	Invalid    []string `json:"invalid,omitempty"`
+	Unverified []string `json:"unverified,omitempty"`
+}
+
+// LoadBundle validates the bundle and extracts it into root/<name>,
+// replacing any previous contents of that namespace. When a verifier is given
+// every template signature is checked; if verification is enforced, a bundle
+// with unsigned or modified templates is rejected.
+func LoadBundle(ctx context.Context, bundle Bundle, root string, verifier *Verifier) (BundleResult, error) {
+	if !namespacePattern.MatchString(bundle.Name) {
+		return BundleResult{}, fmt.Errorf("invalid bundle name %q", bundle.Name)
+	}
+
+	data, format, err := readBundle(ctx, bundle.Source)
+	if err != nil {
+		return BundleResult{}, err
+	}
+
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return BundleResult{}, fmt.Errorf("failed to create bundles directory: %w", err)
+	}
+	staging, err := os.MkdirTemp(root, "."+bundle.Name+"-")
+	if err != nil {
+		return BundleResult{}, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	switch format {
+	case "zip":
+		err = extractZip(data, staging)
+	case "tar.gz":
+		err = extractTarGz(bytes.NewReader(data), staging)
+	default:
+		err = extractTar(bytes.NewReader(data), staging)
+	}
+	if err != nil {
+		return BundleResult{}, fmt.Errorf("failed to extract bundle %s: %w", bundle.Name, err)
+	}
+
+	result := BundleResult{Name: bundle.Name}
+	err = filepath.Walk(staging, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext != ".yaml" && ext != ".yml" && ext != ".json" {
+			return nil
+		}
+		rel, _ := filepath.Rel(staging, path)
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		if id, err := nucleiconfig.GetTemplateIDFromReader(file, path); err != nil || id == "" {
+			result.Invalid = append(result.Invalid, filepath.ToSlash(rel))
+			return nil
+		}
+		if verifier != nil {
+			if err := verifier.VerifyFile(path); err != nil {
+				result.Unverified = append(result.Unverified, filepath.ToSlash(rel))
+			}
+		}
+		result.Templates++
+		return nil
+	})
+	if err != nil {
+		return BundleResult{}, fmt.Errorf("failed to validate bundle %s: %w", bundle.Name, err)
+	}
+	if result.Templates == 0 {
+		return BundleResult{}, fmt.Errorf("bundle %s contains no valid templates", bundle.Name)
+	}
+	if verifier != nil && verifier.Enforced() && len(result.Unverified) > 0 {
+		return BundleResult{}, fmt.Errorf("bundle %s has templates failing %s signature verification: %s",
+			bundle.Name, verifier.Mode(), strings.Join(result.Unverified, ", "))
+	}
+
+	result.Dir = filepath.Join(root, bundle.Name)
+	if err := os.RemoveAll(result.Dir); err != nil {
+		return BundleResult{}, fmt.Errorf("failed to replace bundle %s: %w", bundle.Name, err)
+	}
+	if err := os.Rename(staging, result.Dir); err != nil {
+		return BundleResult{}, fmt.Errorf("failed to install bundle %s: %w", bundle.Name, err)
+	}
+
+	return result, nil
+}
+
+// readBundle loads the raw bundle archive and reports its format
+func readBundle(ctx context.Context, source string) ([]byte, string, error) {
+	if strings.HasPrefix(source, ociScheme) {
+		data, err := pullOCIArtifact(ctx, strings.TrimPrefix(source, ociScheme))
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to pull %s: %w", source, err)
+		}
+		return data, detectFormat(data), nil
+	}
+
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read bundle: %w", err)
+	}
+	return data, detectFormat(data), nil
+}
+
+func detectFormat(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		return "zip"
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		return "tar.gz"
+	default:
+		return "tar"
+	}
+}
+
+// safeJoin joins an archive entry name to dir, rejecting entries that would
+// escape it
+func safeJoin(dir, name string) (string, error) {
+	target := filepath.Join(dir, filepath.FromSlash(name))
+	if target != filepath.Clean(dir) && !strings.HasPrefix(target, filepath.Clean(dir)+string(os.PathSeparator)) {
+		return "", fmt.Errorf("invalid path in archive: %s", name)
+	}
+	return target, nil
+}
+
+func extractTarGz(r io.Reader, dir string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	return extractTar(gz, dir)
+}
+
+func extractTar(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		target, err := safeJoin(dir, header.Name)
+		if err != nil {
+			return err
+		}
+		if err := writeBundleFile(target, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func extractZip(data []byte, dir string) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		target, err := safeJoin(dir, f.Name)
+		if err != nil {
+			return err
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = writeBundleFile(target, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeBundleFile(path string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(file, r)
+	return err
+}
+
+// pullOCIArtifact downloads the first layer of an OCI artifact using the
+// registry v2 HTTP API, handling anonymous bearer token challenges, and
+// checks it against its digest in the manifest
+func pullOCIArtifact(ctx context.Context, reference string) ([]byte, error) {
+	registry, repository, tag, err := parseOCIReference(reference)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &ociClient{http: &http.Client{Timeout: ociTimeout}, scheme: "https"}
+	if strings.HasPrefix(registry, "localhost") || strings.HasPrefix(registry, "127.0.0.1") {
+		client.scheme = "http"
+	}
+
+	manifestURL := fmt.Sprintf("%s://%s/v2/%s/manifests/%s", client.scheme, registry, repository, tag)
+	body, err := client.get(ctx, manifestURL, "application/vnd.oci.image.manifest.v1+json")
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest struct {
+		Layers []struct {
+			Digest string `json:"digest"`
+		} `json:"layers"`
+	}
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if len(manifest.Layers) == 0 {
+		return nil, fmt.Errorf("artifact has no layers")
+	}
+
+	digest := manifest.Layers[0].Digest
+	expected, ok := strings.CutPrefix(digest, "sha256:")
+	if !ok {
+		return nil, fmt.Errorf("unsupported layer digest %q, expected sha256", digest)
+	}
+	blobURL := fmt.Sprintf("%s://%s/v2/%s/blobs/%s", client.scheme, registry, repository, digest)
+	layer, err := client.get(ctx, blobURL, "")
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(layer)
+	if hex.EncodeToString(sum[:]) != strings.ToLower(expected) {
+		return nil, fmt.Errorf("layer does not match its digest %s", digest)
+	}
+	return layer, nil
+}
+
+func parseOCIReference(reference string) (registry, repository, tag string, err error) {
+	slash := strings.Index(reference, "/")
+	if slash <= 0 {
+		return "", "", "", fmt.Errorf("invalid OCI reference %q, expected registry/repository:tag", reference)
+	}
+	registry, rest := reference[:slash], reference[slash+1:]
+
+	tag = "latest"
+	if at := strings.LastIndex(rest, "@"); at > 0 {
+		rest, tag = rest[:at], rest[at+1:]
+	} else if colon := strings.LastIndex(rest, ":"); colon > 0 {
+		rest, tag = rest[:colon], rest[colon+1:]
+	}
+	if rest == "" {
+		return "", "", "", fmt.Errorf("invalid OCI reference %q, missing repository", reference)
+	}
+	return registry, rest, tag, nil
+}
+
+type ociClient struct {
+	http   *http.Client
+	scheme string
+	token  string
+}
+
+func (c *ociClient) get(ctx context.Context, url, accept string) ([]byte, error) {
+	resp, err := c.do(ctx, url, accept)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && c.token == "" {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		if err := c.authenticate(ctx, challenge); err != nil {
+			return nil, err
+		}
+		if resp, err = c.do(ctx, url, accept); err != nil {
+			return nil, err
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s from %s", resp.Status, url)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (c *ociClient) do(ctx context.Context, url, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return c.http.Do(req)
+}
+
+// authenticate obtains an anonymous bearer token from the realm named in a
+// WWW-Authenticate challenge
+func (c *ociClient) authenticate(ctx context.Context, challenge string) error {
+	if !strings.HasPrefix(challenge, "Bearer ") {
+		return fmt.Errorf("registry requires unsupported authentication: %s", challenge)
+	}
+
+	params := map[string]string{}
+	for _, part := range strings.Split(strings.TrimPrefix(challenge, "Bearer "), ",") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(part), "="); ok {
+			params[key] = strings.Trim(value, `"`)
+		}
+	}
+	if params["realm"] == "" {
+		return fmt.Errorf("registry challenge has no realm")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, params["realm"], nil)
+	if err != nil {
+		return err
+	}
+	query := req.URL.Query()
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+	req.URL.RawQuery = query.Encode()
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to obtain registry token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return fmt.Errorf("invalid registry token response: %w", err)
+	}
+	c.token = token.Token
+	if c.token == "" {
+		c.token = token.AccessToken
+	}
+	if c.token == "" {
+		return fmt.Errorf("registry returned an empty token")
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+package templates
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// IndexFile is the name of the index the template manager keeps in its
+// directory. It is not a template and is never listed.
+const IndexFile = ".index.json"
+
+// indexVersion is bumped when TemplateInfo changes so older indexes are
+// rebuilt
+const indexVersion = 2
+
+// Parse statuses of indexed template files
+const (
+	// StatusValid: the file parses and has an id and a name
+	StatusValid = "valid"
+	// StatusInvalid: the file is not valid YAML or JSON or lacks an id or
+	// name
+	StatusInvalid = "invalid"
+	// StatusUnsupported: the file is not a .yaml, .yml or .json template
+	StatusUnsupported = "unsupported"
+)
+
+// TemplateInfo describes a file in the templates directory
+type TemplateInfo struct {
+	// Name is the path relative to the templates directory, slash separated
+	Name string `json:"name"`
+	// Namespace is the directory of Name, empty at the top level
+	Namespace string    `json:"namespace,omitempty"`
+	ID        string    `json:"id,omitempty"`
+	Tags      []string  `json:"tags,omitempty"`
+	Hash      string    `json:"hash"`
+	Size      int64     `json:"size"`
+	ModTime   time.Time `json:"mod_time"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	// Format is FormatYAML or FormatJSON, empty for unsupported files
+	Format string `json:"format,omitempty"`
+
+	// Source is the name of the template directory holding the template
+	Source string `json:"source,omitempty"`
+	// Path is the template file on disk
+	Path string `json:"path,omitempty"`
+	// ShadowedBy is the higher priority source whose template with the same
+	// ID is used instead of this one
+	ShadowedBy string `json:"shadowed_by,omitempty"`
+}
+
+// QualifiedName is the name the template manager lists the template under:
+// Name for the custom templates directory, "<source>:<name>" otherwise
+func (t TemplateInfo) QualifiedName() string {
+	if t.Source == "" || t.Source == CustomSource {
+		return t.Name
+	}
+	return t.Source + ":" + t.Name
+}
+
+// conflictKey identifies the templates that shadow each other: valid
+// templates by ID. Other files never conflict.
+func (t TemplateInfo) conflictKey() string {
+	if t.Status != StatusValid {
+		return ""
+	}
+	return t.ID
+}
+
+// Matches reports whether the name, source, namespace, ID or a tag of the
+// template contains query, ignoring case
+func (t TemplateInfo) Matches(query string) bool {
+	query = strings.ToLower(strings.TrimSpace(query))
+	for _, value := range append([]string{t.Name, t.Source, t.Namespace, t.ID}, t.Tags...) {
+		if strings.Contains(strings.ToLower(value), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// templateIndex is the content of IndexFile
+type templateIndex struct {
+	Version   int                     `json:"version"`
+	Templates map[string]TemplateInfo `json:"templates"`
+}
+
+// loadIndex reads the index of dir. A missing, unreadable or outdated index
+// is returned empty, to be rebuilt.
+func loadIndex(dir string) map[string]TemplateInfo {
+	data, err := os.ReadFile(filepath.Join(dir, IndexFile))
+	if err != nil {
+		return map[string]TemplateInfo{}
+	}
+	var index templateIndex
+	if json.Unmarshal(data, &index) != nil || index.Version != indexVersion || index.Templates == nil {
+		return map[string]TemplateInfo{}
+	}
+	return index.Templates
+}
+
+// saveIndex writes the index of dir, replacing the previous one atomically
+func saveIndex(dir string, templates map[string]TemplateInfo) error {
+	data, err := json.MarshalIndent(templateIndex{Version: indexVersion, Templates: templates}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, IndexFile+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, IndexFile))
+}
+
+// refreshIndex brings index up to date with the files in dir. Only files
+// whose size or modification time changed are read again. It reports
+// whether index changed.
+func refreshIndex(dir string, index map[string]TemplateInfo) (bool, error) {
+	changed := false
+	seen := map[string]bool{}
+	err := filepath.WalkDir(dir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if file != dir && strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// Detached signatures are kept next to their templates
+		if !entry.Type().IsRegular() || strings.HasSuffix(entry.Name(), MinisignExtension) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		seen[name] = true
+		if cached, ok := index[name]; ok && cached.Size == info.Size() && cached.ModTime.Equal(info.ModTime()) {
+			return nil
+		}
+
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		index[name] = describe(name, content, info)
+		changed = true
+		return nil
+	})
+	if err != nil {
+		return changed, fmt.Errorf("failed to read templates directory: %w", err)
+	}
+
+	for name := range index {
+		if !seen[name] {
+			delete(index, name)
+			changed = true
+		}
+	}
+	return changed, nil
+}
+
+// describe indexes the content of a template file
+func describe(name string, content []byte, info fs.FileInfo) TemplateInfo {
+	sum := sha256.Sum256(content)
+	template := TemplateInfo{
+		Name:    name,
+		Hash:    hex.EncodeToString(sum[:]),
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		Status:  StatusValid,
+	}
+	if namespace := path.Dir(name); namespace != "." {
+		template.Namespace = namespace
+	}
+
+	if template.Format = FormatOf(name); template.Format == "" {
+		template.Status, template.Error = StatusUnsupported, "not a .yaml, .yml or .json template"
+		return template
+	}
+
+	// JSON templates are parsed as YAML, of which JSON is a subset
+	var parsed struct {
+		ID   string `yaml:"id"`
+		Info struct {
+			Name string `yaml:"name"`
+			Tags any    `yaml:"tags"`
+		} `yaml:"info"`
+	}
+	if err := yaml.Unmarshal(content, &parsed); err != nil {
+		template.Status, template.Error = StatusInvalid, err.Error()
+		return template
+	}
+	template.ID = parsed.ID
+	template.Tags = tagsOf(parsed.Info.Tags)
+	switch {
+	case parsed.ID == "":
+		template.Status, template.Error = StatusInvalid, "missing id"
+	case parsed.Info.Name == "":
+		template.Status, template.Error = StatusInvalid, "missing info.name"
+	}
+	return template
+}
+
+// tagsOf reads template tags written as a comma-separated string or a list
+func tagsOf(raw any) []string {
+	var values []string
+	switch tags := raw.(type) {
+	case string:
+		values = strings.Split(tags, ",")
+	case []any:
+		for _, tag := range tags {
+			if text, ok := tag.(string); ok {
+				values = append(values, text)
+			}
+		}
+	}
+
+	var result []string
+	for _, tag := range values {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			result = append(result, tag)
+		}
+	}
+	return result
+}
+
+// sortedInfos returns the indexed templates ordered by name
+func sortedInfos(index map[string]TemplateInfo) []TemplateInfo {
+	infos := make([]TemplateInfo, 0, len(index))
+	for _, info := range index {
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
@@ -0,0 +1,168 @@
+package templates
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Template formats nuclei reads
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+)
+
+// FormatOf returns the format of a template file by its extension, or an
+// empty string for files that are not templates
+func FormatOf(name string) string {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".json":
+		return FormatJSON
+	}
+	return ""
+}
+
+// DetectFormat returns the format template content is written in: JSON
+// when it is a JSON object, YAML otherwise
+func DetectFormat(content []byte) string {
+	if trimmed := bytes.TrimSpace(content); bytes.HasPrefix(trimmed, []byte("{")) && json.Valid(trimmed) {
+		return FormatJSON
+	}
+	return FormatYAML
+}
+
+// ConvertTemplate parses content, a template in either format, checks that
+// it has an id and an info block, and writes it in format. Keys keep their
+// order. Comments, including the signature line of a signed template, are
+// not carried over.
+func ConvertTemplate(content []byte, format string) ([]byte, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("failed to parse template: not a mapping of template fields")
+	}
+	root := document.Content[0]
+	if id := mappingValue(root, "id"); id == nil || id.Value == "" {
+		return nil, fmt.Errorf("invalid template: missing id")
+	}
+	if info := mappingValue(root, "info"); info == nil || info.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("invalid template: missing info")
+	}
+
+	switch format {
+	case FormatYAML:
+		clearStyle(root)
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(root); err != nil {
+			return nil, fmt.Errorf("failed to write YAML template: %w", err)
+		}
+		return buf.Bytes(), nil
+	case FormatJSON:
+		var buf bytes.Buffer
+		if err := writeJSON(&buf, root); err != nil {
+			return nil, fmt.Errorf("failed to write JSON template: %w", err)
+		}
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, buf.Bytes(), "", "  "); err != nil {
+			return nil, fmt.Errorf("failed to write JSON template: %w", err)
+		}
+		indented.WriteByte('\n')
+		return indented.Bytes(), nil
+	}
+	return nil, fmt.Errorf("unsupported template format %q, use %s or %s", format, FormatYAML, FormatJSON)
+}
+
+// mappingValue returns the value of key in a mapping node
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// clearStyle writes a template parsed from JSON in block style, with
+// multi-line strings as literal blocks
+func clearStyle(node *yaml.Node) {
+	if node.Kind != yaml.ScalarNode || node.Tag != "!!str" || !strings.Contains(node.Value, "\n") {
+		node.Style = 0
+	} else {
+		node.Style = yaml.LiteralStyle
+	}
+	for _, child := range node.Content {
+		clearStyle(child)
+	}
+}
+
+// writeJSON writes a parsed YAML node as JSON, keeping the order of
+// mapping keys
+func writeJSON(buf *bytes.Buffer, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		return writeJSON(buf, node.Content[0])
+	case yaml.AliasNode:
+		return writeJSON(buf, node.Alias)
+	case yaml.MappingNode:
+		buf.WriteByte('{')
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, err := marshalJSON(node.Content[i].Value)
+			if err != nil {
+				return err
+			}
+			buf.Write(key)
+			buf.WriteByte(':')
+			if err := writeJSON(buf, node.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, item := range node.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSON(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	default:
+		var value any
+		if err := node.Decode(&value); err != nil {
+			return err
+		}
+		encoded, err := marshalJSON(value)
+		if err != nil {
+			return err
+		}
+		buf.Write(encoded)
+	}
+	return nil
+}
+
+// marshalJSON encodes value without escaping HTML characters, which
+// templates match on
+func marshalJSON(value any) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,166 @@
+package templates
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HistoryDir is the directory of the custom templates directory keeping the
+// versions of its templates. Like IndexFile, it is never listed.
+const HistoryDir = ".history"
+
+// maxVersions is how many versions of a template are kept; older ones are
+// removed
+const maxVersions = 50
+
+// versionExt is the extension of version files. nuclei loads every file
+// with a template extension under its template directories, so versions
+// are stored without one.
+const versionExt = ".v"
+
+// ErrUnknownVersion is returned for a template version that does not exist
+var ErrUnknownVersion = errors.New("unknown template version")
+
+// TemplateVersion describes a saved version of a custom template
+type TemplateVersion struct {
+	Version int       `json:"version"`
+	Hash    string    `json:"hash"`
+	Size    int64     `json:"size"`
+	SavedAt time.Time `json:"saved_at"`
+	// Current is set for the version matching the template file
+	Current bool `json:"current,omitempty"`
+}
+
+// versionedName checks that name is a custom template within the custom
+// templates directory and returns it cleaned. The history paths are built
+// from names checked here or by AddTemplate.
+func (tm *templateManagerImpl) versionedName(name string) (string, error) {
+	source, name, err := tm.lookup(name)
+	if err != nil {
+		return "", err
+	}
+	if source != tm.sources[0] {
+		return "", fmt.Errorf("only templates of the %s templates directory are versioned", CustomSource)
+	}
+	return name, nil
+}
+
+// historyPath returns the directory holding the versions of a custom
+// template
+func (tm *templateManagerImpl) historyPath(name string) string {
+	return filepath.Join(tm.Dir, HistoryDir, filepath.FromSlash(name))
+}
+
+// versionPath returns the file of a version of a custom template
+func (tm *templateManagerImpl) versionPath(name string, version int) string {
+	return filepath.Join(tm.historyPath(name), fmt.Sprintf("%06d%s", version, versionExt))
+}
+
+// versions returns the version numbers of a custom template, oldest first
+func (tm *templateManagerImpl) versions(name string) ([]int, error) {
+	entries, err := os.ReadDir(tm.historyPath(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read template history: %w", err)
+	}
+	var versions []int
+	for _, entry := range entries {
+		number, ok := strings.CutSuffix(entry.Name(), versionExt)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		if version, err := strconv.Atoi(number); err == nil {
+			versions = append(versions, version)
+		}
+	}
+	sort.Ints(versions)
+	return versions, nil
+}
+
+// recordVersion saves content as the next version of a custom template,
+// unless it is the latest version already, and removes the versions beyond
+// maxVersions
+func (tm *templateManagerImpl) recordVersion(name string, content []byte) error {
+	versions, err := tm.versions(name)
+	if err != nil {
+		return err
+	}
+	next := 1
+	if len(versions) > 0 {
+		latest := versions[len(versions)-1]
+		if saved, err := os.ReadFile(tm.versionPath(name, latest)); err == nil && bytes.Equal(saved, content) {
+			return nil
+		}
+		next = latest + 1
+	}
+
+	if err := os.MkdirAll(tm.historyPath(name), 0755); err != nil {
+		return fmt.Errorf("failed to create template history: %w", err)
+	}
+	if err := os.WriteFile(tm.versionPath(name, next), content, 0644); err != nil {
+		return fmt.Errorf("failed to save template version: %w", err)
+	}
+	versions = append(versions, next)
+	for _, version := range versions[:max(0, len(versions)-maxVersions)] {
+		_ = os.Remove(tm.versionPath(name, version))
+	}
+	return nil
+}
+
+// History returns the saved versions of a custom template, oldest first
+func (tm *templateManagerImpl) History(name string) ([]TemplateVersion, error) {
+	name, err := tm.versionedName(name)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := tm.versions(name)
+	if err != nil {
+		return nil, err
+	}
+	current, _ := os.ReadFile(filepath.Join(tm.Dir, filepath.FromSlash(name)))
+
+	history := make([]TemplateVersion, 0, len(versions))
+	for _, version := range versions {
+		path := tm.versionPath(name, version)
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template version %d: %w", version, err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template version %d: %w", version, err)
+		}
+		sum := sha256.Sum256(content)
+		history = append(history, TemplateVersion{
+			Version: version,
+			Hash:    hex.EncodeToString(sum[:]),
+			Size:    info.Size(),
+			SavedAt: info.ModTime(),
+			Current: current != nil && bytes.Equal(content, current),
+		})
+	}
+	return history, nil
+}
+
+// GetVersion returns the content of a saved version of a custom template
+func (tm *templateManagerImpl) GetVersion(name string, version int) ([]byte, error) {
+	name, err := tm.versionedName(name)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(tm.versionPath(name, version))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w %d of %s", ErrUnknownVersion, version, name)
+	}
+	return content, err
+}
@@ -0,0 +1,150 @@
+package templates
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"aead.dev/minisign"
+	"github.com/projectdiscovery/nuclei/v3/pkg/templates/signer"
+)
+
+const (
+	// VerifyNuclei checks the "# digest:" signature embedded by nuclei's
+	// template signer against the projectdiscovery certificate and any
+	// configured certificate
+	VerifyNuclei = "nuclei"
+	// VerifyMinisign checks a detached minisign signature stored next to the
+	// template as <template>.minisig
+	VerifyMinisign = "minisign"
+)
+
+// MinisignExtension is the suffix of detached minisign signature files
+const MinisignExtension = ".minisig"
+
+// Verifier checks template signatures
+type Verifier struct {
+	mode       string
+	enforce    bool
+	signers    []*signer.TemplateSigner
+	minisignPK minisign.PublicKey
+}
+
+// NewVerifier creates a verifier for the given mode. For VerifyNuclei the key
+// is an optional PEM certificate trusted in addition to the projectdiscovery
+// one; for VerifyMinisign it is a minisign public key or a path to one. When
+// enforce is set, templates failing verification are rejected.
+func NewVerifier(mode string, key string, enforce bool) (*Verifier, error) {
+	v := &Verifier{mode: mode, enforce: enforce}
+
+	switch mode {
+	case VerifyNuclei:
+		v.signers = append(v.signers, signer.DefaultTemplateVerifiers...)
+		if key != "" {
+			cert, err := readKey(key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read signing certificate: %w", err)
+			}
+			custom, err := signer.NewTemplateSigVerifier(cert)
+			if err != nil {
+				return nil, fmt.Errorf("invalid signing certificate: %w", err)
+			}
+			v.signers = append(v.signers, custom)
+		}
+		if len(v.signers) == 0 {
+			return nil, fmt.Errorf("no template signing certificates available")
+		}
+	case VerifyMinisign:
+		if key == "" {
+			return nil, fmt.Errorf("minisign verification requires a public key")
+		}
+		text, err := readKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read minisign public key: %w", err)
+		}
+		if err := v.minisignPK.UnmarshalText(lastLine(text)); err != nil {
+			return nil, fmt.Errorf("invalid minisign public key: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown signature verification mode %q, expected %s or %s", mode, VerifyNuclei, VerifyMinisign)
+	}
+
+	return v, nil
+}
+
+// Mode returns the verification mode
+func (v *Verifier) Mode() string {
+	return v.mode
+}
+
+// Enforced reports whether unverified templates are rejected
+func (v *Verifier) Enforced() bool {
+	return v.enforce
+}
+
+// Verify checks the signature of template content. The signature is the
+// detached minisign signature and is ignored in nuclei mode, where the
+// signature is embedded in the content.
+func (v *Verifier) Verify(content []byte, signature []byte) error {
+	switch v.mode {
+	case VerifyMinisign:
+		if len(signature) == 0 {
+			return fmt.Errorf("template is not signed")
+		}
+		if !minisign.Verify(v.minisignPK, content, signature) {
+			return fmt.Errorf("template signature is invalid or the template was modified")
+		}
+		return nil
+	default:
+		embedded, _ := signer.ExtractSignatureAndContent(content)
+		if len(embedded) == 0 {
+			return fmt.Errorf("template is not signed")
+		}
+		for _, s := range v.signers {
+			if ok, err := s.Verify(content, unsignedImports{}); err == nil && ok {
+				return nil
+			}
+		}
+		return fmt.Errorf("template signature is invalid or the template was modified")
+	}
+}
+
+// VerifyFile checks the signature of a template on disk, reading a detached
+// minisign signature from <path>.minisig when needed
+func (v *Verifier) VerifyFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var signature []byte
+	if v.mode == VerifyMinisign {
+		signature, err = os.ReadFile(path + MinisignExtension)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return v.Verify(content, signature)
+}
+
+// unsignedImports is the signable view of a template verified from raw
+// content. File imports are not resolved, so templates relying on them fail
+// verification.
+type unsignedImports struct{}
+
+func (unsignedImports) GetFileImports() []string { return nil }
+func (unsignedImports) HasCodeProtocol() bool    { return false }
+
+// readKey returns key material given inline or as a file path
+func readKey(key string) ([]byte, error) {
+	if strings.Contains(key, "\n") || strings.HasPrefix(key, "-----BEGIN") || strings.HasPrefix(key, "RW") {
+		return []byte(key), nil
+	}
+	return os.ReadFile(key)
+}
+
+// lastLine strips the untrusted comment line of a minisign public key file
+func lastLine(text []byte) []byte {
+	lines := strings.Split(strings.TrimSpace(string(text)), "\n")
+	return []byte(strings.TrimSpace(lines[len(lines)-1]))
+}
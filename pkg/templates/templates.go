@@ -1,15 +1,57 @@
 package templates
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 )
 
+// CustomSource names the template manager's own directory, where added
+// templates are written. It has the highest priority.
+const CustomSource = "custom"
+
+// Source is a template directory read by the template manager in addition
+// to its own, such as the official templates or an organization-wide share
+type Source struct {
+	Name string
+	Dir  string
+}
+
+// ManagerOption configures the template manager
+type ManagerOption func(*templateManagerImpl)
+
+// WithSources adds read-only template directories after the custom
+// templates directory, highest priority first. When templates of several
+// directories share an ID, the one listed first is used and the others are
+// reported as shadowed.
+func WithSources(sources ...Source) ManagerOption {
+	return func(tm *templateManagerImpl) {
+		for _, source := range sources {
+			tm.sources = append(tm.sources, &templateSource{Source: source})
+		}
+	}
+}
+
 // TemplateManager handles operations related to Nuclei templates.
 type templateManagerImpl struct {
 	Dir string
+
+	// mu guards the indexes of the sources, the custom templates directory
+	// first, which are loaded from IndexFile on first use and kept up to
+	// date incrementally
+	mu      sync.Mutex
+	sources []*templateSource
+}
+
+// templateSource is a template directory and its index
+type templateSource struct {
+	Source
+	index  map[string]TemplateInfo
+	loaded bool
 }
 
 // TemplateManager defines the interface for managing Nuclei templates.
@@ -17,40 +59,196 @@ type TemplateManager interface {
 	AddTemplate(name string, content []byte) error
 	ListTemplates() ([]string, error)
 	GetTemplate(name string) ([]byte, error)
+	// Catalog returns the indexed metadata of every template of every
+	// source, by source priority and then name
+	Catalog() ([]TemplateInfo, error)
+	// History returns the saved versions of a custom template, oldest first
+	History(name string) ([]TemplateVersion, error)
+	// GetVersion returns the content of a saved version of a custom template
+	GetVersion(name string, version int) ([]byte, error)
 }
 
 // NewTemplateManager creates a new TemplateManager.
-func NewTemplateManager(dir string) (TemplateManager, error) {
+func NewTemplateManager(dir string, opts ...ManagerOption) (TemplateManager, error) {
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create templates directory: %w", err)
 	}
-	return &templateManagerImpl{Dir: dir}, nil
+	tm := &templateManagerImpl{Dir: dir, sources: []*templateSource{{Source: Source{Name: CustomSource, Dir: dir}}}}
+	for _, opt := range opts {
+		opt(tm)
+	}
+
+	names := map[string]bool{}
+	for _, source := range tm.sources {
+		switch {
+		case source.Name == "" || source.Dir == "":
+			return nil, fmt.Errorf("template source needs a name and a directory")
+		case strings.Contains(source.Name, ":"):
+			return nil, fmt.Errorf("invalid template source name %q", source.Name)
+		case names[source.Name]:
+			return nil, fmt.Errorf("duplicate template source name %q", source.Name)
+		}
+		names[source.Name] = true
+	}
+	return tm, nil
 }
 
-// AddTemplate saves a new template to the templates directory.
+// AddTemplate saves a new template to the templates directory. A name with
+// a directory, such as "acme/login.yaml", puts the template in that
+// namespace. Every content a template had is kept in its history, including
+// edits made to the file outside the template manager. A name ending in
+// MinisignExtension saves the detached signature of a template instead,
+// which is neither versioned nor indexed.
 func (tm *templateManagerImpl) AddTemplate(name string, content []byte) error {
+	source, name, err := tm.lookup(name)
+	if err != nil {
+		return err
+	}
+	if source != tm.sources[0] {
+		return fmt.Errorf("templates can only be added to the %s templates directory", CustomSource)
+	}
+
 	path := filepath.Join(tm.Dir, name)
-	return ioutil.WriteFile(path, content, 0644)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create template namespace: %w", err)
+	}
+	if strings.HasSuffix(name, MinisignExtension) {
+		return ioutil.WriteFile(path, content, 0644)
+	}
+	if previous, err := os.ReadFile(path); err == nil {
+		if err := tm.recordVersion(name, previous); err != nil {
+			return err
+		}
+	}
+	if err := ioutil.WriteFile(path, content, 0644); err != nil {
+		return err
+	}
+	if err := tm.recordVersion(name, content); err != nil {
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	custom := tm.sources[0]
+	if !custom.loaded {
+		// The next list builds the whole index, including this template
+		return nil
+	}
+	custom.index[filepath.ToSlash(name)] = describe(filepath.ToSlash(name), content, info)
+	return custom.save()
 }
 
-// ListTemplates returns a list of all available template names.
+// ListTemplates returns a list of all available template names. Templates
+// of other sources than the custom templates directory are named
+// "<source>:<name>"; shadowed templates are left out.
 func (tm *templateManagerImpl) ListTemplates() ([]string, error) {
-	files, err := ioutil.ReadDir(tm.Dir)
+	catalog, err := tm.Catalog()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read templates directory: %w", err)
+		return nil, err
 	}
 
 	var templates []string
-	for _, file := range files {
-		if !file.IsDir() {
-			templates = append(templates, file.Name())
+	for _, template := range catalog {
+		if template.ShadowedBy == "" {
+			templates = append(templates, template.QualifiedName())
 		}
 	}
 	return templates, nil
 }
 
+// Catalog returns the index of every source, re-reading only the files
+// added or modified since each index was last saved
+func (tm *templateManagerImpl) Catalog() ([]TemplateInfo, error) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	var catalog []TemplateInfo
+	owners := map[string]string{}
+	for _, source := range tm.sources {
+		if err := source.refresh(source == tm.sources[0]); err != nil {
+			return nil, err
+		}
+		dir, err := filepath.Abs(source.Dir)
+		if err != nil {
+			dir = source.Dir
+		}
+		for _, template := range sortedInfos(source.index) {
+			template.Source = source.Name
+			template.Path = filepath.Join(dir, filepath.FromSlash(template.Name))
+			if key := template.conflictKey(); key != "" {
+				if owner, ok := owners[key]; ok {
+					template.ShadowedBy = owner
+				} else {
+					owners[key] = source.Name
+				}
+			}
+			catalog = append(catalog, template)
+		}
+	}
+	return catalog, nil
+}
+
+// refresh brings the index of the source up to date. Only the custom
+// templates directory must exist and have a writable index; other sources,
+// such as a network share that is not mounted, are read as they are.
+func (s *templateSource) refresh(required bool) error {
+	if !s.loaded {
+		s.index = loadIndex(s.Dir)
+		s.loaded = true
+	}
+
+	if _, err := os.Stat(s.Dir); !required && errors.Is(err, os.ErrNotExist) {
+		s.index = map[string]TemplateInfo{}
+		return nil
+	}
+	changed, err := refreshIndex(s.Dir, s.index)
+	if err != nil {
+		return fmt.Errorf("template source %s: %w", s.Name, err)
+	}
+	if changed {
+		if err := s.save(); err != nil && required {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *templateSource) save() error {
+	if err := saveIndex(s.Dir, s.index); err != nil {
+		return fmt.Errorf("failed to save template index: %w", err)
+	}
+	return nil
+}
+
+// lookup returns the source of a template name and the name within it,
+// which must stay within the source's directory
+func (tm *templateManagerImpl) lookup(name string) (*templateSource, string, error) {
+	source, rest := tm.sources[0], name
+	if prefix, after, ok := strings.Cut(name, ":"); ok {
+		for _, candidate := range tm.sources[1:] {
+			if candidate.Name == prefix {
+				source, rest = candidate, after
+				break
+			}
+		}
+	}
+	clean := filepath.Clean(filepath.FromSlash(rest))
+	if rest == "" || !filepath.IsLocal(clean) {
+		return nil, "", fmt.Errorf("invalid template name %q, give a path inside the templates directory", name)
+	}
+	return source, filepath.ToSlash(clean), nil
+}
+
 // GetTemplate retrieves the content of a specific template.
 func (tm *templateManagerImpl) GetTemplate(name string) ([]byte, error) {
-	path := filepath.Join(tm.Dir, name)
+	source, name, err := tm.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(source.Dir, filepath.FromSlash(name))
 	return ioutil.ReadFile(path)
 }
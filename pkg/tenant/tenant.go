@@ -0,0 +1,87 @@
+package tenant
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Tenant is a client of a shared server, authenticated by its bearer token
+// and served by its own handler
+type Tenant struct {
+	Name    string
+	Token   string
+	Handler http.Handler
+}
+
+type contextKey struct{}
+
+// FromContext returns the name of the tenant a request was routed to, or ""
+func FromContext(ctx context.Context) string {
+	name, _ := ctx.Value(contextKey{}).(string)
+	return name
+}
+
+// Router sends each HTTP request to the handler of the tenant whose token
+// it carries as a bearer token. Requests without a known token are refused,
+// so a tenant never reaches another tenant's handler.
+type Router struct {
+	tenants []Tenant
+}
+
+// NewRouter creates a router for tenants. Every tenant needs a name, a
+// handler and a token of its own.
+func NewRouter(tenants []Tenant) (*Router, error) {
+	if len(tenants) == 0 {
+		return nil, fmt.Errorf("at least one tenant is required")
+	}
+	names := map[string]bool{}
+	tokens := map[string]bool{}
+	for _, tenant := range tenants {
+		switch {
+		case strings.TrimSpace(tenant.Name) == "":
+			return nil, fmt.Errorf("tenant name is required")
+		case strings.TrimSpace(tenant.Token) == "":
+			return nil, fmt.Errorf("tenant %s needs a token", tenant.Name)
+		case tenant.Handler == nil:
+			return nil, fmt.Errorf("tenant %s has no handler", tenant.Name)
+		case names[tenant.Name]:
+			return nil, fmt.Errorf("duplicate tenant %s", tenant.Name)
+		case tokens[tenant.Token]:
+			return nil, fmt.Errorf("tenant %s shares its token with another tenant", tenant.Name)
+		}
+		names[tenant.Name] = true
+		tokens[tenant.Token] = true
+	}
+	return &Router{tenants: append([]Tenant(nil), tenants...)}, nil
+}
+
+func (r *Router) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	tenant, ok := r.lookup(req)
+	if !ok {
+		http.Error(rw, "invalid or missing token", http.StatusUnauthorized)
+		return
+	}
+	tenant.Handler.ServeHTTP(rw, req.WithContext(context.WithValue(req.Context(), contextKey{}, tenant.Name)))
+}
+
+// lookup returns the tenant of the request's bearer token. Every token is
+// compared so the time taken does not reveal which tenant matched.
+func (r *Router) lookup(req *http.Request) (Tenant, bool) {
+	given, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !ok || given == "" {
+		return Tenant{}, false
+	}
+	var (
+		found Tenant
+		match bool
+	)
+	for _, tenant := range r.tenants {
+		if subtle.ConstantTimeCompare([]byte(given), []byte(tenant.Token)) == 1 {
+			found, match = tenant, true
+		}
+	}
+	return found, match
+}
@@ -0,0 +1,98 @@
+package tracker
+
+import (
+	"context"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/scanner"
+)
+
+// DefaultRetestInterval is how often the retester looks for due retests
+const DefaultRetestInterval = time.Minute
+
+// Retester runs the verification scans of remediated findings when they
+// are due and records whether each finding is still present
+type Retester struct {
+	store    *Store
+	service  scanner.ScannerService
+	console  scanner.LoggerInterface
+	interval time.Duration
+}
+
+// NewRetester creates a retester checking store every interval
+func NewRetester(store *Store, service scanner.ScannerService, console scanner.LoggerInterface, interval time.Duration) *Retester {
+	if interval <= 0 {
+		interval = DefaultRetestInterval
+	}
+	return &Retester{store: store, service: service, console: console, interval: interval}
+}
+
+// Run retests due findings and reopens expired risk acceptances until ctx
+// is done
+func (r *Retester) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		r.ExpireAcceptances()
+		r.RetestDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RetestDue runs the retests due now, one at a time, and returns their
+// updated records
+func (r *Retester) RetestDue(ctx context.Context) []Record {
+	var done []Record
+	for _, record := range r.store.Due(time.Now()) {
+		if ctx.Err() != nil {
+			break
+		}
+		present, err := r.retest(ctx, record)
+		updated, saveErr := r.store.CompleteRetest(record.Fingerprint, present, err)
+		if saveErr != nil {
+			r.console.Log("Failed to record retest of finding %s: %v", record.Fingerprint, saveErr)
+			continue
+		}
+		r.console.Log("Retest of finding %s (%s on %s): %s", record.Fingerprint, record.TemplateID, record.Target, updated.Retest.Result)
+		done = append(done, updated)
+	}
+	return done
+}
+
+// ExpireAcceptances reopens the accepted risks whose acceptance expired
+// and returns their updated records
+func (r *Retester) ExpireAcceptances() []Record {
+	expired, err := r.store.ExpireAcceptances(time.Now())
+	if err != nil {
+		r.console.Log("Failed to reopen expired risk acceptances: %v", err)
+	}
+	for _, record := range expired {
+		r.console.Log("Risk acceptance of finding %s (%s on %s) expired, finding reopened", record.Fingerprint, record.TemplateID, record.Target)
+	}
+	return expired
+}
+
+// retest scans the finding's target with its template. The finding is
+// present when the scan reports it again, or reports the template on the
+// same host at another location.
+func (r *Retester) retest(ctx context.Context, record Record) (bool, error) {
+	result, err := r.service.ThreadSafeScan(ctx, record.Target, "", "", []string{record.TemplateID},
+		scanner.WithPriority(scanner.PriorityBackground), scanner.WithFreshResult())
+	if err != nil {
+		return false, err
+	}
+	for _, finding := range result.Findings {
+		if cache.Fingerprint(finding) == record.Fingerprint {
+			return true, nil
+		}
+		if finding.TemplateID == record.TemplateID && record.Host != "" && finding.Host == record.Host {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,402 @@
+package tracker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"nuclei-mcp/pkg/encryption"
+)
+
+// Status is the lifecycle state of a tracked finding
+type Status string
+
+const (
+	// StatusNew is the status of findings nobody has looked at yet,
+	// including every untracked finding
+	StatusNew Status = "new"
+	// StatusTriaged marks a finding confirmed and awaiting a fix
+	StatusTriaged Status = "triaged"
+	// StatusAcceptedRisk marks a finding its owner chose not to fix
+	StatusAcceptedRisk Status = "accepted-risk"
+	// StatusRemediated marks a finding its owner reports as fixed
+	StatusRemediated Status = "remediated"
+	// StatusReopened marks a remediated finding a retest found again, or
+	// an accepted risk whose acceptance expired
+	StatusReopened Status = "reopened"
+)
+
+// Statuses lists the finding statuses in lifecycle order
+var Statuses = []Status{StatusNew, StatusTriaged, StatusAcceptedRisk, StatusRemediated, StatusReopened}
+
+// ParseStatus parses a status name
+func ParseStatus(name string) (Status, error) {
+	for _, status := range Statuses {
+		if string(status) == strings.ToLower(strings.TrimSpace(name)) {
+			return status, nil
+		}
+	}
+	names := make([]string, len(Statuses))
+	for i, status := range Statuses {
+		names[i] = string(status)
+	}
+	return "", fmt.Errorf("unknown finding status %q, use one of %s", name, strings.Join(names, ", "))
+}
+
+// Results of a retest
+const (
+	RetestPending = "pending"
+	RetestFixed   = "fixed"
+	RetestPresent = "still_present"
+	RetestFailed  = "failed"
+)
+
+// Record is the tracked state of a finding, keyed by its fingerprint
+type Record struct {
+	Fingerprint string    `json:"fingerprint"`
+	Target      string    `json:"target"`
+	TemplateID  string    `json:"template_id"`
+	Host        string    `json:"host"`
+	Status      Status    `json:"status"`
+	Note        string    `json:"note,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Retest      *Retest   `json:"retest,omitempty"`
+	// AcceptedUntil is when the acceptance of an accepted risk expires and
+	// the finding is reopened; accepted risks without it never expire
+	AcceptedUntil *time.Time `json:"accepted_until,omitempty"`
+}
+
+// expired reports whether record is an accepted risk whose acceptance
+// expired at now
+func (r Record) expired(now time.Time) bool {
+	return r.Status == StatusAcceptedRisk && r.AcceptedUntil != nil && !now.Before(*r.AcceptedUntil)
+}
+
+// current returns record with an expired acceptance reopened, for reads
+// between two sweeps of ExpireAcceptances
+func (r Record) current(now time.Time) Record {
+	if r.expired(now) {
+		r.Status = StatusReopened
+	}
+	return r
+}
+
+// Retest is the verification scan scheduled for a remediated finding
+type Retest struct {
+	DueAt     time.Time  `json:"due_at"`
+	Result    string     `json:"result"`
+	CheckedAt *time.Time `json:"checked_at,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// Store keeps tracked findings in a JSON file
+type Store struct {
+	path string
+	key  *encryption.Key
+
+	mu      sync.RWMutex
+	records map[string]Record
+}
+
+// Option configures a Store
+type Option func(*Store)
+
+// WithEncryptionKey encrypts the file with key. A plain file is read and
+// encrypted at the next change.
+func WithEncryptionKey(key *encryption.Key) Option {
+	return func(s *Store) {
+		s.key = key
+	}
+}
+
+// Open loads the records stored at path. A missing file holds no records.
+func Open(path string, opts ...Option) (*Store, error) {
+	s := &Store{path: path, records: map[string]Record{}}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read finding tracker: %w", err)
+	}
+	if encryption.Sealed(data) {
+		if data, err = s.key.Open(data); err != nil {
+			return nil, fmt.Errorf("failed to decrypt finding tracker %s: %w", path, err)
+		}
+	}
+	var records []Record
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("failed to parse finding tracker %s: %w", path, err)
+	}
+	for _, record := range records {
+		s.records[record.Fingerprint] = record
+	}
+	return s, nil
+}
+
+// Get returns the record of the finding with fingerprint
+func (s *Store) Get(fingerprint string) (Record, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	record, ok := s.records[fingerprint]
+	return record.current(time.Now()), ok
+}
+
+// StatusOf returns the status of the finding with fingerprint; untracked
+// findings are new
+func (s *Store) StatusOf(fingerprint string) Status {
+	if s == nil {
+		return StatusNew
+	}
+	if record, ok := s.Get(fingerprint); ok {
+		return record.Status
+	}
+	return StatusNew
+}
+
+// SetStatus records the status of a finding. A pending retest is cancelled
+// when the finding leaves the remediated status; use MarkRemediated to
+// schedule one. The record's AcceptedUntil is kept for accepted risks only.
+func (s *Store) SetStatus(record Record, status Status) (Record, error) {
+	if strings.TrimSpace(record.Fingerprint) == "" {
+		return Record{}, fmt.Errorf("finding fingerprint is required")
+	}
+	if _, err := ParseStatus(string(status)); err != nil {
+		return Record{}, err
+	}
+	now := time.Now().UTC()
+	if status != StatusAcceptedRisk {
+		record.AcceptedUntil = nil
+	} else if record.AcceptedUntil != nil && !now.Before(*record.AcceptedUntil) {
+		return Record{}, fmt.Errorf("acceptance expiry %s is not in the future", record.AcceptedUntil.Format(time.RFC3339))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.records[record.Fingerprint]; ok {
+		record.Retest = existing.Retest
+		if record.Note == "" {
+			record.Note = existing.Note
+		}
+	}
+	if record.Retest != nil && record.Retest.Result == RetestPending && status != StatusRemediated {
+		record.Retest = nil
+	}
+	record.Status = status
+	record.UpdatedAt = now
+	if err := s.put(record); err != nil {
+		return Record{}, err
+	}
+	return record, nil
+}
+
+// List returns the records, most recently updated first
+func (s *Store) List() []Record {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	now := time.Now()
+	records := make([]Record, 0, len(s.records))
+	for _, record := range s.records {
+		records = append(records, record.current(now))
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if !records[i].UpdatedAt.Equal(records[j].UpdatedAt) {
+			return records[i].UpdatedAt.After(records[j].UpdatedAt)
+		}
+		return records[i].Fingerprint < records[j].Fingerprint
+	})
+	return records
+}
+
+// MarkRemediated records a finding as remediated and schedules its retest
+// after retestAfter
+func (s *Store) MarkRemediated(record Record, retestAfter time.Duration) (Record, error) {
+	if strings.TrimSpace(record.Fingerprint) == "" {
+		return Record{}, fmt.Errorf("finding fingerprint is required")
+	}
+	if record.Target == "" || record.TemplateID == "" {
+		return Record{}, fmt.Errorf("finding %s has no target or template to retest", record.Fingerprint)
+	}
+	now := time.Now().UTC()
+	record.Status = StatusRemediated
+	record.UpdatedAt = now
+	record.AcceptedUntil = nil
+	record.Retest = &Retest{DueAt: now.Add(retestAfter), Result: RetestPending}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.put(record); err != nil {
+		return Record{}, err
+	}
+	return record, nil
+}
+
+// Due returns the records whose retest is pending and due at now
+func (s *Store) Due(now time.Time) []Record {
+	var due []Record
+	for _, record := range s.List() {
+		if record.Retest != nil && record.Retest.Result == RetestPending && !record.Retest.DueAt.After(now) {
+			due = append(due, record)
+		}
+	}
+	return due
+}
+
+// CompleteRetest records the result of a finding's retest. A finding found
+// again is reopened; a failed scan leaves its status unchanged.
+func (s *Store) CompleteRetest(fingerprint string, present bool, scanErr error) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.records[fingerprint]
+	if !ok || record.Retest == nil {
+		return Record{}, fmt.Errorf("no retest scheduled for finding %s", fingerprint)
+	}
+
+	now := time.Now().UTC()
+	retest := *record.Retest
+	retest.CheckedAt = &now
+	switch {
+	case scanErr != nil:
+		retest.Result, retest.Error = RetestFailed, scanErr.Error()
+	case present:
+		retest.Result = RetestPresent
+		record.Status = StatusReopened
+	default:
+		retest.Result = RetestFixed
+	}
+	record.Retest = &retest
+	record.UpdatedAt = now
+	if err := s.put(record); err != nil {
+		return Record{}, err
+	}
+	return record, nil
+}
+
+// Acceptances returns the accepted risks expiring before now+within, and
+// the findings reopened because their acceptance expired, soonest expiry
+// first
+func (s *Store) Acceptances(now time.Time, within time.Duration) []Record {
+	var acceptances []Record
+	for _, record := range s.List() {
+		if record.AcceptedUntil == nil {
+			continue
+		}
+		if record.Status == StatusReopened || (record.Status == StatusAcceptedRisk && record.AcceptedUntil.Before(now.Add(within))) {
+			acceptances = append(acceptances, record)
+		}
+	}
+	sort.SliceStable(acceptances, func(i, j int) bool { return acceptances[i].AcceptedUntil.Before(*acceptances[j].AcceptedUntil) })
+	return acceptances
+}
+
+// ExpireAcceptances reopens the accepted risks whose acceptance expired at
+// now and returns their updated records. AcceptedUntil is kept so the
+// finding shows why it was reopened.
+func (s *Store) ExpireAcceptances(now time.Time) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var expired []Record
+	for _, record := range s.records {
+		if !record.expired(now) {
+			continue
+		}
+		record.Status = StatusReopened
+		record.UpdatedAt = now.UTC()
+		if err := s.put(record); err != nil {
+			return expired, err
+		}
+		expired = append(expired, record)
+	}
+	sort.Slice(expired, func(i, j int) bool { return expired[i].Fingerprint < expired[j].Fingerprint })
+	return expired, nil
+}
+
+// Restore stores records, such as those of a workspace archive, as they are.
+// A finding that is already tracked keeps its record unless overwrite. It
+// returns the number of records stored.
+func (s *Store) Restore(records []Record, overwrite bool) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	merged := make(map[string]Record, len(s.records)+len(records))
+	for fingerprint, record := range s.records {
+		merged[fingerprint] = record
+	}
+	restored := 0
+	for _, record := range records {
+		if strings.TrimSpace(record.Fingerprint) == "" {
+			return 0, fmt.Errorf("finding fingerprint is required")
+		}
+		if _, err := ParseStatus(string(record.Status)); err != nil {
+			return 0, err
+		}
+		if _, ok := merged[record.Fingerprint]; ok && !overwrite {
+			continue
+		}
+		merged[record.Fingerprint] = record
+		restored++
+	}
+	if restored == 0 {
+		return 0, nil
+	}
+
+	all := make([]Record, 0, len(merged))
+	for _, record := range merged {
+		all = append(all, record)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Fingerprint < all[j].Fingerprint })
+	if err := s.save(all); err != nil {
+		return 0, err
+	}
+	s.records = merged
+	return restored, nil
+}
+
+// put stores record and saves the store; s.mu must be held
+func (s *Store) put(record Record) error {
+	records := make([]Record, 0, len(s.records)+1)
+	for fingerprint, existing := range s.records {
+		if fingerprint != record.Fingerprint {
+			records = append(records, existing)
+		}
+	}
+	records = append(records, record)
+	sort.Slice(records, func(i, j int) bool { return records[i].Fingerprint < records[j].Fingerprint })
+	if err := s.save(records); err != nil {
+		return err
+	}
+	s.records[record.Fingerprint] = record
+	return nil
+}
+
+func (s *Store) save(records []Record) error {
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode finding tracker: %w", err)
+	}
+	if s.key != nil {
+		data = s.key.Seal(data)
+	}
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create finding tracker directory: %w", err)
+		}
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write finding tracker: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to write finding tracker: %w", err)
+	}
+	return nil
+}
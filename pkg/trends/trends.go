@@ -0,0 +1,145 @@
+package trends
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+)
+
+// DefaultInterval is the width of a trend point
+const DefaultInterval = 24 * time.Hour
+
+// Directions of a target's trend
+const (
+	Improving    = "improving"
+	Worsening    = "worsening"
+	Unchanged    = "unchanged"
+	Insufficient = "insufficient_data"
+)
+
+// severityWeight scores findings when comparing trend points, so a new
+// critical outweighs several resolved low findings
+var severityWeight = map[string]int{
+	"critical": 10,
+	"high":     5,
+	"medium":   2,
+	"low":      1,
+	"info":     0,
+	"unknown":  0,
+}
+
+// Point counts the distinct findings of the scans of a target that started
+// within one interval
+type Point struct {
+	Time       time.Time      `json:"time"`
+	Scans      int            `json:"scans"`
+	Total      int            `json:"total"`
+	BySeverity map[string]int `json:"by_severity"`
+	// Score weighs the findings by severity (critical 10, high 5, medium 2,
+	// low 1)
+	Score int `json:"score"`
+}
+
+// Series is the severity trend of a target, oldest point first
+type Series struct {
+	Target string  `json:"target"`
+	Points []Point `json:"points"`
+	// Direction compares the score of the latest point with the one before
+	Direction string `json:"direction"`
+}
+
+// Build groups results by target and interval. Scans of the same interval
+// are merged and findings reported by several of them counted once. An
+// empty target includes every target; interval defaults to DefaultInterval.
+func Build(results []cache.ScanResult, target string, interval time.Duration) []Series {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	type bucket struct {
+		point Point
+		seen  map[string]struct{}
+	}
+	buckets := map[string]map[time.Time]*bucket{}
+	for _, result := range results {
+		if target != "" && result.Target != target {
+			continue
+		}
+		start := result.ScanTime.UTC().Truncate(interval)
+		if buckets[result.Target] == nil {
+			buckets[result.Target] = map[time.Time]*bucket{}
+		}
+		b := buckets[result.Target][start]
+		if b == nil {
+			b = &bucket{point: Point{Time: start, BySeverity: map[string]int{}}, seen: map[string]struct{}{}}
+			buckets[result.Target][start] = b
+		}
+		b.point.Scans++
+		for _, finding := range result.Findings {
+			if finding == nil {
+				continue
+			}
+			key := findingKey(finding)
+			if _, ok := b.seen[key]; ok {
+				continue
+			}
+			b.seen[key] = struct{}{}
+			severity := severityOf(finding)
+			b.point.BySeverity[severity]++
+			b.point.Total++
+			b.point.Score += severityWeight[severity]
+		}
+	}
+
+	series := make([]Series, 0, len(buckets))
+	for name, byTime := range buckets {
+		s := Series{Target: name}
+		for _, b := range byTime {
+			s.Points = append(s.Points, b.point)
+		}
+		sort.Slice(s.Points, func(i, j int) bool {
+			return s.Points[i].Time.Before(s.Points[j].Time)
+		})
+		s.Direction = direction(s.Points)
+		series = append(series, s)
+	}
+	sort.Slice(series, func(i, j int) bool {
+		return series[i].Target < series[j].Target
+	})
+	return series
+}
+
+func direction(points []Point) string {
+	if len(points) < 2 {
+		return Insufficient
+	}
+	latest, previous := points[len(points)-1], points[len(points)-2]
+	switch {
+	case latest.Score < previous.Score:
+		return Improving
+	case latest.Score > previous.Score:
+		return Worsening
+	case latest.Total < previous.Total:
+		return Improving
+	case latest.Total > previous.Total:
+		return Worsening
+	}
+	return Unchanged
+}
+
+// findingKey identifies a finding across scans with different options
+func findingKey(finding *output.ResultEvent) string {
+	return strings.Join([]string{finding.TemplateID, finding.MatcherName, finding.Matched}, "|")
+}
+
+func severityOf(finding *output.ResultEvent) string {
+	name := strings.ToLower(finding.Info.SeverityHolder.Severity.String())
+	if _, ok := severityWeight[name]; !ok {
+		return "unknown"
+	}
+	return name
+}
@@ -0,0 +1,258 @@
+package triage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/i18n"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+)
+
+// DefaultMaxFindings caps the findings included in a sampling prompt
+const DefaultMaxFindings = 50
+
+const systemPrompt = `You are a security triage assistant reviewing results of a Nuclei vulnerability scan.
+Respond in plain text with two sections:
+"Executive summary" - at most five sentences on overall exposure for a non-technical reader.
+"Prioritized next actions" - a numbered list, most urgent first, each naming the affected host and finding.
+Do not invent findings that are not listed.`
+
+// languageInstruction is appended to the system prompt for non-English reports
+const languageInstruction = `
+Write the whole response in %s, translating the section names as well.`
+
+// severityRank orders severities from most to least urgent
+var severityRank = map[string]int{
+	"critical": 0,
+	"high":     1,
+	"medium":   2,
+	"low":      3,
+	"info":     4,
+	"unknown":  5,
+}
+
+// Sampler requests LLM completions from the MCP client
+type Sampler interface {
+	CreateMessage(ctx context.Context, params mcp.CreateMessageParams) (*mcp.CreateMessageResult, error)
+}
+
+// Summary is a triage summary of scan findings
+type Summary struct {
+	// Source is "sampling" when the client's model wrote the summary and
+	// "heuristic" when it was generated by the server
+	Source        string         `json:"source"`
+	Model         string         `json:"model,omitempty"`
+	Text          string         `json:"summary"`
+	Targets       int            `json:"targets"`
+	Findings      int            `json:"findings"`
+	BySeverity    map[string]int `json:"by_severity"`
+	SamplingError string         `json:"sampling_error,omitempty"`
+}
+
+// Summarizer builds triage summaries, using client sampling when available
+type Summarizer struct {
+	sampler     Sampler
+	maxFindings int
+	localizer   *i18n.Localizer
+}
+
+// SummarizerOption configures a Summarizer
+type SummarizerOption func(*Summarizer)
+
+// WithLocalizer writes summaries in the localizer's language
+func WithLocalizer(localizer *i18n.Localizer) SummarizerOption {
+	return func(s *Summarizer) {
+		if localizer != nil {
+			s.localizer = localizer
+		}
+	}
+}
+
+// NewSummarizer creates a summarizer. A nil sampler always produces
+// heuristic summaries.
+func NewSummarizer(sampler Sampler, opts ...SummarizerOption) *Summarizer {
+	s := &Summarizer{sampler: sampler, maxFindings: DefaultMaxFindings, localizer: i18n.Default()}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Localized returns a copy of the summarizer writing in the localizer's
+// language
+func (s *Summarizer) Localized(localizer *i18n.Localizer) *Summarizer {
+	localized := *s
+	WithLocalizer(localizer)(&localized)
+	return &localized
+}
+
+// Summarize produces an executive summary and prioritized next actions for
+// the findings in the given results
+func (s *Summarizer) Summarize(ctx context.Context, results []cache.ScanResult, maxFindings int) (Summary, error) {
+	if maxFindings <= 0 {
+		maxFindings = s.maxFindings
+	}
+
+	findings, targets := collectFindings(results)
+	summary := Summary{
+		Targets:    targets,
+		Findings:   len(findings),
+		BySeverity: map[string]int{},
+	}
+	for _, finding := range findings {
+		summary.BySeverity[severityOf(finding)]++
+	}
+
+	if len(findings) == 0 {
+		summary.Source = "heuristic"
+		summary.Text = fmt.Sprintf("%s\n%s\n\n%s\n1. %s",
+			s.localizer.T("summary.executive"), s.localizer.T("summary.none", targets),
+			s.localizer.T("summary.actions"), s.localizer.T("summary.none_action"))
+		return summary, nil
+	}
+
+	if s.sampler != nil {
+		result, err := s.sampler.CreateMessage(ctx, mcp.CreateMessageParams{
+			Messages: []mcp.SamplingMessage{{
+				Role:    mcp.RoleUser,
+				Content: mcp.NewTextContent(prompt(findings, targets, maxFindings)),
+			}},
+			SystemPrompt: s.systemPrompt(),
+			MaxTokens:    1024,
+			Temperature:  0.2,
+		})
+		if err == nil {
+			if text := messageText(result.Content); text != "" {
+				summary.Source = "sampling"
+				summary.Model = result.Model
+				summary.Text = text
+				return summary, nil
+			}
+			err = fmt.Errorf("client returned no text")
+		}
+		summary.SamplingError = err.Error()
+	}
+
+	summary.Source = "heuristic"
+	summary.Text = heuristicSummary(s.localizer, findings, targets, summary.BySeverity)
+	return summary, nil
+}
+
+func (s *Summarizer) systemPrompt() string {
+	if s.localizer.Language() == i18n.English {
+		return systemPrompt
+	}
+	return systemPrompt + fmt.Sprintf(languageInstruction, s.localizer.LanguageName())
+}
+
+// collectFindings flattens and sorts findings by urgency, returning the
+// number of distinct targets
+func collectFindings(results []cache.ScanResult) ([]*output.ResultEvent, int) {
+	var findings []*output.ResultEvent
+	targets := map[string]struct{}{}
+	for _, result := range results {
+		targets[result.Target] = struct{}{}
+		for _, finding := range result.Findings {
+			if finding != nil {
+				findings = append(findings, finding)
+			}
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return severityRank[severityOf(findings[i])] < severityRank[severityOf(findings[j])]
+	})
+	return findings, len(targets)
+}
+
+func severityOf(finding *output.ResultEvent) string {
+	name := strings.ToLower(finding.Info.SeverityHolder.Severity.String())
+	if _, ok := severityRank[name]; !ok {
+		return "unknown"
+	}
+	return name
+}
+
+func prompt(findings []*output.ResultEvent, targets int, maxFindings int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Scan results: %d findings across %d targets.\n\n", len(findings), targets)
+	for i, finding := range findings {
+		if i == maxFindings {
+			fmt.Fprintf(&b, "... %d lower priority findings omitted\n", len(findings)-maxFindings)
+			break
+		}
+		fmt.Fprintf(&b, "- [%s] %s (%s) at %s", severityOf(finding), finding.Info.Name, finding.TemplateID, finding.Matched)
+		if finding.Info.Description != "" {
+			fmt.Fprintf(&b, ": %s", strings.TrimSpace(finding.Info.Description))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// messageText extracts the text of a sampled message, which is decoded from
+// JSON as a generic map
+func messageText(content any) string {
+	switch c := content.(type) {
+	case mcp.TextContent:
+		return strings.TrimSpace(c.Text)
+	case map[string]any:
+		if text, ok := c["text"].(string); ok {
+			return strings.TrimSpace(text)
+		}
+	case string:
+		return strings.TrimSpace(c)
+	}
+	return ""
+}
+
+func heuristicSummary(l *i18n.Localizer, findings []*output.ResultEvent, targets int, bySeverity map[string]int) string {
+	var counts []string
+	for _, name := range []string{"critical", "high", "medium", "low", "info", "unknown"} {
+		if bySeverity[name] > 0 {
+			counts = append(counts, fmt.Sprintf("%d %s", bySeverity[name], severityLabel(l, name)))
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(l.T("summary.executive") + "\n")
+	b.WriteString(l.T("summary.counts", len(findings), targets, strings.Join(counts, ", ")))
+	switch {
+	case bySeverity["critical"]+bySeverity["high"] > 0:
+		b.WriteString(" " + l.T("summary.urgent"))
+	case bySeverity["medium"] > 0:
+		b.WriteString(" " + l.T("summary.medium"))
+	default:
+		b.WriteString(" " + l.T("summary.low"))
+	}
+
+	b.WriteString("\n\n" + l.T("summary.actions") + "\n")
+	seen := map[string]bool{}
+	n := 0
+	for _, finding := range findings {
+		key := finding.TemplateID + "|" + finding.Host
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		n++
+		fmt.Fprintf(&b, "%d. [%s] %s\n", n, severityLabel(l, severityOf(finding)), l.T("summary.action", finding.Info.Name, finding.Host))
+		if n == 5 {
+			break
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// severityLabel keeps the lowercase severity names of English summaries and
+// uses the translated labels otherwise
+func severityLabel(l *i18n.Localizer, severity string) string {
+	if l.Language() == i18n.English {
+		return severity
+	}
+	return l.Severity(severity)
+}
@@ -0,0 +1,199 @@
+package wordlists
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Reference is the prefix of a payload value naming a wordlist, as in
+// "paths: wordlist:common-paths"
+const Reference = "wordlist:"
+
+// DefaultMaxEntries caps the entries of a wordlist
+const DefaultMaxEntries = 100000
+
+// extension is the file extension of stored wordlists
+const extension = ".txt"
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// referencePattern matches a payload variable whose value is a wordlist
+// reference, capturing the indentation and key, and the wordlist name
+var referencePattern = regexp.MustCompile(`(?m)^([ \t]*(?:-[ \t]+)?[A-Za-z0-9_-]+:)[ \t]*["']?` + Reference + `([A-Za-z0-9._-]+)["']?[ \t]*\r?$`)
+
+// Wordlist describes a stored wordlist
+type Wordlist struct {
+	Name      string    `json:"name"`
+	Entries   int       `json:"entries"`
+	Size      int64     `json:"size"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Store keeps wordlists as text files, one entry per line, in a directory
+type Store struct {
+	dir        string
+	maxEntries int
+}
+
+// New creates a store of the wordlists in dir holding up to maxEntries
+// entries each
+func New(dir string, maxEntries int) *Store {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	return &Store{dir: dir, maxEntries: maxEntries}
+}
+
+// Dir returns the directory of the wordlists
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Add stores entries as the wordlist name. Blank entries are dropped.
+// An existing wordlist is only replaced with overwrite.
+func (s *Store) Add(name string, entries []string, overwrite bool) (Wordlist, error) {
+	name = strings.TrimSuffix(strings.TrimSpace(name), extension)
+	if !namePattern.MatchString(name) {
+		return Wordlist{}, fmt.Errorf("invalid wordlist name %q: use letters, digits, '.', '_' and '-'", name)
+	}
+
+	var lines []string
+	for _, entry := range entries {
+		for _, line := range strings.Split(entry, "\n") {
+			if line = strings.TrimRight(line, "\r"); strings.TrimSpace(line) != "" {
+				lines = append(lines, line)
+			}
+		}
+	}
+	if len(lines) == 0 {
+		return Wordlist{}, fmt.Errorf("wordlist %s has no entries", name)
+	}
+	if len(lines) > s.maxEntries {
+		return Wordlist{}, fmt.Errorf("wordlist %s has %d entries, more than the limit of %d", name, len(lines), s.maxEntries)
+	}
+
+	path := s.path(name)
+	if _, err := os.Stat(path); err == nil && !overwrite {
+		return Wordlist{}, fmt.Errorf("wordlist %s already exists", name)
+	}
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return Wordlist{}, fmt.Errorf("failed to create wordlist directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		return Wordlist{}, fmt.Errorf("failed to write wordlist: %w", err)
+	}
+	return s.describe(name, len(lines))
+}
+
+// List returns the stored wordlists sorted by name
+func (s *Store) List() ([]Wordlist, error) {
+	files, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []Wordlist{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read wordlist directory: %w", err)
+	}
+
+	lists := []Wordlist{}
+	for _, file := range files {
+		name := strings.TrimSuffix(file.Name(), extension)
+		if file.IsDir() || !strings.HasSuffix(file.Name(), extension) || !namePattern.MatchString(name) {
+			continue
+		}
+		entries, err := s.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		list, err := s.describe(name, len(entries))
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, list)
+	}
+	sort.Slice(lists, func(i, j int) bool { return lists[i].Name < lists[j].Name })
+	return lists, nil
+}
+
+// Get returns the entries of the wordlist name
+func (s *Store) Get(name string) ([]string, error) {
+	name = strings.TrimSuffix(strings.TrimSpace(name), extension)
+	if !namePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid wordlist name %q", name)
+	}
+	data, err := os.ReadFile(s.path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("wordlist %s not found", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read wordlist %s: %w", name, err)
+	}
+
+	var entries []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimRight(line, "\r"); strings.TrimSpace(line) != "" {
+			entries = append(entries, line)
+		}
+	}
+	return entries, nil
+}
+
+// Resolve replaces the wordlist references of the payload variables in a
+// template with the entries of the wordlists, and returns the names of the
+// wordlists used. Payload variables otherwise name files, which templates
+// added through the server cannot read.
+func (s *Store) Resolve(template []byte) ([]byte, []string, error) {
+	var used []string
+	var resolveErr error
+	seen := map[string]bool{}
+
+	resolved := referencePattern.ReplaceAllFunc(template, func(match []byte) []byte {
+		groups := referencePattern.FindSubmatch(match)
+		key, name := string(groups[1]), string(groups[2])
+		entries, err := s.Get(name)
+		if err != nil {
+			if resolveErr == nil {
+				resolveErr = err
+			}
+			return match
+		}
+		if !seen[name] {
+			seen[name] = true
+			used = append(used, name)
+		}
+
+		// Entries are written as a flow sequence of JSON strings, which are
+		// valid YAML whatever characters they hold
+		quoted, err := json.Marshal(entries)
+		if err != nil {
+			if resolveErr == nil {
+				resolveErr = fmt.Errorf("failed to encode wordlist %s: %w", name, err)
+			}
+			return match
+		}
+		return []byte(key + " " + string(quoted))
+	})
+	if resolveErr != nil {
+		return nil, nil, resolveErr
+	}
+	return resolved, used, nil
+}
+
+func (s *Store) describe(name string, entries int) (Wordlist, error) {
+	info, err := os.Stat(s.path(name))
+	if err != nil {
+		return Wordlist{}, fmt.Errorf("failed to read wordlist %s: %w", name, err)
+	}
+	return Wordlist{Name: name, Entries: entries, Size: info.Size(), UpdatedAt: info.ModTime().UTC()}, nil
+}
+
+func (s *Store) path(name string) string {
+	return filepath.Join(s.dir, name+extension)
+}
@@ -0,0 +1,319 @@
+package workspace
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"nuclei-mcp/pkg/templates"
+)
+
+// BundleVersion is the format version written into every template bundle
+const BundleVersion = 1
+
+// BundleManifestEntry is the manifest of a template bundle. It has no
+// template extension so that nuclei, and LoadBundle when the bundle is
+// configured as an offline template bundle, skip it.
+const BundleManifestEntry = "bundle.manifest"
+
+// maxBundleEntry bounds the size of a single file read from a bundle
+const maxBundleEntry = 8 << 20
+
+// BundleManifest describes the templates of a template bundle
+type BundleManifest struct {
+	Version   int              `json:"version"`
+	CreatedAt time.Time        `json:"created_at"`
+	Templates []BundleTemplate `json:"templates"`
+}
+
+// BundleTemplate is the metadata of a template in a bundle
+type BundleTemplate struct {
+	// Name is the path of the template in the bundle
+	Name   string   `json:"name"`
+	ID     string   `json:"id"`
+	Tags   []string `json:"tags,omitempty"`
+	Format string   `json:"format"`
+	// Hash is the SHA-256 hash of the content, checked on import
+	Hash string `json:"hash"`
+	// Source is the template directory the template was exported from
+	Source string `json:"source"`
+	// Signed is set when the bundle has a detached minisign signature of
+	// the template
+	Signed bool `json:"signed,omitempty"`
+}
+
+// ImportOptions controls how a template bundle is imported
+type ImportOptions struct {
+	// Namespace is the directory of the custom templates directory the
+	// templates are imported into; the top level when empty
+	Namespace string
+	// Overwrite replaces existing templates with the same name
+	Overwrite bool
+	// Verifier checks the signature of every template. When verification
+	// is enforced, a bundle with unsigned or modified templates is
+	// rejected.
+	Verifier *templates.Verifier
+	// Check is called with every template before anything is imported; an
+	// error rejects the bundle
+	Check func(name string, content []byte) error
+}
+
+// ImportResult summarizes an imported template bundle
+type ImportResult struct {
+	Manifest BundleManifest `json:"manifest"`
+	Imported []string       `json:"imported"`
+	// Skipped are the templates left out because a template with the same
+	// name exists
+	Skipped []string `json:"skipped,omitempty"`
+	// Unverified are the templates failing signature verification
+	Unverified []string `json:"unverified,omitempty"`
+}
+
+// ExportTemplates writes the templates named by their qualified names, or
+// matching query, into a gzipped tar archive with a manifest and their
+// detached signatures, for another instance to import. With neither, every
+// custom template is exported. Bundles are never encrypted so they can be
+// exchanged between teams.
+func (ws *Workspace) ExportTemplates(archivePath string, names []string, query string) (BundleManifest, error) {
+	catalog, err := ws.templates.Catalog()
+	if err != nil {
+		return BundleManifest{}, fmt.Errorf("failed to list templates: %w", err)
+	}
+	wanted := map[string]bool{}
+	for _, name := range names {
+		wanted[name] = true
+	}
+	var selected []templates.TemplateInfo
+	for _, template := range catalog {
+		switch name := template.QualifiedName(); {
+		case wanted[name]:
+			delete(wanted, name)
+			if template.Status != templates.StatusValid {
+				return BundleManifest{}, fmt.Errorf("template %s is %s: %s", name, template.Status, template.Error)
+			}
+		case template.Status != templates.StatusValid || template.ShadowedBy != "":
+			continue
+		case query != "" && template.Matches(query),
+			len(names) == 0 && query == "" && template.Source == templates.CustomSource:
+		default:
+			continue
+		}
+		selected = append(selected, template)
+	}
+	if len(wanted) > 0 {
+		missing := make([]string, 0, len(wanted))
+		for name := range wanted {
+			missing = append(missing, name)
+		}
+		sort.Strings(missing)
+		return BundleManifest{}, fmt.Errorf("unknown templates: %s", strings.Join(missing, ", "))
+	}
+	if len(selected) == 0 {
+		return BundleManifest{}, fmt.Errorf("no valid templates to export")
+	}
+
+	manifest := BundleManifest{Version: BundleVersion, CreatedAt: time.Now()}
+	contents := map[string][]byte{}
+	for _, template := range selected {
+		if _, ok := contents[template.Name]; ok {
+			return BundleManifest{}, fmt.Errorf("templates of several sources are named %s", template.Name)
+		}
+		content, err := ws.templates.GetTemplate(template.QualifiedName())
+		if err != nil {
+			return BundleManifest{}, fmt.Errorf("failed to read template %s: %w", template.QualifiedName(), err)
+		}
+		contents[template.Name] = content
+		if signature, err := ws.templates.GetTemplate(template.QualifiedName() + templates.MinisignExtension); err == nil {
+			contents[template.Name+templates.MinisignExtension] = signature
+		}
+		sum := sha256.Sum256(content)
+		manifest.Templates = append(manifest.Templates, BundleTemplate{
+			Name:   template.Name,
+			ID:     template.ID,
+			Tags:   template.Tags,
+			Format: template.Format,
+			Hash:   hex.EncodeToString(sum[:]),
+			Source: template.Source,
+			Signed: contents[template.Name+templates.MinisignExtension] != nil,
+		})
+	}
+
+	archivePath, err = ws.Path(archivePath)
+	if err != nil {
+		return BundleManifest{}, err
+	}
+	if err := os.MkdirAll(filepath.Dir(archivePath), 0755); err != nil {
+		return BundleManifest{}, fmt.Errorf("failed to create bundle directory: %w", err)
+	}
+	file, err := os.Create(archivePath)
+	if err != nil {
+		return BundleManifest{}, fmt.Errorf("failed to create bundle: %w", err)
+	}
+	defer file.Close()
+
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	if err := writeJSONEntry(tw, BundleManifestEntry, manifest); err != nil {
+		return BundleManifest{}, err
+	}
+	for _, template := range manifest.Templates {
+		if err := writeEntry(tw, template.Name, contents[template.Name]); err != nil {
+			return BundleManifest{}, err
+		}
+		if template.Signed {
+			if err := writeEntry(tw, template.Name+templates.MinisignExtension, contents[template.Name+templates.MinisignExtension]); err != nil {
+				return BundleManifest{}, err
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return BundleManifest{}, fmt.Errorf("failed to finalize bundle: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return BundleManifest{}, fmt.Errorf("failed to finalize bundle: %w", err)
+	}
+	return manifest, nil
+}
+
+// ImportTemplates adds the templates of a bundle written by ExportTemplates
+// to the custom templates directory, with their signatures. Every template
+// must match the hash in the manifest and pass the checks of opts before
+// any is imported.
+func (ws *Workspace) ImportTemplates(archivePath string, opts ImportOptions) (ImportResult, error) {
+	if opts.Namespace != "" && !validEntryName(opts.Namespace) {
+		return ImportResult{}, fmt.Errorf("invalid namespace %q", opts.Namespace)
+	}
+	archivePath, err := ws.Path(archivePath)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return ImportResult{}, fmt.Errorf("failed to open bundle: %w", err)
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return ImportResult{}, fmt.Errorf("invalid template bundle: %w", err)
+	}
+	defer gz.Close()
+
+	var manifest BundleManifest
+	contents := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return ImportResult{}, fmt.Errorf("failed to read bundle: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if header.Size > maxBundleEntry {
+			return ImportResult{}, fmt.Errorf("invalid template bundle: %s is too large", header.Name)
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return ImportResult{}, fmt.Errorf("failed to read %s: %w", header.Name, err)
+		}
+		if header.Name == BundleManifestEntry {
+			if err := json.Unmarshal(content, &manifest); err != nil {
+				return ImportResult{}, fmt.Errorf("invalid bundle manifest: %w", err)
+			}
+			continue
+		}
+		contents[header.Name] = content
+	}
+
+	if manifest.Version == 0 {
+		return ImportResult{}, fmt.Errorf("invalid template bundle: missing manifest")
+	}
+	if manifest.Version > BundleVersion {
+		return ImportResult{}, fmt.Errorf("unsupported template bundle version %d", manifest.Version)
+	}
+
+	result := ImportResult{Manifest: manifest, Imported: []string{}}
+	for _, template := range manifest.Templates {
+		content, ok := contents[template.Name]
+		switch {
+		case !validEntryName(template.Name):
+			return ImportResult{}, fmt.Errorf("invalid template name in bundle: %s", template.Name)
+		case !ok:
+			return ImportResult{}, fmt.Errorf("invalid template bundle: %s is missing", template.Name)
+		}
+		if sum := sha256.Sum256(content); hex.EncodeToString(sum[:]) != template.Hash {
+			return ImportResult{}, fmt.Errorf("invalid template bundle: %s does not match its hash", template.Name)
+		}
+		if opts.Check != nil {
+			if err := opts.Check(template.Name, content); err != nil {
+				return ImportResult{}, fmt.Errorf("template %s rejected: %w", template.Name, err)
+			}
+		}
+		if opts.Verifier != nil {
+			if err := opts.Verifier.Verify(content, contents[template.Name+templates.MinisignExtension]); err != nil {
+				result.Unverified = append(result.Unverified, template.Name)
+			}
+		}
+	}
+	if opts.Verifier != nil && opts.Verifier.Enforced() && len(result.Unverified) > 0 {
+		return ImportResult{}, fmt.Errorf("bundle has templates failing %s signature verification: %s",
+			opts.Verifier.Mode(), strings.Join(result.Unverified, ", "))
+	}
+
+	existing := map[string]bool{}
+	if !opts.Overwrite {
+		names, err := ws.templates.ListTemplates()
+		if err != nil {
+			return ImportResult{}, fmt.Errorf("failed to list templates: %w", err)
+		}
+		for _, name := range names {
+			existing[name] = true
+		}
+	}
+	for _, template := range manifest.Templates {
+		name := path.Join(opts.Namespace, template.Name)
+		if existing[name] {
+			result.Skipped = append(result.Skipped, name)
+			continue
+		}
+		if err := ws.templates.AddTemplate(name, contents[template.Name]); err != nil {
+			return ImportResult{}, fmt.Errorf("failed to import template %s: %w", name, err)
+		}
+		if signature, ok := contents[template.Name+templates.MinisignExtension]; ok {
+			if err := ws.templates.AddTemplate(name+templates.MinisignExtension, signature); err != nil {
+				return ImportResult{}, fmt.Errorf("failed to import signature of %s: %w", name, err)
+			}
+		}
+		result.Imported = append(result.Imported, name)
+	}
+	return result, nil
+}
+
+// validEntryName reports whether name is a relative slash-separated path
+// that stays within the directory it is joined to
+func validEntryName(name string) bool {
+	if name == "" || path.IsAbs(name) || strings.Contains(name, `\`) || path.Clean(name) != name {
+		return false
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." || strings.HasPrefix(part, ".") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,446 @@
+package workspace
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/encryption"
+	"nuclei-mcp/pkg/exclusions"
+	"nuclei-mcp/pkg/schedule"
+	"nuclei-mcp/pkg/templates"
+	"nuclei-mcp/pkg/tracker"
+)
+
+// ArchiveVersion is the format version written into every workspace archive.
+const ArchiveVersion = 1
+
+const (
+	manifestEntry   = "manifest.json"
+	resultsEntry    = "results.json"
+	templatesEntry  = "templates/"
+	exclusionsEntry = "exclusions.yaml"
+	findingsEntry   = "findings.json"
+	schedulesEntry  = "schedules.json"
+)
+
+// ResultStore defines the cache operations needed to back up scan results
+type ResultStore interface {
+	Snapshot() map[string]cache.ScanResult
+	Set(key string, result cache.ScanResult)
+}
+
+// ExclusionStore defines the exclusion rule operations needed to back up
+// suppressions
+type ExclusionStore interface {
+	List() []exclusions.Rule
+	Export() ([]byte, error)
+	Import(data []byte, replace bool) (exclusions.ImportSummary, error)
+}
+
+// FindingStore defines the finding tracker operations needed to back up
+// finding statuses and risk acceptances
+type FindingStore interface {
+	List() []tracker.Record
+	Restore(records []tracker.Record, overwrite bool) (int, error)
+}
+
+// ScheduleStore defines the scheduled scan operations needed to back up
+// schedules. Restore schedules the scans not already scheduled and returns
+// how many it added.
+type ScheduleStore interface {
+	List() []schedule.Definition
+	Restore(definitions []schedule.Definition) (int, error)
+}
+
+// Manifest describes the contents of a workspace archive
+type Manifest struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Results   int       `json:"results"`
+	Templates int       `json:"templates"`
+	// Exclusions, TrackedFindings and Schedules count the exclusion rules,
+	// the findings with a status, such as accepted risks, and the scheduled
+	// scans in the archive
+	Exclusions      int `json:"exclusions"`
+	TrackedFindings int `json:"tracked_findings"`
+	Schedules       int `json:"schedules"`
+}
+
+// RestoreOptions controls how an archive is applied to the workspace
+type RestoreOptions struct {
+	// Overwrite replaces existing templates and cached results with the same name/key
+	Overwrite bool
+}
+
+// Workspace bundles the server state that can be exported and restored
+type Workspace struct {
+	results    ResultStore
+	templates  templates.TemplateManager
+	exclusions ExclusionStore
+	findings   FindingStore
+	schedules  ScheduleStore
+	key        *encryption.Key
+	exportDir  string
+}
+
+// Option configures a workspace
+type Option func(*Workspace)
+
+// WithEncryptionKey encrypts exported archives with key. Restoring accepts
+// encrypted and plain archives.
+func WithEncryptionKey(key *encryption.Key) Option {
+	return func(ws *Workspace) {
+		ws.key = key
+	}
+}
+
+// WithExclusions backs up the exclusion rules of store. Restored rules are
+// merged into the stored ones.
+func WithExclusions(store ExclusionStore) Option {
+	return func(ws *Workspace) {
+		ws.exclusions = store
+	}
+}
+
+// WithFindingTracker backs up the statuses and risk acceptances of the
+// findings tracked by store
+func WithFindingTracker(store FindingStore) Option {
+	return func(ws *Workspace) {
+		ws.findings = store
+	}
+}
+
+// WithSchedules backs up the scheduled scans of store. Restored scans are
+// added to the scheduled ones.
+func WithSchedules(store ScheduleStore) Option {
+	return func(ws *Workspace) {
+		ws.schedules = store
+	}
+}
+
+// WithExportDir keeps archives in dir instead of the working directory
+func WithExportDir(dir string) Option {
+	return func(ws *Workspace) {
+		ws.exportDir = dir
+	}
+}
+
+// NewWorkspace creates a new workspace over the given stores
+func NewWorkspace(results ResultStore, tm templates.TemplateManager, opts ...Option) *Workspace {
+	ws := &Workspace{
+		results:   results,
+		templates: tm,
+	}
+	for _, opt := range opts {
+		opt(ws)
+	}
+	return ws
+}
+
+// Export writes the workspace state as a gzipped tar archive to w, encrypted
+// when the workspace has an encryption key
+func (ws *Workspace) Export(w io.Writer) (Manifest, error) {
+	if ws.key == nil {
+		return ws.export(w)
+	}
+	var archive bytes.Buffer
+	manifest, err := ws.export(&archive)
+	if err != nil {
+		return Manifest{}, err
+	}
+	if _, err := w.Write(ws.key.Seal(archive.Bytes())); err != nil {
+		return Manifest{}, fmt.Errorf("failed to write archive: %w", err)
+	}
+	return manifest, nil
+}
+
+func (ws *Workspace) export(w io.Writer) (Manifest, error) {
+	results := ws.results.Snapshot()
+
+	catalog, err := ws.templates.Catalog()
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to list templates: %w", err)
+	}
+	// Only the custom templates belong to the workspace; other template
+	// sources are configured per instance
+	var names []string
+	for _, template := range catalog {
+		if template.Source == templates.CustomSource {
+			names = append(names, template.Name)
+		}
+	}
+
+	var rules []byte
+	var records []tracker.Record
+	var definitions []schedule.Definition
+	manifest := Manifest{
+		Version:   ArchiveVersion,
+		CreatedAt: time.Now(),
+		Results:   len(results),
+		Templates: len(names),
+	}
+	if ws.exclusions != nil {
+		if rules, err = ws.exclusions.Export(); err != nil {
+			return Manifest{}, err
+		}
+		manifest.Exclusions = len(ws.exclusions.List())
+	}
+	if ws.findings != nil {
+		records = ws.findings.List()
+		manifest.TrackedFindings = len(records)
+	}
+	if ws.schedules != nil {
+		definitions = ws.schedules.List()
+		manifest.Schedules = len(definitions)
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	if err := writeJSONEntry(tw, manifestEntry, manifest); err != nil {
+		return Manifest{}, err
+	}
+	if err := writeJSONEntry(tw, resultsEntry, results); err != nil {
+		return Manifest{}, err
+	}
+	if rules != nil {
+		if err := writeEntry(tw, exclusionsEntry, rules); err != nil {
+			return Manifest{}, err
+		}
+	}
+	if records != nil {
+		if err := writeJSONEntry(tw, findingsEntry, records); err != nil {
+			return Manifest{}, err
+		}
+	}
+	if definitions != nil {
+		if err := writeJSONEntry(tw, schedulesEntry, definitions); err != nil {
+			return Manifest{}, err
+		}
+	}
+
+	for _, name := range names {
+		content, err := ws.templates.GetTemplate(name)
+		if err != nil {
+			return Manifest{}, fmt.Errorf("failed to read template %s: %w", name, err)
+		}
+		if err := writeEntry(tw, templatesEntry+name, content); err != nil {
+			return Manifest{}, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return Manifest{}, fmt.Errorf("failed to finalize archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return Manifest{}, fmt.Errorf("failed to finalize archive: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// ExportFile writes the workspace archive to the given path
+func (ws *Workspace) ExportFile(archivePath string) (Manifest, error) {
+	archivePath, err := ws.Path(archivePath)
+	if err != nil {
+		return Manifest{}, err
+	}
+	if err := os.MkdirAll(filepath.Dir(archivePath), 0755); err != nil {
+		return Manifest{}, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	file, err := os.Create(archivePath)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer file.Close()
+
+	return ws.Export(file)
+}
+
+// Restore reads a workspace archive from r and applies it to the workspace.
+// Encrypted archives are decrypted with the workspace's encryption key.
+func (ws *Workspace) Restore(r io.Reader, opts RestoreOptions) (Manifest, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to read archive: %w", err)
+	}
+	if encryption.Sealed(data) {
+		if data, err = ws.key.Open(data); err != nil {
+			return Manifest{}, fmt.Errorf("invalid workspace archive: %w", err)
+		}
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return Manifest{}, fmt.Errorf("invalid workspace archive: %w", err)
+	}
+	defer gz.Close()
+
+	var manifest Manifest
+	var results map[string]cache.ScanResult
+	var rules []byte
+	var records []tracker.Record
+	var definitions []schedule.Definition
+	templateContents := make(map[string][]byte)
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Manifest{}, fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		switch {
+		case header.Name == manifestEntry:
+			if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+				return Manifest{}, fmt.Errorf("invalid manifest: %w", err)
+			}
+		case header.Name == resultsEntry:
+			if err := json.NewDecoder(tr).Decode(&results); err != nil {
+				return Manifest{}, fmt.Errorf("invalid results: %w", err)
+			}
+		case header.Name == exclusionsEntry:
+			if rules, err = io.ReadAll(tr); err != nil {
+				return Manifest{}, fmt.Errorf("failed to read exclusions: %w", err)
+			}
+		case header.Name == findingsEntry:
+			if err := json.NewDecoder(tr).Decode(&records); err != nil {
+				return Manifest{}, fmt.Errorf("invalid tracked findings: %w", err)
+			}
+		case header.Name == schedulesEntry:
+			if err := json.NewDecoder(tr).Decode(&definitions); err != nil {
+				return Manifest{}, fmt.Errorf("invalid scheduled scans: %w", err)
+			}
+		case strings.HasPrefix(header.Name, templatesEntry):
+			name := strings.TrimPrefix(header.Name, templatesEntry)
+			if name == "" || name != path.Base(name) {
+				return Manifest{}, fmt.Errorf("invalid template entry in archive: %s", header.Name)
+			}
+			content, err := io.ReadAll(tr)
+			if err != nil {
+				return Manifest{}, fmt.Errorf("failed to read template %s: %w", name, err)
+			}
+			templateContents[name] = content
+		}
+	}
+
+	if manifest.Version == 0 {
+		return Manifest{}, fmt.Errorf("invalid workspace archive: missing manifest")
+	}
+	if manifest.Version > ArchiveVersion {
+		return Manifest{}, fmt.Errorf("unsupported workspace archive version %d", manifest.Version)
+	}
+
+	existingTemplates := make(map[string]bool)
+	if !opts.Overwrite {
+		names, err := ws.templates.ListTemplates()
+		if err != nil {
+			return Manifest{}, fmt.Errorf("failed to list templates: %w", err)
+		}
+		for _, name := range names {
+			existingTemplates[name] = true
+		}
+	}
+
+	for name, content := range templateContents {
+		if existingTemplates[name] {
+			continue
+		}
+		if err := ws.templates.AddTemplate(name, content); err != nil {
+			return Manifest{}, fmt.Errorf("failed to restore template %s: %w", name, err)
+		}
+	}
+
+	existingResults := ws.results.Snapshot()
+	for key, result := range results {
+		if _, found := existingResults[key]; found && !opts.Overwrite {
+			continue
+		}
+		// Spill files are not archived; their findings are only counted
+		result.SpillFile, result.SpillFiles = "", nil
+		ws.results.Set(key, result)
+	}
+
+	// Archives of instances without these stores, or of earlier versions,
+	// have no such entries
+	if rules != nil && ws.exclusions != nil {
+		if _, err := ws.exclusions.Import(rules, false); err != nil {
+			return Manifest{}, fmt.Errorf("failed to restore exclusions: %w", err)
+		}
+	}
+	if records != nil && ws.findings != nil {
+		if _, err := ws.findings.Restore(records, opts.Overwrite); err != nil {
+			return Manifest{}, fmt.Errorf("failed to restore tracked findings: %w", err)
+		}
+	}
+	if definitions != nil && ws.schedules != nil {
+		if _, err := ws.schedules.Restore(definitions); err != nil {
+			return Manifest{}, fmt.Errorf("failed to restore scheduled scans: %w", err)
+		}
+	}
+
+	return manifest, nil
+}
+
+// RestoreFile restores the workspace from the archive at the given path
+func (ws *Workspace) RestoreFile(archivePath string, opts RestoreOptions) (Manifest, error) {
+	archivePath, err := ws.Path(archivePath)
+	if err != nil {
+		return Manifest{}, err
+	}
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer file.Close()
+
+	return ws.Restore(file, opts)
+}
+
+// Path returns the file an archive path refers to in the export directory.
+// Absolute paths and paths leaving the export directory are rejected, so
+// callers cannot read or write other files of the server.
+func (ws *Workspace) Path(archivePath string) (string, error) {
+	if !filepath.IsLocal(archivePath) {
+		return "", fmt.Errorf("invalid archive path %q, give a path inside the export directory", archivePath)
+	}
+	return filepath.Join(ws.exportDir, archivePath), nil
+}
+
+func writeJSONEntry(tw *tar.Writer, name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", name, err)
+	}
+	return writeEntry(tw, name, data)
+}
+
+func writeEntry(tw *tar.Writer, name string, data []byte) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// fragileTemplate requests many paths of a target that drops most
+// connections
+func fragileTemplate(paths int) string {
+	var template strings.Builder
+	template.WriteString("id: fragile-paths\ninfo:\n  name: Fragile Paths\n  author: nuclei-mcp\n  severity: info\nhttp:\n  - method: GET\n    path:\n")
+	for i := 0; i < paths; i++ {
+		fmt.Fprintf(&template, "      - \"{{BaseURL}}/page-%d\"\n", i)
+	}
+	template.WriteString("    matchers:\n      - type: word\n        words:\n          - fragile-marker\n")
+	return template.String()
+}
+
+func TestScannerService_AdaptiveTuning(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The target probe and the first page succeed
+		if r.URL.Path == "/" || r.URL.Path == "/page-0" {
+			_, _ = w.Write([]byte("fragile-marker"))
+			return
+		}
+		// Drop the connection without a response
+		if conn, _, err := w.(http.Hijacker).Hijack(); err == nil {
+			conn.Close()
+		}
+	}))
+	defer srv.Close()
+
+	templateFile := filepath.Join(t.TempDir(), "fragile.yaml")
+	assert.NoError(t, os.WriteFile(templateFile, []byte(fragileTemplate(6)), 0644))
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger,
+		scanner.WithAdaptiveTuning(true, scanner.AdaptiveTuning{ErrorRate: 0.5, Window: 5}),
+	)
+
+	target := srv.Listener.Addr().String()
+	result, err := service.Scan(target, "", "", nil, scanner.WithTemplateSources(templateFile), scanner.WithRateLimit(40))
+	assert.NoError(t, err)
+	if !assert.NotNil(t, result.Stats) || !assert.NotEmpty(t, result.Stats.Adjustments) {
+		return
+	}
+	assert.Greater(t, result.Stats.Errors, 0)
+	first := result.Stats.Adjustments[0]
+	assert.Equal(t, "127.0.0.1", first.Host)
+	assert.Equal(t, 20, first.RateLimit)
+	assert.Contains(t, first.Reason, "requests failed")
+	last := result.Stats.Adjustments[len(result.Stats.Adjustments)-1]
+
+	// A later scan of the host starts at the reduced settings
+	result, err = service.ThreadSafeScan(context.Background(), target, "info", "", nil, scanner.WithTemplateSources(templateFile))
+	assert.NoError(t, err)
+	if assert.NotNil(t, result.Stats) && assert.NotEmpty(t, result.Stats.Adjustments) {
+		assert.Equal(t, last, result.Stats.Adjustments[0])
+		assert.Positive(t, result.Stats.Requests)
+	}
+}
+
+func TestScannerService_AdaptiveTuningThreadSafe(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" || r.URL.Path == "/page-0" {
+			_, _ = w.Write([]byte("fragile-marker"))
+			return
+		}
+		if conn, _, err := w.(http.Hijacker).Hijack(); err == nil {
+			conn.Close()
+		}
+	}))
+	defer srv.Close()
+
+	templateFile := filepath.Join(t.TempDir(), "fragile.yaml")
+	assert.NoError(t, os.WriteFile(templateFile, []byte(fragileTemplate(6)), 0644))
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger,
+		scanner.WithAdaptiveTuning(true, scanner.AdaptiveTuning{ErrorRate: 0.5, Window: 5}),
+	)
+
+	// Thread-safe scans are monitored like standard ones
+	target := srv.Listener.Addr().String()
+	result, err := service.ThreadSafeScan(context.Background(), target, "", "", nil, scanner.WithTemplateSources(templateFile), scanner.WithRateLimit(40))
+	assert.NoError(t, err)
+	if !assert.NotNil(t, result.Stats) || !assert.NotEmpty(t, result.Stats.Adjustments) {
+		return
+	}
+	assert.Positive(t, result.Stats.Errors)
+	first := result.Stats.Adjustments[0]
+	assert.Equal(t, "127.0.0.1", first.Host)
+	assert.Equal(t, 20, first.RateLimit)
+	last := result.Stats.Adjustments[len(result.Stats.Adjustments)-1]
+
+	result, err = service.ThreadSafeScan(context.Background(), target, "info", "", nil, scanner.WithTemplateSources(templateFile))
+	assert.NoError(t, err)
+	if assert.NotNil(t, result.Stats) && assert.NotEmpty(t, result.Stats.Adjustments) {
+		assert.Equal(t, last, result.Stats.Adjustments[0])
+	}
+}
+
+func TestScannerService_AdaptiveTuningDisabled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("fragile-marker"))
+	}))
+	defer srv.Close()
+
+	templateFile := filepath.Join(t.TempDir(), "fragile.yaml")
+	assert.NoError(t, os.WriteFile(templateFile, []byte(fragileTemplate(1)), 0644))
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger)
+
+	result, err := service.ThreadSafeScan(context.Background(), srv.URL, "", "", nil, scanner.WithTemplateSources(templateFile))
+	assert.NoError(t, err)
+	assert.Len(t, result.Findings, 1)
+	if assert.NotNil(t, result.Stats) {
+		assert.Empty(t, result.Stats.Adjustments)
+	}
+}
@@ -2,14 +2,20 @@ package tests
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
 	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/bridge"
 	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/policy"
+	"nuclei-mcp/pkg/scanner"
+	"nuclei-mcp/pkg/templates"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/projectdiscovery/nuclei/v3/pkg/output"
@@ -32,14 +38,14 @@ func (m *MockScannerService) CreateCacheKey(target string, severity string, prot
 	return ""
 }
 
-func (m *MockScannerService) Scan(target string, severity string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+func (m *MockScannerService) Scan(target string, severity string, protocols string, templateIDs []string, opts ...scanner.ScanOption) (cache.ScanResult, error) {
 	if m.MockScan != nil {
 		return m.MockScan(target, severity, protocols, templateIDs)
 	}
 	return cache.ScanResult{}, fmt.Errorf("Scan not implemented")
 }
 
-func (m *MockScannerService) ThreadSafeScan(ctx context.Context, target string, severity string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+func (m *MockScannerService) ThreadSafeScan(ctx context.Context, target string, severity string, protocols string, templateIDs []string, opts ...scanner.ScanOption) (cache.ScanResult, error) {
 	if m.MockThreadSafeScan != nil {
 		return m.MockThreadSafeScan(ctx, target, severity, protocols, templateIDs)
 	}
@@ -65,6 +71,9 @@ type MockTemplateManager struct {
 	MockAddTemplate   func(name string, content []byte) error
 	MockListTemplates func() ([]string, error)
 	MockGetTemplate   func(name string) ([]byte, error)
+	MockCatalog       func() ([]templates.TemplateInfo, error)
+	MockHistory       func(name string) ([]templates.TemplateVersion, error)
+	MockGetVersion    func(name string, version int) ([]byte, error)
 }
 
 func (m *MockTemplateManager) AddTemplate(name string, content []byte) error {
@@ -88,6 +97,27 @@ func (m *MockTemplateManager) GetTemplate(name string) ([]byte, error) {
 	return []byte{}, fmt.Errorf("GetTemplate not implemented")
 }
 
+func (m *MockTemplateManager) Catalog() ([]templates.TemplateInfo, error) {
+	if m.MockCatalog != nil {
+		return m.MockCatalog()
+	}
+	return nil, fmt.Errorf("Catalog not implemented")
+}
+
+func (m *MockTemplateManager) History(name string) ([]templates.TemplateVersion, error) {
+	if m.MockHistory != nil {
+		return m.MockHistory(name)
+	}
+	return nil, fmt.Errorf("History not implemented")
+}
+
+func (m *MockTemplateManager) GetVersion(name string, version int) ([]byte, error) {
+	if m.MockGetVersion != nil {
+		return m.MockGetVersion(name, version)
+	}
+	return nil, fmt.Errorf("GetVersion not implemented")
+}
+
 func TestNewNucleiMCPServer(t *testing.T) {
 	mockScanner := &MockScannerService{}
 	mockTemplateManager := &MockTemplateManager{}
@@ -102,7 +132,7 @@ func TestHandleNucleiScanTool(t *testing.T) {
 	logger := log.New(os.Stdout, "test: ", log.LstdFlags)
 
 	mockScanner := &MockScannerService{
-		MockScan: func(target string, severity string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+		MockThreadSafeScan: func(ctx context.Context, target string, severity string, protocols string, templateIDs []string) (cache.ScanResult, error) {
 			// Return a simple result without trying to mock complex nuclei types
 			return cache.ScanResult{
 				Target:   target,
@@ -122,11 +152,36 @@ func TestHandleNucleiScanTool(t *testing.T) {
 		},
 	}
 
-	result, err := api.HandleNucleiScanTool(ctx, request, mockScanner, logger)
+	result, err := api.HandleNucleiScanTool(ctx, request, mockScanner, logger, nil, api.DefaultScanDefaults)
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
 }
 
+func TestHandleNucleiScanTool_ThreadSafeDefault(t *testing.T) {
+	var engines []string
+	mockScanner := &MockScannerService{
+		MockScan: func(target string, severity string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+			engines = append(engines, "standard")
+			return cache.ScanResult{Target: target, ScanTime: time.Now()}, nil
+		},
+		MockThreadSafeScan: func(ctx context.Context, target string, severity string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+			engines = append(engines, "thread-safe")
+			return cache.ScanResult{Target: target, ScanTime: time.Now()}, nil
+		},
+	}
+	logger := log.New(os.Stdout, "test: ", log.LstdFlags)
+
+	for _, arguments := range []map[string]any{
+		{"target": "example.com"},
+		{"target": "example.com", "thread_safe": true},
+		{"target": "example.com", "thread_safe": false},
+	} {
+		_, err := api.HandleNucleiScanTool(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: arguments}}, mockScanner, logger, nil, api.DefaultScanDefaults)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, []string{"thread-safe", "thread-safe", "standard"}, engines)
+}
+
 func TestHandleBasicScanTool(t *testing.T) {
 	ctx := context.Background()
 	logger := log.New(os.Stdout, "test: ", log.LstdFlags)
@@ -189,12 +244,12 @@ func TestHandleAddTemplate(t *testing.T) {
 		Params: mcp.CallToolParams{
 			Arguments: map[string]interface{}{
 				"name":    "test-template.yaml",
-				"content": "template content",
+				"content": "id: test-template\ninfo:\n  name: Test Template\n  tags: cve\n",
 			},
 		},
 	}
 
-	result, err := api.HandleAddTemplate(ctx, request, mockTemplateManager)
+	result, err := api.HandleAddTemplate(ctx, request, mockTemplateManager, policy.NewTemplatePolicy(policy.DefaultDeniedTags), nil, nil)
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
 }
@@ -236,3 +291,253 @@ func TestHandleGetTemplate(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
 }
+
+func TestHandleTestTemplate(t *testing.T) {
+	ctx := context.Background()
+	mockTemplateManager := &MockTemplateManager{
+		MockGetTemplate: func(name string) ([]byte, error) {
+			return []byte(sandboxTemplate), nil
+		},
+	}
+
+	request := mcp.CallToolRequest{
+		Params: mcp.CallToolParams{
+			Arguments: map[string]interface{}{
+				"name": "sandbox-version.yaml",
+				"responses": []interface{}{
+					map[string]interface{}{"path": "/version", "body": "version=2.0"},
+				},
+			},
+		},
+	}
+
+	result, err := api.HandleTestTemplate(ctx, request, mockTemplateManager, nil)
+	assert.NoError(t, err)
+	assert.NotNil(t, result)
+	assert.Contains(t, result.Content[0].(mcp.TextContent).Text, `"matched":true`)
+
+	_, err = api.HandleTestTemplate(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: map[string]interface{}{}}}, mockTemplateManager, nil)
+	assert.Error(t, err)
+}
+
+func TestHandleAddTemplate_DeniedByPolicy(t *testing.T) {
+	ctx := context.Background()
+	added := false
+	mockTemplateManager := &MockTemplateManager{
+		MockAddTemplate: func(name string, content []byte) error {
+			added = true
+			return nil
+		},
+	}
+	templatePolicy := policy.NewTemplatePolicy(policy.DefaultDeniedTags)
+	content := "id: slowloris\ninfo:\n  name: Slowloris\n  tags: dos,network\n"
+
+	request := mcp.CallToolRequest{
+		Params: mcp.CallToolParams{
+			Arguments: map[string]interface{}{
+				"name":    "slowloris.yaml",
+				"content": content,
+			},
+		},
+	}
+	_, err := api.HandleAddTemplate(ctx, request, mockTemplateManager, templatePolicy, nil, nil)
+	assert.Error(t, err)
+	assert.False(t, added)
+
+	request.Params.Arguments = map[string]interface{}{
+		"name":         "slowloris.yaml",
+		"content":      content,
+		"allow_unsafe": true,
+	}
+	_, err = api.HandleAddTemplate(ctx, request, mockTemplateManager, templatePolicy, nil, nil)
+	assert.Error(t, err, "allow_unsafe without approval must be rejected")
+	assert.False(t, added)
+
+	request.Params.Arguments = map[string]interface{}{
+		"name":         "slowloris.yaml",
+		"content":      content,
+		"allow_unsafe": true,
+		"approval":     "SEC-42 approved by the security lead",
+	}
+	_, err = api.HandleAddTemplate(ctx, request, mockTemplateManager, templatePolicy, nil, nil)
+	assert.NoError(t, err)
+	assert.True(t, added)
+}
+
+func TestNucleiMCPServer_ScopeRoots(t *testing.T) {
+	scanned := false
+	mockScanner := &MockScannerService{
+		MockThreadSafeScan: func(ctx context.Context, target string, severity string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+			scanned = true
+			return cache.ScanResult{Target: target, ScanTime: time.Now()}, nil
+		},
+	}
+	roots := func(ctx context.Context) []mcp.Root {
+		return []mcp.Root{{URI: "https://app.example.com"}}
+	}
+	mcpServer := api.NewNucleiMCPServer(mockScanner, log.New(os.Stdout, "test: ", log.LstdFlags), &MockTemplateManager{},
+		api.WithScopeRoots(roots))
+
+	call := func(arguments map[string]any) string {
+		data, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": "tools/call",
+			"params": map[string]any{"name": "nuclei_scan", "arguments": arguments}})
+		assert.NoError(t, err)
+		response, err := json.Marshal(mcpServer.HandleMessage(context.Background(), data))
+		assert.NoError(t, err)
+		return string(response)
+	}
+
+	assert.Contains(t, call(map[string]any{"target": "https://other.example.org"}), "outside the scan scope")
+	assert.False(t, scanned)
+
+	call(map[string]any{"target": "https://app.example.com/login"})
+	assert.True(t, scanned)
+
+	scanned = false
+	call(map[string]any{"target": "https://other.example.org", "allow_out_of_scope": true, "approval": "SOW-12"})
+	assert.True(t, scanned)
+}
+
+func TestNucleiMCPServer_ScanTargets(t *testing.T) {
+	var scanned []string
+	var mu sync.Mutex
+	mockScanner := &MockScannerService{
+		MockThreadSafeScan: func(ctx context.Context, target string, severity string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+			mu.Lock()
+			scanned = append(scanned, target)
+			mu.Unlock()
+			return cache.ScanResult{Target: target, ScanTime: time.Now()}, nil
+		},
+	}
+	roots := func(ctx context.Context) []mcp.Root {
+		return []mcp.Root{{URI: "https://app.example.com"}}
+	}
+	mcpServer := api.NewNucleiMCPServer(mockScanner, log.New(os.Stdout, "test: ", log.LstdFlags), &MockTemplateManager{},
+		api.WithScopeRoots(roots), api.WithScanConcurrency(scanner.Concurrency{Host: 1, Global: 4}))
+
+	call := func(targets ...any) string {
+		data, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": "tools/call",
+			"params": map[string]any{"name": "nuclei_scan_targets", "arguments": map[string]any{"targets": targets}}})
+		assert.NoError(t, err)
+		response, err := json.Marshal(mcpServer.HandleMessage(context.Background(), data))
+		assert.NoError(t, err)
+		return string(response)
+	}
+
+	assert.Contains(t, call("https://app.example.com/a", "https://other.example.org"), "outside the scan scope")
+	assert.Empty(t, scanned, "one out-of-scope target refuses the whole scan")
+
+	response := call("https://app.example.com/a", "https://app.example.com/b")
+	assert.Contains(t, response, "Scanned 2 targets on 1 hosts (0 failed)")
+	assert.Contains(t, response, "1 per host, 4 overall")
+	assert.ElementsMatch(t, []string{"https://app.example.com/a", "https://app.example.com/b"}, scanned)
+
+	assert.Contains(t, call(), "invalid or missing targets parameter")
+}
+
+func TestErrorCodeOf(t *testing.T) {
+	egress, err := policy.NewEgressPolicy(policy.EgressOptions{DenyPrivate: true})
+	assert.NoError(t, err)
+
+	assert.Equal(t, api.CodeScopeDenied, api.ErrorCodeOf(egress.CheckTarget(context.Background(), "http://127.0.0.1")))
+	assert.Equal(t, api.CodeScopeDenied, api.ErrorCodeOf(policy.NewScope([]string{"https://app.example.com"}).Check("https://other.example.org", policy.Override{})))
+	assert.Equal(t, api.CodeTargetInvalid, api.ErrorCodeOf(egress.CheckTarget(context.Background(), "http://")))
+	assert.Equal(t, api.CodeTargetInvalid, api.ErrorCodeOf(fmt.Errorf("scan: %w", scanner.ErrNoTargets)))
+	assert.Equal(t, api.CodeTemplatesNotFound, api.ErrorCodeOf(scanner.ErrNoTemplates))
+	assert.Equal(t, api.CodeTimeout, api.ErrorCodeOf(fmt.Errorf("%w after 2m", scanner.ErrEngineTimeout)))
+	assert.Equal(t, api.CodeTimeout, api.ErrorCodeOf(context.DeadlineExceeded))
+	assert.Equal(t, api.CodeEngineInitFailed, api.ErrorCodeOf(fmt.Errorf("%w: boom", scanner.ErrEngineInit)))
+	assert.Equal(t, api.CodeRateLimited, api.ErrorCodeOf(fmt.Errorf("%w: try later", api.ErrRateLimited)))
+	assert.Equal(t, api.CodeScanFailed, api.ErrorCodeOf(fmt.Errorf("boom")))
+}
+
+func TestNucleiMCPServer_StructuredErrors(t *testing.T) {
+	mockScanner := &MockScannerService{
+		MockThreadSafeScan: func(ctx context.Context, target string, severity string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+			return cache.ScanResult{}, scanner.ErrNoTemplates
+		},
+	}
+	mcpServer := api.NewNucleiMCPServer(mockScanner, log.New(os.Stdout, "test: ", log.LstdFlags), &MockTemplateManager{})
+
+	call := func(arguments map[string]any) api.ToolError {
+		data, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": "tools/call",
+			"params": map[string]any{"name": "nuclei_scan", "arguments": arguments}})
+		assert.NoError(t, err)
+		response, err := json.Marshal(mcpServer.HandleMessage(context.Background(), data))
+		assert.NoError(t, err)
+		var decoded struct {
+			Result struct {
+				IsError bool `json:"isError"`
+				Content []struct {
+					Text string `json:"text"`
+				} `json:"content"`
+			} `json:"result"`
+		}
+		assert.NoError(t, json.Unmarshal(response, &decoded))
+		assert.True(t, decoded.Result.IsError, "tool errors are returned as error results")
+		var toolError api.ToolError
+		if assert.Len(t, decoded.Result.Content, 1) {
+			assert.NoError(t, json.Unmarshal([]byte(decoded.Result.Content[0].Text), &toolError))
+		}
+		return toolError
+	}
+
+	toolError := call(map[string]any{"target": "https://example.com"})
+	assert.Equal(t, api.CodeTemplatesNotFound, toolError.Code)
+	assert.Equal(t, scanner.ErrNoTemplates.Error(), toolError.Message)
+
+	assert.Equal(t, api.CodeTargetInvalid, call(map[string]any{}).Code)
+}
+
+type fakeElicitor struct {
+	result *bridge.ElicitationResult
+	err    error
+	asked  int
+}
+
+func (f *fakeElicitor) Elicit(_ context.Context, _ string, _ map[string]any) (*bridge.ElicitationResult, error) {
+	f.asked++
+	return f.result, f.err
+}
+
+func TestNucleiMCPServer_ElicitsTargetScheme(t *testing.T) {
+	var scannedTarget string
+	mockScanner := &MockScannerService{
+		MockThreadSafeScan: func(ctx context.Context, target string, severity string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+			scannedTarget = target
+			return cache.ScanResult{Target: target, ScanTime: time.Now()}, nil
+		},
+	}
+
+	call := func(elicitor *fakeElicitor, target string) string {
+		mcpServer := api.NewNucleiMCPServer(mockScanner, log.New(os.Stdout, "test: ", log.LstdFlags), &MockTemplateManager{},
+			api.WithElicitor(elicitor))
+		data, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": "tools/call",
+			"params": map[string]any{"name": "nuclei_scan", "arguments": map[string]any{"target": target}}})
+		assert.NoError(t, err)
+		response, err := json.Marshal(mcpServer.HandleMessage(context.Background(), data))
+		assert.NoError(t, err)
+		return string(response)
+	}
+
+	accept := &fakeElicitor{result: &bridge.ElicitationResult{Action: "accept", Content: map[string]any{"scheme": "http"}}}
+	call(accept, "example.com")
+	assert.Equal(t, "http://example.com", scannedTarget)
+
+	call(accept, "https://example.com")
+	assert.Equal(t, "https://example.com", scannedTarget)
+	assert.Equal(t, 1, accept.asked, "targets with a scheme are not ambiguous")
+
+	none := &fakeElicitor{result: &bridge.ElicitationResult{Action: "accept", Content: map[string]any{"scheme": "none"}}}
+	call(none, "10.0.0.1")
+	assert.Equal(t, "10.0.0.1", scannedTarget)
+
+	scannedTarget = ""
+	declined := &fakeElicitor{result: &bridge.ElicitationResult{Action: "decline"}}
+	assert.Contains(t, call(declined, "example.com"), "not confirmed by the user (decline)")
+	assert.Empty(t, scannedTarget)
+
+	unsupported := &fakeElicitor{err: fmt.Errorf("elicitation: %w", bridge.ErrUnsupported)}
+	call(unsupported, "example.com")
+	assert.Equal(t, "example.com", scannedTarget)
+}
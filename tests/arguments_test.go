@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleNucleiScanTool_ArrayArguments(t *testing.T) {
+	var gotProtocols string
+	var gotTemplateIDs []string
+	mockScanner := &MockScannerService{
+		MockThreadSafeScan: func(ctx context.Context, target string, severity string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+			gotProtocols, gotTemplateIDs = protocols, templateIDs
+			return cache.ScanResult{Target: target, ScanTime: time.Now()}, nil
+		},
+	}
+	logger := log.New(os.Stdout, "test: ", log.LstdFlags)
+	call := func(arguments map[string]any) {
+		_, err := api.HandleNucleiScanTool(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: arguments}}, mockScanner, logger, nil, api.DefaultScanDefaults)
+		assert.NoError(t, err)
+	}
+
+	call(map[string]any{
+		"target":       "example.com",
+		"protocols":    []any{"dns", "ssl"},
+		"template_ids": []any{"self-signed-ssl", " ", "nameserver-fingerprint"},
+	})
+	assert.Equal(t, "dns,ssl", gotProtocols)
+	assert.Equal(t, []string{"self-signed-ssl", "nameserver-fingerprint"}, gotTemplateIDs)
+
+	// Comma-separated strings are still accepted
+	call(map[string]any{
+		"target":       "example.com",
+		"protocols":    "dns,ssl",
+		"template_ids": "self-signed-ssl, nameserver-fingerprint",
+	})
+	assert.Equal(t, "dns,ssl", gotProtocols)
+	assert.Equal(t, []string{"self-signed-ssl", "nameserver-fingerprint"}, gotTemplateIDs)
+}
+
+func TestNucleiMCPServer_ArraySchemas(t *testing.T) {
+	mcpServer := api.NewNucleiMCPServer(&MockScannerService{}, log.New(os.Stdout, "test: ", log.LstdFlags), &MockTemplateManager{})
+
+	response, err := json.Marshal(mcpServer.HandleMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)))
+	assert.NoError(t, err)
+	var decoded struct {
+		Result struct {
+			Tools []struct {
+				Name        string `json:"name"`
+				InputSchema struct {
+					Properties map[string]struct {
+						Type  string         `json:"type"`
+						Items map[string]any `json:"items"`
+					} `json:"properties"`
+				} `json:"inputSchema"`
+			} `json:"tools"`
+		} `json:"result"`
+	}
+	assert.NoError(t, json.Unmarshal(response, &decoded))
+
+	arrays := map[string][]string{
+		"nuclei_scan":         {"protocols", "tags", "template_ids"},
+		"nuclei_scan_targets": {"targets", "protocols", "tags", "template_ids"},
+	}
+	checked := 0
+	for _, tool := range decoded.Result.Tools {
+		for _, name := range arrays[tool.Name] {
+			property := tool.InputSchema.Properties[name]
+			assert.Equal(t, "array", property.Type, "%s.%s", tool.Name, name)
+			assert.Equal(t, "string", property.Items["type"], "%s.%s", tool.Name, name)
+			checked++
+		}
+	}
+	assert.Equal(t, 7, checked)
+}
@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/assets"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+type reconResolver struct {
+	addrs map[string][]net.IPAddr
+	names map[string][]string
+}
+
+func (r reconResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	if addrs, ok := r.addrs[host]; ok {
+		return addrs, nil
+	}
+	return nil, fmt.Errorf("no such host %s", host)
+}
+
+func (r reconResolver) LookupAddr(_ context.Context, addr string) ([]string, error) {
+	if names, ok := r.names[addr]; ok {
+		return names, nil
+	}
+	return nil, fmt.Errorf("no PTR record for %s", addr)
+}
+
+func TestCollector_Collect(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	ctLog := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "%.127.0.0.1", r.URL.Query().Get("q"))
+	}))
+	defer ctLog.Close()
+
+	collector := assets.NewCollector(assets.WithCTLogURL(ctLog.URL), assets.WithTimeout(5*time.Second), assets.WithResolver(reconResolver{
+		names: map[string][]string{"127.0.0.1": {"localhost."}},
+	}))
+
+	asset := collector.Collect(context.Background(), srv.URL, true)
+	assert.Equal(t, "127.0.0.1", asset.Host)
+	assert.Empty(t, asset.Errors)
+	assert.Equal(t, []assets.Address{{IP: "127.0.0.1", ReverseDNS: []string{"localhost"}}}, asset.Addresses)
+	if assert.NotNil(t, asset.Certificate) {
+		assert.Contains(t, asset.Certificate.SANs, "example.com")
+		assert.Contains(t, asset.Certificate.SANs, "127.0.0.1")
+	}
+	assert.Nil(t, asset.CTCheckedAt, "certificate transparency is not queried for IP addresses")
+
+	asset = collector.Collect(context.Background(), "gone.example.com", false)
+	assert.Contains(t, asset.Errors["dns"], "no such host")
+	assert.Contains(t, asset.Errors, "tls")
+}
+
+func TestHandleTargetContext(t *testing.T) {
+	ctLog := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "%.example.com", r.URL.Query().Get("q"))
+		_, _ = w.Write([]byte(`[{"name_value":"*.example.com\nexample.com"},{"name_value":"API.example.com"},{"name_value":"example.org"}]`))
+	}))
+	defer ctLog.Close()
+
+	path := filepath.Join(t.TempDir(), "assets.json")
+	registry, err := assets.Open(path)
+	assert.NoError(t, err)
+	collector := assets.NewCollector(assets.WithCTLogURL(ctLog.URL), assets.WithTimeout(time.Second), assets.WithResolver(reconResolver{
+		addrs: map[string][]net.IPAddr{"example.com": {{IP: net.ParseIP("127.0.0.1")}}},
+	}))
+
+	call := func(args map[string]any) assets.Asset {
+		result, err := api.HandleTargetContext(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: args}}, registry, collector)
+		assert.NoError(t, err)
+		var asset assets.Asset
+		assert.NoError(t, json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &asset))
+		return asset
+	}
+
+	asset := call(map[string]any{"target": "https://Example.com/login", "certificate_transparency": true})
+	assert.Equal(t, "example.com", asset.Host)
+	assert.Equal(t, []assets.Address{{IP: "127.0.0.1"}}, asset.Addresses)
+	assert.Equal(t, []string{"api.example.com", "example.com"}, asset.CTNames)
+	assert.NotNil(t, asset.CTCheckedAt)
+	assert.Contains(t, asset.Errors, "tls", "nothing serves TLS on the resolved host's port 443")
+
+	asset = call(map[string]any{"target": "example.com"})
+	assert.Equal(t, []string{"api.example.com", "example.com"}, asset.CTNames, "names are kept when not looked up again")
+
+	reopened, err := assets.Open(path)
+	assert.NoError(t, err)
+	stored, ok := reopened.Get("example.com")
+	assert.True(t, ok)
+	assert.Equal(t, asset.CTNames, stored.CTNames)
+
+	_, err = api.HandleTargetContext(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: map[string]any{"target": " "}}}, registry, collector)
+	assert.True(t, err != nil && strings.Contains(err.Error(), "target"))
+}
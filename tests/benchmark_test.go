@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/benchmark"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/policy"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestBenchmark_Run(t *testing.T) {
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger,
+		scanner.WithPassiveByDefault(true))
+
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]any{"concurrency": []any{1.0, 3.0}, "rate_limits": []any{0.0, 100.0}, "scans": 3.0}
+	result, err := api.HandleBenchmark(context.Background(), request, service)
+	assert.NoError(t, err)
+
+	var report benchmark.Report
+	assert.NoError(t, json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &report))
+	assert.Len(t, report.Measurements, 4)
+	for _, m := range report.Measurements {
+		assert.Zero(t, m.Failed, m.Error)
+		assert.Equal(t, 3, m.Scans)
+		assert.Equal(t, 60, m.Requests, "each scan requests the 20 benchmark pages")
+		assert.Positive(t, m.RequestsPerSecond)
+		assert.NotEmpty(t, m.Latency.P50)
+	}
+	assert.Equal(t, benchmark.Setting{Concurrency: 3, RateLimit: 100}, report.Measurements[3].Setting)
+	assert.NotNil(t, report.Best)
+
+	request.Params.Arguments = map[string]any{"concurrency": []any{0.0}}
+	_, err = api.HandleBenchmark(context.Background(), request, service)
+	assert.ErrorContains(t, err, "concurrency must be at least 1")
+	request.Params.Arguments = map[string]any{"rate_limits": []any{1.5}}
+	_, err = api.HandleBenchmark(context.Background(), request, service)
+	assert.ErrorContains(t, err, "invalid rate_limits parameter")
+}
+
+func TestBenchmark_EgressDenied(t *testing.T) {
+	egress, err := policy.NewEgressPolicy(policy.EgressOptions{DenyPrivate: true})
+	assert.NoError(t, err)
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	service := scanner.NewScannerService(new(MockResultCache), mockLogger, scanner.WithEgressPolicy(egress))
+
+	report, err := benchmark.Run(context.Background(), service, benchmark.Options{Concurrency: []int{1}, Scans: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Measurements[0].Failed)
+	assert.Contains(t, report.Measurements[0].Error, "policy.egress.deny_private")
+	assert.Nil(t, report.Best)
+}
@@ -0,0 +1,238 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/schedule"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const yamlBlackoutCalendar = `timezone: UTC
+blackouts:
+  - name: Year-end freeze
+    start: 2026-12-21
+    end: 2027-01-03
+  - name: Release window
+    start: "2026-01-08T16:00"
+    end: "2026-01-08T20:00"
+    every: weekly
+    until: 2026-12-31
+`
+
+const icalBlackoutCalendar = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Deploy freeze\\, Q4\r\n" +
+	"DTSTART:20261102T000000Z\r\n" +
+	"DTEND:20261104T000000Z\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"DURATION:PT15M\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Christmas\r\n" +
+	"DTSTART;VALUE=DATE:20261225\r\n" +
+	"RRULE:FREQ=YEARLY;BYMONTH=12;BYMONTHDAY=25\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Maintenance\r\n" +
+	"DTSTART;TZID=Europe/Berlin:20260105T220000\r\n" +
+	"DURATION:PT2H\r\n" +
+	"RRULE:FREQ=WEEKLY;BYDAY=MO;COUNT=2\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Called off\r\n" +
+	"STATUS:CANCELLED\r\n" +
+	"DTSTART;VALUE=DATE:20260301\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func utc(value string) time.Time {
+	t, _ := time.Parse(time.RFC3339, value)
+	return t
+}
+
+func TestParseCalendar_YAML(t *testing.T) {
+	blackouts, err := schedule.ParseCalendar([]byte(yamlBlackoutCalendar))
+	assert.NoError(t, err)
+	calendar := schedule.NewCalendar(blackouts...)
+
+	// Dates cover whole days, the end date included
+	blackout, active := calendar.Active(utc("2027-01-03T23:59:00Z"))
+	assert.True(t, active)
+	assert.Equal(t, "Year-end freeze", blackout.Name)
+	_, active = calendar.Active(utc("2027-01-04T00:00:00Z"))
+	assert.False(t, active)
+
+	// Repeating blackouts keep their time of day until their end
+	_, active = calendar.Active(utc("2026-03-05T17:00:00Z"))
+	assert.True(t, active)
+	_, active = calendar.Active(utc("2026-03-05T20:00:00Z"))
+	assert.False(t, active)
+	_, active = calendar.Active(utc("2027-01-07T17:00:00Z"))
+	assert.False(t, active)
+
+	for _, invalid := range []string{
+		"blackouts:\n  - start: 2026-01-01T10:00:00Z\n",
+		"blackouts:\n  - start: 2026-01-02\n    end: 2026-01-01\n",
+		"blackouts:\n  - start: 2026-01-01\n    every: hourly\n",
+		"blackouts:\n  - start: tomorrow\n",
+		"timezone: Mars/Olympus\nblackouts: []\n",
+	} {
+		_, err := schedule.ParseCalendar([]byte(invalid))
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestParseCalendar_ICal(t *testing.T) {
+	blackouts, err := schedule.ParseCalendar([]byte(icalBlackoutCalendar))
+	assert.NoError(t, err)
+	assert.Len(t, blackouts, 3, "cancelled events are left out")
+	calendar := schedule.NewCalendar(blackouts...)
+
+	blackout, active := calendar.Active(utc("2026-11-03T12:00:00Z"))
+	assert.True(t, active)
+	assert.Equal(t, "Deploy freeze, Q4", blackout.Name)
+	assert.Equal(t, utc("2026-11-04T00:00:00Z"), blackout.End.UTC())
+
+	_, active = calendar.Active(time.Date(2030, 12, 25, 12, 0, 0, 0, time.Local))
+	assert.True(t, active, "yearly events repeat")
+
+	// 22:00 in Berlin is 21:00 UTC in winter; the rule ends after two weeks
+	_, active = calendar.Active(utc("2026-01-12T21:30:00Z"))
+	assert.True(t, active)
+	_, active = calendar.Active(utc("2026-01-19T21:30:00Z"))
+	assert.False(t, active)
+
+	_, err = schedule.ParseCalendar([]byte("BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART:20260105T100000Z\r\nDTEND:20260105T110000Z\r\nRRULE:FREQ=WEEKLY;BYDAY=MO,TU\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"))
+	assert.ErrorContains(t, err, "unsupported recurrence rule part")
+}
+
+func TestCalendar_Window(t *testing.T) {
+	calendar := schedule.NewCalendar(
+		schedule.Blackout{Name: "freeze", Start: utc("2026-06-01T00:00:00Z"), End: utc("2026-06-03T00:00:00Z")},
+		schedule.Blackout{Name: "nightly", Start: utc("2026-01-01T22:00:00Z"), End: utc("2026-01-02T02:00:00Z"), Every: schedule.Daily},
+	)
+
+	// Overlapping blackouts are skipped to the end of the last
+	window, err := calendar.Window(utc("2026-06-02T12:00:00Z"), 0)
+	assert.NoError(t, err)
+	if assert.NotNil(t, window.Blackout) {
+		assert.Equal(t, "freeze", window.Blackout.Name)
+	}
+	assert.Equal(t, utc("2026-06-03T02:00:00Z"), window.Start)
+	if assert.NotNil(t, window.End) {
+		assert.Equal(t, utc("2026-06-03T22:00:00Z"), *window.End)
+	}
+	assert.Len(t, window.Upcoming, 5)
+
+	// A window too short for the scan is passed over
+	window, err = calendar.Window(utc("2026-07-01T12:00:00Z"), 12*time.Hour)
+	assert.NoError(t, err)
+	assert.Nil(t, window.Blackout)
+	assert.Equal(t, utc("2026-07-02T02:00:00Z"), window.Start)
+
+	_, err = calendar.Window(utc("2026-07-01T12:00:00Z"), 24*time.Hour)
+	assert.Error(t, err)
+}
+
+func TestScheduler_Blackouts(t *testing.T) {
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+
+	var scanned []string
+	service := &MockScannerService{
+		MockThreadSafeScan: func(ctx context.Context, target string, severity string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+			scanned = append(scanned, target)
+			return cache.ScanResult{Target: target}, nil
+		},
+	}
+	file := filepath.Join(t.TempDir(), "blackouts.yaml")
+	assert.NoError(t, os.WriteFile(file, []byte(yamlBlackoutCalendar), 0644))
+	calendar, err := schedule.LoadCalendar(file)
+	assert.NoError(t, err)
+	scheduler := schedule.NewScheduler(service, mockLogger, []schedule.Scan{{Target: "https://daily.example.com", Every: 24 * time.Hour}},
+		schedule.WithBlackouts(calendar))
+
+	assert.Empty(t, scheduler.RunDue(context.Background(), utc("2026-12-24T12:00:00Z")))
+	assert.Empty(t, scanned)
+	mockLogger.AssertCalled(t, "Log", "Scheduled scans suspended until %s for blackout %s", mock.Anything)
+
+	// The scan that fell due during the blackout runs when it ends
+	assert.Len(t, scheduler.RunDue(context.Background(), utc("2027-01-04T00:01:00Z")), 1)
+	mockLogger.AssertCalled(t, "Log", "Scheduled scans resumed after blackout", mock.Anything)
+
+	// Changes to the calendar file apply without a restart, and a broken
+	// file keeps the previous blackouts
+	assert.NoError(t, os.WriteFile(file, []byte("blackouts:\n  - start: 2027-02-01\n"), 0644))
+	assert.NoError(t, os.Chtimes(file, time.Now(), time.Now().Add(time.Minute)))
+	assert.Empty(t, scheduler.RunDue(context.Background(), utc("2027-02-01T12:00:00Z")))
+	assert.NoError(t, os.WriteFile(file, []byte("blackouts: [\n"), 0644))
+	assert.NoError(t, os.Chtimes(file, time.Now(), time.Now().Add(2*time.Minute)))
+	assert.Empty(t, scheduler.RunDue(context.Background(), utc("2027-02-01T13:00:00Z")))
+	mockLogger.AssertCalled(t, "Log", "Keeping the previous blackouts: %v", mock.Anything)
+
+	_, err = schedule.LoadCalendar(filepath.Join(t.TempDir(), "missing.ics"))
+	assert.Error(t, err)
+}
+
+func TestScheduler_BlackoutMidRun(t *testing.T) {
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+
+	var scanned []string
+	service := &MockScannerService{
+		MockThreadSafeScan: func(ctx context.Context, target string, severity string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+			scanned = append(scanned, target)
+			time.Sleep(200 * time.Millisecond)
+			return cache.ScanResult{Target: target}, nil
+		},
+	}
+	// The blackout begins while the first scan runs
+	now := time.Now()
+	calendar := schedule.NewCalendar(schedule.Blackout{Name: "deploy", Start: now.Add(100 * time.Millisecond), End: now.Add(time.Hour)})
+	scheduler := schedule.NewScheduler(service, mockLogger, []schedule.Scan{
+		{Target: "https://first.example.com", Every: 24 * time.Hour},
+		{Target: "https://second.example.com", Every: 24 * time.Hour},
+	}, schedule.WithBlackouts(calendar))
+
+	assert.Len(t, scheduler.RunDue(context.Background(), now), 1)
+	assert.Equal(t, []string{"https://first.example.com"}, scanned)
+
+	// The second scan runs once the blackout is over
+	assert.Len(t, scheduler.RunDue(context.Background(), now.Add(2*time.Hour)), 1)
+	assert.Equal(t, []string{"https://first.example.com", "https://second.example.com"}, scanned)
+}
+
+func TestHandleNextScanWindow(t *testing.T) {
+	blackouts, err := schedule.ParseCalendar([]byte(yamlBlackoutCalendar))
+	assert.NoError(t, err)
+	calendar := schedule.NewCalendar(blackouts...)
+
+	result, err := api.HandleNextScanWindow(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: map[string]any{
+		"after":    "2026-12-24T12:00:00Z",
+		"duration": "2h",
+	}}}, calendar)
+	assert.NoError(t, err)
+	var window schedule.ScanWindow
+	assert.NoError(t, json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &window))
+	if assert.NotNil(t, window.Blackout) {
+		assert.Equal(t, "Year-end freeze", window.Blackout.Name)
+	}
+	assert.Equal(t, utc("2027-01-04T00:00:00Z"), window.Start.UTC())
+
+	_, err = api.HandleNextScanWindow(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: map[string]any{
+		"after": "christmas",
+	}}}, calendar)
+	assert.ErrorContains(t, err, "invalid after")
+}
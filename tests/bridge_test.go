@@ -0,0 +1,203 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/bridge"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+// startBridge connects a bridge to in-memory client streams and returns the
+// client's writer, a reader for server-to-client messages and the messages
+// forwarded to the MCP server
+func startBridge(t *testing.T) (*bridge.Bridge, io.Writer, *bufio.Reader, <-chan string) {
+	clientIn, clientWriter := io.Pipe()
+	clientReader, serverOut := io.Pipe()
+	t.Cleanup(func() {
+		clientWriter.Close()
+		serverOut.Close()
+	})
+
+	b := bridge.NewBridge(clientIn, serverOut)
+	b.Start(context.Background())
+
+	forwarded := make(chan string, 16)
+	go func() {
+		scanner := bufio.NewScanner(b.Reader())
+		for scanner.Scan() {
+			forwarded <- scanner.Text()
+		}
+		close(forwarded)
+	}()
+
+	return b, clientWriter, bufio.NewReader(clientReader), forwarded
+}
+
+func send(t *testing.T, w io.Writer, msg map[string]any) {
+	data, err := json.Marshal(msg)
+	assert.NoError(t, err)
+	_, err = w.Write(append(data, '\n'))
+	assert.NoError(t, err)
+}
+
+func TestBridge_FetchesRoots(t *testing.T) {
+	b, client, fromServer, forwarded := startBridge(t)
+
+	send(t, client, map[string]any{"jsonrpc": "2.0", "id": 1, "method": "initialize",
+		"params": map[string]any{"capabilities": map[string]any{"roots": map[string]any{"listChanged": true}}}})
+	assert.Contains(t, <-forwarded, `"initialize"`)
+	assert.True(t, b.Supports("roots"))
+	assert.False(t, b.Supports("sampling"))
+
+	send(t, client, map[string]any{"jsonrpc": "2.0", "method": "notifications/initialized"})
+	assert.Contains(t, <-forwarded, "notifications/initialized")
+
+	line, err := fromServer.ReadBytes('\n')
+	assert.NoError(t, err)
+	var request struct {
+		ID     string `json:"id"`
+		Method string `json:"method"`
+	}
+	assert.NoError(t, json.Unmarshal(line, &request))
+	assert.Equal(t, "roots/list", request.Method)
+
+	send(t, client, map[string]any{"jsonrpc": "2.0", "id": request.ID,
+		"result": map[string]any{"roots": []map[string]any{{"uri": "https://app.example.com", "name": "app"}}}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	assert.Equal(t, []mcp.Root{{URI: "https://app.example.com", Name: "app"}}, b.WaitForRoots(ctx))
+
+	select {
+	case msg := <-forwarded:
+		t.Fatalf("response to a bridge request was forwarded to the server: %s", msg)
+	default:
+	}
+}
+
+func TestBridge_ClientWithoutRoots(t *testing.T) {
+	b, client, _, forwarded := startBridge(t)
+
+	send(t, client, map[string]any{"jsonrpc": "2.0", "id": 1, "method": "initialize",
+		"params": map[string]any{"capabilities": map[string]any{}}})
+	<-forwarded
+	send(t, client, map[string]any{"jsonrpc": "2.0", "method": "notifications/initialized"})
+	<-forwarded
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	assert.Empty(t, b.WaitForRoots(ctx))
+	assert.NoError(t, ctx.Err())
+}
+
+func TestBridge_CreateMessage(t *testing.T) {
+	b, client, fromServer, forwarded := startBridge(t)
+
+	_, err := b.CreateMessage(context.Background(), mcp.CreateMessageParams{MaxTokens: 10})
+	assert.ErrorIs(t, err, bridge.ErrUnsupported)
+
+	send(t, client, map[string]any{"jsonrpc": "2.0", "id": 1, "method": "initialize",
+		"params": map[string]any{"capabilities": map[string]any{"sampling": map[string]any{}}}})
+	<-forwarded
+
+	go func() {
+		line, err := fromServer.ReadBytes('\n')
+		if err != nil {
+			return
+		}
+		var request struct {
+			ID     string `json:"id"`
+			Method string `json:"method"`
+		}
+		_ = json.Unmarshal(line, &request)
+		if request.Method != "sampling/createMessage" {
+			return
+		}
+		send(t, client, map[string]any{"jsonrpc": "2.0", "id": request.ID, "result": map[string]any{
+			"role": "assistant", "model": "test-model", "content": map[string]any{"type": "text", "text": "summary"}}})
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	result, err := b.CreateMessage(ctx, mcp.CreateMessageParams{MaxTokens: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, "test-model", result.Model)
+	assert.Equal(t, "summary", result.Content.(map[string]any)["text"])
+}
+
+func TestBridge_HandleRequest(t *testing.T) {
+	clientIn, client := io.Pipe()
+	fromServer, serverOut := io.Pipe()
+	t.Cleanup(func() {
+		client.Close()
+		serverOut.Close()
+	})
+
+	b := bridge.NewBridge(clientIn, serverOut)
+	b.HandleRequest("completion/complete", "completions", func(_ context.Context, params json.RawMessage) (any, error) {
+		var request struct {
+			Argument struct {
+				Value string `json:"value"`
+			} `json:"argument"`
+		}
+		assert.NoError(t, json.Unmarshal(params, &request))
+		if request.Argument.Value == "" {
+			return nil, fmt.Errorf("empty value")
+		}
+		return map[string]any{"completion": map[string]any{"values": []string{request.Argument.Value + "-1"}}}, nil
+	})
+	b.Start(context.Background())
+
+	forwarded := make(chan string, 16)
+	go func() {
+		lines := bufio.NewScanner(b.Reader())
+		for lines.Scan() {
+			forwarded <- lines.Text()
+		}
+	}()
+	serverLines := bufio.NewReader(fromServer)
+
+	// The server's initialize result advertises the handled capability
+	send(t, client, map[string]any{"jsonrpc": "2.0", "id": 1, "method": "initialize", "params": map[string]any{"capabilities": map[string]any{}}})
+	assert.Contains(t, <-forwarded, `"initialize"`)
+	go func() {
+		_, _ = b.Writer().Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"capabilities":{"tools":{}},"serverInfo":{"name":"test"}}}` + "\n"))
+	}()
+	line, err := serverLines.ReadBytes('\n')
+	assert.NoError(t, err)
+	var initialize struct {
+		Result struct {
+			Capabilities map[string]any `json:"capabilities"`
+			ServerInfo   map[string]any `json:"serverInfo"`
+		} `json:"result"`
+	}
+	assert.NoError(t, json.Unmarshal(line, &initialize))
+	assert.Contains(t, initialize.Result.Capabilities, "completions")
+	assert.Contains(t, initialize.Result.Capabilities, "tools")
+	assert.Equal(t, "test", initialize.Result.ServerInfo["name"])
+
+	// Handled requests are answered by the bridge, not forwarded
+	send(t, client, map[string]any{"jsonrpc": "2.0", "id": 2, "method": "completion/complete", "params": map[string]any{"argument": map[string]any{"name": "tags", "value": "wp"}}})
+	line, err = serverLines.ReadBytes('\n')
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":2,"result":{"completion":{"values":["wp-1"]}}}`, string(line))
+
+	send(t, client, map[string]any{"jsonrpc": "2.0", "id": 3, "method": "completion/complete", "params": map[string]any{"argument": map[string]any{"name": "tags"}}})
+	line, err = serverLines.ReadBytes('\n')
+	assert.NoError(t, err)
+	assert.Contains(t, string(line), `"message":"empty value"`)
+
+	select {
+	case msg := <-forwarded:
+		t.Fatalf("handled request was forwarded to the server: %s", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
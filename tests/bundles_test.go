@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"nuclei-mcp/pkg/templates"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadBundle_TarGz(t *testing.T) {
+	bundlePath := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	assert.NoError(t, os.WriteFile(bundlePath, buildReleaseTarball(t, map[string]string{
+		"http/exposed-panel.yaml": "id: exposed-panel\ninfo:\n  name: Exposed Panel\n",
+		"http/broken.yaml":        "info: no id here",
+		"README.md":               "docs",
+	}), 0644))
+
+	root := t.TempDir()
+	result, err := templates.LoadBundle(context.Background(), templates.Bundle{Name: "internal", Source: bundlePath}, root, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Templates)
+	assert.Equal(t, []string{"http/broken.yaml"}, result.Invalid)
+	assert.Equal(t, filepath.Join(root, "internal"), result.Dir)
+
+	_, err = os.Stat(filepath.Join(root, "internal", "http", "exposed-panel.yaml"))
+	assert.NoError(t, err)
+}
+
+func TestLoadBundle_Zip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("dns/takeover.yaml")
+	assert.NoError(t, err)
+	w.Write([]byte("id: takeover\n"))
+	assert.NoError(t, zw.Close())
+
+	bundlePath := filepath.Join(t.TempDir(), "bundle.zip")
+	assert.NoError(t, os.WriteFile(bundlePath, buf.Bytes(), 0644))
+
+	result, err := templates.LoadBundle(context.Background(), templates.Bundle{Name: "zipped", Source: bundlePath}, t.TempDir(), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Templates)
+}
+
+func TestLoadBundle_RejectsUnsafeBundles(t *testing.T) {
+	root := t.TempDir()
+	bundlePath := filepath.Join(t.TempDir(), "evil.tar.gz")
+	assert.NoError(t, os.WriteFile(bundlePath, buildReleaseTarball(t, map[string]string{
+		"../../escape.yaml": "id: escape",
+	}), 0644))
+
+	_, err := templates.LoadBundle(context.Background(), templates.Bundle{Name: "evil", Source: bundlePath}, root, nil)
+	assert.Error(t, err)
+
+	_, err = templates.LoadBundle(context.Background(), templates.Bundle{Name: "../evil", Source: bundlePath}, root, nil)
+	assert.Error(t, err)
+
+	emptyPath := filepath.Join(t.TempDir(), "empty.tar.gz")
+	assert.NoError(t, os.WriteFile(emptyPath, buildReleaseTarball(t, map[string]string{"README.md": "docs"}), 0644))
+	_, err = templates.LoadBundle(context.Background(), templates.Bundle{Name: "empty", Source: emptyPath}, root, nil)
+	assert.Error(t, err)
+}
+
+func TestLoadBundle_OCI(t *testing.T) {
+	layer := buildReleaseTarball(t, map[string]string{"cloud/bucket.yaml": "id: public-bucket\n"})
+	sum := sha256.Sum256(layer)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	served := layer
+
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			fmt.Fprint(w, `{"token":"anon"}`)
+		case r.Header.Get("Authorization") != "Bearer anon":
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="http://%s/token",service="test"`, r.Host))
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/v2/security/templates/manifests/v1":
+			fmt.Fprintf(w, `{"layers":[{"digest":%q}]}`, digest)
+		case r.URL.Path == "/v2/security/templates/blobs/"+digest:
+			w.Write(served)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer registry.Close()
+
+	source := "oci://" + strings.TrimPrefix(registry.URL, "http://") + "/security/templates:v1"
+	result, err := templates.LoadBundle(context.Background(), templates.Bundle{Name: "oci", Source: source}, t.TempDir(), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Templates)
+
+	// A layer that does not match its digest is not extracted
+	served = buildReleaseTarball(t, map[string]string{"cloud/bucket.yaml": "id: tampered\n"})
+	_, err = templates.LoadBundle(context.Background(), templates.Bundle{Name: "oci", Source: source}, t.TempDir(), nil)
+	assert.ErrorContains(t, err, "does not match its digest")
+}
@@ -0,0 +1,215 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/templates"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+const catalogTemplate = `id: acme-login
+info:
+  name: ACME Login Panel
+  author: nuclei-mcp
+  severity: info
+  tags: panel,acme
+http:
+  - method: GET
+    path:
+      - "{{BaseURL}}/login"
+`
+
+func TestTemplateManager_Catalog(t *testing.T) {
+	dir := t.TempDir()
+	tm, err := templates.NewTemplateManager(dir)
+	assert.NoError(t, err)
+
+	assert.NoError(t, tm.AddTemplate("acme/login.yaml", []byte(catalogTemplate)))
+	assert.NoError(t, tm.AddTemplate("broken.yaml", []byte("id: broken\ninfo: [")))
+	assert.NoError(t, tm.AddTemplate("notes.txt", []byte("not a template")))
+
+	catalog, err := tm.Catalog()
+	assert.NoError(t, err)
+	assert.Len(t, catalog, 3)
+
+	login := catalog[0]
+	assert.Equal(t, "acme/login.yaml", login.Name)
+	assert.Equal(t, "acme", login.Namespace)
+	assert.Equal(t, "acme-login", login.ID)
+	assert.Equal(t, []string{"panel", "acme"}, login.Tags)
+	assert.Equal(t, templates.StatusValid, login.Status)
+	assert.Len(t, login.Hash, 64)
+
+	assert.Equal(t, "broken.yaml", catalog[1].Name)
+	assert.Equal(t, templates.StatusInvalid, catalog[1].Status)
+	assert.NotEmpty(t, catalog[1].Error)
+	assert.Equal(t, templates.StatusUnsupported, catalog[2].Status)
+
+	// The index is persisted and never listed as a template
+	_, err = os.Stat(filepath.Join(dir, templates.IndexFile))
+	assert.NoError(t, err)
+	names, err := tm.ListTemplates()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"acme/login.yaml", "broken.yaml", "notes.txt"}, names)
+}
+
+func TestTemplateManager_CatalogIncremental(t *testing.T) {
+	dir := t.TempDir()
+	tm, err := templates.NewTemplateManager(dir)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "login.yaml"), []byte(catalogTemplate), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "old.yaml"), []byte(catalogTemplate), 0644))
+
+	catalog, err := tm.Catalog()
+	assert.NoError(t, err)
+	assert.Len(t, catalog, 2)
+
+	// A new manager reuses the saved index: an entry whose size and
+	// modification time are unchanged is not re-read
+	indexPath := filepath.Join(dir, templates.IndexFile)
+	data, err := os.ReadFile(indexPath)
+	assert.NoError(t, err)
+	var index map[string]any
+	assert.NoError(t, json.Unmarshal(data, &index))
+	index["templates"].(map[string]any)["login.yaml"].(map[string]any)["id"] = "from-index"
+	data, err = json.Marshal(index)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(indexPath, data, 0644))
+
+	// Files changed, added or removed outside the manager are picked up
+	assert.NoError(t, os.Remove(filepath.Join(dir, "old.yaml")))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "new.yaml"), []byte("id: new\ninfo:\n  name: New\n"), 0644))
+
+	tm, err = templates.NewTemplateManager(dir)
+	assert.NoError(t, err)
+	catalog, err = tm.Catalog()
+	assert.NoError(t, err)
+	assert.Len(t, catalog, 2)
+	assert.Equal(t, "from-index", catalog[0].ID)
+	assert.Equal(t, "new", catalog[1].ID)
+
+	modified := []byte("id: login-v2\ninfo:\n  name: Login v2\n")
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "login.yaml"), modified, 0644))
+	later := time.Now().Add(time.Minute)
+	assert.NoError(t, os.Chtimes(filepath.Join(dir, "login.yaml"), later, later))
+	catalog, err = tm.Catalog()
+	assert.NoError(t, err)
+	assert.Equal(t, "login-v2", catalog[0].ID)
+}
+
+func TestHandleListTemplates_Query(t *testing.T) {
+	tm := &MockTemplateManager{MockCatalog: func() ([]templates.TemplateInfo, error) {
+		return []templates.TemplateInfo{
+			{Name: "acme/login.yaml", Namespace: "acme", ID: "acme-login", Tags: []string{"panel"}, Status: templates.StatusValid},
+			{Name: "git-config.yaml", ID: "git-config", Tags: []string{"exposure"}, Status: templates.StatusValid},
+		}, nil
+	}}
+
+	request := mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: map[string]interface{}{"query": "PANEL"}}}
+	result, err := api.HandleListTemplates(context.Background(), request, tm)
+	assert.NoError(t, err)
+	assert.Equal(t, "Available templates:\n- acme/login.yaml", result.Content[0].(mcp.TextContent).Text)
+
+	request.Params.Arguments = map[string]interface{}{"details": true}
+	result, err = api.HandleListTemplates(context.Background(), request, tm)
+	assert.NoError(t, err)
+	var catalog []templates.TemplateInfo
+	assert.NoError(t, json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &catalog))
+	assert.Len(t, catalog, 2)
+	assert.Equal(t, "acme", catalog[0].Namespace)
+}
+
+func TestTemplateManager_Sources(t *testing.T) {
+	custom, org, official := t.TempDir(), t.TempDir(), t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(org, "login.yaml"), []byte(catalogTemplate), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(org, "org-only.yaml"), []byte("id: org-only\ninfo:\n  name: Org Only\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(official, "acme-login.yaml"), []byte(catalogTemplate), 0644))
+
+	tm, err := templates.NewTemplateManager(custom, templates.WithSources(
+		templates.Source{Name: "org", Dir: org},
+		templates.Source{Name: "official", Dir: official},
+		templates.Source{Name: "unmounted", Dir: filepath.Join(org, "missing")},
+	))
+	assert.NoError(t, err)
+
+	names, err := tm.ListTemplates()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"org:login.yaml", "org:org-only.yaml"}, names)
+
+	// A custom template overrides the org one with the same ID, which
+	// overrides the official one
+	assert.NoError(t, tm.AddTemplate("login.yaml", []byte(catalogTemplate)))
+	catalog, err := tm.Catalog()
+	assert.NoError(t, err)
+	assert.Len(t, catalog, 4)
+	assert.Equal(t, templates.CustomSource, catalog[0].Source)
+	assert.Empty(t, catalog[0].ShadowedBy)
+	assert.Equal(t, filepath.Join(custom, "login.yaml"), catalog[0].Path)
+	assert.Equal(t, "org", catalog[1].Source)
+	assert.Equal(t, templates.CustomSource, catalog[1].ShadowedBy)
+	assert.Equal(t, "official", catalog[3].Source)
+	assert.Equal(t, templates.CustomSource, catalog[3].ShadowedBy)
+
+	names, err = tm.ListTemplates()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"login.yaml", "org:org-only.yaml"}, names)
+
+	content, err := tm.GetTemplate("org:org-only.yaml")
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "Org Only")
+	assert.Error(t, tm.AddTemplate("org:new.yaml", []byte(catalogTemplate)))
+
+	_, err = templates.NewTemplateManager(custom, templates.WithSources(templates.Source{Name: templates.CustomSource, Dir: org}))
+	assert.Error(t, err)
+}
+
+func TestHandleCatalogResource(t *testing.T) {
+	official, custom := t.TempDir(), t.TempDir()
+	files := map[string]string{
+		filepath.Join(official, "CVE-2021-44228.yaml"): "id: CVE-2021-44228\n\ninfo:\n  name: Log4Shell\n  author: nuclei-mcp\n  severity: critical\n  tags: cve,rce,log4j\n\nhttp:\n  - method: GET\n",
+		filepath.Join(official, "CVE-2022-10001.yaml"): "id: CVE-2022-10001\ninfo:\n  name: Five Digits\n  author: nuclei-mcp\n  severity: high\n  tags: cve\nrequests:\n  - method: GET\n",
+		filepath.Join(official, "redis.yaml"):          "id: redis-unauth\ninfo:\n  name: Redis Unauth\n  author: nuclei-mcp\n  severity: high\n  tags: network,redis\n  classification:\n    cve-id: cve-2022-9999\nnetwork:\n  - host:\n",
+		filepath.Join(official, "dns.json"):            `{"id": "dns-caa", "info": {"name": "CAA", "author": "nuclei-mcp", "severity": "info", "tags": "dns"}, "dns": [{"name": "{{FQDN}}"}]}`,
+		filepath.Join(custom, "acme/login.yaml"):       catalogTemplate,
+		// Shadowed by the official template with the same ID
+		filepath.Join(custom, "log4shell.yaml"): "id: CVE-2021-44228\ninfo:\n  name: Log4Shell Copy\n  author: nuclei-mcp\n  severity: low\n",
+	}
+	for path, content := range files {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	newest := time.Now().Add(time.Hour)
+	assert.NoError(t, os.Chtimes(filepath.Join(custom, "acme/login.yaml"), newest, newest))
+
+	contents, err := api.HandleCatalogResource(context.Background(), mcp.ReadResourceRequest{Params: mcp.ReadResourceParams{URI: "catalog"}}, nil, []string{official, custom})
+	assert.NoError(t, err)
+	var catalog api.TemplateCatalog
+	assert.NoError(t, json.Unmarshal([]byte(contents[0].(mcp.TextResourceContents).Text), &catalog))
+
+	assert.Equal(t, 5, catalog.Templates)
+	assert.Equal(t, map[string]int{"critical": 1, "high": 2, "info": 2}, catalog.BySeverity)
+	assert.Equal(t, map[string]int{"http": 3, "tcp": 1, "dns": 1}, catalog.ByProtocol)
+	assert.Equal(t, 8, catalog.Tags)
+	assert.Equal(t, "cve", catalog.TopTags[0].Tag)
+	assert.Equal(t, 2, catalog.TopTags[0].Templates)
+	assert.Equal(t, "acme-login", catalog.Newest[0].ID)
+	assert.Equal(t, "ACME Login Panel", catalog.Newest[0].Name)
+
+	// Latest CVEs first, by year and then number (not as text); templates
+	// classified with a CVE are included
+	assert.Equal(t, 3, catalog.CVEs)
+	if assert.Len(t, catalog.RecentCVEs, 3) {
+		assert.Equal(t, "CVE-2022-10001", catalog.RecentCVEs[0].CVE)
+		assert.Equal(t, "CVE-2022-9999", catalog.RecentCVEs[1].CVE)
+		assert.Equal(t, "redis-unauth", catalog.RecentCVEs[1].ID)
+		assert.Equal(t, "Log4Shell", catalog.RecentCVEs[2].Name)
+	}
+}
@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/policy"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestScannerService_SkipUnchanged(t *testing.T) {
+	var mu sync.Mutex
+	content, scanned := "v1", 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/page-0" {
+			scanned++
+		}
+		_, _ = w.Write([]byte(content))
+	}))
+	defer srv.Close()
+	templateFile := filepath.Join(t.TempDir(), "paths.yaml")
+	assert.NoError(t, os.WriteFile(templateFile, []byte(fragileTemplate(1)), 0644))
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger)
+	scan := func() cache.ScanResult {
+		result, err := service.ThreadSafeScan(context.Background(), srv.URL, "info", "", nil,
+			scanner.WithTemplateSources(templateFile), scanner.WithFreshResult(), scanner.WithSkipUnchanged())
+		assert.NoError(t, err)
+		return result
+	}
+
+	first := scan()
+	assert.NotEmpty(t, first.TargetFingerprint)
+	assert.Nil(t, first.UnchangedSince)
+	assert.Equal(t, 1, scanned)
+
+	// The unchanged target is not scanned again, and its result is cached
+	// as checked now
+	second := scan()
+	assert.Equal(t, 1, scanned)
+	if assert.NotNil(t, second.UnchangedSince) {
+		assert.Equal(t, first.ScanTime, *second.UnchangedSince)
+	}
+	assert.True(t, second.ScanTime.After(first.ScanTime))
+	assert.Equal(t, first.TargetFingerprint, second.TargetFingerprint)
+
+	mu.Lock()
+	content = "v2"
+	mu.Unlock()
+	third := scan()
+	assert.Equal(t, 2, scanned)
+	assert.Nil(t, third.UnchangedSince)
+	assert.NotEqual(t, first.TargetFingerprint, third.TargetFingerprint)
+}
+
+func TestTargetFingerprint(t *testing.T) {
+	var conditional []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conditional = append(conditional, r.Header.Get("If-None-Match"))
+		w.Header().Set("ETag", `"abc"`)
+		if r.Header.Get("If-None-Match") == `"abc"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		_, _ = w.Write([]byte("body"))
+	}))
+	defer srv.Close()
+
+	client := scanner.NewTargetClient(scanner.TargetClientOptions{})
+	fingerprint, err := scanner.TargetFingerprint(context.Background(), client, srv.URL, "")
+	assert.NoError(t, err)
+	assert.Equal(t, `etag:200:"abc"`, fingerprint)
+
+	// A 304 answer to the conditional request keeps the fingerprint
+	again, err := scanner.TargetFingerprint(context.Background(), client, srv.URL, fingerprint)
+	assert.NoError(t, err)
+	assert.Equal(t, fingerprint, again)
+	assert.Equal(t, []string{"", `"abc"`}, conditional)
+
+	// Connections are checked against the egress policy after DNS
+	egress, err := policy.NewEgressPolicy(policy.EgressOptions{DenyPrivate: true})
+	assert.NoError(t, err)
+	_, err = scanner.TargetFingerprint(context.Background(), scanner.NewTargetClient(scanner.TargetClientOptions{Egress: egress}), srv.URL, "")
+	assert.ErrorIs(t, err, policy.ErrDenied)
+	assert.Len(t, conditional, 2)
+}
@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/projectdiscovery/nuclei/v3/pkg/model/types/stringslice"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+func cloudFinding(host, templateID string, tags ...string) *output.ResultEvent {
+	finding := &output.ResultEvent{TemplateID: templateID, Host: host}
+	finding.Info.Name = templateID
+	finding.Info.Tags = stringslice.StringSlice{Value: tags}
+	return finding
+}
+
+// cloudService returns the cloud findings of each target and records the
+// tags it is asked to run
+type cloudService struct {
+	MockScannerService
+	mu   sync.Mutex
+	tags []string
+}
+
+func (s *cloudService) ThreadSafeScan(_ context.Context, target string, _ string, _ string, _ []string, opts ...scanner.ScanOption) (cache.ScanResult, error) {
+	var scanOpts scanner.ScanOptions
+	for _, opt := range opts {
+		opt(&scanOpts)
+	}
+	s.mu.Lock()
+	s.tags = scanOpts.Tags
+	s.mu.Unlock()
+
+	result := cache.ScanResult{Target: target}
+	switch target {
+	case "https://assets.s3.amazonaws.com":
+		result.Findings = append(result.Findings, cloudFinding(target, "s3-bucket-listing", "bucket", "misconfig"))
+	case "https://k8s.example.com:6443":
+		result.Findings = append(result.Findings, cloudFinding(target, "kube-api-anonymous", "kubernetes", "k8s"))
+	}
+	return result, nil
+}
+
+func TestCloudProvider(t *testing.T) {
+	assert.Equal(t, "aws", scanner.CloudProvider(cloudFinding("https://assets.s3.amazonaws.com", "t", "bucket")))
+	assert.Equal(t, "azure", scanner.CloudProvider(cloudFinding("data.blob.core.windows.net", "t")))
+	assert.Equal(t, "gcp", scanner.CloudProvider(cloudFinding("https://example.com", "t", "gcp", "bucket")))
+	assert.Equal(t, "kubernetes", scanner.CloudProvider(cloudFinding("https://k8s.example.com:6443", "t", "k8s")))
+	assert.Equal(t, "", scanner.CloudProvider(cloudFinding("https://example.com", "t", "exposure")))
+}
+
+func TestHandleCloudScanTool(t *testing.T) {
+	service := &cloudService{}
+	multi := scanner.NewMultiScanner(service, scanner.Concurrency{})
+	scan := func(arguments map[string]any) (string, error) {
+		result, err := api.HandleCloudScanTool(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: arguments}}, multi, api.DefaultScanDefaults)
+		if err != nil {
+			return "", err
+		}
+		return result.Content[0].(mcp.TextContent).Text, nil
+	}
+
+	text, err := scan(map[string]any{"targets": []any{"https://assets.s3.amazonaws.com", "https://k8s.example.com:6443", "https://www.example.com"}})
+	assert.NoError(t, err)
+	assert.Contains(t, text, "Cloud scan of 3 targets on 3 hosts (0 failed) found 2 exposures.")
+	assert.Contains(t, text, "By provider:\n- aws: 1\n- kubernetes: 1\n")
+	assert.Contains(t, text, "- [aws] s3-bucket-listing (")
+	assert.Contains(t, text, "- [kubernetes] kube-api-anonymous (")
+	assert.Equal(t, scanner.CloudTags, service.tags)
+
+	// Tags given by the caller replace the preset
+	_, err = scan(map[string]any{"targets": []any{"https://www.example.com"}, "tags": []any{"exposure"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"exposure"}, service.tags)
+
+	_, err = scan(map[string]any{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/report"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/projectdiscovery/nuclei/v3/pkg/model/types/severity"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+// headerFindings returns a missing header finding on each of n pages, and
+// one finding of another root cause
+func headerFindings(n int) []*output.ResultEvent {
+	var findings []*output.ResultEvent
+	for i := 1; i <= n; i++ {
+		finding := triageFinding("http-missing-security-headers", "Missing X-Frame-Options", severity.Info, fmt.Sprintf("https://example.com/page-%d", i))
+		finding.MatcherName = "x-frame-options"
+		findings = append(findings, finding)
+	}
+	return append(findings, triageFinding("CVE-2024-0001", "RCE in Widget", severity.Critical, "https://example.com/widget"))
+}
+
+func TestClusterFindings(t *testing.T) {
+	findings := headerFindings(3)
+	// Another matcher of the template is another root cause
+	csp := triageFinding("http-missing-security-headers", "Missing CSP", severity.Info, "https://example.com/page-1")
+	csp.MatcherName = "content-security-policy"
+	// The same endpoint reported twice is listed once
+	findings = append(findings, csp, findings[0])
+
+	clusters := report.ClusterFindings(findings)
+	if assert.Len(t, clusters, 3) {
+		assert.Equal(t, "x-frame-options", clusters[0].Finding.MatcherName)
+		assert.Equal(t, []string{"https://example.com/page-1", "https://example.com/page-2", "https://example.com/page-3"}, clusters[0].Endpoints)
+		assert.Equal(t, "CVE-2024-0001", clusters[1].Finding.TemplateID)
+		assert.Equal(t, []string{"https://example.com/widget"}, clusters[1].Endpoints)
+		assert.Equal(t, "content-security-policy", clusters[2].Finding.MatcherName)
+	}
+
+	markdown := report.NewGenerator(nil).Clustered().Markdown([]cache.ScanResult{{Target: "example.com", ScanTime: time.Now(), Findings: headerFindings(3)}}, time.Now())
+	assert.Contains(t, markdown, "- Affected endpoints (3):\n  - https://example.com/page-1\n")
+	assert.Equal(t, 2, strings.Count(markdown, "#### "))
+}
+
+func TestHandleNucleiScanTool_Cluster(t *testing.T) {
+	service := &MockScannerService{
+		MockThreadSafeScan: func(ctx context.Context, target string, severities string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+			return cache.ScanResult{Target: target, ScanTime: time.Now(), Findings: headerFindings(200)}, nil
+		},
+	}
+	logger := log.New(os.Stdout, "test: ", log.LstdFlags)
+	scan := func(arguments map[string]any) (*mcp.CallToolResult, error) {
+		return api.HandleNucleiScanTool(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: arguments}}, service, logger, api.NewResultPager(50, time.Minute), api.DefaultScanDefaults)
+	}
+
+	result, err := scan(map[string]any{"target": "https://example.com", "cluster": true})
+	assert.NoError(t, err)
+	text := result.Content[0].(mcp.TextContent).Text
+	assert.Contains(t, text, "Found 201 vulnerabilities with 2 root causes")
+	assert.Contains(t, text, "- Affected endpoints (200): https://example.com/page-1, https://example.com/page-2,")
+	assert.NotContains(t, text, "fetch_more_results")
+
+	result, err = scan(map[string]any{"target": "https://example.com", "cluster": true, "format": "json"})
+	assert.NoError(t, err)
+	var response struct {
+		Total    int `json:"total"`
+		Findings []struct {
+			TemplateID        string   `json:"template_id"`
+			AffectedEndpoints []string `json:"affected_endpoints"`
+		} `json:"findings"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &response))
+	assert.Equal(t, 2, response.Total)
+	if assert.Len(t, response.Findings, 2) {
+		assert.Len(t, response.Findings[0].AffectedEndpoints, 200)
+		assert.Empty(t, response.Findings[1].AffectedEndpoints)
+	}
+}
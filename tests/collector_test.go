@@ -0,0 +1,110 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const spillTemplate = `id: spill-marker
+info:
+  name: Spill Marker
+  author: nuclei-mcp
+  severity: info
+http:
+  - method: GET
+    path:
+      - "{{BaseURL}}/1"
+      - "{{BaseURL}}/2"
+      - "{{BaseURL}}/3"
+      - "{{BaseURL}}/4"
+      - "{{BaseURL}}/5"
+    matchers:
+      - type: word
+        words:
+          - spill-marker
+`
+
+func TestScannerService_ThreadSafeScan_SpillsFindings(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("spill-marker"))
+	}))
+	defer srv.Close()
+
+	templateDir := t.TempDir()
+	spillDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(templateDir, "spill-marker.yaml"), []byte(spillTemplate), 0644))
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	resultCache := cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags))
+	service := scanner.NewScannerService(resultCache, mockLogger,
+		scanner.WithTemplateDirs(templateDir), scanner.WithResultBuffer(1), scanner.WithSpillThreshold(2, spillDir))
+
+	result, err := service.ThreadSafeScan(context.Background(), srv.URL, "", "", []string{"spill-marker"})
+	assert.NoError(t, err)
+	assert.Len(t, result.Findings, 2, "findings past the threshold are only counted")
+	assert.Equal(t, 3, result.SpilledFindings)
+	assert.Equal(t, spillDir, filepath.Dir(result.SpillFile))
+	for _, finding := range result.Findings {
+		assert.Equal(t, "spill-marker", finding.TemplateID)
+		assert.Contains(t, finding.Response, "spill-marker")
+	}
+
+	file, err := os.Open(result.SpillFile)
+	if assert.NoError(t, err) {
+		defer file.Close()
+		spilled := 0
+		lines := bufio.NewScanner(file)
+		lines.Buffer(nil, 1<<20)
+		for lines.Scan() {
+			var event output.ResultEvent
+			assert.NoError(t, json.Unmarshal(lines.Bytes(), &event))
+			assert.Equal(t, "spill-marker", event.TemplateID)
+			assert.Contains(t, event.Response, "spill-marker")
+			spilled++
+		}
+		assert.Equal(t, 3, spilled)
+	}
+
+	// A newer result replaces the spill file, and shutdown removes the last
+	// one
+	previous := result.SpillFile
+	result, err = service.ThreadSafeScan(context.Background(), srv.URL, "", "", []string{"spill-marker"}, scanner.WithFreshResult())
+	assert.NoError(t, err)
+	assert.NoFileExists(t, previous)
+	assert.FileExists(t, result.SpillFile)
+	resultCache.Close()
+	assert.NoFileExists(t, result.SpillFile)
+}
+
+func TestResultCache_ExpiredSpillFile(t *testing.T) {
+	spillFile := filepath.Join(t.TempDir(), "findings.jsonl")
+	assert.NoError(t, os.WriteFile(spillFile, []byte("{}\n"), 0600))
+
+	resultCache := cache.NewResultCache(50*time.Millisecond, log.New(os.Stderr, "test: ", log.LstdFlags))
+	resultCache.Set("old", cache.ScanResult{Target: "a.example.com", ScanTime: time.Now(), SpillFile: spillFile, SpilledFindings: 1})
+	assert.FileExists(t, spillFile)
+	time.Sleep(100 * time.Millisecond)
+
+	// Expired results with a spill file leave the cache at the next result
+	// cached, with their file
+	resultCache.Set("new", cache.ScanResult{Target: "b.example.com", ScanTime: time.Now()})
+	assert.NoFileExists(t, spillFile)
+	_, found := resultCache.Peek("old")
+	assert.False(t, found)
+}
@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func completionTemplates(t *testing.T) string {
+	dir := t.TempDir()
+	files := map[string]string{
+		"wp-login.yaml":   "id: wordpress-login\n\ninfo:\n  name: WordPress Login\n  author: nuclei-mcp\n  severity: info\n  tags: wordpress,panel\n",
+		"wp-xmlrpc.yaml":  "id: \"wordpress-xmlrpc\"\ninfo:\n  name: XML-RPC\n  author: nuclei-mcp\n  tags: \"wordpress, xmlrpc\"\n",
+		"nested/git.yaml": "id: git-config\ninfo:\n  name: Git Config\n  author: nuclei-mcp\n  tags:\n    - exposure\n    - git\n  classification:\n    cwe-id:\n      - cwe-538\n",
+		"README.md":       "id: not-a-template\n",
+		"broken.yaml":     "info:\n  name: No ID\n  author: nuclei-mcp\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	return dir
+}
+
+func TestTemplateIndex_Templates(t *testing.T) {
+	index := scanner.NewTemplateIndex("").Templates(completionTemplates(t), "/nonexistent")
+	tags := map[string][]string{}
+	for _, template := range index {
+		tags[template.ID] = template.Tags
+		if template.ID == "git-config" {
+			// Block-style lists are read like inline ones
+			assert.Equal(t, []string{"CWE-538"}, template.CWEs)
+		}
+	}
+	assert.Equal(t, map[string][]string{
+		"wordpress-login":  {"wordpress", "panel"},
+		"wordpress-xmlrpc": {"wordpress", "xmlrpc"},
+		"git-config":       {"exposure", "git"},
+	}, tags)
+}
+
+func TestCompleter(t *testing.T) {
+	service := &MockScannerService{MockGetAll: func() []cache.ScanResult {
+		return []cache.ScanResult{{Target: "https://app.example.com"}, {Target: "https://api.example.com"}, {Target: "https://app.example.com"}}
+	}}
+	tm := &MockTemplateManager{MockListTemplates: func() ([]string, error) {
+		return []string{"custom-check.yaml", "login-check.yaml"}, nil
+	}}
+	completer := api.NewCompleter(service, tm, nil, completionTemplates(t))
+
+	tests := []struct {
+		argument string
+		value    string
+		expected []string
+	}{
+		{"template_ids", "wordpress", []string{"wordpress-login", "wordpress-xmlrpc"}},
+		{"template_id", "CONFIG", []string{"git-config"}},
+		{"tags", "p", []string{"panel", "exposure", "wordpress", "xmlrpc"}},
+		{"tags", "", []string{"exposure", "git", "panel", "wordpress", "xmlrpc"}},
+		{"targets", "https://ap", []string{"https://api.example.com", "https://app.example.com"}},
+		{"target", "api", []string{"https://api.example.com"}},
+		{"severity", "h", []string{"high"}},
+		{"protocols", "ht", []string{"http", "https"}},
+		{"format", "", []string{"json", "text"}},
+		{"name", "check", []string{"custom-check.yaml", "login-check.yaml"}},
+		{"language", "d", []string{"de"}},
+		{"interval", "1", []string{}},
+	}
+	for _, test := range tests {
+		values, total := completer.Complete(test.argument, test.value)
+		assert.Equal(t, test.expected, values, "%s=%q", test.argument, test.value)
+		assert.Equal(t, len(test.expected), total, "%s=%q", test.argument, test.value)
+	}
+}
+
+func TestHandleComplete(t *testing.T) {
+	var targets []cache.ScanResult
+	for i := 0; i < api.MaxCompletions+20; i++ {
+		targets = append(targets, cache.ScanResult{Target: fmt.Sprintf("https://host%03d.example.com", i)})
+	}
+	completer := api.NewCompleter(&MockScannerService{MockGetAll: func() []cache.ScanResult { return targets }}, &MockTemplateManager{}, nil)
+
+	var request mcp.CompleteRequest
+	assert.NoError(t, json.Unmarshal([]byte(`{"ref":{"type":"ref/tool","name":"nuclei_scan"},"argument":{"name":"target","value":"https://host"}}`), &request.Params))
+	result, err := api.HandleComplete(context.Background(), request, completer)
+	assert.NoError(t, err)
+	assert.Len(t, result.Completion.Values, api.MaxCompletions)
+	assert.Equal(t, "https://host000.example.com", result.Completion.Values[0])
+	assert.Equal(t, api.MaxCompletions+20, result.Completion.Total)
+	assert.True(t, result.Completion.HasMore)
+
+	request.Params.Ref = map[string]any{"type": "ref/unknown"}
+	_, err = api.HandleComplete(context.Background(), request, completer)
+	assert.Error(t, err)
+}
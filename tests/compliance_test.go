@@ -0,0 +1,122 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/compliance"
+	"nuclei-mcp/pkg/report"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/projectdiscovery/nuclei/v3/pkg/model"
+	"github.com/projectdiscovery/nuclei/v3/pkg/model/types/severity"
+	"github.com/projectdiscovery/nuclei/v3/pkg/model/types/stringslice"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+func classifiedFinding(templateID string, sev severity.Severity, host string, cwes []string, cves []string, tags ...string) *output.ResultEvent {
+	finding := triageFinding(templateID, templateID, sev, host)
+	finding.Info.Tags = stringslice.StringSlice{Value: tags}
+	if len(cwes)+len(cves) > 0 {
+		finding.Info.Classification = &model.Classification{
+			CWEID: stringslice.StringSlice{Value: cwes},
+			CVEID: stringslice.StringSlice{Value: cves},
+		}
+	}
+	return finding
+}
+
+func complianceResults() []cache.ScanResult {
+	sqli := classifiedFinding("sqli-error", severity.High, "https://a.example.com/search", []string{"cwe-89"}, nil, "sqli")
+	return []cache.ScanResult{
+		{Target: "a.example.com", ScanTime: time.Now(), Findings: []*output.ResultEvent{
+			sqli,
+			classifiedFinding("CVE-2024-0001", severity.Critical, "https://a.example.com", []string{"CWE-502"}, []string{"CVE-2024-0001"}, "cve", "rce"),
+			classifiedFinding("missing-headers", severity.Info, "https://a.example.com", nil, nil, "misconfig", "headers"),
+			classifiedFinding("tech-detect", severity.Info, "https://a.example.com", nil, nil, "tech"),
+		}},
+		// The same finding in a later scan is counted once
+		{Target: "a.example.com", ScanTime: time.Now(), Findings: []*output.ResultEvent{sqli}},
+		{Target: "b.example.com", ScanTime: time.Now(), Findings: []*output.ResultEvent{
+			classifiedFinding("weak-tls", severity.Medium, "https://b.example.com", nil, nil, "ssl", "tls"),
+		}},
+	}
+}
+
+func controlIDs(controls []compliance.Control) []string {
+	var ids []string
+	for _, control := range controls {
+		ids = append(ids, control.ID)
+	}
+	return ids
+}
+
+func TestComplianceMap(t *testing.T) {
+	results := complianceResults()
+	// CWE IDs decide the category; tags are not consulted
+	assert.Equal(t, []string{"A03:2021", "6.2.4", "SI-10"}, controlIDs(compliance.Map(results[0].Findings[0])))
+	// A CVE ID adds vulnerable components
+	assert.Equal(t, []string{"A06:2021", "A08:2021", "6.3.3", "6.2.4", "SI-2", "RA-5", "SI-7"}, controlIDs(compliance.Map(results[0].Findings[1])))
+	// Without CWE IDs, tags decide
+	assert.Equal(t, []string{"A05:2021", "2.2", "CM-6", "CM-7"}, controlIDs(compliance.Map(results[0].Findings[2])))
+	assert.Empty(t, compliance.Map(results[0].Findings[3]))
+
+	summaries := compliance.Summarize(results, "", "")
+	if assert.Len(t, summaries, 2) {
+		a := summaries[0]
+		assert.Equal(t, "a.example.com", a.Target)
+		assert.Equal(t, 4, a.Findings)
+		assert.Equal(t, 1, a.Unmapped)
+		if assert.Len(t, a.Frameworks, 3) {
+			assert.Equal(t, compliance.PCI, a.Frameworks[1].Framework)
+			pci := a.Frameworks[1].Controls
+			assert.Equal(t, []string{"2.2", "6.2.4", "6.3.3"}, []string{pci[0].ID, pci[1].ID, pci[2].ID})
+			assert.Equal(t, 2, pci[1].Findings)
+			assert.Equal(t, []string{"CVE-2024-0001", "sqli-error"}, pci[1].Templates)
+			assert.Equal(t, map[string]int{"high": 1, "critical": 1}, pci[1].BySeverity)
+		}
+	}
+
+	summaries = compliance.Summarize(results, "b.example.com", compliance.NIST)
+	if assert.Len(t, summaries, 1) && assert.Len(t, summaries[0].Frameworks, 1) {
+		assert.Equal(t, []string{"SC-8", "SC-13"}, []string{summaries[0].Frameworks[0].Controls[0].ID, summaries[0].Frameworks[0].Controls[1].ID})
+	}
+
+	markdown := report.NewGenerator(nil).Markdown(results, time.Now())
+	assert.Contains(t, markdown, "### Compliance")
+	assert.Contains(t, markdown, "**OWASP Top 10 (2021)**")
+	assert.Contains(t, markdown, "| A03:2021 Injection | 2 |")
+	assert.Contains(t, markdown, "Findings not mapped to a control: 1")
+}
+
+func TestHandleComplianceSummary(t *testing.T) {
+	service := &MockScannerService{
+		MockGetAll: func() []cache.ScanResult { return complianceResults() },
+	}
+	summarize := func(arguments map[string]any) ([]compliance.Summary, error) {
+		result, err := api.HandleComplianceSummary(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: arguments}}, service)
+		if err != nil {
+			return nil, err
+		}
+		var summaries []compliance.Summary
+		assert.NoError(t, json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &summaries))
+		return summaries, nil
+	}
+
+	summaries, err := summarize(map[string]any{"target": "a.example.com", "framework": compliance.OWASP})
+	assert.NoError(t, err)
+	if assert.Len(t, summaries, 1) && assert.Len(t, summaries[0].Frameworks, 1) {
+		assert.Len(t, summaries[0].Frameworks[0].Controls, 4)
+	}
+
+	_, err = summarize(map[string]any{"framework": "iso-27001"})
+	assert.ErrorContains(t, err, "unsupported framework")
+
+	_, err = summarize(map[string]any{"target": "c.example.com"})
+	assert.ErrorContains(t, err, "no cached scan results")
+}
@@ -2,6 +2,7 @@ package tests
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -42,3 +43,20 @@ logging:
 	_, err = config.LoadConfig(emptyTempDir)
 	assert.Error(t, err)
 }
+
+func TestLoadConfig_UserDirDefaults(t *testing.T) {
+	_, dataDir, cacheDir := setUserDirs(t)
+	tempDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "config.yaml"), []byte("logging:\n"), 0644))
+
+	cfg, err := config.LoadConfig(tempDir)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(cacheDir, "nuclei-mcp", "logs", "nuclei_mcp.log"), cfg.Logging.Path)
+	assert.Equal(t, filepath.Join(cacheDir, "nuclei-mcp", "bundles"), cfg.Nuclei.BundlesDir)
+	assert.Equal(t, filepath.Join(dataDir, "nuclei-mcp", "nuclei-templates"), cfg.Nuclei.TemplatesDir)
+	assert.Equal(t, filepath.Join(dataDir, "nuclei-mcp", "exports"), cfg.Server.ExportDir)
+	assert.Equal(t, filepath.Join(dataDir, "nuclei-mcp", "exclusions.json"), cfg.Scanner.ExclusionsFile)
+	assert.Equal(t, filepath.Join(dataDir, "nuclei-mcp", "findings.json"), cfg.Findings.TrackerFile)
+	assert.Equal(t, filepath.Join(dataDir, "nuclei-mcp", "wordlists"), cfg.Wordlists.Dir)
+	assert.Empty(t, cfg.Validate())
+}
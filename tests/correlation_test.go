@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/correlation"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCorrelation_IDs(t *testing.T) {
+	id := correlation.New()
+	assert.True(t, correlation.Valid(id))
+	assert.NotEqual(t, id, correlation.New())
+
+	assert.True(t, correlation.Valid("ticket-42:run.1"))
+	assert.False(t, correlation.Valid(""))
+	assert.False(t, correlation.Valid("two words"))
+	assert.False(t, correlation.Valid(strings.Repeat("a", 65)))
+
+	assert.Empty(t, correlation.FromContext(context.Background()))
+	assert.Equal(t, id, correlation.FromContext(correlation.NewContext(context.Background(), id)))
+}
+
+func TestScannerService_ThreadSafeScan_CorrelationID(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("spill-marker"))
+	}))
+	defer srv.Close()
+
+	templateDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(templateDir, "spill-marker.yaml"), []byte(spillTemplate), 0644))
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger,
+		scanner.WithTemplateDirs(templateDir))
+
+	ctx := correlation.NewContext(context.Background(), "req-7")
+	result, err := service.ThreadSafeScan(ctx, srv.URL, "", "", []string{"spill-marker"})
+	assert.NoError(t, err)
+	assert.Equal(t, "req-7", result.CorrelationID)
+	assert.NotEmpty(t, result.Findings)
+	for _, finding := range result.Findings {
+		assert.Equal(t, "req-7", finding.Metadata[correlation.MetaKey])
+	}
+
+	tagged := 0
+	for _, call := range mockLogger.Calls {
+		format := call.Arguments.String(0)
+		args := call.Arguments.Get(1).([]interface{})
+		if strings.HasPrefix(format, "[%s] ") && len(args) > 0 && args[0] == "req-7" {
+			tagged++
+		}
+	}
+	assert.Equal(t, len(mockLogger.Calls), tagged, "every scan log line carries the correlation ID")
+
+	cached, err := service.ThreadSafeScan(context.Background(), srv.URL, "", "", []string{"spill-marker"})
+	assert.NoError(t, err)
+	assert.Equal(t, "req-7", cached.CorrelationID, "a cached result keeps the ID of the scan that produced it")
+}
+
+func TestNucleiMCPServer_CorrelationID(t *testing.T) {
+	var seen string
+	mockScanner := &MockScannerService{
+		MockThreadSafeScan: func(ctx context.Context, target string, severity string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+			seen = correlation.FromContext(ctx)
+			return cache.ScanResult{Target: target, ScanTime: time.Now()}, nil
+		},
+	}
+	mcpServer := api.NewNucleiMCPServer(mockScanner, log.New(os.Stdout, "test: ", log.LstdFlags), &MockTemplateManager{})
+
+	call := func(meta map[string]any) map[string]any {
+		params := map[string]any{"name": "nuclei_scan", "arguments": map[string]any{"target": "https://app.example.com"}}
+		if meta != nil {
+			params["_meta"] = meta
+		}
+		data, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": "tools/call", "params": params})
+		assert.NoError(t, err)
+		response, err := json.Marshal(mcpServer.HandleMessage(context.Background(), data))
+		assert.NoError(t, err)
+		var decoded struct {
+			Result struct {
+				Meta map[string]any `json:"_meta"`
+			} `json:"result"`
+		}
+		assert.NoError(t, json.Unmarshal(response, &decoded))
+		return decoded.Result.Meta
+	}
+
+	meta := call(map[string]any{"correlation_id": "ticket-42"})
+	assert.Equal(t, "ticket-42", meta[correlation.MetaKey])
+	assert.Equal(t, "ticket-42", seen)
+
+	meta = call(map[string]any{"correlation_id": "not valid"})
+	assert.NotEqual(t, "not valid", meta[correlation.MetaKey])
+	assert.Equal(t, seen, meta[correlation.MetaKey])
+
+	meta = call(nil)
+	assert.NotEmpty(t, meta[correlation.MetaKey])
+	assert.Equal(t, seen, meta[correlation.MetaKey])
+}
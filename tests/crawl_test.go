@@ -0,0 +1,166 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/policy"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const debugPanelTemplate = `id: debug-panel
+info:
+  name: Debug Panel Exposed
+  author: nuclei-mcp
+  severity: low
+http:
+  - method: GET
+    path:
+      - "{{BaseURL}}"
+    matchers:
+      - type: word
+        words:
+          - debug-panel
+`
+
+func crawlServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			_, _ = w.Write([]byte(`<a href="/app/">App</a> <a href="/admin/">Admin</a> <a href="https://other.example.test/">Other</a>
+<img src="/logo.png"> <a href="#top">Top</a> <a href="mailto:admin@example.test">Mail</a>`))
+		case "/app/":
+			_, _ = w.Write([]byte(`<form action="search?q=x"></form> <a href="/app/settings/debug#panel">Debug</a>`))
+		case "/app/settings/debug":
+			_, _ = w.Write([]byte("debug-panel"))
+		}
+	}))
+}
+
+func TestCrawl(t *testing.T) {
+	srv := crawlServer()
+	defer srv.Close()
+
+	client := scanner.NewTargetClient(scanner.TargetClientOptions{})
+	urls, err := scanner.Crawl(context.Background(), client, srv.URL, 1, 100, nil)
+	assert.NoError(t, err)
+	// Links off the target's host, to static files and to other schemes
+	// are skipped
+	assert.Equal(t, []string{srv.URL + "/admin/", srv.URL + "/app/"}, urls)
+
+	urls, err = scanner.Crawl(context.Background(), client, srv.URL, 2, 100, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{srv.URL + "/admin/", srv.URL + "/app/", srv.URL + "/app/search?q=x", srv.URL + "/app/settings/debug"}, urls)
+
+	urls, err = scanner.Crawl(context.Background(), client, srv.URL, 5, 2, nil)
+	assert.NoError(t, err)
+	assert.Len(t, urls, 2)
+
+	// URLs outside the scope are neither collected nor followed
+	scope := policy.NewScope([]string{srv.URL + "/app/"})
+	urls, err = scanner.Crawl(context.Background(), client, srv.URL, 2, 100, scope.Contains)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{srv.URL + "/app/", srv.URL + "/app/search?q=x", srv.URL + "/app/settings/debug"}, urls)
+}
+
+func TestCrawl_EgressAndRateLimit(t *testing.T) {
+	srv := crawlServer()
+	defer srv.Close()
+
+	// Connections are checked after name resolution, so a host resolving
+	// to a denied address is refused
+	egress, err := policy.NewEgressPolicy(policy.EgressOptions{DenyPrivate: true})
+	assert.NoError(t, err)
+	denied := scanner.NewTargetClient(scanner.TargetClientOptions{Egress: egress})
+	_, err = scanner.Crawl(context.Background(), denied, strings.Replace(srv.URL, "127.0.0.1", "localhost", 1), 1, 100, nil)
+	assert.ErrorIs(t, err, policy.ErrDenied)
+
+	// Three pages at two requests per second take at least a second
+	limited := scanner.NewTargetClient(scanner.TargetClientOptions{RateLimit: 2})
+	start := time.Now()
+	urls, err := scanner.Crawl(context.Background(), limited, srv.URL, 2, 100, nil)
+	assert.NoError(t, err)
+	assert.Len(t, urls, 4)
+	assert.GreaterOrEqual(t, time.Since(start), 900*time.Millisecond)
+}
+
+func TestScannerService_Crawl(t *testing.T) {
+	srv := crawlServer()
+	defer srv.Close()
+
+	template := filepath.Join(t.TempDir(), "debug-panel.yaml")
+	assert.NoError(t, os.WriteFile(template, []byte(debugPanelTemplate), 0644))
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	quotas := scanner.NewQuotaTracker(0, scanner.Quota{}, nil)
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger, scanner.WithQuotas(quotas))
+
+	// The base URL alone has no debug panel
+	result, err := service.ThreadSafeScan(context.Background(), srv.URL, "", "http", nil, scanner.WithTemplateSources(template))
+	assert.NoError(t, err)
+	assert.Empty(t, result.Findings)
+
+	result, err = service.ThreadSafeScan(context.Background(), srv.URL, "", "http", nil, scanner.WithTemplateSources(template), scanner.WithCrawl(2, 0))
+	assert.NoError(t, err)
+	assert.Len(t, result.CrawledURLs, 4)
+	if assert.Len(t, result.Findings, 1) {
+		assert.Equal(t, srv.URL+"/app/settings/debug", result.Findings[0].Matched)
+	}
+
+	// The crawl's two page requests, of the target and /app/, are charged
+	// to the quota
+	before := quotas.Usage()[0].Requests
+	result, err = service.ThreadSafeScan(context.Background(), srv.URL, "", "http", nil, scanner.WithTemplateSources(template), scanner.WithCrawl(2, 0),
+		scanner.WithCrawlScope(policy.NewScope([]string{srv.URL + "/app/"}).Contains), scanner.WithFreshResult())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{srv.URL + "/app/", srv.URL + "/app/search?q=x", srv.URL + "/app/settings/debug"}, result.CrawledURLs)
+	assert.Equal(t, before+result.Stats.Requests+2, quotas.Usage()[0].Requests)
+
+	_, err = service.ThreadSafeScan(context.Background(), srv.URL, "", "http", nil, scanner.WithTemplateSources(template), scanner.WithCrawl(2, 0), scanner.WithPassive(true))
+	assert.ErrorContains(t, err, "passive mode")
+}
+
+// crawlService records the crawl scope of the scans it is asked for
+type crawlService struct {
+	MockScannerService
+	scope func(string) bool
+}
+
+func (s *crawlService) ThreadSafeScan(_ context.Context, target string, _ string, _ string, _ []string, opts ...scanner.ScanOption) (cache.ScanResult, error) {
+	var scanOpts scanner.ScanOptions
+	for _, opt := range opts {
+		opt(&scanOpts)
+	}
+	s.scope = scanOpts.CrawlScope
+	return cache.ScanResult{Target: target, ScanTime: time.Now()}, nil
+}
+
+func TestHandleNucleiScanTool_CrawlScope(t *testing.T) {
+	service := &crawlService{}
+	mcpServer := api.NewNucleiMCPServer(service, log.New(os.Stdout, "test: ", log.LstdFlags), &MockTemplateManager{},
+		api.WithScope([]string{"https://app.example.com/app/"}))
+	data, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": "tools/call",
+		"params": map[string]any{"name": "nuclei_scan", "arguments": map[string]any{"target": "https://app.example.com/app/", "crawl_depth": 2}}})
+	assert.NoError(t, err)
+	mcpServer.HandleMessage(context.Background(), data)
+
+	if assert.NotNil(t, service.scope) {
+		assert.True(t, service.scope("https://app.example.com/app/settings"))
+		assert.False(t, service.scope("https://app.example.com/admin/"))
+	}
+}
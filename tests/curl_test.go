@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/report"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/model/types/severity"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCurlCommand(t *testing.T) {
+	finding := triageFinding("login-sqli", "Login SQL Injection", severity.High, "https://app.example.com")
+	finding.Type = "http"
+	finding.Matched = "https://app.example.com/login"
+	finding.Request = "POST /login HTTP/1.1\r\n" +
+		"Host: app.example.com\r\n" +
+		"Content-Type: application/x-www-form-urlencoded\r\n" +
+		"Content-Length: 27\r\n" +
+		"X-Note: it's\r\n" +
+		"\r\n" +
+		"user=admin'--&password=test"
+
+	assert.Equal(t, `curl -X 'POST' -H 'Content-Type: application/x-www-form-urlencoded' -H 'X-Note: it'\''s' `+
+		`--data-binary 'user=admin'\''--&password=test' 'https://app.example.com/login'`, report.CurlCommand(finding))
+
+	// The command recorded by nuclei wins
+	finding.CURLCommand = "curl -X 'POST' 'https://app.example.com/login'"
+	assert.Equal(t, finding.CURLCommand, report.CurlCommand(finding))
+}
+
+func TestCurlCommand_NoRequest(t *testing.T) {
+	finding := triageFinding("open-redis", "Open Redis", severity.High, "redis.example.com:6379")
+	finding.Type = "network"
+	finding.Request = "INFO\r\n"
+	assert.Empty(t, report.CurlCommand(finding))
+
+	finding.Type = "http"
+	finding.Request = ""
+	assert.Empty(t, report.CurlCommand(finding))
+}
+
+func TestReportGenerator_Reproduce(t *testing.T) {
+	finding := triageFinding("git-config", "Git Config Exposure", severity.Medium, "https://a.example.com")
+	finding.Type = "http"
+	finding.Matched = "https://a.example.com/.git/config"
+	finding.Request = "GET /.git/config HTTP/1.1\r\nHost: a.example.com\r\nUser-Agent: nuclei\r\n\r\n"
+
+	markdown := report.NewGenerator(nil).Markdown([]cache.ScanResult{
+		{Target: "a.example.com", ScanTime: time.Now(), Findings: []*output.ResultEvent{finding}},
+	}, time.Now())
+	assert.Contains(t, markdown, "- Reproduce:\n\n```sh\ncurl -X 'GET' -H 'User-Agent: nuclei' 'https://a.example.com/.git/config'\n```")
+}
@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/assets"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/policy"
+	"nuclei-mcp/pkg/report"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestViolationLog(t *testing.T) {
+	violations := policy.NewViolationLog(2)
+	violations.Record(policy.Violation{Tool: "nuclei_scan", Target: "a"})
+	violations.Record(policy.Violation{Tool: "nuclei_scan", Target: "b"})
+	violations.Record(policy.Violation{Tool: "add_template", Target: "c"})
+
+	recent := violations.Recent()
+	assert.Len(t, recent, 2)
+	assert.Equal(t, "c", recent[0].Target)
+	assert.Equal(t, "b", recent[1].Target)
+	assert.False(t, recent[0].Time.IsZero())
+}
+
+func TestDashboard(t *testing.T) {
+	violations := []policy.Violation{{
+		Time:   time.Now(),
+		Tool:   "nuclei_scan",
+		Target: "https://other.example.org",
+		Reason: "target <b>https://other.example.org</b> is outside the scan scope",
+	}}
+
+	html, err := report.Dashboard(triageResults(), violations, time.Now())
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(html, "<!DOCTYPE html>"))
+	assert.Contains(t, html, "<div class=\"muted\">Findings</div><div class=\"value\">3</div>")
+	assert.Contains(t, html, "Critical 1")
+	// The host with the critical finding is listed first
+	assert.Less(t, strings.Index(html, "<td>a.example.com</td>"), strings.Index(html, "<td>b.example.com</td>"))
+	assert.Contains(t, html, "&lt;b&gt;https://other.example.org&lt;/b&gt;")
+	assert.NotContains(t, html, "<b>")
+
+	html, err = report.Dashboard(nil, nil, time.Now())
+	assert.NoError(t, err)
+	assert.Contains(t, html, "No scans yet.")
+	assert.Contains(t, html, "No policy violations.")
+}
+
+func TestNucleiMCPServer_DashboardRecordsViolations(t *testing.T) {
+	mockScanner := &MockScannerService{
+		MockThreadSafeScan: func(ctx context.Context, target string, severity string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+			return cache.ScanResult{}, fmt.Errorf("%w: target resolves to a metadata address", policy.ErrDenied)
+		},
+		MockGetAll: func() []cache.ScanResult { return nil },
+	}
+	mcpServer := api.NewNucleiMCPServer(mockScanner, log.New(os.Stdout, "test: ", log.LstdFlags), &MockTemplateManager{})
+
+	send := func(method string, params map[string]any) []byte {
+		data, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+		assert.NoError(t, err)
+		response, err := json.Marshal(mcpServer.HandleMessage(context.Background(), data))
+		assert.NoError(t, err)
+		return response
+	}
+
+	send("tools/call", map[string]any{"name": "nuclei_scan", "arguments": map[string]any{"target": "http://169.254.169.254"}})
+
+	var decoded struct {
+		Result struct {
+			Contents []struct {
+				MIMEType string `json:"mimeType"`
+				Text     string `json:"text"`
+			} `json:"contents"`
+		} `json:"result"`
+	}
+	assert.NoError(t, json.Unmarshal(send("resources/read", map[string]any{"uri": "dashboard"}), &decoded))
+	if assert.Len(t, decoded.Result.Contents, 1) {
+		assert.Equal(t, "text/html", decoded.Result.Contents[0].MIMEType)
+		assert.Contains(t, decoded.Result.Contents[0].Text, "<td>nuclei_scan</td><td>http://169.254.169.254</td>")
+		assert.Contains(t, decoded.Result.Contents[0].Text, "metadata address")
+	}
+}
+
+func TestNucleiMCPServer_TargetContextOutOfScope(t *testing.T) {
+	registry, err := assets.Open(filepath.Join(t.TempDir(), "assets.json"))
+	assert.NoError(t, err)
+	mcpServer := api.NewNucleiMCPServer(&MockScannerService{MockGetAll: func() []cache.ScanResult { return nil }}, log.New(os.Stdout, "test: ", log.LstdFlags), &MockTemplateManager{},
+		api.WithScope([]string{"https://app.example.com"}), api.WithAssetRegistry(registry, assets.NewCollector()))
+
+	send := func(method string, params map[string]any) []byte {
+		data, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+		assert.NoError(t, err)
+		response, err := json.Marshal(mcpServer.HandleMessage(context.Background(), data))
+		assert.NoError(t, err)
+		return response
+	}
+
+	// The refusal is a structured error and recorded as a violation
+	var called struct {
+		Result struct {
+			IsError bool `json:"isError"`
+			Content []struct {
+				Text string `json:"text"`
+			} `json:"content"`
+		} `json:"result"`
+	}
+	assert.NoError(t, json.Unmarshal(send("tools/call", map[string]any{"name": "target_context", "arguments": map[string]any{"target": "https://other.example.org"}}), &called))
+	assert.True(t, called.Result.IsError)
+	var toolError api.ToolError
+	if assert.Len(t, called.Result.Content, 1) {
+		assert.NoError(t, json.Unmarshal([]byte(called.Result.Content[0].Text), &toolError))
+	}
+	assert.Equal(t, api.CodeScopeDenied, toolError.Code)
+
+	var dashboard struct {
+		Result struct {
+			Contents []struct {
+				Text string `json:"text"`
+			} `json:"contents"`
+		} `json:"result"`
+	}
+	assert.NoError(t, json.Unmarshal(send("resources/read", map[string]any{"uri": "dashboard"}), &dashboard))
+	if assert.Len(t, dashboard.Result.Contents, 1) {
+		assert.Contains(t, dashboard.Result.Contents[0].Text, "<td>target_context</td><td>https://other.example.org</td>")
+	}
+}
@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/projectdiscovery/nuclei/v3/pkg/model/types/severity"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandleNucleiScanTool_ScanDefaults(t *testing.T) {
+	var gotSeverity, gotProtocols string
+	mockScanner := &MockScannerService{
+		MockThreadSafeScan: func(ctx context.Context, target string, sev string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+			gotSeverity, gotProtocols = sev, protocols
+			return cache.ScanResult{Target: target, ScanTime: time.Now(), Findings: []*output.ResultEvent{
+				triageFinding("CVE-2024-0001", "RCE in Widget", severity.Critical, "https://a.example.com"),
+			}}, nil
+		},
+	}
+	defaults := api.ScanDefaults{Severity: "high", Protocols: "dns", Tags: []string{"cve"}, RateLimit: 20, Format: api.FormatJSON}
+	logger := log.New(os.Stdout, "test: ", log.LstdFlags)
+	call := func(arguments map[string]any) (*mcp.CallToolResult, error) {
+		return api.HandleNucleiScanTool(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: arguments}}, mockScanner, logger, nil, defaults)
+	}
+
+	result, err := call(map[string]any{"target": "a.example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, "high", gotSeverity)
+	assert.Equal(t, "dns", gotProtocols)
+
+	var decoded struct {
+		Target   string `json:"target"`
+		Total    int    `json:"total"`
+		Findings []struct {
+			TemplateID string `json:"template_id"`
+			Severity   string `json:"severity"`
+		} `json:"findings"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &decoded))
+	assert.Equal(t, "a.example.com", decoded.Target)
+	assert.Equal(t, 1, decoded.Total)
+	if assert.Len(t, decoded.Findings, 1) {
+		assert.Equal(t, "CVE-2024-0001", decoded.Findings[0].TemplateID)
+		assert.Equal(t, "critical", decoded.Findings[0].Severity)
+	}
+
+	// Caller arguments override the profile
+	result, err = call(map[string]any{"target": "a.example.com", "severity": "low", "protocols": "http", "format": "text"})
+	assert.NoError(t, err)
+	assert.Equal(t, "low", gotSeverity)
+	assert.Equal(t, "http", gotProtocols)
+	assert.Contains(t, result.Content[0].(mcp.TextContent).Text, "Found 1 vulnerabilities for target: a.example.com")
+
+	_, err = call(map[string]any{"target": "a.example.com", "format": "xml"})
+	assert.ErrorContains(t, err, "unsupported format")
+}
+
+func TestScanDefaults_Validate(t *testing.T) {
+	assert.NoError(t, api.DefaultScanDefaults.Validate())
+	assert.NoError(t, api.ScanDefaults{}.Validate())
+	assert.Error(t, api.ScanDefaults{Format: "yaml"}.Validate())
+	assert.Error(t, api.ScanDefaults{RateLimit: -1}.Validate())
+}
+
+func TestScannerService_Scan_TagsAndRateLimitCacheKey(t *testing.T) {
+	mockCache := new(MockResultCache)
+	mockLogger := new(MockConsoleLogger)
+	service := scanner.NewScannerService(mockCache, mockLogger)
+
+	expectedResult := cache.ScanResult{Target: "tags.com", ScanTime: time.Now(), Findings: []*output.ResultEvent{}}
+	mockCache.On("Get", "tags.com:info:http:tags=cve,panel:rl=5").Return(expectedResult, true).Once()
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+
+	result, err := service.Scan("tags.com", "info", "http", nil, scanner.WithTags("cve", " ", "panel"), scanner.WithRateLimit(5))
+	assert.NoError(t, err)
+	assert.Equal(t, expectedResult, result)
+	mockCache.AssertExpectations(t)
+}
@@ -0,0 +1,280 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/distributed"
+	"nuclei-mcp/pkg/policy"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/projectdiscovery/nuclei/v3/pkg/model"
+	"github.com/projectdiscovery/nuclei/v3/pkg/model/types/severity"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const testWorkerToken = "s3cret"
+
+// startWorker serves scan jobs for service under name
+func startWorker(t *testing.T, name string, service scanner.ScannerService) *httptest.Server {
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	handler, err := distributed.NewWorkerHandler(service, mockLogger, testWorkerToken, distributed.WithWorkerName(name))
+	assert.NoError(t, err)
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// newTestCoordinator creates a coordinator for workers with a real cache
+func newTestCoordinator(t *testing.T, workers ...distributed.Worker) *distributed.Coordinator {
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	local := &MockScannerService{
+		MockCreateCacheKey: func(target string, severity string, protocols string) string {
+			return target + ":" + severity + ":" + protocols
+		},
+	}
+	resultCache := cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags))
+	coordinator, err := distributed.NewCoordinator(local, resultCache, mockLogger, testWorkerToken, workers)
+	assert.NoError(t, err)
+	return coordinator
+}
+
+func TestCoordinator_DispatchesToWorker(t *testing.T) {
+	var scans atomic.Int32
+	worker := startWorker(t, "scanner-1", &MockScannerService{
+		MockThreadSafeScan: func(ctx context.Context, target string, minSeverity string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+			scans.Add(1)
+			assert.Equal(t, "high", minSeverity)
+			assert.Equal(t, []string{"tech-detect"}, templateIDs)
+			return cache.ScanResult{
+				Target:   target,
+				ScanTime: time.Now(),
+				Findings: []*output.ResultEvent{{
+					TemplateID: "tech-detect",
+					Host:       target,
+					Info:       model.Info{Name: "Tech Detect", SeverityHolder: severity.Holder{Severity: severity.Info}},
+				}},
+			}, nil
+		},
+	})
+	coordinator := newTestCoordinator(t, distributed.Worker{Name: "scanner-1", URL: worker.URL})
+
+	result, err := coordinator.ThreadSafeScan(context.Background(), "https://example.com", "high", "http", []string{"tech-detect"})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com", result.Target)
+	if assert.Len(t, result.Findings, 1) {
+		assert.Equal(t, "tech-detect", result.Findings[0].TemplateID)
+	}
+	assert.Len(t, coordinator.GetAll(), 1)
+
+	// The same scan is answered from the coordinator's cache
+	_, err = coordinator.ThreadSafeScan(context.Background(), "https://example.com", "high", "http", []string{"tech-detect"})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), scans.Load())
+
+	statuses := coordinator.Workers()
+	if assert.Len(t, statuses, 1) {
+		assert.True(t, statuses[0].Healthy)
+		assert.Equal(t, int64(1), statuses[0].Completed)
+		assert.Zero(t, statuses[0].Active)
+	}
+}
+
+func TestCoordinator_TLSWorker(t *testing.T) {
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	handler, err := distributed.NewWorkerHandler(&MockScannerService{
+		MockThreadSafeScan: func(ctx context.Context, target string, severity string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+			return cache.ScanResult{Target: target, ScanTime: time.Now()}, nil
+		},
+	}, mockLogger, testWorkerToken, distributed.WithWorkerName("scanner-1"))
+	assert.NoError(t, err)
+	worker := httptest.NewTLSServer(handler)
+	defer worker.Close()
+
+	local := &MockScannerService{MockCreateCacheKey: func(target string, severity string, protocols string) string { return target }}
+	resultCache := cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags))
+	coordinator, err := distributed.NewCoordinator(local, resultCache, mockLogger, testWorkerToken,
+		[]distributed.Worker{{Name: "scanner-1", URL: worker.URL}}, distributed.WithHTTPClient(worker.Client()))
+	assert.NoError(t, err)
+
+	result, err := coordinator.ThreadSafeScan(context.Background(), "https://example.com", "", "", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com", result.Target)
+}
+
+func TestCoordinator_TargetPolicy(t *testing.T) {
+	var requests, scans atomic.Int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer target.Close()
+	worker := startWorker(t, "scanner-1", &MockScannerService{
+		MockThreadSafeScan: func(ctx context.Context, target string, severity string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+			scans.Add(1)
+			return cache.ScanResult{Target: target, ScanTime: time.Now()}, nil
+		},
+	})
+	egress, err := policy.NewEgressPolicy(policy.EgressOptions{DenyPrivate: true})
+	assert.NoError(t, err)
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	local := &MockScannerService{MockCreateCacheKey: func(target string, severity string, protocols string) string { return target }}
+	resultCache := cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags))
+	coordinator, err := distributed.NewCoordinator(local, resultCache, mockLogger, testWorkerToken,
+		[]distributed.Worker{{Name: "scanner-1", URL: worker.URL}}, distributed.WithTargetPolicy(egress, nil))
+	assert.NoError(t, err)
+
+	// The target is refused before change detection fetches it
+	_, err = coordinator.ThreadSafeScan(context.Background(), target.URL, "", "", nil, scanner.WithSkipUnchanged())
+	assert.ErrorIs(t, err, policy.ErrDenied)
+	assert.Zero(t, requests.Load())
+	assert.Zero(t, scans.Load())
+}
+
+func TestCoordinator_WorkerErrorKeepsItsKind(t *testing.T) {
+	worker := startWorker(t, "scanner-1", &MockScannerService{
+		MockThreadSafeScan: func(ctx context.Context, target string, severity string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+			return cache.ScanResult{}, scanner.ErrNoTemplates
+		},
+	})
+	coordinator := newTestCoordinator(t, distributed.Worker{Name: "scanner-1", URL: worker.URL})
+
+	_, err := coordinator.ThreadSafeScan(context.Background(), "https://example.com", "", "", nil)
+	assert.ErrorIs(t, err, scanner.ErrNoTemplates)
+	assert.Contains(t, err.Error(), "scanner-1")
+	assert.Equal(t, api.ErrorCodeOf(scanner.ErrNoTemplates), api.ErrorCodeOf(err))
+	assert.Equal(t, int64(1), coordinator.Workers()[0].Failed)
+}
+
+func TestCoordinator_FailsOverToHealthyWorker(t *testing.T) {
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+	worker := startWorker(t, "scanner-2", &MockScannerService{
+		MockThreadSafeScan: func(ctx context.Context, target string, severity string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+			return cache.ScanResult{Target: target}, nil
+		},
+	})
+	coordinator := newTestCoordinator(t,
+		distributed.Worker{Name: "scanner-1", URL: dead.URL},
+		distributed.Worker{Name: "scanner-2", URL: worker.URL},
+	)
+
+	for _, target := range []string{"https://a.example.com", "https://b.example.com"} {
+		result, err := coordinator.ThreadSafeScan(context.Background(), target, "", "", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, target, result.Target)
+	}
+
+	statuses := coordinator.Workers()
+	assert.False(t, statuses[0].Healthy)
+	assert.NotEmpty(t, statuses[0].Error)
+	assert.True(t, statuses[1].Healthy)
+	assert.Equal(t, int64(2), statuses[1].Completed)
+}
+
+func TestCoordinator_NoHealthyWorkers(t *testing.T) {
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+	coordinator := newTestCoordinator(t, distributed.Worker{Name: "scanner-1", URL: dead.URL})
+
+	coordinator.CheckHealth(context.Background())
+	assert.False(t, coordinator.Workers()[0].Healthy)
+
+	_, err := coordinator.ThreadSafeScan(context.Background(), "https://example.com", "", "", nil)
+	assert.ErrorIs(t, err, distributed.ErrNoWorkers)
+}
+
+func TestCoordinator_CheckHealth(t *testing.T) {
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	handler, err := distributed.NewWorkerHandler(&MockScannerService{}, mockLogger, testWorkerToken, distributed.WithCapacity(7))
+	assert.NoError(t, err)
+	worker := httptest.NewServer(handler)
+	defer worker.Close()
+	coordinator := newTestCoordinator(t, distributed.Worker{Name: "scanner-1", URL: worker.URL + "/"})
+
+	coordinator.CheckHealth(context.Background())
+	status := coordinator.Workers()[0]
+	assert.True(t, status.Healthy)
+	assert.Equal(t, worker.URL, status.URL)
+	assert.Equal(t, 7, status.Capacity)
+	assert.False(t, status.LastSeen.IsZero())
+}
+
+func TestWorkerHandler_RequiresToken(t *testing.T) {
+	mockLogger := new(MockConsoleLogger)
+	_, err := distributed.NewWorkerHandler(&MockScannerService{}, mockLogger, "")
+	assert.Error(t, err)
+
+	worker := startWorker(t, "scanner-1", &MockScannerService{})
+	for _, header := range []string{"", "Bearer wrong", testWorkerToken} {
+		req, err := http.NewRequest(http.MethodPost, worker.URL+distributed.JobsPath, strings.NewReader(`{"target":"https://example.com"}`))
+		assert.NoError(t, err)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if assert.NoError(t, err) {
+			resp.Body.Close()
+			assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		}
+	}
+}
+
+func TestNewCoordinator_InvalidWorkers(t *testing.T) {
+	mockLogger := new(MockConsoleLogger)
+	local := &MockScannerService{}
+	resultCache := cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags))
+
+	_, err := distributed.NewCoordinator(local, resultCache, mockLogger, testWorkerToken, nil)
+	assert.Error(t, err)
+	_, err = distributed.NewCoordinator(local, resultCache, mockLogger, "", []distributed.Worker{{Name: "a", URL: "https://a"}})
+	assert.Error(t, err)
+	_, err = distributed.NewCoordinator(local, resultCache, mockLogger, testWorkerToken, []distributed.Worker{{Name: "a", URL: "https://a"}, {Name: "a", URL: "https://b"}})
+	assert.ErrorContains(t, err, "duplicate")
+
+	// The token and findings may not cross the network in clear
+	_, err = distributed.NewCoordinator(local, resultCache, mockLogger, testWorkerToken, []distributed.Worker{{Name: "a", URL: "http://10.0.0.5:8765"}})
+	assert.ErrorContains(t, err, "must use https")
+	_, err = distributed.NewCoordinator(local, resultCache, mockLogger, testWorkerToken, []distributed.Worker{{Name: "a", URL: "ftp://a"}})
+	assert.Error(t, err)
+	for _, url := range []string{"https://10.0.0.5:8765", "http://127.0.0.1:8765", "http://localhost:8765", "http://[::1]:8765"} {
+		_, err = distributed.NewCoordinator(local, resultCache, mockLogger, testWorkerToken, []distributed.Worker{{Name: "a", URL: url}})
+		assert.NoError(t, err, url)
+	}
+}
+
+func TestHandleListWorkers(t *testing.T) {
+	worker := startWorker(t, "scanner-1", &MockScannerService{})
+	coordinator := newTestCoordinator(t, distributed.Worker{Name: "scanner-1", URL: worker.URL, Capacity: 2})
+
+	result, err := api.HandleListWorkers(context.Background(), mcp.CallToolRequest{}, coordinator)
+	assert.NoError(t, err)
+	if !assert.Len(t, result.Content, 1) {
+		return
+	}
+	var statuses []distributed.WorkerStatus
+	assert.NoError(t, json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &statuses))
+	if assert.Len(t, statuses, 1) {
+		assert.Equal(t, "scanner-1", statuses[0].Name)
+		assert.Equal(t, 2, statuses[0].Capacity)
+		assert.True(t, statuses[0].Healthy)
+	}
+}
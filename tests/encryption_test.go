@@ -0,0 +1,183 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/assets"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/encryption"
+	"nuclei-mcp/pkg/scanner"
+	"nuclei-mcp/pkg/templates"
+	"nuclei-mcp/pkg/tracker"
+	"nuclei-mcp/pkg/workspace"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func testKey(t *testing.T, fill byte) *encryption.Key {
+	key, err := encryption.ParseKey(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{fill}, encryption.KeySize)))
+	assert.NoError(t, err)
+	return key
+}
+
+func TestKey_SealOpen(t *testing.T) {
+	key := testKey(t, 1)
+	sealed := key.Seal([]byte("finding"))
+	assert.True(t, encryption.Sealed(sealed))
+	assert.NotContains(t, string(sealed), "finding")
+
+	opened, err := key.Open(sealed)
+	assert.NoError(t, err)
+	assert.Equal(t, "finding", string(opened))
+
+	_, err = testKey(t, 2).Open(sealed)
+	assert.ErrorContains(t, err, "wrong key")
+	var noKey *encryption.Key
+	_, err = noKey.Open(sealed)
+	assert.ErrorIs(t, err, encryption.ErrNoKey)
+
+	line, err := key.OpenLine(key.SealLine([]byte(`{"template-id":"a"}`)))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"template-id":"a"}`, string(line))
+}
+
+func TestLoadKey(t *testing.T) {
+	t.Setenv("TEST_NUCLEI_MCP_KEY", hex.EncodeToString(bytes.Repeat([]byte{3}, encryption.KeySize)))
+	_, err := encryption.Load("TEST_NUCLEI_MCP_KEY", "")
+	assert.NoError(t, err)
+
+	keyFile := filepath.Join(t.TempDir(), "key")
+	assert.NoError(t, os.WriteFile(keyFile, []byte(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{4}, encryption.KeySize))+"\n"), 0600))
+	_, err = encryption.Load("TEST_NUCLEI_MCP_UNSET", keyFile)
+	assert.NoError(t, err)
+
+	_, err = encryption.ParseKey("c2hvcnQ=")
+	assert.ErrorContains(t, err, "must be 32 bytes")
+	_, err = encryption.Load("TEST_NUCLEI_MCP_UNSET", "")
+	assert.Error(t, err)
+}
+
+func TestWorkspace_EncryptedExport(t *testing.T) {
+	logger := log.New(os.Stdout, "test: ", log.LstdFlags)
+	key := testKey(t, 1)
+
+	srcCache := cache.NewResultCache(5*time.Minute, logger)
+	srcCache.Set("secret.example.com:info:http", cache.ScanResult{Target: "secret.example.com", ScanTime: time.Now()})
+	srcTemplates, err := templates.NewTemplateManager(t.TempDir())
+	assert.NoError(t, err)
+
+	var archive bytes.Buffer
+	_, err = workspace.NewWorkspace(srcCache, srcTemplates, workspace.WithEncryptionKey(key)).Export(&archive)
+	assert.NoError(t, err)
+	assert.True(t, encryption.Sealed(archive.Bytes()))
+	data := archive.Bytes()
+
+	dstTemplates, err := templates.NewTemplateManager(t.TempDir())
+	assert.NoError(t, err)
+	_, err = workspace.NewWorkspace(cache.NewResultCache(5*time.Minute, logger), dstTemplates).Restore(bytes.NewReader(data), workspace.RestoreOptions{})
+	assert.ErrorIs(t, err, encryption.ErrNoKey)
+
+	dstCache := cache.NewResultCache(5*time.Minute, logger)
+	_, err = workspace.NewWorkspace(dstCache, dstTemplates, workspace.WithEncryptionKey(key)).Restore(bytes.NewReader(data), workspace.RestoreOptions{})
+	assert.NoError(t, err)
+	_, found := dstCache.Get("secret.example.com:info:http")
+	assert.True(t, found)
+}
+
+func TestScannerService_EncryptedSpill(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("spill-marker"))
+	}))
+	defer srv.Close()
+
+	templateDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(templateDir, "spill-marker.yaml"), []byte(spillTemplate), 0644))
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	key := testKey(t, 1)
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger,
+		scanner.WithTemplateDirs(templateDir), scanner.WithSpillThreshold(2, t.TempDir()), scanner.WithEncryptionKey(key))
+
+	result, err := service.ThreadSafeScan(context.Background(), srv.URL, "", "", []string{"spill-marker"})
+	assert.NoError(t, err)
+	assert.True(t, strings.HasSuffix(result.SpillFile, ".jsonl.enc"))
+
+	spilled, err := os.ReadFile(result.SpillFile)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(spilled), "spill-marker")
+
+	var fingerprint string
+	for _, line := range strings.Split(strings.TrimSpace(string(spilled)), "\n") {
+		record, err := key.OpenLine([]byte(line))
+		assert.NoError(t, err)
+		var event output.ResultEvent
+		assert.NoError(t, json.Unmarshal(record, &event))
+		fingerprint = cache.Fingerprint(&event)
+	}
+	_, finding, ok := cache.FindFinding([]cache.ScanResult{result}, fingerprint, key)
+	assert.True(t, ok)
+	assert.Contains(t, finding.Response, "spill-marker")
+
+	_, _, ok = cache.FindFinding([]cache.ScanResult{result}, fingerprint, nil)
+	assert.False(t, ok, "spilled records cannot be read without the key")
+}
+
+func TestStateFiles_Encrypted(t *testing.T) {
+	key := testKey(t, 1)
+	dir := t.TempDir()
+
+	// A plain tracker is read and encrypted at the next change
+	trackerFile := filepath.Join(dir, "findings.json")
+	plain, err := tracker.Open(trackerFile)
+	assert.NoError(t, err)
+	_, err = plain.SetStatus(tracker.Record{Fingerprint: "abc123", Target: "secret.example.com", TemplateID: "weak-cipher"}, tracker.StatusTriaged)
+	assert.NoError(t, err)
+	store, err := tracker.Open(trackerFile, tracker.WithEncryptionKey(key))
+	assert.NoError(t, err)
+	_, err = store.SetStatus(tracker.Record{Fingerprint: "def456", Target: "secret.example.com", TemplateID: "xss"}, tracker.StatusTriaged)
+	assert.NoError(t, err)
+
+	registryFile := filepath.Join(dir, "assets.json")
+	registry, err := assets.Open(registryFile, assets.WithEncryptionKey(key))
+	assert.NoError(t, err)
+	_, err = registry.Put(assets.Asset{Host: "secret.example.com"})
+	assert.NoError(t, err)
+
+	for _, path := range []string{trackerFile, registryFile} {
+		data, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.True(t, encryption.Sealed(data), path)
+		assert.NotContains(t, string(data), "secret.example.com")
+		info, err := os.Stat(path)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), path)
+	}
+
+	reopened, err := tracker.Open(trackerFile, tracker.WithEncryptionKey(key))
+	assert.NoError(t, err)
+	assert.Len(t, reopened.List(), 2)
+	reloaded, err := assets.Open(registryFile, assets.WithEncryptionKey(key))
+	assert.NoError(t, err)
+	_, found := reloaded.Get("secret.example.com")
+	assert.True(t, found)
+
+	_, err = tracker.Open(trackerFile)
+	assert.ErrorIs(t, err, encryption.ErrNoKey)
+	_, err = assets.Open(registryFile, assets.WithEncryptionKey(testKey(t, 2)))
+	assert.Error(t, err)
+}
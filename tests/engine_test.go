@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"nuclei-mcp/pkg/engine"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func buildReleaseTarball(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		assert.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, tw.Close())
+	assert.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestUpdater_Info(t *testing.T) {
+	updater := engine.NewUpdater(t.TempDir(), "")
+	info := updater.Info()
+	assert.NotEmpty(t, info.EngineVersion)
+	assert.True(t, info.PinSatisfied)
+
+	updater = engine.NewUpdater(t.TempDir(), "v0.0.1-never-installed")
+	assert.False(t, updater.Info().PinSatisfied)
+}
+
+func TestUpdater_UpdateInstallsPinnedRelease(t *testing.T) {
+	tarball := buildReleaseTarball(t, map[string]string{
+		"nuclei-templates-10.1.5/http/test.yaml": "id: test",
+	})
+
+	var requested string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.Path
+		w.Write(tarball)
+	}))
+	defer srv.Close()
+
+	dir := filepath.Join(t.TempDir(), "nuclei-templates")
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "http"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "http", "removed.yaml"), []byte("id: removed"), 0644))
+	updater := engine.NewUpdater(dir, "v10.1.5")
+	updater.ReleaseURL = srv.URL + "/%s.tar.gz"
+
+	version, err := updater.Update(context.Background(), "")
+	assert.NoError(t, err)
+	assert.Equal(t, "v10.1.5", version)
+	assert.Equal(t, "/v10.1.5.tar.gz", requested)
+
+	content, err := os.ReadFile(filepath.Join(dir, "http", "test.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, "id: test", string(content))
+
+	// The release replaces the directory, without templates removed upstream
+	// or staging leftovers
+	assert.NoFileExists(t, filepath.Join(dir, "http", "removed.yaml"))
+	entries, err := os.ReadDir(filepath.Dir(dir))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestUpdater_UpdateRejectsInvalidVersions(t *testing.T) {
+	updater := engine.NewUpdater(t.TempDir(), "")
+	_, err := updater.Update(context.Background(), "")
+	assert.Error(t, err)
+
+	_, err = updater.Update(context.Background(), "latest; rm -rf /")
+	assert.Error(t, err)
+
+	updater = engine.NewUpdater(t.TempDir(), "v10.1.5")
+	_, err = updater.Update(context.Background(), "v10.2.0")
+	assert.Error(t, err)
+}
+
+func TestUpdater_CheckUpdates(t *testing.T) {
+	releases := map[string]string{
+		"/repos/projectdiscovery/nuclei-templates/releases/latest": `{"tag_name":"v99.0.0","html_url":"https://example.com/templates","published_at":"2026-01-02T03:04:05Z","body":"## New templates\n- **CVE-2099-0001** detection\nThanks to all contributors\n* ` + "`fix`" + ` of a matcher"}`,
+		"/repos/projectdiscovery/nuclei/releases/latest":           `{"tag_name":"v0.0.1","html_url":"https://example.com/nuclei","body":"- old"}`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := releases[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	updater := engine.NewUpdater(t.TempDir(), "v10.1.5")
+	updater.ReleasesURL = srv.URL + "/repos/%s/releases/latest"
+
+	check, err := updater.CheckUpdates(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, check.Templates.UpdateAvailable)
+	assert.Equal(t, "v99.0.0", check.Templates.Latest)
+	assert.Equal(t, "https://example.com/templates", check.Templates.ReleaseURL)
+	assert.Equal(t, []string{"New templates", "CVE-2099-0001 detection", "fix of a matcher"}, check.Templates.ReleaseNotes)
+	assert.Contains(t, check.Templates.Hint, "nuclei.templates_version")
+
+	assert.False(t, check.Engine.UpdateAvailable)
+	assert.Equal(t, "v0.0.1", check.Engine.Latest)
+	assert.Empty(t, check.Engine.ReleaseNotes)
+
+	// A single failing release is reported without failing the check
+	delete(releases, "/repos/projectdiscovery/nuclei/releases/latest")
+	check, err = updater.CheckUpdates(context.Background())
+	assert.NoError(t, err)
+	assert.NotEmpty(t, check.Engine.Error)
+
+	delete(releases, "/repos/projectdiscovery/nuclei-templates/releases/latest")
+	_, err = updater.CheckUpdates(context.Background())
+	assert.Error(t, err)
+}
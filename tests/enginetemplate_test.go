@@ -0,0 +1,57 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"nuclei-mcp/pkg/api"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleGetEngineTemplate(t *testing.T) {
+	official, custom := t.TempDir(), t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(official, "http", "cves"), 0755))
+	officialPath := filepath.Join(official, "http", "cves", "CVE-2021-44228.yaml")
+	assert.NoError(t, os.WriteFile(officialPath, []byte("id: CVE-2021-44228\ninfo:\n  name: Log4Shell\n  author: nuclei-mcp\n  severity: critical\n"), 0644))
+	customPath := filepath.Join(custom, "log4shell.yaml")
+	assert.NoError(t, os.WriteFile(customPath, []byte("id: CVE-2021-44228\ninfo:\n  name: Custom Log4Shell\n  author: nuclei-mcp\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(custom, "acme.yaml"), []byte(catalogTemplate), 0644))
+
+	get := func(args map[string]any) (*api.EngineTemplate, error) {
+		result, err := api.HandleGetEngineTemplate(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: args}}, nil, []string{official, custom})
+		if err != nil {
+			return nil, err
+		}
+		var template api.EngineTemplate
+		assert.NoError(t, json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &template))
+		return &template, nil
+	}
+
+	// The first directory holding the ID wins and shadows the others
+	template, err := get(map[string]any{"id": "cve-2021-44228"})
+	assert.NoError(t, err)
+	assert.Equal(t, "CVE-2021-44228", template.ID)
+	assert.Equal(t, "Log4Shell", template.Name)
+	assert.Equal(t, officialPath, template.Path)
+	assert.Equal(t, official, template.Source)
+	assert.Equal(t, "yaml", template.Format)
+	assert.Contains(t, template.Content, "severity: critical")
+	assert.Equal(t, []string{customPath}, template.Shadowed)
+
+	template, err = get(map[string]any{"id": "acme-login", "format": "json"})
+	assert.NoError(t, err)
+	assert.Equal(t, custom, template.Source)
+	assert.Equal(t, "json", template.Format)
+	assert.True(t, json.Valid([]byte(template.Content)))
+	assert.Empty(t, template.Shadowed)
+
+	_, err = get(map[string]any{"id": "missing-template"})
+	assert.ErrorContains(t, err, "not found")
+	_, err = get(map[string]any{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,155 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/enrich"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// mmdbValue encodes a value in the MaxMind DB data format: strings, uint32
+// and maps given as key/value pairs
+func mmdbValue(value any) []byte {
+	switch v := value.(type) {
+	case string:
+		if len(v) >= 29 {
+			return append([]byte{2<<5 | 29, byte(len(v) - 29)}, v...)
+		}
+		return append([]byte{2<<5 | byte(len(v))}, v...)
+	case uint32:
+		return []byte{6<<5 | 4, byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+	case []any:
+		encoded := []byte{7<<5 | byte(len(v)/2)}
+		for _, item := range v {
+			encoded = append(encoded, mmdbValue(item)...)
+		}
+		return encoded
+	}
+	panic(fmt.Sprintf("unsupported value %T", value))
+}
+
+// writeMMDB writes an IPv4 MaxMind DB with 24-bit records mapping the /24
+// network of ip to record
+func writeMMDB(t *testing.T, ip net.IP, record []any) string {
+	const nodeCount = 24
+	ip = ip.To4()
+	var tree []byte
+	for i := 0; i < nodeCount; i++ {
+		next := uint32(i + 1)
+		if i == nodeCount-1 {
+			next = nodeCount + 16 // the record at offset 0 of the data section
+		}
+		records := [2]uint32{nodeCount, nodeCount}
+		records[(ip[i/8]>>(7-uint(i%8)))&1] = next
+		for _, r := range records {
+			tree = append(tree, byte(r>>16), byte(r>>8), byte(r))
+		}
+	}
+
+	var db bytes.Buffer
+	db.Write(tree)
+	db.Write(make([]byte, 16))
+	db.Write(mmdbValue(record))
+	db.WriteString("\xAB\xCD\xEFMaxMind.com")
+	db.Write(mmdbValue([]any{"node_count", uint32(nodeCount), "record_size", uint32(24), "ip_version", uint32(4)}))
+
+	path := filepath.Join(t.TempDir(), "test.mmdb")
+	assert.NoError(t, os.WriteFile(path, db.Bytes(), 0644))
+	return path
+}
+
+type staticResolver map[string][]net.IPAddr
+
+func (r staticResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	if addrs, ok := r[host]; ok {
+		return addrs, nil
+	}
+	return nil, fmt.Errorf("no such host %s", host)
+}
+
+func TestDB_Lookup(t *testing.T) {
+	db, err := enrich.OpenDB(writeMMDB(t, net.ParseIP("198.51.100.0"), []any{
+		"autonomous_system_number", uint32(64500),
+		"autonomous_system_organization", "Example Hosting",
+	}))
+	assert.NoError(t, err)
+
+	record, err := db.Lookup(net.ParseIP("198.51.100.7"))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(64500), record["autonomous_system_number"])
+	assert.Equal(t, "Example Hosting", record["autonomous_system_organization"])
+
+	record, err = db.Lookup(net.ParseIP("198.51.101.7"))
+	assert.NoError(t, err)
+	assert.Nil(t, record)
+
+	_, err = enrich.OpenDB(filepath.Join(t.TempDir(), "missing.mmdb"))
+	assert.Error(t, err)
+	garbage := filepath.Join(t.TempDir(), "garbage.mmdb")
+	assert.NoError(t, os.WriteFile(garbage, []byte("not a database"), 0644))
+	_, err = enrich.OpenDB(garbage)
+	assert.ErrorContains(t, err, "metadata not found")
+}
+
+func TestEnricher_Enrich(t *testing.T) {
+	asn, err := enrich.OpenDB(writeMMDB(t, net.ParseIP("198.51.100.0"), []any{
+		"autonomous_system_number", uint32(64500),
+		"autonomous_system_organization", "Example Hosting",
+	}))
+	assert.NoError(t, err)
+	geo, err := enrich.OpenDB(writeMMDB(t, net.ParseIP("198.51.100.0"), []any{
+		"city", []any{"names", []any{"en", "Amsterdam"}},
+		"country", []any{"iso_code", "NL", "names", []any{"en", "Netherlands"}},
+	}))
+	assert.NoError(t, err)
+
+	enricher := enrich.New(enrich.WithASNDatabase(asn), enrich.WithGeoDatabase(geo), enrich.WithResolver(staticResolver{
+		"app.example.com": {{IP: net.ParseIP("198.51.100.7")}, {IP: net.ParseIP("10.0.0.5")}},
+	}))
+
+	info := enricher.Enrich(context.Background(), "app.example.com")
+	assert.Empty(t, info.Error)
+	assert.Equal(t, []cache.AddressInfo{
+		{IP: "198.51.100.7", ASN: 64500, ASOrg: "Example Hosting", Country: "Netherlands", CountryCode: "NL", City: "Amsterdam"},
+		{IP: "10.0.0.5", Private: true},
+	}, info.Addresses)
+
+	info = enricher.Enrich(context.Background(), "gone.example.com")
+	assert.Contains(t, info.Error, "no such host")
+	assert.Empty(t, info.Addresses)
+}
+
+func TestScannerService_EnrichesHosts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("spill-marker"))
+	}))
+	defer srv.Close()
+
+	templateDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(templateDir, "spill-marker.yaml"), []byte(spillTemplate), 0644))
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger,
+		scanner.WithTemplateDirs(templateDir), scanner.WithHostEnricher(enrich.New()))
+
+	result, err := service.ThreadSafeScan(context.Background(), srv.URL, "", "", []string{"spill-marker"})
+	assert.NoError(t, err)
+	assert.Len(t, result.Hosts, 1, "findings on the target's host are not enriched twice")
+	assert.Equal(t, "127.0.0.1", result.Hosts[0].Host)
+	assert.Equal(t, []cache.AddressInfo{{IP: "127.0.0.1", Private: true}}, result.Hosts[0].Addresses)
+}
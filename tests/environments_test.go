@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+func environmentScan(target string, scanned time.Time, labels map[string]string, findings ...*output.ResultEvent) cache.ScanResult {
+	return cache.ScanResult{Target: target, ScanTime: scanned, Labels: labels, Findings: findings}
+}
+
+func TestHandleCompareEnvironments(t *testing.T) {
+	now := time.Now()
+	staging := map[string]string{"environment": "staging", "app": "shop"}
+	production := map[string]string{"environment": "production", "app": "shop"}
+	finding := func(templateID, matched string) *output.ResultEvent {
+		return &output.ResultEvent{TemplateID: templateID, Host: matched, Matched: matched}
+	}
+	service := &MockScannerService{MockGetAll: func() []cache.ScanResult {
+		return []cache.ScanResult{
+			environmentScan("https://staging.shop.test", now.Add(-2*time.Hour), staging,
+				finding("stale-finding", "https://staging.shop.test/old")),
+			environmentScan("https://staging.shop.test", now.Add(-time.Hour), staging,
+				finding("git-config", "https://staging.shop.test/.git/config"),
+				finding("debug-panel", "https://staging.shop.test/debug"),
+				finding("weak-cipher", "staging.shop.test:443")),
+			environmentScan("https://www.shop.test", now, production,
+				finding("git-config", "https://www.shop.test/.git/config"),
+				finding("weak-cipher", "www.shop.test:443"),
+				finding("missing-hsts", "https://www.shop.test/")),
+			// Another application
+			environmentScan("https://www.blog.test", now, map[string]string{"environment": "production", "app": "blog"},
+				finding("debug-panel", "https://www.blog.test/debug")),
+		}
+	}}
+	compare := func(arguments map[string]any) (api.EnvironmentComparison, error) {
+		var comparison api.EnvironmentComparison
+		result, err := api.HandleCompareEnvironments(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: arguments}}, service)
+		if err != nil {
+			return comparison, err
+		}
+		err = json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &comparison)
+		return comparison, err
+	}
+
+	comparison, err := compare(map[string]any{
+		"environments": []any{"staging", "production"},
+		"labels":       map[string]any{"app": "shop"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://staging.shop.test"}, comparison.Targets["staging"])
+	assert.Equal(t, []string{"https://www.shop.test"}, comparison.Targets["production"])
+	// git-config and weak-cipher match despite the different hosts
+	assert.Equal(t, 2, comparison.Common)
+	if assert.Len(t, comparison.Only["staging"], 1) {
+		assert.Equal(t, "debug-panel", comparison.Only["staging"][0].TemplateID)
+	}
+	if assert.Len(t, comparison.Only["production"], 1) {
+		assert.Equal(t, "missing-hsts", comparison.Only["production"][0].TemplateID)
+		assert.Equal(t, "/", comparison.Only["production"][0].Path)
+	}
+
+	// Without the app filter, the blog's debug panel is at the same path
+	comparison, err = compare(map[string]any{"environments": []any{"staging", "production"}})
+	assert.NoError(t, err)
+	assert.Empty(t, comparison.Only["staging"])
+
+	_, err = compare(map[string]any{"environments": []any{"staging", "qa"}})
+	assert.ErrorContains(t, err, "no cached scans labelled environment=qa")
+	_, err = compare(map[string]any{"environments": []any{"staging"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,202 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/exclusions"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestExclusionStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "exclusions.json")
+	store, err := exclusions.Open(path)
+	assert.NoError(t, err)
+	assert.Empty(t, store.List())
+
+	_, err = store.Add(exclusions.Rule{Target: "*.example.com"})
+	assert.Error(t, err)
+	_, err = store.Add(exclusions.Rule{TemplateIDs: []string{"tech-detect"}})
+	assert.Error(t, err)
+
+	hostRule, err := store.Add(exclusions.Rule{Target: "*.example.com", TemplateIDs: []string{"tech-detect", " "}, Reason: "noisy"})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, hostRule.ID)
+	assert.Equal(t, []string{"tech-detect"}, hostRule.TemplateIDs)
+	_, err = store.Add(exclusions.Rule{Target: "https://app.example.com/api/*", Tags: []string{"XSS"}, TemplateIDs: []string{"tech-detect"}})
+	assert.NoError(t, err)
+
+	ids, tags := store.Match("https://App.example.com/api/users")
+	assert.Equal(t, []string{"tech-detect"}, ids)
+	assert.Equal(t, []string{"xss"}, tags)
+
+	ids, tags = store.Match("https://app.example.com/login")
+	assert.Equal(t, []string{"tech-detect"}, ids)
+	assert.Empty(t, tags)
+
+	ids, tags = store.Match("example.org")
+	assert.Empty(t, ids)
+	assert.Empty(t, tags)
+
+	// Rules survive a restart
+	reopened, err := exclusions.Open(path)
+	assert.NoError(t, err)
+	assert.Len(t, reopened.List(), 2)
+	ids, _ = reopened.Match("api.example.com:8443")
+	assert.Equal(t, []string{"tech-detect"}, ids)
+
+	assert.NoError(t, reopened.Remove(hostRule.ID))
+	assert.Error(t, reopened.Remove(hostRule.ID))
+	ids, _ = reopened.Match("api.example.com")
+	assert.Empty(t, ids)
+}
+
+func TestScannerService_Scan_Exclusions(t *testing.T) {
+	store, err := exclusions.Open(filepath.Join(t.TempDir(), "exclusions.json"))
+	assert.NoError(t, err)
+	_, err = store.Add(exclusions.Rule{Target: "noisy.com", TemplateIDs: []string{"tech-detect"}, Tags: []string{"panel"}})
+	assert.NoError(t, err)
+
+	mockCache := new(MockResultCache)
+	mockLogger := new(MockConsoleLogger)
+	service := scanner.NewScannerService(mockCache, mockLogger, scanner.WithExclusions(store))
+
+	expectedResult := cache.ScanResult{Target: "noisy.com", ScanTime: time.Now(), Findings: []*output.ResultEvent{}}
+	mockCache.On("Get", "noisy.com:info:http:excl=tech-detect/panel").Return(expectedResult, true).Once()
+	mockCache.On("Get", "quiet.com:info:http").Return(expectedResult, true).Once()
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+
+	result, err := service.Scan("noisy.com", "info", "http", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedResult, result)
+	mockLogger.AssertCalled(t, "Log", "Exclusion rules for %s: skipping templates %v and tags %v",
+		[]interface{}{"noisy.com", []string{"tech-detect"}, []string{"panel"}})
+
+	_, err = service.Scan("quiet.com", "info", "http", nil)
+	assert.NoError(t, err)
+	mockCache.AssertExpectations(t)
+}
+
+func TestHandleExclusionTools(t *testing.T) {
+	store, err := exclusions.Open(filepath.Join(t.TempDir(), "exclusions.json"))
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	result, err := api.HandleAddExclusion(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: map[string]interface{}{
+		"target":       "*.example.com",
+		"template_ids": "tech-detect, waf-detect",
+		"reason":       "FP-12",
+	}}}, store)
+	assert.NoError(t, err)
+	var rule exclusions.Rule
+	assert.NoError(t, json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &rule))
+	assert.Equal(t, []string{"tech-detect", "waf-detect"}, rule.TemplateIDs)
+	assert.Empty(t, rule.Tags)
+	assert.Equal(t, "FP-12", rule.Reason)
+
+	_, err = api.HandleAddExclusion(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: map[string]interface{}{
+		"target": "*.example.com",
+	}}}, store)
+	assert.Error(t, err)
+
+	result, err = api.HandleListExclusions(ctx, mcp.CallToolRequest{}, store)
+	assert.NoError(t, err)
+	var rules []exclusions.Rule
+	assert.NoError(t, json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &rules))
+	assert.Len(t, rules, 1)
+
+	_, err = api.HandleRemoveExclusion(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: map[string]interface{}{"id": rule.ID}}}, store)
+	assert.NoError(t, err)
+	assert.Empty(t, store.List())
+}
+
+func TestExclusionStore_ImportExport(t *testing.T) {
+	store, err := exclusions.Open(filepath.Join(t.TempDir(), "exclusions.json"))
+	assert.NoError(t, err)
+
+	expires, err := exclusions.ParseExpiry("2099-01-31", time.Now())
+	assert.NoError(t, err)
+	_, err = store.Add(exclusions.Rule{Target: "*.example.com", TemplateIDs: []string{"tech-detect"}, Reason: "noisy", ExpiresAt: &expires})
+	assert.NoError(t, err)
+	_, err = store.Add(exclusions.Rule{Target: "legacy.example.com", Tags: []string{"xss"}})
+	assert.NoError(t, err)
+	past := time.Now().Add(-time.Hour)
+	_, err = store.Add(exclusions.Rule{Target: "old.example.com", Tags: []string{"xss"}, ExpiresAt: &past})
+	assert.ErrorContains(t, err, "in the past")
+
+	data, err := store.Export()
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "- target: '*.example.com'\n    template_ids: [tech-detect]\n    reason: noisy\n    expires_at: 2099-01-31T00:00:00Z\n")
+
+	// Imported into a fresh store, such as after a reinstall
+	restored, err := exclusions.Open(filepath.Join(t.TempDir(), "exclusions.json"))
+	assert.NoError(t, err)
+	summary, err := restored.Import(data, false)
+	assert.NoError(t, err)
+	assert.Equal(t, exclusions.ImportSummary{Added: 2}, summary)
+	ids, _ := restored.Match("https://www.example.com")
+	assert.Equal(t, []string{"tech-detect"}, ids)
+
+	// The reviewed file drops a rule, expires another and adds one
+	reviewed := `exclusions:
+  - target: "*.example.com"
+    template_ids: [tech-detect]
+    reason: FP-42
+    expires_at: 2020-01-01T00:00:00Z
+  - target: "*.internal.test"
+    tags: [dos]
+`
+	summary, err = restored.Import([]byte(reviewed), true)
+	assert.NoError(t, err)
+	assert.Equal(t, exclusions.ImportSummary{Added: 1, Updated: 1, Removed: 1}, summary)
+	assert.Len(t, restored.List(), 2)
+	// Expired rules are kept but no longer match
+	ids, _ = restored.Match("https://www.example.com")
+	assert.Empty(t, ids)
+
+	_, err = restored.Import([]byte("exclusions:\n  - target: \"*.example.com\"\n"), false)
+	assert.ErrorContains(t, err, "invalid exclusion 1")
+	_, err = restored.Import([]byte("exclusions:\n  - target: x\n    templates: [a]\n"), false)
+	assert.Error(t, err)
+	assert.Len(t, restored.List(), 2)
+}
+
+func TestHandleImportExclusions(t *testing.T) {
+	store, err := exclusions.Open(filepath.Join(t.TempDir(), "exclusions.json"))
+	assert.NoError(t, err)
+
+	result, err := api.HandleImportExclusions(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: map[string]any{
+		"content": "exclusions:\n  - target: \"*.example.com\"\n    template_ids: [tech-detect]\n",
+	}}}, store)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"added":1,"updated":0,"removed":0}`, result.Content[0].(mcp.TextContent).Text)
+
+	result, err = api.HandleAddExclusion(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: map[string]any{
+		"target": "staging.example.com", "tags": []any{"xss"}, "expires": "48h",
+	}}}, store)
+	assert.NoError(t, err)
+	var rule exclusions.Rule
+	assert.NoError(t, json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &rule))
+	if assert.NotNil(t, rule.ExpiresAt) {
+		assert.WithinDuration(t, time.Now().Add(48*time.Hour), *rule.ExpiresAt, time.Minute)
+	}
+
+	_, err = api.HandleAddExclusion(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: map[string]any{
+		"target": "staging.example.com", "tags": []any{"xss"}, "expires": "next week",
+	}}}, store)
+	assert.ErrorContains(t, err, "invalid expiry")
+
+	result, err = api.HandleExportExclusions(context.Background(), mcp.CallToolRequest{}, store)
+	assert.NoError(t, err)
+	assert.Contains(t, result.Content[0].(mcp.TextContent).Text, "- target: staging.example.com\n    tags: [xss]\n")
+}
@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRunExtractors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "nginx/1.25.3")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"version":"2.4.1","build":"b1234"}`))
+	}))
+	defer srv.Close()
+
+	extractions, err := scanner.RunExtractors(context.Background(), srv.URL, []scanner.Extractor{
+		{Type: scanner.ExtractorPreset, Expression: "server-banner"},
+		{Name: "api-version", Type: scanner.ExtractorJSON, Expression: ".version"},
+		{Type: scanner.ExtractorRegex, Expression: `"build":"(b\d+)"`, Group: 1},
+	})
+	assert.NoError(t, err)
+
+	values := map[string][]string{}
+	for _, extraction := range extractions {
+		values[extraction.Name] = extraction.Values
+	}
+	assert.Equal(t, map[string][]string{
+		"api-version":   {"2.4.1"},
+		"extractor-3":   {"b1234"},
+		"server-banner": {"nginx/1.25.3"},
+	}, values)
+}
+
+func TestRunExtractors_InvalidExtractors(t *testing.T) {
+	invalid := [][]scanner.Extractor{
+		{{Type: scanner.ExtractorPreset, Expression: "no-such-preset"}},
+		{{Type: scanner.ExtractorRegex, Expression: "("}},
+		{{Type: scanner.ExtractorRegex, Expression: "v(\\d+)", Group: 2}},
+		{{Type: "xpath", Expression: "//title"}},
+		{{Type: scanner.ExtractorJSON, Expression: ""}},
+		{{Type: scanner.ExtractorJSON, Expression: ".a", Part: "cookie"}},
+	}
+	for _, extractors := range invalid {
+		_, err := scanner.RunExtractors(context.Background(), "http://127.0.0.1:1", extractors)
+		assert.Error(t, err, "%+v", extractors)
+	}
+}
+
+func TestScannerService_Scan_InvalidExtractors(t *testing.T) {
+	mockCache := new(MockResultCache)
+	mockLogger := new(MockConsoleLogger)
+	service := scanner.NewScannerService(mockCache, mockLogger)
+
+	_, err := service.Scan("example.com", "info", "http", nil, scanner.WithExtractors(scanner.Extractor{Type: "xpath", Expression: "//a"}))
+	assert.Error(t, err)
+	mockCache.AssertNotCalled(t, "Get", mock.Anything)
+}
+
+func TestHandleNucleiScanTool_Extractors(t *testing.T) {
+	mockScanner := &MockScannerService{
+		MockThreadSafeScan: func(ctx context.Context, target string, severity string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+			return cache.ScanResult{
+				Target:   target,
+				ScanTime: time.Now(),
+				Extractions: []cache.Extraction{
+					{Name: "server-banner", URL: "https://example.com", Values: []string{"nginx/1.25.3"}},
+				},
+			}, nil
+		},
+	}
+
+	request := mcp.CallToolRequest{
+		Params: mcp.CallToolParams{
+			Arguments: map[string]interface{}{
+				"target": "https://example.com",
+				"extractors": []interface{}{
+					map[string]interface{}{"type": "preset", "expression": "server-banner"},
+				},
+			},
+		},
+	}
+
+	result, err := api.HandleNucleiScanTool(context.Background(), request, mockScanner, log.New(os.Stdout, "test: ", log.LstdFlags), nil, api.DefaultScanDefaults)
+	assert.NoError(t, err)
+	assert.Contains(t, result.Content[0].(mcp.TextContent).Text, "server-banner (https://example.com): nginx/1.25.3")
+
+	request.Params.Arguments = map[string]interface{}{
+		"target":     "https://example.com",
+		"extractors": "not-a-list",
+	}
+	_, err = api.HandleNucleiScanTool(context.Background(), request, mockScanner, log.New(os.Stdout, "test: ", log.LstdFlags), nil, api.DefaultScanDefaults)
+	assert.Error(t, err)
+}
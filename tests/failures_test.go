@@ -0,0 +1,136 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestFailureCause(t *testing.T) {
+	causes := map[error]string{
+		&net.DNSError{Err: "no such host", Name: "missing.example"}:                        scanner.FailureDNS,
+		&net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}: scanner.FailureConnectionRefused,
+		fmt.Errorf("read: %w", syscall.ECONNRESET):                                         scanner.FailureConnectionReset,
+		fmt.Errorf("request failed: %w", context.DeadlineExceeded):                         scanner.FailureTimeout,
+		// nuclei's wrapped errors keep only the message
+		errors.New("[:RUNTIME] got err while executing https://a.example.com <- tls: handshake failure"): scanner.FailureTLS,
+		errors.New("could not resolve host: missing.example"):                                            scanner.FailureDNS,
+		errors.New("dial tcp 10.0.0.1:443: connect: no route to host"):                                   scanner.FailureUnreachable,
+		errors.New("unexpected status"):                                                                  scanner.FailureOther,
+	}
+	for err, cause := range causes {
+		assert.Equal(t, cause, scanner.FailureCause(err), err.Error())
+	}
+}
+
+func TestScannerService_RequestFailures(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" || r.URL.Path == "/page-0" {
+			_, _ = w.Write([]byte("ok"))
+			return
+		}
+		// Drop the connection without a response
+		if conn, _, err := w.(http.Hijacker).Hijack(); err == nil {
+			conn.Close()
+		}
+	}))
+	defer srv.Close()
+
+	templateFile := filepath.Join(t.TempDir(), "paths.yaml")
+	assert.NoError(t, os.WriteFile(templateFile, []byte(fragileTemplate(3)), 0644))
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger)
+
+	result, err := service.Scan(srv.Listener.Addr().String(), "", "", nil, scanner.WithTemplateSources(templateFile))
+	assert.NoError(t, err)
+	if assert.NotNil(t, result.Stats) && assert.Len(t, result.Stats.Failures, 1) {
+		failures := result.Stats.Failures[0]
+		assert.Equal(t, "127.0.0.1", failures.Host)
+		assert.Equal(t, result.Stats.Requests, failures.Requests)
+		assert.Positive(t, failures.Failed)
+		assert.Equal(t, failures.Failed, failures.Causes[scanner.FailureConnectionReset], failures.Causes)
+		assert.False(t, failures.Unreachable, "the first page loaded")
+	}
+
+	// nuclei drops a target it cannot connect to without sending a
+	// request, so the scan tries connecting itself
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	closed := listener.Addr().String()
+	listener.Close()
+	for _, target := range []string{closed, "http://" + closed} {
+		result, err := service.Scan(target, "", "", nil, scanner.WithTemplateSources(templateFile))
+		assert.NoError(t, err)
+		assert.Empty(t, result.Findings)
+		if assert.NotNil(t, result.Stats) && assert.Len(t, result.Stats.Failures, 1, target) {
+			failures := result.Stats.Failures[0]
+			assert.Equal(t, "127.0.0.1", failures.Host)
+			assert.Equal(t, 1, failures.Requests)
+			assert.True(t, failures.Unreachable)
+			assert.Equal(t, 1, failures.Causes[scanner.FailureConnectionRefused], failures.Causes)
+			assert.Contains(t, failures.Example, "refused")
+		}
+	}
+}
+
+func TestScannerService_RequestFailuresThreadSafe(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" || r.URL.Path == "/page-0" {
+			_, _ = w.Write([]byte("ok"))
+			return
+		}
+		if conn, _, err := w.(http.Hijacker).Hijack(); err == nil {
+			conn.Close()
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "paths.yaml"), []byte(fragileTemplate(3)), 0644))
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger,
+		scanner.WithTemplateDirs(dir))
+
+	check := func(result cache.ScanResult) {
+		if assert.NotNil(t, result.Stats) && assert.Len(t, result.Stats.Failures, 1) {
+			failures := result.Stats.Failures[0]
+			assert.Equal(t, "127.0.0.1", failures.Host)
+			assert.Equal(t, result.Stats.Requests, failures.Requests)
+			assert.Positive(t, failures.Failed)
+			assert.Equal(t, failures.Failed, failures.Causes[scanner.FailureConnectionReset], failures.Causes)
+		}
+	}
+
+	// On a fresh thread-safe engine
+	result, err := service.ThreadSafeScan(context.Background(), srv.URL, "", "", []string{"fragile-paths"})
+	assert.NoError(t, err)
+	check(result)
+
+	// On the warm engine, where each scan only counts its own requests
+	assert.NoError(t, service.(scanner.Preloader).Preload(context.Background()))
+	for i := 0; i < 2; i++ {
+		result, err = service.ThreadSafeScan(context.Background(), fmt.Sprintf("%s/%d", srv.URL, i), "", "", []string{"fragile-paths"})
+		assert.NoError(t, err)
+		check(result)
+		assert.Equal(t, 3, result.Stats.Requests)
+	}
+}
@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func resolvedOptions(defaults api.ScanDefaults, argMap map[string]any) scanner.ScanOptions {
+	_, _, opts := defaults.Resolve(argMap)
+	var scanOpts scanner.ScanOptions
+	for _, opt := range opts {
+		opt(&scanOpts)
+	}
+	return scanOpts
+}
+
+func TestScanDefaults_ResolveFilters(t *testing.T) {
+	defaults := api.ScanDefaults{
+		ExcludeTags:       []string{"dos"},
+		ExcludeIDs:        []string{"noisy-template"},
+		ExcludeSeverities: []string{"info"},
+		Authors:           []string{"pdteam"},
+		TemplateIDs:       []string{"tech-detect"},
+	}
+
+	scanOpts := resolvedOptions(defaults, map[string]any{"target": "a.example.com"})
+	assert.Equal(t, []string{"dos"}, scanOpts.ExcludeTags)
+	assert.Equal(t, []string{"noisy-template"}, scanOpts.ExcludeIDs)
+	assert.Equal(t, []string{"info"}, scanOpts.ExcludeSeverities)
+	assert.Equal(t, []string{"pdteam"}, scanOpts.Authors)
+	assert.Equal(t, []string{"tech-detect"}, scanOpts.DefaultTemplateIDs)
+
+	// Arguments of the same name replace the defaults, and empty exclusions
+	// lift them
+	scanOpts = resolvedOptions(defaults, map[string]any{
+		"exclude_tags":       []any{"intrusive"},
+		"exclude_ids":        []any{},
+		"exclude_severities": []any{},
+		"authors":            []any{"acme"},
+	})
+	assert.Equal(t, []string{"intrusive"}, scanOpts.ExcludeTags)
+	assert.Empty(t, scanOpts.ExcludeIDs)
+	assert.Empty(t, scanOpts.ExcludeSeverities)
+	assert.Equal(t, []string{"acme"}, scanOpts.Authors)
+
+	assert.NoError(t, defaults.Validate())
+	assert.ErrorIs(t, api.ScanDefaults{ExcludeSeverities: []string{"severe"}}.Validate(), scanner.ErrInvalidSeverity)
+}
+
+func TestScannerService_Scan_DefaultFiltersCacheKey(t *testing.T) {
+	mockCache := new(MockResultCache)
+	mockLogger := new(MockConsoleLogger)
+	service := scanner.NewScannerService(mockCache, mockLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+
+	expected := cache.ScanResult{Target: "filters.com", ScanTime: time.Now(), Findings: []*output.ResultEvent{}}
+	opts := []scanner.ScanOption{
+		scanner.WithDefaultTemplateIDs("tech-detect"),
+		scanner.WithAuthors("pdteam"),
+		scanner.WithExcludedTags("dos"),
+		scanner.WithExcludedSeverities("info"),
+	}
+
+	// The default template IDs run when the scan names none
+	mockCache.On("Get", "filters.com:info:http:tech-detect:authors=pdteam:without=dos//info").Return(expected, true).Once()
+	result, err := service.Scan("filters.com", "info", "http", nil, opts...)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, result)
+
+	mockCache.On("Get", "filters.com:info:http:ssl-dns-names:authors=pdteam:without=dos//info").Return(expected, true).Once()
+	_, err = service.Scan("filters.com", "info", "http", []string{"ssl-dns-names"}, opts...)
+	assert.NoError(t, err)
+	mockCache.AssertExpectations(t)
+
+	_, err = service.Scan("filters.com", "info", "http", nil, scanner.WithExcludedSeverities("severe"))
+	assert.ErrorIs(t, err, scanner.ErrInvalidSeverity)
+}
@@ -0,0 +1,191 @@
+package tests
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/projectdiscovery/nuclei/v3/pkg/model/types/severity"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprint(t *testing.T) {
+	finding := triageFinding("CVE-2024-0001", "RCE in Widget", severity.Critical, "https://a.example.com")
+	fingerprint := cache.Fingerprint(finding)
+	assert.Len(t, fingerprint, 16)
+	assert.Equal(t, "finding://"+fingerprint, cache.FindingURI(finding))
+
+	// Scan details other than the location don't change the fingerprint
+	rescanned := *finding
+	rescanned.Response = "HTTP/1.1 200 OK"
+	rescanned.Timestamp = time.Now()
+	assert.Equal(t, fingerprint, cache.Fingerprint(&rescanned))
+
+	other := triageFinding("CVE-2024-0001", "RCE in Widget", severity.Critical, "https://b.example.com")
+	assert.NotEqual(t, fingerprint, cache.Fingerprint(other))
+}
+
+func TestFindFinding(t *testing.T) {
+	older := triageFinding("weak-tls", "Weak TLS", severity.Medium, "https://a.example.com")
+	newer := *older
+	newer.Response = "newer"
+	results := []cache.ScanResult{
+		{Target: "a.example.com", ScanTime: time.Now(), Findings: []*output.ResultEvent{&newer}},
+		{Target: "a.example.com", ScanTime: time.Now().Add(-time.Hour), Findings: []*output.ResultEvent{older}},
+	}
+
+	result, finding, ok := cache.FindFinding(results, cache.Fingerprint(older), nil)
+	assert.True(t, ok)
+	assert.Equal(t, "newer", finding.Response)
+	assert.Equal(t, results[0].ScanTime, result.ScanTime)
+
+	_, _, ok = cache.FindFinding(results, "0000000000000000", nil)
+	assert.False(t, ok)
+}
+
+func TestFindFinding_Spilled(t *testing.T) {
+	full := triageFinding("spill-marker", "Spill Marker", severity.Info, "https://a.example.com/5")
+	full.Request = "GET /5 HTTP/1.1"
+	full.Response = "HTTP/1.1 200 OK\r\n\r\nspill-marker"
+	full.CURLCommand = "curl -X 'GET' 'https://a.example.com/5'"
+
+	spillFile := filepath.Join(t.TempDir(), "findings.jsonl")
+	data, err := json.Marshal(full)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(spillFile, append(data, '\n'), 0600))
+
+	results := []cache.ScanResult{{Target: "a.example.com", ScanTime: time.Now(), SpillFile: spillFile, SpilledFindings: 1}}
+
+	_, finding, ok := cache.FindFinding(results, cache.Fingerprint(full), nil)
+	assert.True(t, ok)
+	assert.Equal(t, full.Response, finding.Response)
+	assert.Equal(t, full.CURLCommand, finding.CURLCommand)
+}
+
+func TestHandleFindingResource(t *testing.T) {
+	finding := triageFinding("CVE-2024-0001", "RCE in Widget", severity.Critical, "https://a.example.com")
+	finding.Info.Remediation = "Upgrade Widget to 2.0"
+	finding.CURLCommand = "curl -X 'GET' 'https://a.example.com'"
+	finding.Response = "HTTP/1.1 500 Internal Server Error"
+	service := &MockScannerService{MockGetAll: func() []cache.ScanResult {
+		return []cache.ScanResult{{Target: "a.example.com", ScanTime: time.Now(), Findings: []*output.ResultEvent{finding}}}
+	}}
+
+	uri := cache.FindingURI(finding)
+	request := mcp.ReadResourceRequest{}
+	request.Params.URI = uri
+	contents, err := api.HandleFindingResource(context.Background(), request, service, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, uri, contents[0].(mcp.TextResourceContents).URI)
+
+	var detail api.FindingDetail
+	assert.NoError(t, json.Unmarshal([]byte(contents[0].(mcp.TextResourceContents).Text), &detail))
+	assert.Equal(t, "CVE-2024-0001", detail.TemplateID)
+	assert.Equal(t, "critical", detail.Severity)
+	assert.Equal(t, "Upgrade Widget to 2.0", detail.Remediation)
+	assert.Equal(t, finding.CURLCommand, detail.CURLCommand)
+	assert.Equal(t, finding.Response, detail.Response)
+
+	request.Params.URI = "finding://0000000000000000"
+	_, err = api.HandleFindingResource(context.Background(), request, service, nil, nil)
+	assert.ErrorContains(t, err, "not found")
+
+	request.Params.URI = "dashboard"
+	_, err = api.HandleFindingResource(context.Background(), request, service, nil, nil)
+	assert.Error(t, err)
+}
+
+func TestHandleEvidenceResource(t *testing.T) {
+	finding := triageFinding("exposed-backup", "Exposed Backup", severity.High, "https://a.example.com")
+	finding.Request = "GET /backup.png?v=1 HTTP/1.1\r\nHost: a.example.com\r\n\r\n"
+	body := strings.Repeat("\x89PNG", 2000)
+	finding.Response = "HTTP/1.1 200 OK\r\nContent-Type: image/png\r\n\r\n" + body
+	service := &MockScannerService{MockGetAll: func() []cache.ScanResult {
+		return []cache.ScanResult{{Target: "a.example.com", ScanTime: time.Now(), Findings: []*output.ResultEvent{finding}}}
+	}}
+	mcpServer := api.NewNucleiMCPServer(service, log.New(os.Stdout, "test: ", log.LstdFlags), &MockTemplateManager{})
+
+	read := func(uri string) map[string]any {
+		data, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": "resources/read",
+			"params": map[string]any{"uri": uri}})
+		assert.NoError(t, err)
+		response, err := json.Marshal(mcpServer.HandleMessage(context.Background(), data))
+		assert.NoError(t, err)
+		var decoded map[string]any
+		assert.NoError(t, json.Unmarshal(response, &decoded))
+		return decoded
+	}
+
+	decoded := read(cache.FindingURI(finding))
+	var detail api.FindingDetail
+	text := decoded["result"].(map[string]any)["contents"].([]any)[0].(map[string]any)["text"].(string)
+	assert.NoError(t, json.Unmarshal([]byte(text), &detail))
+	assert.Empty(t, detail.Response, "large responses are not inlined")
+	assert.Equal(t, finding.Request, detail.Request)
+
+	links := map[string]api.EvidenceLink{}
+	for _, link := range detail.Evidence {
+		links[link.Name] = link
+	}
+	assert.Equal(t, "image/png", links["body"].MIMEType)
+	assert.Equal(t, len(body), links["body"].Size)
+
+	contents := read(links["body"].URI)["result"].(map[string]any)["contents"].([]any)[0].(map[string]any)
+	assert.Equal(t, "image/png", contents["mimeType"])
+	blob, err := base64.StdEncoding.DecodeString(contents["blob"].(string))
+	assert.NoError(t, err)
+	assert.Equal(t, body, string(blob))
+
+	contents = read(links["har"].URI)["result"].(map[string]any)["contents"].([]any)[0].(map[string]any)
+	blob, err = base64.StdEncoding.DecodeString(contents["blob"].(string))
+	assert.NoError(t, err)
+	var har struct {
+		Log struct {
+			Entries []struct {
+				Request struct {
+					URL string `json:"url"`
+				} `json:"request"`
+				Response struct {
+					Status int `json:"status"`
+				} `json:"response"`
+			} `json:"entries"`
+		} `json:"log"`
+	}
+	assert.NoError(t, json.Unmarshal(blob, &har))
+	assert.Equal(t, "https://a.example.com/backup.png?v=1", har.Log.Entries[0].Request.URL)
+	assert.Equal(t, 200, har.Log.Entries[0].Response.Status)
+
+	assert.Contains(t, read(api.EvidenceScheme+cache.Fingerprint(finding)+"/screenshot"), "error")
+}
+
+func TestHandleNucleiScanTool_FindingLinks(t *testing.T) {
+	finding := triageFinding("CVE-2024-0001", "RCE in Widget", severity.Critical, "https://example.com")
+	mockScanner := &MockScannerService{
+		MockThreadSafeScan: func(ctx context.Context, target string, severity string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+			return cache.ScanResult{Target: target, ScanTime: time.Now(), Findings: []*output.ResultEvent{finding}}, nil
+		},
+	}
+	pager := api.NewResultPager(api.DefaultPageSize, time.Minute)
+	logger := log.New(os.Stdout, "test: ", log.LstdFlags)
+
+	request := mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: map[string]interface{}{"target": "https://example.com"}}}
+	result, err := api.HandleNucleiScanTool(context.Background(), request, mockScanner, logger, pager, api.DefaultScanDefaults)
+	assert.NoError(t, err)
+	assert.Contains(t, result.Content[0].(mcp.TextContent).Text, "- Details: "+cache.FindingURI(finding))
+
+	request.Params.Arguments = map[string]interface{}{"target": "https://example.com", "format": "json"}
+	result, err = api.HandleNucleiScanTool(context.Background(), request, mockScanner, logger, pager, api.DefaultScanDefaults)
+	assert.NoError(t, err)
+	assert.Contains(t, result.Content[0].(mcp.TextContent).Text, `"resource":"`+cache.FindingURI(finding)+`"`)
+}
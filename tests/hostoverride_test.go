@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const virtualHostTemplate = `id: virtual-host-marker
+info:
+  name: Virtual Host Marker
+  author: nuclei-mcp
+  severity: info
+http:
+  - method: GET
+    path:
+      - "{{BaseURL}}/"
+    matchers:
+      - type: word
+        words:
+          - virtual-host-marker
+`
+
+// hostOverrideService records the host override of the scans it is asked
+// for
+type hostOverrideService struct {
+	MockScannerService
+	sni, hostHeader string
+}
+
+func (s *hostOverrideService) ThreadSafeScan(_ context.Context, target string, _ string, _ string, _ []string, opts ...scanner.ScanOption) (cache.ScanResult, error) {
+	var scanOpts scanner.ScanOptions
+	for _, opt := range opts {
+		opt(&scanOpts)
+	}
+	s.sni, s.hostHeader = scanOpts.SNI, scanOpts.HostHeader
+	return cache.ScanResult{Target: target, ScanTime: time.Now()}, nil
+}
+
+func TestValidateHostOverrides(t *testing.T) {
+	assert.NoError(t, scanner.ValidateHostOverrides("", ""))
+	assert.NoError(t, scanner.ValidateHostOverrides("www.example.com", "www.example.com:8443"))
+	assert.NoError(t, scanner.ValidateHostOverrides("", "[2001:db8::1]:8443"))
+
+	for _, sni := range []string{"https://example.com", "example.com:443", "exa mple.com"} {
+		assert.ErrorIs(t, scanner.ValidateHostOverrides(sni, ""), scanner.ErrInvalidHostOverride, sni)
+	}
+	for _, host := range []string{"https://example.com", "example.com/admin", "user@example.com", ":8443"} {
+		assert.ErrorIs(t, scanner.ValidateHostOverrides("", host), scanner.ErrInvalidHostOverride, host)
+	}
+	assert.Equal(t, api.CodeInvalidParameter, api.ErrorCodeOf(scanner.ValidateHostOverrides("", "example.com/admin")))
+}
+
+func TestScannerService_HostOverride(t *testing.T) {
+	var serverName, host string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ = strings.Cut(r.Host, ":")
+		serverName = r.TLS.ServerName
+		if host == "www.example.test" {
+			_, _ = w.Write([]byte("virtual-host-marker"))
+		}
+	}))
+	defer srv.Close()
+
+	templateFile := filepath.Join(t.TempDir(), "virtual-host.yaml")
+	assert.NoError(t, os.WriteFile(templateFile, []byte(virtualHostTemplate), 0644))
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger)
+
+	// srv.URL is https://127.0.0.1:port
+	result, err := service.ThreadSafeScan(context.Background(), srv.URL, "", "", nil, scanner.WithTemplateSources(templateFile))
+	assert.NoError(t, err)
+	assert.Empty(t, result.Findings)
+
+	result, err = service.ThreadSafeScan(context.Background(), srv.URL, "", "", nil, scanner.WithTemplateSources(templateFile),
+		scanner.WithHostHeader("www.example.test"))
+	assert.NoError(t, err)
+	if assert.Len(t, result.Findings, 1) {
+		assert.Equal(t, "127.0.0.1", result.Findings[0].IP)
+	}
+	assert.Equal(t, "www.example.test", serverName)
+
+	result, err = service.ThreadSafeScan(context.Background(), srv.URL, "", "", nil, scanner.WithTemplateSources(templateFile),
+		scanner.WithHostHeader("www.example.test"), scanner.WithSNI("origin.example.test"))
+	assert.NoError(t, err)
+	assert.Len(t, result.Findings, 1)
+	assert.Equal(t, "origin.example.test", serverName)
+	assert.Equal(t, "www.example.test", host)
+
+	_, err = service.ThreadSafeScan(context.Background(), srv.URL, "", "", nil, scanner.WithTemplateSources(templateFile),
+		scanner.WithHostHeader("www.example.test/admin"))
+	assert.ErrorIs(t, err, scanner.ErrInvalidHostOverride)
+}
+
+func TestHandleNucleiScanTool_HostOverride(t *testing.T) {
+	service := &hostOverrideService{}
+	logger := log.New(os.Stdout, "test: ", log.LstdFlags)
+	scan := func(arguments map[string]any) error {
+		_, err := api.HandleNucleiScanTool(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: arguments}}, service, logger, nil, api.DefaultScanDefaults)
+		return err
+	}
+
+	assert.NoError(t, scan(map[string]any{"target": "https://203.0.113.10", "sni": "www.example.com", "host_header": "www.example.com"}))
+	assert.Equal(t, "www.example.com", service.sni)
+	assert.Equal(t, "www.example.com", service.hostHeader)
+
+	assert.ErrorIs(t, scan(map[string]any{"target": "https://203.0.113.10", "host_header": "https://www.example.com"}), scanner.ErrInvalidHostOverride)
+}
@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/policy"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// httpVersionsService reports the HTTP versions of a target when the scan
+// asks for them
+type httpVersionsService struct {
+	MockScannerService
+}
+
+func (s *httpVersionsService) ThreadSafeScan(_ context.Context, target string, _ string, _ string, _ []string, opts ...scanner.ScanOption) (cache.ScanResult, error) {
+	var scanOpts scanner.ScanOptions
+	for _, opt := range opts {
+		opt(&scanOpts)
+	}
+	result := cache.ScanResult{Target: target, ScanTime: time.Now()}
+	if scanOpts.ProbeHTTPVersions {
+		result.HTTPVersions = &cache.HTTPVersions{HTTP2: true, HTTP3: true, AltSvc: `h3=":443"; ma=86400`}
+	}
+	return result, nil
+}
+
+func TestProbeHTTPVersions(t *testing.T) {
+	modern := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Alt-Svc", `h3=":443"; ma=86400, h2=":443"`)
+	}))
+	modern.EnableHTTP2 = true
+	modern.StartTLS()
+	defer modern.Close()
+
+	client := scanner.NewTargetClient(scanner.TargetClientOptions{HTTP2: true})
+	versions, err := scanner.ProbeHTTPVersions(context.Background(), client, modern.URL)
+	assert.NoError(t, err)
+	assert.True(t, versions.HTTP2)
+	assert.True(t, versions.HTTP3)
+	assert.Contains(t, versions.AltSvc, "h3=")
+
+	legacy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer legacy.Close()
+
+	versions, err = scanner.ProbeHTTPVersions(context.Background(), client, legacy.URL)
+	assert.NoError(t, err)
+	assert.False(t, versions.HTTP2)
+	assert.False(t, versions.HTTP3)
+
+	// Connections are checked against the egress policy after DNS
+	egress, err := policy.NewEgressPolicy(policy.EgressOptions{DenyPrivate: true})
+	assert.NoError(t, err)
+	_, err = scanner.ProbeHTTPVersions(context.Background(), scanner.NewTargetClient(scanner.TargetClientOptions{Egress: egress, HTTP2: true}), modern.URL)
+	assert.ErrorIs(t, err, policy.ErrDenied)
+}
+
+func TestScannerService_HTTPVersionProbeNotPassive(t *testing.T) {
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger)
+
+	_, err := service.ThreadSafeScan(context.Background(), "http://192.0.2.1", "", "", nil, scanner.WithPassive(true), scanner.WithHTTPVersionProbe())
+	assert.ErrorContains(t, err, "cannot run in passive mode")
+}
+
+func TestHandleNucleiScanTool_ProbeHTTPVersions(t *testing.T) {
+	service := &httpVersionsService{}
+	logger := log.New(os.Stdout, "test: ", log.LstdFlags)
+	scan := func(arguments map[string]any) string {
+		result, err := api.HandleNucleiScanTool(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: arguments}}, service, logger, nil, api.DefaultScanDefaults)
+		assert.NoError(t, err)
+		return result.Content[0].(mcp.TextContent).Text
+	}
+
+	text := scan(map[string]any{"target": "https://example.com", "probe_http_versions": true})
+	assert.Contains(t, text, `HTTP versions: HTTP/2 supported, HTTP/3 advertised (Alt-Svc: h3=":443"; ma=86400)`)
+
+	text = scan(map[string]any{"target": "https://example.com", "probe_http_versions": true, "format": "json"})
+	assert.Contains(t, text, `"http_versions":{"http2":true,"http3":true`)
+
+	assert.NotContains(t, scan(map[string]any{"target": "https://example.com"}), "HTTP versions")
+}
@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/i18n"
+	"nuclei-mcp/pkg/report"
+	"nuclei-mcp/pkg/triage"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalizer(t *testing.T) {
+	l, err := i18n.New("")
+	assert.NoError(t, err)
+	assert.Equal(t, i18n.English, l.Language())
+	assert.Equal(t, "Critical", l.Severity("critical"))
+	assert.Equal(t, "Unknown", l.Severity("bogus"))
+
+	l, err = i18n.New("de-AT")
+	assert.NoError(t, err)
+	assert.Equal(t, i18n.German, l.Language())
+	assert.Equal(t, "Hoch", l.Severity("HIGH"))
+	assert.Equal(t, "German", l.LanguageName())
+
+	l, err = i18n.New("ja")
+	assert.NoError(t, err)
+	assert.Equal(t, "2件の対象で3件を検出しました(x)。", l.T("summary.counts", 3, 2, "x"))
+	assert.Equal(t, "missing.key", l.T("missing.key"))
+
+	_, err = i18n.New("fr")
+	assert.ErrorContains(t, err, "unsupported report language")
+	assert.Equal(t, []string{"de", "en", "es", "ja"}, i18n.Supported())
+}
+
+func TestReportGenerator_Markdown(t *testing.T) {
+	generatedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	markdown := report.NewGenerator(nil).Markdown(triageResults(), generatedAt)
+	assert.True(t, strings.HasPrefix(markdown, "# Vulnerability Scan Report\n\nGenerated: 2026-01-02T03:04:05Z"))
+	assert.Contains(t, markdown, "| Critical | 1 |")
+	assert.Contains(t, markdown, "### Target: a.example.com")
+	// Findings are ordered by severity within a target
+	assert.Less(t, strings.Index(markdown, "RCE in Widget"), strings.Index(markdown, "Tech Detect"))
+
+	es, err := i18n.New("es")
+	assert.NoError(t, err)
+	markdown = report.NewGenerator(es).Markdown(triageResults(), generatedAt)
+	assert.Contains(t, markdown, "# Informe de análisis de vulnerabilidades")
+	assert.Contains(t, markdown, "| Crítica | 1 |")
+	assert.Contains(t, markdown, "- Severidad: Media")
+	assert.NotContains(t, markdown, "Findings")
+}
+
+func TestSummarizer_Localized(t *testing.T) {
+	de, err := i18n.New("de")
+	assert.NoError(t, err)
+
+	summary, err := triage.NewSummarizer(nil, triage.WithLocalizer(de)).Summarize(context.Background(), triageResults(), 0)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(summary.Text, "Management-Zusammenfassung\n3 Befunde bei 2 Zielen (1 Kritisch, 1 Mittel, 1 Information)."))
+	assert.Contains(t, summary.Text, "1. [Kritisch] RCE in Widget auf https://a.example.com beheben")
+
+	sampler := &fakeSampler{text: "Resumen ejecutivo\n..."}
+	es, err := i18n.New("es")
+	assert.NoError(t, err)
+	_, err = triage.NewSummarizer(sampler).Localized(es).Summarize(context.Background(), triageResults(), 0)
+	assert.NoError(t, err)
+	assert.Contains(t, sampler.params.SystemPrompt, "Write the whole response in Spanish")
+}
+
+func TestHandleGenerateReport(t *testing.T) {
+	mockScanner := &MockScannerService{
+		MockGetAll: func() []cache.ScanResult { return triageResults() },
+	}
+	request := mcp.CallToolRequest{
+		Params: mcp.CallToolParams{Arguments: map[string]interface{}{"target": "b.example.com", "language": "ja"}},
+	}
+
+	result, err := api.HandleGenerateReport(context.Background(), request, mockScanner, report.NewGenerator(nil))
+	assert.NoError(t, err)
+	text := result.Content[0].(mcp.TextContent).Text
+	assert.Contains(t, text, "# 脆弱性スキャンレポート")
+	assert.Contains(t, text, "Weak TLS")
+	assert.NotContains(t, text, "a.example.com")
+
+	request.Params.Arguments = map[string]interface{}{"language": "xx"}
+	_, err = api.HandleGenerateReport(context.Background(), request, mockScanner, report.NewGenerator(nil))
+	assert.ErrorContains(t, err, "unsupported report language")
+
+	request.Params.Arguments = map[string]interface{}{"target": "c.example.com"}
+	_, err = api.HandleGenerateReport(context.Background(), request, mockScanner, report.NewGenerator(nil))
+	assert.ErrorContains(t, err, "no cached scan results")
+}
@@ -0,0 +1,159 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/policy"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// inlineHypothesisTemplate matches the test server's response at a severity
+// the default scan profile would not reach through its filters alone
+const inlineHypothesisTemplate = `id: inline-hypothesis
+info:
+  name: Inline Hypothesis
+  author: nuclei-mcp
+  severity: high
+  tags: hypothesis
+http:
+  - method: GET
+    path:
+      - "{{BaseURL}}/"
+    matchers:
+      - type: word
+        words:
+          - hypothesis-confirmed
+`
+
+func inlineDirs(t *testing.T) []string {
+	dirs, err := filepath.Glob(filepath.Join(os.TempDir(), "nuclei-mcp-inline-*"))
+	assert.NoError(t, err)
+	return dirs
+}
+
+func TestScannerService_InlineTemplates(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hypothesis-confirmed"))
+	}))
+	defer srv.Close()
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	quarantine := scanner.NewTemplateQuarantine(filepath.Join(t.TempDir(), "template-quarantine.json"), 1)
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger, scanner.WithTemplateQuarantine(quarantine))
+	before := inlineDirs(t)
+
+	result, err := service.ThreadSafeScan(context.Background(), srv.URL, "", "", nil, scanner.WithInlineTemplates(inlineHypothesisTemplate, brokenFlowTemplate))
+	assert.NoError(t, err)
+	if assert.Len(t, result.Findings, 1) {
+		assert.Equal(t, "inline-hypothesis", result.Findings[0].TemplateID)
+	}
+	if assert.NotNil(t, result.Stats) && assert.NotNil(t, result.Stats.Templates) {
+		assert.Equal(t, 2, result.Stats.Templates.Loaded)
+	}
+
+	// The templates are discarded after the scan, and their errors do not
+	// count towards the quarantine of the template library
+	assert.Equal(t, before, inlineDirs(t))
+	assert.Empty(t, quarantine.List(true))
+
+	_, err = service.Scan(srv.URL, "", "", nil, scanner.WithInlineTemplates("info:\n  name: No ID\n"))
+	assert.ErrorIs(t, err, scanner.ErrInvalidInlineTemplate)
+	_, err = service.Scan(srv.URL, "", "", nil, scanner.WithInlineTemplates(inlineHypothesisTemplate, inlineHypothesisTemplate))
+	assert.ErrorIs(t, err, scanner.ErrInvalidInlineTemplate)
+}
+
+func TestScannerService_InlineTemplatesCacheKey(t *testing.T) {
+	mockCache := new(MockResultCache)
+	mockLogger := new(MockConsoleLogger)
+	service := scanner.NewScannerService(mockCache, mockLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+
+	expected := cache.ScanResult{Target: "inline.com", ScanTime: time.Now()}
+	var keys []string
+	mockCache.On("Get", mock.MatchedBy(func(key string) bool {
+		keys = append(keys, key)
+		return true
+	})).Return(expected, true)
+
+	for _, content := range []string{inlineHypothesisTemplate, healthyTemplate, inlineHypothesisTemplate} {
+		_, err := service.Scan("inline.com", "", "", nil, scanner.WithInlineTemplates(content))
+		assert.NoError(t, err)
+	}
+	if assert.Len(t, keys, 3) {
+		assert.True(t, strings.HasPrefix(keys[0], "inline.com:::inline="))
+		assert.NotEqual(t, keys[0], keys[1])
+		assert.Equal(t, keys[0], keys[2])
+	}
+}
+
+func TestHandleNucleiScanTool_InlineTemplates(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hypothesis-confirmed"))
+	}))
+	defer srv.Close()
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger)
+	defaults := api.DefaultScanDefaults
+	defaults.Tags = []string{"tech"}
+
+	// The profile's severity, protocols and tags do not filter out the
+	// inline template
+	result, err := api.HandleNucleiScanTool(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: map[string]any{
+		"target":           srv.URL,
+		"inline_templates": []any{inlineHypothesisTemplate},
+		"format":           api.FormatJSON,
+	}}}, service, log.New(os.Stderr, "test: ", log.LstdFlags), nil, defaults)
+	assert.NoError(t, err)
+	if assert.NotNil(t, result) && assert.Len(t, result.Content, 1) {
+		assert.Contains(t, result.Content[0].(mcp.TextContent).Text, "inline-hypothesis")
+	}
+
+	_, err = api.HandleNucleiScanTool(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: map[string]any{
+		"target":           srv.URL,
+		"inline_templates": []any{42},
+	}}}, service, log.New(os.Stderr, "test: ", log.LstdFlags), nil, defaults)
+	assert.ErrorContains(t, err, "invalid inline_templates")
+}
+
+func TestNucleiMCPServer_InlineTemplatePolicy(t *testing.T) {
+	scanned := false
+	mockScanner := &MockScannerService{
+		MockThreadSafeScan: func(ctx context.Context, target string, severity string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+			scanned = true
+			return cache.ScanResult{Target: target, ScanTime: time.Now()}, nil
+		},
+	}
+	mcpServer := api.NewNucleiMCPServer(mockScanner, log.New(os.Stdout, "test: ", log.LstdFlags), &MockTemplateManager{},
+		api.WithTemplatePolicy(policy.NewTemplatePolicy([]string{"hypothesis"})))
+
+	data, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": "tools/call", "params": map[string]any{
+		"name": "nuclei_scan",
+		"arguments": map[string]any{
+			"target":           "example.com",
+			"inline_templates": []any{inlineHypothesisTemplate},
+		},
+	}})
+	assert.NoError(t, err)
+	response, err := json.Marshal(mcpServer.HandleMessage(context.Background(), data))
+	assert.NoError(t, err)
+	assert.Contains(t, string(response), "blocked by policy")
+	assert.False(t, scanned)
+}
@@ -0,0 +1,138 @@
+package tests
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/policy"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const dualStackTemplate = `id: dual-stack-marker
+info:
+  name: Dual Stack Marker
+  author: nuclei-mcp
+  severity: info
+http:
+  - method: GET
+    path:
+      - "{{BaseURL}}/"
+    matchers:
+      - type: word
+        words:
+          - dual-stack-marker
+`
+
+func TestNormalizeTarget(t *testing.T) {
+	for target, want := range map[string]string{
+		"2001:db8::1":                   "[2001:db8::1]",
+		" fe80::1%eth0 ":                "[fe80::1%25eth0]",
+		"[fe80::1%eth0]:8443":           "[fe80::1%25eth0]:8443",
+		"http://[fe80::1%eth0]:80/path": "http://[fe80::1%25eth0]:80/path",
+		"http://[fe80::1%25eth0]/":      "http://[fe80::1%25eth0]/",
+		"[2001:db8::1]:443":             "[2001:db8::1]:443",
+		"example.com":                   "example.com",
+		"192.0.2.1:8080":                "192.0.2.1:8080",
+	} {
+		assert.Equal(t, want, policy.NormalizeTarget(target), target)
+	}
+	assert.Equal(t, "2001:db8::1", scanner.TargetHost(policy.NormalizeTarget("2001:db8::1")))
+}
+
+func TestEgressPolicy_IPv6(t *testing.T) {
+	egress, err := policy.NewEgressPolicy(policy.EgressOptions{DenyPrivate: true, DenyMetadata: true})
+	assert.NoError(t, err)
+
+	for _, addr := range []string{"fe80::1%eth0", "fd00::1", "::ffff:10.0.0.1", "64:ff9b::a00:1", "2002:c0a8:101::1", "64:ff9b::a9fe:a9fe"} {
+		assert.ErrorIs(t, egress.CheckAddress(netip.MustParseAddr(addr)), policy.ErrDenied, addr)
+	}
+	assert.NoError(t, egress.CheckAddress(netip.MustParseAddr("2001:db8::1")))
+	assert.NoError(t, egress.CheckAddress(netip.MustParseAddr("64:ff9b::808:808")))
+
+	for _, target := range []string{"::1", "fd00::1", "[fd00::1]:8443", "fe80::1%eth0", "http://[fe80::1%eth0]/"} {
+		assert.ErrorIs(t, egress.CheckTarget(context.Background(), target), policy.ErrDenied, target)
+	}
+	assert.NoError(t, egress.CheckTarget(context.Background(), "2001:db8::1"))
+
+	scope := policy.NewScope([]string{"https://[2001:DB8:0::1]"})
+	assert.True(t, scope.Contains("2001:db8::1"))
+	assert.True(t, scope.Contains("https://[2001:db8::1]/login"))
+	assert.False(t, scope.Contains("2001:db8::2"))
+}
+
+func TestParseAddressFamily(t *testing.T) {
+	family, err := scanner.ParseAddressFamily(" V6 ")
+	assert.NoError(t, err)
+	assert.Equal(t, scanner.AddressFamilyV6, family)
+	family, err = scanner.ParseAddressFamily("")
+	assert.NoError(t, err)
+	assert.Equal(t, scanner.AddressFamilyAny, family)
+
+	_, err = scanner.ParseAddressFamily("ipx")
+	assert.ErrorIs(t, err, scanner.ErrInvalidAddressFamily)
+	assert.Equal(t, api.CodeInvalidParameter, api.ErrorCodeOf(err))
+}
+
+func TestScannerService_AddressFamily(t *testing.T) {
+	listener, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 loopback unavailable: %v", err)
+	}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("dual-stack-marker"))
+	}))
+	srv.Listener.Close()
+	srv.Listener = listener
+	srv.Start()
+	defer srv.Close()
+
+	templateFile := filepath.Join(t.TempDir(), "dual-stack.yaml")
+	assert.NoError(t, os.WriteFile(templateFile, []byte(dualStackTemplate), 0644))
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger)
+
+	// srv.URL is http://[::1]:port
+	result, err := service.ThreadSafeScan(context.Background(), srv.URL, "", "", nil, scanner.WithTemplateSources(templateFile), scanner.WithAddressFamily(scanner.AddressFamilyV6))
+	assert.NoError(t, err)
+	assert.Len(t, result.Findings, 1)
+
+	_, err = service.ThreadSafeScan(context.Background(), srv.URL, "", "", nil, scanner.WithTemplateSources(templateFile), scanner.WithAddressFamily(scanner.AddressFamilyV4))
+	assert.ErrorIs(t, err, scanner.ErrNoTargets)
+	assert.ErrorContains(t, err, "::1 is not an IPv4 address")
+}
+
+func TestScannerService_AddressFamilyPinsAddress(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("dual-stack-marker"))
+	}))
+	defer srv.Close()
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+
+	templateFile := filepath.Join(t.TempDir(), "dual-stack.yaml")
+	assert.NoError(t, os.WriteFile(templateFile, []byte(dualStackTemplate), 0644))
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger)
+
+	result, err := service.ThreadSafeScan(context.Background(), "http://localhost:"+port, "", "", nil, scanner.WithTemplateSources(templateFile), scanner.WithAddressFamily(scanner.AddressFamilyV4))
+	assert.NoError(t, err)
+	if assert.Len(t, result.Findings, 1) {
+		assert.Equal(t, "127.0.0.1", result.Findings[0].IP)
+	}
+}
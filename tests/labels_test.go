@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/report"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/projectdiscovery/nuclei/v3/pkg/model/types/severity"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+// labellingService returns results carrying the labels of the scan
+type labellingService struct {
+	MockScannerService
+}
+
+func (s *labellingService) ThreadSafeScan(_ context.Context, target string, _ string, _ string, _ []string, opts ...scanner.ScanOption) (cache.ScanResult, error) {
+	var scanOpts scanner.ScanOptions
+	for _, opt := range opts {
+		opt(&scanOpts)
+	}
+	return cache.ScanResult{
+		Target:   target,
+		ScanTime: time.Now(),
+		Findings: []*output.ResultEvent{triageFinding("CVE-2024-0001", "RCE in Widget", severity.Critical, "https://example.com")},
+		Labels:   scanOpts.Labels,
+	}, nil
+}
+
+func TestParseLabels(t *testing.T) {
+	labels, err := scanner.ParseLabels([]string{"environment=staging", " ticket = SEC-123 "})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"environment": "staging", "ticket": "SEC-123"}, labels)
+	assert.Equal(t, "environment=staging,ticket=SEC-123", scanner.FormatLabels(labels))
+
+	_, err = scanner.ParseLabels([]string{"staging"})
+	assert.ErrorIs(t, err, scanner.ErrInvalidLabel)
+	_, err = scanner.ParseLabels([]string{"bad key=1"})
+	assert.ErrorIs(t, err, scanner.ErrInvalidLabel)
+	assert.ErrorIs(t, scanner.ValidateLabels(map[string]string{"note": "a\nb"}), scanner.ErrInvalidLabel)
+
+	var scanOpts scanner.ScanOptions
+	scanner.WithLabels(map[string]string{"environment": "staging", "team": "web"})(&scanOpts)
+	scanner.WithLabels(map[string]string{"environment": "prod"})(&scanOpts)
+	assert.Equal(t, map[string]string{"environment": "prod", "team": "web"}, scanOpts.Labels)
+	assert.True(t, scanner.HasLabels(scanOpts.Labels, map[string]string{"team": "web"}))
+	assert.False(t, scanner.HasLabels(scanOpts.Labels, map[string]string{"team": "api"}))
+}
+
+func TestHandleNucleiScanTool_Labels(t *testing.T) {
+	service := &labellingService{}
+	logger := log.New(os.Stdout, "test: ", log.LstdFlags)
+	scan := func(arguments map[string]any) (*mcp.CallToolResult, error) {
+		return api.HandleNucleiScanTool(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: arguments}}, service, logger, nil, api.DefaultScanDefaults)
+	}
+
+	result, err := scan(map[string]any{"target": "https://example.com", "labels": map[string]any{"environment": "staging", "ticket": "SEC-123"}})
+	assert.NoError(t, err)
+	assert.Contains(t, result.Content[0].(mcp.TextContent).Text, "Labels: environment=staging,ticket=SEC-123")
+
+	result, err = scan(map[string]any{"target": "https://example.com", "labels": map[string]any{"environment": "staging"}, "format": "json"})
+	assert.NoError(t, err)
+	var response struct {
+		Labels map[string]string `json:"labels"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &response))
+	assert.Equal(t, map[string]string{"environment": "staging"}, response.Labels)
+
+	_, err = scan(map[string]any{"target": "https://example.com", "labels": map[string]any{"environment": []any{"staging"}}})
+	assert.ErrorIs(t, err, scanner.ErrInvalidLabel)
+	assert.Equal(t, api.CodeInvalidParameter, api.ErrorCodeOf(err))
+}
+
+func TestLabelledExports(t *testing.T) {
+	results := []cache.ScanResult{
+		{Target: "staging.example.com", ScanTime: time.Now(), Labels: map[string]string{"environment": "staging", "ticket": "SEC-123"},
+			Findings: []*output.ResultEvent{triageFinding("CVE-2024-0001", "RCE in Widget", severity.Critical, "https://staging.example.com")}},
+		{Target: "example.com", ScanTime: time.Now(), Labels: map[string]string{"environment": "production"},
+			Findings: []*output.ResultEvent{triageFinding("weak-tls", "Weak TLS", severity.Medium, "https://example.com")}},
+	}
+
+	markdown := report.NewGenerator(nil).Markdown(results, time.Now())
+	assert.Contains(t, markdown, "Labels: `environment=staging`, `ticket=SEC-123`")
+
+	sarif, err := report.SARIF(results, "")
+	assert.NoError(t, err)
+	assert.Contains(t, string(sarif), `"labels": {`)
+
+	service := &MockScannerService{MockGetAll: func() []cache.ScanResult { return results }}
+	generate := func(arguments map[string]any) (string, error) {
+		result, err := api.HandleGenerateReport(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: arguments}}, service, report.NewGenerator(nil))
+		if err != nil {
+			return "", err
+		}
+		return result.Content[0].(mcp.TextContent).Text, nil
+	}
+
+	text, err := generate(map[string]any{"labels": map[string]any{"environment": "staging"}})
+	assert.NoError(t, err)
+	assert.Contains(t, text, "staging.example.com")
+	assert.NotContains(t, text, "Weak TLS")
+
+	_, err = generate(map[string]any{"labels": map[string]any{"environment": "dev"}})
+	assert.ErrorContains(t, err, "no cached scan results labelled environment=dev")
+}
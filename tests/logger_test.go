@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"bytes"
 	"io/ioutil"
 	"os"
 	"strings"
@@ -62,3 +63,36 @@ func TestConsoleLogger_Close(t *testing.T) {
 	// Depending on the implementation, this might need a specific check or be omitted.
 	// For now, we just ensure Close doesn't return an error.
 }
+
+func TestNewConsoleLoggerWithOutput(t *testing.T) {
+	logPath := "/tmp/test_console_logger_output.log"
+	defer os.Remove(logPath)
+
+	var console bytes.Buffer
+	logger, err := logging.NewConsoleLoggerWithOutput(logPath, &console)
+	assert.NoError(t, err)
+	defer logger.Close()
+
+	logger.Log("scan of %s started", "example.com")
+	assert.Contains(t, console.String(), "scan of example.com started")
+
+	content, err := os.ReadFile(logPath)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "scan of example.com started")
+}
+
+func TestServerOutput(t *testing.T) {
+	assert.Equal(t, os.Stderr, logging.ServerOutput(true, false))
+	assert.Equal(t, os.Stderr, logging.ServerOutput(true, true), "stdio never logs to stdout")
+	assert.Equal(t, os.Stderr, logging.ServerOutput(false, false))
+	assert.Equal(t, os.Stdout, logging.ServerOutput(false, true))
+}
+
+func TestReserveStdout(t *testing.T) {
+	original := os.Stdout
+	defer func() { os.Stdout = original }()
+
+	stdout := logging.ReserveStdout()
+	assert.Equal(t, original, stdout)
+	assert.Equal(t, os.Stderr, os.Stdout)
+}
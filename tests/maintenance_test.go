@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestMaintenance_DrainsActiveScans(t *testing.T) {
+	m := scanner.NewMaintenance()
+	done, err := m.Start()
+	assert.NoError(t, err)
+
+	status := m.Pause("templates update")
+	assert.True(t, status.Paused)
+	assert.Equal(t, 1, status.Active)
+	assert.False(t, status.Drained)
+
+	_, err = m.Start()
+	assert.ErrorIs(t, err, scanner.ErrMaintenance)
+	assert.ErrorContains(t, err, "templates update")
+	assert.Equal(t, api.CodeMaintenance, api.ErrorCodeOf(err))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, m.Wait(ctx), context.DeadlineExceeded)
+
+	done()
+	done()
+	assert.NoError(t, m.Wait(context.Background()))
+	assert.True(t, m.Status().Drained)
+
+	assert.False(t, m.Resume().Paused)
+	_, err = m.Start()
+	assert.NoError(t, err)
+}
+
+func TestScannerService_RefusesScansWhilePaused(t *testing.T) {
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	resultCache := cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags))
+	m := scanner.NewMaintenance()
+	service := scanner.NewScannerService(resultCache, mockLogger, scanner.WithMaintenance(m))
+	m.Pause("")
+
+	_, err := service.ThreadSafeScan(context.Background(), "http://192.0.2.1", "", "", nil)
+	assert.ErrorIs(t, err, scanner.ErrMaintenance)
+	_, err = service.BasicScan("http://192.0.2.1")
+	assert.ErrorIs(t, err, scanner.ErrMaintenance)
+
+	// Cached results are still served
+	cached := new(MockResultCache)
+	cached.On("Get", mock.Anything).Return(cache.ScanResult{Target: "http://192.0.2.2"}, true)
+	service = scanner.NewScannerService(cached, mockLogger, scanner.WithMaintenance(m))
+	result, err := service.ThreadSafeScan(context.Background(), "http://192.0.2.2", "", "", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://192.0.2.2", result.Target)
+}
+
+func TestHandlePauseScanning(t *testing.T) {
+	m := scanner.NewMaintenance()
+	done, err := m.Start()
+	assert.NoError(t, err)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		done()
+	}()
+
+	call := func(handler func(context.Context, mcp.CallToolRequest, *scanner.Maintenance) (*mcp.CallToolResult, error), arguments map[string]any) scanner.MaintenanceStatus {
+		result, err := handler(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: arguments}}, m)
+		assert.NoError(t, err)
+		var status scanner.MaintenanceStatus
+		assert.NoError(t, json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &status))
+		return status
+	}
+
+	status := call(api.HandlePauseScanning, map[string]any{"reason": "host reboot", "wait_seconds": float64(5)})
+	assert.True(t, status.Paused)
+	assert.Equal(t, "host reboot", status.Reason)
+	assert.True(t, status.Drained)
+	assert.NotNil(t, status.Since)
+
+	status = call(api.HandleResumeScanning, nil)
+	assert.False(t, status.Paused)
+	assert.Nil(t, status.Since)
+}
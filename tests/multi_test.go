@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTargetHost(t *testing.T) {
+	assert.Equal(t, "example.com", scanner.TargetHost("https://Example.com/login"))
+	assert.Equal(t, "example.com", scanner.TargetHost("example.com:8443"))
+	assert.Equal(t, "example.com", scanner.TargetHost("example.com"))
+	assert.Equal(t, "10.0.0.1", scanner.TargetHost("10.0.0.1"))
+	assert.Equal(t, "::1", scanner.TargetHost("http://[::1]:8080/"))
+}
+
+func TestMultiScanner_Scan(t *testing.T) {
+	var mu sync.Mutex
+	running := map[string]int{}
+	maxHost := map[string]int{}
+	total, maxTotal := 0, 0
+
+	mockScanner := &MockScannerService{
+		MockThreadSafeScan: func(ctx context.Context, target string, severity string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+			host := scanner.TargetHost(target)
+			mu.Lock()
+			running[host]++
+			total++
+			maxHost[host] = max(maxHost[host], running[host])
+			maxTotal = max(maxTotal, total)
+			mu.Unlock()
+
+			time.Sleep(20 * time.Millisecond)
+
+			mu.Lock()
+			running[host]--
+			total--
+			mu.Unlock()
+
+			if target == "https://b.example.com/broken" {
+				return cache.ScanResult{}, fmt.Errorf("connection refused")
+			}
+			if target == "c.example.com" {
+				panic("malformed response")
+			}
+			return cache.ScanResult{Target: target, Findings: []*output.ResultEvent{{TemplateID: "t", Host: target}}}, nil
+		},
+	}
+
+	var targets []string
+	for i := 0; i < 6; i++ {
+		targets = append(targets, fmt.Sprintf("https://a.example.com/%d", i))
+	}
+	targets = append(targets, "https://b.example.com/ok", "https://b.example.com/broken", "", "c.example.com")
+
+	hosts := scanner.NewMultiScanner(mockScanner, scanner.Concurrency{Host: 2, Global: 3}).
+		Scan(context.Background(), targets, "info", "http", nil)
+
+	if assert.Len(t, hosts, 3) {
+		assert.Equal(t, "a.example.com", hosts[0].Host)
+		assert.Len(t, hosts[0].Targets, 6)
+		assert.Equal(t, "https://a.example.com/0", hosts[0].Targets[0].Target)
+		assert.Equal(t, 6, hosts[0].Findings())
+
+		assert.Equal(t, "b.example.com", hosts[1].Host)
+		assert.NoError(t, hosts[1].Targets[0].Err)
+		assert.ErrorContains(t, hosts[1].Targets[1].Err, "connection refused")
+		assert.Equal(t, 1, hosts[1].Findings())
+
+		assert.Equal(t, "c.example.com", hosts[2].Host)
+		assert.ErrorIs(t, hosts[2].Targets[0].Err, scanner.ErrScanPanic)
+	}
+	assert.LessOrEqual(t, maxHost["a.example.com"], 2)
+	assert.LessOrEqual(t, maxTotal, 3)
+	assert.Greater(t, maxTotal, 1, "targets run in parallel")
+}
+
+func TestMultiScanner_ScanCanceled(t *testing.T) {
+	mockScanner := &MockScannerService{
+		MockThreadSafeScan: func(ctx context.Context, target string, severity string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+			return cache.ScanResult{Target: target}, nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	hosts := scanner.NewMultiScanner(mockScanner, scanner.Concurrency{}).Scan(ctx, []string{"a.example.com"}, "info", "http", nil)
+	if assert.Len(t, hosts, 1) {
+		assert.ErrorIs(t, hosts[0].Targets[0].Err, context.Canceled)
+	}
+	assert.Equal(t, scanner.Concurrency{Host: scanner.DefaultHostConcurrency, Global: scanner.DefaultGlobalConcurrency},
+		scanner.NewMultiScanner(mockScanner, scanner.Concurrency{}).Limits())
+}
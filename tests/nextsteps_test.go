@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/projectdiscovery/nuclei/v3/pkg/model/types/severity"
+	"github.com/projectdiscovery/nuclei/v3/pkg/model/types/stringslice"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleNextSteps(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"tech-detect.yaml":      "id: tech-detect\ninfo:\n  name: Wappalyzer Technology Detection\n  author: nuclei-mcp\n  severity: info\n  tags: tech\n",
+		"wp-xmlrpc.yaml":        "id: wordpress-xmlrpc-listmethods\ninfo:\n  name: WordPress XML-RPC\n  author: nuclei-mcp\n  severity: info\n  tags: wordpress,wp\n",
+		"CVE-2022-21661.yaml":   "id: CVE-2022-21661\ninfo:\n  name: WordPress Core SQLi\n  author: nuclei-mcp\n  severity: high\n  classification:\n    cve-id: CVE-2022-21661\n  tags: cve,cve2022,wordpress,sqli\n",
+		"wp-plugin-backup.yaml": "id: wp-backup-plugin-exposure\ninfo:\n  name: WordPress Backup Plugin Exposure\n  author: nuclei-mcp\n  severity: critical\n  tags: wp-plugin,exposure\n",
+		"drupal.yaml":           "id: drupal-user-enum\ninfo:\n  name: Drupal User Enumeration\n  author: nuclei-mcp\n  severity: medium\n  tags: drupal\n",
+		"generic-exposure.yaml": "id: git-config\ninfo:\n  name: Git Config\n  author: nuclei-mcp\n  severity: medium\n  tags: config,exposure\n",
+	}
+	for name, content := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	detected := triageFinding("tech-detect", "Wappalyzer Technology Detection", severity.Info, "https://blog.example.com")
+	detected.MatcherName = "wordpress"
+	detected.Info.Tags = stringslice.StringSlice{Value: []string{"tech"}}
+	detected.ExtractedResults = []string{"WordPress 5.8.1"}
+	xmlrpc := triageFinding("wordpress-xmlrpc-listmethods", "WordPress XML-RPC", severity.Info, "https://blog.example.com")
+	xmlrpc.Info.Tags = stringslice.StringSlice{Value: []string{"wordpress", "wp"}}
+	service := &MockScannerService{MockGetAll: func() []cache.ScanResult {
+		return []cache.ScanResult{{Target: "blog.example.com", ScanTime: time.Now(), Findings: []*output.ResultEvent{detected, xmlrpc}}}
+	}}
+
+	call := func(arguments map[string]any) (api.NextSteps, error) {
+		var steps api.NextSteps
+		result, err := api.HandleNextSteps(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: arguments}}, service, nil, []string{dir})
+		if err != nil {
+			return steps, err
+		}
+		err = json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &steps)
+		return steps, err
+	}
+
+	steps, err := call(map[string]any{"target": "blog.example.com"})
+	assert.NoError(t, err)
+	if assert.Len(t, steps.Signals, 2) {
+		assert.Equal(t, "wordpress", steps.Signals[0].Tag)
+		assert.Equal(t, []string{"tech-detect:wordpress", "wordpress-xmlrpc-listmethods"}, steps.Signals[0].From)
+		assert.Equal(t, []string{"5.8.1"}, steps.Signals[0].Versions)
+	}
+	assert.Contains(t, steps.Tags, "wp-plugin")
+	// Templates tagged with a signal come first, most severe first; the
+	// templates that already reported and unrelated ones are left out
+	ids := []string{}
+	for _, recommendation := range steps.Recommendations {
+		ids = append(ids, recommendation.ID)
+	}
+	assert.Equal(t, []string{"CVE-2022-21661", "wp-backup-plugin-exposure"}, ids)
+	assert.Equal(t, "tagged wordpress", steps.Recommendations[0].Reason)
+	assert.Equal(t, "high", steps.Recommendations[0].Severity)
+
+	steps, err = call(map[string]any{"target": "blog.example.com", "limit": float64(1)})
+	assert.NoError(t, err)
+	assert.Len(t, steps.Recommendations, 1)
+
+	_, err = call(map[string]any{"target": "shop.example.com"})
+	assert.ErrorContains(t, err, "no cached scans of shop.example.com")
+}
@@ -0,0 +1,216 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/notify"
+	"nuclei-mcp/pkg/report"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/model/types/severity"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// smtpServer accepts mail without TLS or auth and sends each message's
+// recipients and data to received
+func smtpServer(t *testing.T, received chan<- []string) (string, int) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+				reply("220 localhost ESMTP")
+				var message []string
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					command := strings.ToUpper(strings.TrimSpace(line))
+					switch {
+					case strings.HasPrefix(command, "EHLO"):
+						reply("250-localhost")
+						reply("250 8BITMIME")
+					case strings.HasPrefix(command, "RCPT TO:"):
+						message = append(message, strings.TrimSpace(line[len("RCPT TO:"):]))
+						reply("250 OK")
+					case command == "DATA":
+						reply("354 Go ahead")
+						var data strings.Builder
+						for {
+							line, err := reader.ReadString('\n')
+							if err != nil || line == ".\r\n" {
+								break
+							}
+							data.WriteString(line)
+						}
+						received <- append(message, data.String())
+						reply("250 Queued")
+					case command == "QUIT":
+						reply("221 Bye")
+						return
+					default:
+						reply("250 OK")
+					}
+				}
+			}(conn)
+		}
+	}()
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+	return host, portNumber
+}
+
+func TestSMTPSink(t *testing.T) {
+	received := make(chan []string, 1)
+	host, port := smtpServer(t, received)
+
+	sink, err := notify.NewSMTPSink(notify.SMTPConfig{
+		Host: host,
+		Port: port,
+		TLS:  notify.SMTPPlain,
+		From: "nuclei-mcp <scanner@example.com>",
+		To:   []string{"security@example.com", "Ops <ops@example.com>"},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, sink.Send(context.Background(), notify.Message{Subject: "Scan alert", Text: "line one\n.line two\n"}))
+
+	select {
+	case message := <-received:
+		assert.Equal(t, []string{"<security@example.com>", "<ops@example.com>"}, message[:2])
+		assert.Contains(t, message[2], "Subject: Scan alert\r\n")
+		assert.Contains(t, message[2], "To: <security@example.com>, \"Ops\" <ops@example.com>\r\n")
+		// Lines starting with a dot are escaped on the wire
+		assert.Contains(t, message[2], "\r\n\r\nline one\r\n..line two\r\n")
+	case <-time.After(5 * time.Second):
+		t.Fatal("no email received")
+	}
+
+	// STARTTLS is required unless disabled
+	sink, err = notify.NewSMTPSink(notify.SMTPConfig{Host: host, Port: port, From: "scanner@example.com", To: []string{"security@example.com"}})
+	assert.NoError(t, err)
+	assert.ErrorContains(t, sink.Send(context.Background(), notify.Message{Subject: "Scan alert"}), "does not offer STARTTLS")
+
+	_, err = notify.NewSMTPSink(notify.SMTPConfig{Host: host, From: "scanner@example.com"})
+	assert.ErrorContains(t, err, "at least one smtp to address")
+	_, err = notify.NewSMTPSink(notify.SMTPConfig{Host: host, TLS: "ssl", From: "scanner@example.com", To: []string{"security@example.com"}})
+	assert.ErrorContains(t, err, "invalid smtp tls mode")
+}
+
+func TestAlerter(t *testing.T) {
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	minSeverity, err := notify.ParseSeverity("high")
+	assert.NoError(t, err)
+	alerter := notify.NewAlerter(nil, minSeverity, mockLogger)
+
+	finding := func(templateID string, sev severity.Severity) *output.ResultEvent {
+		event := triageFinding(templateID, templateID, sev, "https://a.example.com")
+		event.Matched = "https://a.example.com/" + templateID
+		return event
+	}
+	result := cache.ScanResult{
+		Target:   "https://a.example.com",
+		ScanTime: time.Now(),
+		Labels:   map[string]string{"environment": "production"},
+		Findings: []*output.ResultEvent{
+			finding("missing-hsts", severity.Info),
+			finding("exposed-panel", severity.High),
+			finding("CVE-2024-0001", severity.Critical),
+			finding("odd-template", severity.Unknown),
+		},
+	}
+	message, ok := alerter.Message(result)
+	assert.True(t, ok)
+	assert.Equal(t, "[nuclei-mcp] 2 findings on https://a.example.com (1 critical, 1 high)", message.Subject)
+	assert.Contains(t, message.Text, "Labels: environment=production")
+	assert.Less(t, strings.Index(message.Text, "CVE-2024-0001"), strings.Index(message.Text, "exposed-panel"), "most severe first")
+	assert.Contains(t, message.Text, "https://a.example.com/exposed-panel")
+	assert.NotContains(t, message.Text, "missing-hsts")
+
+	result.Findings = result.Findings[:1]
+	_, ok = alerter.Message(result)
+	assert.False(t, ok, "no alert below the minimum severity")
+
+	_, err = notify.ParseSeverity("urgent")
+	assert.Error(t, err)
+}
+
+func TestWebhookSink(t *testing.T) {
+	posted := make(chan map[string]any, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		posted <- payload
+	}))
+	defer srv.Close()
+
+	message := notify.Message{
+		Subject:  "[nuclei-mcp] 2 findings on https://a.example.com (1 critical, 1 medium)",
+		Text:     "plain text",
+		Target:   "https://a.example.com",
+		ScanTime: time.Now(),
+		Labels:   map[string]string{"environment": "production"},
+		Findings: []notify.Finding{
+			{Severity: "critical", Name: "RCE <in> Widget", TemplateID: "CVE-2024-0001", Matched: "https://a.example.com/rce", Resource: "finding://abc"},
+			{Severity: "medium", Name: "Exposed Panel", TemplateID: "exposed-panel", Matched: "https://a.example.com/admin", Resource: "finding://def"},
+		},
+	}
+	send := func(format string) map[string]any {
+		sink, err := notify.NewWebhookSink(srv.URL, format)
+		assert.NoError(t, err)
+		assert.NoError(t, sink.Send(context.Background(), message))
+		return <-posted
+	}
+
+	payload := send("json")
+	assert.Equal(t, "https://a.example.com", payload["target"])
+	assert.Len(t, payload["findings"], 2)
+
+	// Slack: a Block Kit attachment colored by the most severe finding
+	payload = send("slack")
+	assert.Equal(t, message.Subject, payload["text"])
+	attachment := payload["attachments"].([]any)[0].(map[string]any)
+	assert.Equal(t, report.SeverityColors["critical"], attachment["color"])
+	first := attachment["blocks"].([]any)[3].(map[string]any)["text"].(map[string]any)
+	assert.Contains(t, first["text"], "*[CRITICAL]* RCE &lt;in&gt; Widget")
+	blocks, _ := json.Marshal(attachment["blocks"])
+	assert.Contains(t, string(blocks), "finding://def")
+	assert.Contains(t, string(blocks), "environment=production")
+
+	// Teams: an Adaptive Card with each finding colored
+	payload = send("teams")
+	attachment = payload["attachments"].([]any)[0].(map[string]any)
+	assert.Equal(t, "application/vnd.microsoft.card.adaptive", attachment["contentType"])
+	card := attachment["content"].(map[string]any)
+	assert.Equal(t, "AdaptiveCard", card["type"])
+	body, _ := json.Marshal(card["body"])
+	assert.Contains(t, string(body), `"color":"attention"`)
+	assert.Contains(t, string(body), `"color":"warning"`)
+	assert.Contains(t, string(body), "finding://abc")
+
+	_, err := notify.NewWebhookSink("https://hooks.example.com/secret", "discord")
+	assert.ErrorContains(t, err, "invalid webhook format")
+	_, err = notify.NewWebhookSink("hooks.example.com/secret", "slack")
+	assert.ErrorContains(t, err, "must be an http(s) URL")
+	assert.NotContains(t, err.Error(), "secret", "webhook URLs are not echoed")
+}
@@ -0,0 +1,303 @@
+package tests
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/policy"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const petstoreSpec = `openapi: 3.0.0
+info:
+  title: Pets
+  version: "1.0"
+servers:
+  - url: https://api.example.test/v1
+security:
+  - apiKey: []
+components:
+  securitySchemes:
+    apiKey:
+      type: apiKey
+      in: query
+      name: api_key
+paths:
+  /pets/{id}:
+    get:
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: integer
+            example: 42
+      responses:
+        "200":
+          description: A pet
+    delete:
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: integer
+            example: 42
+      responses:
+        "204":
+          description: Deleted
+  /search:
+    get:
+      parameters:
+        - name: q
+          in: query
+          required: true
+          schema:
+            type: string
+            example: cat
+      responses:
+        "200":
+          description: Matching pets
+`
+
+const swaggerSpec = `swagger: "2.0"
+info:
+  title: Pets
+  version: "1.0"
+host: api.example.test
+basePath: /v2
+schemes:
+  - https
+paths:
+  /pets:
+    get:
+      responses:
+        "200":
+          description: Pets
+`
+
+const petRecordTemplate = `id: pet-record
+info:
+  name: Pet Record Exposed
+  author: nuclei-mcp
+  severity: info
+http:
+  - method: GET
+    path:
+      - "{{BaseURL}}"
+    matchers:
+      - type: word
+        words:
+          - pet-record
+`
+
+const reflectionTemplate = `id: query-reflection
+info:
+  name: Query Reflection
+  author: nuclei-mcp
+  severity: low
+  tags: dast
+http:
+  - payloads:
+      reflection:
+        - "zq7marker"
+    fuzzing:
+      - part: query
+        type: postfix
+        mode: single
+        fuzz:
+          - "{{reflection}}"
+    matchers:
+      - type: word
+        part: body
+        words:
+          - "zq7marker"
+`
+
+// openAPIService records the OpenAPI options of the scans it is asked for
+type openAPIService struct {
+	MockScannerService
+	target, protocols string
+	scanOpts          scanner.ScanOptions
+}
+
+func (s *openAPIService) ThreadSafeScan(_ context.Context, target string, _ string, protocols string, _ []string, opts ...scanner.ScanOption) (cache.ScanResult, error) {
+	s.target, s.protocols = target, protocols
+	s.scanOpts = scanner.ScanOptions{}
+	for _, opt := range opts {
+		opt(&s.scanOpts)
+	}
+	return cache.ScanResult{Target: target, ScanTime: time.Now(), APIOperations: []string{"GET " + target + "/pets"}}, nil
+}
+
+func TestOpenAPITarget(t *testing.T) {
+	target, err := scanner.OpenAPITarget([]byte(petstoreSpec))
+	assert.NoError(t, err)
+	assert.Equal(t, "https://api.example.test/v1", target)
+
+	// Swagger 2.0 documents are converted
+	target, err = scanner.OpenAPITarget([]byte(swaggerSpec))
+	assert.NoError(t, err)
+	assert.Equal(t, "https://api.example.test/v2", target)
+
+	_, err = scanner.OpenAPITarget([]byte("id: not-an-api\n"))
+	assert.ErrorIs(t, err, scanner.ErrInvalidOpenAPI)
+	assert.Equal(t, api.CodeInvalidParameter, api.ErrorCodeOf(err))
+
+	assert.ErrorIs(t, scanner.ValidateAPIParameters(map[string]string{"X-API-Key": "k3y\n"}), scanner.ErrInvalidAPIParameter)
+	assert.NoError(t, scanner.ValidateAPIParameters(map[string]string{"X-API-Key": "k3y"}))
+}
+
+func TestScannerService_OpenAPI(t *testing.T) {
+	var mu sync.Mutex
+	var requested []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested = append(requested, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		if r.URL.Query().Get("api_key") != "k3y" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/pets/42":
+			_, _ = w.Write([]byte("pet-record"))
+		case "/v1/search":
+			_, _ = w.Write([]byte("results for " + r.URL.Query().Get("q")))
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	recordTemplate := filepath.Join(dir, "pet-record.yaml")
+	assert.NoError(t, os.WriteFile(recordTemplate, []byte(petRecordTemplate), 0644))
+	fuzzTemplate := filepath.Join(dir, "query-reflection.yaml")
+	assert.NoError(t, os.WriteFile(fuzzTemplate, []byte(reflectionTemplate), 0644))
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger)
+
+	// The API key security scheme must be set
+	_, err := service.ThreadSafeScan(context.Background(), srv.URL, "", "http", nil, scanner.WithTemplateSources(recordTemplate),
+		scanner.WithOpenAPI([]byte(petstoreSpec), nil))
+	assert.ErrorIs(t, err, scanner.ErrInvalidAPIParameter)
+	assert.ErrorContains(t, err, "api_key")
+
+	result, err := service.ThreadSafeScan(context.Background(), srv.URL, "", "http", nil, scanner.WithTemplateSources(recordTemplate),
+		scanner.WithOpenAPI([]byte(petstoreSpec), map[string]string{"api_key": "k3y"}))
+	assert.NoError(t, err)
+	if assert.Len(t, result.Findings, 1) {
+		assert.Contains(t, result.Findings[0].Matched, "/v1/pets/42")
+	}
+	// GET and DELETE of /pets/{id} share their URL, which regular templates
+	// scan once
+	assert.Equal(t, []string{
+		"GET " + srv.URL + "/v1/pets/42?api_key=k3y",
+		"GET " + srv.URL + "/v1/search?api_key=k3y&q=cat",
+	}, result.APIOperations)
+
+	// Fuzzing sends each operation with its method
+	mu.Lock()
+	requested = nil
+	mu.Unlock()
+	result, err = service.ThreadSafeScan(context.Background(), srv.URL, "", "http", nil, scanner.WithTemplateSources(fuzzTemplate),
+		scanner.WithOpenAPI([]byte(petstoreSpec), map[string]string{"api_key": "k3y"}), scanner.WithFuzzing())
+	assert.NoError(t, err)
+	assert.Len(t, result.APIOperations, 3)
+	if assert.NotEmpty(t, result.Findings) {
+		assert.Equal(t, "query-reflection", result.Findings[0].TemplateID)
+		assert.Contains(t, result.Findings[0].Matched, "/v1/search")
+	}
+	mu.Lock()
+	assert.Contains(t, requested, "DELETE /v1/pets/42")
+	mu.Unlock()
+
+	_, err = service.ThreadSafeScan(context.Background(), srv.URL, "", "http", nil, scanner.WithTemplateSources(fuzzTemplate), scanner.WithFuzzing())
+	assert.ErrorIs(t, err, scanner.ErrInvalidOpenAPI)
+}
+
+func TestHandleOpenAPIScanTool(t *testing.T) {
+	service := &openAPIService{}
+	logger := log.New(os.Stdout, "test: ", log.LstdFlags)
+	scan := func(arguments map[string]any) (string, error) {
+		result, err := api.HandleOpenAPIScanTool(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: arguments}}, service, logger, nil, api.DefaultScanDefaults)
+		if err != nil {
+			return "", err
+		}
+		return result.Content[0].(mcp.TextContent).Text, nil
+	}
+
+	text, err := scan(map[string]any{
+		"target":     "https://staging.example.test",
+		"spec":       petstoreSpec,
+		"parameters": map[string]any{"api_key": "secret://pets-api"},
+		"fuzz":       true,
+		"protocols":  []any{"dns"},
+	})
+	assert.NoError(t, err)
+	assert.Contains(t, text, "API operations scanned (1):\n- GET https://staging.example.test/pets\n")
+	assert.Equal(t, "https://staging.example.test", service.target)
+	assert.Equal(t, "http", service.protocols)
+	assert.Equal(t, petstoreSpec, string(service.scanOpts.OpenAPI))
+	assert.Equal(t, map[string]string{"api_key": "secret://pets-api"}, service.scanOpts.APIParameters)
+	assert.True(t, service.scanOpts.Fuzz)
+
+	_, err = scan(map[string]any{"target": "https://staging.example.test"})
+	assert.ErrorContains(t, err, "missing spec")
+	_, err = scan(map[string]any{"target": "https://staging.example.test", "spec": petstoreSpec, "parameters": map[string]any{"bad name": "x"}})
+	assert.True(t, strings.Contains(err.Error(), "invalid API parameter"))
+}
+
+func TestScannerService_FetchOpenAPI(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/moved":
+			http.Redirect(w, r, "/openapi.yaml", http.StatusFound)
+		case "/elsewhere":
+			http.Redirect(w, r, strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)+"/openapi.yaml", http.StatusFound)
+		default:
+			_, _ = w.Write([]byte(petstoreSpec))
+		}
+	}))
+	defer srv.Close()
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	resultCache := cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags))
+	service := scanner.NewScannerService(resultCache, mockLogger, scanner.WithSelfTargetGuard(policy.NewSelfTargetGuardFor()))
+	fetcher := service.(scanner.OpenAPIFetcher)
+
+	// spec_url is checked like a scan target
+	_, err := fetcher.FetchOpenAPI(context.Background(), srv.URL+"/openapi.yaml")
+	assert.ErrorIs(t, err, policy.ErrDenied)
+	spec, err := fetcher.FetchOpenAPI(context.Background(), srv.URL+"/moved", scanner.WithSelfTargetAllowed("TICKET-1"))
+	assert.NoError(t, err)
+	assert.Equal(t, petstoreSpec, string(spec))
+
+	// Redirects may not leave the host
+	_, err = fetcher.FetchOpenAPI(context.Background(), srv.URL+"/elsewhere", scanner.WithSelfTargetAllowed("TICKET-1"))
+	assert.ErrorIs(t, err, scanner.ErrInvalidOpenAPI)
+	assert.ErrorContains(t, err, "another host")
+
+	egress, err := policy.NewEgressPolicy(policy.EgressOptions{DenyPrivate: true})
+	assert.NoError(t, err)
+	service = scanner.NewScannerService(resultCache, mockLogger, scanner.WithEgressPolicy(egress))
+	_, err = service.(scanner.OpenAPIFetcher).FetchOpenAPI(context.Background(), srv.URL+"/openapi.yaml")
+	assert.ErrorIs(t, err, policy.ErrDenied)
+}
@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/projectdiscovery/nuclei/v3/pkg/model/types/severity"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+func numberedFindings(n int) []*output.ResultEvent {
+	findings := make([]*output.ResultEvent, 0, n)
+	for i := 1; i <= n; i++ {
+		findings = append(findings, triageFinding(fmt.Sprintf("t-%d", i), fmt.Sprintf("Finding %d", i), severity.Low, "https://example.com"))
+	}
+	return findings
+}
+
+func TestResultPager(t *testing.T) {
+	pager := api.NewResultPager(2, time.Minute)
+
+	page := pager.First("example.com", numberedFindings(2), 0)
+	assert.Len(t, page.Findings, 2)
+	assert.Empty(t, page.NextToken)
+
+	page = pager.First("example.com", numberedFindings(5), 0)
+	assert.Len(t, page.Findings, 2)
+	assert.Equal(t, 5, page.Total)
+	assert.NotEmpty(t, page.NextToken)
+
+	second, err := pager.Next(page.NextToken, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, second.Offset)
+	assert.Equal(t, "t-3", second.Findings[0].TemplateID)
+
+	retry, err := pager.Next(page.NextToken, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, second.Findings, retry.Findings, "fetching a token again returns the same page")
+
+	last, err := pager.Next(second.NextToken, 10)
+	assert.NoError(t, err)
+	assert.Len(t, last.Findings, 1)
+	assert.Equal(t, "t-5", last.Findings[0].TemplateID)
+	assert.Empty(t, last.NextToken)
+
+	for _, token := range []string{"", "garbage", "unknown.2", "abc.-1"} {
+		_, err := pager.Next(token, 0)
+		assert.Error(t, err, token)
+	}
+}
+
+func TestResultPager_Expiry(t *testing.T) {
+	pager := api.NewResultPager(1, time.Millisecond)
+	page := pager.First("example.com", numberedFindings(3), 0)
+	time.Sleep(5 * time.Millisecond)
+
+	_, err := pager.Next(page.NextToken, 0)
+	assert.Error(t, err)
+}
+
+func TestHandleNucleiScanTool_Paging(t *testing.T) {
+	mockScanner := &MockScannerService{
+		MockThreadSafeScan: func(ctx context.Context, target string, severity string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+			return cache.ScanResult{Target: target, ScanTime: time.Now(), Findings: numberedFindings(5)}, nil
+		},
+	}
+	pager := api.NewResultPager(api.DefaultPageSize, time.Minute)
+	request := mcp.CallToolRequest{
+		Params: mcp.CallToolParams{Arguments: map[string]interface{}{"target": "https://example.com", "page_size": float64(3)}},
+	}
+
+	result, err := api.HandleNucleiScanTool(context.Background(), request, mockScanner, log.New(os.Stdout, "test: ", log.LstdFlags), pager, api.DefaultScanDefaults)
+	assert.NoError(t, err)
+	text := result.Content[0].(mcp.TextContent).Text
+	assert.Contains(t, text, "Found 5 vulnerabilities")
+	assert.Contains(t, text, "Finding #3:")
+	assert.NotContains(t, text, "Finding #4:")
+	assert.Contains(t, text, "Showing findings 1-3 of 5")
+
+	token := regexp.MustCompile(`continuation_token "([^"]+)"`).FindStringSubmatch(text)
+	assert.Len(t, token, 2)
+
+	request.Params.Arguments = map[string]interface{}{"continuation_token": token[1]}
+	result, err = api.HandleFetchMoreResults(context.Background(), request, pager)
+	assert.NoError(t, err)
+	text = result.Content[0].(mcp.TextContent).Text
+	assert.Contains(t, text, "Findings 4-5 of 5 for target: https://example.com")
+	assert.Contains(t, text, "Finding #5:")
+	assert.Contains(t, text, "No more results.")
+}
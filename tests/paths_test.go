@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"nuclei-mcp/pkg/paths"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setUserDirs points the per-user config, data and cache directories of the
+// current OS at temporary directories and returns them
+func setUserDirs(t *testing.T) (string, string, string) {
+	configDir, dataDir, cacheDir := t.TempDir(), t.TempDir(), t.TempDir()
+	switch runtime.GOOS {
+	case "windows":
+		t.Setenv("AppData", configDir)
+		t.Setenv("LocalAppData", cacheDir)
+		dataDir = configDir
+	case "darwin", "ios":
+		home := t.TempDir()
+		t.Setenv("HOME", home)
+		configDir = filepath.Join(home, "Library", "Application Support")
+		cacheDir = filepath.Join(home, "Library", "Caches")
+		dataDir = configDir
+	case "plan9":
+		t.Skip("per-user directories are not set through the environment on plan9")
+	default:
+		t.Setenv("XDG_CONFIG_HOME", configDir)
+		t.Setenv("XDG_DATA_HOME", dataDir)
+		t.Setenv("XDG_CACHE_HOME", cacheDir)
+	}
+	return configDir, dataDir, cacheDir
+}
+
+func TestPaths_UserDirs(t *testing.T) {
+	configDir, dataDir, cacheDir := setUserDirs(t)
+
+	assert.Equal(t, filepath.Join(configDir, "nuclei-mcp"), paths.ConfigDir())
+	assert.Equal(t, filepath.Join(dataDir, "nuclei-mcp"), paths.DataDir())
+	assert.Equal(t, filepath.Join(cacheDir, "nuclei-mcp"), paths.CacheDir())
+	assert.Equal(t, filepath.Join(dataDir, "nuclei-mcp", "nuclei-templates"), paths.TemplatesDir())
+	assert.Equal(t, filepath.Join(dataDir, "nuclei-mcp", "exports"), paths.ExportsDir())
+	assert.Equal(t, filepath.Join(dataDir, "nuclei-mcp", "tenants"), paths.TenantsDir())
+	assert.Equal(t, filepath.Join(dataDir, "nuclei-mcp", "findings.json"), paths.DataFile("findings.json"))
+	assert.Equal(t, filepath.Join(cacheDir, "nuclei-mcp", "logs", "nuclei_mcp.log"), paths.LogFile())
+	assert.Equal(t, filepath.Join(cacheDir, "nuclei-mcp", "bundles"), paths.BundlesDir())
+}
+
+func TestPaths_XDGDefaults(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the XDG layout applies to Unix systems other than macOS")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_DATA_HOME", "relative/is/ignored")
+	t.Setenv("XDG_CACHE_HOME", "")
+
+	assert.Equal(t, filepath.Join(home, ".config", "nuclei-mcp"), paths.ConfigDir())
+	assert.Equal(t, filepath.Join(home, ".local", "share", "nuclei-mcp"), paths.DataDir())
+	assert.Equal(t, filepath.Join(home, ".cache", "nuclei-mcp"), paths.CacheDir())
+}
+
+func TestPaths_NoHome(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("unsetting the home directory is only portable on linux")
+	}
+	t.Setenv("HOME", "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("XDG_CACHE_HOME", "")
+
+	assert.Equal(t, ".", paths.ConfigDir())
+	assert.Equal(t, ".", paths.DataDir())
+	assert.True(t, filepath.IsAbs(paths.CacheDir()))
+}
+
+func TestPaths_Migrate(t *testing.T) {
+	legacy, data := t.TempDir(), t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(legacy, "findings.json"), []byte("[]"), 0644))
+	assert.NoError(t, os.MkdirAll(filepath.Join(legacy, "nuclei-templates", "web"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(legacy, "nuclei-templates", "web", "custom.yaml"), []byte("id: custom"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(legacy, "assets.json"), []byte("old"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(data, "assets.json"), []byte("new"), 0644))
+
+	moves := []paths.Move{
+		{From: filepath.Join(legacy, "findings.json"), To: filepath.Join(data, "findings.json")},
+		{From: filepath.Join(legacy, "nuclei-templates"), To: filepath.Join(data, "templates", "nuclei-templates")},
+		{From: filepath.Join(legacy, "assets.json"), To: filepath.Join(data, "assets.json")},
+		{From: filepath.Join(legacy, "missing.json"), To: filepath.Join(data, "missing.json")},
+		{From: filepath.Join(data, "assets.json"), To: filepath.Join(data, "assets.json")},
+	}
+	done, err := paths.Migrate(moves)
+	assert.NoError(t, err)
+	assert.Equal(t, moves[:2], done)
+
+	assert.NoFileExists(t, filepath.Join(legacy, "findings.json"))
+	assert.FileExists(t, filepath.Join(data, "findings.json"))
+	content, err := os.ReadFile(filepath.Join(data, "templates", "nuclei-templates", "web", "custom.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, "id: custom", string(content))
+	content, err = os.ReadFile(filepath.Join(data, "assets.json"))
+	assert.NoError(t, err)
+	assert.Equal(t, "new", string(content), "existing files are not replaced")
+	assert.FileExists(t, filepath.Join(legacy, "assets.json"))
+}
+
+func TestPaths_Pending(t *testing.T) {
+	legacy, data := t.TempDir(), t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(legacy, "findings.json"), []byte("[]"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(legacy, "assets.json"), []byte("old"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(data, "assets.json"), []byte("new"), 0644))
+
+	moves := []paths.Move{
+		{From: filepath.Join(legacy, "findings.json"), To: filepath.Join(data, "findings.json")},
+		{From: filepath.Join(legacy, "assets.json"), To: filepath.Join(data, "assets.json")},
+		{From: filepath.Join(legacy, "missing.json"), To: filepath.Join(data, "missing.json")},
+	}
+	assert.Equal(t, moves[:1], paths.Pending(moves))
+	// Nothing is moved
+	assert.FileExists(t, filepath.Join(legacy, "findings.json"))
+	assert.NoFileExists(t, filepath.Join(data, "findings.json"))
+}
@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"nuclei-mcp/pkg/policy"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplateTags(t *testing.T) {
+	tags, err := policy.TemplateTags([]byte("id: a\ninfo:\n  name: A\n  tags: cve, DoS ,network\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"cve", "DoS", "network"}, tags)
+
+	tags, err = policy.TemplateTags([]byte("id: b\ninfo:\n  name: B\n  tags:\n    - fuzz\n    - xss\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"fuzz", "xss"}, tags)
+
+	_, err = policy.TemplateTags([]byte("id: [unterminated"))
+	assert.Error(t, err)
+}
+
+func TestTemplatePolicy_CheckTemplate(t *testing.T) {
+	p := policy.NewTemplatePolicy(policy.DefaultDeniedTags)
+	safe := []byte("id: safe\ninfo:\n  name: Safe\n  tags: cve,xss\n")
+	unsafe := []byte("id: flood\ninfo:\n  name: Flood\n  tags: DoS\n")
+
+	assert.NoError(t, p.CheckTemplate(safe, policy.Override{}))
+	assert.Error(t, p.CheckTemplate(unsafe, policy.Override{}))
+	assert.Error(t, p.CheckTemplate(unsafe, policy.Override{Allow: true}))
+	assert.Error(t, p.CheckTemplate(unsafe, policy.Override{Approval: "SEC-42"}))
+	assert.NoError(t, p.CheckTemplate(unsafe, policy.Override{Allow: true, Approval: "SEC-42"}))
+
+	// Content that cannot be parsed cannot be checked, so it is denied
+	err := p.CheckTemplate([]byte("id: broken\ninfo: [unclosed"), policy.Override{Allow: true, Approval: "SEC-42"})
+	assert.ErrorIs(t, err, policy.ErrDenied)
+}
+
+func TestTemplatePolicy_CustomDenyList(t *testing.T) {
+	p := policy.NewTemplatePolicy([]string{" Brute-Force ", ""})
+	assert.Equal(t, []string{"brute-force"}, p.DeniedTags())
+	assert.Equal(t, []string{"brute-force"}, p.Denied([]string{"dos", "brute-force"}))
+}
+
+func TestScope_Contains(t *testing.T) {
+	scope := policy.NewScope([]string{"https://example.com", "http://intranet.local:8080/app/", "file:///home/user/project"})
+	assert.Equal(t, []string{"https://example.com", "http://intranet.local:8080/app/"}, scope.Entries())
+
+	assert.True(t, scope.Contains("https://example.com/login"))
+	assert.True(t, scope.Contains("api.example.com"))
+	assert.True(t, scope.Contains("http://intranet.local:8080/app/admin"))
+	assert.True(t, scope.Contains("http://intranet.local:8080/app"))
+
+	assert.False(t, scope.Contains("https://notexample.com"))
+	assert.False(t, scope.Contains("http://intranet.local:8080/application"))
+	assert.False(t, scope.Contains("http://intranet.local:9090/app/"))
+	assert.False(t, scope.Contains(""))
+
+	assert.True(t, policy.NewScope(nil).Contains("anything.test"))
+	assert.True(t, policy.NewScope([]string{"file:///tmp"}).Empty())
+}
+
+func TestScope_Check(t *testing.T) {
+	scope := policy.NewScope([]string{"https://example.com"})
+	assert.NoError(t, scope.Check("example.com", policy.Override{}))
+	assert.Error(t, scope.Check("other.com", policy.Override{}))
+	assert.Error(t, scope.Check("other.com", policy.Override{Allow: true}))
+	assert.NoError(t, scope.Check("other.com", policy.Override{Allow: true, Approval: "pentest SOW 7"}))
+}
+
+func TestNewEgressPolicy(t *testing.T) {
+	egress, err := policy.NewEgressPolicy(policy.EgressOptions{})
+	assert.NoError(t, err)
+	assert.Nil(t, egress)
+	assert.NoError(t, egress.CheckTarget(context.Background(), "http://10.0.0.1"))
+
+	_, err = policy.NewEgressPolicy(policy.EgressOptions{DenyCIDRs: []string{"10.0.0.0/33"}})
+	assert.Error(t, err)
+	_, err = policy.NewEgressPolicy(policy.EgressOptions{AllowedPorts: []int{0}})
+	assert.Error(t, err)
+
+	egress, err = policy.NewEgressPolicy(policy.EgressOptions{DenyMetadata: true, DenyCIDRs: []string{"203.0.113.7"}})
+	assert.NoError(t, err)
+	assert.Contains(t, egress.DenyList(), "169.254.0.0/16")
+	assert.Contains(t, egress.DenyList(), "203.0.113.7/32")
+	assert.NotContains(t, egress.DenyList(), "10.0.0.0/8")
+}
+
+func TestEgressPolicy_CheckTarget(t *testing.T) {
+	egress, err := policy.NewEgressPolicy(policy.EgressOptions{
+		DenyPrivate:  true,
+		DenyMetadata: true,
+		AllowedPorts: []int{80, 443, 8443},
+	})
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	denied := []string{
+		"http://169.254.169.254/latest/meta-data/",
+		"10.1.2.3",
+		"https://192.168.1.10:8443",
+		"http://[::ffff:172.16.0.1]",
+		"http://[::1]",
+		"http://localhost",
+		"https://93.184.215.14:22",
+		"http://93.184.215.14:8080",
+	}
+	for _, target := range denied {
+		assert.Error(t, egress.CheckTarget(ctx, target), target)
+	}
+
+	allowed := []string{
+		"93.184.215.14",
+		"https://93.184.215.14:8443/login",
+		"http://[2606:2800:21f:cb07:6820:80da:af6b:8b2c]",
+	}
+	for _, target := range allowed {
+		assert.NoError(t, egress.CheckTarget(ctx, target), target)
+	}
+
+	httpsOnly, err := policy.NewEgressPolicy(policy.EgressOptions{AllowedPorts: []int{443}})
+	assert.NoError(t, err)
+	assert.NoError(t, httpsOnly.CheckTarget(ctx, "93.184.215.14"))
+	assert.ErrorContains(t, httpsOnly.CheckTarget(ctx, "http://93.184.215.14"), "port 80")
+}
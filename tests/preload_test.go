@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const preloadTemplate = `id: preload-marker
+info:
+  name: Preload Marker
+  author: nuclei-mcp
+  severity: info
+http:
+  - method: GET
+    path:
+      - "{{BaseURL}}"
+    matchers:
+      - type: word
+        words:
+          - preload-marker
+`
+
+func TestScannerService_Preload(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("preload-marker"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "preload-marker.yaml"), []byte(preloadTemplate), 0644))
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger,
+		scanner.WithTemplateDirs(dir))
+
+	preloader, ok := service.(scanner.Preloader)
+	assert.True(t, ok)
+	assert.NoError(t, preloader.Preload(context.Background()))
+	assert.NoError(t, preloader.Preload(context.Background()))
+
+	// Each scan runs on the warm engine and only sees its own findings,
+	// including after another engine reset nuclei's shared protocol state
+	for i := 0; i < 2; i++ {
+		target := fmt.Sprintf("%s/%d", srv.URL, i)
+		result, err := service.ThreadSafeScan(context.Background(), target, "", "", []string{"preload-marker"})
+		assert.NoError(t, err)
+		if assert.Len(t, result.Findings, 1) {
+			assert.Equal(t, "preload-marker", result.Findings[0].TemplateID)
+			assert.Contains(t, result.Findings[0].Matched, target)
+		}
+
+		_, err = scanner.RunExtractors(context.Background(), srv.URL, []scanner.Extractor{{Type: scanner.ExtractorPreset, Expression: "html-title"}})
+		assert.NoError(t, err)
+	}
+}
+
+func TestScannerService_PreloadCodeTemplates(t *testing.T) {
+	recorder := recordSpans(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("preload-marker"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "preload-marker.yaml"), []byte(preloadTemplate), 0644))
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger,
+		scanner.WithTemplateDirs(dir), scanner.WithCodeTemplatesAllowed(true))
+	assert.NoError(t, service.(scanner.Preloader).Preload(context.Background()))
+
+	// Only scans that opt in to code templates get them, on a fresh engine
+	_, err := service.ThreadSafeScan(context.Background(), srv.URL, "", "", []string{"preload-marker"})
+	assert.NoError(t, err)
+	_, err = service.ThreadSafeScan(context.Background(), srv.URL, "", "", []string{"preload-marker"}, scanner.WithCodeTemplates())
+	assert.NoError(t, err)
+
+	var warm []bool
+	for _, span := range recorder.Ended() {
+		if span.Name() != "scan.execute" {
+			continue
+		}
+		onWarm := false
+		for _, attr := range span.Attributes() {
+			if attr.Key == "engine.warm" {
+				onWarm = attr.Value.AsBool()
+			}
+		}
+		warm = append(warm, onWarm)
+	}
+	assert.Equal(t, []bool{true, false}, warm)
+}
@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestScannerService_Preview(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "fragile.yaml"), []byte(fragileTemplate(30)), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "small.yaml"), []byte(
+		"id: small-check\ninfo:\n  name: Small Check\n  author: nuclei-mcp\n  severity: info\nhttp:\n  - method: GET\n    path:\n      - \"{{BaseURL}}/a\"\n      - \"{{BaseURL}}/b\"\n    matchers:\n      - type: word\n        words:\n          - marker\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "high.yaml"), []byte(
+		"id: high-check\ninfo:\n  name: High Check\n  author: nuclei-mcp\n  severity: high\nhttp:\n  - method: GET\n    path:\n      - \"{{BaseURL}}\"\n    matchers:\n      - type: word\n        words:\n          - marker\n"), 0644))
+
+	// small-check took 40s in past scans
+	timingsFile := filepath.Join(t.TempDir(), "template-timings.json")
+	assert.NoError(t, os.WriteFile(timingsFile, []byte(`{"small-check":{"mean_ns":40000000000,"runs":3}}`), 0644))
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger,
+		scanner.WithTemplateTimings(scanner.NewTemplateTimings(timingsFile)))
+	previewer := service.(scanner.Previewer)
+
+	preview, err := previewer.Preview(context.Background(), "http://127.0.0.1:1", "info", "", nil,
+		scanner.WithTemplateSources(dir), scanner.WithRateLimit(4))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, preview.Available)
+	assert.Equal(t, 2, preview.Templates)
+	assert.Equal(t, 1, preview.Excluded[scanner.ExcludedFilters])
+	assert.Equal(t, map[string]int{"http": 2}, preview.Protocols)
+	assert.Equal(t, 32, preview.Requests)
+	assert.Equal(t, 4, preview.RateLimit)
+	assert.Equal(t, 8.0, preview.RateLimitedSeconds)
+	assert.Equal(t, 1, preview.TemplatesWithHistory)
+	// Without a history, fragile-paths is assumed to take as long as
+	// small-check
+	assert.InDelta(t, 80/float64(preview.Concurrency), preview.HistorySeconds, 0.001)
+	assert.Equal(t, max(preview.RateLimitedSeconds, preview.HistorySeconds), preview.EstimatedSeconds)
+	if assert.Len(t, preview.Heaviest, 2) {
+		assert.Equal(t, scanner.PreviewTemplate{ID: "fragile-paths", Protocol: "http", Requests: 30}, preview.Heaviest[0])
+	}
+	assert.Empty(t, service.GetAll(), "nothing was scanned")
+
+	// Templates over the request limit would be skipped
+	preview, err = previewer.Preview(context.Background(), "http://127.0.0.1:1", "info", "", nil,
+		scanner.WithTemplateSources(dir), scanner.WithMaxTemplateRequests(10))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"fragile-paths"}, preview.SkippedTemplates)
+	assert.Equal(t, 2, preview.Requests)
+
+	// nuclei_scan previews with dry_run
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]any{
+		"target":   "http://127.0.0.1:1",
+		"severity": "info",
+		"dry_run":  true,
+	}
+	result, err := api.HandleNucleiScanTool(context.Background(), request, service, nil, nil, api.ScanDefaults{}, scanner.WithTemplateSources(dir))
+	assert.NoError(t, err)
+	text := result.Content[0].(mcp.TextContent).Text
+	assert.Contains(t, text, "Dry run for target: http://127.0.0.1:1")
+	assert.Contains(t, text, "Estimated requests: 32")
+	assert.Contains(t, text, "- fragile-paths (http): 30 requests")
+}
@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestScannerService_RunningScans(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(2 * time.Second):
+			}
+			_, _ = w.Write([]byte("slow-marker"))
+			return
+		}
+		_, _ = w.Write([]byte("exposed-marker"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "exposed-marker.yaml"), []byte(exposedTemplate), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "slow-marker.yaml"), []byte(slowTemplate), 0644))
+	timingsFile := filepath.Join(t.TempDir(), "template-timings.json")
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger,
+		scanner.WithTemplateDirs(dir), scanner.WithTemplateTimings(scanner.NewTemplateTimings(timingsFile)))
+	reporter, ok := service.(scanner.ProgressReporter)
+	if !assert.True(t, ok) {
+		return
+	}
+
+	// scan waits for a scan's estimate while it runs
+	scan := func() scanner.ScanProgress {
+		done := make(chan error, 1)
+		go func() {
+			_, err := service.ThreadSafeScan(context.Background(), srv.URL, "", "", nil, scanner.WithFreshResult())
+			done <- err
+		}()
+
+		var estimated scanner.ScanProgress
+		for {
+			select {
+			case err := <-done:
+				assert.NoError(t, err)
+				assert.Empty(t, reporter.RunningScans())
+				return estimated
+			case <-time.After(50 * time.Millisecond):
+			}
+			for _, status := range reporter.RunningScans() {
+				if status.EstimatedRemainingSeconds != nil && estimated.EstimatedRemainingSeconds == nil {
+					estimated = status
+				}
+			}
+		}
+	}
+
+	status := scan()
+	assert.Equal(t, srv.URL, status.Target)
+	assert.Equal(t, scanner.StageRunning, status.Stage)
+	assert.Equal(t, 2, status.TemplatesTotal)
+	if assert.NotNil(t, status.PercentComplete) {
+		assert.Less(t, *status.PercentComplete, 100.0)
+	}
+	assert.Zero(t, status.TemplatesWithHistory)
+
+	data, err := os.ReadFile(timingsFile)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "slow-marker")
+
+	// The next scan is estimated from the run times of the first
+	status = scan()
+	assert.Equal(t, 2, status.TemplatesWithHistory)
+	if assert.NotNil(t, status.EstimatedRemainingSeconds) {
+		assert.Greater(t, *status.EstimatedRemainingSeconds, 0.5)
+	}
+}
+
+type staticProgress []scanner.ScanProgress
+
+func (p staticProgress) RunningScans() []scanner.ScanProgress {
+	return p
+}
+
+func TestHandleScanStatus(t *testing.T) {
+	percent := 40.0
+	reporter := staticProgress{
+		{CorrelationID: "req-1", Target: "https://a.example.com", Stage: scanner.StageRunning, TemplatesTotal: 10, TemplatesDone: 4, PercentComplete: &percent},
+		{CorrelationID: "req-2", Target: "https://b.example.com", Stage: scanner.StageLoading},
+	}
+	status := func(arguments map[string]any) []scanner.ScanProgress {
+		result, err := api.HandleScanStatus(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: arguments}}, reporter)
+		assert.NoError(t, err)
+		var response struct {
+			Scans []scanner.ScanProgress `json:"scans"`
+		}
+		assert.NoError(t, json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &response))
+		return response.Scans
+	}
+
+	assert.Len(t, status(nil), 2)
+
+	scans := status(map[string]any{"target": "https://a.example.com"})
+	if assert.Len(t, scans, 1) && assert.NotNil(t, scans[0].PercentComplete) {
+		assert.Equal(t, 40.0, *scans[0].PercentComplete)
+	}
+
+	scans = status(map[string]any{"correlation_id": "req-2"})
+	if assert.Len(t, scans, 1) {
+		assert.Nil(t, scans[0].PercentComplete)
+	}
+
+	assert.Empty(t, status(map[string]any{"correlation_id": "req-3"}))
+}
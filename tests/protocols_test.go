@@ -0,0 +1,60 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestNormalizeProtocols(t *testing.T) {
+	assert.Equal(t, []string{"dns", "file", "http", "headless", "tcp", "workflow", "ssl", "websocket", "whois", "code", "javascript"}, scanner.SupportedProtocols())
+
+	tests := map[string]string{
+		"":                 "",
+		"http,https":       "http",
+		"HTTPS":            "http",
+		" dns , ssl ":      "dns,ssl",
+		"network,js,tcp":   "tcp,javascript",
+		"http,,headless,":  "http,headless",
+		"whois,http,whois": "whois,http",
+	}
+	for input, expected := range tests {
+		normalized, err := scanner.NormalizeProtocols(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, expected, normalized, input)
+	}
+
+	_, err := scanner.NormalizeProtocols("http,ftp")
+	assert.ErrorIs(t, err, scanner.ErrInvalidProtocol)
+	assert.ErrorContains(t, err, `"ftp"`)
+	assert.Equal(t, api.CodeInvalidParameter, api.ErrorCodeOf(err))
+}
+
+func TestScannerService_Scan_InvalidProtocol(t *testing.T) {
+	mockCache := new(MockResultCache)
+	mockLogger := new(MockConsoleLogger)
+	service := scanner.NewScannerService(mockCache, mockLogger)
+
+	_, err := service.Scan("example.com", "info", "smtp", nil)
+	assert.ErrorIs(t, err, scanner.ErrInvalidProtocol)
+	_, err = service.ThreadSafeScan(context.Background(), "example.com", "info", "http,smtp", nil)
+	assert.ErrorIs(t, err, scanner.ErrInvalidProtocol)
+	mockCache.AssertNotCalled(t, "Get", mock.Anything)
+}
+
+func TestHandleMultiScanTool_InvalidProtocol(t *testing.T) {
+	mockScanner := &MockScannerService{}
+	multi := scanner.NewMultiScanner(mockScanner, scanner.Concurrency{})
+
+	_, err := api.HandleMultiScanTool(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: map[string]any{
+		"targets":   []any{"a.example.com", "b.example.com"},
+		"protocols": "http,gopher",
+	}}}, multi, api.DefaultScanDefaults)
+	assert.ErrorIs(t, err, scanner.ErrInvalidProtocol)
+}
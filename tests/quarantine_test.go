@@ -0,0 +1,177 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// brokenFlowTemplate fails at runtime: its flow calls an undefined function
+const brokenFlowTemplate = `id: broken-flow
+info:
+  name: Broken Flow
+  author: nuclei-mcp
+  severity: info
+flow: |
+  http(1);
+  undefinedFunction();
+http:
+  - method: GET
+    path:
+      - "{{BaseURL}}/"
+    matchers:
+      - type: word
+        words:
+          - never-matches
+`
+
+const healthyTemplate = `id: working-check
+info:
+  name: Working Check
+  author: nuclei-mcp
+  severity: info
+http:
+  - method: GET
+    path:
+      - "{{BaseURL}}/"
+    matchers:
+      - type: word
+        words:
+          - never-matches
+`
+
+func TestScannerService_TemplateQuarantine(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "broken.yaml"), []byte(brokenFlowTemplate), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "working.yaml"), []byte(healthyTemplate), 0644))
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	file := filepath.Join(t.TempDir(), "template-quarantine.json")
+	quarantine := scanner.NewTemplateQuarantine(file, 2)
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger, scanner.WithTemplateQuarantine(quarantine))
+	scan := func() cache.ScanResult {
+		result, err := service.Scan(srv.URL, "info", "", nil, scanner.WithTemplateSources(dir), scanner.WithFreshResult())
+		assert.NoError(t, err)
+		return result
+	}
+
+	// The first error only puts the template under watch
+	scan()
+	assert.Empty(t, quarantine.List(false))
+	watched := quarantine.List(true)
+	if assert.Len(t, watched, 1) {
+		assert.Equal(t, "broken-flow", watched[0].ID)
+		assert.Equal(t, 1, watched[0].Errors)
+		assert.Contains(t, watched[0].LastError, "undefinedFunction")
+		assert.Nil(t, watched[0].QuarantinedAt)
+	}
+
+	scan()
+	quarantined := quarantine.List(false)
+	if assert.Len(t, quarantined, 1) {
+		assert.Equal(t, 2, quarantined[0].Errors)
+		assert.NotNil(t, quarantined[0].QuarantinedAt)
+	}
+
+	// Quarantined templates are left out of later scans, also after a
+	// restart
+	result := scan()
+	if assert.NotNil(t, result.Stats) && assert.NotNil(t, result.Stats.Templates) {
+		assert.Equal(t, 1, result.Stats.Templates.Loaded)
+	}
+	assert.Len(t, scanner.NewTemplateQuarantine(file, 2).List(false), 1)
+
+	released, err := quarantine.Release("broken-flow")
+	assert.NoError(t, err)
+	assert.True(t, released)
+	assert.Empty(t, quarantine.List(true))
+	released, err = quarantine.Release("broken-flow")
+	assert.NoError(t, err)
+	assert.False(t, released)
+}
+
+func TestScannerService_TemplateQuarantineIgnoresTargetErrors(t *testing.T) {
+	// The target accepts connections and drops them without a response
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if conn, _, err := w.(http.Hijacker).Hijack(); err == nil {
+			conn.Close()
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "healthy.yaml"), []byte(healthyTemplate), 0644))
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	quarantine := scanner.NewTemplateQuarantine(filepath.Join(t.TempDir(), "template-quarantine.json"), 1)
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger, scanner.WithTemplateQuarantine(quarantine))
+
+	result, err := service.Scan(srv.URL, "info", "", nil, scanner.WithTemplateSources(dir))
+	assert.NoError(t, err)
+	if assert.NotNil(t, result.Stats) && assert.NotNil(t, result.Stats.Templates) {
+		assert.Equal(t, 1, result.Stats.Templates.Executed)
+	}
+	assert.Empty(t, quarantine.List(true))
+}
+
+func TestHandleQuarantinedTemplates(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "template-quarantine.json")
+	since := time.Now()
+	stored := map[string]scanner.QuarantinedTemplate{
+		"broken-flow": {ID: "broken-flow", Errors: 3, LastError: "failed to execute flow", LastErrorAt: since, QuarantinedAt: &since},
+		"flaky-check": {ID: "flaky-check", Errors: 1, LastError: "failed to evaluate", LastErrorAt: since},
+	}
+	data, err := json.Marshal(stored)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(file, data, 0644))
+	quarantine := scanner.NewTemplateQuarantine(file, 3)
+
+	list := func(args map[string]any) []scanner.QuarantinedTemplate {
+		result, err := api.HandleListQuarantinedTemplates(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: args}}, quarantine)
+		assert.NoError(t, err)
+		var decoded struct {
+			Threshold int                           `json:"threshold"`
+			Templates []scanner.QuarantinedTemplate `json:"templates"`
+		}
+		assert.NoError(t, json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &decoded))
+		assert.Equal(t, 3, decoded.Threshold)
+		return decoded.Templates
+	}
+	if templates := list(map[string]any{}); assert.Len(t, templates, 1) {
+		assert.Equal(t, "broken-flow", templates[0].ID)
+	}
+	assert.Len(t, list(map[string]any{"watched": true}), 2)
+
+	unquarantine := func(id string) (*mcp.CallToolResult, error) {
+		return api.HandleUnquarantineTemplate(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: map[string]any{"id": id}}}, quarantine)
+	}
+	result, err := unquarantine("broken-flow")
+	assert.NoError(t, err)
+	assert.Contains(t, result.Content[0].(mcp.TextContent).Text, "broken-flow")
+	assert.Empty(t, list(map[string]any{}))
+	_, err = unquarantine("broken-flow")
+	assert.ErrorContains(t, err, "not quarantined")
+	_, err = unquarantine(" ")
+	assert.Error(t, err)
+}
@@ -0,0 +1,157 @@
+package tests
+
+import (
+	"context"
+	"log"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// queueOrder fills the single slot of queue, queues scans of the given
+// priorities one after another and returns the order in which they start
+func queueOrder(t *testing.T, queue *scanner.ScanQueue, priorities ...scanner.Priority) []string {
+	release, err := queue.Acquire(context.Background(), scanner.PriorityInteractive)
+	assert.NoError(t, err)
+
+	var mu sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	for i, priority := range priorities {
+		wg.Add(1)
+		go func(name string, priority scanner.Priority) {
+			defer wg.Done()
+			release, err := queue.Acquire(context.Background(), priority)
+			if !assert.NoError(t, err) {
+				return
+			}
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			release()
+		}(priority.String()+string(rune('1'+i)), priority)
+
+		// Wait for the scan to be queued so the queue order is known
+		assert.Eventually(t, func() bool {
+			stats := queue.Stats()
+			return stats.Interactive+stats.Background == i+1
+		}, time.Second, time.Millisecond)
+	}
+
+	release()
+	wg.Wait()
+	return order
+}
+
+func TestScanQueue_StrictPolicy(t *testing.T) {
+	queue := scanner.NewScanQueue(1, scanner.QueueStrict, 0)
+	order := queueOrder(t, queue,
+		scanner.PriorityBackground,
+		scanner.PriorityBackground,
+		scanner.PriorityInteractive,
+		scanner.PriorityInteractive,
+	)
+	assert.Equal(t, []string{"interactive3", "interactive4", "background1", "background2"}, order)
+}
+
+func TestScanQueue_FairPolicy(t *testing.T) {
+	queue := scanner.NewScanQueue(1, scanner.QueueFair, 2)
+	order := queueOrder(t, queue,
+		scanner.PriorityBackground,
+		scanner.PriorityInteractive,
+		scanner.PriorityInteractive,
+		scanner.PriorityInteractive,
+		scanner.PriorityInteractive,
+	)
+	assert.Equal(t, []string{"interactive2", "interactive3", "background1", "interactive4", "interactive5"}, order)
+}
+
+func TestScanQueue_Defaults(t *testing.T) {
+	stats := scanner.NewScanQueue(0, "", 0).Stats()
+	assert.Equal(t, scanner.DefaultQueueSlots, stats.Slots)
+	assert.Equal(t, scanner.QueueFair, stats.Policy)
+}
+
+func TestScanQueue_GiveUpWaiting(t *testing.T) {
+	queue := scanner.NewScanQueue(1, scanner.QueueFair, 0)
+	release, err := queue.Acquire(context.Background(), scanner.PriorityInteractive)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = queue.Acquire(ctx, scanner.PriorityBackground)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Zero(t, queue.Stats().Background)
+
+	release()
+	stats := queue.Stats()
+	assert.Zero(t, stats.Running)
+
+	// The freed slot is available again
+	release, err = queue.Acquire(context.Background(), scanner.PriorityBackground)
+	assert.NoError(t, err)
+	release()
+}
+
+func TestScannerService_ScanWaitsForQueueSlot(t *testing.T) {
+	queue := scanner.NewScanQueue(1, scanner.QueueFair, 0)
+	release, err := queue.Acquire(context.Background(), scanner.PriorityInteractive)
+	assert.NoError(t, err)
+	defer release()
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger,
+		scanner.WithScanQueue(queue),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = service.ThreadSafeScan(ctx, "https://example.com", "", "", nil, scanner.WithPriority(scanner.PriorityBackground))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	mockLogger.AssertCalled(t, "Log", "Scan of %s queued (%s priority): %d scans running, %d interactive and %d background waiting", mock.Anything)
+}
+
+func TestParsePriority(t *testing.T) {
+	priority, err := scanner.ParsePriority(" Background ")
+	assert.NoError(t, err)
+	assert.Equal(t, scanner.PriorityBackground, priority)
+
+	priority, err = scanner.ParsePriority("")
+	assert.NoError(t, err)
+	assert.Equal(t, scanner.PriorityInteractive, priority)
+
+	_, err = scanner.ParsePriority("urgent")
+	assert.ErrorIs(t, err, scanner.ErrInvalidPriority)
+	assert.Equal(t, api.CodeInvalidParameter, api.ErrorCodeOf(err))
+}
+
+func TestHandleNucleiScanTool_Priority(t *testing.T) {
+	var scanned bool
+	mockService := &MockScannerService{
+		MockThreadSafeScan: func(ctx context.Context, target string, severity string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+			scanned = true
+			return cache.ScanResult{Target: target}, nil
+		},
+	}
+
+	arguments := map[string]any{"target": "https://example.com", "priority": "background"}
+	_, err := api.HandleNucleiScanTool(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: arguments}}, mockService, nil, nil, api.DefaultScanDefaults)
+	assert.NoError(t, err)
+	assert.True(t, scanned)
+
+	scanned = false
+	arguments["priority"] = "urgent"
+	_, err = api.HandleNucleiScanTool(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: arguments}}, mockService, nil, nil, api.DefaultScanDefaults)
+	assert.ErrorIs(t, err, scanner.ErrInvalidPriority)
+	assert.False(t, scanned)
+}
@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestQuotaTracker_RefusesExhaustedClient(t *testing.T) {
+	tracker := scanner.NewQuotaTracker(time.Hour, scanner.Quota{Scans: 1}, map[string]scanner.Quota{
+		"CI-Bot": {Requests: 10},
+	})
+
+	assert.NoError(t, tracker.Check("agent"))
+	tracker.Record("agent", &cache.ScanStats{Requests: 3, BytesSent: 100, BytesReceived: 400, WallTime: time.Second})
+	err := tracker.Check("agent")
+	assert.ErrorIs(t, err, scanner.ErrQuotaExceeded)
+	assert.ErrorContains(t, err, "1 of 1 scans")
+	assert.Equal(t, api.CodeQuotaExceeded, api.ErrorCodeOf(err))
+
+	// Client quotas override the default and are matched case-insensitively
+	tracker.Record("ci-bot", &cache.ScanStats{Requests: 4})
+	assert.NoError(t, tracker.Check("ci-bot"))
+	tracker.Record("ci-bot", &cache.ScanStats{Requests: 6})
+	assert.ErrorContains(t, tracker.Check("CI-Bot"), "10 of 10 requests")
+
+	usage := tracker.Usage()
+	if assert.Len(t, usage, 2) {
+		assert.Equal(t, "agent", usage[0].Client)
+		assert.Equal(t, int64(500), usage[0].Bytes)
+		assert.Equal(t, time.Second, usage[0].WallTime)
+		assert.Equal(t, "ci-bot", usage[1].Client)
+		assert.Equal(t, 2, usage[1].Scans)
+		assert.Equal(t, 10, usage[1].Quota.Requests)
+	}
+}
+
+func TestQuotaTracker_PeriodResets(t *testing.T) {
+	tracker := scanner.NewQuotaTracker(20*time.Millisecond, scanner.Quota{Scans: 1}, nil)
+	tracker.Record("", nil)
+	assert.ErrorIs(t, tracker.Check(""), scanner.ErrQuotaExceeded)
+	assert.Equal(t, scanner.AnonymousClient, tracker.Usage()[0].Client)
+
+	time.Sleep(30 * time.Millisecond)
+	assert.NoError(t, tracker.Check(""))
+}
+
+func TestScannerService_ScanAccounting(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("fragile-marker"))
+	}))
+	defer srv.Close()
+
+	templateFile := filepath.Join(t.TempDir(), "paths.yaml")
+	assert.NoError(t, os.WriteFile(templateFile, []byte(fragileTemplate(3)), 0644))
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	tracker := scanner.NewQuotaTracker(time.Hour, scanner.Quota{Scans: 1}, map[string]scanner.Quota{
+		"other": {Requests: 3, Bytes: 1 << 20},
+	})
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger,
+		scanner.WithQuotas(tracker),
+	)
+
+	result, err := service.Scan(srv.Listener.Addr().String(), "", "", nil, scanner.WithTemplateSources(templateFile), scanner.WithClient("agent"))
+	assert.NoError(t, err)
+	if assert.NotNil(t, result.Stats) {
+		assert.Positive(t, result.Stats.WallTime)
+		assert.GreaterOrEqual(t, result.Stats.Requests, 3)
+		assert.Positive(t, result.Stats.BytesSent)
+		assert.Positive(t, result.Stats.BytesReceived)
+	}
+
+	usage := tracker.Usage()
+	if assert.Len(t, usage, 1) {
+		assert.Equal(t, 1, usage[0].Scans)
+		assert.Equal(t, result.Stats.Requests, usage[0].Requests)
+	}
+
+	// The cached result is returned without counting against the quota
+	_, err = service.Scan(srv.Listener.Addr().String(), "", "", nil, scanner.WithTemplateSources(templateFile), scanner.WithClient("agent"))
+	assert.NoError(t, err)
+
+	_, err = service.ThreadSafeScan(context.Background(), srv.URL, "info", "", nil, scanner.WithTemplateSources(templateFile), scanner.WithClient("agent"))
+	assert.ErrorIs(t, err, scanner.ErrQuotaExceeded)
+
+	// Other clients keep their own quota, and thread-safe scans count their
+	// requests and bytes towards it
+	result, err = service.ThreadSafeScan(context.Background(), srv.URL, "info", "", nil, scanner.WithTemplateSources(templateFile), scanner.WithClient("other"))
+	assert.NoError(t, err)
+	if assert.NotNil(t, result.Stats) {
+		assert.Positive(t, result.Stats.WallTime)
+		assert.GreaterOrEqual(t, result.Stats.Requests, 3)
+		assert.Positive(t, result.Stats.BytesSent)
+		assert.Positive(t, result.Stats.BytesReceived)
+	}
+	_, err = service.ThreadSafeScan(context.Background(), srv.URL, "low", "", nil, scanner.WithTemplateSources(templateFile), scanner.WithClient("other"))
+	assert.ErrorIs(t, err, scanner.ErrQuotaExceeded)
+	assert.ErrorContains(t, err, "requests")
+}
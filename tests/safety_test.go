@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func safetyScanner(t *testing.T, limits scanner.SafetyLimits) scanner.ScannerService {
+	templateDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(templateDir, "spill-marker.yaml"), []byte(spillTemplate), 0644))
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	return scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger,
+		scanner.WithTemplateDirs(templateDir), scanner.WithSafetyLimits(limits))
+}
+
+func TestScannerService_SafetyLimits_MaxBodyRead(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(strings.Repeat("x", 64*1024) + "spill-marker"))
+	}))
+	defer srv.Close()
+
+	result, err := safetyScanner(t, scanner.SafetyLimits{}).ThreadSafeScan(context.Background(), srv.URL, "", "", []string{"spill-marker"})
+	assert.NoError(t, err)
+	assert.Len(t, result.Findings, 5, "the marker is found when the whole body is read")
+
+	result, err = safetyScanner(t, scanner.SafetyLimits{MaxBodyRead: 1024}).ThreadSafeScan(context.Background(), srv.URL, "", "", []string{"spill-marker"})
+	assert.NoError(t, err)
+	assert.Empty(t, result.Findings, "the marker is past the body read limit")
+}
+
+func TestScannerService_SafetyLimits_MaxConnectionLifetime(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+		_, _ = w.Write([]byte("spill-marker"))
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	start := time.Now()
+	result, err := safetyScanner(t, scanner.SafetyLimits{MaxConnectionLifetime: time.Second}).ThreadSafeScan(context.Background(), srv.URL, "", "", []string{"spill-marker"})
+	assert.NoError(t, err)
+	assert.Empty(t, result.Findings)
+	assert.Less(t, time.Since(start), 25*time.Second, "requests are cut off instead of waiting for the engine's 30s timeout")
+}
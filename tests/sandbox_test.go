@@ -0,0 +1,166 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/sandbox"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const sandboxTemplate = `id: sandbox-version
+info:
+  name: Sandbox Version
+  author: test
+  severity: info
+http:
+  - method: GET
+    path:
+      - "{{BaseURL}}/version"
+    matchers:
+      - type: word
+        words:
+          - "version="
+    extractors:
+      - type: regex
+        group: 1
+        regex:
+          - 'version=([0-9.]+)'
+`
+
+func TestSandboxServer_CannedResponses(t *testing.T) {
+	srv := sandbox.NewServer([]sandbox.Response{
+		{Path: "/admin", Status: http.StatusForbidden, Body: "denied"},
+		{Method: "GET", Headers: map[string]string{"X-Test": "yes"}, Body: "ok"},
+	})
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL() + "/admin")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp, err = http.Get(srv.URL() + "/anything")
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "yes", resp.Header.Get("X-Test"))
+	assert.Equal(t, "ok", string(body))
+
+	resp, err = http.Post(srv.URL()+"/anything", "text/plain", nil)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	assert.Len(t, srv.Requests(), 3)
+}
+
+func TestSandboxRun_Match(t *testing.T) {
+	result, err := sandbox.Run(context.Background(), []byte(sandboxTemplate), sandbox.Options{
+		Responses: []sandbox.Response{{Path: "/version", Body: "app version=1.2.3"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "sandbox-version", result.TemplateID)
+	assert.True(t, result.Matched)
+	if assert.Len(t, result.Matches, 1) {
+		assert.Equal(t, []string{"1.2.3"}, result.Matches[0].ExtractedResults)
+	}
+	assert.NotEmpty(t, result.Requests)
+	assert.Empty(t, result.Trace)
+}
+
+func TestSandboxRun_NoMatch(t *testing.T) {
+	result, err := sandbox.Run(context.Background(), []byte(sandboxTemplate), sandbox.Options{})
+	assert.NoError(t, err)
+	assert.False(t, result.Matched)
+	assert.Empty(t, result.Matches)
+}
+
+func TestSandboxRun_InvalidTemplate(t *testing.T) {
+	_, err := sandbox.Run(context.Background(), []byte("not: a template"), sandbox.Options{})
+	assert.Error(t, err)
+}
+
+func TestSandboxRun_Trace(t *testing.T) {
+	template := `id: sandbox-trace
+info:
+  name: Sandbox Trace
+  author: test
+  severity: info
+http:
+  - method: GET
+    path:
+      - "{{BaseURL}}"
+    matchers-condition: and
+    matchers:
+      - type: status
+        status:
+          - 200
+      - type: word
+        name: banner
+        part: header
+        words:
+          - "nginx"
+    extractors:
+      - type: regex
+        part: body
+        regex:
+          - 'build-[0-9]+'
+`
+
+	result, err := sandbox.Run(context.Background(), []byte(template), sandbox.Options{
+		Responses: []sandbox.Response{{Headers: map[string]string{"Server": "apache"}, Body: "build-42"}},
+		Trace:     true,
+	})
+	assert.NoError(t, err)
+	assert.False(t, result.Matched)
+	if !assert.Len(t, result.Trace, 1) || !assert.Len(t, result.Trace[0].Blocks, 1) {
+		return
+	}
+
+	block := result.Trace[0].Blocks[0]
+	assert.Equal(t, "and", block.Condition)
+	assert.False(t, block.Matched)
+	if assert.Len(t, block.Matchers, 2) {
+		assert.True(t, block.Matchers[0].Matched)
+		assert.Equal(t, "status", block.Matchers[0].Type)
+		assert.False(t, block.Matchers[1].Matched)
+		assert.Equal(t, "banner", block.Matchers[1].Name)
+	}
+	if assert.Len(t, block.Extractors, 1) {
+		assert.Equal(t, []string{"build-42"}, block.Extractors[0].Values)
+	}
+}
+
+func TestSandboxRun_WaitsForScanEngines(t *testing.T) {
+	unlock := scanner.LockEngines()
+	done := make(chan error, 1)
+	go func() {
+		_, err := sandbox.Run(context.Background(), []byte(sandboxTemplate), sandbox.Options{})
+		done <- err
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("the sandbox ran while scan engines held the engine lock")
+	case <-time.After(200 * time.Millisecond):
+	}
+	unlock()
+	assert.NoError(t, <-done)
+}
+
+func TestSandboxRun_RejectsOtherProtocols(t *testing.T) {
+	for _, template := range []string{
+		"id: sandbox-dns\ninfo:\n  name: DNS\n  author: test\n  severity: info\ndns:\n  - name: \"{{FQDN}}\"\n    type: A\n    matchers:\n      - type: word\n        words:\n          - \"IN\"\n",
+		"id: sandbox-tcp\ninfo:\n  name: TCP\n  author: test\n  severity: info\ntcp:\n  - host:\n      - \"169.254.169.254:80\"\n    inputs:\n      - data: \"GET / HTTP/1.0\\r\\n\\r\\n\"\n    matchers:\n      - type: word\n        words:\n          - \"HTTP\"\n",
+	} {
+		_, err := sandbox.Run(context.Background(), []byte(template), sandbox.Options{})
+		assert.ErrorContains(t, err, "only runs HTTP templates")
+	}
+}
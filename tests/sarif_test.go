@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/report"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/model/types/severity"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSARIF(t *testing.T) {
+	results := append(triageResults(), cache.ScanResult{Target: "c.example.com", ScanTime: time.Now(), Findings: []*output.ResultEvent{
+		triageFinding("CVE-2024-0001", "RCE in Widget", severity.Critical, "https://c.example.com"),
+	}})
+
+	data, err := report.SARIF(results, "v3.3.10")
+	assert.NoError(t, err)
+
+	var decoded struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name    string `json:"name"`
+					Version string `json:"version"`
+					Rules   []struct {
+						ID                   string `json:"id"`
+						DefaultConfiguration struct {
+							Level string `json:"level"`
+						} `json:"defaultConfiguration"`
+						Properties map[string]any `json:"properties"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				Level     string `json:"level"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, "2.1.0", decoded.Version)
+	assert.Len(t, decoded.Runs, 1)
+
+	driver := decoded.Runs[0].Tool.Driver
+	assert.Equal(t, "nuclei", driver.Name)
+	assert.Equal(t, "v3.3.10", driver.Version)
+	// The critical finding on two hosts shares one rule
+	assert.Len(t, driver.Rules, 3)
+	assert.Equal(t, "CVE-2024-0001", driver.Rules[0].ID)
+	assert.Equal(t, "error", driver.Rules[0].DefaultConfiguration.Level)
+	assert.Equal(t, "9.5", driver.Rules[0].Properties["security-severity"])
+
+	levels := map[string]string{}
+	uris := []string{}
+	for _, result := range decoded.Runs[0].Results {
+		levels[result.RuleID] = result.Level
+		uris = append(uris, result.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	}
+	assert.Len(t, decoded.Runs[0].Results, 4)
+	assert.Equal(t, map[string]string{"CVE-2024-0001": "error", "weak-tls": "warning", "tech-detect": "note"}, levels)
+	assert.Contains(t, uris, "https://c.example.com")
+}
+
+func TestSARIF_NoResults(t *testing.T) {
+	data, err := report.SARIF(nil, "")
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"results": []`)
+	assert.Contains(t, string(data), `"rules": []`)
+}
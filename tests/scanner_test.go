@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/policy"
 	"nuclei-mcp/pkg/scanner"
 
 	"github.com/projectdiscovery/nuclei/v3/pkg/output"
@@ -147,3 +148,154 @@ func TestScannerService_BasicScan_CacheMiss(t *testing.T) {
 	mockCache.AssertExpectations(t)
 	mockLogger.AssertExpectations(t)
 }
+
+func TestScannerService_Scan_CodeTemplatesDisabled(t *testing.T) {
+	mockCache := new(MockResultCache)
+	mockLogger := new(MockConsoleLogger)
+	service := scanner.NewScannerService(mockCache, mockLogger)
+
+	_, err := service.Scan("example.com", "info", "http", nil, scanner.WithCodeTemplates())
+	assert.Error(t, err)
+	mockCache.AssertNotCalled(t, "Get", mock.Anything)
+}
+
+func TestScannerService_Scan_CodeTemplatesCacheKey(t *testing.T) {
+	mockCache := new(MockResultCache)
+	mockLogger := new(MockConsoleLogger)
+	service := scanner.NewScannerService(mockCache, mockLogger, scanner.WithCodeTemplatesAllowed(true))
+
+	expectedResult := cache.ScanResult{
+		Target:   "code.com",
+		ScanTime: time.Now(),
+		Findings: []*output.ResultEvent{},
+	}
+	mockCache.On("Get", "code.com:info:http:code").Return(expectedResult, true).Once()
+	mockLogger.On("Log", mock.Anything, mock.Anything, mock.Anything).Return().Once()
+
+	result, err := service.Scan("code.com", "info", "http", nil, scanner.WithCodeTemplates())
+	assert.NoError(t, err)
+	assert.Equal(t, expectedResult, result)
+	mockCache.AssertExpectations(t)
+}
+
+func TestScannerService_Scan_UnsafeRequiresApproval(t *testing.T) {
+	mockCache := new(MockResultCache)
+	mockLogger := new(MockConsoleLogger)
+	service := scanner.NewScannerService(mockCache, mockLogger, scanner.WithDeniedTags([]string{"dos"}))
+
+	_, err := service.Scan("example.com", "info", "http", nil, scanner.WithUnsafeTemplates(" "))
+	assert.Error(t, err)
+	mockCache.AssertNotCalled(t, "Get", mock.Anything)
+}
+
+func TestScannerService_Scan_UnsafeCacheKey(t *testing.T) {
+	mockCache := new(MockResultCache)
+	mockLogger := new(MockConsoleLogger)
+	service := scanner.NewScannerService(mockCache, mockLogger, scanner.WithDeniedTags([]string{"dos"}))
+
+	expectedResult := cache.ScanResult{
+		Target:   "unsafe.com",
+		ScanTime: time.Now(),
+		Findings: []*output.ResultEvent{},
+	}
+	mockCache.On("Get", "unsafe.com:info:http:unsafe").Return(expectedResult, true).Once()
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+
+	result, err := service.Scan("unsafe.com", "info", "http", nil, scanner.WithUnsafeTemplates("SEC-42"))
+	assert.NoError(t, err)
+	assert.Equal(t, expectedResult, result)
+	mockCache.AssertExpectations(t)
+	mockLogger.AssertCalled(t, "Log", "Denied template tags lifted for scan, approval: %s", []interface{}{"SEC-42"})
+}
+
+func TestScannerService_Scan_EgressDenied(t *testing.T) {
+	mockCache := new(MockResultCache)
+	mockLogger := new(MockConsoleLogger)
+	egress, err := policy.NewEgressPolicy(policy.EgressOptions{DenyPrivate: true, DenyMetadata: true})
+	assert.NoError(t, err)
+	service := scanner.NewScannerService(mockCache, mockLogger, scanner.WithEgressPolicy(egress))
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+
+	_, err = service.Scan("http://169.254.169.254", "info", "http", nil)
+	assert.ErrorContains(t, err, "egress policy")
+
+	_, err = service.BasicScan("http://127.0.0.1:8080")
+	assert.ErrorContains(t, err, "egress policy")
+	mockCache.AssertNotCalled(t, "Get", mock.Anything)
+}
+
+func TestScannerService_Scan_PassiveByDefault(t *testing.T) {
+	mockCache := new(MockResultCache)
+	mockLogger := new(MockConsoleLogger)
+	service := scanner.NewScannerService(mockCache, mockLogger, scanner.WithPassiveByDefault(true), scanner.WithPassiveRateLimit(2))
+
+	expectedResult := cache.ScanResult{Target: "passive.com", ScanTime: time.Now(), Findings: []*output.ResultEvent{}}
+	mockCache.On("Get", "passive.com:info:http:passive").Return(expectedResult, true).Once()
+	mockCache.On("Get", "passive.com:info:http").Return(expectedResult, true).Once()
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+
+	_, err := service.Scan("passive.com", "info", "http", nil)
+	assert.NoError(t, err)
+	mockLogger.AssertCalled(t, "Log", "Passive scan: limited to %s templates at %d requests/s", []interface{}{scanner.PassiveProtocols, 2})
+
+	_, err = service.Scan("passive.com", "info", "http", nil, scanner.WithPassive(false))
+	assert.NoError(t, err)
+	mockCache.AssertExpectations(t)
+}
+
+func TestScannerService_Scan_PassiveRejectsActiveOptions(t *testing.T) {
+	mockCache := new(MockResultCache)
+	mockLogger := new(MockConsoleLogger)
+	service := scanner.NewScannerService(mockCache, mockLogger, scanner.WithCodeTemplatesAllowed(true))
+
+	_, err := service.Scan("example.com", "info", "http", nil, scanner.WithPassive(true), scanner.WithCodeTemplates())
+	assert.ErrorContains(t, err, "passive mode")
+
+	_, err = service.Scan("example.com", "info", "http", nil, scanner.WithPassive(true), scanner.WithUnsafeTemplates("SEC-1"))
+	assert.ErrorContains(t, err, "passive mode")
+
+	_, err = service.Scan("example.com", "info", "http", nil, scanner.WithPassive(true),
+		scanner.WithExtractors(scanner.Extractor{Type: scanner.ExtractorPreset, Expression: "jwt"}))
+	assert.ErrorContains(t, err, "passive mode")
+	mockCache.AssertNotCalled(t, "Get", mock.Anything)
+}
+
+func TestScannerService_Scan_EngineTimeout(t *testing.T) {
+	mockCache := new(MockResultCache)
+	mockLogger := new(MockConsoleLogger)
+	service := scanner.NewScannerService(mockCache, mockLogger, scanner.WithEngineTimeout(time.Nanosecond))
+
+	mockCache.On("Get", mock.Anything).Return(cache.ScanResult{}, false)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+
+	_, err := service.Scan("http://127.0.0.1:1", "info", "http", nil)
+	assert.ErrorIs(t, err, scanner.ErrEngineTimeout)
+	assert.ErrorContains(t, err, "scanner.engine_timeout")
+	mockCache.AssertNotCalled(t, "Set", mock.Anything, mock.Anything)
+}
+
+// panickingLogger panics when asked to log the given format
+type panickingLogger struct {
+	format string
+}
+
+func (l panickingLogger) Log(format string, v ...interface{}) {
+	if format == l.format {
+		panic("malformed response")
+	}
+}
+
+func (l panickingLogger) Close() error {
+	return nil
+}
+
+func TestScannerService_Scan_RecoversPanic(t *testing.T) {
+	mockCache := new(MockResultCache)
+	service := scanner.NewScannerService(mockCache, panickingLogger{format: "Starting new scan for target: %s"})
+	mockCache.On("Get", mock.Anything).Return(cache.ScanResult{}, false)
+
+	_, err := service.Scan("example.com", "info", "http", nil)
+	assert.ErrorIs(t, err, scanner.ErrScanPanic)
+	assert.ErrorContains(t, err, "malformed response")
+	mockCache.AssertNotCalled(t, "Set", mock.Anything, mock.Anything)
+}
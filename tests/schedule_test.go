@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/scanner"
+	"nuclei-mcp/pkg/schedule"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestScheduler(t *testing.T) {
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+
+	var scanned []string
+	service := &MockScannerService{
+		MockThreadSafeScan: func(ctx context.Context, target string, severity string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+			scanned = append(scanned, target)
+			if target == "https://down.example.com" {
+				return cache.ScanResult{}, errors.New("connection refused")
+			}
+			return cache.ScanResult{Target: target}, nil
+		},
+	}
+	scheduler := schedule.NewScheduler(service, mockLogger, []schedule.Scan{
+		{Target: "https://daily.example.com", Every: 24 * time.Hour},
+		{Target: "https://key.example.com", WarmCache: true},
+		{Target: "https://down.example.com", Every: time.Hour},
+	}, schedule.WithCacheWarming(time.Hour, 10*time.Minute))
+
+	start := time.Now()
+	assert.Len(t, scheduler.RunDue(context.Background(), start), 3, "every scan runs on the first check")
+	assert.Empty(t, scheduler.RunDue(context.Background(), start.Add(49*time.Minute)))
+
+	// The cache-warming scan runs 10 minutes before its result expires
+	scanned = nil
+	scheduler.RunDue(context.Background(), start.Add(51*time.Minute))
+	assert.Equal(t, []string{"https://key.example.com"}, scanned)
+
+	// A failed scan waits for its next period
+	scanned = nil
+	scheduler.RunDue(context.Background(), start.Add(61*time.Minute))
+	assert.Equal(t, []string{"https://down.example.com"}, scanned)
+
+	// A margin longer than the expiry warms halfway through it
+	scheduler = schedule.NewScheduler(service, mockLogger, []schedule.Scan{{Target: "https://key.example.com", WarmCache: true}},
+		schedule.WithCacheWarming(5*time.Minute, 10*time.Minute))
+	scheduler.RunDue(context.Background(), start)
+	assert.Empty(t, scheduler.RunDue(context.Background(), start.Add(2*time.Minute)))
+	assert.Len(t, scheduler.RunDue(context.Background(), start.Add(3*time.Minute)), 1)
+
+	// Added scans are due at the next check
+	scanned = nil
+	scheduler.Add(schedule.Scan{Target: "https://restored.example.com", Every: time.Hour})
+	scheduler.RunDue(context.Background(), start.Add(4*time.Minute))
+	assert.Equal(t, []string{"https://restored.example.com"}, scanned)
+}
+
+func TestScheduler_WarmsCache(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+	templateFile := filepath.Join(t.TempDir(), "paths.yaml")
+	assert.NoError(t, os.WriteFile(templateFile, []byte(fragileTemplate(1)), 0644))
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger)
+
+	scheduler := schedule.NewScheduler(service, mockLogger, []schedule.Scan{{
+		Target:    srv.URL,
+		Severity:  "info",
+		Options:   []scanner.ScanOption{scanner.WithTemplateSources(templateFile)},
+		WarmCache: true,
+	}}, schedule.WithCacheWarming(time.Hour, 0))
+	assert.Len(t, scheduler.RunDue(context.Background(), time.Now()), 1)
+	warmed := service.GetAll()
+	if assert.Len(t, warmed, 1) {
+		// An interactive scan with the same arguments hits the warmed result
+		result, err := service.ThreadSafeScan(context.Background(), srv.URL, "info", "", nil, scanner.WithTemplateSources(templateFile))
+		assert.NoError(t, err)
+		assert.Equal(t, warmed[0].ScanTime, result.ScanTime)
+	}
+}
@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/policy"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSelfTargetGuard_Check(t *testing.T) {
+	guard := policy.NewSelfTargetGuardFor(netip.MustParseAddr("192.0.2.10"))
+	ctx := context.Background()
+
+	for _, target := range []string{"http://127.0.0.1:8080", "localhost", "https://192.0.2.10", "http://[::1]/", "0.0.0.0"} {
+		err := guard.Check(ctx, target, policy.Override{})
+		assert.True(t, errors.Is(err, policy.ErrDenied), "%s should be refused", target)
+	}
+	assert.NoError(t, guard.Check(ctx, "https://198.51.100.7", policy.Override{}))
+	assert.NoError(t, guard.Check(ctx, "http://127.0.0.1", policy.Override{Allow: true, Approval: "SEC-7"}))
+	assert.Error(t, guard.Check(ctx, "http://127.0.0.1", policy.Override{Allow: true}), "an override needs an approval")
+
+	var disabled *policy.SelfTargetGuard
+	assert.NoError(t, disabled.Check(ctx, "http://127.0.0.1", policy.Override{}))
+}
+
+func TestScannerService_Scan_SelfTarget(t *testing.T) {
+	mockCache := new(MockResultCache)
+	mockLogger := new(MockConsoleLogger)
+	service := scanner.NewScannerService(mockCache, mockLogger,
+		scanner.WithSelfTargetGuard(policy.NewSelfTargetGuardFor()))
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+
+	_, err := service.Scan("http://127.0.0.1:8080", "info", "http", nil)
+	assert.True(t, errors.Is(err, policy.ErrDenied))
+	_, err = service.BasicScan("http://localhost")
+	assert.True(t, errors.Is(err, policy.ErrDenied))
+	_, err = service.Scan("http://127.0.0.1:8080", "info", "http", nil, scanner.WithSelfTargetAllowed(""))
+	assert.ErrorContains(t, err, "requires an approval")
+	mockCache.AssertNotCalled(t, "Get", mock.Anything)
+
+	expectedResult := cache.ScanResult{Target: "http://127.0.0.1:8080", ScanTime: time.Now(), Findings: []*output.ResultEvent{}}
+	mockCache.On("Get", "http://127.0.0.1:8080:info:http").Return(expectedResult, true).Once()
+	result, err := service.Scan("http://127.0.0.1:8080", "info", "http", nil, scanner.WithSelfTargetAllowed("SEC-7"))
+	assert.NoError(t, err)
+	assert.Equal(t, expectedResult, result)
+	mockLogger.AssertCalled(t, "Log", "Self-target guard lifted for scan of %s, approval: %s", []interface{}{"http://127.0.0.1:8080", "SEC-7"})
+}
@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/policy"
+	"nuclei-mcp/pkg/scanner"
+	"nuclei-mcp/pkg/selftest"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSelfTest_Passes(t *testing.T) {
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger,
+		scanner.WithPassiveByDefault(true))
+
+	result, err := api.HandleSelfTest(context.Background(), mcp.CallToolRequest{}, service)
+	assert.NoError(t, err)
+
+	var report selftest.Result
+	assert.NoError(t, json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &report))
+	assert.True(t, report.Passed, "%+v", report.Checks)
+
+	names := []string{}
+	for _, check := range report.Checks {
+		names = append(names, check.Name)
+		assert.True(t, check.Passed, "%s: %s", check.Name, check.Detail)
+	}
+	assert.Equal(t, []string{
+		"scan",
+		"template selftest-status",
+		"template selftest-banner",
+		"template selftest-git-config",
+		"template selftest-admin-panel",
+		"requests",
+		"cache",
+	}, names)
+	assert.Equal(t, `matched and extracted "1.0"`, report.Checks[2].Detail)
+}
+
+func TestSelfTest_EgressDenied(t *testing.T) {
+	egress, err := policy.NewEgressPolicy(policy.EgressOptions{DenyPrivate: true})
+	assert.NoError(t, err)
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	service := scanner.NewScannerService(new(MockResultCache), mockLogger, scanner.WithEgressPolicy(egress))
+
+	report, err := selftest.Run(context.Background(), service)
+	assert.NoError(t, err)
+	assert.False(t, report.Passed)
+	if assert.Len(t, report.Checks, 1) {
+		assert.Equal(t, "scan", report.Checks[0].Name)
+		assert.Contains(t, report.Checks[0].Detail, "policy.egress.deny_private")
+	}
+}
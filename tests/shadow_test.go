@@ -0,0 +1,59 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const shadowTemplate = `id: shadow-marker
+info:
+  name: Shadow Marker
+  author: nuclei-mcp
+  severity: info
+http:
+  - method: GET
+    path:
+      - "{{BaseURL}}/%s"
+    matchers:
+      - type: word
+        words:
+          - shadow-marker
+`
+
+func TestScannerService_ShadowedTemplates(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("shadow-marker"))
+	}))
+	defer srv.Close()
+
+	high, low := t.TempDir(), t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(high, "shadow.yaml"), []byte(fmt.Sprintf(shadowTemplate, "high")), 0644))
+	shadowed := filepath.Join(low, "shadow.yaml")
+	assert.NoError(t, os.WriteFile(shadowed, []byte(fmt.Sprintf(shadowTemplate, "low")), 0644))
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger,
+		scanner.WithTemplateDirs(high, low),
+		scanner.WithShadowedTemplates(func() []string { return []string{shadowed} }),
+	)
+
+	result, err := service.ThreadSafeScan(context.Background(), srv.URL, "", "", []string{"shadow-marker"})
+	assert.NoError(t, err)
+	if assert.Len(t, result.Findings, 1) {
+		assert.Contains(t, result.Findings[0].Matched, "/high")
+	}
+}
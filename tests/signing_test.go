@@ -0,0 +1,168 @@
+package tests
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/templates"
+
+	"aead.dev/minisign"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/projectdiscovery/nuclei/v3/pkg/templates/signer"
+	"github.com/stretchr/testify/assert"
+)
+
+const signedTemplate = "id: signed-check\ninfo:\n  name: Signed Check\n  severity: info\n"
+
+func newMinisignVerifier(t *testing.T, enforce bool) (*templates.Verifier, minisign.PrivateKey) {
+	publicKey, privateKey, err := minisign.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	text, err := publicKey.MarshalText()
+	assert.NoError(t, err)
+
+	verifier, err := templates.NewVerifier(templates.VerifyMinisign, string(text), enforce)
+	assert.NoError(t, err)
+	return verifier, privateKey
+}
+
+func TestVerifier_Minisign(t *testing.T) {
+	verifier, privateKey := newMinisignVerifier(t, true)
+	signature := minisign.Sign(privateKey, []byte(signedTemplate))
+
+	assert.NoError(t, verifier.Verify([]byte(signedTemplate), signature))
+	assert.Error(t, verifier.Verify([]byte(signedTemplate+"  tags: changed\n"), signature))
+	assert.Error(t, verifier.Verify([]byte(signedTemplate), nil))
+}
+
+func TestVerifier_MinisignKeyFile(t *testing.T) {
+	publicKey, _, err := minisign.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	text, err := publicKey.MarshalText()
+	assert.NoError(t, err)
+
+	keyPath := filepath.Join(t.TempDir(), "templates.pub")
+	assert.NoError(t, os.WriteFile(keyPath, append([]byte("untrusted comment: minisign public key\n"), text...), 0644))
+
+	_, err = templates.NewVerifier(templates.VerifyMinisign, keyPath, true)
+	assert.NoError(t, err)
+
+	_, err = templates.NewVerifier(templates.VerifyMinisign, "", true)
+	assert.Error(t, err)
+	_, err = templates.NewVerifier("gpg", "", true)
+	assert.Error(t, err)
+}
+
+func TestVerifier_NucleiSignature(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	certDER, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "nuclei-mcp-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}, &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "nuclei-mcp-test"},
+	}, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	templateSigner, err := signer.NewTemplateSigner(certPEM, keyPEM)
+	assert.NoError(t, err)
+	digest, err := templateSigner.Sign([]byte(signedTemplate), plainTemplate{})
+	assert.NoError(t, err)
+	signed := signedTemplate + digest + "\n"
+
+	verifier, err := templates.NewVerifier(templates.VerifyNuclei, string(certPEM), true)
+	assert.NoError(t, err)
+	assert.NoError(t, verifier.Verify([]byte(signed), nil))
+	assert.Error(t, verifier.Verify([]byte("id: tampered\n"+signed), nil))
+	assert.Error(t, verifier.Verify([]byte(signedTemplate), nil))
+}
+
+type plainTemplate struct{}
+
+func (plainTemplate) GetFileImports() []string { return nil }
+func (plainTemplate) HasCodeProtocol() bool    { return false }
+
+func TestLoadBundle_SignatureVerification(t *testing.T) {
+	enforced, privateKey := newMinisignVerifier(t, true)
+	signature := minisign.Sign(privateKey, []byte(signedTemplate))
+
+	bundlePath := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	assert.NoError(t, os.WriteFile(bundlePath, buildReleaseTarball(t, map[string]string{
+		"signed.yaml":         signedTemplate,
+		"signed.yaml.minisig": string(signature),
+	}), 0644))
+
+	result, err := templates.LoadBundle(context.Background(), templates.Bundle{Name: "signed", Source: bundlePath}, t.TempDir(), enforced)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Templates)
+	assert.Empty(t, result.Unverified)
+
+	unsignedPath := filepath.Join(t.TempDir(), "unsigned.tar.gz")
+	assert.NoError(t, os.WriteFile(unsignedPath, buildReleaseTarball(t, map[string]string{
+		"signed.yaml":         signedTemplate,
+		"signed.yaml.minisig": string(signature),
+		"unsigned.yaml":       "id: unsigned\ninfo:\n  name: Unsigned\n",
+	}), 0644))
+
+	root := t.TempDir()
+	_, err = templates.LoadBundle(context.Background(), templates.Bundle{Name: "mixed", Source: unsignedPath}, root, enforced)
+	assert.Error(t, err)
+	_, statErr := os.Stat(filepath.Join(root, "mixed"))
+	assert.True(t, os.IsNotExist(statErr))
+
+	warnOnly, _ := newMinisignVerifier(t, false)
+	result, err = templates.LoadBundle(context.Background(), templates.Bundle{Name: "mixed", Source: unsignedPath}, root, warnOnly)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"signed.yaml", "unsigned.yaml"}, result.Unverified)
+}
+
+func TestHandleAddTemplate_SignatureVerification(t *testing.T) {
+	ctx := context.Background()
+	added := false
+	mockTemplateManager := &MockTemplateManager{
+		MockAddTemplate: func(name string, content []byte) error {
+			added = true
+			return nil
+		},
+	}
+	verifier, privateKey := newMinisignVerifier(t, true)
+
+	request := mcp.CallToolRequest{
+		Params: mcp.CallToolParams{
+			Arguments: map[string]interface{}{
+				"name":    "signed.yaml",
+				"content": signedTemplate,
+			},
+		},
+	}
+	_, err := api.HandleAddTemplate(ctx, request, mockTemplateManager, nil, verifier, nil)
+	assert.Error(t, err)
+	assert.False(t, added)
+
+	request.Params.Arguments = map[string]interface{}{
+		"name":      "signed.yaml",
+		"content":   signedTemplate,
+		"signature": string(minisign.Sign(privateKey, []byte(signedTemplate))),
+	}
+	result, err := api.HandleAddTemplate(ctx, request, mockTemplateManager, nil, verifier, nil)
+	assert.NoError(t, err)
+	assert.NotNil(t, result)
+	assert.True(t, added)
+}
@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const exposedTemplate = `id: exposed-marker
+info:
+  name: Exposed Marker
+  author: nuclei-mcp
+  severity: critical
+http:
+  - method: GET
+    path:
+      - "{{BaseURL}}/exposed"
+    matchers:
+      - type: word
+        words:
+          - exposed-marker
+`
+
+const slowTemplate = `id: slow-marker
+info:
+  name: Slow Marker
+  author: nuclei-mcp
+  severity: low
+http:
+  - method: GET
+    path:
+      - "{{BaseURL}}/slow"
+    matchers:
+      - type: word
+        words:
+          - slow-marker
+`
+
+func TestScannerService_StopAtSeverity(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(3 * time.Second):
+			}
+			_, _ = w.Write([]byte("slow-marker"))
+			return
+		}
+		_, _ = w.Write([]byte("exposed-marker"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "exposed-marker.yaml"), []byte(exposedTemplate), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "slow-marker.yaml"), []byte(slowTemplate), 0644))
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger, scanner.WithTemplateDirs(dir))
+
+	result, err := service.ThreadSafeScan(context.Background(), srv.URL, "", "", nil, scanner.WithStopAtSeverity("critical"))
+	assert.NoError(t, err)
+	assert.True(t, result.StoppedEarly)
+	if assert.NotEmpty(t, result.Findings) {
+		assert.Equal(t, "exposed-marker", result.Findings[0].TemplateID)
+	}
+
+	// A threshold no finding reaches runs every template
+	result, err = service.ThreadSafeScan(context.Background(), srv.URL, "low", "", nil, scanner.WithStopAtSeverity("high"))
+	assert.NoError(t, err)
+	assert.False(t, result.StoppedEarly)
+	if assert.Len(t, result.Findings, 1) {
+		assert.Equal(t, "slow-marker", result.Findings[0].TemplateID)
+	}
+
+	_, err = service.ThreadSafeScan(context.Background(), srv.URL, "", "", nil, scanner.WithStopAtSeverity("severe"))
+	assert.ErrorIs(t, err, scanner.ErrInvalidSeverity)
+}
+
+func TestHandleNucleiScanTool_StopOnFirstMatch(t *testing.T) {
+	service := &MockScannerService{
+		MockThreadSafeScan: func(ctx context.Context, target string, severities string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+			return cache.ScanResult{Target: target, ScanTime: time.Now(), Findings: numberedFindings(1), StoppedEarly: true}, nil
+		},
+	}
+	logger := log.New(os.Stdout, "test: ", log.LstdFlags)
+	scan := func(arguments map[string]any) (*mcp.CallToolResult, error) {
+		return api.HandleNucleiScanTool(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: arguments}}, service, logger, nil, api.DefaultScanDefaults)
+	}
+
+	result, err := scan(map[string]any{"target": "https://example.com", "stop_on_first_match": true})
+	assert.NoError(t, err)
+	assert.Contains(t, result.Content[0].(mcp.TextContent).Text, "Scan stopped at its first finding")
+
+	result, err = scan(map[string]any{"target": "https://example.com", "stop_at_severity": "critical", "format": "json"})
+	assert.NoError(t, err)
+	var response struct {
+		StoppedEarly bool `json:"stopped_early"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &response))
+	assert.True(t, response.StoppedEarly)
+}
@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/config"
+	"nuclei-mcp/pkg/scanner"
+	"nuclei-mcp/pkg/telemetry"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// recordSpans installs a tracer provider recording the spans of the test
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+	return recorder
+}
+
+// spansByName indexes the ended spans of recorder by name
+func spansByName(recorder *tracetest.SpanRecorder) map[string]sdktrace.ReadOnlySpan {
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	return spans
+}
+
+func TestTelemetry_Setup(t *testing.T) {
+	var exported atomic.Int32
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/traces" {
+			exported.Add(1)
+		}
+	}))
+	defer collector.Close()
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	shutdown, err := telemetry.Setup(context.Background(), "")
+	assert.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+
+	_, err = telemetry.Setup(context.Background(), "localhost:4318")
+	assert.ErrorContains(t, err, "invalid OTLP endpoint")
+
+	shutdown, err = telemetry.Setup(context.Background(), collector.URL)
+	assert.NoError(t, err)
+	_, span := telemetry.Tracer().Start(context.Background(), "test")
+	span.End()
+	assert.NoError(t, shutdown(context.Background()))
+	assert.Equal(t, int32(1), exported.Load())
+
+	cfg := config.Config{Logging: config.LoggingConfig{Path: "nuclei_mcp.log"}, Telemetry: config.TelemetryConfig{OTLPEndpoint: "localhost:4318"}}
+	assert.Len(t, cfg.Validate(), 1)
+}
+
+func TestScannerService_ThreadSafeScan_Spans(t *testing.T) {
+	recorder := recordSpans(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("spill-marker"))
+	}))
+	defer srv.Close()
+
+	templateDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(templateDir, "spill-marker.yaml"), []byte(spillTemplate), 0644))
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger,
+		scanner.WithTemplateDirs(templateDir))
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+	_, err := service.ThreadSafeScan(ctx, srv.URL, "", "", []string{"spill-marker"})
+	parent.End()
+	assert.NoError(t, err)
+
+	spans := spansByName(recorder)
+	for _, name := range []string{"scan", "engine.create", "scan.execute"} {
+		assert.Contains(t, spans, name)
+	}
+	assert.Equal(t, parent.SpanContext().SpanID(), spans["scan"].Parent().SpanID())
+	assert.Equal(t, spans["scan"].SpanContext().SpanID(), spans["engine.create"].Parent().SpanID())
+	assert.Equal(t, spans["scan"].SpanContext().SpanID(), spans["scan.execute"].Parent().SpanID())
+}
+
+func TestNucleiMCPServer_ToolSpans(t *testing.T) {
+	recorder := recordSpans(t)
+	mockScanner := &MockScannerService{
+		MockThreadSafeScan: func(ctx context.Context, target string, severity string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+			return cache.ScanResult{Target: target, ScanTime: time.Now()}, nil
+		},
+	}
+	mcpServer := api.NewNucleiMCPServer(mockScanner, log.New(os.Stdout, "test: ", log.LstdFlags), &MockTemplateManager{})
+
+	data, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": "tools/call",
+		"params": map[string]any{"name": "nuclei_scan", "arguments": map[string]any{"target": "https://app.example.com"}}})
+	assert.NoError(t, err)
+	mcpServer.HandleMessage(context.Background(), data)
+
+	spans := spansByName(recorder)
+	assert.Contains(t, spans, "tool nuclei_scan")
+	assert.Contains(t, spans, "format_results")
+	assert.Equal(t, spans["tool nuclei_scan"].SpanContext().SpanID(), spans["format_results"].Parent().SpanID())
+}
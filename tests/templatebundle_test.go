@@ -0,0 +1,168 @@
+package tests
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/policy"
+	"nuclei-mcp/pkg/templates"
+	"nuclei-mcp/pkg/workspace"
+
+	"aead.dev/minisign"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+const intrusiveTemplate = "id: intrusive-check\ninfo:\n  name: Intrusive Check\n  severity: high\n  tags: intrusive\n"
+
+func newBundleWorkspace(t *testing.T, exportDir string) (*workspace.Workspace, templates.TemplateManager, string) {
+	dir := t.TempDir()
+	tm, err := templates.NewTemplateManager(dir)
+	assert.NoError(t, err)
+	logger := log.New(os.Stdout, "test: ", log.LstdFlags)
+	return workspace.NewWorkspace(cache.NewResultCache(time.Minute, logger), tm, workspace.WithExportDir(exportDir)), tm, dir
+}
+
+func TestWorkspace_ExportImportTemplates(t *testing.T) {
+	verifier, privateKey := newMinisignVerifier(t, true)
+	exportDir := t.TempDir()
+	src, srcTemplates, srcDir := newBundleWorkspace(t, exportDir)
+
+	// add_template keeps the verified signature next to the template
+	_, err := api.HandleAddTemplate(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: map[string]any{
+		"name":      "signed.yaml",
+		"content":   signedTemplate,
+		"signature": string(minisign.Sign(privateKey, []byte(signedTemplate))),
+	}}}, srcTemplates, nil, verifier, nil)
+	assert.NoError(t, err)
+	assert.FileExists(t, filepath.Join(srcDir, "signed.yaml"+templates.MinisignExtension))
+	assert.NoError(t, srcTemplates.AddTemplate("acme/login.yaml", []byte(workingTemplate)))
+	names, err := srcTemplates.ListTemplates()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"acme/login.yaml", "signed.yaml"}, names)
+
+	bundlePath := "team.tar.gz"
+	manifest, err := src.ExportTemplates(bundlePath, []string{"signed.yaml"}, "")
+	assert.NoError(t, err)
+	if assert.Len(t, manifest.Templates, 1) {
+		assert.Equal(t, "signed-check", manifest.Templates[0].ID)
+		assert.True(t, manifest.Templates[0].Signed)
+	}
+	_, err = src.ExportTemplates(bundlePath, []string{"missing.yaml"}, "")
+	assert.ErrorContains(t, err, "unknown templates: missing.yaml")
+
+	dst, dstTemplates, dstDir := newBundleWorkspace(t, exportDir)
+	result, err := dst.ImportTemplates(bundlePath, workspace.ImportOptions{Namespace: "team-a", Verifier: verifier})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"team-a/signed.yaml"}, result.Imported)
+	assert.Empty(t, result.Unverified)
+	assert.FileExists(t, filepath.Join(dstDir, "team-a", "signed.yaml"+templates.MinisignExtension))
+	assert.NoError(t, verifier.VerifyFile(filepath.Join(dstDir, "team-a", "signed.yaml")))
+
+	result, err = dst.ImportTemplates(bundlePath, workspace.ImportOptions{Namespace: "team-a"})
+	assert.NoError(t, err)
+	assert.Empty(t, result.Imported)
+	assert.Equal(t, []string{"team-a/signed.yaml"}, result.Skipped)
+
+	// Without names, every custom template is exported; the unsigned one
+	// fails enforced verification
+	manifest, err = src.ExportTemplates(bundlePath, nil, "")
+	assert.NoError(t, err)
+	assert.Len(t, manifest.Templates, 2)
+	_, err = dst.ImportTemplates(bundlePath, workspace.ImportOptions{Overwrite: true, Verifier: verifier})
+	assert.ErrorContains(t, err, "acme/login.yaml")
+	_, err = dstTemplates.GetTemplate("acme/login.yaml")
+	assert.Error(t, err)
+
+	// Exported bundles also load as offline template bundles
+	loaded, err := templates.LoadBundle(context.Background(), templates.Bundle{Name: "team", Source: filepath.Join(exportDir, bundlePath)}, t.TempDir(), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, loaded.Templates)
+	assert.Empty(t, loaded.Invalid)
+}
+
+func TestWorkspace_ImportTemplatesRejectsModifiedBundle(t *testing.T) {
+	exportDir := t.TempDir()
+	src, srcTemplates, _ := newBundleWorkspace(t, exportDir)
+	assert.NoError(t, srcTemplates.AddTemplate("login.yaml", []byte(workingTemplate)))
+	_, err := src.ExportTemplates("bundle.tar.gz", nil, "")
+	assert.NoError(t, err)
+
+	// Rewrite the bundle with the template changed but the manifest kept
+	bundlePath := filepath.Join(exportDir, "bundle.tar.gz")
+	in, err := os.Open(bundlePath)
+	assert.NoError(t, err)
+	gz, err := gzip.NewReader(in)
+	assert.NoError(t, err)
+	entries := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for header, err := tr.Next(); err == nil; header, err = tr.Next() {
+		entries[header.Name], _ = io.ReadAll(tr)
+	}
+	in.Close()
+	var manifest workspace.BundleManifest
+	assert.NoError(t, json.Unmarshal(entries[workspace.BundleManifestEntry], &manifest))
+	entries["login.yaml"] = []byte(intrusiveTemplate)
+
+	out, err := os.Create(bundlePath)
+	assert.NoError(t, err)
+	gzw := gzip.NewWriter(out)
+	tw := tar.NewWriter(gzw)
+	for name, content := range entries {
+		assert.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}))
+		_, _ = tw.Write(content)
+	}
+	assert.NoError(t, tw.Close())
+	assert.NoError(t, gzw.Close())
+	assert.NoError(t, out.Close())
+
+	dst, _, _ := newBundleWorkspace(t, exportDir)
+	_, err = dst.ImportTemplates("bundle.tar.gz", workspace.ImportOptions{})
+	assert.ErrorContains(t, err, "does not match its hash")
+}
+
+func TestHandleImportTemplates_Policy(t *testing.T) {
+	exportDir := t.TempDir()
+	src, srcTemplates, _ := newBundleWorkspace(t, exportDir)
+	assert.NoError(t, srcTemplates.AddTemplate("intrusive.yaml", []byte(intrusiveTemplate)))
+	call := func(handler func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error), arguments map[string]any) (string, error) {
+		result, err := handler(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: arguments}})
+		if err != nil {
+			return "", err
+		}
+		return result.Content[0].(mcp.TextContent).Text, nil
+	}
+
+	text, err := call(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return api.HandleExportTemplates(ctx, request, src)
+	}, map[string]any{"path": "bundle.tar.gz", "query": "intrusive"})
+	assert.NoError(t, err)
+	assert.Contains(t, text, "Exported 1 templates (0 with detached signatures)")
+
+	dst, dstTemplates, _ := newBundleWorkspace(t, exportDir)
+	importTemplates := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return api.HandleImportTemplates(ctx, request, dst, policy.NewTemplatePolicy([]string{"intrusive"}), nil)
+	}
+	_, err = call(importTemplates, map[string]any{"path": "bundle.tar.gz"})
+	assert.ErrorIs(t, err, policy.ErrDenied)
+
+	text, err = call(importTemplates, map[string]any{"path": "bundle.tar.gz", "allow_unsafe": true, "approval": "security lead, shared by team B"})
+	assert.NoError(t, err)
+	assert.Contains(t, text, `"imported":["intrusive.yaml"]`)
+	content, err := dstTemplates.GetTemplate("intrusive.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, intrusiveTemplate, string(content))
+
+	_, err = call(importTemplates, map[string]any{"path": "bundle.tar.gz", "namespace": "../escape"})
+	assert.ErrorContains(t, err, "invalid namespace")
+}
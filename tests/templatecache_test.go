@@ -0,0 +1,152 @@
+package tests
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const templateCacheMessage = "Templates changed on disk, template cache cleared"
+
+func TestScannerService_Scan_TemplateCache(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "preload-marker.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(preloadTemplate), 0644))
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger,
+		scanner.WithTemplateDirs(dir))
+
+	_, err := service.Scan(srv.URL+"/a", "", "", []string{"preload-marker"})
+	assert.NoError(t, err)
+	_, err = service.Scan(srv.URL+"/b", "", "", []string{"preload-marker"})
+	assert.NoError(t, err)
+	mockLogger.AssertNotCalled(t, "Log", templateCacheMessage, []interface{}(nil))
+
+	// Touching a template invalidates the cache
+	modified := time.Now().Add(time.Minute)
+	assert.NoError(t, os.Chtimes(path, modified, modified))
+	_, err = service.Scan(srv.URL+"/c", "", "", []string{"preload-marker"})
+	assert.NoError(t, err)
+	mockLogger.AssertCalled(t, "Log", templateCacheMessage, []interface{}(nil))
+}
+
+func TestScannerService_Scan_TemplateCacheDisabled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "preload-marker.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(preloadTemplate), 0644))
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger,
+		scanner.WithTemplateDirs(dir), scanner.WithTemplateCache(false))
+
+	_, err := service.Scan(srv.URL+"/a", "", "", []string{"preload-marker"})
+	assert.NoError(t, err)
+	modified := time.Now().Add(time.Minute)
+	assert.NoError(t, os.Chtimes(path, modified, modified))
+	_, err = service.Scan(srv.URL+"/b", "", "", []string{"preload-marker"})
+	assert.NoError(t, err)
+	mockLogger.AssertNotCalled(t, "Log", templateCacheMessage, []interface{}(nil))
+}
+
+const indexedTemplate = `id: indexed-marker
+info:
+  name: Indexed Marker
+  author: nuclei-mcp
+  severity: high
+  tags: indexed
+http:
+  - method: GET
+    path:
+      - "{{BaseURL}}"
+    matchers:
+      - type: word
+        words:
+          - preload-marker
+`
+
+func TestScannerService_ThreadSafeScan_TemplateIndex(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("preload-marker"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "preload-marker.yaml")
+	assert.NoError(t, os.WriteFile(marker, []byte(preloadTemplate), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "indexed-marker.yaml"), []byte(indexedTemplate), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "broken.yaml"), []byte("id: [broken"), 0644))
+	indexFile := filepath.Join(t.TempDir(), "template-index.json")
+
+	newService := func() (scanner.ScannerService, *MockConsoleLogger) {
+		mockLogger := new(MockConsoleLogger)
+		mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+		return scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger,
+			scanner.WithTemplateDirs(dir), scanner.WithTemplateIndex(scanner.NewTemplateIndex(indexFile))), mockLogger
+	}
+	indexed := func(logger *MockConsoleLogger) bool {
+		for _, call := range logger.Calls {
+			if call.Arguments.String(0) == "Indexed %d template files (%d in the template index)" {
+				return true
+			}
+		}
+		return false
+	}
+
+	service, logger := newService()
+	result, err := service.ThreadSafeScan(context.Background(), srv.URL, "high", "", nil)
+	assert.NoError(t, err)
+	if assert.Len(t, result.Findings, 1) {
+		assert.Equal(t, "indexed-marker", result.Findings[0].TemplateID)
+	}
+	result, err = service.ThreadSafeScan(context.Background(), srv.URL, "", "", []string{"preload-*"})
+	assert.NoError(t, err)
+	if assert.Len(t, result.Findings, 1) {
+		assert.Equal(t, "preload-marker", result.Findings[0].TemplateID)
+	}
+	assert.True(t, indexed(logger))
+
+	data, err := os.ReadFile(indexFile)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"id":"indexed-marker"`)
+	assert.Contains(t, string(data), `"invalid":true`)
+
+	// After a restart the index is read back instead of parsing the templates
+	service, logger = newService()
+	result, err = service.ThreadSafeScan(context.Background(), srv.URL, "", "", nil, scanner.WithTags("indexed"))
+	assert.NoError(t, err)
+	if assert.Len(t, result.Findings, 1) {
+		assert.Equal(t, "indexed-marker", result.Findings[0].TemplateID)
+	}
+	assert.False(t, indexed(logger))
+
+	// Changed templates are re-indexed
+	modified := time.Now().Add(time.Minute)
+	assert.NoError(t, os.Chtimes(marker, modified, modified))
+	_, err = service.ThreadSafeScan(context.Background(), srv.URL, "info", "", nil)
+	assert.NoError(t, err)
+	logger.AssertCalled(t, "Log", "Indexed %d template files (%d in the template index)", []interface{}{1, 3})
+}
@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/scanner"
+	"nuclei-mcp/pkg/templates"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const jsonTemplate = `{
+  "id": "json-marker",
+  "info": {
+    "name": "JSON Marker",
+    "author": "nuclei-mcp",
+    "severity": "info",
+    "tags": "json,marker",
+    "classification": {"cve-id": ["cve-2024-0001"]}
+  },
+  "http": [
+    {
+      "method": "GET",
+      "path": ["{{BaseURL}}/"],
+      "matchers": [{"type": "word", "words": ["<b>json-marker</b>"]}]
+    }
+  ]
+}`
+
+func TestConvertTemplate(t *testing.T) {
+	assert.Equal(t, templates.FormatJSON, templates.DetectFormat([]byte(jsonTemplate)))
+	assert.Equal(t, templates.FormatYAML, templates.DetectFormat([]byte("id: x\ninfo:\n  name: X\n")))
+	assert.Equal(t, templates.FormatJSON, templates.FormatOf("acme/login.json"))
+	assert.Equal(t, "", templates.FormatOf("notes.txt"))
+
+	converted, err := templates.ConvertTemplate([]byte(jsonTemplate), templates.FormatYAML)
+	assert.NoError(t, err)
+	assert.Contains(t, string(converted), "id: json-marker\ninfo:\n  name: JSON Marker\n")
+	assert.Contains(t, string(converted), "<b>json-marker</b>")
+
+	back, err := templates.ConvertTemplate(converted, templates.FormatJSON)
+	assert.NoError(t, err)
+	assert.JSONEq(t, jsonTemplate, string(back))
+	assert.Contains(t, string(back), `"<b>json-marker</b>"`)
+	assert.Less(t, strings.Index(string(back), `"id"`), strings.Index(string(back), `"info"`))
+
+	_, err = templates.ConvertTemplate([]byte(`{"info": {"name": "No ID"}}`), templates.FormatYAML)
+	assert.ErrorContains(t, err, "missing id")
+	_, err = templates.ConvertTemplate([]byte("id: x\ninfo: ["), templates.FormatJSON)
+	assert.Error(t, err)
+}
+
+func TestTemplateManager_JSONTemplates(t *testing.T) {
+	dir := t.TempDir()
+	tm, err := templates.NewTemplateManager(dir)
+	assert.NoError(t, err)
+	assert.NoError(t, tm.AddTemplate("acme/marker.json", []byte(jsonTemplate)))
+
+	catalog, err := tm.Catalog()
+	assert.NoError(t, err)
+	if assert.Len(t, catalog, 1) {
+		assert.Equal(t, templates.StatusValid, catalog[0].Status)
+		assert.Equal(t, templates.FormatJSON, catalog[0].Format)
+		assert.Equal(t, "json-marker", catalog[0].ID)
+		assert.Equal(t, []string{"json", "marker"}, catalog[0].Tags)
+	}
+
+	index := scanner.NewTemplateIndex("").Templates(dir)
+	if assert.Len(t, index, 1) {
+		assert.Equal(t, "json-marker", index[0].ID)
+		assert.Equal(t, []string{"CVE-2024-0001"}, index[0].CVEs)
+	}
+}
+
+func TestHandleAddTemplate_JSON(t *testing.T) {
+	saved := map[string]string{}
+	tm := &MockTemplateManager{MockAddTemplate: func(name string, content []byte) error {
+		saved[name] = string(content)
+		return nil
+	}}
+	add := func(arguments map[string]any) (string, error) {
+		result, err := api.HandleAddTemplate(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: arguments}}, tm, nil, nil, nil)
+		if err != nil {
+			return "", err
+		}
+		return result.Content[0].(mcp.TextContent).Text, nil
+	}
+
+	text, err := add(map[string]any{"name": "marker", "content": jsonTemplate})
+	assert.NoError(t, err)
+	assert.NotContains(t, text, "Converted")
+	assert.JSONEq(t, jsonTemplate, saved["marker.json"])
+
+	text, err = add(map[string]any{"name": "marker.yaml", "content": jsonTemplate})
+	assert.NoError(t, err)
+	assert.Contains(t, text, "Converted from JSON to YAML.")
+	assert.Contains(t, saved["marker.yaml"], "id: json-marker\n")
+
+	_, err = add(map[string]any{"name": "broken.json", "content": "id: broken\ninfo: ["})
+	assert.Error(t, err)
+	_, err = add(map[string]any{"name": "anonymous.json", "content": `{"info": {"name": "Anonymous"}}`})
+	assert.ErrorContains(t, err, "missing id")
+}
+
+func TestScannerService_JSONTemplate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<b>json-marker</b>"))
+	}))
+	defer srv.Close()
+
+	templateFile := filepath.Join(t.TempDir(), "marker.json")
+	assert.NoError(t, os.WriteFile(templateFile, []byte(jsonTemplate), 0644))
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger)
+
+	result, err := service.ThreadSafeScan(context.Background(), srv.URL, "", "", nil, scanner.WithTemplateSources(templateFile))
+	assert.NoError(t, err)
+	if assert.Len(t, result.Findings, 1) {
+		assert.Equal(t, "json-marker", result.Findings[0].TemplateID)
+	}
+}
@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/policy"
+	"nuclei-mcp/pkg/scanner"
+	"nuclei-mcp/pkg/templates"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+const workingTemplate = `id: login-page
+info:
+  name: Login Page
+  author: nuclei-mcp
+  severity: info
+http:
+  - method: GET
+    path:
+      - "{{BaseURL}}/login"
+`
+
+func TestTemplateManager_History(t *testing.T) {
+	dir := t.TempDir()
+	tm, err := templates.NewTemplateManager(dir)
+	assert.NoError(t, err)
+
+	broken := strings.Replace(workingTemplate, "/login", "/logn", 1)
+	assert.NoError(t, tm.AddTemplate("acme/login.yaml", []byte(workingTemplate)))
+	assert.NoError(t, tm.AddTemplate("acme/login.yaml", []byte(workingTemplate)))
+	assert.NoError(t, tm.AddTemplate("acme/login.yaml", []byte(broken)))
+
+	history, err := tm.History("acme/login.yaml")
+	assert.NoError(t, err)
+	if assert.Len(t, history, 2) {
+		assert.Equal(t, 1, history[0].Version)
+		assert.False(t, history[0].Current)
+		assert.True(t, history[1].Current)
+	}
+
+	// Edits made outside the template manager are kept on the next update
+	edited := strings.Replace(workingTemplate, "info", "high", 1)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "acme", "login.yaml"), []byte(edited), 0644))
+	assert.NoError(t, tm.AddTemplate("acme/login.yaml", []byte(workingTemplate)))
+	history, err = tm.History("acme/login.yaml")
+	assert.NoError(t, err)
+	assert.Len(t, history, 4)
+	content, err := tm.GetVersion("acme/login.yaml", 3)
+	assert.NoError(t, err)
+	assert.Equal(t, edited, string(content))
+
+	_, err = tm.GetVersion("acme/login.yaml", 9)
+	assert.ErrorIs(t, err, templates.ErrUnknownVersion)
+
+	// Versions are neither listed nor indexed as templates
+	names, err := tm.ListTemplates()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"acme/login.yaml"}, names)
+	assert.Len(t, scanner.NewTemplateIndex("").Templates(dir), 1)
+
+	// History is only read from within the custom templates directory
+	blue := filepath.Join(filepath.Dir(dir), "blue")
+	assert.NoError(t, os.MkdirAll(filepath.Join(blue, templates.HistoryDir, "login.yaml"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(blue, templates.HistoryDir, "login.yaml", "000001.v"), []byte("secret"), 0644))
+	escape := "../../blue/" + templates.HistoryDir + "/login.yaml"
+	_, err = tm.History(escape)
+	assert.ErrorContains(t, err, "invalid template name")
+	_, err = tm.GetVersion(escape, 1)
+	assert.ErrorContains(t, err, "invalid template name")
+	_, err = tm.History("/etc/passwd")
+	assert.ErrorContains(t, err, "invalid template name")
+}
+
+func TestHandleTemplateHistoryTools(t *testing.T) {
+	tm, err := templates.NewTemplateManager(t.TempDir())
+	assert.NoError(t, err)
+	call := func(handler func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error), arguments map[string]any) (string, error) {
+		result, err := handler(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: arguments}})
+		if err != nil {
+			return "", err
+		}
+		return result.Content[0].(mcp.TextContent).Text, nil
+	}
+	history := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return api.HandleTemplateHistory(ctx, request, tm)
+	}
+	diff := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return api.HandleDiffTemplate(ctx, request, tm)
+	}
+	rollback := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return api.HandleRollbackTemplate(ctx, request, tm, policy.NewTemplatePolicy(nil))
+	}
+
+	text, err := call(history, map[string]any{"name": "login.yaml"})
+	assert.NoError(t, err)
+	assert.Contains(t, text, "no saved versions")
+
+	assert.NoError(t, tm.AddTemplate("login.yaml", []byte(workingTemplate)))
+	text, err = call(diff, map[string]any{"name": "login.yaml"})
+	assert.NoError(t, err)
+	assert.Contains(t, text, "no earlier version")
+
+	assert.NoError(t, tm.AddTemplate("login.yaml", []byte(strings.Replace(workingTemplate, "/login", "/logn", 1))))
+	text, err = call(history, map[string]any{"name": "login.yaml"})
+	assert.NoError(t, err)
+	assert.Contains(t, text, `"version":2`)
+
+	text, err = call(diff, map[string]any{"name": "login.yaml"})
+	assert.NoError(t, err)
+	assert.Contains(t, text, "--- login.yaml@v1\n+++ login.yaml (current)\n")
+	assert.Contains(t, text, "\n-      - \"{{BaseURL}}/login\"\n+      - \"{{BaseURL}}/logn\"\n")
+
+	text, err = call(rollback, map[string]any{"name": "login.yaml", "version": float64(1)})
+	assert.NoError(t, err)
+	assert.Contains(t, text, "rolled back to version 1")
+	content, err := tm.GetTemplate("login.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, workingTemplate, string(content))
+
+	text, err = call(diff, map[string]any{"name": "login.yaml", "from": float64(1), "to": float64(3)})
+	assert.NoError(t, err)
+	assert.Contains(t, text, "are identical")
+
+	_, err = call(rollback, map[string]any{"name": "login.yaml", "version": float64(7)})
+	assert.ErrorIs(t, err, templates.ErrUnknownVersion)
+}
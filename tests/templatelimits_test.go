@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const bruteTemplate = `id: brute-marker
+info:
+  name: Brute Marker
+  author: nuclei-mcp
+  severity: medium
+http:
+  - method: GET
+    path:
+      - "{{BaseURL}}/a"
+      - "{{BaseURL}}/b"
+      - "{{BaseURL}}/c"
+      - "{{BaseURL}}/d"
+    matchers:
+      - type: word
+        words:
+          - exposed-marker
+`
+
+func TestScannerService_TemplateLimits(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			_, _ = w.Write([]byte("slow-marker"))
+			return
+		}
+		_, _ = w.Write([]byte("exposed-marker"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "exposed-marker.yaml"), []byte(exposedTemplate), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "slow-marker.yaml"), []byte(slowTemplate), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "brute-marker.yaml"), []byte(bruteTemplate), 0644))
+
+	newService := func(limits scanner.SafetyLimits) scanner.ScannerService {
+		mockLogger := new(MockConsoleLogger)
+		mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+		return scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger,
+			scanner.WithTemplateDirs(dir), scanner.WithSafetyLimits(limits))
+	}
+
+	service := newService(scanner.SafetyLimits{})
+	start := time.Now()
+	result, err := service.ThreadSafeScan(context.Background(), srv.URL, "", "", nil,
+		scanner.WithTemplateTimeout(time.Second), scanner.WithMaxTemplateRequests(2))
+	assert.NoError(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	if assert.Len(t, result.Findings, 1) {
+		assert.Equal(t, "exposed-marker", result.Findings[0].TemplateID)
+	}
+	if assert.NotNil(t, result.Stats) {
+		assert.Equal(t, []string{"slow-marker"}, result.Stats.TimedOutTemplates)
+		assert.Equal(t, []string{"brute-marker"}, result.Stats.SkippedTemplates)
+	}
+
+	// The service's safety limit caps what a scan asks for
+	service = newService(scanner.SafetyLimits{MaxTemplateRequests: 2})
+	result, err = service.ThreadSafeScan(context.Background(), srv.URL, "critical,medium", "", nil, scanner.WithMaxTemplateRequests(10))
+	assert.NoError(t, err)
+	if assert.NotNil(t, result.Stats) {
+		assert.Equal(t, []string{"brute-marker"}, result.Stats.SkippedTemplates)
+	}
+}
@@ -0,0 +1,54 @@
+package tests
+
+import (
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestScannerService_TemplateStats(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	template := func(id, severity string) string {
+		return "id: " + id + "\ninfo:\n  name: " + id + "\n  author: nuclei-mcp\n  severity: " + severity +
+			"\nhttp:\n  - method: GET\n    path:\n      - \"{{BaseURL}}/" + id + "\"\n    matchers:\n      - type: word\n        words:\n          - never-matches\n"
+	}
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "info-one.yaml"), []byte(template("info-one", "info")), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "info-two.yaml"), []byte(template("info-two", "info")), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "high-one.yaml"), []byte(template("high-one", "high")), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a template"), 0644))
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger)
+
+	result, err := service.Scan(srv.URL, "info", "", nil, scanner.WithTemplateSources(dir))
+	assert.NoError(t, err)
+	if assert.NotNil(t, result.Stats) && assert.NotNil(t, result.Stats.Templates) {
+		templates := result.Stats.Templates
+		assert.Equal(t, 3, templates.Available)
+		assert.Equal(t, 2, templates.Loaded)
+		assert.Equal(t, 2, templates.Executed)
+		assert.Equal(t, 1, templates.Excluded[scanner.ExcludedFilters], templates.Excluded)
+		assert.Empty(t, templates.Skipped)
+	}
+
+	// A filter matching nothing fails the scan, saying why
+	_, err = service.Scan(srv.URL, "critical", "", nil, scanner.WithTemplateSources(dir))
+	assert.ErrorIs(t, err, scanner.ErrNoTemplates)
+	assert.ErrorContains(t, err, "none of 3 available templates loaded (excluded: 3 filters)")
+}
@@ -0,0 +1,146 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/templates"
+	"nuclei-mcp/pkg/tenant"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouter_RoutesByToken(t *testing.T) {
+	handler := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, name+":"+tenant.FromContext(r.Context()))
+		})
+	}
+	router, err := tenant.NewRouter([]tenant.Tenant{
+		{Name: "red-team", Token: "r3d", Handler: handler("red")},
+		{Name: "blue-team", Token: "blu3", Handler: handler("blue")},
+	})
+	assert.NoError(t, err)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	get := func(token string) (int, string) {
+		req, err := http.NewRequest(http.MethodPost, srv.URL+"/mcp", nil)
+		assert.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	status, body := get("r3d")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "red:red-team", body)
+	status, body = get("blu3")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "blue:blue-team", body)
+
+	status, _ = get("")
+	assert.Equal(t, http.StatusUnauthorized, status)
+	status, _ = get("r3")
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
+
+func TestNewRouter_RejectsSharedTokens(t *testing.T) {
+	ok := http.NotFoundHandler()
+	_, err := tenant.NewRouter([]tenant.Tenant{
+		{Name: "red-team", Token: "same", Handler: ok},
+		{Name: "blue-team", Token: "same", Handler: ok},
+	})
+	assert.ErrorContains(t, err, "tenant blue-team shares its token")
+
+	_, err = tenant.NewRouter([]tenant.Tenant{{Name: "audit", Handler: ok}})
+	assert.ErrorContains(t, err, "tenant audit needs a token")
+
+	_, err = tenant.NewRouter(nil)
+	assert.Error(t, err)
+}
+
+func TestNucleiMCPServer_TenantScope(t *testing.T) {
+	scanned := false
+	mockScanner := &MockScannerService{
+		MockThreadSafeScan: func(ctx context.Context, target string, severity string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+			scanned = true
+			return cache.ScanResult{Target: target, ScanTime: time.Now()}, nil
+		},
+	}
+	mcpServer := api.NewNucleiMCPServer(mockScanner, log.New(os.Stdout, "test: ", log.LstdFlags), &MockTemplateManager{},
+		api.WithScope([]string{"https://app.example.com"}))
+
+	call := func(arguments map[string]any) string {
+		data, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": "tools/call",
+			"params": map[string]any{"name": "nuclei_scan", "arguments": arguments}})
+		assert.NoError(t, err)
+		response, err := json.Marshal(mcpServer.HandleMessage(context.Background(), data))
+		assert.NoError(t, err)
+		return string(response)
+	}
+
+	call(map[string]any{"target": "https://app.example.com/login"})
+	assert.True(t, scanned)
+
+	scanned = false
+	response := call(map[string]any{"target": "https://other.example.org", "allow_out_of_scope": true, "approval": "SOW-12"})
+	assert.Contains(t, response, "outside the scan scope")
+	assert.Contains(t, response, "SCOPE_DENIED")
+	assert.False(t, scanned, "a fixed scope cannot be overridden")
+}
+
+func TestNucleiMCPServer_TenantTemplateTraversal(t *testing.T) {
+	root := t.TempDir()
+	official := filepath.Join(root, "official")
+	assert.NoError(t, os.MkdirAll(official, 0755))
+	other := filepath.Join(root, "tenants", "blue-team")
+	assert.NoError(t, os.MkdirAll(filepath.Join(other, "templates"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(other, "exclusions.json"), []byte(`{"secret":true}`), 0600))
+
+	tm, err := templates.NewTemplateManager(filepath.Join(root, "tenants", "red-team", "templates"),
+		templates.WithSources(templates.Source{Name: "official", Dir: official}))
+	assert.NoError(t, err)
+	mcpServer := api.NewNucleiMCPServer(&MockScannerService{}, log.New(os.Stdout, "test: ", log.LstdFlags), tm,
+		api.WithScope([]string{"https://app.example.com"}))
+	call := func(tool string, arguments map[string]any) string {
+		data, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": "tools/call",
+			"params": map[string]any{"name": tool, "arguments": arguments}})
+		assert.NoError(t, err)
+		response, err := json.Marshal(mcpServer.HandleMessage(context.Background(), data))
+		assert.NoError(t, err)
+		return string(response)
+	}
+
+	for _, name := range []string{"../../blue-team/exclusions.json", "official:../tenants/blue-team/exclusions.json", filepath.Join(other, "exclusions.json")} {
+		response := call("get_template", map[string]any{"name": name})
+		assert.Contains(t, response, "invalid template name", name)
+		assert.NotContains(t, response, "secret", name)
+	}
+
+	for _, name := range []string{"../../blue-team/templates/login.yaml", "official:../login.yaml"} {
+		response := call("add_template", map[string]any{"name": name, "content": workingTemplate})
+		assert.Contains(t, response, "invalid template name", name)
+	}
+	assert.NoFileExists(t, filepath.Join(other, "templates", "login.yaml"))
+	assert.NoFileExists(t, filepath.Join(root, "login.yaml"))
+
+	// Names that stay within the workspace still work
+	assert.Contains(t, call("add_template", map[string]any{"name": "acme/../login.yaml", "content": workingTemplate}), "added successfully")
+	assert.Contains(t, call("get_template", map[string]any{"name": "login.yaml"}), "login-page")
+}
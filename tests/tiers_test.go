@@ -0,0 +1,168 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/projectdiscovery/nuclei/v3/pkg/model"
+	"github.com/projectdiscovery/nuclei/v3/pkg/model/types/severity"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+func tierFinding(templateID string, level severity.Severity) *output.ResultEvent {
+	return &output.ResultEvent{
+		TemplateID: templateID,
+		Host:       "example.com",
+		Matched:    "https://example.com",
+		Info:       model.Info{Name: templateID, SeverityHolder: severity.Holder{Severity: level}},
+	}
+}
+
+func TestSeverityTiers(t *testing.T) {
+	names := func(tiers []scanner.SeverityTier) []string {
+		var names []string
+		for _, tier := range tiers {
+			names = append(names, tier.Name+"="+strings.Join(tier.Severities, ","))
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"critical/high=critical,high", "medium/low=medium,low", "info=info,unknown"}, names(scanner.SeverityTiers("")))
+	assert.Equal(t, []string{"critical/high=high", "medium/low=low"}, names(scanner.SeverityTiers("low, HIGH")))
+	assert.Equal(t, []string{"info=info"}, names(scanner.SeverityTiers("info")))
+	assert.Equal(t, []string{"high,severe=high,severe"}, names(scanner.SeverityTiers("high,severe")))
+}
+
+func TestTieredScan(t *testing.T) {
+	var mu sync.Mutex
+	var scanned []string
+	service := &MockScannerService{
+		MockThreadSafeScan: func(ctx context.Context, target string, severities string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+			mu.Lock()
+			scanned = append(scanned, severities)
+			mu.Unlock()
+			// A template without severity matches every tier
+			findings := []*output.ResultEvent{tierFinding("no-severity", severity.Undefined)}
+			switch severities {
+			case "critical,high":
+				findings = append(findings, tierFinding("critical-one", severity.Critical))
+			case "medium,low":
+				findings = append(findings, tierFinding("medium-one", severity.Medium))
+			case "info,unknown":
+				return cache.ScanResult{}, scanner.ErrNoTemplates
+			}
+			return cache.ScanResult{Target: target, ScanTime: time.Now(), Findings: findings, Stats: &cache.ScanStats{Requests: 2}}, nil
+		},
+	}
+
+	var tiers []string
+	result, err := scanner.TieredScan(context.Background(), service, "https://example.com", "", "", nil, func(tier scanner.TierResult) {
+		tiers = append(tiers, tier.Tier.Name)
+	})
+	assert.NoError(t, err)
+	sort.Strings(scanned)
+	assert.Equal(t, []string{"critical,high", "info,unknown", "medium,low"}, scanned)
+	assert.Len(t, tiers, 3)
+
+	var ids []string
+	for _, finding := range result.Findings {
+		ids = append(ids, finding.TemplateID)
+	}
+	assert.Equal(t, []string{"no-severity", "critical-one", "medium-one"}, ids)
+	assert.Equal(t, 4, result.Stats.Requests)
+
+	// A failing tier fails the scan; no templates in any tier is reported
+	service.MockThreadSafeScan = func(ctx context.Context, target string, severities string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+		if severities == "medium,low" {
+			return cache.ScanResult{}, scanner.ErrEngineTimeout
+		}
+		return cache.ScanResult{Target: target}, nil
+	}
+	_, err = scanner.TieredScan(context.Background(), service, "https://example.com", "", "", nil, nil)
+	assert.True(t, errors.Is(err, scanner.ErrEngineTimeout))
+
+	service.MockThreadSafeScan = func(ctx context.Context, target string, severities string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+		return cache.ScanResult{}, scanner.ErrNoTemplates
+	}
+	_, err = scanner.TieredScan(context.Background(), service, "https://example.com", "high", "", nil, nil)
+	assert.True(t, errors.Is(err, scanner.ErrNoTemplates))
+}
+
+func TestTieredScan_SpilledTiers(t *testing.T) {
+	// The critical/high and medium/low tiers each spill one finding
+	dir := t.TempDir()
+	spilled := map[string]*output.ResultEvent{
+		"critical,high": tierFinding("critical-spilled", severity.Critical),
+		"medium,low":    tierFinding("medium-spilled", severity.Medium),
+	}
+	service := &MockScannerService{
+		MockThreadSafeScan: func(ctx context.Context, target string, severities string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+			finding, ok := spilled[severities]
+			if !ok {
+				return cache.ScanResult{}, scanner.ErrNoTemplates
+			}
+			data, err := json.Marshal(finding)
+			if err != nil {
+				return cache.ScanResult{}, err
+			}
+			spillFile := filepath.Join(dir, strings.ReplaceAll(severities, ",", "-")+".jsonl")
+			if err := os.WriteFile(spillFile, append(data, '\n'), 0600); err != nil {
+				return cache.ScanResult{}, err
+			}
+			return cache.ScanResult{Target: target, ScanTime: time.Now(), Findings: []*output.ResultEvent{}, SpillFile: spillFile, SpilledFindings: 1}, nil
+		},
+	}
+
+	result, err := scanner.TieredScan(context.Background(), service, "https://example.com", "", "", nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.SpilledFindings)
+	assert.Len(t, result.SpillFiles, 2)
+	for _, finding := range spilled {
+		_, found, ok := cache.FindFinding([]cache.ScanResult{result}, cache.Fingerprint(finding), nil)
+		if assert.True(t, ok, finding.TemplateID) {
+			assert.Equal(t, finding.TemplateID, found.TemplateID)
+		}
+	}
+}
+
+func TestHandleNucleiScanTool_SeverityTiers(t *testing.T) {
+	var mu sync.Mutex
+	var scanned []string
+	service := &MockScannerService{
+		MockThreadSafeScan: func(ctx context.Context, target string, severities string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+			mu.Lock()
+			scanned = append(scanned, severities)
+			mu.Unlock()
+			return cache.ScanResult{Target: target, ScanTime: time.Now()}, nil
+		},
+	}
+	logger := log.New(os.Stdout, "test: ", log.LstdFlags)
+	scan := func(arguments map[string]any) error {
+		_, err := api.HandleNucleiScanTool(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: arguments}}, service, logger, nil, api.DefaultScanDefaults)
+		return err
+	}
+
+	assert.NoError(t, scan(map[string]any{"target": "https://example.com", "severity": "critical,high,medium", "strategy": "severity_tiers"}))
+	sort.Strings(scanned)
+	assert.Equal(t, []string{"critical,high", "medium"}, scanned)
+
+	err := scan(map[string]any{"target": "https://example.com", "strategy": "fastest"})
+	assert.Equal(t, api.CodeInvalidParameter, api.ErrorCodeOf(err))
+	err = scan(map[string]any{"target": "https://example.com", "strategy": "severity_tiers", "thread_safe": false})
+	assert.Equal(t, api.CodeInvalidParameter, api.ErrorCodeOf(err))
+}
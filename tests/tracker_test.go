@@ -0,0 +1,250 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/tracker"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/projectdiscovery/nuclei/v3/pkg/model/types/severity"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRetester_VerifiesRemediatedFindings(t *testing.T) {
+	fixed := triageFinding("CVE-2024-0001", "RCE in Widget", severity.Critical, "https://a.example.com")
+	present := triageFinding("exposed-panel", "Exposed Panel", severity.Medium, "https://b.example.com")
+	failing := triageFinding("weak-tls", "Weak TLS", severity.Low, "https://c.example.com")
+	unscheduled := triageFinding("tech-detect", "Tech Detect", severity.Info, "https://a.example.com")
+
+	rescanned := *present
+	rescanned.Matched = "https://b.example.com/admin"
+	service := &MockScannerService{
+		MockGetAll: func() []cache.ScanResult {
+			return []cache.ScanResult{
+				{Target: "a.example.com", ScanTime: time.Now(), Findings: []*output.ResultEvent{fixed, unscheduled}},
+				{Target: "b.example.com", ScanTime: time.Now(), Findings: []*output.ResultEvent{present}},
+				{Target: "c.example.com", ScanTime: time.Now(), Findings: []*output.ResultEvent{failing}},
+			}
+		},
+		MockThreadSafeScan: func(ctx context.Context, target string, severity string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+			switch target {
+			case "b.example.com":
+				assert.Equal(t, []string{"exposed-panel"}, templateIDs)
+				return cache.ScanResult{Target: target, Findings: []*output.ResultEvent{&rescanned}}, nil
+			case "c.example.com":
+				return cache.ScanResult{}, fmt.Errorf("connection refused")
+			}
+			return cache.ScanResult{Target: target}, nil
+		},
+	}
+
+	path := filepath.Join(t.TempDir(), "findings.json")
+	store, err := tracker.Open(path)
+	assert.NoError(t, err)
+	mark := func(finding *output.ResultEvent, retestAfter string) tracker.Record {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = map[string]any{"fingerprint": cache.FindingURI(finding), "retest_after": retestAfter, "note": "patched"}
+		result, err := api.HandleMarkRemediated(context.Background(), request, service, store, time.Hour, nil)
+		assert.NoError(t, err)
+		var record tracker.Record
+		assert.NoError(t, json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &record))
+		return record
+	}
+
+	record := mark(fixed, "0s")
+	assert.Equal(t, tracker.StatusRemediated, record.Status)
+	assert.Equal(t, tracker.RetestPending, record.Retest.Result)
+	mark(present, "0s")
+	mark(failing, "0s")
+	later := mark(unscheduled, "")
+	assert.WithinDuration(t, time.Now().Add(time.Hour), later.Retest.DueAt, time.Minute)
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	done := tracker.NewRetester(store, service, mockLogger, time.Minute).RetestDue(context.Background())
+	assert.Len(t, done, 3)
+
+	record, _ = store.Get(cache.Fingerprint(fixed))
+	assert.Equal(t, tracker.StatusRemediated, record.Status)
+	assert.Equal(t, tracker.RetestFixed, record.Retest.Result)
+	record, _ = store.Get(cache.Fingerprint(present))
+	assert.Equal(t, tracker.StatusReopened, record.Status, "the template matching the same host again reopens the finding")
+	assert.Equal(t, tracker.RetestPresent, record.Retest.Result)
+	record, _ = store.Get(cache.Fingerprint(failing))
+	assert.Equal(t, tracker.RetestFailed, record.Retest.Result)
+	assert.Contains(t, record.Retest.Error, "connection refused")
+
+	reopened, err := tracker.Open(path)
+	assert.NoError(t, err)
+	assert.Len(t, reopened.List(), 4)
+
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]any{"result": tracker.RetestPending}
+	result, err := api.HandleListRetests(context.Background(), request, store)
+	assert.NoError(t, err)
+	var pending []tracker.Record
+	assert.NoError(t, json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &pending))
+	assert.Len(t, pending, 1)
+	assert.Equal(t, "tech-detect", pending[0].TemplateID)
+}
+
+func TestHandleMarkRemediated_UnknownFinding(t *testing.T) {
+	store, err := tracker.Open(filepath.Join(t.TempDir(), "findings.json"))
+	assert.NoError(t, err)
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]any{"fingerprint": "0000000000000000"}
+	_, err = api.HandleMarkRemediated(context.Background(), request, &MockScannerService{}, store, time.Hour, nil)
+	assert.ErrorContains(t, err, "not found")
+
+	request.Params.Arguments = map[string]any{"fingerprint": "0000000000000000", "retest_after": "soon"}
+	_, err = api.HandleMarkRemediated(context.Background(), request, &MockScannerService{}, store, time.Hour, nil)
+	assert.ErrorContains(t, err, "invalid retest_after")
+}
+
+func TestFindingLifecycle(t *testing.T) {
+	critical := triageFinding("CVE-2024-0001", "RCE in Widget", severity.Critical, "https://a.example.com")
+	medium := triageFinding("exposed-panel", "Exposed Panel", severity.Medium, "https://a.example.com")
+	info := triageFinding("tech-detect", "Tech Detect", severity.Info, "https://b.example.com")
+	service := &MockScannerService{MockGetAll: func() []cache.ScanResult {
+		return []cache.ScanResult{
+			{Target: "a.example.com", ScanTime: time.Now().Add(-time.Hour), Findings: []*output.ResultEvent{critical}},
+			{Target: "a.example.com", ScanTime: time.Now(), Findings: []*output.ResultEvent{critical, medium}},
+			{Target: "b.example.com", ScanTime: time.Now(), Findings: []*output.ResultEvent{info}},
+		}
+	}}
+	store, err := tracker.Open(filepath.Join(t.TempDir(), "findings.json"))
+	assert.NoError(t, err)
+
+	setStatus := func(finding *output.ResultEvent, status string) error {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = map[string]any{"fingerprint": cache.Fingerprint(finding), "status": status, "note": "JIRA-42"}
+		_, err := api.HandleSetFindingStatus(context.Background(), request, service, store, time.Hour, nil)
+		return err
+	}
+	list := func(arguments map[string]any) []api.TrackedFinding {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = arguments
+		result, err := api.HandleListFindings(context.Background(), request, service, store)
+		assert.NoError(t, err)
+		var findings []api.TrackedFinding
+		assert.NoError(t, json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &findings))
+		return findings
+	}
+
+	all := list(nil)
+	assert.Len(t, all, 3, "a finding found by several scans is listed once")
+	assert.Equal(t, "CVE-2024-0001", all[0].TemplateID)
+	assert.Equal(t, tracker.StatusNew, all[0].Status)
+
+	assert.NoError(t, setStatus(critical, "triaged"))
+	assert.NoError(t, setStatus(info, "accepted-risk"))
+	assert.NoError(t, setStatus(medium, "remediated"))
+	assert.ErrorContains(t, setStatus(medium, "wontfix"), "unknown finding status")
+
+	record, _ := store.Get(cache.Fingerprint(medium))
+	assert.Equal(t, tracker.RetestPending, record.Retest.Result, "remediated findings are retested")
+	assert.NoError(t, setStatus(medium, "triaged"))
+	record, _ = store.Get(cache.Fingerprint(medium))
+	assert.Nil(t, record.Retest, "leaving remediated cancels the pending retest")
+
+	triaged := list(map[string]any{"status": []any{"triaged"}})
+	assert.Len(t, triaged, 2)
+	assert.Equal(t, "JIRA-42", triaged[0].Note)
+	assert.Len(t, list(map[string]any{"status": []any{"accepted-risk"}, "target": "b.example.com"}), 1)
+	assert.Len(t, list(map[string]any{"severity": []any{"critical", "high"}}), 1)
+	assert.Len(t, list(map[string]any{"status": []any{"new"}}), 0)
+
+	request := mcp.ReadResourceRequest{}
+	request.Params.URI = cache.FindingURI(info)
+	contents, err := api.HandleFindingResource(context.Background(), request, service, nil, store)
+	assert.NoError(t, err)
+	var detail api.FindingDetail
+	assert.NoError(t, json.Unmarshal([]byte(contents[0].(mcp.TextResourceContents).Text), &detail))
+	assert.Equal(t, tracker.StatusAcceptedRisk, detail.Status)
+	assert.Equal(t, "JIRA-42", detail.StatusNote)
+}
+
+func TestExpiringAcceptances(t *testing.T) {
+	soon := triageFinding("exposed-panel", "Exposed Panel", severity.Medium, "https://a.example.com")
+	later := triageFinding("tech-detect", "Tech Detect", severity.Info, "https://a.example.com")
+	lapsed := triageFinding("weak-tls", "Weak TLS", severity.Low, "https://b.example.com")
+	service := &MockScannerService{MockGetAll: func() []cache.ScanResult {
+		return []cache.ScanResult{
+			{Target: "a.example.com", ScanTime: time.Now(), Findings: []*output.ResultEvent{soon, later}},
+			{Target: "b.example.com", ScanTime: time.Now(), Findings: []*output.ResultEvent{lapsed}},
+		}
+	}}
+
+	// An acceptance that expired while the server was down
+	path := filepath.Join(t.TempDir(), "findings.json")
+	expiredAt := time.Now().Add(-time.Hour).UTC()
+	data, err := json.Marshal([]tracker.Record{{
+		Fingerprint:   cache.Fingerprint(lapsed),
+		Target:        "b.example.com",
+		TemplateID:    "weak-tls",
+		Status:        tracker.StatusAcceptedRisk,
+		UpdatedAt:     expiredAt.Add(-24 * time.Hour),
+		AcceptedUntil: &expiredAt,
+	}})
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(path, data, 0644))
+	store, err := tracker.Open(path)
+	assert.NoError(t, err)
+
+	accept := func(finding *output.ResultEvent, status, until string) error {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = map[string]any{"fingerprint": cache.Fingerprint(finding), "status": status, "accepted_until": until}
+		_, err := api.HandleSetFindingStatus(context.Background(), request, service, store, time.Hour, nil)
+		return err
+	}
+	assert.NoError(t, accept(soon, "accepted-risk", "48h"))
+	assert.NoError(t, accept(later, "accepted-risk", time.Now().AddDate(1, 0, 0).Format(time.DateOnly)))
+	assert.ErrorContains(t, accept(later, "triaged", "48h"), "only applies to the accepted-risk status")
+	assert.ErrorContains(t, accept(later, "accepted-risk", "-1h"), "must be in the future")
+
+	// Reads resurface the expired acceptance before the retester sweeps it
+	record, _ := store.Get(cache.Fingerprint(lapsed))
+	assert.Equal(t, tracker.StatusReopened, record.Status)
+
+	list := func(arguments map[string]any) []api.ExpiringAcceptance {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = arguments
+		result, err := api.HandleListExpiringAcceptances(context.Background(), request, store)
+		assert.NoError(t, err)
+		var acceptances []api.ExpiringAcceptance
+		assert.NoError(t, json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &acceptances))
+		return acceptances
+	}
+	acceptances := list(nil)
+	if assert.Len(t, acceptances, 2, "acceptances beyond the window are not listed") {
+		assert.Equal(t, "weak-tls", acceptances[0].TemplateID)
+		assert.True(t, acceptances[0].Expired)
+		assert.Equal(t, "exposed-panel", acceptances[1].TemplateID)
+		assert.False(t, acceptances[1].Expired)
+	}
+	assert.Len(t, list(map[string]any{"within": "8760h", "include_expired": false}), 2)
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	expired := tracker.NewRetester(store, service, mockLogger, time.Minute).ExpireAcceptances()
+	assert.Len(t, expired, 1)
+	reloaded, err := tracker.Open(path)
+	assert.NoError(t, err)
+	record, _ = reloaded.Get(cache.Fingerprint(lapsed))
+	assert.Equal(t, tracker.StatusReopened, record.Status, "the reopened status is stored")
+	assert.NotNil(t, record.AcceptedUntil)
+
+	// Triaging the reopened finding takes it off the list
+	assert.NoError(t, accept(lapsed, "triaged", ""))
+	assert.Len(t, list(nil), 1)
+}
@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/trends"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/projectdiscovery/nuclei/v3/pkg/model/types/severity"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+func trendResults() []cache.ScanResult {
+	day1 := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	day2 := day1.Add(24 * time.Hour)
+	rce := triageFinding("CVE-2024-0001", "RCE in Widget", severity.Critical, "https://a.example.com")
+	tls := triageFinding("weak-tls", "Weak TLS", severity.Medium, "https://a.example.com")
+	banner := triageFinding("tech-detect", "Tech Detect", severity.Info, "https://a.example.com")
+	return []cache.ScanResult{
+		{Target: "a.example.com", ScanTime: day1, Findings: []*output.ResultEvent{rce, tls}},
+		// A scan with other options in the same interval reports the RCE again
+		{Target: "a.example.com", ScanTime: day1.Add(time.Hour), Findings: []*output.ResultEvent{rce, banner}},
+		{Target: "a.example.com", ScanTime: day2, Findings: []*output.ResultEvent{tls, banner}},
+		{Target: "b.example.com", ScanTime: day2, Findings: []*output.ResultEvent{
+			triageFinding("weak-tls", "Weak TLS", severity.Medium, "https://b.example.com"),
+		}},
+	}
+}
+
+func TestBuildTrends(t *testing.T) {
+	series := trends.Build(trendResults(), "", 0)
+	assert.Len(t, series, 2)
+
+	a := series[0]
+	assert.Equal(t, "a.example.com", a.Target)
+	assert.Len(t, a.Points, 2)
+	assert.Equal(t, 2, a.Points[0].Scans)
+	assert.Equal(t, 3, a.Points[0].Total)
+	assert.Equal(t, map[string]int{"critical": 1, "medium": 1, "info": 1}, a.Points[0].BySeverity)
+	assert.Equal(t, 12, a.Points[0].Score)
+	assert.Equal(t, map[string]int{"medium": 1, "info": 1}, a.Points[1].BySeverity)
+	assert.Equal(t, trends.Improving, a.Direction)
+
+	assert.Equal(t, "b.example.com", series[1].Target)
+	assert.Equal(t, trends.Insufficient, series[1].Direction)
+
+	// Hourly points keep the two scans of the first day apart
+	series = trends.Build(trendResults(), "a.example.com", time.Hour)
+	assert.Len(t, series, 1)
+	assert.Len(t, series[0].Points, 3)
+	assert.Equal(t, 10, series[0].Points[1].Score)
+}
+
+func TestHandleFindingTrends(t *testing.T) {
+	service := &MockScannerService{MockGetAll: trendResults}
+
+	result, err := api.HandleFindingTrends(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: map[string]interface{}{
+		"target": "b.example.com",
+	}}}, service)
+	assert.NoError(t, err)
+	var series []trends.Series
+	assert.NoError(t, json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &series))
+	assert.Len(t, series, 1)
+	assert.Equal(t, 1, series[0].Points[0].BySeverity["medium"])
+
+	_, err = api.HandleFindingTrends(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: map[string]interface{}{
+		"interval": "daily",
+	}}}, service)
+	assert.Error(t, err)
+
+	contents, err := api.HandleTrendsResource(context.Background(), mcp.ReadResourceRequest{}, service)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal([]byte(contents[0].(mcp.TextResourceContents).Text), &series))
+	assert.Len(t, series, 2)
+}
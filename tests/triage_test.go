@@ -0,0 +1,111 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/bridge"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/triage"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/projectdiscovery/nuclei/v3/pkg/model"
+	"github.com/projectdiscovery/nuclei/v3/pkg/model/types/severity"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSampler struct {
+	params mcp.CreateMessageParams
+	text   string
+	err    error
+}
+
+func (f *fakeSampler) CreateMessage(_ context.Context, params mcp.CreateMessageParams) (*mcp.CreateMessageResult, error) {
+	f.params = params
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &mcp.CreateMessageResult{
+		SamplingMessage: mcp.SamplingMessage{Role: mcp.RoleAssistant, Content: map[string]any{"type": "text", "text": f.text}},
+		Model:           "test-model",
+	}, nil
+}
+
+func triageFinding(templateID, name string, sev severity.Severity, host string) *output.ResultEvent {
+	return &output.ResultEvent{
+		TemplateID: templateID,
+		Host:       host,
+		Matched:    host,
+		Info:       model.Info{Name: name, SeverityHolder: severity.Holder{Severity: sev}},
+	}
+}
+
+func triageResults() []cache.ScanResult {
+	return []cache.ScanResult{
+		{Target: "a.example.com", ScanTime: time.Now(), Findings: []*output.ResultEvent{
+			triageFinding("tech-detect", "Tech Detect", severity.Info, "https://a.example.com"),
+			triageFinding("CVE-2024-0001", "RCE in Widget", severity.Critical, "https://a.example.com"),
+		}},
+		{Target: "b.example.com", ScanTime: time.Now(), Findings: []*output.ResultEvent{
+			triageFinding("weak-tls", "Weak TLS", severity.Medium, "https://b.example.com"),
+		}},
+	}
+}
+
+func TestSummarizer_Sampling(t *testing.T) {
+	sampler := &fakeSampler{text: "Executive summary\nOne critical RCE."}
+	summary, err := triage.NewSummarizer(sampler).Summarize(context.Background(), triageResults(), 2)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "sampling", summary.Source)
+	assert.Equal(t, "test-model", summary.Model)
+	assert.Equal(t, "Executive summary\nOne critical RCE.", summary.Text)
+	assert.Equal(t, 3, summary.Findings)
+	assert.Equal(t, 2, summary.Targets)
+	assert.Equal(t, map[string]int{"critical": 1, "medium": 1, "info": 1}, summary.BySeverity)
+
+	prompt := sampler.params.Messages[0].Content.(mcp.TextContent).Text
+	assert.Contains(t, prompt, "[critical] RCE in Widget (CVE-2024-0001)")
+	assert.Contains(t, prompt, "1 lower priority findings omitted")
+	assert.NotContains(t, prompt, "Tech Detect")
+	assert.Less(t, strings.Index(prompt, "critical"), strings.Index(prompt, "medium"))
+}
+
+func TestSummarizer_HeuristicFallback(t *testing.T) {
+	sampler := &fakeSampler{err: fmt.Errorf("sampling: %w", bridge.ErrUnsupported)}
+	summary, err := triage.NewSummarizer(sampler).Summarize(context.Background(), triageResults(), 0)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "heuristic", summary.Source)
+	assert.NotEmpty(t, summary.SamplingError)
+	assert.Contains(t, summary.Text, "3 findings across 2 targets (1 critical, 1 medium, 1 info)")
+	assert.Contains(t, summary.Text, "1. [critical] Address RCE in Widget on https://a.example.com")
+
+	summary, err = triage.NewSummarizer(nil).Summarize(context.Background(), nil, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, summary.Findings)
+	assert.Contains(t, summary.Text, "No findings")
+}
+
+func TestHandleSummarizeFindings(t *testing.T) {
+	mockScanner := &MockScannerService{
+		MockGetAll: func() []cache.ScanResult { return triageResults() },
+	}
+	request := mcp.CallToolRequest{
+		Params: mcp.CallToolParams{Arguments: map[string]interface{}{"target": "b.example.com"}},
+	}
+
+	result, err := api.HandleSummarizeFindings(context.Background(), request, mockScanner, triage.NewSummarizer(nil))
+	assert.NoError(t, err)
+
+	var summary triage.Summary
+	assert.NoError(t, json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &summary))
+	assert.Equal(t, 1, summary.Findings)
+	assert.Equal(t, map[string]int{"medium": 1}, summary.BySeverity)
+}
@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/policy"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsageTracker_Stats(t *testing.T) {
+	usage := api.NewUsageTracker()
+	for i := 1; i <= 100; i++ {
+		usage.Record("nuclei_scan", time.Duration(i)*time.Millisecond, mcp.NewToolResultText("ok"), nil)
+	}
+	usage.Record("get_template", time.Millisecond, nil, errors.New("template not found"))
+	usage.Record("get_template", time.Millisecond, mcp.NewToolResultError("plain failure"), nil)
+
+	stats := usage.Stats()
+	assert.Equal(t, 102, stats.Calls)
+	assert.Equal(t, 2, stats.Errors)
+	if assert.Len(t, stats.Tools, 2) {
+		scan := stats.Tools[0]
+		assert.Equal(t, "nuclei_scan", scan.Name)
+		assert.Equal(t, api.UsageLatency{Mean: 50.5, P50: 50, P95: 95, Max: 100}, scan.Latency)
+		assert.Zero(t, scan.ErrorRate)
+		assert.Nil(t, scan.ErrorCodes)
+
+		template := stats.Tools[1]
+		assert.Equal(t, 1.0, template.ErrorRate)
+		assert.Equal(t, map[api.ErrorCode]int{api.CodeOther: 2}, template.ErrorCodes)
+		assert.Equal(t, "plain failure", template.LastError)
+	}
+}
+
+func TestNucleiMCPServer_UsageResource(t *testing.T) {
+	mockScanner := &MockScannerService{
+		MockThreadSafeScan: func(ctx context.Context, target string, severity string, protocols string, templateIDs []string) (cache.ScanResult, error) {
+			if target == "denied.example.com" {
+				return cache.ScanResult{}, fmt.Errorf("%w: target is out of scope", policy.ErrDenied)
+			}
+			return cache.ScanResult{Target: target, ScanTime: time.Now()}, nil
+		},
+	}
+	mcpServer := api.NewNucleiMCPServer(mockScanner, log.New(os.Stdout, "test: ", log.LstdFlags), &MockTemplateManager{})
+
+	send := func(method string, params map[string]any) []byte {
+		data, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+		assert.NoError(t, err)
+		response, err := json.Marshal(mcpServer.HandleMessage(context.Background(), data))
+		assert.NoError(t, err)
+		return response
+	}
+	for _, arguments := range []map[string]any{
+		{"target": "a.example.com"},
+		{"target": "b.example.com"},
+		{"target": "denied.example.com"},
+		{},
+	} {
+		send("tools/call", map[string]any{"name": "nuclei_scan", "arguments": arguments})
+	}
+	send("tools/call", map[string]any{"name": "add_template", "arguments": map[string]any{}})
+
+	var decoded struct {
+		Result struct {
+			Contents []struct {
+				MIMEType string `json:"mimeType"`
+				Text     string `json:"text"`
+			} `json:"contents"`
+		} `json:"result"`
+	}
+	assert.NoError(t, json.Unmarshal(send("resources/read", map[string]any{"uri": "usage"}), &decoded))
+	if !assert.Len(t, decoded.Result.Contents, 1) {
+		return
+	}
+	assert.Equal(t, "application/json", decoded.Result.Contents[0].MIMEType)
+	var stats api.UsageStats
+	assert.NoError(t, json.Unmarshal([]byte(decoded.Result.Contents[0].Text), &stats))
+
+	assert.Equal(t, 5, stats.Calls)
+	assert.Equal(t, 3, stats.Errors)
+	if assert.Len(t, stats.Tools, 2) {
+		scan := stats.Tools[0]
+		assert.Equal(t, "nuclei_scan", scan.Name)
+		assert.Equal(t, 4, scan.Calls)
+		assert.Equal(t, 0.5, scan.ErrorRate)
+		assert.Equal(t, map[api.ErrorCode]int{api.CodeScopeDenied: 1, api.CodeTargetInvalid: 1}, scan.ErrorCodes)
+		assert.False(t, scan.LastCalled.IsZero())
+
+		assert.Equal(t, "add_template", stats.Tools[1].Name)
+		assert.Equal(t, map[api.ErrorCode]int{api.CodeOther: 1}, stats.Tools[1].ErrorCodes)
+		assert.Contains(t, stats.Tools[1].LastError, "name")
+	}
+}
@@ -0,0 +1,226 @@
+package tests
+
+import (
+	"context"
+	"encoding/base64"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/scanner"
+	"nuclei-mcp/pkg/secrets"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const apiKeyTemplate = `id: api-key-auth
+info:
+  name: API Key Accepted
+  author: nuclei-mcp
+  severity: info
+http:
+  - method: GET
+    path:
+      - "{{BaseURL}}/admin"
+    headers:
+      X-Api-Key: "{{api_key}}"
+    matchers:
+      - type: word
+        words:
+          - authenticated
+`
+
+const basicAuthTemplate = `id: basic-auth
+info:
+  name: Basic Credentials Accepted
+  author: nuclei-mcp
+  severity: info
+http:
+  - method: GET
+    path:
+      - "{{BaseURL}}/admin?password={{url_encode(password)}}"
+    headers:
+      Authorization: "Basic {{base64(concat(username, ':', password))}}"
+    matchers:
+      - type: word
+        words:
+          - authenticated
+`
+
+// variablesService records the variables of the scans it is asked for
+type variablesService struct {
+	MockScannerService
+	variables map[string]string
+}
+
+func (s *variablesService) ThreadSafeScan(_ context.Context, target string, _ string, _ string, _ []string, opts ...scanner.ScanOption) (cache.ScanResult, error) {
+	var scanOpts scanner.ScanOptions
+	for _, opt := range opts {
+		opt(&scanOpts)
+	}
+	s.variables = scanOpts.Variables
+	return cache.ScanResult{Target: target, ScanTime: time.Now()}, nil
+}
+
+func TestSecretStore(t *testing.T) {
+	t.Setenv("NUCLEI_MCP_TEST_SECRET", "from-env")
+	file := filepath.Join(t.TempDir(), "token")
+	assert.NoError(t, os.WriteFile(file, []byte("from-file\n"), 0600))
+	store := secrets.NewStore(map[string]secrets.Source{
+		"DB-Admin":  {Env: "NUCLEI_MCP_TEST_SECRET"},
+		"api-token": {Env: "NUCLEI_MCP_TEST_UNSET", File: file},
+		"missing":   {Env: "NUCLEI_MCP_TEST_UNSET"},
+	})
+
+	value, err := store.Resolve("db-admin")
+	assert.NoError(t, err)
+	assert.Equal(t, "from-env", value)
+	value, err = store.Resolve("api-token")
+	assert.NoError(t, err)
+	assert.Equal(t, "from-file", value)
+	_, err = store.Resolve("missing")
+	assert.ErrorContains(t, err, "secret missing is not set")
+
+	err = store.Check("root")
+	assert.ErrorIs(t, err, secrets.ErrUnknownSecret)
+	assert.ErrorContains(t, err, "configured secrets: api-token, db-admin, missing")
+	assert.Equal(t, api.CodeInvalidParameter, api.ErrorCodeOf(err))
+	var none *secrets.Store
+	assert.ErrorContains(t, none.Check("root"), "no secrets are configured")
+
+	name, ok := secrets.Reference("secret://DB-Admin")
+	assert.True(t, ok)
+	assert.Equal(t, "db-admin", name)
+	_, ok = secrets.Reference("hunter2")
+	assert.False(t, ok)
+}
+
+func TestParseVariables(t *testing.T) {
+	vars, err := scanner.ParseVariables([]string{"username=admin", " password = secret://db-admin "})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"username": "admin", "password": "secret://db-admin"}, vars)
+
+	_, err = scanner.ParseVariables([]string{"admin"})
+	assert.ErrorIs(t, err, scanner.ErrInvalidVariable)
+	_, err = scanner.ParseVariables([]string{"user-name=admin"})
+	assert.ErrorIs(t, err, scanner.ErrInvalidVariable)
+	assert.Equal(t, api.CodeInvalidParameter, api.ErrorCodeOf(err))
+}
+
+func TestScannerService_SecretVariables(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") == "hunter2-token" {
+			_, _ = w.Write([]byte("authenticated"))
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	templateFile := filepath.Join(t.TempDir(), "api-key.yaml")
+	assert.NoError(t, os.WriteFile(templateFile, []byte(apiKeyTemplate), 0644))
+	t.Setenv("NUCLEI_MCP_TEST_API_TOKEN", "hunter2-token")
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger,
+		scanner.WithSecrets(secrets.NewStore(map[string]secrets.Source{"api-token": {Env: "NUCLEI_MCP_TEST_API_TOKEN"}})),
+	)
+
+	result, err := service.ThreadSafeScan(context.Background(), srv.URL, "", "", nil, scanner.WithTemplateSources(templateFile))
+	assert.NoError(t, err)
+	assert.Empty(t, result.Findings)
+
+	result, err = service.ThreadSafeScan(context.Background(), srv.URL, "", "", nil, scanner.WithTemplateSources(templateFile),
+		scanner.WithVariables(map[string]string{"api_key": "secret://api-token"}))
+	assert.NoError(t, err)
+	if assert.Len(t, result.Findings, 1) {
+		assert.Contains(t, result.Findings[0].Request, "X-Api-Key: secret://api-token")
+		assert.NotContains(t, result.Findings[0].Request, "hunter2-token")
+	}
+
+	_, err = service.ThreadSafeScan(context.Background(), srv.URL, "", "", nil, scanner.WithTemplateSources(templateFile),
+		scanner.WithVariables(map[string]string{"api_key": "secret://root"}))
+	assert.ErrorIs(t, err, secrets.ErrUnknownSecret)
+}
+
+func TestScannerService_SecretVariablesEncoded(t *testing.T) {
+	const password = `p@ss w&rd"42`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, got, ok := r.BasicAuth(); ok && username == "admin" && got == password && r.URL.Query().Get("password") == password {
+			_, _ = w.Write([]byte("authenticated"))
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	templateFile := filepath.Join(t.TempDir(), "basic-auth.yaml")
+	assert.NoError(t, os.WriteFile(templateFile, []byte(basicAuthTemplate), 0644))
+	t.Setenv("NUCLEI_MCP_TEST_PASSWORD", password)
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger,
+		scanner.WithSecrets(secrets.NewStore(map[string]secrets.Source{"admin-password": {Env: "NUCLEI_MCP_TEST_PASSWORD"}})),
+	)
+	result, err := service.ThreadSafeScan(context.Background(), srv.URL, "", "", nil, scanner.WithTemplateSources(templateFile),
+		scanner.WithVariables(map[string]string{"username": "admin", "password": "secret://admin-password"}))
+	assert.NoError(t, err)
+	if assert.Len(t, result.Findings, 1) {
+		finding := result.Findings[0]
+		// The Basic credentials are encoded again with the reference, and
+		// the URL-encoded password is replaced
+		redacted := base64.StdEncoding.EncodeToString([]byte("admin:secret://admin-password"))
+		assert.Contains(t, finding.Request, "Authorization: Basic "+redacted)
+		assert.Contains(t, finding.Matched, "password=secret://admin-password")
+		for _, leaked := range []string{password, url.QueryEscape(password), base64.StdEncoding.EncodeToString([]byte("admin:" + password))} {
+			assert.NotContains(t, finding.Request, leaked)
+			assert.NotContains(t, finding.Matched, leaked)
+			assert.NotContains(t, finding.CURLCommand, leaked)
+		}
+	}
+}
+
+func TestScannerService_SecretVariablesCacheKey(t *testing.T) {
+	t.Setenv("NUCLEI_MCP_TEST_API_TOKEN", "hunter2-token")
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	cached := new(MockResultCache)
+	cached.On("Get", mock.MatchedBy(func(key string) bool {
+		return strings.Contains(key, "api_key=secret://api-token") && !strings.Contains(key, "hunter2-token")
+	})).Return(cache.ScanResult{Target: "http://192.0.2.1"}, true)
+	service := scanner.NewScannerService(cached, mockLogger,
+		scanner.WithSecrets(secrets.NewStore(map[string]secrets.Source{"api-token": {Env: "NUCLEI_MCP_TEST_API_TOKEN"}})),
+	)
+
+	result, err := service.ThreadSafeScan(context.Background(), "http://192.0.2.1", "", "", nil,
+		scanner.WithVariables(map[string]string{"api_key": "secret://api-token"}))
+	assert.NoError(t, err)
+	assert.Equal(t, "http://192.0.2.1", result.Target)
+}
+
+func TestHandleNucleiScanTool_Variables(t *testing.T) {
+	service := &variablesService{}
+	logger := log.New(os.Stdout, "test: ", log.LstdFlags)
+	scan := func(arguments map[string]any) error {
+		_, err := api.HandleNucleiScanTool(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: arguments}}, service, logger, nil, api.DefaultScanDefaults)
+		return err
+	}
+
+	assert.NoError(t, scan(map[string]any{"target": "https://example.com", "variables": map[string]any{"username": "admin", "password": "secret://db-admin"}}))
+	assert.Equal(t, map[string]string{"username": "admin", "password": "secret://db-admin"}, service.variables)
+
+	err := scan(map[string]any{"target": "https://example.com", "variables": map[string]any{"password": []any{"x"}}})
+	assert.ErrorIs(t, err, scanner.ErrInvalidVariable)
+}
@@ -0,0 +1,146 @@
+package tests
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/policy"
+	"nuclei-mcp/pkg/scanner"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const wafBlockPage = `<html><head><title>Attention Required! | Cloudflare</title></head><body>Sorry, you have been blocked</body></html>`
+
+const adminPanelTemplate = `id: admin-panel-exposed
+info:
+  name: Admin Panel Exposed
+  author: nuclei-mcp
+  severity: medium
+http:
+  - method: GET
+    path:
+      - "{{BaseURL}}/admin"
+    matchers:
+      - type: word
+        words:
+          - blocked
+`
+
+// cloudflareHandler fronts a site with a Cloudflare-like WAF blocking
+// requests with script tags and the admin panel
+func cloudflareHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("CF-Ray", "8a1b2c3d4e5f-AMS")
+	w.Header().Set("Server", "cloudflare")
+	if strings.Contains(r.URL.RawQuery, "%3Cscript") || r.URL.Path == "/admin" {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(wafBlockPage))
+		return
+	}
+	_, _ = w.Write([]byte("welcome"))
+}
+
+// wafService reports a Cloudflare WAF when the scan asks for detection
+type wafService struct {
+	MockScannerService
+}
+
+func (s *wafService) ThreadSafeScan(_ context.Context, target string, _ string, _ string, _ []string, opts ...scanner.ScanOption) (cache.ScanResult, error) {
+	var scanOpts scanner.ScanOptions
+	for _, opt := range opts {
+		opt(&scanOpts)
+	}
+	result := cache.ScanResult{Target: target, ScanTime: time.Now()}
+	if scanOpts.DetectWAF {
+		result.WAF = &cache.WAFDetection{Detected: true, Name: "Cloudflare", Evidence: []string{"header cf-ray"}, Blocking: true}
+	}
+	return result, nil
+}
+
+func TestDetectWAF(t *testing.T) {
+	waf := httptest.NewServer(http.HandlerFunc(cloudflareHandler))
+	defer waf.Close()
+
+	client := scanner.NewTargetClient(scanner.TargetClientOptions{})
+	detection, err := scanner.DetectWAF(context.Background(), client, waf.URL, false)
+	assert.NoError(t, err)
+	assert.True(t, detection.Detected)
+	assert.Equal(t, "Cloudflare", detection.Name)
+	assert.True(t, detection.Blocking)
+	assert.Contains(t, detection.Evidence, "header cf-ray")
+	assert.Contains(t, detection.Evidence, "probe request answered 403")
+
+	detection, err = scanner.DetectWAF(context.Background(), client, waf.URL, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "Cloudflare", detection.Name)
+	assert.False(t, detection.Blocking)
+
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("welcome"))
+	}))
+	defer plain.Close()
+
+	detection, err = scanner.DetectWAF(context.Background(), client, plain.URL, false)
+	assert.NoError(t, err)
+	assert.False(t, detection.Detected)
+	assert.Empty(t, detection.Name)
+
+	// Connections are checked against the egress policy after DNS
+	egress, err := policy.NewEgressPolicy(policy.EgressOptions{DenyPrivate: true})
+	assert.NoError(t, err)
+	_, err = scanner.DetectWAF(context.Background(), scanner.NewTargetClient(scanner.TargetClientOptions{Egress: egress}), waf.URL, false)
+	assert.ErrorIs(t, err, policy.ErrDenied)
+}
+
+func TestScannerService_WAFInterference(t *testing.T) {
+	waf := httptest.NewServer(http.HandlerFunc(cloudflareHandler))
+	defer waf.Close()
+
+	templateFile := filepath.Join(t.TempDir(), "admin-panel.yaml")
+	assert.NoError(t, os.WriteFile(templateFile, []byte(adminPanelTemplate), 0644))
+
+	mockLogger := new(MockConsoleLogger)
+	mockLogger.On("Log", mock.Anything, mock.Anything).Return()
+	service := scanner.NewScannerService(cache.NewResultCache(time.Hour, log.New(os.Stderr, "test: ", log.LstdFlags)), mockLogger)
+
+	result, err := service.ThreadSafeScan(context.Background(), waf.URL, "", "", nil, scanner.WithTemplateSources(templateFile))
+	assert.NoError(t, err)
+	assert.Nil(t, result.WAF)
+
+	result, err = service.ThreadSafeScan(context.Background(), waf.URL, "", "", nil, scanner.WithTemplateSources(templateFile), scanner.WithWAFDetection())
+	assert.NoError(t, err)
+	if assert.NotNil(t, result.WAF) && assert.Len(t, result.Findings, 1) {
+		assert.Equal(t, "Cloudflare", result.WAF.Name)
+		assert.Equal(t, []string{cache.FindingURI(result.Findings[0])}, result.WAF.Interfered)
+	}
+}
+
+func TestHandleNucleiScanTool_DetectWAF(t *testing.T) {
+	service := &wafService{}
+	logger := log.New(os.Stdout, "test: ", log.LstdFlags)
+	scan := func(arguments map[string]any) string {
+		result, err := api.HandleNucleiScanTool(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: arguments}}, service, logger, nil, api.DefaultScanDefaults)
+		assert.NoError(t, err)
+		return result.Content[0].(mcp.TextContent).Text
+	}
+
+	text := scan(map[string]any{"target": "https://example.com", "detect_waf": true})
+	assert.Contains(t, text, "WAF: Cloudflare detected (header cf-ray)")
+	assert.Contains(t, text, "a missing finding does not mean the target is not vulnerable")
+
+	text = scan(map[string]any{"target": "https://example.com", "detect_waf": true, "format": "json"})
+	assert.Contains(t, text, `"waf":{"detected":true,"name":"Cloudflare"`)
+
+	assert.NotContains(t, scan(map[string]any{"target": "https://example.com"}), "WAF")
+}
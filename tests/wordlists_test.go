@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/wordlists"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+const wordlistTemplate = `id: wordlist-paths
+info:
+  name: Wordlist Paths
+  author: test
+  severity: info
+http:
+  - method: GET
+    path:
+      - "{{BaseURL}}/{{path}}"
+    payloads:
+      path: wordlist:paths
+    stop-at-first-match: true
+    matchers:
+      - type: word
+        words:
+          - "admin panel"
+`
+
+func TestStore_AddListGet(t *testing.T) {
+	store := wordlists.New(filepath.Join(t.TempDir(), "wordlists"), 3)
+
+	lists, err := store.List()
+	assert.NoError(t, err)
+	assert.Empty(t, lists)
+
+	list, err := store.Add("paths.txt", []string{"admin\r\n\nlogin", "  ", `back"up`}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "paths", list.Name)
+	assert.Equal(t, 3, list.Entries)
+
+	_, err = store.Add("paths", []string{"other"}, false)
+	assert.ErrorContains(t, err, "already exists")
+	_, err = store.Add("../etc/passwd", []string{"x"}, true)
+	assert.ErrorContains(t, err, "invalid wordlist name")
+	_, err = store.Add("big", []string{"a", "b", "c", "d"}, false)
+	assert.ErrorContains(t, err, "limit of 3")
+
+	entries, err := store.Get("paths")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"admin", "login", `back"up`}, entries)
+
+	lists, err = store.List()
+	assert.NoError(t, err)
+	assert.Len(t, lists, 1)
+
+	resolved, used, err := store.Resolve([]byte("payloads:\n  path: \"wordlist:paths\"\n  user: users.txt\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"paths"}, used)
+	assert.Equal(t, "payloads:\n  path: [\"admin\",\"login\",\"back\\\"up\"]\n  user: users.txt\n", string(resolved))
+
+	_, _, err = store.Resolve([]byte("payloads:\n  path: wordlist:missing\n"))
+	assert.ErrorContains(t, err, "wordlist missing not found")
+}
+
+func TestHandleTestTemplate_Wordlist(t *testing.T) {
+	ctx := context.Background()
+	store := wordlists.New(t.TempDir(), 0)
+
+	_, err := api.HandleAddWordlist(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: map[string]any{
+		"name":    "paths",
+		"content": "login\nadmin\n",
+	}}}, store)
+	assert.NoError(t, err)
+
+	result, err := api.HandleGetWordlist(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: map[string]any{"name": "paths", "limit": float64(1)}}}, store)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"name":"paths","total":2,"entries":["login"],"truncated":true}`, result.Content[0].(mcp.TextContent).Text)
+
+	result, err = api.HandleTestTemplate(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: map[string]any{
+		"content": wordlistTemplate,
+		"responses": []any{
+			map[string]any{"path": "/admin", "body": "admin panel"},
+		},
+	}}}, &MockTemplateManager{}, store)
+	assert.NoError(t, err)
+	assert.Contains(t, result.Content[0].(mcp.TextContent).Text, `"matched":true`)
+
+	var saved []byte
+	tm := &MockTemplateManager{MockAddTemplate: func(name string, content []byte) error {
+		saved = content
+		return nil
+	}}
+	result, err = api.HandleAddTemplate(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: map[string]any{
+		"name":    "wordlist-paths.yaml",
+		"content": wordlistTemplate,
+	}}}, tm, nil, nil, store)
+	assert.NoError(t, err)
+	assert.Contains(t, result.Content[0].(mcp.TextContent).Text, "Wordlists inlined: paths.")
+	assert.Contains(t, string(saved), `path: ["login","admin"]`)
+}
@@ -0,0 +1,243 @@
+package tests
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/cache"
+	"nuclei-mcp/pkg/exclusions"
+	"nuclei-mcp/pkg/schedule"
+	"nuclei-mcp/pkg/templates"
+	"nuclei-mcp/pkg/tracker"
+	"nuclei-mcp/pkg/workspace"
+
+	"github.com/projectdiscovery/nuclei/v3/pkg/model"
+	"github.com/projectdiscovery/nuclei/v3/pkg/model/types/severity"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkspace_ExportRestore(t *testing.T) {
+	logger := log.New(os.Stdout, "test: ", log.LstdFlags)
+
+	srcCache := cache.NewResultCache(5*time.Minute, logger)
+	srcCache.Set("example.com:info:http", cache.ScanResult{
+		Target:   "example.com",
+		ScanTime: time.Now(),
+		Findings: []*output.ResultEvent{{
+			TemplateID: "tech-detect",
+			Host:       "example.com",
+			Info:       model.Info{Name: "Tech Detect", SeverityHolder: severity.Holder{Severity: severity.Info}},
+		}},
+	})
+	srcTemplates, err := templates.NewTemplateManager(t.TempDir())
+	assert.NoError(t, err)
+	assert.NoError(t, srcTemplates.AddTemplate("custom.yaml", []byte("id: custom")))
+
+	var archive bytes.Buffer
+	manifest, err := workspace.NewWorkspace(srcCache, srcTemplates).Export(&archive)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, manifest.Results)
+	assert.Equal(t, 1, manifest.Templates)
+
+	dstCache := cache.NewResultCache(5*time.Minute, logger)
+	dstTemplates, err := templates.NewTemplateManager(t.TempDir())
+	assert.NoError(t, err)
+
+	restored, err := workspace.NewWorkspace(dstCache, dstTemplates).Restore(&archive, workspace.RestoreOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, workspace.ArchiveVersion, restored.Version)
+
+	result, found := dstCache.Get("example.com:info:http")
+	assert.True(t, found)
+	assert.Equal(t, "example.com", result.Target)
+	assert.Len(t, result.Findings, 1)
+	assert.Equal(t, "tech-detect", result.Findings[0].TemplateID)
+
+	content, err := dstTemplates.GetTemplate("custom.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, "id: custom", string(content))
+}
+
+func TestWorkspace_RestoreKeepsExistingWithoutOverwrite(t *testing.T) {
+	logger := log.New(os.Stdout, "test: ", log.LstdFlags)
+
+	srcTemplates, err := templates.NewTemplateManager(t.TempDir())
+	assert.NoError(t, err)
+	assert.NoError(t, srcTemplates.AddTemplate("custom.yaml", []byte("id: from-archive")))
+
+	exportDir := t.TempDir()
+	archivePath := filepath.Join("backup", "workspace.tar.gz")
+	_, err = workspace.NewWorkspace(cache.NewResultCache(time.Minute, logger), srcTemplates, workspace.WithExportDir(exportDir)).ExportFile(archivePath)
+	assert.NoError(t, err)
+
+	dstTemplates, err := templates.NewTemplateManager(t.TempDir())
+	assert.NoError(t, err)
+	assert.NoError(t, dstTemplates.AddTemplate("custom.yaml", []byte("id: local")))
+	ws := workspace.NewWorkspace(cache.NewResultCache(time.Minute, logger), dstTemplates, workspace.WithExportDir(exportDir))
+
+	_, err = ws.RestoreFile(archivePath, workspace.RestoreOptions{})
+	assert.NoError(t, err)
+	content, _ := dstTemplates.GetTemplate("custom.yaml")
+	assert.Equal(t, "id: local", string(content))
+
+	_, err = ws.RestoreFile(archivePath, workspace.RestoreOptions{Overwrite: true})
+	assert.NoError(t, err)
+	content, _ = dstTemplates.GetTemplate("custom.yaml")
+	assert.Equal(t, "id: from-archive", string(content))
+}
+
+func TestWorkspace_ExportRestoreTriage(t *testing.T) {
+	logger := log.New(os.Stdout, "test: ", log.LstdFlags)
+	tm, err := templates.NewTemplateManager(t.TempDir())
+	assert.NoError(t, err)
+
+	srcRules, err := exclusions.Open(filepath.Join(t.TempDir(), "exclusions.json"))
+	assert.NoError(t, err)
+	_, err = srcRules.Add(exclusions.Rule{Target: "*.example.com", TemplateIDs: []string{"tech-detect"}, Reason: "noisy"})
+	assert.NoError(t, err)
+	srcFindings, err := tracker.Open(filepath.Join(t.TempDir(), "findings.json"))
+	assert.NoError(t, err)
+	until := time.Now().Add(30 * 24 * time.Hour).UTC().Truncate(time.Second)
+	_, err = srcFindings.SetStatus(tracker.Record{Fingerprint: "abc123", Target: "example.com", TemplateID: "weak-cipher", AcceptedUntil: &until}, tracker.StatusAcceptedRisk)
+	assert.NoError(t, err)
+	_, err = srcFindings.SetStatus(tracker.Record{Fingerprint: "def456", Target: "example.com", TemplateID: "xss"}, tracker.StatusTriaged)
+	assert.NoError(t, err)
+
+	var archive bytes.Buffer
+	manifest, err := workspace.NewWorkspace(cache.NewResultCache(time.Minute, logger), tm,
+		workspace.WithExclusions(srcRules), workspace.WithFindingTracker(srcFindings)).Export(&archive)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, manifest.Exclusions)
+	assert.Equal(t, 2, manifest.TrackedFindings)
+
+	dstRules, err := exclusions.Open(filepath.Join(t.TempDir(), "exclusions.json"))
+	assert.NoError(t, err)
+	dstFindingsFile := filepath.Join(t.TempDir(), "findings.json")
+	dstFindings, err := tracker.Open(dstFindingsFile)
+	assert.NoError(t, err)
+	// A finding already tracked here keeps its status without overwrite
+	_, err = dstFindings.SetStatus(tracker.Record{Fingerprint: "def456", Target: "example.com", TemplateID: "xss"}, tracker.StatusRemediated)
+	assert.NoError(t, err)
+
+	_, err = workspace.NewWorkspace(cache.NewResultCache(time.Minute, logger), tm,
+		workspace.WithExclusions(dstRules), workspace.WithFindingTracker(dstFindings)).Restore(bytes.NewReader(archive.Bytes()), workspace.RestoreOptions{})
+	assert.NoError(t, err)
+
+	if rules := dstRules.List(); assert.Len(t, rules, 1) {
+		assert.Equal(t, "*.example.com", rules[0].Target)
+		assert.Equal(t, "noisy", rules[0].Reason)
+	}
+	accepted, ok := dstFindings.Get("abc123")
+	assert.True(t, ok)
+	assert.Equal(t, tracker.StatusAcceptedRisk, accepted.Status)
+	if assert.NotNil(t, accepted.AcceptedUntil) {
+		assert.True(t, until.Equal(*accepted.AcceptedUntil))
+	}
+	assert.Equal(t, tracker.StatusRemediated, dstFindings.StatusOf("def456"))
+
+	// Restored records are saved
+	reopened, err := tracker.Open(dstFindingsFile)
+	assert.NoError(t, err)
+	assert.Len(t, reopened.List(), 2)
+}
+
+// storedSchedules backs up the scheduled scans of a schedule store
+type storedSchedules struct {
+	store      *schedule.Store
+	configured []schedule.Definition
+}
+
+func (s storedSchedules) List() []schedule.Definition {
+	return append(append([]schedule.Definition(nil), s.configured...), s.store.List()...)
+}
+
+func (s storedSchedules) Restore(definitions []schedule.Definition) (int, error) {
+	added, err := s.store.Add(definitions, s.configured)
+	return len(added), err
+}
+
+func TestWorkspace_ExportRestoreSchedules(t *testing.T) {
+	logger := log.New(os.Stdout, "test: ", log.LstdFlags)
+	tm, err := templates.NewTemplateManager(t.TempDir())
+	assert.NoError(t, err)
+
+	daily := schedule.Definition{Target: "https://app.example.com", Severity: "high", Tags: []string{"cve"}, Every: 24 * time.Hour}
+	warm := schedule.Definition{Target: "https://key.example.com", WarmCache: true, SkipUnchanged: true}
+	srcStore, err := schedule.OpenStore(filepath.Join(t.TempDir(), "schedules.json"))
+	assert.NoError(t, err)
+	_, err = srcStore.Add([]schedule.Definition{warm}, nil)
+	assert.NoError(t, err)
+
+	var archive bytes.Buffer
+	manifest, err := workspace.NewWorkspace(cache.NewResultCache(time.Minute, logger), tm,
+		workspace.WithSchedules(storedSchedules{store: srcStore, configured: []schedule.Definition{daily}})).Export(&archive)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, manifest.Schedules)
+
+	// Scans already configured here are not scheduled twice
+	dstFile := filepath.Join(t.TempDir(), "schedules.json")
+	dstStore, err := schedule.OpenStore(dstFile)
+	assert.NoError(t, err)
+	dst := workspace.NewWorkspace(cache.NewResultCache(time.Minute, logger), tm,
+		workspace.WithSchedules(storedSchedules{store: dstStore, configured: []schedule.Definition{daily}}))
+	_, err = dst.Restore(bytes.NewReader(archive.Bytes()), workspace.RestoreOptions{})
+	assert.NoError(t, err)
+	_, err = dst.Restore(bytes.NewReader(archive.Bytes()), workspace.RestoreOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []schedule.Definition{warm}, dstStore.List())
+
+	// Restored scans are saved
+	reopened, err := schedule.OpenStore(dstFile)
+	assert.NoError(t, err)
+	assert.Equal(t, []schedule.Definition{warm}, reopened.List())
+}
+
+func TestWorkspace_RestoreRejectsInvalidArchive(t *testing.T) {
+	logger := log.New(os.Stdout, "test: ", log.LstdFlags)
+	tm, err := templates.NewTemplateManager(t.TempDir())
+	assert.NoError(t, err)
+
+	ws := workspace.NewWorkspace(cache.NewResultCache(time.Minute, logger), tm)
+	_, err = ws.Restore(bytes.NewBufferString("not an archive"), workspace.RestoreOptions{})
+	assert.Error(t, err)
+}
+
+func TestWorkspace_ExportDir(t *testing.T) {
+	logger := log.New(os.Stdout, "test: ", log.LstdFlags)
+	tm, err := templates.NewTemplateManager(t.TempDir())
+	assert.NoError(t, err)
+	assert.NoError(t, tm.AddTemplate("custom.yaml", []byte("id: custom")))
+
+	exportDir := t.TempDir()
+	ws := workspace.NewWorkspace(cache.NewResultCache(time.Minute, logger), tm, workspace.WithExportDir(exportDir))
+	relative := filepath.Join("nightly", "workspace.tar.gz")
+	resolved, err := ws.Path(relative)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(exportDir, relative), resolved)
+
+	// Archives stay inside the export directory
+	outside := t.TempDir()
+	for _, archivePath := range []string{filepath.Join(outside, "workspace.tar.gz"), filepath.Join("..", filepath.Base(outside), "workspace.tar.gz"), "nightly/../../workspace.tar.gz", ""} {
+		_, err = ws.Path(archivePath)
+		assert.Error(t, err, archivePath)
+		_, err = ws.ExportFile(archivePath)
+		assert.Error(t, err, archivePath)
+		_, err = ws.RestoreFile(archivePath, workspace.RestoreOptions{})
+		assert.Error(t, err, archivePath)
+	}
+	entries, err := os.ReadDir(outside)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	_, err = ws.ExportFile(relative)
+	assert.NoError(t, err)
+	assert.FileExists(t, filepath.Join(exportDir, relative))
+	manifest, err := ws.RestoreFile(relative, workspace.RestoreOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, manifest.Templates)
+}
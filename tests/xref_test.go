@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nuclei-mcp/pkg/api"
+	"nuclei-mcp/pkg/cache"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/projectdiscovery/nuclei/v3/pkg/model/types/severity"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+func xrefTemplates(t *testing.T) string {
+	dir := t.TempDir()
+	files := map[string]string{
+		"CVE-2021-44228.yaml": "id: CVE-2021-44228\n\ninfo:\n  name: Log4Shell\n  author: nuclei-mcp\n  severity: critical\n  tags: cve,rce,log4j\n",
+		"log4j-header.yaml":   "id: log4j-header-rce\ninfo:\n  name: Log4j RCE via Header\n  author: nuclei-mcp\n  classification:\n    cve-id: cve-2021-44228\n    cwe-id: CWE-502,CWE-917\n  tags: rce\n",
+		"xss.yaml":            "id: reflected-xss\ninfo:\n  name: Reflected XSS\n  author: nuclei-mcp\n  classification:\n    cwe-id: CWE-79\n",
+	}
+	for name, content := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	return dir
+}
+
+func xrefResults() []cache.ScanResult {
+	log4shell := triageFinding("CVE-2021-44228", "Log4Shell", severity.Critical, "https://a.example.com")
+	return []cache.ScanResult{
+		{Target: "a.example.com", ScanTime: time.Now().Add(-time.Hour), Findings: []*output.ResultEvent{
+			log4shell,
+			triageFinding("reflected-xss", "Reflected XSS", severity.Medium, "https://a.example.com/search"),
+		}},
+		{Target: "a.example.com", ScanTime: time.Now(), Findings: []*output.ResultEvent{log4shell}},
+		{Target: "b.example.com", ScanTime: time.Now().Add(-2 * time.Hour), Findings: []*output.ResultEvent{
+			// Not a local template, but classified with the CVE
+			classifiedFinding("vendor-log4j", severity.Critical, "https://b.example.com", []string{"cwe-502"}, []string{"cve-2021-44228"}),
+		}},
+	}
+}
+
+func TestHandleCrossReference(t *testing.T) {
+	dir := xrefTemplates(t)
+	service := &MockScannerService{MockGetAll: xrefResults}
+	lookup := func(id string) (*api.CrossReference, error) {
+		result, err := api.HandleCrossReference(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: map[string]any{"id": id}}}, service, nil, []string{dir}, nil)
+		if err != nil {
+			return nil, err
+		}
+		var xref api.CrossReference
+		assert.NoError(t, json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &xref))
+		return &xref, nil
+	}
+
+	xref, err := lookup("cve-2021-44228")
+	assert.NoError(t, err)
+	assert.Equal(t, "CVE-2021-44228", xref.ID)
+	assert.Equal(t, "cve", xref.Kind)
+	assert.Len(t, xref.Templates, 2)
+	if assert.Len(t, xref.Findings, 2) {
+		// The latest scan of each finding, most recent first
+		assert.Equal(t, "a.example.com", xref.Findings[0].Target)
+		assert.Equal(t, "vendor-log4j", xref.Findings[1].TemplateID)
+	}
+
+	xref, err = lookup("CWE-79")
+	assert.NoError(t, err)
+	if assert.Len(t, xref.Templates, 1) && assert.Len(t, xref.Findings, 1) {
+		assert.Equal(t, "reflected-xss", xref.Templates[0].ID)
+		assert.Equal(t, "https://a.example.com/search", xref.Findings[0].Host)
+	}
+
+	xref, err = lookup("CWE-502")
+	assert.NoError(t, err)
+	assert.Len(t, xref.Templates, 1)
+	assert.Len(t, xref.Findings, 1)
+
+	_, err = lookup("log4shell")
+	assert.ErrorContains(t, err, "invalid id")
+
+	contents, err := api.HandleCrossReferenceResource(context.Background(), mcp.ReadResourceRequest{Params: mcp.ReadResourceParams{URI: "xref://CWE-917"}}, service, nil, []string{dir}, nil)
+	assert.NoError(t, err)
+	if assert.Len(t, contents, 1) {
+		assert.Contains(t, contents[0].(mcp.TextResourceContents).Text, `"id":"log4j-header-rce"`)
+	}
+}